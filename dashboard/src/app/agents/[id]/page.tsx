@@ -13,7 +13,7 @@ import { StatCardWithTrend } from "@/components/StatCardWithTrend";
 import { ProtocolBreakdownCards } from "@/components/ProtocolBreakdownCards";
 import { ToolPerformanceTable } from "@/components/ToolPerformanceTable";
 import { TrendChart } from "@/components/TrendChart";
-import { openApi, type Agent, type NetworkAgent, type AgentMetadata, type AgentService } from "@/lib/api";
+import { openApi, type Agent, type APIKeyInfo, type NetworkAgent, type AgentMetadata, type AgentService } from "@/lib/api";
 import { NETWORKS, resolveImageUrl } from "@/lib/networks";
 import { updateAgentURI, encodeDataUri } from "@/lib/erc8004";
 import { signChallenge } from "@/lib/wallet";
@@ -439,19 +439,23 @@ function SettingsTab({
   networkAgent,
   refreshAgent,
 }: SettingsTabProps) {
-  const [fetchedKey, setFetchedKey] = useState<string | null>(null);
+  const [keys, setKeys] = useState<APIKeyInfo[]>([]);
   const [generatedKey, setGeneratedKey] = useState<string | null>(null);
   const [keyLoading, setKeyLoading] = useState(false);
 
-  // Fetch existing API key on mount
-  useEffect(() => {
+  // Keys are stored hashed, so only their metadata can be listed.
+  const loadKeys = useCallback(() => {
     const auth = apiKey || (walletAddress ? { walletAddress } : null);
     if (!auth) return;
-    openApi.getAPIKey(agent?.agent_id || id, auth)
-      .then((res) => setFetchedKey(res.api_key))
-      .catch(() => {}); // key may not exist yet for legacy agents
+    openApi.listAPIKeys(agent?.agent_id || id, auth)
+      .then((res) => setKeys(res.keys))
+      .catch(() => {});
   }, [agent?.agent_id, id, apiKey, walletAddress]);
 
+  useEffect(() => {
+    loadKeys();
+  }, [loadKeys]);
+
   // Metadata editor state
   const [editingMetadata, setEditingMetadata] = useState(false);
   const [metadataValue, setMetadataValue] = useState("");
@@ -576,6 +580,7 @@ function SettingsTab({
     try {
       const res = await openApi.regenerateAPIKey(agent?.agent_id || id, auth);
       setGeneratedKey(res.api_key);
+      loadKeys();
     } catch (err) {
       console.error("Failed to regenerate API key:", err);
     } finally {
@@ -588,7 +593,7 @@ function SettingsTab({
       <div className="bg-[#0f0f0f] border border-[#1a1a1a] rounded-lg p-5">
         <h4 className="text-sm font-semibold text-zinc-400 mb-4">API Key</h4>
         {(() => {
-          const displayKey = generatedKey || fetchedKey || apiKey;
+          const displayKey = generatedKey || apiKey;
           return displayKey ? (
             <>
               <div className="flex items-center gap-2">
@@ -599,7 +604,7 @@ function SettingsTab({
               </div>
               <div className="flex items-center justify-between mt-3">
                 <p className="text-xs text-gray-600">
-                  Use this key to authenticate SDK and API requests.
+                  Use this key to authenticate SDK and API requests. It is only shown once.
                 </p>
                 <button
                   onClick={handleRegenerateKey}
@@ -612,9 +617,26 @@ function SettingsTab({
             </>
           ) : (
             <div className="space-y-3">
-              <p className="text-sm text-zinc-400">
-                No API key found. Generate a key to use with SDK and API.
-              </p>
+              {keys.length > 0 ? (
+                <ul className="space-y-1">
+                  {keys.map((k) => (
+                    <li key={k.id} className="flex items-center gap-3 text-xs text-zinc-400">
+                      <code className="font-mono text-gray-300">{k.key_prefix}…</code>
+                      <span>{k.name}</span>
+                      <span className="text-gray-600">{k.scopes.join(", ")}</span>
+                      {k.last_used_at && (
+                        <span className="text-gray-600 ml-auto">
+                          last used {new Date(k.last_used_at).toLocaleString()}
+                        </span>
+                      )}
+                    </li>
+                  ))}
+                </ul>
+              ) : (
+                <p className="text-sm text-zinc-400">
+                  No API key found. Generate a key to use with SDK and API.
+                </p>
+              )}
               <button
                 onClick={handleRegenerateKey}
                 disabled={keyLoading || (!apiKey && !walletAddress)}
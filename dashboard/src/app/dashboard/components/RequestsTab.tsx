@@ -185,7 +185,7 @@ export function RequestsTab({ agents, walletDaily }: RequestsTabProps) {
             Requests Over Time (Last 30 Days)
           </h3>
           <ResponsiveContainer width="100%" height={260}>
-            <LineChart data={[...walletDaily.stats].reverse()}>
+            <LineChart data={walletDaily.stats}>
               <XAxis
                 dataKey="date"
                 stroke="#71717a"
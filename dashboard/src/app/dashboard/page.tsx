@@ -321,7 +321,8 @@ function MyAgentsContent() {
   const healthyEndpoints = Object.values(healthStatus).filter(s => s === "healthy").length;
 
   // Today's stats from daily time-series
-  const todayStr = new Date().toISOString().slice(0, 10);
+  // Buckets are in the browser's time zone (see getWalletDailyStats).
+  const todayStr = new Date().toLocaleDateString("en-CA");
   const todayEntry = walletDaily?.stats?.find((s) => s.date === todayStr);
   const todayRequests = todayEntry?.requests ?? 0;
   const todayRevenue = todayEntry?.revenue ?? 0;
@@ -118,6 +118,200 @@ export interface Agent {
   created_at: string;
 }
 
+export type OrgRole = "owner" | "admin" | "analyst" | "billing_viewer";
+
+export interface Organization {
+  id: string;
+  name: string;
+  created_by: string;
+  created_at: string;
+  updated_at: string;
+  role?: OrgRole;
+}
+
+export interface OrgMember {
+  wallet_address: string;
+  role: OrgRole;
+  invited_by?: string;
+  joined_at: string;
+}
+
+export interface OrgAgent {
+  id: string;
+  agent_id: string;
+  name: string;
+  evm_address?: string;
+  status: string;
+}
+
+export interface OrgInvitation {
+  id: string;
+  org_id: string;
+  org_name: string;
+  wallet_address: string;
+  role: OrgRole;
+  invited_by: string;
+  message: string;
+  inviter_message: string;
+  expires_at: string;
+  created_at: string;
+}
+
+// inviterMessage is what the inviting wallet signs with personal_sign to
+// create an invitation; it must match the registry's copy exactly.
+export function inviterMessage(
+  orgId: string,
+  role: OrgRole,
+  walletAddress: string,
+  invitedBy: string,
+  issuedAt: string
+): string {
+  return (
+    "GT8004 organization invitation request\n\n" +
+    `Organization ID: ${orgId}\n` +
+    `Role: ${role}\n` +
+    `Wallet: ${walletAddress.toLowerCase()}\n` +
+    `Invited By: ${invitedBy.toLowerCase()}\n` +
+    `Issued At: ${issuedAt}`
+  );
+}
+
+export interface AuditEntry {
+  id: number;
+  chain: string;
+  seq: number;
+  actor_type: "wallet" | "api_key" | "internal" | "anonymous";
+  actor: string;
+  action: string;
+  target_type: string;
+  target_id: string;
+  before?: Record<string, unknown>;
+  after?: Record<string, unknown>;
+  ip_address?: string;
+  user_agent?: string;
+  created_at: string;
+  prev_hash: string;
+  hash: string;
+}
+
+export interface AuditLogResponse {
+  chain: string;
+  entries: AuditEntry[];
+  verification?: {
+    valid: boolean;
+    length: number;
+    head_hash?: string;
+    broken_at_seq?: number;
+  };
+}
+
+// One paid tier period.
+export interface TierSubscription {
+  id: number;
+  tier: string;
+  period_start: string;
+  period_end: string;
+  amount_usdc: number;
+  chain_id?: number;
+  tx_hash?: string;
+  payer?: string;
+  created_at: string;
+}
+
+// x402 payment requirements for a paid tier.
+export interface X402Requirements {
+  scheme: string;
+  network: string;
+  maxAmountRequired: string;
+  resource: string;
+  description: string;
+  mimeType: string;
+  payTo: string;
+  maxTimeoutSeconds: number;
+  asset: string;
+  extra?: Record<string, unknown>;
+}
+
+export interface TierStatus {
+  agent_id: string;
+  tier: string;
+  tier_updated_at?: string;
+  subscription: TierSubscription | null;
+  expires_at?: string;
+  periods: TierSubscription[];
+  plans?: X402Requirements[];
+}
+
+// An agent's owner change after its ERC-8004 token was transferred.
+export interface OwnershipTransfer {
+  id: number;
+  agent_id: string;
+  chain_id: number;
+  token_id: number;
+  from_address: string;
+  to_address: string;
+  tx_hash?: string;
+  log_index?: number;
+  block_number?: number;
+  revoked_api_keys: number;
+  created_at: string;
+}
+
+// Webhook subscription. The signing secret is only returned when a
+// subscription is created or its secret is rotated.
+export interface WebhookSubscription {
+  id: string;
+  wallet_address?: string;
+  url: string;
+  events: string[];
+  description?: string;
+  active: boolean;
+  created_by?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface WebhookDelivery {
+  id: string;
+  subscription_id: string;
+  event_id: string;
+  event_type: string;
+  payload: unknown;
+  redelivery_of?: string;
+  status: "pending" | "delivering" | "succeeded" | "failed";
+  attempts: number;
+  next_attempt_at?: string;
+  last_status_code?: number;
+  last_error?: string;
+  delivered_at?: string;
+  created_at: string;
+}
+
+export interface WebhookAttempt {
+  attempt: number;
+  status_code?: number;
+  error?: string;
+  response_body?: string;
+  duration_ms: number;
+  created_at: string;
+}
+
+export type APIKeyScope = "ingest" | "analytics:read" | "registry:admin";
+
+// API key metadata. The raw key is only returned when a key is created or rotated.
+export interface APIKeyInfo {
+  id: string;
+  name: string;
+  key_prefix: string;
+  scopes: APIKeyScope[];
+  ip_allowlist: string[];
+  expires_at?: string;
+  last_used_at?: string;
+  last_used_ip?: string;
+  rotated_from?: string;
+  created_at: string;
+}
+
 export interface RegisterRequest {
   // ERC-8004 fields - all metadata comes from contract
   // Backend verifies token ownership via RPC call
@@ -161,6 +355,11 @@ export interface Customer {
   churn_risk: string;
   country: string;
   city: string;
+  recency_score?: number;
+  frequency_score?: number;
+  monetary_score?: number;
+  segment?: string;
+  projected_ltv?: number;
 }
 
 export interface RevenuePeriod {
@@ -205,10 +404,13 @@ export interface PerformanceReport {
   p90_response_ms: number;
   health_score: number;
   health_status: string;
+  timezone: string;
+  trend_buckets: string[];
   p95_trend: number[];
   error_rate_trend: number[];
   throughput_trend: number[];
   uptime_trend: number[];
+  deployments: DeploymentMarker[];
   health_delta: number;
   p95_delta_ms: number;
   error_delta: number;
@@ -216,6 +418,14 @@ export interface PerformanceReport {
   uptime_delta: number;
 }
 
+export interface DeploymentMarker {
+  id: string;
+  version: string;
+  commit?: string;
+  deployed_at: string;
+  bucket: string;
+}
+
 // Wallet analytics types
 export interface WalletStats {
   total_requests: number;
@@ -262,6 +472,18 @@ export interface BenchmarkEntry {
   revenue: number;
   customer_count: number;
   calculated_at: string;
+  previous_rank?: number;
+  rank_change?: number;
+  breakdown?: {
+    category_size: number;
+    components: {
+      name: string;
+      value: number;
+      percentile: number;
+      weight: number;
+      contribution: number;
+    }[];
+  };
 }
 
 export interface RequestLog {
@@ -648,13 +870,61 @@ export const openApi = {
     ),
 
   // Wallet login (public)
-  walletLogin: (address: string, challenge: string, signature: string) =>
-    openFetcherPost<{ agent: Agent; api_key: string }>("/v1/auth/wallet-login", {
+  walletLogin: (address: string, challenge: string, signature: string, agentId?: string) =>
+    openFetcherPost<{ agent: Agent; api_key: string; role: OrgRole; scopes: APIKeyScope[] }>("/v1/auth/wallet-login", {
       address,
       challenge,
       signature,
+      agent_id: agentId,
     }),
 
+  // Organizations (wallet session token)
+  listOrganizations: (session: string) =>
+    openFetcher<{ organizations: Organization[] }>("/v1/orgs", session),
+  createOrganization: (name: string, session: string) =>
+    openFetcherPost<{ organization: Organization }>("/v1/orgs", { name }, session),
+  getOrganization: (orgId: string, session: string) =>
+    openFetcher<{ organization: Organization; members: OrgMember[]; agents: OrgAgent[] }>(
+      `/v1/orgs/${orgId}`,
+      session
+    ),
+  addOrgAgent: (orgId: string, agentId: string, session: string) =>
+    openFetcherPost<{ status: string }>(`/v1/orgs/${orgId}/agents`, { agent_id: agentId }, session),
+  removeOrgAgent: (orgId: string, agentId: string, session: string) =>
+    openFetcherDelete(`/v1/orgs/${orgId}/agents/${agentId}`, session),
+  updateOrgMember: (orgId: string, address: string, role: OrgRole, session: string) =>
+    openFetcherPut<{ wallet_address: string; role: OrgRole }>(
+      `/v1/orgs/${orgId}/members/${address}`,
+      { role },
+      session
+    ),
+  removeOrgMember: (orgId: string, address: string, session: string) =>
+    openFetcherDelete(`/v1/orgs/${orgId}/members/${address}`, session),
+  // issuedAt is RFC 3339 without fractional seconds; signature covers
+  // inviterMessage for the same fields.
+  inviteOrgMember: (
+    orgId: string,
+    walletAddress: string,
+    role: OrgRole,
+    issuedAt: string,
+    signature: string,
+    session: string,
+    chainId?: number
+  ) =>
+    openFetcherPost<{ invitation: OrgInvitation }>(
+      `/v1/orgs/${orgId}/invitations`,
+      { wallet_address: walletAddress, role, issued_at: issuedAt, signature, chain_id: chainId },
+      session
+    ),
+  listMyInvitations: (session: string) =>
+    openFetcher<{ invitations: OrgInvitation[] }>("/v1/invitations", session),
+  // The invited wallet signs invitation.message with personal_sign.
+  acceptInvitation: (invitationId: string, signature: string, chainId?: number) =>
+    openFetcherPost<{ org_id: string; member: OrgMember }>(
+      `/v1/invitations/${invitationId}/accept`,
+      { signature, chain_id: chainId }
+    ),
+
   // Wallet agents (public)
   getWalletAgents: (address: string) =>
     openFetcher<{ agents: Agent[]; total: number }>(
@@ -668,8 +938,9 @@ export const openApi = {
   },
   getWalletDailyStats: (address: string, auth: string | { walletAddress: string }, days = 30, chainIds?: number[]) => {
     const chainParam = chainIds?.length ? `&chain_ids=${chainIds.join(",")}` : "";
-    return openFetcher<{ stats: WalletDailyStats[] }>(
-      `/v1/wallet/${address}/daily?days=${days}${chainParam}`, auth
+    const tz = encodeURIComponent(Intl.DateTimeFormat().resolvedOptions().timeZone);
+    return openFetcher<{ stats: WalletDailyStats[]; timezone: string }>(
+      `/v1/wallet/${address}/daily?days=${days}&tz=${tz}${chainParam}`, auth
     );
   },
   getWalletErrors: (address: string, auth: string | { walletAddress: string }, chainIds?: number[]) => {
@@ -678,17 +949,133 @@ export const openApi = {
   },
 
   // API key management (API key or wallet owner)
-  getAPIKey: (agentId: string, auth: string | { walletAddress: string }) =>
-    openFetcher<{ api_key: string }>(
-      `/v1/agents/${agentId}/api-key`,
+  listAPIKeys: (agentId: string, auth: string | { walletAddress: string }) =>
+    openFetcher<{ keys: APIKeyInfo[]; scopes: APIKeyScope[] }>(
+      `/v1/agents/${agentId}/api-keys`,
+      auth
+    ),
+  createAPIKey: (
+    agentId: string,
+    req: { name: string; scopes?: APIKeyScope[]; ip_allowlist?: string[]; expires_at?: string },
+    auth: string | { walletAddress: string }
+  ) =>
+    openFetcherPost<{ key: APIKeyInfo; api_key: string }>(
+      `/v1/agents/${agentId}/api-keys`,
+      req,
+      auth
+    ),
+  rotateAPIKey: (
+    agentId: string,
+    keyId: string,
+    gracePeriodSeconds: number,
+    auth: string | { walletAddress: string }
+  ) =>
+    openFetcherPost<{ key: APIKeyInfo; api_key: string; previous_key_id: string; previous_expires_at: string }>(
+      `/v1/agents/${agentId}/api-keys/${keyId}/rotate`,
+      { grace_period_seconds: gracePeriodSeconds },
       auth
     ),
+  revokeAPIKey: (agentId: string, keyId: string, auth: string | { walletAddress: string }) =>
+    openFetcherDelete(`/v1/agents/${agentId}/api-keys/${keyId}`, auth),
   regenerateAPIKey: (agentId: string, auth: string | { walletAddress: string }) =>
     openFetcherPost<{ api_key: string }>(
       `/v1/agents/${agentId}/api-key/regenerate`,
       {},
       auth
     ),
+  getAuditLog: (
+    agentId: string,
+    auth: string | { walletAddress: string },
+    opts?: { action?: string; beforeSeq?: number; limit?: number; verify?: boolean }
+  ) => {
+    const params = new URLSearchParams();
+    if (opts?.action) params.set("action", opts.action);
+    if (opts?.beforeSeq) params.set("before_seq", String(opts.beforeSeq));
+    if (opts?.limit) params.set("limit", String(opts.limit));
+    if (opts?.verify) params.set("verify", "true");
+    const qs = params.toString();
+    return openFetcher<AuditLogResponse>(
+      `/v1/agents/${agentId}/audit-log${qs ? `?${qs}` : ""}`,
+      auth
+    );
+  },
+
+  getTierStatus: (agentId: string, auth: string | { walletAddress: string }) =>
+    openFetcher<TierStatus>(`/v1/services/${agentId}/tier`, auth),
+  getOwnershipHistory: (agentId: string, auth: string | { walletAddress: string }) =>
+    openFetcher<{ transfers: OwnershipTransfer[] }>(`/v1/agents/${agentId}/ownership-history`, auth),
+
+  // Webhooks (agent-scoped; owner auth)
+  listWebhooks: (agentId: string, auth: string | { walletAddress: string }) =>
+    openFetcher<{ webhooks: WebhookSubscription[]; events: string[] }>(
+      `/v1/agents/${agentId}/webhooks`,
+      auth
+    ),
+  createWebhook: (
+    agentId: string,
+    req: { url: string; events: string[]; description?: string },
+    auth: string | { walletAddress: string }
+  ) =>
+    openFetcherPost<{ webhook: WebhookSubscription; secret: string }>(
+      `/v1/agents/${agentId}/webhooks`,
+      req,
+      auth
+    ),
+  updateWebhook: (
+    agentId: string,
+    webhookId: string,
+    req: { url?: string; events?: string[]; description?: string; active?: boolean },
+    auth: string | { walletAddress: string }
+  ) =>
+    openFetcherPut<{ webhook: WebhookSubscription }>(
+      `/v1/agents/${agentId}/webhooks/${webhookId}`,
+      req,
+      auth
+    ),
+  rotateWebhookSecret: (agentId: string, webhookId: string, auth: string | { walletAddress: string }) =>
+    openFetcherPost<{ secret: string }>(
+      `/v1/agents/${agentId}/webhooks/${webhookId}/rotate-secret`,
+      {},
+      auth
+    ),
+  deleteWebhook: (agentId: string, webhookId: string, auth: string | { walletAddress: string }) =>
+    openFetcherDelete(`/v1/agents/${agentId}/webhooks/${webhookId}`, auth),
+  listWebhookDeliveries: (
+    agentId: string,
+    webhookId: string,
+    auth: string | { walletAddress: string },
+    opts?: { status?: string; limit?: number }
+  ) => {
+    const params = new URLSearchParams();
+    if (opts?.status) params.set("status", opts.status);
+    if (opts?.limit) params.set("limit", String(opts.limit));
+    const qs = params.toString();
+    return openFetcher<{ deliveries: WebhookDelivery[] }>(
+      `/v1/agents/${agentId}/webhooks/${webhookId}/deliveries${qs ? `?${qs}` : ""}`,
+      auth
+    );
+  },
+  getWebhookDelivery: (
+    agentId: string,
+    webhookId: string,
+    deliveryId: string,
+    auth: string | { walletAddress: string }
+  ) =>
+    openFetcher<{ delivery: WebhookDelivery; attempts: WebhookAttempt[] }>(
+      `/v1/agents/${agentId}/webhooks/${webhookId}/deliveries/${deliveryId}`,
+      auth
+    ),
+  redeliverWebhook: (
+    agentId: string,
+    webhookId: string,
+    deliveryId: string,
+    auth: string | { walletAddress: string }
+  ) =>
+    openFetcherPost<{ delivery_id: string; status: string }>(
+      `/v1/agents/${agentId}/webhooks/${webhookId}/deliveries/${deliveryId}/redeliver`,
+      {},
+      auth
+    ),
 
   // On-chain reputation (public)
   getReputationSummary: (tokenId: number, chainId?: number) =>
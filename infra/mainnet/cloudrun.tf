@@ -94,6 +94,12 @@ resource "google_cloud_run_v2_service" "registry" {
         name  = "BASE_RPC_URL"
         value = var.base_rpc_url
       }
+      env {
+        name  = "TRUSTED_PROXIES"
+        # Only the API gateway can invoke this service, and it forwards
+        # the client IP it resolved as X-Forwarded-For.
+        value = "0.0.0.0/0,::/0"
+      }
     }
   }
 
@@ -166,6 +172,12 @@ resource "google_cloud_run_v2_service" "analytics" {
         name  = "REGISTRY_URL"
         value = google_cloud_run_v2_service.registry.uri
       }
+      env {
+        name  = "TRUSTED_PROXIES"
+        # Only the API gateway can invoke this service, and it forwards
+        # the client IP it resolved as X-Forwarded-For.
+        value = "0.0.0.0/0,::/0"
+      }
     }
   }
 
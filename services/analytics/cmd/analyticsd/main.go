@@ -15,10 +15,13 @@ import (
 	"github.com/GT8004/gt8004-analytics/internal/analytics"
 	"github.com/GT8004/gt8004-analytics/internal/cache"
 	"github.com/GT8004/gt8004-analytics/internal/config"
+	"github.com/GT8004/gt8004-analytics/internal/export"
 	"github.com/GT8004/gt8004-analytics/internal/handler"
 	"github.com/GT8004/gt8004-analytics/internal/retention"
 	"github.com/GT8004/gt8004-analytics/internal/server"
 	"github.com/GT8004/gt8004-analytics/internal/store"
+	"github.com/GT8004/gt8004-common/ratelimit"
+	"github.com/GT8004/gt8004-common/session"
 )
 
 func main() {
@@ -53,6 +56,13 @@ func main() {
 		defer redisCache.Close()
 	}
 
+	// Rate limits (shared through Redis, per instance without it)
+	limiter, err := ratelimit.New(cfg.RedisURL, logger)
+	if err != nil {
+		logger.Fatal("failed to configure rate limits", zap.Error(err))
+	}
+	defer limiter.Close()
+
 	// Body retention cleanup job
 	retentionJob := retention.NewJob(db, cfg.BodyRetentionDays, logger)
 	retentionJob.Start()
@@ -61,27 +71,70 @@ func main() {
 	custAnalytics := analytics.NewCustomerAnalytics(db, logger)
 	revAnalytics := analytics.NewRevenueAnalytics(db, logger)
 	perfAnalytics := analytics.NewPerformanceAnalytics(db, logger)
+	funnelAnalytics := analytics.NewFunnelAnalytics(db, logger)
+	peerAnalytics := analytics.NewPeerAnalytics(db, logger, cfg.PeerMinGroupSize)
 
 	// Benchmark calculator (background job)
-	benchCalc := analytics.NewBenchmarkCalculator(db, logger, time.Duration(cfg.BenchmarkInterval)*time.Second)
+	benchWeights := make(map[string]analytics.BenchmarkWeights, len(cfg.BenchmarkWeights))
+	for category, w := range cfg.BenchmarkWeights {
+		benchWeights[category] = w
+	}
+	if err := analytics.ValidateBenchmarkWeights(benchWeights); err != nil {
+		logger.Fatal("invalid BENCHMARK_WEIGHTS", zap.Error(err))
+	}
+	benchCalc := analytics.NewBenchmarkCalculator(db, logger, time.Duration(cfg.BenchmarkInterval)*time.Second, benchWeights)
 	benchCalc.Start()
 
 	// Reputation calculator (background job)
 	repCalc := analytics.NewReputationCalculator(db, logger, time.Duration(cfg.ReputationInterval)*time.Second)
 	repCalc.Start()
 
+	// Churn scorer (background job)
+	churnScorer := analytics.NewChurnScorer(db, custAnalytics, logger, time.Duration(cfg.ChurnInterval)*time.Second)
+	churnScorer.Start()
+
+	// Customer sessionizer (background job)
+	sessionizer := analytics.NewSessionizer(db, logger,
+		time.Duration(cfg.SessionInterval)*time.Second,
+		time.Duration(cfg.SessionGapMinutes)*time.Minute,
+	)
+	sessionizer.Start()
+
+	// Data export worker (background job)
+	exportBlobs, err := export.NewLocalStore(cfg.ExportDir)
+	if err != nil {
+		logger.Fatal("failed to initialize export storage", zap.Error(err))
+	}
+	// Download links must verify on every instance and across restarts.
+	if cfg.ExportSigningSecret == "" {
+		logger.Fatal("EXPORT_SIGNING_SECRET must be set")
+	}
+	exportSigner := export.NewSigner(cfg.ExportSigningSecret, time.Duration(cfg.ExportURLTTLSeconds)*time.Second)
+	exportWorker := export.NewWorker(db, exportBlobs, time.Duration(cfg.ExportRetentionHours)*time.Hour, logger)
+	exportWorker.Start()
+
 	// Handler
 	h := handler.New(
 		db,
-		custAnalytics, revAnalytics, perfAnalytics,
+		custAnalytics, revAnalytics, perfAnalytics, funnelAnalytics, peerAnalytics,
 		redisCache,
 		logger,
 		cfg.RegistryURL,
 		cfg.ChainIDs(),
+		exportWorker, exportBlobs, exportSigner,
 	)
 
+	// Wallet session tokens issued by the registry
+	sessionSigner, err := session.NewSigner(cfg.SessionSecret)
+	if err != nil {
+		logger.Fatal("invalid session secret", zap.Error(err))
+	}
+	if !sessionSigner.Enabled() {
+		logger.Warn("SESSION_SECRET not set, wallet session tokens will be rejected")
+	}
+
 	// Router + HTTP server
-	router := server.NewRouter(cfg, h)
+	router := server.NewRouter(cfg, h, sessionSigner, limiter)
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
 		Handler:      router,
@@ -123,7 +176,10 @@ func main() {
 
 	benchCalc.Stop()
 	repCalc.Stop()
+	churnScorer.Stop()
+	sessionizer.Stop()
 	retentionJob.Stop()
+	exportWorker.Stop()
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
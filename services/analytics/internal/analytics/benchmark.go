@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"context"
+	"fmt"
 	"math"
 	"sort"
 	"time"
@@ -11,24 +12,90 @@ import (
 	"github.com/GT8004/gt8004-analytics/internal/store"
 )
 
+// Benchmark score components.
+const (
+	BenchmarkRequests    = "requests"
+	BenchmarkReliability = "reliability"
+	BenchmarkLatency     = "latency"
+	BenchmarkCustomers   = "customers"
+	BenchmarkRevenue     = "revenue"
+)
+
+// BenchmarkWeights maps component name to its relative weight.
+type BenchmarkWeights map[string]float64
+
+// DefaultBenchmarkWeights is used for categories without configured weights.
+var DefaultBenchmarkWeights = BenchmarkWeights{
+	BenchmarkRequests:    0.20,
+	BenchmarkReliability: 0.25,
+	BenchmarkLatency:     0.20,
+	BenchmarkCustomers:   0.20,
+	BenchmarkRevenue:     0.15,
+}
+
+// benchmarkComponents fixes the order components appear in breakdowns.
+var benchmarkComponents = []string{
+	BenchmarkRequests, BenchmarkReliability, BenchmarkLatency, BenchmarkCustomers, BenchmarkRevenue,
+}
+
+// ValidateBenchmarkWeights checks per-category weights: every key must be a
+// known component, weights must be non-negative and not all zero.
+func ValidateBenchmarkWeights(byCategory map[string]BenchmarkWeights) error {
+	for category, w := range byCategory {
+		var sum float64
+		for name, v := range w {
+			known := false
+			for _, c := range benchmarkComponents {
+				known = known || c == name
+			}
+			if !known {
+				return fmt.Errorf("benchmark weights for %q: unknown component %q", category, name)
+			}
+			if v < 0 {
+				return fmt.Errorf("benchmark weights for %q: %s must not be negative", category, name)
+			}
+			sum += v
+		}
+		if sum == 0 {
+			return fmt.Errorf("benchmark weights for %q: at least one weight must be positive", category)
+		}
+	}
+	return nil
+}
+
 // BenchmarkCalculator periodically recalculates benchmark rankings for each agent category.
 type BenchmarkCalculator struct {
 	store    *store.Store
 	logger   *zap.Logger
 	interval time.Duration
-	stopCh   chan struct{}
+	// weights holds per-category overrides; the "default" key replaces
+	// DefaultBenchmarkWeights for all other categories.
+	weights map[string]BenchmarkWeights
+	stopCh  chan struct{}
 }
 
 // NewBenchmarkCalculator creates a new BenchmarkCalculator.
-func NewBenchmarkCalculator(s *store.Store, logger *zap.Logger, interval time.Duration) *BenchmarkCalculator {
+func NewBenchmarkCalculator(s *store.Store, logger *zap.Logger, interval time.Duration, weights map[string]BenchmarkWeights) *BenchmarkCalculator {
 	return &BenchmarkCalculator{
 		store:    s,
 		logger:   logger,
 		interval: interval,
+		weights:  weights,
 		stopCh:   make(chan struct{}),
 	}
 }
 
+// weightsFor returns the weights used for a category.
+func (bc *BenchmarkCalculator) weightsFor(category string) BenchmarkWeights {
+	if w, ok := bc.weights[category]; ok {
+		return w
+	}
+	if w, ok := bc.weights["default"]; ok {
+		return w
+	}
+	return DefaultBenchmarkWeights
+}
+
 // Start begins the periodic benchmark recalculation loop in a background goroutine.
 func (bc *BenchmarkCalculator) Start() {
 	go func() {
@@ -59,9 +126,10 @@ func (bc *BenchmarkCalculator) Stop() {
 
 // agentScore holds intermediate scoring data for ranking.
 type agentScore struct {
-	agent   store.Agent
-	score   float64
-	errRate float64
+	agent     store.Agent
+	errRate   float64
+	score     float64
+	breakdown *store.BenchmarkBreakdown
 }
 
 // Calculate recalculates benchmark rankings for every distinct agent category.
@@ -75,6 +143,10 @@ func (bc *BenchmarkCalculator) Calculate() {
 		return
 	}
 
+	if err := bc.store.PruneBenchmarkCategories(ctx, categories); err != nil {
+		bc.logger.Warn("failed to prune benchmark categories", zap.Error(err))
+	}
+
 	if len(categories) == 0 {
 		bc.logger.Debug("no agent categories found, skipping benchmark calculation")
 		return
@@ -88,6 +160,8 @@ func (bc *BenchmarkCalculator) Calculate() {
 }
 
 // calculateCategory computes benchmark scores and rankings for a single category.
+// Each component is converted to a within-category percentile before
+// weighting, so no single raw metric (such as request volume) can dominate.
 func (bc *BenchmarkCalculator) calculateCategory(ctx context.Context, category string) {
 	agents, err := bc.store.GetActiveAgentsByCategory(ctx, category)
 	if err != nil {
@@ -102,9 +176,8 @@ func (bc *BenchmarkCalculator) calculateCategory(ctx context.Context, category s
 		return
 	}
 
-	// Calculate composite scores for each agent.
-	scored := make([]agentScore, 0, len(agents))
-	for _, agent := range agents {
+	scored := make([]agentScore, len(agents))
+	for i, agent := range agents {
 		errRate, err := bc.store.GetAgentErrorRate(ctx, agent.ID)
 		if err != nil {
 			bc.logger.Warn("failed to get error rate for agent",
@@ -113,42 +186,73 @@ func (bc *BenchmarkCalculator) calculateCategory(ctx context.Context, category s
 			)
 			errRate = 0
 		}
+		scored[i] = agentScore{agent: agent, errRate: errRate}
+	}
 
-		// Composite score formula:
-		//   score = (total_requests * 0.3) + ((1 - error_rate) * 100 * 0.3)
-		//         + (customer_count * 0.2) + (max(0, 100 - avg_response_ms/10) * 0.2)
-		requestScore := float64(agent.TotalRequests) * 0.3
-		reliabilityScore := (1 - errRate) * 100 * 0.3
-		customerScore := float64(agent.TotalCustomers) * 0.2
-		latencyScore := math.Max(0, 100-agent.AvgResponseMs/10) * 0.2
+	// Raw values per component; higher is better except latency. Agents
+	// without traffic have no meaningful reliability or latency and are
+	// left out of those populations (scored at the 0th percentile).
+	values := map[string][]float64{}
+	served := make([]bool, len(scored))
+	for i, s := range scored {
+		served[i] = s.agent.TotalRequests > 0
+		values[BenchmarkRequests] = append(values[BenchmarkRequests], float64(s.agent.TotalRequests))
+		values[BenchmarkReliability] = append(values[BenchmarkReliability], 1-s.errRate)
+		values[BenchmarkLatency] = append(values[BenchmarkLatency], -s.agent.AvgResponseMs)
+		values[BenchmarkCustomers] = append(values[BenchmarkCustomers], float64(s.agent.TotalCustomers))
+		values[BenchmarkRevenue] = append(values[BenchmarkRevenue], s.agent.TotalRevenueUSDC)
+	}
+	percentiles := map[string][]float64{}
+	for _, name := range benchmarkComponents {
+		if name == BenchmarkReliability || name == BenchmarkLatency {
+			percentiles[name] = percentileRanks(values[name], served)
+		} else {
+			percentiles[name] = percentileRanks(values[name], nil)
+		}
+	}
 
-		composite := requestScore + reliabilityScore + customerScore + latencyScore
+	weights := bc.weightsFor(category)
+	var totalWeight float64
+	for _, w := range weights {
+		totalWeight += w
+	}
 
-		scored = append(scored, agentScore{
-			agent:   agent,
-			score:   composite,
-			errRate: errRate,
-		})
+	for i := range scored {
+		bd := &store.BenchmarkBreakdown{CategorySize: len(scored)}
+		var score float64
+		for _, name := range benchmarkComponents {
+			w := weights[name] / totalWeight
+			pct := percentiles[name][i]
+			value := values[name][i]
+			if name == BenchmarkLatency {
+				value = -value
+			}
+			contribution := w * pct
+			score += contribution
+			bd.Components = append(bd.Components, store.BenchmarkComponent{
+				Name:         name,
+				Value:        value,
+				Percentile:   math.Round(pct*100) / 100,
+				Weight:       math.Round(w*1000) / 1000,
+				Contribution: math.Round(contribution*100) / 100,
+			})
+		}
+		scored[i].score = math.Round(score*100) / 100
+		scored[i].breakdown = bd
 	}
 
-	// Sort by score descending.
-	sort.Slice(scored, func(i, j int) bool {
-		return scored[i].score > scored[j].score
+	// Sort by score descending; ties go to the busier agent.
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].score != scored[j].score {
+			return scored[i].score > scored[j].score
+		}
+		return scored[i].agent.TotalRequests > scored[j].agent.TotalRequests
 	})
 
-	// Clear old entries for this category.
-	if err := bc.store.ClearBenchmarkCategory(ctx, category); err != nil {
-		bc.logger.Error("failed to clear benchmark category",
-			zap.String("category", category),
-			zap.Error(err),
-		)
-		return
-	}
-
-	// Upsert new ranked entries.
 	now := time.Now().UTC()
+	entries := make([]store.BenchmarkEntry, len(scored))
 	for rank, s := range scored {
-		entry := &store.BenchmarkEntry{
+		entries[rank] = store.BenchmarkEntry{
 			Category:      category,
 			AgentID:       s.agent.ID,
 			Rank:          rank + 1,
@@ -159,15 +263,16 @@ func (bc *BenchmarkCalculator) calculateCategory(ctx context.Context, category s
 			Revenue:       s.agent.TotalRevenueUSDC,
 			CustomerCount: s.agent.TotalCustomers,
 			CalculatedAt:  now,
+			Breakdown:     s.breakdown,
 		}
+	}
 
-		if err := bc.store.UpsertBenchmarkEntry(ctx, entry); err != nil {
-			bc.logger.Error("failed to upsert benchmark entry",
-				zap.String("category", category),
-				zap.String("agent_id", s.agent.AgentID),
-				zap.Error(err),
-			)
-		}
+	if err := bc.store.SaveBenchmarkCategory(ctx, category, entries); err != nil {
+		bc.logger.Error("failed to save benchmark category",
+			zap.String("category", category),
+			zap.Error(err),
+		)
+		return
 	}
 
 	bc.logger.Debug("benchmark calculated for category",
@@ -175,3 +280,33 @@ func (bc *BenchmarkCalculator) calculateCategory(ctx context.Context, category s
 		zap.Int("agents_ranked", len(scored)),
 	)
 }
+
+// percentileRanks returns each value's mid-rank percentile (0-100) among
+// the included values; ties share the same percentile. When include is
+// non-nil, excluded entries get 0 and are not part of the population.
+// A population of one scores 100.
+func percentileRanks(values []float64, include []bool) []float64 {
+	var pop []float64
+	for i, v := range values {
+		if include == nil || include[i] {
+			pop = append(pop, v)
+		}
+	}
+	sort.Float64s(pop)
+
+	out := make([]float64, len(values))
+	n := len(pop)
+	for i, v := range values {
+		if include != nil && !include[i] {
+			continue
+		}
+		if n == 1 {
+			out[i] = 100
+			continue
+		}
+		below := sort.SearchFloat64s(pop, v)
+		equal := sort.Search(n, func(k int) bool { return pop[k] > v }) - below
+		out[i] = (float64(below) + float64(equal-1)/2) / float64(n-1) * 100
+	}
+	return out
+}
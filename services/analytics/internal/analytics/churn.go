@@ -0,0 +1,283 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// Weights for each churn factor. The trend factors only apply to customers
+// who visit at least weekly, and payment trend only to customers that have
+// paid; weights of the applicable factors are renormalized to sum to 1.
+const (
+	churnWeightRecency = 0.50
+	churnWeightUsage   = 0.20
+	churnWeightErrors  = 0.15
+	churnWeightPayment = 0.15
+)
+
+// Churn risk label thresholds on the 0-1 score.
+const (
+	churnHighThreshold   = 0.70
+	churnMediumThreshold = 0.40
+)
+
+// minCustomerGaps is how many visit gaps a customer needs before their own
+// cadence is trusted over the agent-wide one.
+const minCustomerGaps = 3
+
+// maxTrendGap is the longest expected visit gap for which the 14-day trends
+// are meaningful; slower customers are judged on recency alone.
+const maxTrendGap = 7 * 24 * time.Hour
+
+// defaultExpectedGap is used when neither the customer nor the agent has
+// enough history.
+const defaultExpectedGap = 7 * 24 * time.Hour
+
+// ChurnFactors explains a churn score. Each factor is 0 (healthy) to 1
+// (strong churn signal).
+type ChurnFactors struct {
+	Recency      RecencyFactor      `json:"recency"`
+	UsageTrend   *TrendFactor       `json:"usage_trend,omitempty"`
+	Errors       ErrorFactor        `json:"errors"`
+	PaymentTrend *TrendFactor       `json:"payment_trend,omitempty"`
+	Weights      map[string]float64 `json:"weights"`
+}
+
+// RecencyFactor compares time since last visit with the customer's usual gap.
+type RecencyFactor struct {
+	Score              float64 `json:"score"`
+	HoursSinceLastSeen float64 `json:"hours_since_last_seen"`
+	ExpectedGapHours   float64 `json:"expected_gap_hours"`
+	// Basis is "customer", "agent" or "default" depending on whose visit
+	// history set the expected gap.
+	Basis string `json:"basis"`
+}
+
+// TrendFactor compares the last 14 days with the 14 days before.
+type TrendFactor struct {
+	Score    float64 `json:"score"`
+	Recent   float64 `json:"recent"`
+	Previous float64 `json:"previous"`
+}
+
+// ErrorFactor reflects the share of failed requests in the last 30 days.
+type ErrorFactor struct {
+	Score     float64 `json:"score"`
+	ErrorRate float64 `json:"error_rate"`
+	Requests  int64   `json:"requests"`
+}
+
+// ChurnScorer periodically rescores churn risk and RFM segments for every
+// active agent.
+type ChurnScorer struct {
+	store     *store.Store
+	customers *CustomerAnalytics
+	logger    *zap.Logger
+	interval  time.Duration
+	stopCh    chan struct{}
+}
+
+// NewChurnScorer creates a new ChurnScorer.
+func NewChurnScorer(s *store.Store, customers *CustomerAnalytics, logger *zap.Logger, interval time.Duration) *ChurnScorer {
+	return &ChurnScorer{
+		store:     s,
+		customers: customers,
+		logger:    logger,
+		interval:  interval,
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// Start begins the periodic scoring loop in a background goroutine.
+func (cs *ChurnScorer) Start() {
+	go func() {
+		ticker := time.NewTicker(cs.interval)
+		defer ticker.Stop()
+
+		cs.logger.Info("churn scorer started", zap.Duration("interval", cs.interval))
+
+		cs.Calculate()
+
+		for {
+			select {
+			case <-ticker.C:
+				cs.Calculate()
+			case <-cs.stopCh:
+				cs.logger.Info("churn scorer stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop signals the churn scorer to stop.
+func (cs *ChurnScorer) Stop() {
+	close(cs.stopCh)
+}
+
+// Calculate rescores churn risk, then RFM segments, for all active agents.
+func (cs *ChurnScorer) Calculate() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	agentIDs, err := cs.store.ListAllActiveAgentIDs(ctx)
+	if err != nil {
+		cs.logger.Error("failed to list active agents for churn scoring", zap.Error(err))
+		return
+	}
+
+	updated := 0
+	for _, agentID := range agentIDs {
+		if err := cs.customers.RefreshChurnRisk(ctx, agentID); err != nil {
+			cs.logger.Warn("failed to refresh churn risk",
+				zap.String("agent_id", agentID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		// Segments use the fresh churn score for projected LTV.
+		if err := cs.customers.RefreshSegments(ctx, agentID); err != nil {
+			cs.logger.Warn("failed to refresh customer segments",
+				zap.String("agent_id", agentID.String()),
+				zap.Error(err),
+			)
+		}
+		updated++
+	}
+
+	cs.logger.Info("churn scoring complete",
+		zap.Int("total_agents", len(agentIDs)),
+		zap.Int("updated", updated),
+	)
+}
+
+// agentTypicalGap returns the median of customers' p90 visit gaps, used as
+// the expected gap for customers with too little history of their own.
+func agentTypicalGap(inputs []store.ChurnInputs) time.Duration {
+	var gaps []float64
+	for _, in := range inputs {
+		if in.GapCount >= minCustomerGaps && in.P90GapSec > 0 {
+			gaps = append(gaps, in.P90GapSec)
+		}
+	}
+	if len(gaps) == 0 {
+		return 0
+	}
+	sort.Float64s(gaps)
+	return time.Duration(gaps[len(gaps)/2] * float64(time.Second))
+}
+
+// scoreChurn combines four signals into a 0-1 churn score:
+//   - recency: time since last visit relative to the customer's own p90
+//     inter-visit gap, so a monthly customer is not flagged after a week and
+//     an hourly one is flagged within a day;
+//   - usage trend: decline in requests over the last 14 days vs the prior 14,
+//     for customers that visit at least weekly;
+//   - errors: error rate experienced over the last 30 days;
+//   - payment trend: decline in verified revenue over the same windows, for
+//     paying customers that visit at least weekly.
+func scoreChurn(in store.ChurnInputs, agentGap time.Duration, now time.Time) store.ChurnScore {
+	expected, basis := defaultExpectedGap, "default"
+	switch {
+	case in.GapCount >= minCustomerGaps && in.P90GapSec > 0:
+		expected, basis = time.Duration(in.P90GapSec*float64(time.Second)), "customer"
+	case agentGap > 0:
+		expected, basis = agentGap, "agent"
+	}
+
+	elapsed := now.Sub(in.LastSeenAt)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	// Logistic in elapsed/expected: 0.5 at the usual gap, ~0.95 at twice it.
+	ratio := elapsed.Seconds() / expected.Seconds()
+	recency := 1 / (1 + math.Exp(-3*(ratio-1)))
+
+	factors := ChurnFactors{
+		Recency: RecencyFactor{
+			Score:              round3(recency),
+			HoursSinceLastSeen: round3(elapsed.Hours()),
+			ExpectedGapHours:   round3(expected.Hours()),
+			Basis:              basis,
+		},
+		Errors: ErrorFactor{Requests: in.Requests30d},
+		Weights: map[string]float64{
+			"recency": churnWeightRecency,
+			"errors":  churnWeightErrors,
+		},
+	}
+	if in.Requests30d > 0 {
+		rate := float64(in.Errors30d) / float64(in.Requests30d)
+		factors.Errors.ErrorRate = round3(rate)
+		// A 50% error rate is treated as a maximal signal.
+		factors.Errors.Score = round3(math.Min(1, rate*2))
+	}
+
+	weighted := churnWeightRecency*factors.Recency.Score + churnWeightErrors*factors.Errors.Score
+	totalWeight := churnWeightRecency + churnWeightErrors
+
+	if expected <= maxTrendGap {
+		ut := TrendFactor{
+			Score:    round3(decline(float64(in.RecentRequests), float64(in.PreviousRequests))),
+			Recent:   float64(in.RecentRequests),
+			Previous: float64(in.PreviousRequests),
+		}
+		factors.UsageTrend = &ut
+		factors.Weights["usage_trend"] = churnWeightUsage
+		weighted += churnWeightUsage * ut.Score
+		totalWeight += churnWeightUsage
+	}
+
+	if in.TotalRevenue > 0 && expected <= maxTrendGap {
+		pt := TrendFactor{
+			Score:    round3(decline(in.RecentRevenue, in.PreviousRevenue)),
+			Recent:   in.RecentRevenue,
+			Previous: in.PreviousRevenue,
+		}
+		factors.PaymentTrend = &pt
+		factors.Weights["payment_trend"] = churnWeightPayment
+		weighted += churnWeightPayment * pt.Score
+		totalWeight += churnWeightPayment
+	}
+
+	for k, w := range factors.Weights {
+		factors.Weights[k] = round3(w / totalWeight)
+	}
+
+	score := round3(weighted / totalWeight)
+	risk := "low"
+	switch {
+	case score >= churnHighThreshold:
+		risk = "high"
+	case score >= churnMediumThreshold:
+		risk = "medium"
+	}
+
+	raw, _ := json.Marshal(factors)
+	return store.ChurnScore{
+		CustomerID: in.CustomerID,
+		Score:      score,
+		Risk:       risk,
+		Factors:    raw,
+	}
+}
+
+// decline returns the relative drop from previous to recent, 0 when flat or
+// growing and 1 when activity stopped entirely.
+func decline(recent, previous float64) float64 {
+	if previous <= 0 || recent >= previous {
+		return 0
+	}
+	return (previous - recent) / previous
+}
+
+func round3(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -3,6 +3,7 @@ package analytics
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -10,11 +11,46 @@ import (
 	"github.com/GT8004/gt8004-analytics/internal/store"
 )
 
-// Cohort represents a monthly customer cohort with retention data.
+// CohortCell is one period of a cohort's retention row. Offset 0 is the
+// period the cohort was acquired in.
+type CohortCell struct {
+	Offset           int      `json:"offset"`
+	ActiveCustomers  int      `json:"active_customers"`
+	RetentionRate    float64  `json:"retention_rate"`
+	Revenue          *float64 `json:"revenue,omitempty"`
+	RevenueRetention *float64 `json:"revenue_retention,omitempty"`
+	// Partial marks the current, still-running period.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// Cohort is a row of the retention matrix: customers first seen in the same
+// week or month and their activity in each following period.
 type Cohort struct {
-	Month             string `json:"month"`
-	NewCustomers      int    `json:"new_customers"`
-	RetainedCustomers int    `json:"retained_customers"`
+	Cohort    string       `json:"cohort"`
+	StartDate time.Time    `json:"start_date"`
+	Size      int          `json:"size"`
+	Periods   []CohortCell `json:"periods"`
+	// NetRevenueRetention is the revenue retention of the latest complete
+	// period; nil when revenue is not requested or the cohort had no revenue
+	// in its first period.
+	NetRevenueRetention *float64 `json:"net_revenue_retention,omitempty"`
+}
+
+// CohortAverage is the size-weighted retention across all cohorts that have
+// completed a given period offset.
+type CohortAverage struct {
+	Offset           int      `json:"offset"`
+	Cohorts          int      `json:"cohorts"`
+	RetentionRate    float64  `json:"retention_rate"`
+	RevenueRetention *float64 `json:"revenue_retention,omitempty"`
+}
+
+// CohortReport is a cohort x period retention matrix.
+type CohortReport struct {
+	Granularity string          `json:"granularity"`
+	Periods     int             `json:"periods"`
+	Cohorts     []Cohort        `json:"cohorts"`
+	Average     []CohortAverage `json:"average"`
 }
 
 // CustomerAnalytics provides customer intelligence operations.
@@ -31,35 +67,184 @@ func NewCustomerAnalytics(s *store.Store, logger *zap.Logger) *CustomerAnalytics
 	}
 }
 
-// RefreshChurnRisk recalculates churn risk for all customers of an agent.
+// RefreshChurnRisk rescores churn risk for all customers of an agent.
+// See scoreChurn for how each customer's score is derived.
 func (ca *CustomerAnalytics) RefreshChurnRisk(ctx context.Context, agentDBID uuid.UUID) error {
-	if err := ca.store.UpdateChurnRisk(ctx, agentDBID); err != nil {
+	inputs, err := ca.store.GetChurnInputs(ctx, agentDBID)
+	if err != nil {
 		return fmt.Errorf("refresh churn risk: %w", err)
 	}
-	ca.logger.Debug("churn risk refreshed", zap.String("agent_db_id", agentDBID.String()))
+
+	now := time.Now()
+	fallback := agentTypicalGap(inputs)
+	scores := make([]store.ChurnScore, 0, len(inputs))
+	for _, in := range inputs {
+		scores = append(scores, scoreChurn(in, fallback, now))
+	}
+
+	if err := ca.store.UpdateChurnScores(ctx, agentDBID, scores); err != nil {
+		return fmt.Errorf("refresh churn risk: %w", err)
+	}
+	ca.logger.Debug("churn risk refreshed",
+		zap.String("agent_db_id", agentDBID.String()),
+		zap.Int("customers", len(scores)),
+	)
 	return nil
 }
 
-// GetCohortAnalysis returns monthly cohort analysis for an agent.
-// Each cohort includes new customers and retained customers (those still active).
-func (ca *CustomerAnalytics) GetCohortAnalysis(ctx context.Context, agentDBID uuid.UUID) ([]Cohort, error) {
-	storeCohorts, err := ca.store.GetCustomerCohorts(ctx, agentDBID)
+// GetCohortAnalysis builds a retention matrix for the last periods weeks or
+// months (granularity "weekly" or "monthly") from request history. A
+// customer belongs to the cohort of their first request and counts as
+// retained in any later period with at least one request. With
+// includeRevenue, each cell also carries verified revenue and its ratio to
+// the cohort's first-period revenue (net dollar retention).
+func (ca *CustomerAnalytics) GetCohortAnalysis(ctx context.Context, agentDBID uuid.UUID, granularity string, periods int, includeRevenue bool) (*CohortReport, error) {
+	unit := "month"
+	if granularity == "weekly" {
+		unit = "week"
+	}
+
+	current := truncatePeriod(time.Now().UTC(), unit)
+	since := addPeriods(current, unit, -(periods - 1))
+
+	cells, err := ca.store.GetCohortActivity(ctx, agentDBID, unit, since)
 	if err != nil {
 		return nil, fmt.Errorf("get cohort analysis: %w", err)
 	}
+	report := buildCohortReport(cells, since, unit, periods, includeRevenue)
+	report.Granularity = granularity
+	return report, nil
+}
 
-	cohorts := make([]Cohort, len(storeCohorts))
-	for i, sc := range storeCohorts {
-		cohorts[i] = Cohort{
-			Month:             sc.Month,
-			NewCustomers:      sc.NewCustomers,
-			RetainedCustomers: sc.NewCustomers, // initial: all new are retained; enriched by churn later
+// buildCohortReport lays cells out as a matrix of periods cohorts starting at
+// since, the oldest first. The last period of each row is the current one and
+// is marked partial; averages only count complete periods.
+func buildCohortReport(cells []store.CohortActivity, since time.Time, unit string, periods int, includeRevenue bool) *CohortReport {
+	// Build one dense row per cohort, oldest first.
+	rows := make([]Cohort, periods)
+	revenue := make([][]float64, periods)
+	for i := range rows {
+		start := addPeriods(since, unit, i)
+		n := periods - i
+		rows[i] = Cohort{
+			Cohort:    periodLabel(start, unit),
+			StartDate: start,
+			Periods:   make([]CohortCell, n),
 		}
+		for k := range rows[i].Periods {
+			rows[i].Periods[k].Offset = k
+		}
+		rows[i].Periods[n-1].Partial = true
+		revenue[i] = make([]float64, n)
 	}
 
-	if cohorts == nil {
-		cohorts = []Cohort{}
+	for _, c := range cells {
+		i := periodsBetween(since, c.Cohort, unit)
+		k := periodsBetween(c.Cohort, c.Period, unit)
+		if i < 0 || i >= periods || k < 0 || k >= len(rows[i].Periods) {
+			continue
+		}
+		rows[i].Periods[k].ActiveCustomers = c.Customers
+		revenue[i][k] = c.Revenue
 	}
 
-	return cohorts, nil
+	avgActive := make([]int, periods)
+	avgSize := make([]int, periods)
+	avgCohorts := make([]int, periods)
+	avgRev := make([]float64, periods)
+	avgBase := make([]float64, periods)
+
+	cohorts := make([]Cohort, 0, periods)
+	for i := range rows {
+		row := rows[i]
+		row.Size = row.Periods[0].ActiveCustomers
+		if row.Size == 0 {
+			continue
+		}
+		base := revenue[i][0]
+		for k := range row.Periods {
+			cell := &row.Periods[k]
+			cell.RetentionRate = float64(cell.ActiveCustomers) / float64(row.Size)
+			if includeRevenue {
+				amount := revenue[i][k]
+				cell.Revenue = &amount
+				if base > 0 {
+					ratio := amount / base
+					cell.RevenueRetention = &ratio
+					if !cell.Partial {
+						row.NetRevenueRetention = &ratio
+					}
+				}
+			}
+			if !cell.Partial {
+				avgActive[k] += cell.ActiveCustomers
+				avgSize[k] += row.Size
+				avgCohorts[k]++
+				if base > 0 {
+					avgRev[k] += revenue[i][k]
+					avgBase[k] += base
+				}
+			}
+		}
+		cohorts = append(cohorts, row)
+	}
+
+	average := []CohortAverage{}
+	for k := 0; k < periods; k++ {
+		if avgCohorts[k] == 0 {
+			continue
+		}
+		avg := CohortAverage{
+			Offset:        k,
+			Cohorts:       avgCohorts[k],
+			RetentionRate: float64(avgActive[k]) / float64(avgSize[k]),
+		}
+		if includeRevenue && avgBase[k] > 0 {
+			ratio := avgRev[k] / avgBase[k]
+			avg.RevenueRetention = &ratio
+		}
+		average = append(average, avg)
+	}
+
+	return &CohortReport{
+		Periods: periods,
+		Cohorts: cohorts,
+		Average: average,
+	}
+}
+
+// truncatePeriod truncates t (UTC) to the start of its ISO week or month,
+// matching Postgres date_trunc.
+func truncatePeriod(t time.Time, unit string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if unit == "week" {
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	return day.AddDate(0, 0, 1-day.Day())
+}
+
+func addPeriods(t time.Time, unit string, n int) time.Time {
+	if unit == "week" {
+		return t.AddDate(0, 0, 7*n)
+	}
+	return t.AddDate(0, n, 0)
+}
+
+// periodsBetween returns the number of whole periods from a to b, both
+// already truncated to unit.
+func periodsBetween(a, b time.Time, unit string) int {
+	if unit == "week" {
+		return int(b.Sub(a).Hours()) / (7 * 24)
+	}
+	return (b.Year()-a.Year())*12 + int(b.Month()) - int(a.Month())
+}
+
+// periodLabel formats a period start like the revenue report: YYYY-MM or
+// ISO YYYY-WW.
+func periodLabel(t time.Time, unit string) string {
+	if unit == "week" {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%02d", year, week)
+	}
+	return t.Format("2006-01")
 }
@@ -0,0 +1,258 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestTruncatePeriod(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        time.Time
+		wantWeek  time.Time
+		wantMonth time.Time
+	}{
+		{"midweek", time.Date(2026, 10, 14, 15, 4, 5, 0, time.UTC), date(2026, 10, 12), date(2026, 10, 1)},
+		{"monday midnight", date(2026, 10, 12), date(2026, 10, 12), date(2026, 10, 1)},
+		{"sunday night", time.Date(2026, 10, 18, 23, 59, 59, 0, time.UTC), date(2026, 10, 12), date(2026, 10, 1)},
+		{"week starts in previous year", date(2026, 1, 1), date(2025, 12, 29), date(2026, 1, 1)},
+		{"leap day", date(2024, 2, 29), date(2024, 2, 26), date(2024, 2, 1)},
+		{"first of month", time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC), date(2026, 2, 23), date(2026, 3, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncatePeriod(tt.in, "week"); !got.Equal(tt.wantWeek) {
+				t.Errorf("expected week %s, got %s", tt.wantWeek, got)
+			}
+			if got := truncatePeriod(tt.in, "month"); !got.Equal(tt.wantMonth) {
+				t.Errorf("expected month %s, got %s", tt.wantMonth, got)
+			}
+		})
+	}
+}
+
+func TestPeriodsBetween(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b time.Time
+		unit string
+		want int
+	}{
+		{"same week", date(2026, 10, 12), date(2026, 10, 12), "week", 0},
+		{"weeks across year", date(2025, 12, 29), date(2026, 1, 12), "week", 2},
+		{"weeks backwards", date(2026, 10, 12), date(2026, 9, 28), "week", -2},
+		{"same month", date(2026, 10, 1), date(2026, 10, 1), "month", 0},
+		{"months across year", date(2025, 11, 1), date(2026, 2, 1), "month", 3},
+		{"months backwards", date(2026, 2, 1), date(2025, 12, 1), "month", -2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := periodsBetween(tt.a, tt.b, tt.unit); got != tt.want {
+				t.Errorf("expected %d periods, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPeriodLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		in   time.Time
+		unit string
+		want string
+	}{
+		{"iso week", date(2026, 10, 12), "week", "2026-42"},
+		{"iso week of next year", date(2025, 12, 29), "week", "2026-01"},
+		{"single digit week", date(2026, 3, 2), "week", "2026-10"},
+		{"month", date(2026, 1, 1), "month", "2026-01"},
+		{"december", date(2025, 12, 1), "month", "2025-12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := periodLabel(tt.in, tt.unit); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// ratioEqual reports whether an optional ratio is nil when wantNil, or else
+// equal to want.
+func ratioEqual(got *float64, want float64, wantNil bool) bool {
+	if wantNil || got == nil {
+		return wantNil == (got == nil)
+	}
+	return math.Abs(*got-want) < 1e-9
+}
+
+func TestBuildCohortReport(t *testing.T) {
+	aug, sep, oct := date(2026, 8, 1), date(2026, 9, 1), date(2026, 10, 1)
+	monthly := []store.CohortActivity{
+		{Cohort: aug, Period: aug, Customers: 10, Revenue: 100},
+		{Cohort: aug, Period: sep, Customers: 5, Revenue: 80},
+		{Cohort: aug, Period: oct, Customers: 4, Revenue: 120},
+		// September's cohort paid nothing in its first month.
+		{Cohort: sep, Period: sep, Customers: 4},
+		{Cohort: sep, Period: oct, Customers: 2, Revenue: 10},
+		{Cohort: oct, Period: oct, Customers: 3, Revenue: 30},
+		// Outside the window: ignored.
+		{Cohort: date(2026, 7, 1), Period: aug, Customers: 9, Revenue: 50},
+	}
+
+	type row struct {
+		label     string
+		size      int
+		retention []float64
+		// revenueRetention per offset; math.NaN() means nil.
+		revenueRetention []float64
+		nrr              float64
+		nrrNil           bool
+	}
+	type avg struct {
+		offset           int
+		cohorts          int
+		retention        float64
+		revenueRetention float64
+		revenueNil       bool
+	}
+
+	tests := []struct {
+		name           string
+		cells          []store.CohortActivity
+		since          time.Time
+		unit           string
+		periods        int
+		includeRevenue bool
+		rows           []row
+		average        []avg
+	}{
+		{
+			name:           "monthly with revenue",
+			cells:          monthly,
+			since:          aug,
+			unit:           "month",
+			periods:        3,
+			includeRevenue: true,
+			rows: []row{
+				{label: "2026-08", size: 10, retention: []float64{1, 0.5, 0.4}, revenueRetention: []float64{1, 0.8, 1.2}, nrr: 0.8},
+				{label: "2026-09", size: 4, retention: []float64{1, 0.5}, revenueRetention: []float64{math.NaN(), math.NaN()}, nrrNil: true},
+				{label: "2026-10", size: 3, retention: []float64{1}, revenueRetention: []float64{1}, nrrNil: true},
+			},
+			average: []avg{
+				// October's cohort is still partial; September's has no
+				// first-period revenue, so it is left out of revenue retention.
+				{offset: 0, cohorts: 2, retention: 1, revenueRetention: 1},
+				{offset: 1, cohorts: 1, retention: 0.5, revenueRetention: 0.8},
+			},
+		},
+		{
+			name:    "monthly without revenue",
+			cells:   monthly,
+			since:   aug,
+			unit:    "month",
+			periods: 3,
+			rows: []row{
+				{label: "2026-08", size: 10, retention: []float64{1, 0.5, 0.4}, revenueRetention: []float64{math.NaN(), math.NaN(), math.NaN()}, nrrNil: true},
+				{label: "2026-09", size: 4, retention: []float64{1, 0.5}, revenueRetention: []float64{math.NaN(), math.NaN()}, nrrNil: true},
+				{label: "2026-10", size: 3, retention: []float64{1}, revenueRetention: []float64{math.NaN()}, nrrNil: true},
+			},
+			average: []avg{
+				{offset: 0, cohorts: 2, retention: 1, revenueNil: true},
+				{offset: 1, cohorts: 1, retention: 0.5, revenueNil: true},
+			},
+		},
+		{
+			name: "weekly skips empty cohorts",
+			cells: []store.CohortActivity{
+				{Cohort: date(2026, 9, 28), Period: date(2026, 9, 28), Customers: 4, Revenue: 40},
+				{Cohort: date(2026, 9, 28), Period: date(2026, 10, 5), Customers: 3, Revenue: 10},
+				{Cohort: date(2026, 9, 28), Period: date(2026, 10, 12), Customers: 1, Revenue: 5},
+			},
+			since:          date(2026, 9, 28),
+			unit:           "week",
+			periods:        3,
+			includeRevenue: true,
+			rows: []row{
+				{label: "2026-40", size: 4, retention: []float64{1, 0.75, 0.25}, revenueRetention: []float64{1, 0.25, 0.125}, nrr: 0.25},
+			},
+			average: []avg{
+				{offset: 0, cohorts: 1, retention: 1, revenueRetention: 1},
+				{offset: 1, cohorts: 1, retention: 0.75, revenueRetention: 0.25},
+			},
+		},
+		{
+			name:    "no activity",
+			since:   aug,
+			unit:    "month",
+			periods: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := buildCohortReport(tt.cells, tt.since, tt.unit, tt.periods, tt.includeRevenue)
+			if r.Periods != tt.periods {
+				t.Errorf("expected %d periods, got %d", tt.periods, r.Periods)
+			}
+			if len(r.Cohorts) != len(tt.rows) {
+				t.Fatalf("expected %d cohorts, got %d", len(tt.rows), len(r.Cohorts))
+			}
+			for i, want := range tt.rows {
+				got := r.Cohorts[i]
+				if got.Cohort != want.label || got.Size != want.size {
+					t.Errorf("cohort %d: expected %s of %d, got %s of %d", i, want.label, want.size, got.Cohort, got.Size)
+				}
+				if len(got.Periods) != len(want.retention) {
+					t.Fatalf("cohort %s: expected %d periods, got %d", want.label, len(want.retention), len(got.Periods))
+				}
+				for k, cell := range got.Periods {
+					if cell.Offset != k {
+						t.Errorf("cohort %s: expected offset %d, got %d", want.label, k, cell.Offset)
+					}
+					if partial := k == len(got.Periods)-1; cell.Partial != partial {
+						t.Errorf("cohort %s offset %d: expected partial %v, got %v", want.label, k, partial, cell.Partial)
+					}
+					if math.Abs(cell.RetentionRate-want.retention[k]) > 1e-9 {
+						t.Errorf("cohort %s offset %d: expected retention %v, got %v", want.label, k, want.retention[k], cell.RetentionRate)
+					}
+					if (cell.Revenue != nil) != tt.includeRevenue {
+						t.Errorf("cohort %s offset %d: expected revenue set %v, got %v", want.label, k, tt.includeRevenue, cell.Revenue)
+					}
+					rr := want.revenueRetention[k]
+					if !ratioEqual(cell.RevenueRetention, rr, math.IsNaN(rr)) {
+						t.Errorf("cohort %s offset %d: expected revenue retention %v, got %v", want.label, k, rr, cell.RevenueRetention)
+					}
+				}
+				if !ratioEqual(got.NetRevenueRetention, want.nrr, want.nrrNil) {
+					t.Errorf("cohort %s: expected net revenue retention %v (nil %v), got %v", want.label, want.nrr, want.nrrNil, got.NetRevenueRetention)
+				}
+			}
+
+			if len(r.Average) != len(tt.average) {
+				t.Fatalf("expected %d averages, got %d", len(tt.average), len(r.Average))
+			}
+			for i, want := range tt.average {
+				got := r.Average[i]
+				if got.Offset != want.offset || got.Cohorts != want.cohorts {
+					t.Errorf("average %d: expected offset %d over %d cohorts, got offset %d over %d", i, want.offset, want.cohorts, got.Offset, got.Cohorts)
+				}
+				if math.Abs(got.RetentionRate-want.retention) > 1e-9 {
+					t.Errorf("average %d: expected retention %v, got %v", i, want.retention, got.RetentionRate)
+				}
+				if !ratioEqual(got.RevenueRetention, want.revenueRetention, want.revenueNil) {
+					t.Errorf("average %d: expected revenue retention %v (nil %v), got %v", i, want.revenueRetention, want.revenueNil, got.RevenueRetention)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// Deployment comparison metric names.
+const (
+	ReleaseAvgResponseMs = "avg_response_ms"
+	ReleaseP95ResponseMs = "p95_response_ms"
+	ReleaseErrorRate     = "error_rate"
+	ReleaseConversion    = "conversion_rate"
+	ReleaseRevenuePerReq = "revenue_per_request"
+)
+
+// releaseSignificance is the two-sided p-value below which a change is
+// reported as significant.
+const releaseSignificance = 0.05
+
+// minReleaseSamples is the number of requests each window needs before any
+// change is called significant; the normal approximations below are
+// unreliable on fewer.
+const minReleaseSamples = 30
+
+// DeploymentMarker annotates a time series with a deployment. Bucket is the
+// series bucket (day or hour label) the deployment falls in.
+type DeploymentMarker struct {
+	ID         uuid.UUID `json:"id"`
+	Version    string    `json:"version"`
+	Commit     *string   `json:"commit,omitempty"`
+	DeployedAt time.Time `json:"deployed_at"`
+	Bucket     string    `json:"bucket"`
+}
+
+// NewDeploymentMarker builds a marker for d placed in bucket.
+func NewDeploymentMarker(d store.Deployment, bucket string) DeploymentMarker {
+	return DeploymentMarker{
+		ID:         d.ID,
+		Version:    d.Version,
+		Commit:     d.CommitSHA,
+		DeployedAt: d.DeployedAt,
+		Bucket:     bucket,
+	}
+}
+
+// ReleaseWindow is one side of a deployment comparison.
+type ReleaseWindow struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Requests int64     `json:"requests"`
+}
+
+// ReleaseMetric compares a metric before and after a deployment. PValue is
+// omitted for metrics without a significance test (percentiles). Direction
+// is improved or regressed for significant changes and unchanged otherwise.
+type ReleaseMetric struct {
+	Name           string   `json:"name"`
+	HigherIsBetter bool     `json:"higher_is_better"`
+	Before         float64  `json:"before"`
+	After          float64  `json:"after"`
+	Change         float64  `json:"change"`
+	ChangePct      *float64 `json:"change_pct,omitempty"`
+	PValue         *float64 `json:"p_value,omitempty"`
+	Significant    bool     `json:"significant"`
+	Direction      string   `json:"direction"`
+}
+
+// DeploymentComparison is the before/after report for one deployment.
+type DeploymentComparison struct {
+	Deployment  store.Deployment `json:"deployment"`
+	WindowHours int              `json:"window_hours"`
+	Before      ReleaseWindow    `json:"before"`
+	After       ReleaseWindow    `json:"after"`
+	// Insufficient is set when either window has fewer than the minimum
+	// number of requests; no change is then marked significant.
+	Insufficient bool            `json:"insufficient_data"`
+	Metrics      []ReleaseMetric `json:"metrics"`
+}
+
+// CompareDeployment compares the windowHours before a deployment with the
+// windowHours after it. Each window is cut short at the neighbouring
+// deployment (and the after window at now) so that changes are attributed to
+// this release only.
+func (pa *PerformanceAnalytics) CompareDeployment(ctx context.Context, d *store.Deployment, windowHours int) (*DeploymentComparison, error) {
+	prev, next, err := pa.store.GetNeighborDeploymentTimes(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	window := time.Duration(windowHours) * time.Hour
+	beforeFrom := d.DeployedAt.Add(-window)
+	if prev != nil && prev.After(beforeFrom) {
+		beforeFrom = *prev
+	}
+	afterTo := d.DeployedAt.Add(window)
+	if next != nil && next.Before(afterTo) {
+		afterTo = *next
+	}
+	if now := time.Now().UTC(); now.Before(afterTo) {
+		afterTo = now
+	}
+	if afterTo.Before(d.DeployedAt) {
+		afterTo = d.DeployedAt
+	}
+
+	before, err := pa.store.GetReleaseWindowMetrics(ctx, d.AgentID, beforeFrom, d.DeployedAt)
+	if err != nil {
+		return nil, fmt.Errorf("get before window: %w", err)
+	}
+	after, err := pa.store.GetReleaseWindowMetrics(ctx, d.AgentID, d.DeployedAt, afterTo)
+	if err != nil {
+		return nil, fmt.Errorf("get after window: %w", err)
+	}
+
+	result := &DeploymentComparison{
+		Deployment:   *d,
+		WindowHours:  windowHours,
+		Before:       ReleaseWindow{From: beforeFrom, To: d.DeployedAt, Requests: before.Requests},
+		After:        ReleaseWindow{From: d.DeployedAt, To: afterTo, Requests: after.Requests},
+		Insufficient: before.Requests < minReleaseSamples || after.Requests < minReleaseSamples,
+	}
+
+	b, a := before, after
+	result.Metrics = []ReleaseMetric{
+		releaseMetric(ReleaseAvgResponseMs, false, b.MeanResponseMs, a.MeanResponseMs,
+			welchPValue(b.MeanResponseMs, b.StddevMs*b.StddevMs, b.LatencySamples,
+				a.MeanResponseMs, a.StddevMs*a.StddevMs, a.LatencySamples)),
+		releaseMetric(ReleaseP95ResponseMs, false, b.P95ResponseMs, a.P95ResponseMs, nil),
+		releaseMetric(ReleaseErrorRate, false, ratio(b.Errors, b.Requests), ratio(a.Errors, a.Requests),
+			twoProportionPValue(b.Errors, b.Requests, a.Errors, a.Requests)),
+		releaseMetric(ReleaseConversion, true, ratio(b.PayingCustomers, b.Customers), ratio(a.PayingCustomers, a.Customers),
+			twoProportionPValue(b.PayingCustomers, b.Customers, a.PayingCustomers, a.Customers)),
+		releaseMetric(ReleaseRevenuePerReq, true, perUnit(b.Revenue, b.Requests), perUnit(a.Revenue, a.Requests),
+			welchPValue(perUnit(b.Revenue, b.Requests), sampleVariance(b.Revenue, b.RevenueSumSq, b.Requests), b.Requests,
+				perUnit(a.Revenue, a.Requests), sampleVariance(a.Revenue, a.RevenueSumSq, a.Requests), a.Requests)),
+	}
+	if result.Insufficient {
+		for i := range result.Metrics {
+			result.Metrics[i].Significant = false
+			result.Metrics[i].Direction = "unchanged"
+		}
+	}
+
+	return result, nil
+}
+
+func releaseMetric(name string, higherIsBetter bool, before, after float64, p *float64) ReleaseMetric {
+	m := ReleaseMetric{
+		Name:           name,
+		HigherIsBetter: higherIsBetter,
+		Before:         round4(before),
+		After:          round4(after),
+		Change:         round4(after - before),
+		Direction:      "unchanged",
+	}
+	if before != 0 {
+		pct := math.Round((after-before)/before*10000) / 100
+		m.ChangePct = &pct
+	}
+	if p != nil {
+		pv := round4(*p)
+		m.PValue = &pv
+		if *p < releaseSignificance && after != before {
+			m.Significant = true
+			if (after > before) == higherIsBetter {
+				m.Direction = "improved"
+			} else {
+				m.Direction = "regressed"
+			}
+		}
+	}
+	return m
+}
+
+// welchPValue returns the two-sided p-value of Welch's t-test for a
+// difference in means, using the normal approximation (adequate at the
+// sample sizes required for significance). It returns nil when either side
+// has fewer than two samples.
+func welchPValue(mean1, var1 float64, n1 int64, mean2, var2 float64, n2 int64) *float64 {
+	if n1 < 2 || n2 < 2 {
+		return nil
+	}
+	se := math.Sqrt(var1/float64(n1) + var2/float64(n2))
+	if se == 0 {
+		p := 1.0
+		if mean1 != mean2 {
+			p = 0
+		}
+		return &p
+	}
+	p := twoSidedP((mean2 - mean1) / se)
+	return &p
+}
+
+// twoProportionPValue returns the two-sided p-value of a pooled two-proportion
+// z-test. It returns nil when either side has no trials.
+func twoProportionPValue(x1, n1, x2, n2 int64) *float64 {
+	if n1 == 0 || n2 == 0 {
+		return nil
+	}
+	pooled := float64(x1+x2) / float64(n1+n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		p := 1.0
+		return &p
+	}
+	p := twoSidedP((float64(x2)/float64(n2) - float64(x1)/float64(n1)) / se)
+	return &p
+}
+
+func twoSidedP(z float64) float64 {
+	return math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// sampleVariance derives the sample variance from a sum and sum of squares.
+func sampleVariance(sum, sumSq float64, n int64) float64 {
+	if n < 2 {
+		return 0
+	}
+	mean := sum / float64(n)
+	v := (sumSq - float64(n)*mean*mean) / float64(n-1)
+	if v < 0 {
+		return 0
+	}
+	return v
+}
+
+func ratio(x, n int64) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(x) / float64(n)
+}
+
+func perUnit(x float64, n int64) float64 {
+	if n == 0 {
+		return 0
+	}
+	return x / float64(n)
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
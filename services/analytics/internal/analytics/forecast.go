@@ -0,0 +1,448 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// forecastHistoryDays is how much daily history the forecast is fitted on,
+// and forecastHistoryShown how much of it is returned for charting.
+const (
+	forecastHistoryDays  = 180
+	forecastHistoryShown = 90
+)
+
+// forecastSeason is the seasonal period in days (weekly pattern). Seasonal
+// smoothing needs at least two full seasons of history.
+const forecastSeason = 7
+
+// forecastDamping damps the trend so long horizons level off instead of
+// extrapolating a short-term slope indefinitely.
+const forecastDamping = 0.98
+
+// forecastMaxTools is how many tools get their own forecast; the rest are
+// combined under "other".
+const forecastMaxTools = 10
+
+// Forecast horizons (days) reported as totals.
+var forecastHorizons = []int{30, 60, 90}
+
+// Two-sided normal quantiles for the confidence bands.
+const (
+	z80 = 1.2816
+	z95 = 1.9600
+)
+
+// Grid searched for the smoothing parameters; the combination with the
+// lowest one-step-ahead squared error is used.
+var (
+	forecastAlphas = []float64{0.05, 0.1, 0.2, 0.3, 0.5, 0.7}
+	forecastBetas  = []float64{0, 0.01, 0.05, 0.1, 0.2}
+	forecastGammas = []float64{0.05, 0.1, 0.2, 0.3, 0.5}
+)
+
+// Forecast methods, by how much history was available.
+const (
+	ForecastHoltWinters = "holt_winters" // damped trend + weekly seasonality
+	ForecastHolt        = "holt"         // damped trend, no seasonality
+	ForecastMean        = "mean"         // too little history for a trend
+)
+
+// RevenueHistoryPoint is one day of verified revenue.
+type RevenueHistoryPoint struct {
+	Date    string  `json:"date"`
+	Revenue float64 `json:"revenue"`
+}
+
+// RevenueForecastPoint is the projected revenue for one day with 80% and
+// 95% prediction intervals.
+type RevenueForecastPoint struct {
+	Date    string  `json:"date"`
+	Revenue float64 `json:"revenue"`
+	Lower80 float64 `json:"lower_80"`
+	Upper80 float64 `json:"upper_80"`
+	Lower95 float64 `json:"lower_95"`
+	Upper95 float64 `json:"upper_95"`
+}
+
+// RevenueHorizon is projected revenue summed over the next Days days. The
+// bounds sum the daily 95% bounds, which is conservative.
+type RevenueHorizon struct {
+	Days    int     `json:"days"`
+	Revenue float64 `json:"revenue"`
+	Lower95 float64 `json:"lower_95"`
+	Upper95 float64 `json:"upper_95"`
+}
+
+// ForecastParams are the fitted smoothing parameters.
+type ForecastParams struct {
+	Alpha   float64 `json:"alpha"`
+	Beta    float64 `json:"beta"`
+	Gamma   float64 `json:"gamma"`
+	Damping float64 `json:"damping"`
+	Season  int     `json:"season"`
+}
+
+// ToolForecast is one tool's own projection and its share of the projected
+// revenue of all tools.
+type ToolForecast struct {
+	Tool       string           `json:"tool"`
+	Last28Days float64          `json:"last_28_days"`
+	GrowthRate *float64         `json:"growth_rate,omitempty"`
+	Method     string           `json:"method"`
+	Horizons   []RevenueHorizon `json:"horizons"`
+	Share      float64          `json:"share"`
+}
+
+// RevenueForecast projects daily verified revenue forward.
+type RevenueForecast struct {
+	Timezone    string                 `json:"timezone"`
+	Method      string                 `json:"method"`
+	Params      ForecastParams         `json:"params"`
+	HistoryDays int                    `json:"history_days"`
+	History     []RevenueHistoryPoint  `json:"history"`
+	Forecast    []RevenueForecastPoint `json:"forecast"`
+	Horizons    []RevenueHorizon       `json:"horizons"`
+	// MonthlyRunRate is the last 28 days' average daily revenue times the
+	// average month length; AnnualRunRate the same times 365.
+	MonthlyRunRate float64 `json:"monthly_run_rate"`
+	AnnualRunRate  float64 `json:"annual_run_rate"`
+	// GrowthRate compares the last 28 days with the 28 days before; omitted
+	// when the earlier window had no revenue.
+	GrowthRate *float64       `json:"growth_rate,omitempty"`
+	ByTool     []ToolForecast `json:"by_tool"`
+}
+
+// GetRevenueForecast fits the agent's daily verified revenue (complete days
+// in loc) and projects it horizon days ahead, starting today. Each of the
+// top tools is forecast separately; a tool's share is its projected
+// revenue over the horizon relative to the sum of all tool projections.
+func (ra *RevenueAnalytics) GetRevenueForecast(ctx context.Context, agentDBID uuid.UUID, horizon int, loc *time.Location) (*RevenueForecast, error) {
+	dates, byTool, err := ra.store.GetDailyRevenueByTool(ctx, agentDBID, forecastHistoryDays, loc)
+	if err != nil {
+		return nil, fmt.Errorf("get revenue history: %w", err)
+	}
+
+	total := make([]float64, len(dates))
+	for _, series := range byTool {
+		for i, v := range series {
+			total[i] += v
+		}
+	}
+
+	model := fitForecastModel(total)
+	last, _ := time.ParseInLocation("2006-01-02", dates[len(dates)-1], loc)
+
+	result := &RevenueForecast{
+		Timezone:    loc.String(),
+		Method:      model.method,
+		Params:      model.params(),
+		HistoryDays: model.n,
+		Forecast:    make([]RevenueForecastPoint, horizon),
+	}
+
+	shown := len(dates) - forecastHistoryShown
+	for i := shown; i < len(dates); i++ {
+		result.History = append(result.History, RevenueHistoryPoint{Date: dates[i], Revenue: round4(total[i])})
+	}
+
+	for h := 1; h <= horizon; h++ {
+		point, sd := model.predict(h)
+		result.Forecast[h-1] = RevenueForecastPoint{
+			Date:    last.AddDate(0, 0, h).Format("2006-01-02"),
+			Revenue: round4(math.Max(0, point)),
+			Lower80: round4(math.Max(0, point-z80*sd)),
+			Upper80: round4(math.Max(0, point+z80*sd)),
+			Lower95: round4(math.Max(0, point-z95*sd)),
+			Upper95: round4(math.Max(0, point+z95*sd)),
+		}
+	}
+	result.Horizons = horizonTotals(result.Forecast)
+
+	recent, previous := windowSums(total, 28)
+	result.MonthlyRunRate = round4(recent / 28 * 30.4375)
+	result.AnnualRunRate = round4(recent / 28 * 365)
+	result.GrowthRate = growthRate(recent, previous)
+
+	result.ByTool = ra.forecastTools(byTool, horizon)
+
+	ra.logger.Debug("revenue forecast generated",
+		zap.String("agent_db_id", agentDBID.String()),
+		zap.String("method", model.method),
+		zap.Int("horizon", horizon),
+	)
+
+	return result, nil
+}
+
+// forecastTools forecasts the top tools by recent revenue (the rest
+// combined as "other") and computes each one's share of the projection.
+func (ra *RevenueAnalytics) forecastTools(byTool map[string][]float64, horizon int) []ToolForecast {
+	type toolSeries struct {
+		tool   string
+		series []float64
+		recent float64
+	}
+	tools := make([]toolSeries, 0, len(byTool))
+	for tool, series := range byTool {
+		recent, _ := windowSums(series, 28)
+		tools = append(tools, toolSeries{tool, series, recent})
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		if tools[i].recent != tools[j].recent {
+			return tools[i].recent > tools[j].recent
+		}
+		return tools[i].tool < tools[j].tool
+	})
+	if len(tools) > forecastMaxTools {
+		other := toolSeries{tool: "other", series: make([]float64, len(tools[0].series))}
+		for _, t := range tools[forecastMaxTools-1:] {
+			for i, v := range t.series {
+				other.series[i] += v
+			}
+			other.recent += t.recent
+		}
+		tools = append(tools[:forecastMaxTools-1], other)
+	}
+
+	forecasts := make([]ToolForecast, 0, len(tools))
+	var projectedTotal float64
+	for _, t := range tools {
+		model := fitForecastModel(t.series)
+		points := make([]RevenueForecastPoint, horizon)
+		for h := 1; h <= horizon; h++ {
+			point, sd := model.predict(h)
+			points[h-1] = RevenueForecastPoint{
+				Revenue: math.Max(0, point),
+				Lower95: math.Max(0, point-z95*sd),
+				Upper95: math.Max(0, point+z95*sd),
+			}
+		}
+		horizons := horizonTotals(points)
+		recent, previous := windowSums(t.series, 28)
+		forecasts = append(forecasts, ToolForecast{
+			Tool:       t.tool,
+			Last28Days: round4(recent),
+			GrowthRate: growthRate(recent, previous),
+			Method:     model.method,
+			Horizons:   horizons,
+		})
+		projectedTotal += horizons[len(horizons)-1].Revenue
+	}
+
+	for i := range forecasts {
+		if projectedTotal > 0 {
+			h := forecasts[i].Horizons
+			forecasts[i].Share = round4(h[len(h)-1].Revenue / projectedTotal)
+		}
+	}
+	return forecasts
+}
+
+// horizonTotals sums daily points over each reporting horizon within the
+// forecast, always ending with the full forecast length.
+func horizonTotals(points []RevenueForecastPoint) []RevenueHorizon {
+	var horizons []RevenueHorizon
+	for _, days := range forecastHorizons {
+		if days < len(points) {
+			horizons = append(horizons, sumHorizon(points[:days]))
+		}
+	}
+	return append(horizons, sumHorizon(points))
+}
+
+func sumHorizon(points []RevenueForecastPoint) RevenueHorizon {
+	h := RevenueHorizon{Days: len(points)}
+	for _, p := range points {
+		h.Revenue += p.Revenue
+		h.Lower95 += p.Lower95
+		h.Upper95 += p.Upper95
+	}
+	h.Revenue, h.Lower95, h.Upper95 = round4(h.Revenue), round4(h.Lower95), round4(h.Upper95)
+	return h
+}
+
+// windowSums returns the sum of the last n values and of the n before them.
+func windowSums(series []float64, n int) (recent, previous float64) {
+	for i := len(series) - 1; i >= 0 && i >= len(series)-2*n; i-- {
+		if i >= len(series)-n {
+			recent += series[i]
+		} else {
+			previous += series[i]
+		}
+	}
+	return recent, previous
+}
+
+func growthRate(recent, previous float64) *float64 {
+	if previous <= 0 {
+		return nil
+	}
+	g := round4((recent - previous) / previous)
+	return &g
+}
+
+// ---------- Holt-Winters ----------
+
+// forecastModel is a fitted additive exponential smoothing model.
+type forecastModel struct {
+	method             string
+	n                  int
+	alpha, beta, gamma float64
+	level, trend       float64
+	seasonal           []float64 // nil without seasonality
+	sigma              float64   // one-step-ahead residual standard deviation
+}
+
+func (m *forecastModel) params() ForecastParams {
+	p := ForecastParams{Alpha: m.alpha, Beta: m.beta, Gamma: m.gamma}
+	if m.method != ForecastMean {
+		p.Damping = forecastDamping
+	}
+	if m.seasonal != nil {
+		p.Season = len(m.seasonal)
+	}
+	return p
+}
+
+// fitForecastModel chooses the richest model the history supports. Leading
+// days without revenue (before the agent started earning) are ignored.
+func fitForecastModel(series []float64) *forecastModel {
+	start := 0
+	for start < len(series) && series[start] == 0 {
+		start++
+	}
+	y := series[start:]
+
+	switch {
+	case len(y) >= 2*forecastSeason:
+		return gridFit(y, forecastSeason, forecastGammas)
+	case len(y) >= 3:
+		return gridFit(y, 0, []float64{0})
+	default:
+		m := &forecastModel{method: ForecastMean, n: len(y)}
+		if len(y) > 0 {
+			var sum, sq float64
+			for _, v := range y {
+				sum += v
+			}
+			m.level = sum / float64(len(y))
+			for _, v := range y {
+				sq += (v - m.level) * (v - m.level)
+			}
+			m.sigma = math.Sqrt(sq / float64(len(y)))
+		}
+		return m
+	}
+}
+
+func gridFit(y []float64, season int, gammas []float64) *forecastModel {
+	var best *forecastModel
+	bestSSE := math.Inf(1)
+	for _, a := range forecastAlphas {
+		for _, b := range forecastBetas {
+			for _, g := range gammas {
+				m, sse := smooth(y, season, a, b, g)
+				if sse < bestSSE {
+					best, bestSSE = m, sse
+				}
+			}
+		}
+	}
+
+	params := 2 // alpha, beta
+	if season > 0 {
+		params += 1 + season
+	}
+	dof := len(y) - params
+	if dof < 1 {
+		dof = 1
+	}
+	best.sigma = math.Sqrt(bestSSE / float64(dof))
+	return best
+}
+
+// smooth runs damped additive Holt(-Winters) over y and returns the final
+// state with the sum of squared one-step-ahead errors. season 0 disables
+// seasonality.
+func smooth(y []float64, season int, alpha, beta, gamma float64) (*forecastModel, float64) {
+	m := &forecastModel{method: ForecastHolt, n: len(y), alpha: alpha, beta: beta}
+
+	if season > 0 {
+		m.method, m.gamma = ForecastHoltWinters, gamma
+		first, second := mean(y[:season]), mean(y[season:2*season])
+		m.level = first
+		m.trend = (second - first) / float64(season)
+		m.seasonal = make([]float64, season)
+		for i := range m.seasonal {
+			m.seasonal[i] = y[i] - first
+		}
+	} else {
+		m.level = y[0]
+		m.trend = y[1] - y[0]
+	}
+
+	var sse float64
+	for t, v := range y {
+		var s float64
+		if season > 0 {
+			s = m.seasonal[t%season]
+		}
+		forecast := m.level + forecastDamping*m.trend + s
+		sse += (v - forecast) * (v - forecast)
+
+		level := alpha*(v-s) + (1-alpha)*(m.level+forecastDamping*m.trend)
+		m.trend = beta*(level-m.level) + (1-beta)*forecastDamping*m.trend
+		if season > 0 {
+			m.seasonal[t%season] = gamma*(v-level) + (1-gamma)*s
+		}
+		m.level = level
+	}
+	return m, sse
+}
+
+// predict returns the h-step-ahead point forecast and its standard
+// deviation. The variance uses the additive Holt-Winters approximation
+//
+//	σ²·[1 + (h−1)(α² + αβh + β²h(2h−1)/6) + k·γ(2α+γ)],  k = ⌊(h−1)/m⌋
+//
+// which ignores damping and is therefore slightly wide at long horizons.
+func (m *forecastModel) predict(h int) (point, sd float64) {
+	if m.method == ForecastMean {
+		return m.level, m.sigma * math.Sqrt(1+1/math.Max(1, float64(m.n)))
+	}
+
+	var damp float64
+	phi := 1.0
+	for i := 0; i < h; i++ {
+		phi *= forecastDamping
+		damp += phi
+	}
+	point = m.level + damp*m.trend
+	if m.seasonal != nil {
+		point += m.seasonal[(m.n+h-1)%len(m.seasonal)]
+	}
+
+	hf := float64(h)
+	a, b, g := m.alpha, m.beta, m.gamma
+	variance := 1 + (hf-1)*(a*a+a*b*hf+b*b*hf*(2*hf-1)/6)
+	if m.seasonal != nil {
+		k := float64((h - 1) / len(m.seasonal))
+		variance += k * g * (2*a + g)
+	}
+	return point, m.sigma * math.Sqrt(variance)
+}
+
+func mean(v []float64) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += x
+	}
+	return sum / float64(len(v))
+}
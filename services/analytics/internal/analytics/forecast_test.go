@@ -0,0 +1,289 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+func repeat(v float64, n int) []float64 {
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = v
+	}
+	return s
+}
+
+// weekly returns n days of a fixed weekly pattern on top of base.
+func weekly(base float64, n int) []float64 {
+	pattern := []float64{0, 2, 4, 6, 4, 2, -18}
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = base + pattern[i%len(pattern)]
+	}
+	return s
+}
+
+func TestFitForecastModel_Method(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []float64
+		method string
+		n      int
+	}{
+		{"empty", nil, ForecastMean, 0},
+		{"all zero", repeat(0, 30), ForecastMean, 0},
+		{"two days", []float64{0, 0, 5, 7}, ForecastMean, 2},
+		{"three days", []float64{5, 6, 7}, ForecastHolt, 3},
+		{"under two seasons", repeat(3, 2*forecastSeason-1), ForecastHolt, 13},
+		{"two seasons", repeat(3, 2*forecastSeason), ForecastHoltWinters, 14},
+		{"leading zeros ignored", append(repeat(0, 20), repeat(4, 10)...), ForecastHolt, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := fitForecastModel(tt.series)
+			if m.method != tt.method {
+				t.Errorf("expected method %s, got %s", tt.method, m.method)
+			}
+			if m.n != tt.n {
+				t.Errorf("expected %d fitted days, got %d", tt.n, m.n)
+			}
+		})
+	}
+}
+
+func TestForecastModel_Predict(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []float64
+		// want returns the expected point forecast h days ahead.
+		want func(h int) float64
+		tol  float64
+	}{
+		{
+			name:   "constant",
+			series: repeat(10, 60),
+			want:   func(int) float64 { return 10 },
+			tol:    1e-6,
+		},
+		{
+			name:   "mean of short history",
+			series: []float64{4, 8},
+			want:   func(int) float64 { return 6 },
+			tol:    1e-9,
+		},
+		{
+			name:   "weekly pattern",
+			series: weekly(20, 84),
+			want:   func(h int) float64 { return weekly(20, 84+h)[84+h-1] },
+			tol:    0.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := fitForecastModel(tt.series)
+			for _, h := range []int{1, 3, 7, 14, 30} {
+				got, _ := m.predict(h)
+				if want := tt.want(h); math.Abs(got-want) > tt.tol {
+					t.Errorf("h=%d: expected %.4f, got %.4f", h, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestForecastModel_DampedTrend(t *testing.T) {
+	series := make([]float64, 2*forecastSeason-1)
+	for i := range series {
+		series[i] = 10 + float64(i)
+	}
+	m := fitForecastModel(series)
+	if m.method != ForecastHolt {
+		t.Fatalf("expected %s, got %s", ForecastHolt, m.method)
+	}
+
+	last := series[len(series)-1]
+	prev, _ := m.predict(1)
+	if prev <= last {
+		t.Errorf("expected growth past %.2f, got %.2f", last, prev)
+	}
+	prevStep := math.Inf(1)
+	for h := 2; h <= 90; h++ {
+		p, _ := m.predict(h)
+		step := p - prev
+		if step <= 0 || step >= prevStep {
+			t.Fatalf("h=%d: expected shrinking positive steps, got %.4f after %.4f", h, step, prevStep)
+		}
+		prev, prevStep = p, step
+	}
+	// An undamped trend would add about 90 over the horizon.
+	if p, _ := m.predict(90); p >= last+90 {
+		t.Errorf("expected damping below %.2f, got %.2f", last+90, p)
+	}
+}
+
+func TestForecastModel_IntervalWidens(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []float64
+	}{
+		{"holt", []float64{3, 9, 4, 12, 6, 10}},
+		{"holt-winters", append(weekly(20, 56), 31, 17, 25)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := fitForecastModel(tt.series)
+			_, prev := m.predict(1)
+			if prev <= 0 {
+				t.Fatalf("expected positive one-step sd, got %v", prev)
+			}
+			for h := 2; h <= 60; h++ {
+				_, sd := m.predict(h)
+				if sd < prev {
+					t.Fatalf("h=%d: expected sd >= %.4f, got %.4f", h, prev, sd)
+				}
+				prev = sd
+			}
+		})
+	}
+}
+
+func TestForecastModel_Params(t *testing.T) {
+	tests := []struct {
+		name    string
+		series  []float64
+		damping float64
+		season  int
+	}{
+		{"mean", []float64{1}, 0, 0},
+		{"holt", []float64{1, 2, 3}, forecastDamping, 0},
+		{"holt-winters", weekly(10, 21), forecastDamping, forecastSeason},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := fitForecastModel(tt.series).params()
+			if p.Damping != tt.damping || p.Season != tt.season {
+				t.Errorf("expected damping %v season %d, got %+v", tt.damping, tt.season, p)
+			}
+		})
+	}
+}
+
+func TestHorizonTotals(t *testing.T) {
+	point := RevenueForecastPoint{Revenue: 1, Lower95: 0.5, Upper95: 2}
+
+	tests := []struct {
+		name string
+		days int
+		want []int
+	}{
+		{"short", 14, []int{14}},
+		{"exactly 30", 30, []int{30}},
+		{"45", 45, []int{30, 45}},
+		{"90", 90, []int{30, 60, 90}},
+		{"120", 120, []int{30, 60, 90, 120}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := make([]RevenueForecastPoint, tt.days)
+			for i := range points {
+				points[i] = point
+			}
+			got := horizonTotals(points)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected horizons %v, got %+v", tt.want, got)
+			}
+			for i, days := range tt.want {
+				h := got[i]
+				if h.Days != days || h.Revenue != float64(days) || h.Lower95 != float64(days)/2 || h.Upper95 != float64(2*days) {
+					t.Errorf("expected %d-day totals, got %+v", days, h)
+				}
+			}
+		})
+	}
+}
+
+func TestWindowSums(t *testing.T) {
+	tests := []struct {
+		name     string
+		series   []float64
+		n        int
+		recent   float64
+		previous float64
+	}{
+		{"empty", nil, 3, 0, 0},
+		{"shorter than window", []float64{1, 2}, 3, 3, 0},
+		{"partial previous", []float64{1, 2, 3, 4}, 3, 9, 1},
+		{"both full", []float64{9, 1, 2, 3, 4, 5, 6}, 3, 15, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recent, previous := windowSums(tt.series, tt.n)
+			if recent != tt.recent || previous != tt.previous {
+				t.Errorf("expected %v/%v, got %v/%v", tt.recent, tt.previous, recent, previous)
+			}
+		})
+	}
+}
+
+func TestGrowthRate(t *testing.T) {
+	tests := []struct {
+		name             string
+		recent, previous float64
+		want             *float64
+	}{
+		{"no previous", 10, 0, nil},
+		{"doubled", 20, 10, ptr(1.0)},
+		{"halved", 5, 10, ptr(-0.5)},
+		{"rounded", 2, 3, ptr(-0.3333)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := growthRate(tt.recent, tt.previous)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("expected %v, got %v", deref(tt.want), deref(got))
+			}
+		})
+	}
+}
+
+func TestForecastTools(t *testing.T) {
+	byTool := make(map[string][]float64)
+	for i := 0; i < forecastMaxTools+2; i++ {
+		byTool[string(rune('a'+i))] = repeat(float64(i+1), 30)
+	}
+
+	got := (&RevenueAnalytics{}).forecastTools(byTool, 30)
+	if len(got) != forecastMaxTools {
+		t.Fatalf("expected %d forecasts, got %d", forecastMaxTools, len(got))
+	}
+	if got[0].Tool != "l" {
+		t.Errorf("expected the highest earner first, got %s", got[0].Tool)
+	}
+	if last := got[len(got)-1]; last.Tool != "other" || last.Last28Days != 28*(1+2+3) {
+		t.Errorf("expected the three smallest tools combined as other, got %+v", last)
+	}
+
+	var share float64
+	for _, f := range got {
+		share += f.Share
+	}
+	if math.Abs(share-1) > 0.001 {
+		t.Errorf("expected shares to sum to 1, got %.4f", share)
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
+
+func deref(p *float64) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
@@ -0,0 +1,243 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// FunnelStepStats reports how many customers reached a funnel step.
+type FunnelStepStats struct {
+	Step      int    `json:"step"`
+	Name      string `json:"name"`
+	Customers int64  `json:"customers"`
+	// ConversionRate is relative to the previous step; OverallRate to step 1.
+	ConversionRate float64 `json:"conversion_rate"`
+	OverallRate    float64 `json:"overall_rate"`
+	DropOff        int64   `json:"drop_off"`
+	DropOffRate    float64 `json:"drop_off_rate"`
+	// MedianSecondsFromPrevious is the median time from the previous step.
+	MedianSecondsFromPrevious *float64 `json:"median_seconds_from_previous,omitempty"`
+}
+
+// FunnelDay counts customers by the day they entered the funnel and how far
+// they got.
+type FunnelDay struct {
+	Date           string  `json:"date"`
+	Entered        int64   `json:"entered"`
+	Completed      int64   `json:"completed"`
+	ConversionRate float64 `json:"conversion_rate"`
+	Steps          []int64 `json:"steps"`
+}
+
+// FunnelReport is the computed result for one user-defined funnel.
+type FunnelReport struct {
+	Funnel                 *store.Funnel     `json:"funnel"`
+	Days                   int               `json:"days"`
+	Timezone               string            `json:"timezone"`
+	Entered                int64             `json:"entered"`
+	Completed              int64             `json:"completed"`
+	ConversionRate         float64           `json:"conversion_rate"`
+	MedianSecondsToConvert *float64          `json:"median_seconds_to_convert,omitempty"`
+	Steps                  []FunnelStepStats `json:"steps"`
+	DailyTrend             []FunnelDay       `json:"daily_trend"`
+}
+
+// FunnelAnalytics evaluates owner-defined funnels against request history.
+type FunnelAnalytics struct {
+	store  *store.Store
+	logger *zap.Logger
+}
+
+// NewFunnelAnalytics creates a new FunnelAnalytics instance.
+func NewFunnelAnalytics(s *store.Store, logger *zap.Logger) *FunnelAnalytics {
+	return &FunnelAnalytics{
+		store:  s,
+		logger: logger,
+	}
+}
+
+// funnelAttempt tracks one customer's progress from a step-1 entry.
+type funnelAttempt struct {
+	times []time.Time
+}
+
+func (a *funnelAttempt) depth() int { return len(a.times) }
+
+// funnelWalker evaluates one customer's ordered events. A customer enters at
+// their first step-1 request; if the window lapses before completion, a
+// later step-1 request starts a new attempt. The deepest attempt counts.
+type funnelWalker struct {
+	steps  int
+	window time.Duration
+	cur    funnelAttempt
+	best   funnelAttempt
+}
+
+func (w *funnelWalker) reset() {
+	w.cur = funnelAttempt{}
+	w.best = funnelAttempt{}
+}
+
+func (w *funnelWalker) observe(at time.Time, mask uint32) {
+	if w.best.depth() == w.steps {
+		return
+	}
+	if w.cur.depth() > 0 && at.Sub(w.cur.times[0]) > w.window {
+		w.keep()
+		w.cur = funnelAttempt{}
+	}
+	next := w.cur.depth()
+	if next > 0 && mask&(1<<next) != 0 {
+		w.cur.times = append(w.cur.times, at)
+		if w.cur.depth() == w.steps {
+			w.keep()
+		}
+		return
+	}
+	if next == 0 && mask&1 != 0 {
+		w.cur.times = []time.Time{at}
+	}
+}
+
+func (w *funnelWalker) keep() {
+	if w.cur.depth() > w.best.depth() {
+		w.best = w.cur
+	}
+}
+
+func (w *funnelWalker) result() funnelAttempt {
+	w.keep()
+	return w.best
+}
+
+// GetFunnelReport computes step conversion, drop-off, median time between
+// steps and a gap-filled daily trend for a funnel over the last days local
+// days in loc.
+func (fa *FunnelAnalytics) GetFunnelReport(ctx context.Context, f *store.Funnel, days int, loc *time.Location) (*FunnelReport, error) {
+	n := len(f.Steps)
+	since, dates := store.DayBuckets(loc, days, time.Now())
+
+	reached := make([]int64, n)
+	gaps := make([][]float64, n)
+	var toConvert []float64
+	daily := map[string][]int64{}
+
+	walker := &funnelWalker{steps: n, window: time.Duration(f.WindowSeconds) * time.Second}
+	current := ""
+	flush := func() {
+		if current == "" {
+			return
+		}
+		a := walker.result()
+		if a.depth() == 0 {
+			return
+		}
+		day := a.times[0].In(loc).Format("2006-01-02")
+		if daily[day] == nil {
+			daily[day] = make([]int64, n)
+		}
+		for k := 0; k < a.depth(); k++ {
+			reached[k]++
+			daily[day][k]++
+			if k > 0 {
+				gaps[k] = append(gaps[k], a.times[k].Sub(a.times[k-1]).Seconds())
+			}
+		}
+		if a.depth() == n {
+			toConvert = append(toConvert, a.times[n-1].Sub(a.times[0]).Seconds())
+		}
+	}
+
+	err := fa.store.ForEachFunnelEvent(ctx, f.AgentID, f.Steps, since, func(customer string, at time.Time, mask uint32) {
+		if customer != current {
+			flush()
+			current = customer
+			walker.reset()
+		}
+		walker.observe(at, mask)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get funnel report: %w", err)
+	}
+	flush()
+
+	report := &FunnelReport{
+		Funnel:                 f,
+		Days:                   days,
+		Timezone:               loc.String(),
+		Entered:                reached[0],
+		Completed:              reached[n-1],
+		MedianSecondsToConvert: median(toConvert),
+		Steps:                  make([]FunnelStepStats, n),
+		DailyTrend:             []FunnelDay{},
+	}
+	if report.Entered > 0 {
+		report.ConversionRate = float64(report.Completed) / float64(report.Entered)
+	}
+
+	for k, st := range f.Steps {
+		name := st.Name
+		if name == "" {
+			name = fmt.Sprintf("step_%d", k+1)
+		}
+		s := FunnelStepStats{
+			Step:           k + 1,
+			Name:           name,
+			Customers:      reached[k],
+			ConversionRate: 1,
+		}
+		if k > 0 {
+			prev := reached[k-1]
+			s.DropOff = prev - reached[k]
+			if prev > 0 {
+				s.ConversionRate = float64(reached[k]) / float64(prev)
+				s.DropOffRate = float64(s.DropOff) / float64(prev)
+			} else {
+				s.ConversionRate = 0
+			}
+			s.MedianSecondsFromPrevious = median(gaps[k])
+		}
+		if reached[0] > 0 {
+			s.OverallRate = float64(reached[k]) / float64(reached[0])
+		}
+		report.Steps[k] = s
+	}
+
+	for _, d := range dates {
+		counts := daily[d]
+		if counts == nil {
+			counts = make([]int64, n)
+		}
+		day := FunnelDay{
+			Date:      d,
+			Entered:   counts[0],
+			Completed: counts[n-1],
+			Steps:     counts,
+		}
+		if day.Entered > 0 {
+			day.ConversionRate = float64(day.Completed) / float64(day.Entered)
+		}
+		report.DailyTrend = append(report.DailyTrend, day)
+	}
+
+	return report, nil
+}
+
+// median returns the median of v, or nil when v is empty. v is sorted in place.
+func median(v []float64) *float64 {
+	if len(v) == 0 {
+		return nil
+	}
+	sort.Float64s(v)
+	m := v[len(v)/2]
+	if len(v)%2 == 0 {
+		m = (v[len(v)/2-1] + v[len(v)/2]) / 2
+	}
+	return &m
+}
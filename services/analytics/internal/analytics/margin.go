@@ -0,0 +1,265 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// daysPerMonth converts the fixed monthly cost into a daily amount.
+const daysPerMonth = 365.0 / 12
+
+// Price sources for a tool's margin.
+const (
+	PriceListed   = "pricing_amount"
+	PriceObserved = "observed"
+)
+
+// MarginLine is the revenue, cost and margin of one group. Fixed cost is the
+// group's share of the fixed monthly cost: per calendar day for daily
+// groups, by share of requests for tools and customers. Gross margin is
+// revenue minus variable and fixed cost; contribution margin ignores fixed
+// cost.
+type MarginLine struct {
+	Key                string   `json:"key"`
+	Requests           int64    `json:"requests"`
+	PaidRequests       int64    `json:"paid_requests"`
+	InputTokens        int64    `json:"input_tokens"`
+	OutputTokens       int64    `json:"output_tokens"`
+	Revenue            float64  `json:"revenue"`
+	VariableCost       float64  `json:"variable_cost"`
+	FixedCost          float64  `json:"fixed_cost"`
+	TotalCost          float64  `json:"total_cost"`
+	ContributionMargin float64  `json:"contribution_margin"`
+	GrossMargin        float64  `json:"gross_margin"`
+	GrossMarginPct     *float64 `json:"gross_margin_pct"`
+}
+
+// ToolMargin is a tool's margin with its price compared to its measured
+// cost per request. Price is the agent's PricingAmount when set, otherwise
+// the average verified revenue per paid request.
+type ToolMargin struct {
+	MarginLine
+	CostConfigured    bool    `json:"cost_configured"`
+	Price             float64 `json:"price"`
+	PriceSource       string  `json:"price_source,omitempty"`
+	UnitCost          float64 `json:"unit_cost"`
+	FullUnitCost      float64 `json:"full_unit_cost"`
+	BelowVariableCost bool    `json:"below_variable_cost"`
+	BelowFullCost     bool    `json:"below_full_cost"`
+}
+
+// MarginReport is gross margin over the last Days days, grouped by tool,
+// customer or day. UnderpricedTools lists the tools whose price is below
+// their fully loaded cost per request, whatever the grouping.
+type MarginReport struct {
+	GroupBy          string       `json:"group_by"`
+	Days             int          `json:"days"`
+	Timezone         string       `json:"timezone"`
+	Currency         string       `json:"currency,omitempty"`
+	FixedMonthlyCost float64      `json:"fixed_monthly_cost"`
+	Totals           MarginLine   `json:"totals"`
+	Tools            []ToolMargin `json:"tools,omitempty"`
+	Customers        []MarginLine `json:"customers,omitempty"`
+	Daily            []MarginLine `json:"daily,omitempty"`
+	UnderpricedTools []ToolMargin `json:"underpriced_tools"`
+}
+
+// GetMarginReport combines the agent's cost inputs with request traffic and
+// verified x402 revenue over the last days days in loc. Customers are
+// ordered by revenue and capped at customerLimit.
+func (ra *RevenueAnalytics) GetMarginReport(ctx context.Context, agentDBID uuid.UUID, groupBy string, days, customerLimit int, loc *time.Location) (*MarginReport, error) {
+	since, dates := store.DayBuckets(loc, days, time.Now())
+
+	var (
+		agent    *store.Agent
+		settings *store.CostSettings
+		tools    []store.MarginUsage
+		grouped  []store.MarginUsage
+	)
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		agent, err = ra.store.GetAgentByDBID(gctx, agentDBID)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		settings, err = ra.store.GetCostSettings(gctx, agentDBID)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		tools, err = ra.store.GetMarginUsage(gctx, agentDBID, store.MarginByTool, since, loc)
+		return err
+	})
+	if groupBy != store.MarginByTool {
+		g.Go(func() error {
+			var err error
+			grouped, err = ra.store.GetMarginUsage(gctx, agentDBID, groupBy, since, loc)
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, fmt.Errorf("get margin report: %w", err)
+	}
+
+	dailyFixed := settings.FixedMonthlyCost / daysPerMonth
+	windowFixed := dailyFixed * float64(days)
+
+	var totalRequests int64
+	totals := MarginLine{Key: "total"}
+	for _, u := range tools {
+		totalRequests += u.Requests
+		totals.Requests += u.Requests
+		totals.PaidRequests += u.PaidRequests
+		totals.InputTokens += u.InputTokens
+		totals.OutputTokens += u.OutputTokens
+		totals.Revenue += u.Revenue
+		totals.VariableCost += u.VariableCost
+	}
+	finishMarginLine(&totals, windowFixed)
+
+	report := &MarginReport{
+		GroupBy:          groupBy,
+		Days:             days,
+		Timezone:         loc.String(),
+		Currency:         agent.PricingCurrency,
+		FixedMonthlyCost: settings.FixedMonthlyCost,
+		Totals:           totals,
+		UnderpricedTools: []ToolMargin{},
+	}
+
+	configured := make(map[string]bool, len(settings.Tools))
+	for _, tc := range settings.Tools {
+		configured[tc.ToolName] = true
+	}
+	toolMargins := make([]ToolMargin, 0, len(tools))
+	for _, u := range tools {
+		tm := toolMargin(u, shareOf(windowFixed, u.Requests, totalRequests), agent.PricingAmount)
+		tm.CostConfigured = configured[u.Key]
+		toolMargins = append(toolMargins, tm)
+		if tm.BelowFullCost {
+			report.UnderpricedTools = append(report.UnderpricedTools, tm)
+		}
+	}
+	sort.Slice(toolMargins, func(i, j int) bool { return toolMargins[i].GrossMargin < toolMargins[j].GrossMargin })
+
+	switch groupBy {
+	case store.MarginByTool:
+		report.Tools = toolMargins
+	case store.MarginByCustomer:
+		report.Customers = customerMargins(grouped, windowFixed, totalRequests, customerLimit)
+	case store.MarginByDay:
+		report.Daily = dailyMargins(grouped, dates, dailyFixed)
+	}
+	return report, nil
+}
+
+// toolMargin prices a tool against its cost per request. A tool with no
+// listed price and no paid requests has no price and is never flagged.
+func toolMargin(u store.MarginUsage, fixed, listPrice float64) ToolMargin {
+	tm := ToolMargin{MarginLine: marginLine(u, fixed)}
+	switch {
+	case listPrice > 0:
+		tm.Price, tm.PriceSource = listPrice, PriceListed
+	case u.PaidRequests > 0:
+		tm.Price, tm.PriceSource = round8(u.Revenue/float64(u.PaidRequests)), PriceObserved
+	}
+	tm.UnitCost = round8(perUnit(u.VariableCost, u.Requests))
+	tm.FullUnitCost = round8(perUnit(u.VariableCost+fixed, u.Requests))
+	if tm.Price > 0 && u.Requests > 0 {
+		tm.BelowVariableCost = tm.Price < tm.UnitCost
+		tm.BelowFullCost = tm.Price < tm.FullUnitCost
+	}
+	return tm
+}
+
+// customerMargins allocates fixed cost by request share and returns the
+// limit customers with the most revenue, then the most requests.
+func customerMargins(usage []store.MarginUsage, windowFixed float64, totalRequests int64, limit int) []MarginLine {
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Revenue != usage[j].Revenue {
+			return usage[i].Revenue > usage[j].Revenue
+		}
+		return usage[i].Requests > usage[j].Requests
+	})
+	if len(usage) > limit {
+		usage = usage[:limit]
+	}
+	lines := make([]MarginLine, 0, len(usage))
+	for _, u := range usage {
+		lines = append(lines, marginLine(u, shareOf(windowFixed, u.Requests, totalRequests)))
+	}
+	return lines
+}
+
+// dailyMargins returns one line per day bucket, oldest first, each carrying
+// a full day of fixed cost.
+func dailyMargins(usage []store.MarginUsage, dates []string, dailyFixed float64) []MarginLine {
+	byDate := make(map[string]store.MarginUsage, len(usage))
+	for _, u := range usage {
+		byDate[u.Key] = u
+	}
+	lines := make([]MarginLine, 0, len(dates))
+	for _, d := range dates {
+		u, ok := byDate[d]
+		if !ok {
+			u = store.MarginUsage{Key: d}
+		}
+		lines = append(lines, marginLine(u, dailyFixed))
+	}
+	return lines
+}
+
+func marginLine(u store.MarginUsage, fixed float64) MarginLine {
+	line := MarginLine{
+		Key:          u.Key,
+		Requests:     u.Requests,
+		PaidRequests: u.PaidRequests,
+		InputTokens:  u.InputTokens,
+		OutputTokens: u.OutputTokens,
+		Revenue:      u.Revenue,
+		VariableCost: u.VariableCost,
+	}
+	finishMarginLine(&line, fixed)
+	return line
+}
+
+func finishMarginLine(line *MarginLine, fixed float64) {
+	line.FixedCost = fixed
+	line.TotalCost = line.VariableCost + fixed
+	line.ContributionMargin = line.Revenue - line.VariableCost
+	line.GrossMargin = line.Revenue - line.TotalCost
+	if line.Revenue > 0 {
+		pct := round4(line.GrossMargin / line.Revenue * 100)
+		line.GrossMarginPct = &pct
+	}
+
+	line.Revenue = round8(line.Revenue)
+	line.VariableCost = round8(line.VariableCost)
+	line.FixedCost = round8(line.FixedCost)
+	line.TotalCost = round8(line.TotalCost)
+	line.ContributionMargin = round8(line.ContributionMargin)
+	line.GrossMargin = round8(line.GrossMargin)
+}
+
+// shareOf splits amount by a group's share of requests.
+func shareOf(amount float64, requests, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return amount * float64(requests) / float64(total)
+}
+
+// round8 rounds to the precision of the NUMERIC(20,8) money columns.
+func round8(v float64) float64 {
+	return math.Round(v*1e8) / 1e8
+}
@@ -0,0 +1,210 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// Peer comparison metric names.
+const (
+	PeerP95ResponseMs = "p95_response_ms"
+	PeerErrorRate     = "error_rate"
+	PeerConversion    = "conversion_rate"
+	PeerARPU          = "arpu"
+)
+
+// minPeerGroupFloor is the smallest peer group ever reported, regardless of
+// configuration: with fewer peers a quartile is effectively one competitor's
+// own number.
+const minPeerGroupFloor = 3
+
+// ErrNoCategory is returned when the agent has no category to compare within.
+var ErrNoCategory = errors.New("agent has no category")
+
+// PeerMetric compares one of the owner's metrics with the category.
+// Percentiles are omitted (Suppressed) when fewer than the minimum number of
+// peers have data for the metric.
+type PeerMetric struct {
+	Name           string   `json:"name"`
+	HigherIsBetter bool     `json:"higher_is_better"`
+	Value          *float64 `json:"value"`
+	Peers          int      `json:"peers"`
+	Suppressed     bool     `json:"suppressed"`
+	P25            *float64 `json:"p25,omitempty"`
+	P50            *float64 `json:"p50,omitempty"`
+	P75            *float64 `json:"p75,omitempty"`
+	// Position is the owner's quartile band among peers: below_p25,
+	// p25_p50, p50_p75 or above_p75.
+	Position string `json:"position,omitempty"`
+}
+
+// PeerComparison is an agent's metrics next to anonymized category
+// percentiles over the same window.
+type PeerComparison struct {
+	Category         string       `json:"category"`
+	Days             int          `json:"days"`
+	PeerGroupSize    int          `json:"peer_group_size"`
+	MinPeerGroupSize int          `json:"min_peer_group_size"`
+	Metrics          []PeerMetric `json:"metrics"`
+}
+
+// PeerAnalytics compares agents with the other agents in their benchmark
+// category.
+type PeerAnalytics struct {
+	store    *store.Store
+	logger   *zap.Logger
+	minPeers int
+}
+
+// NewPeerAnalytics creates a new PeerAnalytics. minPeers below the floor of
+// 3 is raised to it.
+func NewPeerAnalytics(s *store.Store, logger *zap.Logger, minPeers int) *PeerAnalytics {
+	if minPeers < minPeerGroupFloor {
+		minPeers = minPeerGroupFloor
+	}
+	return &PeerAnalytics{
+		store:    s,
+		logger:   logger,
+		minPeers: minPeers,
+	}
+}
+
+// GetPeerComparison compares the agent with the other active agents of its
+// category (the grouping used by BenchmarkCalculator) over the last days days.
+func (pa *PeerAnalytics) GetPeerComparison(ctx context.Context, agentDBID uuid.UUID, days int) (*PeerComparison, error) {
+	agent, err := pa.store.GetAgentByDBID(ctx, agentDBID)
+	if err != nil {
+		return nil, fmt.Errorf("get agent: %w", err)
+	}
+	if agent.Category == "" {
+		return nil, ErrNoCategory
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	all, err := pa.store.GetCategoryPeerMetrics(ctx, agent.Category, since)
+	if err != nil {
+		return nil, err
+	}
+
+	var own *store.PeerMetrics
+	peers := make([]store.PeerMetrics, 0, len(all))
+	for i := range all {
+		if all[i].AgentID == agentDBID {
+			own = &all[i]
+			continue
+		}
+		peers = append(peers, all[i])
+	}
+	if own == nil {
+		own = &store.PeerMetrics{AgentID: agentDBID}
+	}
+
+	result := &PeerComparison{
+		Category:         agent.Category,
+		Days:             days,
+		PeerGroupSize:    len(peers),
+		MinPeerGroupSize: pa.minPeers,
+	}
+
+	for _, def := range []struct {
+		name           string
+		higherIsBetter bool
+	}{
+		{PeerP95ResponseMs, false},
+		{PeerErrorRate, false},
+		{PeerConversion, true},
+		{PeerARPU, true},
+	} {
+		m := PeerMetric{
+			Name:           def.name,
+			HigherIsBetter: def.higherIsBetter,
+			Value:          peerValue(def.name, *own),
+		}
+
+		var values []float64
+		for _, p := range peers {
+			if v := peerValue(def.name, p); v != nil {
+				values = append(values, *v)
+			}
+		}
+		m.Peers = len(values)
+
+		if len(values) < pa.minPeers {
+			m.Suppressed = true
+		} else {
+			sort.Float64s(values)
+			p25, p50, p75 := quantile(values, 0.25), quantile(values, 0.5), quantile(values, 0.75)
+			m.P25, m.P50, m.P75 = &p25, &p50, &p75
+			if m.Value != nil {
+				m.Position = quartileBand(*m.Value, p25, p50, p75)
+			}
+		}
+		result.Metrics = append(result.Metrics, m)
+	}
+
+	return result, nil
+}
+
+// peerValue derives a comparison metric from raw totals. It returns nil when
+// the agent has no data the metric could be computed from.
+func peerValue(name string, m store.PeerMetrics) *float64 {
+	var v float64
+	switch name {
+	case PeerP95ResponseMs:
+		if m.P95ResponseMs == nil {
+			return nil
+		}
+		v = *m.P95ResponseMs
+	case PeerErrorRate:
+		if m.Requests == 0 {
+			return nil
+		}
+		v = float64(m.Errors) / float64(m.Requests)
+	case PeerConversion:
+		if m.Customers == 0 {
+			return nil
+		}
+		v = float64(m.PayingCustomers) / float64(m.Customers)
+	case PeerARPU:
+		if m.Customers == 0 {
+			return nil
+		}
+		v = m.Revenue / float64(m.Customers)
+	default:
+		return nil
+	}
+	v = math.Round(v*10000) / 10000
+	return &v
+}
+
+// quantile returns the q-quantile of sorted values using linear
+// interpolation (matching percentile_cont).
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	v := sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+	return math.Round(v*10000) / 10000
+}
+
+func quartileBand(v, p25, p50, p75 float64) string {
+	switch {
+	case v < p25:
+		return "below_p25"
+	case v < p50:
+		return "p25_p50"
+	case v < p75:
+		return "p50_p75"
+	default:
+		return "above_p75"
+	}
+}
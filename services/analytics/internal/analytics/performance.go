@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -30,11 +31,16 @@ type PerformanceReport struct {
 	HealthScore     float64   `json:"health_score"`
 	HealthStatus    string    `json:"health_status"`
 
-	// Trend data (24 hourly samples)
+	// Trend data (24 hourly samples, oldest first). TrendBuckets holds the
+	// start of each hour in Timezone.
+	Timezone        string    `json:"timezone"`
+	TrendBuckets    []string  `json:"trend_buckets"`
 	P95Trend        []float64 `json:"p95_trend"`
 	ErrorRateTrend  []float64 `json:"error_rate_trend"`
 	ThroughputTrend []float64 `json:"throughput_trend"`
 	UptimeTrend     []float64 `json:"uptime_trend"`
+	// Deployments within the trend range, bucketed into TrendBuckets.
+	Deployments []DeploymentMarker `json:"deployments"`
 
 	// Deltas (vs 24h ago)
 	HealthDelta     float64 `json:"health_delta"`
@@ -88,8 +94,9 @@ func calculateHealthScore(p95Ms, errorRate, uptime float64, totalRequests int64)
 	return score, status
 }
 
-// GetPerformanceReport returns an aggregated performance report for the given agent and time window.
-func (pa *PerformanceAnalytics) GetPerformanceReport(ctx context.Context, agentDBID uuid.UUID, windowHours int) (*PerformanceReport, error) {
+// GetPerformanceReport returns an aggregated performance report for the given
+// agent and time window. The 24-hour trend is bucketed by hour in loc.
+func (pa *PerformanceAnalytics) GetPerformanceReport(ctx context.Context, agentDBID uuid.UUID, windowHours int, loc *time.Location) (*PerformanceReport, error) {
 	if windowHours <= 0 {
 		windowHours = 24
 	}
@@ -137,38 +144,64 @@ func (pa *PerformanceAnalytics) GetPerformanceReport(ctx context.Context, agentD
 	// 2. Calculate health score
 	healthScore, healthStatus := calculateHealthScore(p95, errorRate, uptime*100, total)
 
-	// 3. Get 24h trend data (hourly buckets)
+	// 3. Get 24h trend data (hourly buckets). Rows are bucketed by their
+	// offset from the first hour so each bucket is one absolute hour.
+	buckets := store.HourBuckets(loc, 24, time.Now())
 	trendRows, err := pa.store.Pool().Query(ctx, `
 		SELECT
-			EXTRACT(HOUR FROM created_at) AS hour,
+			FLOOR(EXTRACT(EPOCH FROM created_at - $2) / 3600)::int AS bucket,
 			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY response_ms), 0) AS p95,
 			COALESCE(AVG(CASE WHEN status_code >= 400 AND status_code != 402 THEN 1.0 ELSE 0.0 END), 0) AS error_rate,
 			COUNT(*) AS requests
 		FROM request_logs
-		WHERE agent_id = $1 AND created_at >= NOW() - INTERVAL '24 hours'
-		GROUP BY EXTRACT(HOUR FROM created_at)
-		ORDER BY hour
-	`, agentDBID)
+		WHERE agent_id = $1 AND created_at >= $2
+		GROUP BY 1
+	`, agentDBID, buckets[0])
 	if err != nil {
 		return nil, fmt.Errorf("get trend data: %w", err)
 	}
 	defer trendRows.Close()
 
-	// Initialize trend arrays with 24 hours of data
-	p95Trend := make([]float64, 0, 24)
-	errorRateTrend := make([]float64, 0, 24)
-	throughputTrend := make([]float64, 0, 24)
-	uptimeTrend := make([]float64, 0, 24)
+	// Hours without traffic stay at zero latency/throughput and full uptime.
+	trendBuckets := make([]string, len(buckets))
+	p95Trend := make([]float64, len(buckets))
+	errorRateTrend := make([]float64, len(buckets))
+	throughputTrend := make([]float64, len(buckets))
+	uptimeTrend := make([]float64, len(buckets))
+	for i, b := range buckets {
+		trendBuckets[i] = b.Format(time.RFC3339)
+		uptimeTrend[i] = 100
+	}
 
 	for trendRows.Next() {
-		var hour, p95Val, errorVal, requests float64
-		if err := trendRows.Scan(&hour, &p95Val, &errorVal, &requests); err != nil {
+		var bucket int
+		var p95Val, errorVal, requests float64
+		if err := trendRows.Scan(&bucket, &p95Val, &errorVal, &requests); err != nil {
 			return nil, fmt.Errorf("scan trend row: %w", err)
 		}
-		p95Trend = append(p95Trend, p95Val)
-		errorRateTrend = append(errorRateTrend, errorVal)
-		throughputTrend = append(throughputTrend, requests/60.0) // requests per minute
-		uptimeTrend = append(uptimeTrend, (1.0-errorVal)*100.0)
+		if bucket < 0 || bucket >= len(buckets) {
+			continue
+		}
+		p95Trend[bucket] = p95Val
+		errorRateTrend[bucket] = errorVal
+		throughputTrend[bucket] = requests / 60.0 // requests per minute
+		uptimeTrend[bucket] = (1.0 - errorVal) * 100.0
+	}
+	if err := trendRows.Err(); err != nil {
+		return nil, fmt.Errorf("get trend data: %w", err)
+	}
+
+	deployments, err := pa.store.ListDeployments(ctx, agentDBID, buckets[0], time.Now(), 100)
+	if err != nil {
+		return nil, err
+	}
+	markers := make([]DeploymentMarker, 0, len(deployments))
+	for i := len(deployments) - 1; i >= 0; i-- {
+		idx := int(deployments[i].DeployedAt.Sub(buckets[0]) / time.Hour)
+		if idx < 0 || idx >= len(buckets) {
+			continue
+		}
+		markers = append(markers, NewDeploymentMarker(deployments[i], trendBuckets[idx]))
 	}
 
 	// 4. Get delta comparison (24-48h ago window)
@@ -235,10 +268,13 @@ func (pa *PerformanceAnalytics) GetPerformanceReport(ctx context.Context, agentD
 		P90ResponseMs:   p90,
 		HealthScore:     healthScore,
 		HealthStatus:    healthStatus,
+		Timezone:        loc.String(),
+		TrendBuckets:    trendBuckets,
 		P95Trend:        p95Trend,
 		ErrorRateTrend:  errorRateTrend,
 		ThroughputTrend: throughputTrend,
 		UptimeTrend:     uptimeTrend,
+		Deployments:     markers,
 		HealthDelta:     healthDelta,
 		P95DeltaMs:      p95DeltaMs,
 		ErrorDelta:      errorDelta,
@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"context"
+	"encoding/json"
 	"math"
 	"time"
 
@@ -122,7 +123,122 @@ func (rc *ReputationCalculator) Calculate() {
 	)
 }
 
-// computeBreakdown calculates all sub-scores and the weighted total.
+// Peer review credibility parameters.
+const (
+	// reviewBaseWeight is the credibility of an unknown, brand-new wallet.
+	reviewBaseWeight = 0.25
+	// reviewAgeWeight is added in proportion to wallet age, up to reviewMatureAge.
+	reviewAgeWeight = 0.25
+	reviewMatureAge = 90 * 24 * time.Hour
+	// reviewCustomerWeight is added for verified paying customers of the agent.
+	reviewCustomerWeight = 0.5
+
+	// Reviews from wallets younger than reviewFreshAge (at review time) are
+	// burst-dampened: beyond reviewBurstAllowance such reviews within
+	// reviewBurstWindow of each other share the weight of the allowance.
+	reviewFreshAge       = 7 * 24 * time.Hour
+	reviewBurstWindow    = 24 * time.Hour
+	reviewBurstAllowance = 3
+
+	// reviewPriorWeight pulls the weighted average toward reviewPriorScore so
+	// a handful of reviews cannot produce an extreme score.
+	reviewPriorWeight = 2.0
+	reviewPriorScore  = 3.0
+)
+
+// ReputationComponent explains one reputation component.
+type ReputationComponent struct {
+	Name         string         `json:"name"`
+	Score        float64        `json:"score"`
+	Weight       float64        `json:"weight"`
+	Contribution float64        `json:"contribution"`
+	Formula      string         `json:"formula"`
+	Inputs       map[string]any `json:"inputs"`
+}
+
+// ReviewCredibility summarizes how peer reviews were weighted.
+type ReviewCredibility struct {
+	Reviews                 int     `json:"reviews"`
+	VerifiedCustomerReviews int     `json:"verified_customer_reviews"`
+	FreshWalletReviews      int     `json:"fresh_wallet_reviews"`
+	DampenedReviews         int     `json:"dampened_reviews"`
+	EffectiveWeight         float64 `json:"effective_weight"`
+	RawAverage              float64 `json:"raw_average"`
+	WeightedAverage         float64 `json:"weighted_average"`
+}
+
+// ReputationExplanation is stored with each breakdown as its inputs.
+type ReputationExplanation struct {
+	Components []ReputationComponent `json:"components"`
+	Reviews    ReviewCredibility     `json:"reviews"`
+}
+
+// reviewWeights returns the credibility weight of each review (same order)
+// and a summary.
+func reviewWeights(reviews []store.ReviewSignal) ([]float64, ReviewCredibility) {
+	summary := ReviewCredibility{Reviews: len(reviews)}
+	weights := make([]float64, len(reviews))
+	fresh := make([]bool, len(reviews))
+
+	for i, r := range reviews {
+		age := r.CreatedAt.Sub(r.KnownSince)
+		w := reviewBaseWeight + reviewAgeWeight*math.Min(1, age.Hours()/reviewMatureAge.Hours())
+		if r.VerifiedCustomer {
+			w += reviewCustomerWeight
+			summary.VerifiedCustomerReviews++
+		}
+		weights[i] = w
+		// Paying customers are never treated as part of a burst.
+		fresh[i] = age < reviewFreshAge && !r.VerifiedCustomer
+		if age < reviewFreshAge {
+			summary.FreshWalletReviews++
+		}
+	}
+
+	// Reviews are ordered by time; count fresh-wallet neighbours in the window.
+	for i := range reviews {
+		if !fresh[i] {
+			continue
+		}
+		n := 0
+		for j := range reviews {
+			if fresh[j] && absDuration(reviews[j].CreatedAt.Sub(reviews[i].CreatedAt)) <= reviewBurstWindow {
+				n++
+			}
+		}
+		if n > reviewBurstAllowance {
+			weights[i] *= float64(reviewBurstAllowance) / float64(n)
+			summary.DampenedReviews++
+		}
+	}
+
+	var raw, weighted, total float64
+	for i, r := range reviews {
+		raw += float64(r.Score)
+		weighted += weights[i] * float64(r.Score)
+		total += weights[i]
+	}
+	if len(reviews) > 0 {
+		summary.RawAverage = round2(raw / float64(len(reviews)))
+		summary.WeightedAverage = round2((weighted + reviewPriorWeight*reviewPriorScore) / (total + reviewPriorWeight))
+	}
+	summary.EffectiveWeight = round2(total)
+	return weights, summary
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// computeBreakdown calculates all sub-scores and the weighted total, and
+// records the inputs behind each one.
 func (rc *ReputationCalculator) computeBreakdown(agentID [16]byte, inputs *store.ReputationInputs, now time.Time) *store.ReputationBreakdown {
 	// Reliability: (1 - error_rate) * 100 → 0-100
 	reliability := (1 - inputs.ErrorRate) * 100
@@ -150,37 +266,147 @@ func (rc *ReputationCalculator) computeBreakdown(agentID [16]byte, inputs *store
 		customerRetention = float64(inputs.LowRiskCustomers) / float64(inputs.TotalCustomerRows) * 100
 	}
 
-	// Peer Review: average score normalized to 0-100 (reviews are 1-5)
+	// Peer Review: credibility-weighted average (1-5), shrunk toward a
+	// neutral prior, normalized to 0-100.
 	peerReview := 0.0
+	_, credibility := reviewWeights(inputs.Reviews)
 	if inputs.ReviewCount > 0 {
-		peerReview = inputs.AvgReviewScore / 5.0 * 100
+		peerReview = credibility.WeightedAverage / 5.0 * 100
 	}
 
 	// On-chain Score: use the raw score from the reputation registry.
 	// On-chain scores are typically 0-100.
 	onchainScore := math.Max(0, math.Min(100, inputs.OnchainScore))
 
+	components := []ReputationComponent{
+		{
+			Name: "reliability", Score: reliability, Weight: weightReliability,
+			Formula: "(1 - error_rate) * 100",
+			Inputs: map[string]any{
+				"error_rate":     math.Round(inputs.ErrorRate*10000) / 10000,
+				"requests_30d":   inputs.TotalRequests,
+				"window":         "30d",
+				"error_criteria": "status_code >= 400 excluding 402",
+			},
+		},
+		{
+			Name: "performance", Score: performance, Weight: weightPerformance,
+			Formula: "max(0, 100 - avg_response_ms / 10)",
+			Inputs:  map[string]any{"avg_response_ms": round2(inputs.AvgResponseMs), "window": "30d"},
+		},
+		{
+			Name: "activity", Score: activity, Weight: weightActivity,
+			Formula: "min(100, log2(requests_30d + 1) * 10)",
+			Inputs:  map[string]any{"requests_30d": inputs.TotalRequests},
+		},
+		{
+			Name: "revenue_quality", Score: revenueQuality, Weight: weightRevenueQuality,
+			Formula: "min(100, log2(total_revenue_usdc + 1) * 15)",
+			Inputs:  map[string]any{"total_revenue_usdc": round2(inputs.TotalRevenueUSDC)},
+		},
+		{
+			Name: "customer_retention", Score: customerRetention, Weight: weightCustomerRetention,
+			Formula: "low_risk_customers / customers * 100",
+			Inputs: map[string]any{
+				"low_risk_customers": inputs.LowRiskCustomers,
+				"customers":          inputs.TotalCustomerRows,
+			},
+		},
+		{
+			Name: "peer_review", Score: peerReview, Weight: weightPeerReview,
+			Formula: "credibility-weighted average score / 5 * 100",
+			Inputs: map[string]any{
+				"reviews":                   credibility.Reviews,
+				"raw_average":               credibility.RawAverage,
+				"weighted_average":          credibility.WeightedAverage,
+				"verified_customer_reviews": credibility.VerifiedCustomerReviews,
+				"fresh_wallet_reviews":      credibility.FreshWalletReviews,
+				"dampened_reviews":          credibility.DampenedReviews,
+			},
+		},
+		{
+			Name: "onchain", Score: onchainScore, Weight: weightOnchain,
+			Formula: "clamp(onchain_score, 0, 100)",
+			Inputs: map[string]any{
+				"onchain_score":  round2(inputs.OnchainScore),
+				"feedback_count": inputs.OnchainCount,
+			},
+		},
+	}
+
 	// Weighted total
-	total := reliability*weightReliability +
-		performance*weightPerformance +
-		activity*weightActivity +
-		revenueQuality*weightRevenueQuality +
-		customerRetention*weightCustomerRetention +
-		peerReview*weightPeerReview +
-		onchainScore*weightOnchain
+	var total float64
+	for i := range components {
+		c := &components[i]
+		c.Contribution = round2(c.Score * c.Weight)
+		c.Score = round2(c.Score)
+		total += c.Score * c.Weight
+	}
+
+	explanation, _ := json.Marshal(ReputationExplanation{
+		Components: components,
+		Reviews:    credibility,
+	})
 
 	return &store.ReputationBreakdown{
 		AgentID:           agentID,
-		Reliability:       math.Round(reliability*100) / 100,
-		Performance:       math.Round(performance*100) / 100,
-		Activity:          math.Round(activity*100) / 100,
-		RevenueQuality:    math.Round(revenueQuality*100) / 100,
-		CustomerRetention: math.Round(customerRetention*100) / 100,
-		PeerReview:        math.Round(peerReview*100) / 100,
-		OnchainScore:      math.Round(onchainScore*100) / 100,
-		TotalScore:        math.Round(total*100) / 100,
+		Reliability:       round2(reliability),
+		Performance:       round2(performance),
+		Activity:          round2(activity),
+		RevenueQuality:    round2(revenueQuality),
+		CustomerRetention: round2(customerRetention),
+		PeerReview:        round2(peerReview),
+		OnchainScore:      round2(onchainScore),
+		TotalScore:        round2(total),
 		OnchainCount:      inputs.OnchainCount,
 		ReviewCount:       inputs.ReviewCount,
 		CalculatedAt:      now,
+		Inputs:            explanation,
+	}
+}
+
+// ReputationChange explains the difference between two consecutive
+// reputation snapshots.
+type ReputationChange struct {
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	TotalDelta float64   `json:"total_delta"`
+	// ContributionDeltas is the change in each component's weighted
+	// contribution; they sum to TotalDelta.
+	ContributionDeltas map[string]float64 `json:"contribution_deltas"`
+	// MainDriver is the component with the largest absolute contribution change.
+	MainDriver string `json:"main_driver,omitempty"`
+}
+
+// ReputationChanges explains how the score moved between consecutive
+// snapshots (ordered oldest first).
+func ReputationChanges(history []store.ReputationBreakdown) []ReputationChange {
+	changes := []ReputationChange{}
+	for i := 1; i < len(history); i++ {
+		prev, cur := history[i-1], history[i]
+		deltas := map[string]float64{
+			"reliability":        round2((cur.Reliability - prev.Reliability) * weightReliability),
+			"performance":        round2((cur.Performance - prev.Performance) * weightPerformance),
+			"activity":           round2((cur.Activity - prev.Activity) * weightActivity),
+			"revenue_quality":    round2((cur.RevenueQuality - prev.RevenueQuality) * weightRevenueQuality),
+			"customer_retention": round2((cur.CustomerRetention - prev.CustomerRetention) * weightCustomerRetention),
+			"peer_review":        round2((cur.PeerReview - prev.PeerReview) * weightPeerReview),
+			"onchain":            round2((cur.OnchainScore - prev.OnchainScore) * weightOnchain),
+		}
+		change := ReputationChange{
+			From:               prev.CalculatedAt,
+			To:                 cur.CalculatedAt,
+			TotalDelta:         round2(cur.TotalScore - prev.TotalScore),
+			ContributionDeltas: deltas,
+		}
+		var biggest float64
+		for name, d := range deltas {
+			if math.Abs(d) > biggest || (math.Abs(d) == biggest && biggest > 0 && name < change.MainDriver) {
+				biggest = math.Abs(d)
+				change.MainDriver = name
+			}
+		}
+		changes = append(changes, change)
 	}
+	return changes
 }
@@ -0,0 +1,183 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// Customer segment names.
+const (
+	SegmentChampions          = "champions"
+	SegmentLoyal              = "loyal"
+	SegmentNew                = "new"
+	SegmentPromising          = "promising"
+	SegmentPotentialLoyalists = "potential_loyalists"
+	SegmentNeedsAttention     = "needs_attention"
+	SegmentAtRiskBigSpenders  = "at_risk_big_spenders"
+	SegmentAtRisk             = "at_risk"
+	SegmentHibernating        = "hibernating"
+	SegmentLost               = "lost"
+)
+
+// Segments lists every segment, from most to least engaged.
+var Segments = []string{
+	SegmentChampions,
+	SegmentLoyal,
+	SegmentNew,
+	SegmentPromising,
+	SegmentPotentialLoyalists,
+	SegmentNeedsAttention,
+	SegmentAtRiskBigSpenders,
+	SegmentAtRisk,
+	SegmentHibernating,
+	SegmentLost,
+}
+
+// IsSegment reports whether name is a known segment.
+func IsSegment(name string) bool {
+	for _, s := range Segments {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// newCustomerWindow is how recently a customer must have first appeared to
+// be segmented as new.
+const newCustomerWindow = 30 * 24 * time.Hour
+
+// ltvHorizon is how far ahead projected lifetime value looks, and
+// minLTVTenure the shortest spending history a daily spend rate is
+// derived from, so a single recent payment is not extrapolated as daily.
+const (
+	ltvHorizon   = 365 * 24 * time.Hour
+	minLTVTenure = 30 * 24 * time.Hour
+)
+
+// defaultRetention is the retention probability used for customers that
+// have not been churn-scored yet.
+const defaultRetention = 0.5
+
+// RefreshSegments rescores RFM and segments for all customers of an agent.
+// Recency (time since last seen), frequency (active days in the last year)
+// and monetary value (verified revenue) are each scored 1-5 by quintile
+// among the agent's customers; see segmentFor for how scores map to
+// segments and projectLTV for the lifetime value projection.
+func (ca *CustomerAnalytics) RefreshSegments(ctx context.Context, agentDBID uuid.UUID) error {
+	inputs, err := ca.store.GetSegmentInputs(ctx, agentDBID)
+	if err != nil {
+		return fmt.Errorf("refresh segments: %w", err)
+	}
+
+	segments := scoreSegments(inputs, time.Now())
+	if err := ca.store.UpdateCustomerSegments(ctx, agentDBID, segments); err != nil {
+		return fmt.Errorf("refresh segments: %w", err)
+	}
+	ca.logger.Debug("customer segments refreshed",
+		zap.String("agent_db_id", agentDBID.String()),
+		zap.Int("customers", len(segments)),
+	)
+	return nil
+}
+
+func scoreSegments(inputs []store.SegmentInputs, now time.Time) []store.CustomerSegment {
+	recency := make([]float64, len(inputs))
+	frequency := make([]float64, len(inputs))
+	monetary := make([]float64, len(inputs))
+	for i, in := range inputs {
+		// More recent is better, so score the negated age.
+		recency[i] = -now.Sub(in.LastSeenAt).Hours()
+		frequency[i] = float64(in.ActiveDays)
+		monetary[i] = in.Revenue
+	}
+	r, f, m := quintileScores(recency), quintileScores(frequency), quintileScores(monetary)
+
+	segments := make([]store.CustomerSegment, len(inputs))
+	for i, in := range inputs {
+		isNew := now.Sub(in.FirstSeenAt) <= newCustomerWindow
+		segments[i] = store.CustomerSegment{
+			CustomerID:   in.CustomerID,
+			Recency:      r[i],
+			Frequency:    f[i],
+			Monetary:     m[i],
+			Segment:      segmentFor(r[i], f[i], m[i], isNew),
+			ProjectedLTV: projectLTV(in, now),
+		}
+	}
+	return segments
+}
+
+// quintileScores maps each value to 1-5 by the share of values strictly
+// below it, so ties share a score and a metric everyone has equally (such
+// as zero revenue) scores 1 across the board.
+func quintileScores(values []float64) []int {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	scores := make([]int, len(values))
+	for i, v := range values {
+		below := sort.SearchFloat64s(sorted, v)
+		score := 1 + int(5*float64(below)/float64(len(values)))
+		if score > 5 {
+			score = 5
+		}
+		scores[i] = score
+	}
+	return scores
+}
+
+// segmentFor names the segment for recency, frequency and monetary scores.
+// Rules are checked in order; the first match wins.
+func segmentFor(r, f, m int, isNew bool) string {
+	switch {
+	case r >= 4 && f >= 4 && m >= 4:
+		return SegmentChampions
+	case r <= 2 && m >= 4:
+		return SegmentAtRiskBigSpenders
+	case isNew && r >= 3:
+		return SegmentNew
+	case r >= 3 && f >= 4:
+		return SegmentLoyal
+	case r >= 4 && f <= 2:
+		return SegmentPromising
+	case r >= 3 && f >= 2:
+		return SegmentPotentialLoyalists
+	case r == 3:
+		return SegmentNeedsAttention
+	case f >= 3:
+		return SegmentAtRisk
+	case r == 2:
+		return SegmentHibernating
+	default:
+		return SegmentLost
+	}
+}
+
+// projectLTV returns verified revenue to date plus the revenue expected over
+// the next year: the customer's historical daily spend rate (since their
+// first payment, at least minLTVTenure) times the horizon, weighted by the
+// probability of retaining them (1 - churn score).
+func projectLTV(in store.SegmentInputs, now time.Time) float64 {
+	if in.Revenue <= 0 || in.FirstPaymentAt == nil {
+		return 0
+	}
+	tenure := now.Sub(*in.FirstPaymentAt)
+	if tenure < minLTVTenure {
+		tenure = minLTVTenure
+	}
+	retention := defaultRetention
+	if in.ChurnScore != nil {
+		retention = math.Max(0, math.Min(1, 1-*in.ChurnScore))
+	}
+	future := in.Revenue / tenure.Hours() * ltvHorizon.Hours() * retention
+	return round4(in.Revenue + future)
+}
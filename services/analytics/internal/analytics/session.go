@@ -0,0 +1,223 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// sessionIngestLag keeps the sessionizer behind the newest request logs so
+// that batches still being inserted are not skipped by the cursor.
+const sessionIngestLag = time.Minute
+
+// sessionChunk is how much request history is sessionized per query, and
+// maxSessionChunks bounds one run so a large backfill spreads over runs.
+const (
+	sessionChunk     = 24 * time.Hour
+	maxSessionChunks = 31
+)
+
+// maxSessionTools caps the stored tool sequence of a single session.
+const maxSessionTools = 100
+
+// Sessionizer periodically splits each customer's requests into sessions by
+// inactivity gap and stores session aggregates. It works incrementally from
+// a per-agent cursor, extending a customer's last session when new requests
+// arrive within the gap.
+type Sessionizer struct {
+	store    *store.Store
+	logger   *zap.Logger
+	interval time.Duration
+	gap      time.Duration
+	stopCh   chan struct{}
+}
+
+// NewSessionizer creates a new Sessionizer.
+func NewSessionizer(s *store.Store, logger *zap.Logger, interval, gap time.Duration) *Sessionizer {
+	return &Sessionizer{
+		store:    s,
+		logger:   logger,
+		interval: interval,
+		gap:      gap,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins the periodic sessionization loop in a background goroutine.
+func (sz *Sessionizer) Start() {
+	go func() {
+		ticker := time.NewTicker(sz.interval)
+		defer ticker.Stop()
+
+		sz.logger.Info("sessionizer started",
+			zap.Duration("interval", sz.interval),
+			zap.Duration("gap", sz.gap),
+		)
+
+		sz.Calculate()
+
+		for {
+			select {
+			case <-ticker.C:
+				sz.Calculate()
+			case <-sz.stopCh:
+				sz.logger.Info("sessionizer stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop signals the sessionizer to stop.
+func (sz *Sessionizer) Stop() {
+	close(sz.stopCh)
+}
+
+// Calculate sessionizes new request logs for all active agents.
+func (sz *Sessionizer) Calculate() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	agentIDs, err := sz.store.ListAllActiveAgentIDs(ctx)
+	if err != nil {
+		sz.logger.Error("failed to list active agents for sessionization", zap.Error(err))
+		return
+	}
+
+	total := 0
+	for _, agentID := range agentIDs {
+		n, err := sz.sessionizeAgent(ctx, agentID)
+		if err != nil {
+			sz.logger.Warn("failed to sessionize agent",
+				zap.String("agent_id", agentID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		total += n
+	}
+
+	sz.logger.Info("sessionization complete",
+		zap.Int("total_agents", len(agentIDs)),
+		zap.Int("sessions_updated", total),
+	)
+}
+
+// sessionizeAgent processes the agent's request logs after its cursor and
+// returns the number of sessions written.
+func (sz *Sessionizer) sessionizeAgent(ctx context.Context, agentDBID uuid.UUID) (int, error) {
+	from, ok, err := sz.store.GetSessionCursor(ctx, agentDBID)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		first, err := sz.store.GetFirstRequestTime(ctx, agentDBID)
+		if err != nil || first == nil {
+			return 0, err
+		}
+		// The log query is exclusive of from; step back below Postgres'
+		// microsecond resolution to include the first request.
+		from = first.Add(-time.Microsecond)
+	}
+
+	until := time.Now().Add(-sessionIngestLag)
+	written := 0
+	for chunk := 0; chunk < maxSessionChunks && from.Before(until); chunk++ {
+		to := from.Add(sessionChunk)
+		if to.After(until) {
+			to = until
+		}
+
+		logs, err := sz.store.GetSessionLogs(ctx, agentDBID, from, to)
+		if err != nil {
+			return written, err
+		}
+
+		var sessions []store.CustomerSession
+		if len(logs) > 0 {
+			var customers []string
+			for i, l := range logs {
+				if i == 0 || l.CustomerID != logs[i-1].CustomerID {
+					customers = append(customers, l.CustomerID)
+				}
+			}
+			open, err := sz.store.GetLatestSessions(ctx, agentDBID, customers, from.Add(-sz.gap))
+			if err != nil {
+				return written, err
+			}
+
+			// Logs are ordered by customer, so each customer is a contiguous run.
+			start := 0
+			for i := 1; i <= len(logs); i++ {
+				if i < len(logs) && logs[i].CustomerID == logs[start].CustomerID {
+					continue
+				}
+				sessions = append(sessions, buildSessions(open[logs[start].CustomerID], logs[start:i], sz.gap)...)
+				start = i
+			}
+		}
+
+		if err := sz.store.SaveSessions(ctx, agentDBID, sessions, to); err != nil {
+			return written, err
+		}
+		written += len(sessions)
+		from = to
+	}
+
+	return written, nil
+}
+
+// buildSessions folds one customer's time-ordered logs into sessions. When
+// the first log falls within gap of the customer's open session, that
+// session is extended (and returned with its original start so it is
+// updated in place); otherwise a new session starts.
+func buildSessions(open *store.CustomerSession, logs []store.SessionLog, gap time.Duration) []store.CustomerSession {
+	var sessions []store.CustomerSession
+	var cur *store.CustomerSession
+	if open != nil && len(logs) > 0 && logs[0].CreatedAt.Sub(open.EndedAt) <= gap {
+		extended := *open
+		extended.ToolSequence = append([]string(nil), open.ToolSequence...)
+		cur = &extended
+	}
+
+	for _, l := range logs {
+		if cur != nil && l.CreatedAt.Sub(cur.EndedAt) > gap {
+			sessions = append(sessions, *cur)
+			cur = nil
+		}
+		if cur == nil {
+			cur = &store.CustomerSession{
+				CustomerID:   l.CustomerID,
+				StartedAt:    l.CreatedAt,
+				ToolSequence: []string{},
+			}
+		}
+
+		cur.EndedAt = l.CreatedAt
+		cur.RequestCount++
+		isError := l.StatusCode >= 400 && l.StatusCode != 402
+		if isError {
+			cur.ErrorCount++
+		}
+		cur.EndedInError = isError
+		if l.X402Amount != nil && *l.X402Amount > 0 {
+			cur.PaidRequests++
+			cur.PaidAmount += *l.X402Amount
+		}
+		if l.ToolName != nil && *l.ToolName != "" {
+			seq := cur.ToolSequence
+			if (len(seq) == 0 || seq[len(seq)-1] != *l.ToolName) && len(seq) < maxSessionTools {
+				cur.ToolSequence = append(seq, *l.ToolName)
+			}
+		}
+	}
+
+	if cur != nil {
+		sessions = append(sessions, *cur)
+	}
+	return sessions
+}
@@ -1,6 +1,15 @@
 package config
 
-import "github.com/spf13/viper"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/GT8004/gt8004-common/clientip"
+	"github.com/GT8004/gt8004-common/ratelimit"
+)
 
 type Config struct {
 	Port              int    `mapstructure:"PORT"`
@@ -12,11 +21,45 @@ type Config struct {
 	IngestBufferSize  int    `mapstructure:"INGEST_BUFFER_SIZE"`
 	BenchmarkInterval  int    `mapstructure:"BENCHMARK_INTERVAL"`
 	ReputationInterval int    `mapstructure:"REPUTATION_INTERVAL"`
+	ChurnInterval      int    `mapstructure:"CHURN_INTERVAL"`
+	SessionInterval    int    `mapstructure:"SESSION_INTERVAL"`
+	// SessionGapMinutes is the inactivity gap that ends a customer session.
+	SessionGapMinutes int `mapstructure:"SESSION_GAP_MINUTES"`
+	// BenchmarkWeights maps category (or "default") to component weights,
+	// parsed from BENCHMARK_WEIGHTS JSON.
+	BenchmarkWeights map[string]map[string]float64 `mapstructure:"-"`
+	// PeerMinGroupSize is the minimum number of category peers with data
+	// before peer percentiles are reported.
+	PeerMinGroupSize int `mapstructure:"PEER_MIN_GROUP_SIZE"`
 	MaxBodySizeBytes  int    `mapstructure:"MAX_BODY_SIZE_BYTES"`
 	BodyRetentionDays int    `mapstructure:"BODY_RETENTION_DAYS"`
 	GeoIPDBPath       string `mapstructure:"GEOIP_DB_PATH"`
 	RegistryURL       string `mapstructure:"REGISTRY_URL"`
 	NetworkMode       string `mapstructure:"NETWORK_MODE"`
+
+	// Data exports
+	ExportDir            string `mapstructure:"EXPORT_DIR"`
+	ExportSigningSecret  string `mapstructure:"EXPORT_SIGNING_SECRET"`
+	ExportURLTTLSeconds  int    `mapstructure:"EXPORT_URL_TTL_SECONDS"`
+	ExportRetentionHours int    `mapstructure:"EXPORT_RETENTION_HOURS"`
+
+	// Wallet sessions: SessionSecret verifies session tokens issued by the
+	// registry. The raw X-Wallet-Address header is accepted until
+	// WalletHeaderAuthUntil; unset rejects it.
+	SessionSecret         string    `mapstructure:"SESSION_SECRET"`
+	WalletHeaderAuthUntil time.Time `mapstructure:"WALLET_HEADER_AUTH_UNTIL"`
+
+	// Rate limits per minute: RateLimitCaller per API key, session wallet
+	// or IP, RateLimitIP per IP whatever the caller presents,
+	// RateLimitAgentTiers per agent by its tier. Zero disables a limit.
+	RateLimitCaller     int                        `mapstructure:"RATE_LIMIT_CALLER_PER_MIN"`
+	RateLimitIP         int                        `mapstructure:"RATE_LIMIT_IP_PER_MIN"`
+	RateLimitAgentTiers map[string]ratelimit.Limit `mapstructure:"-"`
+
+	// TrustedProxies are the proxies (addresses or CIDR ranges) whose
+	// X-Forwarded-For entries are believed when reading the client IP for
+	// API key allowlists and per-IP rate limits.
+	TrustedProxies []string `mapstructure:"-"`
 }
 
 // ChainIDs returns the chain IDs for the current network mode.
@@ -37,9 +80,20 @@ func Load() (*Config, error) {
 	viper.SetDefault("INGEST_BUFFER_SIZE", 1000)
 	viper.SetDefault("BENCHMARK_INTERVAL", 300)
 	viper.SetDefault("REPUTATION_INTERVAL", 600)
+	viper.SetDefault("CHURN_INTERVAL", 3600)
+	viper.SetDefault("SESSION_INTERVAL", 300)
+	viper.SetDefault("SESSION_GAP_MINUTES", 30)
+	viper.SetDefault("PEER_MIN_GROUP_SIZE", 5)
 	viper.SetDefault("MAX_BODY_SIZE_BYTES", 51200)
 	viper.SetDefault("BODY_RETENTION_DAYS", 30)
 	viper.SetDefault("NETWORK_MODE", "testnet")
+	viper.SetDefault("EXPORT_DIR", "/tmp/gt8004-exports")
+	viper.SetDefault("EXPORT_URL_TTL_SECONDS", 900)
+	viper.SetDefault("EXPORT_RETENTION_HOURS", 72)
+	viper.SetDefault("RATE_LIMIT_CALLER_PER_MIN", 600)
+	viper.SetDefault("RATE_LIMIT_IP_PER_MIN", 1200)
+	viper.SetDefault("RATE_LIMIT_AGENT_TIERS", "open=300,lite=1200")
+	viper.SetDefault("TRUSTED_PROXIES", clientip.CloudRun)
 
 	cfg := &Config{}
 	cfg.Port = viper.GetInt("PORT")
@@ -51,11 +105,44 @@ func Load() (*Config, error) {
 	cfg.IngestBufferSize = viper.GetInt("INGEST_BUFFER_SIZE")
 	cfg.BenchmarkInterval = viper.GetInt("BENCHMARK_INTERVAL")
 	cfg.ReputationInterval = viper.GetInt("REPUTATION_INTERVAL")
+	cfg.ChurnInterval = viper.GetInt("CHURN_INTERVAL")
+	cfg.SessionInterval = viper.GetInt("SESSION_INTERVAL")
+	cfg.SessionGapMinutes = viper.GetInt("SESSION_GAP_MINUTES")
+	if raw := viper.GetString("BENCHMARK_WEIGHTS"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.BenchmarkWeights); err != nil {
+			return nil, fmt.Errorf("parse BENCHMARK_WEIGHTS: %w", err)
+		}
+	}
+	cfg.PeerMinGroupSize = viper.GetInt("PEER_MIN_GROUP_SIZE")
 	cfg.MaxBodySizeBytes = viper.GetInt("MAX_BODY_SIZE_BYTES")
 	cfg.BodyRetentionDays = viper.GetInt("BODY_RETENTION_DAYS")
 	cfg.GeoIPDBPath = viper.GetString("GEOIP_DB_PATH")
 	cfg.RegistryURL = viper.GetString("REGISTRY_URL")
 	cfg.NetworkMode = viper.GetString("NETWORK_MODE")
+	cfg.ExportDir = viper.GetString("EXPORT_DIR")
+	cfg.ExportSigningSecret = viper.GetString("EXPORT_SIGNING_SECRET")
+	cfg.ExportURLTTLSeconds = viper.GetInt("EXPORT_URL_TTL_SECONDS")
+	cfg.ExportRetentionHours = viper.GetInt("EXPORT_RETENTION_HOURS")
+	cfg.SessionSecret = viper.GetString("SESSION_SECRET")
+	if until := viper.GetString("WALLET_HEADER_AUTH_UNTIL"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WALLET_HEADER_AUTH_UNTIL: %w", err)
+		}
+		cfg.WalletHeaderAuthUntil = t
+	}
+	cfg.RateLimitCaller = viper.GetInt("RATE_LIMIT_CALLER_PER_MIN")
+	cfg.RateLimitIP = viper.GetInt("RATE_LIMIT_IP_PER_MIN")
+	agentTiers, err := ratelimit.ParseTiers(viper.GetString("RATE_LIMIT_AGENT_TIERS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_AGENT_TIERS: %w", err)
+	}
+	cfg.RateLimitAgentTiers = agentTiers
+	trustedProxies, err := clientip.ParseProxies(viper.GetString("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	cfg.TrustedProxies = trustedProxies
 
 	return cfg, nil
 }
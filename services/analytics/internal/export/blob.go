@@ -0,0 +1,105 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BlobStore persists finished export files. Implementations must allow
+// streaming writes so exports never need to be held in memory.
+type BlobStore interface {
+	// Create opens a writer for key. The blob becomes visible on Close.
+	Create(ctx context.Context, key string) (io.WriteCloser, error)
+	// Open returns a reader for key and its size in bytes.
+	Open(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalStore is a BlobStore backed by a directory on the local filesystem.
+type LocalStore struct {
+	dir string
+}
+
+// NewLocalStore creates the directory if needed and returns a LocalStore.
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create export dir: %w", err)
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+func (l *LocalStore) path(key string) (string, error) {
+	p := filepath.Join(l.dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(p, filepath.Clean(l.dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return p, nil
+}
+
+// Create writes to a temp file and renames it into place on Close, so a
+// crashed export never leaves a truncated file under the final key.
+func (l *LocalStore) Create(_ context.Context, key string) (io.WriteCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return nil, fmt.Errorf("create blob dir: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("create blob: %w", err)
+	}
+	return &localWriter{File: f, final: p}, nil
+}
+
+func (l *LocalStore) Open(_ context.Context, key string) (io.ReadCloser, int64, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, 0, fmt.Errorf("open blob: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, fmt.Errorf("stat blob: %w", err)
+	}
+	return f, info.Size(), nil
+}
+
+func (l *LocalStore) Delete(_ context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("delete blob: %w", err)
+	}
+	return nil
+}
+
+type localWriter struct {
+	*os.File
+	final string
+}
+
+func (w *localWriter) Close() error {
+	tmp := w.File.Name()
+	if err := w.File.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("close blob: %w", err)
+	}
+	if err := os.Rename(tmp, w.final); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("commit blob: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package export_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GT8004/gt8004-analytics/internal/export"
+)
+
+func TestLocalStore_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	blobs, err := export.NewLocalStore(filepath.Join(dir, "exports"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	w, err := blobs.Create(ctx, "agent/job.csv")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := io.WriteString(w, "id\n1\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, _, err := blobs.Open(ctx, "agent/job.csv"); err == nil {
+		t.Error("expected blob to be invisible before Close")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	r, size, err := blobs.Open(ctx, "agent/job.csv")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "id\n1\n" || size != int64(len(data)) {
+		t.Errorf("expected 5 bytes of content, got %q (size %d)", data, size)
+	}
+
+	if err := blobs.Delete(ctx, "agent/job.csv"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := blobs.Delete(ctx, "agent/job.csv"); err != nil {
+		t.Errorf("expected deleting a missing blob to succeed, got %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "exports", "agent"))
+	if len(entries) != 0 {
+		t.Errorf("expected no leftover files, got %d", len(entries))
+	}
+}
+
+func TestLocalStore_RejectsEscapingKeys(t *testing.T) {
+	ctx := context.Background()
+	blobs, err := export.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	tests := []string{"../outside.csv", "a/../../outside.csv", "", "."}
+	for _, key := range tests {
+		t.Run(key, func(t *testing.T) {
+			if _, err := blobs.Create(ctx, key); err == nil {
+				t.Errorf("expected create %q to fail", key)
+			}
+			if _, _, err := blobs.Open(ctx, key); err == nil {
+				t.Errorf("expected open %q to fail", key)
+			}
+			if err := blobs.Delete(ctx, key); err == nil {
+				t.Errorf("expected delete %q to fail", key)
+			}
+		})
+	}
+}
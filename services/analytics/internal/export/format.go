@@ -0,0 +1,258 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// Supported export formats.
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// parquetRowGroupSize bounds how many rows the parquet writer buffers in
+// memory before flushing a row group.
+const parquetRowGroupSize = 10000
+
+// ValidFormat reports whether f is a supported export format.
+func ValidFormat(f string) bool {
+	return f == FormatCSV || f == FormatNDJSON || f == FormatParquet
+}
+
+// ContentType returns the MIME type for an export format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// rowWriter encodes rows of a fixed column set to an output stream.
+type rowWriter interface {
+	Write(row []any) error
+	Close() error
+}
+
+func newRowWriter(format string, w io.Writer, cols []store.ExportColumn) (rowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, cols)
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w), cols: cols}, nil
+	case FormatParquet:
+		return newParquetWriter(w, cols), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// normalize converts a database value to the Go type for the column kind.
+func normalize(v any, typ string) any {
+	if v == nil {
+		return nil
+	}
+	switch typ {
+	case "int":
+		switch n := v.(type) {
+		case int64:
+			return n
+		case int32:
+			return int64(n)
+		case int16:
+			return int64(n)
+		}
+	case "float":
+		switch n := v.(type) {
+		case float64:
+			return n
+		case float32:
+			return float64(n)
+		}
+	case "time":
+		if t, ok := v.(time.Time); ok {
+			return t.UTC()
+		}
+	case "bool":
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// ---------- CSV ----------
+
+type csvWriter struct {
+	w    *csv.Writer
+	cols []store.ExportColumn
+	rec  []string
+}
+
+func newCSVWriter(w io.Writer, cols []store.ExportColumn) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), cols: cols, rec: make([]string, len(cols))}
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.Name
+	}
+	if err := cw.w.Write(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (c *csvWriter) Write(row []any) error {
+	for i, col := range c.cols {
+		switch v := normalize(row[i], col.Type).(type) {
+		case nil:
+			c.rec[i] = ""
+		case int64:
+			c.rec[i] = strconv.FormatInt(v, 10)
+		case float64:
+			c.rec[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			c.rec[i] = strconv.FormatBool(v)
+		case time.Time:
+			c.rec[i] = v.Format(time.RFC3339Nano)
+		case string:
+			c.rec[i] = v
+		}
+	}
+	return c.w.Write(c.rec)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ---------- NDJSON ----------
+
+type ndjsonWriter struct {
+	enc  *json.Encoder
+	cols []store.ExportColumn
+}
+
+func (n *ndjsonWriter) Write(row []any) error {
+	obj := make(map[string]any, len(n.cols))
+	for i, col := range n.cols {
+		obj[col.Name] = normalize(row[i], col.Type)
+	}
+	return n.enc.Encode(obj)
+}
+
+func (n *ndjsonWriter) Close() error { return nil }
+
+// ---------- Parquet ----------
+
+type parquetWriter struct {
+	w    *parquet.Writer
+	cols []store.ExportColumn
+	// leaf maps output column i to its parquet leaf index; parquet.Group
+	// orders leaves by name, not declaration order.
+	leaf []int
+	buf  []parquet.Row
+}
+
+func newParquetWriter(w io.Writer, cols []store.ExportColumn) *parquetWriter {
+	group := parquet.Group{}
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		var node parquet.Node
+		switch c.Type {
+		case "int":
+			node = parquet.Int(64)
+		case "float":
+			node = parquet.Leaf(parquet.DoubleType)
+		case "bool":
+			node = parquet.Leaf(parquet.BooleanType)
+		case "time":
+			node = parquet.Timestamp(parquet.Millisecond)
+		default:
+			node = parquet.String()
+		}
+		group[c.Name] = parquet.Optional(node)
+		names[i] = c.Name
+	}
+
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	pos := make(map[string]int, len(sorted))
+	for i, n := range sorted {
+		pos[n] = i
+	}
+	leaf := make([]int, len(cols))
+	for i, n := range names {
+		leaf[i] = pos[n]
+	}
+
+	schema := parquet.NewSchema("export", group)
+	return &parquetWriter{
+		w: parquet.NewWriter(w, schema,
+			parquet.MaxRowsPerRowGroup(parquetRowGroupSize),
+			parquet.Compression(&parquet.Snappy),
+		),
+		cols: cols,
+		leaf: leaf,
+	}
+}
+
+func (p *parquetWriter) Write(row []any) error {
+	out := make(parquet.Row, len(p.cols))
+	for i, col := range p.cols {
+		var v parquet.Value
+		switch n := normalize(row[i], col.Type).(type) {
+		case nil:
+			out[p.leaf[i]] = parquet.NullValue().Level(0, 0, p.leaf[i])
+			continue
+		case int64:
+			v = parquet.Int64Value(n)
+		case float64:
+			v = parquet.DoubleValue(n)
+		case bool:
+			v = parquet.BooleanValue(n)
+		case time.Time:
+			v = parquet.Int64Value(n.UnixMilli())
+		case string:
+			v = parquet.ByteArrayValue([]byte(n))
+		}
+		out[p.leaf[i]] = v.Level(0, 1, p.leaf[i])
+	}
+	p.buf = append(p.buf, out)
+	if len(p.buf) >= 1000 {
+		return p.flush()
+	}
+	return nil
+}
+
+func (p *parquetWriter) flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	if _, err := p.w.WriteRows(p.buf); err != nil {
+		return err
+	}
+	p.buf = p.buf[:0]
+	return nil
+}
+
+func (p *parquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	return p.w.Close()
+}
@@ -0,0 +1,205 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+var testColumns = []store.ExportColumn{
+	{Name: "id", Type: "int"},
+	{Name: "at", Type: "time"},
+	{Name: "tool", Type: "string"},
+	{Name: "ms", Type: "float"},
+	{Name: "paid", Type: "bool"},
+}
+
+var testAt = time.Date(2026, 5, 1, 12, 30, 0, 250_000_000, time.FixedZone("KST", 9*3600))
+
+var testRows = [][]any{
+	{int64(1), testAt, "search", float64(12.5), true},
+	{int32(2), testAt, "sum,\"quoted\"", float32(0.25), false},
+	{int16(3), nil, nil, nil, nil},
+}
+
+func writeRows(t *testing.T, format string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := newRowWriter(format, &buf, testColumns)
+	if err != nil {
+		t.Fatalf("new %s writer: %v", format, err)
+	}
+	for _, row := range testRows {
+		if err := w.Write(row); err != nil {
+			t.Fatalf("write %s row: %v", format, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close %s writer: %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		typ  string
+		want any
+	}{
+		{"nil", nil, "int", nil},
+		{"int64", int64(7), "int", int64(7)},
+		{"int32", int32(7), "int", int64(7)},
+		{"int16", int16(7), "int", int64(7)},
+		{"float32", float32(1.5), "float", float64(1.5)},
+		{"float64", 2.25, "float", 2.25},
+		{"time to utc", testAt, "time", testAt.UTC()},
+		{"bool", true, "bool", true},
+		{"string", "x", "string", "x"},
+		{"mismatched kind falls back to string", int64(9), "string", "9"},
+		{"unexpected int type", uint8(4), "int", "4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalize(tt.v, tt.typ)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewRowWriter_UnsupportedFormat(t *testing.T) {
+	if _, err := newRowWriter("xlsx", io.Discard, testColumns); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewReader(writeRows(t, FormatCSV))).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+
+	want := [][]string{
+		{"id", "at", "tool", "ms", "paid"},
+		{"1", "2026-05-01T03:30:00.25Z", "search", "12.5", "true"},
+		{"2", "2026-05-01T03:30:00.25Z", "sum,\"quoted\"", "0.25", "false"},
+		{"3", "", "", "", ""},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("expected %q, got %q", want, records)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(string(writeRows(t, FormatNDJSON))), "\n")
+	if len(lines) != len(testRows) {
+		t.Fatalf("expected %d lines, got %d", len(testRows), len(lines))
+	}
+
+	want := []map[string]any{
+		{"id": 1.0, "at": "2026-05-01T03:30:00.25Z", "tool": "search", "ms": 12.5, "paid": true},
+		{"id": 2.0, "at": "2026-05-01T03:30:00.25Z", "tool": "sum,\"quoted\"", "ms": 0.25, "paid": false},
+		{"id": 3.0, "at": nil, "tool": nil, "ms": nil, "paid": nil},
+	}
+	for i, line := range lines {
+		var got map[string]any
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("line %d: expected %v, got %v", i, want[i], got)
+		}
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	type row struct {
+		ID   *int64   `parquet:"id,optional"`
+		At   *int64   `parquet:"at,optional"` // Unix milliseconds
+		Tool *string  `parquet:"tool,optional"`
+		MS   *float64 `parquet:"ms,optional"`
+		Paid *bool    `parquet:"paid,optional"`
+	}
+
+	data := writeRows(t, FormatParquet)
+	got, err := parquet.Read[row](bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("read parquet: %v", err)
+	}
+	if len(got) != len(testRows) {
+		t.Fatalf("expected %d rows, got %d", len(testRows), len(got))
+	}
+
+	tests := []struct {
+		name string
+		got  row
+		id   int64
+		tool string
+		ms   float64
+		paid bool
+		null bool
+	}{
+		{name: "row 1", got: got[0], id: 1, tool: "search", ms: 12.5, paid: true},
+		{name: "row 2", got: got[1], id: 2, tool: "sum,\"quoted\"", ms: 0.25, paid: false},
+		{name: "nulls", got: got[2], id: 3, null: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got.ID == nil || *tt.got.ID != tt.id {
+				t.Errorf("expected id %d, got %v", tt.id, tt.got.ID)
+			}
+			if tt.null {
+				if tt.got.At != nil || tt.got.Tool != nil || tt.got.MS != nil || tt.got.Paid != nil {
+					t.Errorf("expected nulls, got %+v", tt.got)
+				}
+				return
+			}
+			if tt.got.At == nil || *tt.got.At != testAt.UnixMilli() {
+				t.Errorf("expected at %d, got %v", testAt.UnixMilli(), tt.got.At)
+			}
+			if tt.got.Tool == nil || *tt.got.Tool != tt.tool {
+				t.Errorf("expected tool %q, got %v", tt.tool, tt.got.Tool)
+			}
+			if tt.got.MS == nil || *tt.got.MS != tt.ms {
+				t.Errorf("expected ms %v, got %v", tt.ms, tt.got.MS)
+			}
+			if tt.got.Paid == nil || *tt.got.Paid != tt.paid {
+				t.Errorf("expected paid %v, got %v", tt.paid, tt.got.Paid)
+			}
+		})
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatCSV, "text/csv"},
+		{FormatNDJSON, "application/x-ndjson"},
+		{FormatParquet, "application/vnd.apache.parquet"},
+	}
+	for _, tt := range tests {
+		if !ValidFormat(tt.format) {
+			t.Errorf("expected %s to be valid", tt.format)
+		}
+		if got := ContentType(tt.format); got != tt.want {
+			t.Errorf("expected %s for %s, got %s", tt.want, tt.format, got)
+		}
+	}
+	if ValidFormat("xlsx") {
+		t.Error("expected xlsx to be invalid")
+	}
+}
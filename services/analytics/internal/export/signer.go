@@ -0,0 +1,49 @@
+package export
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Signer issues and verifies time-limited download URLs for export files.
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSigner creates a Signer. URLs it issues are valid for ttl.
+func NewSigner(secret string, ttl time.Duration) *Signer {
+	return &Signer{secret: []byte(secret), ttl: ttl}
+}
+
+func (s *Signer) mac(jobID uuid.UUID, expires int64) string {
+	m := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(m, "%s:%d", jobID, expires)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// DownloadURL returns a signed path for downloading the job's file.
+func (s *Signer) DownloadURL(jobID uuid.UUID) (string, time.Time) {
+	expiresAt := time.Now().Add(s.ttl)
+	exp := expiresAt.Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(exp, 10))
+	q.Set("sig", s.mac(jobID, exp))
+	return fmt.Sprintf("/v1/exports/%s/download?%s", jobID, q.Encode()), expiresAt
+}
+
+// Verify checks a download signature and its expiry.
+func (s *Signer) Verify(jobID uuid.UUID, expires, sig string) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(s.mac(jobID, exp)))
+}
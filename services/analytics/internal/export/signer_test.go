@@ -0,0 +1,75 @@
+package export_test
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/GT8004/gt8004-analytics/internal/export"
+)
+
+func TestSigner_DownloadURL(t *testing.T) {
+	signer := export.NewSigner("secret", time.Hour)
+	jobID := uuid.New()
+
+	path, expiresAt := signer.DownloadURL(jobID)
+	if until := time.Until(expiresAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("expected expiry about an hour out, got %v", until)
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		t.Fatalf("parse %q: %v", path, err)
+	}
+	if want := "/v1/exports/" + jobID.String() + "/download"; u.Path != want {
+		t.Errorf("expected path %s, got %s", want, u.Path)
+	}
+	q := u.Query()
+	if q.Get("expires") != strconv.FormatInt(expiresAt.Unix(), 10) {
+		t.Errorf("expected expires %d, got %s", expiresAt.Unix(), q.Get("expires"))
+	}
+	if !signer.Verify(jobID, q.Get("expires"), q.Get("sig")) {
+		t.Error("expected issued URL to verify")
+	}
+}
+
+func TestSigner_Verify(t *testing.T) {
+	signer := export.NewSigner("secret", time.Hour)
+	jobID := uuid.New()
+	path, _ := signer.DownloadURL(jobID)
+	u, _ := url.Parse(path)
+	expires, sig := u.Query().Get("expires"), u.Query().Get("sig")
+
+	expired := export.NewSigner("secret", -time.Minute)
+	expiredPath, _ := expired.DownloadURL(jobID)
+	eu, _ := url.Parse(expiredPath)
+
+	tests := []struct {
+		name    string
+		signer  *export.Signer
+		jobID   uuid.UUID
+		expires string
+		sig     string
+		want    bool
+	}{
+		{"valid", signer, jobID, expires, sig, true},
+		{"other job", signer, uuid.New(), expires, sig, false},
+		{"other secret", export.NewSigner("other", time.Hour), jobID, expires, sig, false},
+		{"extended expiry", signer, jobID, strconv.FormatInt(time.Now().Add(48*time.Hour).Unix(), 10), sig, false},
+		{"tampered signature", signer, jobID, expires, strings.Repeat("0", len(sig)), false},
+		{"empty signature", signer, jobID, expires, "", false},
+		{"non-numeric expiry", signer, jobID, "soon", sig, false},
+		{"expired", expired, jobID, eu.Query().Get("expires"), eu.Query().Get("sig"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.signer.Verify(tt.jobID, tt.expires, tt.sig); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+const (
+	// batchSize is the number of rows fetched per query while streaming.
+	batchSize = 5000
+	// pollInterval is how often the worker checks for queued jobs when it
+	// has not been notified.
+	pollInterval = 10 * time.Second
+	// maxJobDuration fails running jobs whose instance presumably died.
+	maxJobDuration = time.Hour
+)
+
+// Worker processes queued export jobs one at a time, streaming rows from
+// Postgres in keyset-paginated batches into the blob store.
+type Worker struct {
+	store     *store.Store
+	blobs     BlobStore
+	retention time.Duration
+	logger    *zap.Logger
+	notifyCh  chan struct{}
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+	// ctx is cancelled on Stop so an in-flight export aborts promptly.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewWorker creates an export worker. Finished files are kept for retention.
+func NewWorker(s *store.Store, blobs BlobStore, retention time.Duration, logger *zap.Logger) *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Worker{
+		store:     s,
+		blobs:     blobs,
+		retention: retention,
+		logger:    logger,
+		notifyCh:  make(chan struct{}, 1),
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Notify wakes the worker after a job has been queued.
+func (w *Worker) Notify() {
+	select {
+	case w.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// Start begins processing in a background goroutine.
+func (w *Worker) Start() {
+	go func() {
+		defer close(w.doneCh)
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		w.logger.Info("export worker started", zap.Duration("retention", w.retention))
+
+		for {
+			w.housekeep()
+			w.drain()
+
+			select {
+			case <-ticker.C:
+			case <-w.notifyCh:
+			case <-w.stopCh:
+				w.logger.Info("export worker stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop signals the worker to stop, aborting any in-flight export.
+func (w *Worker) Stop() {
+	close(w.stopCh)
+	w.cancel()
+	<-w.doneCh
+}
+
+// drain processes queued jobs until the queue is empty or the worker stops.
+func (w *Worker) drain() {
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		job, err := w.store.ClaimExportJob(ctx)
+		cancel()
+		if err != nil {
+			w.logger.Error("failed to claim export job", zap.Error(err))
+			return
+		}
+		if job == nil {
+			return
+		}
+		w.process(job)
+	}
+}
+
+func (w *Worker) process(job *store.ExportJob) {
+	ctx, cancel := context.WithTimeout(w.ctx, maxJobDuration)
+	defer cancel()
+
+	logger := w.logger.With(
+		zap.String("job_id", job.ID.String()),
+		zap.String("dataset", job.Dataset),
+		zap.String("format", job.Format),
+	)
+	start := time.Now()
+
+	key := fmt.Sprintf("%s/%s.%s", job.AgentID, job.ID, job.Format)
+	rows, size, err := w.write(ctx, job, key)
+	if err != nil {
+		logger.Error("export failed", zap.Error(err))
+		_ = w.blobs.Delete(context.Background(), key)
+		if ferr := w.store.FailExportJob(context.Background(), job.ID, err.Error()); ferr != nil {
+			logger.Error("failed to record export failure", zap.Error(ferr))
+		}
+		return
+	}
+
+	if err := w.store.CompleteExportJob(ctx, job.ID, rows, size, key, time.Now().Add(w.retention)); err != nil {
+		logger.Error("failed to record export completion", zap.Error(err))
+		return
+	}
+	logger.Info("export complete",
+		zap.Int64("rows", rows),
+		zap.Int64("bytes", size),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+}
+
+// write streams the job's dataset into the blob at key and returns the row
+// count and byte size.
+func (w *Worker) write(ctx context.Context, job *store.ExportJob, key string) (int64, int64, error) {
+	cols := store.ExportColumns(job.Dataset)
+	if cols == nil {
+		return 0, 0, fmt.Errorf("unknown dataset %q", job.Dataset)
+	}
+
+	blob, err := w.blobs.Create(ctx, key)
+	if err != nil {
+		return 0, 0, err
+	}
+	counter := &countingWriter{w: blob}
+
+	rw, err := newRowWriter(job.Format, counter, cols)
+	if err != nil {
+		blob.Close()
+		return 0, 0, err
+	}
+
+	var total int64
+	cursor := ""
+	for {
+		batch, next, err := w.store.FetchExportBatch(ctx, job, cursor, batchSize)
+		if err != nil {
+			blob.Close()
+			return 0, 0, err
+		}
+		for _, row := range batch {
+			if err := rw.Write(row); err != nil {
+				blob.Close()
+				return 0, 0, fmt.Errorf("write row: %w", err)
+			}
+		}
+		total += int64(len(batch))
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if err := rw.Close(); err != nil {
+		blob.Close()
+		return 0, 0, fmt.Errorf("finish %s: %w", job.Format, err)
+	}
+	if err := blob.Close(); err != nil {
+		return 0, 0, err
+	}
+	return total, counter.n, nil
+}
+
+// housekeep fails abandoned jobs and deletes expired files.
+func (w *Worker) housekeep() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if n, err := w.store.FailStaleExportJobs(ctx, maxJobDuration); err != nil {
+		w.logger.Error("failed to fail stale export jobs", zap.Error(err))
+	} else if n > 0 {
+		w.logger.Warn("failed stale export jobs", zap.Int64("count", n))
+	}
+
+	keys, err := w.store.ExpireExportJobs(ctx)
+	if err != nil {
+		w.logger.Error("failed to expire export jobs", zap.Error(err))
+		return
+	}
+	for _, key := range keys {
+		if err := w.blobs.Delete(ctx, key); err != nil {
+			w.logger.Warn("failed to delete expired export", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -15,12 +15,29 @@ import (
 )
 
 // AgentStats handles GET /v1/agents/:agent_id/stats.
+// With group_by=tag:<key> or tag=<key>:<value> filters it returns tag-grouped
+// metrics over the last ?days=30 instead.
 func (h *Handler) AgentStats(c *gin.Context) {
 	dbID, ok := h.resolveOwnedAgent(c)
 	if !ok {
 		return
 	}
 
+	tq, tagged, ok := parseTagQuery(c)
+	if !ok {
+		return
+	}
+	if tagged {
+		days := 30
+		if d := c.Query("days"); d != "" {
+			if v, err := strconv.Atoi(d); err == nil && v > 0 && v <= 365 {
+				days = v
+			}
+		}
+		h.tagBreakdown(c, dbID, "stats", tq, time.Duration(days)*24*time.Hour, time.Minute)
+		return
+	}
+
 	cacheKey := fmt.Sprintf("agent:%s:stats", c.Param("agent_id"))
 
 	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
@@ -40,12 +57,16 @@ func (h *Handler) AgentStats(c *gin.Context) {
 	c.Data(http.StatusOK, "application/json", data)
 }
 
-// AgentDailyStats handles GET /v1/agents/:agent_id/stats/daily.
+// AgentDailyStats handles GET /v1/agents/:agent_id/stats/daily?days=30&tz=
 func (h *Handler) AgentDailyStats(c *gin.Context) {
 	dbID, ok := h.resolveOwnedAgent(c)
 	if !ok {
 		return
 	}
+	loc, ok := h.resolveTimezone(c, dbID)
+	if !ok {
+		return
+	}
 
 	days := 30
 	if d := c.Query("days"); d != "" {
@@ -55,21 +76,29 @@ func (h *Handler) AgentDailyStats(c *gin.Context) {
 		}
 	}
 
-	cacheKey := fmt.Sprintf("agent:%s:daily:%d", c.Param("agent_id"), days)
+	cacheKey := fmt.Sprintf("agent:%s:daily:%d:%s", c.Param("agent_id"), days, loc)
 
 	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
 		c.Data(http.StatusOK, "application/json", cached)
 		return
 	}
 
-	stats, err := h.store.GetDailyStats(c.Request.Context(), dbID, days)
+	stats, err := h.store.GetDailyStats(c.Request.Context(), dbID, days, loc)
 	if err != nil {
 		h.logger.Error("failed to get daily stats", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get daily stats"})
 		return
 	}
 
-	resp := gin.H{"stats": stats}
+	since, _ := store.DayBuckets(loc, days, time.Now())
+	deployments, err := h.dailyDeploymentMarkers(c.Request.Context(), dbID, since, loc)
+	if err != nil {
+		h.logger.Error("failed to list deployments", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get daily stats"})
+		return
+	}
+
+	resp := gin.H{"stats": stats, "timezone": loc.String(), "deployments": deployments}
 	data, _ := json.Marshal(resp)
 	h.cache.Set(c.Request.Context(), cacheKey, data, 60*time.Second)
 	c.Data(http.StatusOK, "application/json", data)
@@ -216,12 +245,16 @@ func (h *Handler) AnalyticsReport(c *gin.Context) {
 	c.Data(http.StatusOK, "application/json", data)
 }
 
-// ConversionFunnel handles GET /v1/agents/:agent_id/funnel?days=30
+// ConversionFunnel handles GET /v1/agents/:agent_id/funnel?days=30&tz=
 func (h *Handler) ConversionFunnel(c *gin.Context) {
 	dbID, ok := h.resolveOwnedAgent(c)
 	if !ok {
 		return
 	}
+	loc, ok := h.resolveTimezone(c, dbID)
+	if !ok {
+		return
+	}
 
 	days := 30
 	if d := c.Query("days"); d != "" {
@@ -230,7 +263,7 @@ func (h *Handler) ConversionFunnel(c *gin.Context) {
 		}
 	}
 
-	cacheKey := fmt.Sprintf("agent:%s:funnel:%d", c.Param("agent_id"), days)
+	cacheKey := fmt.Sprintf("agent:%s:funnel:%d:%s", c.Param("agent_id"), days, loc)
 	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
 		c.Data(http.StatusOK, "application/json", cached)
 		return
@@ -250,7 +283,7 @@ func (h *Handler) ConversionFunnel(c *gin.Context) {
 	})
 	g.Go(func() error {
 		var err error
-		dailyTrend, err = h.store.GetDailyFunnelStats(gctx, dbID, days)
+		dailyTrend, err = h.store.GetDailyFunnelStats(gctx, dbID, days, loc)
 		return err
 	})
 	g.Go(func() error {
@@ -2,12 +2,19 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/analytics"
+	"github.com/GT8004/gt8004-analytics/internal/store"
 )
 
 // GetBenchmark handles GET /v1/benchmark?category=
@@ -46,3 +53,156 @@ func (h *Handler) GetBenchmark(c *gin.Context) {
 	h.cache.Set(c.Request.Context(), cacheKey, data, 5*time.Minute)
 	c.Data(http.StatusOK, "application/json", data)
 }
+
+// benchmarkNeighbor summarizes an adjacent agent on the leaderboard.
+type benchmarkNeighbor struct {
+	AgentStringID string  `json:"agent_string_id"`
+	AgentName     string  `json:"agent_name"`
+	Rank          int     `json:"rank"`
+	Score         float64 `json:"score"`
+	ScoreGap      float64 `json:"score_gap"`
+}
+
+// GetAgentBenchmark handles GET /v1/benchmark/agents/:agent_id
+// Returns the agent's current rank, score breakdown and the agents directly
+// above and below it.
+func (h *Handler) GetAgentBenchmark(c *gin.Context) {
+	agentID := c.Param("agent_id")
+
+	cacheKey := fmt.Sprintf("benchmark:agent:%s", agentID)
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	entry, err := h.store.GetAgentBenchmark(c.Request.Context(), agentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "agent is not ranked"})
+			return
+		}
+		h.logger.Error("failed to get agent benchmark", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get benchmark"})
+		return
+	}
+
+	entries, err := h.store.GetBenchmarkByCategory(c.Request.Context(), entry.Category)
+	if err != nil {
+		h.logger.Error("failed to get benchmark", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get benchmark"})
+		return
+	}
+
+	resp := gin.H{"benchmark": entry, "category_size": len(entries)}
+	for i, e := range entries {
+		if e.AgentID != entry.AgentID {
+			continue
+		}
+		if i > 0 {
+			resp["ahead"] = neighbor(entries[i-1], entry.Score)
+		}
+		if i < len(entries)-1 {
+			resp["behind"] = neighbor(entries[i+1], entry.Score)
+		}
+		break
+	}
+
+	data, _ := json.Marshal(resp)
+	h.cache.Set(c.Request.Context(), cacheKey, data, 5*time.Minute)
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+func neighbor(e store.BenchmarkEntry, score float64) benchmarkNeighbor {
+	return benchmarkNeighbor{
+		AgentStringID: e.AgentStringID,
+		AgentName:     e.AgentName,
+		Rank:          e.Rank,
+		Score:         e.Score,
+		ScoreGap:      math.Round((e.Score-score)*100) / 100,
+	}
+}
+
+// GetAgentBenchmarkHistory handles GET /v1/benchmark/agents/:agent_id/history?days=90
+func (h *Handler) GetAgentBenchmarkHistory(c *gin.Context) {
+	agentID := c.Param("agent_id")
+
+	days := 90
+	if d := c.Query("days"); d != "" {
+		if v, err := strconv.Atoi(d); err == nil && v > 0 && v <= 365 {
+			days = v
+		}
+	}
+
+	cacheKey := fmt.Sprintf("benchmark:agent:%s:history:%d", agentID, days)
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	entry, err := h.store.GetAgentBenchmark(c.Request.Context(), agentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "agent is not ranked"})
+			return
+		}
+		h.logger.Error("failed to get agent benchmark", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get benchmark history"})
+		return
+	}
+
+	history, err := h.store.GetBenchmarkHistory(c.Request.Context(), entry.AgentID, entry.Category, days)
+	if err != nil {
+		h.logger.Error("failed to get benchmark history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get benchmark history"})
+		return
+	}
+
+	resp := gin.H{
+		"agent_string_id": agentID,
+		"category":        entry.Category,
+		"current_rank":    entry.Rank,
+		"rank_change":     entry.RankChange,
+		"history":         history,
+	}
+	data, _ := json.Marshal(resp)
+	h.cache.Set(c.Request.Context(), cacheKey, data, 5*time.Minute)
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// PeerComparison handles GET /v1/agents/:agent_id/peers?days=30
+// Returns the owner's p95 latency, error rate, conversion and ARPU next to
+// anonymized percentiles of the other agents in the same benchmark category.
+func (h *Handler) PeerComparison(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	days := 30
+	if d := c.Query("days"); d != "" {
+		if v, err := strconv.Atoi(d); err == nil && v > 0 && v <= 90 {
+			days = v
+		}
+	}
+
+	cacheKey := fmt.Sprintf("agent:%s:peers:%d", c.Param("agent_id"), days)
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	comparison, err := h.peerAnalytics.GetPeerComparison(c.Request.Context(), dbID, days)
+	if err != nil {
+		if errors.Is(err, analytics.ErrNoCategory) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "agent has no category to compare against"})
+			return
+		}
+		h.logger.Error("failed to get peer comparison", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get peer comparison"})
+		return
+	}
+
+	data, _ := json.Marshal(comparison)
+	h.cache.Set(c.Request.Context(), cacheKey, data, 15*time.Minute)
+	c.Data(http.StatusOK, "application/json", data)
+}
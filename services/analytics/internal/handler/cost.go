@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// maxToolCosts caps the per-tool entries in a cost update.
+const maxToolCosts = 200
+
+// GetCosts handles GET /v1/agents/:agent_id/costs
+func (h *Handler) GetCosts(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	settings, err := h.store.GetCostSettings(c.Request.Context(), dbID)
+	if err != nil {
+		h.logger.Error("failed to get cost settings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get costs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+type toolCostRequest struct {
+	ToolName        string   `json:"tool_name" binding:"required,max=128"`
+	CostPerRequest  float64  `json:"cost_per_request" binding:"gte=0"`
+	InputTokenRate  *float64 `json:"input_token_rate" binding:"omitempty,gte=0"`
+	OutputTokenRate *float64 `json:"output_token_rate" binding:"omitempty,gte=0"`
+}
+
+type updateCostsRequest struct {
+	FixedMonthlyCost float64           `json:"fixed_monthly_cost" binding:"gte=0"`
+	InputTokenRate   float64           `json:"input_token_rate" binding:"gte=0"`
+	OutputTokenRate  float64           `json:"output_token_rate" binding:"gte=0"`
+	Tools            []toolCostRequest `json:"tools" binding:"dive"`
+}
+
+// UpdateCosts handles PUT /v1/agents/:agent_id/costs
+// Replaces the agent's cost inputs. Token rates are per million tokens; a
+// tool's rates override the agent-wide ones for that tool.
+func (h *Handler) UpdateCosts(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	var req updateCostsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if len(req.Tools) > maxToolCosts {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d tools", maxToolCosts)})
+		return
+	}
+
+	settings := &store.CostSettings{
+		FixedMonthlyCost: req.FixedMonthlyCost,
+		InputTokenRate:   req.InputTokenRate,
+		OutputTokenRate:  req.OutputTokenRate,
+		Tools:            make([]store.ToolCost, 0, len(req.Tools)),
+	}
+	seen := make(map[string]bool, len(req.Tools))
+	for _, t := range req.Tools {
+		name := strings.TrimSpace(t.ToolName)
+		if name == "" || seen[name] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tool names must be non-empty and unique"})
+			return
+		}
+		seen[name] = true
+		settings.Tools = append(settings.Tools, store.ToolCost{
+			ToolName:        name,
+			CostPerRequest:  t.CostPerRequest,
+			InputTokenRate:  t.InputTokenRate,
+			OutputTokenRate: t.OutputTokenRate,
+		})
+	}
+
+	if err := h.store.SaveCostSettings(c.Request.Context(), dbID, settings); err != nil {
+		h.logger.Error("failed to save cost settings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update costs"})
+		return
+	}
+	h.cache.DelPattern(c.Request.Context(), fmt.Sprintf("agent:%s:margins:*", c.Param("agent_id")))
+
+	saved, err := h.store.GetCostSettings(c.Request.Context(), dbID)
+	if err != nil {
+		h.logger.Error("failed to get cost settings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get costs"})
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+// MarginReport handles GET /v1/agents/:agent_id/margins?group_by=tool&days=30&limit=100
+// group_by is tool, customer or day; limit caps the customers returned.
+func (h *Handler) MarginReport(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+	loc, ok := h.resolveTimezone(c, dbID)
+	if !ok {
+		return
+	}
+
+	groupBy := c.DefaultQuery("group_by", store.MarginByTool)
+	if groupBy != store.MarginByTool && groupBy != store.MarginByCustomer && groupBy != store.MarginByDay {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be tool, customer or day"})
+		return
+	}
+	days := 30
+	if d := c.Query("days"); d != "" {
+		if v, err := strconv.Atoi(d); err == nil && v > 0 && v <= 365 {
+			days = v
+		}
+	}
+	limit := 100
+	if l := c.Query("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 1000 {
+			limit = v
+		}
+	}
+
+	cacheKey := fmt.Sprintf("agent:%s:margins:%s:%d:%d:%s", c.Param("agent_id"), groupBy, days, limit, loc)
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	report, err := h.revenueAnalytics.GetMarginReport(c.Request.Context(), dbID, groupBy, days, limit, loc)
+	if err != nil {
+		h.logger.Error("failed to get margin report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get margin report"})
+		return
+	}
+
+	data, _ := json.Marshal(report)
+	h.cache.Set(c.Request.Context(), cacheKey, data, 5*time.Minute)
+	c.Data(http.StatusOK, "application/json", data)
+}
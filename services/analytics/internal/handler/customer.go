@@ -183,3 +183,97 @@ func (h *Handler) CustomerDailyStats(c *gin.Context) {
 	h.cache.Set(c.Request.Context(), cacheKey, data, 30*time.Second)
 	c.Data(http.StatusOK, "application/json", data)
 }
+
+// CustomerCohorts handles GET /v1/agents/:agent_id/customers/cohorts?granularity=monthly&periods=12&revenue=true.
+func (h *Handler) CustomerCohorts(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	granularity := c.DefaultQuery("granularity", "monthly")
+	if granularity != "monthly" && granularity != "weekly" {
+		granularity = "monthly"
+	}
+
+	maxPeriods := 24
+	if granularity == "weekly" {
+		maxPeriods = 52
+	}
+	periods := 12
+	if p := c.Query("periods"); p != "" {
+		if v, err := strconv.Atoi(p); err == nil && v > 0 && v <= maxPeriods {
+			periods = v
+		}
+	}
+
+	includeRevenue := c.Query("revenue") == "true"
+
+	cacheKey := fmt.Sprintf("agent:%s:cohorts:%s:%d:%t", c.Param("agent_id"), granularity, periods, includeRevenue)
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	report, err := h.customerAnalytics.GetCohortAnalysis(c.Request.Context(), dbID, granularity, periods, includeRevenue)
+	if err != nil {
+		h.logger.Error("failed to get cohort analysis", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get cohort analysis"})
+		return
+	}
+
+	data, _ := json.Marshal(report)
+	h.cache.Set(c.Request.Context(), cacheKey, data, 5*time.Minute)
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// AtRiskCustomers handles GET /v1/agents/:agent_id/customers/at-risk?risk=medium&limit=50&offset=0.
+// risk selects the minimum label (medium or high); min_score overrides it.
+func (h *Handler) AtRiskCustomers(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	minScore := 0.4
+	if c.Query("risk") == "high" {
+		minScore = 0.7
+	}
+	if s := c.Query("min_score"); s != "" {
+		if v, err := strconv.ParseFloat(s, 64); err == nil && v >= 0 && v <= 1 {
+			minScore = v
+		}
+	}
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 200 {
+			limit = v
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if v, err := strconv.Atoi(o); err == nil && v >= 0 {
+			offset = v
+		}
+	}
+
+	cacheKey := fmt.Sprintf("agent:%s:customers:at-risk:%g:%d:%d", c.Param("agent_id"), minScore, limit, offset)
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	customers, total, err := h.store.GetAtRiskCustomers(c.Request.Context(), dbID, minScore, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to get at-risk customers", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get at-risk customers"})
+		return
+	}
+
+	resp := gin.H{"customers": customers, "total": total, "min_score": minScore}
+	data, _ := json.Marshal(resp)
+	h.cache.Set(c.Request.Context(), cacheKey, data, time.Minute)
+	c.Data(http.StatusOK, "application/json", data)
+}
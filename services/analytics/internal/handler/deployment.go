@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/analytics"
+)
+
+// maxDeploymentWindowHours bounds the comparison window on each side of a
+// deployment.
+const maxDeploymentWindowHours = 168
+
+// ListDeployments handles GET /v1/agents/:agent_id/deployments?days=90
+func (h *Handler) ListDeployments(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	days := 90
+	if d := c.Query("days"); d != "" {
+		if v, err := strconv.Atoi(d); err == nil && v > 0 && v <= 365 {
+			days = v
+		}
+	}
+
+	now := time.Now()
+	deployments, err := h.store.ListDeployments(c.Request.Context(), dbID, now.AddDate(0, 0, -days), now, 500)
+	if err != nil {
+		h.logger.Error("failed to list deployments", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list deployments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deployments": deployments, "total": len(deployments)})
+}
+
+// DeploymentComparison handles GET /v1/agents/:agent_id/deployments/:deployment_id/compare?window=24h
+func (h *Handler) DeploymentComparison(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	deploymentID, err := uuid.Parse(c.Param("deployment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid deployment_id"})
+		return
+	}
+
+	windowHours := 24
+	if w := c.Query("window"); w != "" {
+		windowHours = parseWindowHours(w)
+	}
+	if windowHours > maxDeploymentWindowHours {
+		windowHours = maxDeploymentWindowHours
+	}
+
+	cacheKey := fmt.Sprintf("agent:%s:deployments:%s:%d", c.Param("agent_id"), deploymentID, windowHours)
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	d, err := h.store.GetDeployment(c.Request.Context(), dbID, deploymentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "deployment not found"})
+			return
+		}
+		h.logger.Error("failed to get deployment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compare deployment"})
+		return
+	}
+
+	result, err := h.perfAnalytics.CompareDeployment(c.Request.Context(), d, windowHours)
+	if err != nil {
+		h.logger.Error("failed to compare deployment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compare deployment"})
+		return
+	}
+
+	data, _ := json.Marshal(result)
+	h.cache.Set(c.Request.Context(), cacheKey, data, 60*time.Second)
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// dailyDeploymentMarkers returns the agent's deployments since since, oldest
+// first, labelled with their local date in loc.
+func (h *Handler) dailyDeploymentMarkers(ctx context.Context, agentDBID uuid.UUID, since time.Time, loc *time.Location) ([]analytics.DeploymentMarker, error) {
+	deployments, err := h.store.ListDeployments(ctx, agentDBID, since, time.Now(), 500)
+	if err != nil {
+		return nil, err
+	}
+	markers := make([]analytics.DeploymentMarker, 0, len(deployments))
+	for i := len(deployments) - 1; i >= 0; i-- {
+		markers = append(markers, analytics.NewDeploymentMarker(deployments[i], deployments[i].DeployedAt.In(loc).Format("2006-01-02")))
+	}
+	return markers, nil
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/export"
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// downloadWriteTimeout bounds how long a single export download may stream.
+const downloadWriteTimeout = 30 * time.Minute
+
+type CreateExportRequest struct {
+	Dataset string `json:"dataset" binding:"required"`
+	Format  string `json:"format" binding:"required"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	// Filters takes the same keys as the log search query params
+	// (status, tool, protocol, ip, country, paid, ...).
+	Filters map[string]string `json:"filters"`
+}
+
+// exportDatasetFilters lists the filter keys the customers and revenue
+// datasets honour; logs take every log search filter.
+var exportDatasetFilters = map[string]map[string]bool{
+	store.ExportDatasetCustomers: {"ip": true, "country": true},
+	store.ExportDatasetRevenue:   {"ip": true, "tool": true},
+}
+
+// exportResponse adds a signed download URL to completed jobs.
+type exportResponse struct {
+	*store.ExportJob
+	DownloadURL          string     `json:"download_url,omitempty"`
+	DownloadURLExpiresAt *time.Time `json:"download_url_expires_at,omitempty"`
+}
+
+func (h *Handler) exportResponse(job *store.ExportJob) exportResponse {
+	resp := exportResponse{ExportJob: job}
+	if job.Status == store.ExportStatusCompleted {
+		url, exp := h.exportSigner.DownloadURL(job.ID)
+		resp.DownloadURL = url
+		resp.DownloadURLExpiresAt = &exp
+	}
+	return resp
+}
+
+// CreateExport handles POST /v1/agents/:agent_id/exports
+// Queues an export job and returns 202 with the job; poll GetExport for status.
+func (h *Handler) CreateExport(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	var req CreateExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if store.ExportColumns(req.Dataset) == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dataset: must be logs, customers or revenue"})
+		return
+	}
+	if !export.ValidFormat(req.Format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format: must be csv, ndjson or parquet"})
+		return
+	}
+
+	if allowed, ok := exportDatasetFilters[req.Dataset]; ok {
+		keys := make([]string, 0, len(req.Filters))
+		for key, v := range req.Filters {
+			if v != "" && !allowed[key] {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			sort.Strings(keys)
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("filter %q does not apply to the %s dataset", keys[0], req.Dataset)})
+			return
+		}
+	}
+
+	filter, err := parseLogSearchFilter(func(key string) string {
+		switch key {
+		case "from":
+			return req.From
+		case "to":
+			return req.To
+		case "limit", "cursor":
+			return ""
+		}
+		return req.Filters[key]
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job := &store.ExportJob{
+		AgentID: dbID,
+		Dataset: req.Dataset,
+		Format:  req.Format,
+		Filters: filter,
+	}
+	if err := h.store.CreateExportJob(c.Request.Context(), job); err != nil {
+		h.logger.Error("failed to create export job", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export"})
+		return
+	}
+	h.exportWorker.Notify()
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ListExports handles GET /v1/agents/:agent_id/exports?limit=20
+func (h *Handler) ListExports(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	limit := 20
+	if l := c.Query("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 100 {
+			limit = v
+		}
+	}
+
+	jobs, err := h.store.ListExportJobs(c.Request.Context(), dbID, limit)
+	if err != nil {
+		h.logger.Error("failed to list export jobs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list exports"})
+		return
+	}
+
+	resp := make([]exportResponse, len(jobs))
+	for i := range jobs {
+		resp[i] = h.exportResponse(&jobs[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"exports": resp, "total": len(resp)})
+}
+
+// GetExport handles GET /v1/agents/:agent_id/exports/:export_id
+func (h *Handler) GetExport(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	jobID, err := uuid.Parse(c.Param("export_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid export_id"})
+		return
+	}
+
+	job, err := h.store.GetExportJob(c.Request.Context(), dbID, jobID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "export not found"})
+			return
+		}
+		h.logger.Error("failed to get export job", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get export"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.exportResponse(job))
+}
+
+// DownloadExport handles GET /v1/exports/:export_id/download?expires=&sig=
+// The signed URL from GetExport is the only authorization.
+func (h *Handler) DownloadExport(c *gin.Context) {
+	jobID, err := uuid.Parse(c.Param("export_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid export_id"})
+		return
+	}
+	if !h.exportSigner.Verify(jobID, c.Query("expires"), c.Query("sig")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid or expired download link"})
+		return
+	}
+
+	job, err := h.store.GetExportJobByID(c.Request.Context(), jobID)
+	if err != nil || job.Status != store.ExportStatusCompleted || job.BlobKey == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "export not available"})
+		return
+	}
+
+	r, size, err := h.exportBlobs.Open(c.Request.Context(), *job.BlobKey)
+	if err != nil {
+		h.logger.Error("failed to open export file", zap.Error(err), zap.String("job_id", jobID.String()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "export not available"})
+		return
+	}
+	defer r.Close()
+
+	// Large files outlive the server's default write timeout.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(downloadWriteTimeout))
+
+	filename := fmt.Sprintf("%s-%s.%s", job.Dataset, job.CreatedAt.UTC().Format("20060102-150405"), job.Format)
+	c.DataFromReader(http.StatusOK, size, export.ContentType(job.Format), r, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, filename),
+		"Cache-Control":       "private, no-store",
+	})
+}
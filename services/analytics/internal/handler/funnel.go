@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// defaultFunnelWindow is used when a funnel is created without a window.
+const defaultFunnelWindow = 7 * 24 * time.Hour
+
+type FunnelRequest struct {
+	Name          string             `json:"name" binding:"required"`
+	Steps         []store.FunnelStep `json:"steps" binding:"required"`
+	WindowSeconds int                `json:"window_seconds"`
+}
+
+func (r *FunnelRequest) funnel(agentID uuid.UUID) *store.Funnel {
+	f := &store.Funnel{
+		AgentID:       agentID,
+		Name:          r.Name,
+		Steps:         r.Steps,
+		WindowSeconds: r.WindowSeconds,
+	}
+	if f.WindowSeconds == 0 {
+		f.WindowSeconds = int(defaultFunnelWindow.Seconds())
+	}
+	return f
+}
+
+// resolveFunnel loads the :funnel_id funnel for the agent, writing the
+// error response itself on failure.
+func (h *Handler) resolveFunnel(c *gin.Context, agentID uuid.UUID) (*store.Funnel, bool) {
+	funnelID, err := uuid.Parse(c.Param("funnel_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid funnel_id"})
+		return nil, false
+	}
+	f, err := h.store.GetFunnel(c.Request.Context(), agentID, funnelID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "funnel not found"})
+			return nil, false
+		}
+		h.logger.Error("failed to get funnel", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get funnel"})
+		return nil, false
+	}
+	return f, true
+}
+
+// CreateFunnel handles POST /v1/agents/:agent_id/funnels
+func (h *Handler) CreateFunnel(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	var req FunnelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	f := req.funnel(dbID)
+	if err := f.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	count, err := h.store.CountFunnels(c.Request.Context(), dbID)
+	if err != nil {
+		h.logger.Error("failed to count funnels", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create funnel"})
+		return
+	}
+	if count >= store.MaxFunnels {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("funnel limit reached (%d)", store.MaxFunnels)})
+		return
+	}
+
+	if err := h.store.CreateFunnel(c.Request.Context(), f); err != nil {
+		if errors.Is(err, store.ErrFunnelNameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("failed to create funnel", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create funnel"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, f)
+}
+
+// ListFunnels handles GET /v1/agents/:agent_id/funnels
+func (h *Handler) ListFunnels(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	funnels, err := h.store.ListFunnels(c.Request.Context(), dbID)
+	if err != nil {
+		h.logger.Error("failed to list funnels", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list funnels"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"funnels": funnels, "total": len(funnels)})
+}
+
+// GetFunnel handles GET /v1/agents/:agent_id/funnels/:funnel_id
+func (h *Handler) GetFunnel(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+	f, ok := h.resolveFunnel(c, dbID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// UpdateFunnel handles PUT /v1/agents/:agent_id/funnels/:funnel_id
+func (h *Handler) UpdateFunnel(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+	existing, ok := h.resolveFunnel(c, dbID)
+	if !ok {
+		return
+	}
+
+	var req FunnelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	f := req.funnel(dbID)
+	f.ID = existing.ID
+	if err := f.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.UpdateFunnel(c.Request.Context(), f); err != nil {
+		if errors.Is(err, store.ErrFunnelNameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "funnel not found"})
+			return
+		}
+		h.logger.Error("failed to update funnel", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update funnel"})
+		return
+	}
+
+	c.JSON(http.StatusOK, f)
+}
+
+// DeleteFunnel handles DELETE /v1/agents/:agent_id/funnels/:funnel_id
+func (h *Handler) DeleteFunnel(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	funnelID, err := uuid.Parse(c.Param("funnel_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid funnel_id"})
+		return
+	}
+
+	deleted, err := h.store.DeleteFunnel(c.Request.Context(), dbID, funnelID)
+	if err != nil {
+		h.logger.Error("failed to delete funnel", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete funnel"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "funnel not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
+
+// FunnelReport handles GET /v1/agents/:agent_id/funnels/:funnel_id/report?days=30
+func (h *Handler) FunnelReport(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+	f, ok := h.resolveFunnel(c, dbID)
+	if !ok {
+		return
+	}
+	loc, ok := h.resolveTimezone(c, dbID)
+	if !ok {
+		return
+	}
+
+	days := 30
+	if d := c.Query("days"); d != "" {
+		if v, err := strconv.Atoi(d); err == nil && v > 0 && v <= 90 {
+			days = v
+		}
+	}
+
+	// updated_at is part of the key so edits are reflected immediately.
+	cacheKey := fmt.Sprintf("agent:%s:funnels:%s:%d:%d:%s", c.Param("agent_id"), f.ID, f.UpdatedAt.UnixNano(), days, loc)
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	report, err := h.funnelAnalytics.GetFunnelReport(c.Request.Context(), f, days, loc)
+	if err != nil {
+		h.logger.Error("failed to get funnel report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate funnel report"})
+		return
+	}
+
+	data, _ := json.Marshal(report)
+	h.cache.Set(c.Request.Context(), cacheKey, data, time.Minute)
+	c.Data(http.StatusOK, "application/json", data)
+}
@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/GT8004/gt8004-analytics/internal/analytics"
 	"github.com/GT8004/gt8004-analytics/internal/cache"
+	"github.com/GT8004/gt8004-analytics/internal/export"
 	"github.com/GT8004/gt8004-analytics/internal/store"
 )
 
@@ -20,8 +23,15 @@ type Handler struct {
 	customerAnalytics *analytics.CustomerAnalytics
 	revenueAnalytics  *analytics.RevenueAnalytics
 	perfAnalytics     *analytics.PerformanceAnalytics
+	funnelAnalytics   *analytics.FunnelAnalytics
+	peerAnalytics     *analytics.PeerAnalytics
 	registryURL       string
 	chainIDs          []int
+
+	// Data exports
+	exportWorker *export.Worker
+	exportBlobs  export.BlobStore
+	exportSigner *export.Signer
 }
 
 func New(
@@ -29,10 +39,15 @@ func New(
 	custAnalytics *analytics.CustomerAnalytics,
 	revAnalytics *analytics.RevenueAnalytics,
 	perfAnalytics *analytics.PerformanceAnalytics,
+	funnelAnalytics *analytics.FunnelAnalytics,
+	peerAnalytics *analytics.PeerAnalytics,
 	redisCache *cache.Cache,
 	logger *zap.Logger,
 	registryURL string,
 	chainIDs []int,
+	exportWorker *export.Worker,
+	exportBlobs export.BlobStore,
+	exportSigner *export.Signer,
 ) *Handler {
 	return &Handler{
 		store:             s,
@@ -40,9 +55,14 @@ func New(
 		customerAnalytics: custAnalytics,
 		revenueAnalytics:  revAnalytics,
 		perfAnalytics:     perfAnalytics,
+		funnelAnalytics:   funnelAnalytics,
+		peerAnalytics:     peerAnalytics,
 		logger:            logger,
 		registryURL:       registryURL,
 		chainIDs:          chainIDs,
+		exportWorker:      exportWorker,
+		exportBlobs:       exportBlobs,
+		exportSigner:      exportSigner,
 	}
 }
 
@@ -51,7 +71,8 @@ func (h *Handler) Store() *store.Store {
 }
 
 // resolveOwnedAgent resolves an agent by slug and verifies the authenticated
-// user owns it (matching EVM address set by OwnerAuthMiddleware).
+// user owns it (matching EVM address set by OwnerAuthMiddleware) or was
+// granted access to it through an organization role.
 func (h *Handler) resolveOwnedAgent(c *gin.Context) (uuid.UUID, bool) {
 	slug := c.Param("agent_id")
 	if slug == "" {
@@ -65,6 +86,11 @@ func (h *Handler) resolveOwnedAgent(c *gin.Context) (uuid.UUID, bool) {
 		return uuid.UUID{}, false
 	}
 
+	// OwnerAuthMiddleware already checked the wallet's role for this agent.
+	if _, ok := c.Get("member_role"); ok {
+		return dbID, true
+	}
+
 	// Check ownership against authenticated EVM address
 	authEVM, _ := c.Get("auth_evm_address")
 	authAddr, _ := authEVM.(string)
@@ -75,3 +101,36 @@ func (h *Handler) resolveOwnedAgent(c *gin.Context) (uuid.UUID, bool) {
 
 	return dbID, true
 }
+
+// resolveTimezone returns the zone used for daily and hourly buckets: the
+// IANA name in ?tz= when present, otherwise the agent's default (UTC when
+// agentDBID is zero or unset). It writes a 400 response for unknown zones.
+func (h *Handler) resolveTimezone(c *gin.Context, agentDBID uuid.UUID) (*time.Location, bool) {
+	name := c.Query("tz")
+	if name == "" && agentDBID != uuid.Nil {
+		tz, err := h.store.GetAgentTimezone(c.Request.Context(), agentDBID)
+		if err != nil {
+			h.logger.Warn("failed to get agent timezone", zap.Error(err))
+		}
+		name = tz
+	}
+	if name == "" {
+		return time.UTC, true
+	}
+
+	loc, err := loadTimezone(name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tz: must be an IANA time zone name such as Asia/Seoul"})
+		return nil, false
+	}
+	return loc, true
+}
+
+// loadTimezone loads an IANA zone, rejecting "Local" and the empty name,
+// which time.LoadLocation accepts but Postgres does not.
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return time.LoadLocation(name)
+}
@@ -2,13 +2,18 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
 )
 
 // ListLogs handles GET /v1/agents/:agent_id/logs?limit=50
@@ -47,3 +52,164 @@ func (h *Handler) ListLogs(c *gin.Context) {
 	h.cache.Set(c.Request.Context(), cacheKey, data, 5*time.Second)
 	c.Data(http.StatusOK, "application/json", data)
 }
+
+// SearchLogs handles GET /v1/agents/:agent_id/logs/search
+//
+//	?from=&to=                       RFC3339 time range
+//	&status=404,5xx                  exact codes and/or classes
+//	&tool=&protocol=&method=         comma-separated lists
+//	&path=&q=                        substring match on path / bodies
+//	&ip=&country=&sdk_version=       ip is the client IP
+//	&paid=true|false
+//	&min_latency_ms=&max_latency_ms=
+//	&cursor=&limit=50
+func (h *Handler) SearchLogs(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	filter, err := parseLogSearchFilter(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.store.SearchRequestLogs(c.Request.Context(), dbID, filter)
+	if err != nil {
+		h.logger.Error("failed to search logs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetLog handles GET /v1/agents/:agent_id/logs/:log_id
+// Returns a single request log with full bodies and headers.
+func (h *Handler) GetLog(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	logID, err := strconv.ParseInt(c.Param("log_id"), 10, 64)
+	if err != nil || logID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid log_id"})
+		return
+	}
+
+	entry, err := h.store.GetRequestLog(c.Request.Context(), dbID, logID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "log not found"})
+			return
+		}
+		h.logger.Error("failed to get log", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// parseLogSearchFilter builds a store.LogSearchFilter from string params
+// looked up with get (query params for search, a JSON map for exports).
+func parseLogSearchFilter(get func(string) string) (store.LogSearchFilter, error) {
+	var f store.LogSearchFilter
+
+	f.Limit = 50
+	if l := get("limit"); l != "" {
+		v, err := strconv.Atoi(l)
+		if err != nil || v < 1 || v > 500 {
+			return f, fmt.Errorf("invalid limit: must be between 1 and 500")
+		}
+		f.Limit = v
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &f.From}, {"to", &f.To}} {
+		if raw := get(p.name); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return f, fmt.Errorf("invalid %s: must be RFC3339", p.name)
+			}
+			*p.dst = &t
+		}
+	}
+
+	for _, s := range splitCSV(get("status")) {
+		s = strings.ToLower(s)
+		if len(s) == 3 && strings.HasSuffix(s, "xx") {
+			class, err := strconv.Atoi(s[:1])
+			if err != nil || class < 1 || class > 5 {
+				return f, fmt.Errorf("invalid status class %q", s)
+			}
+			f.StatusClasses = append(f.StatusClasses, class)
+			continue
+		}
+		code, err := strconv.Atoi(s)
+		if err != nil || code < 100 || code > 599 {
+			return f, fmt.Errorf("invalid status %q", s)
+		}
+		f.StatusCodes = append(f.StatusCodes, code)
+	}
+
+	f.Tools = splitCSV(get("tool"))
+	f.Protocols = splitCSV(get("protocol"))
+	for _, m := range splitCSV(get("method")) {
+		f.Methods = append(f.Methods, strings.ToUpper(m))
+	}
+	f.Path = get("path")
+	f.IP = get("ip")
+	f.Country = get("country")
+	f.SDKVersion = get("sdk_version")
+	f.Query = get("q")
+
+	if raw := get("paid"); raw != "" {
+		paid, err := strconv.ParseBool(raw)
+		if err != nil {
+			return f, fmt.Errorf("invalid paid: must be true or false")
+		}
+		f.Paid = &paid
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  **float64
+	}{{"min_latency_ms", &f.MinLatencyMs}, {"max_latency_ms", &f.MaxLatencyMs}} {
+		if raw := get(p.name); raw != "" {
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil || v < 0 {
+				return f, fmt.Errorf("invalid %s", p.name)
+			}
+			*p.dst = &v
+		}
+	}
+
+	if raw := get("cursor"); raw != "" {
+		cur, err := store.DecodeLogCursor(raw)
+		if err != nil {
+			return f, err
+		}
+		f.Cursor = cur
+	}
+
+	return f, nil
+}
+
+// splitCSV splits a comma-separated query value, dropping empty items.
+func splitCSV(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
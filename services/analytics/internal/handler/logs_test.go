@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+func TestParseLogSearchFilter(t *testing.T) {
+	from := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	cursor := store.LogCursor{CreatedAt: from, ID: 9}
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		check   func(t *testing.T, f store.LogSearchFilter)
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			params: map[string]string{},
+			check: func(t *testing.T, f store.LogSearchFilter) {
+				if f.Limit != 50 {
+					t.Errorf("expected limit 50, got %d", f.Limit)
+				}
+				if f.From != nil || f.Cursor != nil || f.Paid != nil {
+					t.Errorf("expected no optional filters, got %+v", f)
+				}
+			},
+		},
+		{
+			name: "all filters",
+			params: map[string]string{
+				"limit":          "500",
+				"from":           from.Format(time.RFC3339),
+				"status":         "404, 5xx",
+				"tool":           "search,,summarize",
+				"method":         "get,post",
+				"ip":             "203.0.113.7",
+				"country":        "KR",
+				"paid":           "true",
+				"min_latency_ms": "12.5",
+				"cursor":         cursor.Encode(),
+			},
+			check: func(t *testing.T, f store.LogSearchFilter) {
+				if f.Limit != 500 {
+					t.Errorf("expected limit 500, got %d", f.Limit)
+				}
+				if f.From == nil || !f.From.Equal(from) {
+					t.Errorf("expected from %v, got %v", from, f.From)
+				}
+				if !reflect.DeepEqual(f.StatusCodes, []int{404}) || !reflect.DeepEqual(f.StatusClasses, []int{5}) {
+					t.Errorf("expected codes [404] classes [5], got %v %v", f.StatusCodes, f.StatusClasses)
+				}
+				if !reflect.DeepEqual(f.Tools, []string{"search", "summarize"}) {
+					t.Errorf("expected tools [search summarize], got %v", f.Tools)
+				}
+				if !reflect.DeepEqual(f.Methods, []string{"GET", "POST"}) {
+					t.Errorf("expected methods [GET POST], got %v", f.Methods)
+				}
+				if f.IP != "203.0.113.7" || f.Country != "KR" {
+					t.Errorf("expected ip and country, got %q %q", f.IP, f.Country)
+				}
+				if f.Paid == nil || !*f.Paid {
+					t.Errorf("expected paid=true, got %v", f.Paid)
+				}
+				if f.MinLatencyMs == nil || *f.MinLatencyMs != 12.5 {
+					t.Errorf("expected min latency 12.5, got %v", f.MinLatencyMs)
+				}
+				if f.Cursor == nil || f.Cursor.ID != 9 || !f.Cursor.CreatedAt.Equal(from) {
+					t.Errorf("expected cursor %+v, got %+v", cursor, f.Cursor)
+				}
+			},
+		},
+		{name: "limit zero", params: map[string]string{"limit": "0"}, wantErr: true},
+		{name: "limit too large", params: map[string]string{"limit": "501"}, wantErr: true},
+		{name: "limit not a number", params: map[string]string{"limit": "ten"}, wantErr: true},
+		{name: "bad from", params: map[string]string{"from": "yesterday"}, wantErr: true},
+		{name: "bad status class", params: map[string]string{"status": "9xx"}, wantErr: true},
+		{name: "bad status code", params: map[string]string{"status": "99"}, wantErr: true},
+		{name: "bad paid", params: map[string]string{"paid": "maybe"}, wantErr: true},
+		{name: "negative latency", params: map[string]string{"max_latency_ms": "-1"}, wantErr: true},
+		{name: "bad cursor", params: map[string]string{"cursor": "!!!"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseLogSearchFilter(func(k string) string { return tt.params[k] })
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", f)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, f)
+		})
+	}
+}
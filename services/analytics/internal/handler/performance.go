@@ -12,12 +12,18 @@ import (
 	"go.uber.org/zap"
 )
 
-// PerformanceReport handles GET /v1/agents/:agent_id/performance?window=24h
+// PerformanceReport handles GET /v1/agents/:agent_id/performance?window=24h&tz=
+// With group_by=tag:<key> or tag=<key>:<value> filters it returns tag-grouped
+// metrics over the window instead.
 func (h *Handler) PerformanceReport(c *gin.Context) {
 	dbID, ok := h.resolveOwnedAgent(c)
 	if !ok {
 		return
 	}
+	loc, ok := h.resolveTimezone(c, dbID)
+	if !ok {
+		return
+	}
 
 	// Parse window query param (default "24h")
 	windowHours := 24
@@ -25,14 +31,23 @@ func (h *Handler) PerformanceReport(c *gin.Context) {
 		windowHours = parseWindowHours(w)
 	}
 
-	cacheKey := fmt.Sprintf("agent:%s:perf:%d", c.Param("agent_id"), windowHours)
+	tq, tagged, ok := parseTagQuery(c)
+	if !ok {
+		return
+	}
+	if tagged {
+		h.tagBreakdown(c, dbID, "perf", tq, time.Duration(windowHours)*time.Hour, 30*time.Second)
+		return
+	}
+
+	cacheKey := fmt.Sprintf("agent:%s:perf:%d:%s", c.Param("agent_id"), windowHours, loc)
 
 	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
 		c.Data(http.StatusOK, "application/json", cached)
 		return
 	}
 
-	report, err := h.perfAnalytics.GetPerformanceReport(c.Request.Context(), dbID, windowHours)
+	report, err := h.perfAnalytics.GetPerformanceReport(c.Request.Context(), dbID, windowHours, loc)
 	if err != nil {
 		h.logger.Error("failed to get performance report", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get performance report"})
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/analytics"
+)
+
+// GetReputation handles GET /v1/agents/:agent_id/reputation
+// Public: returns the agent's current reputation score together with the
+// inputs and formula behind each component.
+func (h *Handler) GetReputation(c *gin.Context) {
+	cacheKey := fmt.Sprintf("agent:%s:reputation", c.Param("agent_id"))
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	dbID, _, err := h.store.GetAgentEVMAddress(c.Request.Context(), c.Param("agent_id"))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+			return
+		}
+		h.logger.Error("failed to resolve agent", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get reputation"})
+		return
+	}
+
+	rb, err := h.store.GetReputationBreakdown(c.Request.Context(), dbID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "reputation not calculated yet"})
+			return
+		}
+		h.logger.Error("failed to get reputation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get reputation"})
+		return
+	}
+
+	data, _ := json.Marshal(gin.H{"reputation": rb})
+	h.cache.Set(c.Request.Context(), cacheKey, data, 5*time.Minute)
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// GetReputationHistory handles GET /v1/agents/:agent_id/reputation/history?days=90
+// Public: returns daily reputation snapshots and what drove each change.
+func (h *Handler) GetReputationHistory(c *gin.Context) {
+	days := 90
+	if d := c.Query("days"); d != "" {
+		if v, err := strconv.Atoi(d); err == nil && v > 0 && v <= 365 {
+			days = v
+		}
+	}
+
+	cacheKey := fmt.Sprintf("agent:%s:reputation:history:%d", c.Param("agent_id"), days)
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	dbID, _, err := h.store.GetAgentEVMAddress(c.Request.Context(), c.Param("agent_id"))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+			return
+		}
+		h.logger.Error("failed to resolve agent", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get reputation history"})
+		return
+	}
+
+	history, err := h.store.GetReputationHistory(c.Request.Context(), dbID, days)
+	if err != nil {
+		h.logger.Error("failed to get reputation history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get reputation history"})
+		return
+	}
+
+	resp := gin.H{
+		"days":    days,
+		"history": history,
+		"changes": analytics.ReputationChanges(history),
+	}
+	data, _ := json.Marshal(resp)
+	h.cache.Set(c.Request.Context(), cacheKey, data, 15*time.Minute)
+	c.Data(http.StatusOK, "application/json", data)
+}
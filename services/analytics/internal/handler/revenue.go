@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,12 +12,29 @@ import (
 )
 
 // RevenueReport handles GET /v1/agents/:agent_id/revenue.
+// With group_by=tag:<key> or tag=<key>:<value> filters it returns tag-grouped
+// metrics over the last ?days=30 instead.
 func (h *Handler) RevenueReport(c *gin.Context) {
 	dbID, ok := h.resolveOwnedAgent(c)
 	if !ok {
 		return
 	}
 
+	tq, tagged, ok := parseTagQuery(c)
+	if !ok {
+		return
+	}
+	if tagged {
+		days := 30
+		if d := c.Query("days"); d != "" {
+			if v, err := strconv.Atoi(d); err == nil && v > 0 && v <= 365 {
+				days = v
+			}
+		}
+		h.tagBreakdown(c, dbID, "revenue", tq, time.Duration(days)*24*time.Hour, time.Minute)
+		return
+	}
+
 	period := c.DefaultQuery("period", "monthly")
 	if period != "monthly" && period != "weekly" {
 		period = "monthly"
@@ -39,3 +57,45 @@ func (h *Handler) RevenueReport(c *gin.Context) {
 	h.cache.Set(c.Request.Context(), cacheKey, data, 30*time.Second)
 	c.Data(http.StatusOK, "application/json", data)
 }
+
+// RevenueForecast handles GET /v1/agents/:agent_id/revenue/forecast?horizon=90&tz=
+// Projects daily verified revenue 30, 60 or 90 days ahead with confidence
+// bands, run rate, growth and per-tool contribution.
+func (h *Handler) RevenueForecast(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+	loc, ok := h.resolveTimezone(c, dbID)
+	if !ok {
+		return
+	}
+
+	horizon := 90
+	if v := c.Query("horizon"); v != "" {
+		switch v {
+		case "30", "60", "90":
+			horizon, _ = strconv.Atoi(v)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "horizon must be 30, 60 or 90"})
+			return
+		}
+	}
+
+	cacheKey := fmt.Sprintf("agent:%s:revenue:forecast:%d:%s", c.Param("agent_id"), horizon, loc)
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	forecast, err := h.revenueAnalytics.GetRevenueForecast(c.Request.Context(), dbID, horizon, loc)
+	if err != nil {
+		h.logger.Error("failed to get revenue forecast", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get revenue forecast"})
+		return
+	}
+
+	data, _ := json.Marshal(forecast)
+	h.cache.Set(c.Request.Context(), cacheKey, data, 15*time.Minute)
+	c.Data(http.StatusOK, "application/json", data)
+}
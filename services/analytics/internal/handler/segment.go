@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/analytics"
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// CustomerSegments handles GET /v1/agents/:agent_id/customers/segments
+// Returns customer counts and projected LTV for every RFM segment.
+func (h *Handler) CustomerSegments(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	cacheKey := fmt.Sprintf("agent:%s:customers:segments", c.Param("agent_id"))
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	counts, err := h.store.GetSegmentCounts(c.Request.Context(), dbID)
+	if err != nil {
+		h.logger.Error("failed to get segment counts", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get customer segments"})
+		return
+	}
+
+	// Report every segment, in engagement order, including empty ones.
+	bySegment := make(map[string]store.SegmentCount, len(counts))
+	var total int64
+	for _, sc := range counts {
+		bySegment[sc.Segment] = sc
+		total += sc.Customers
+	}
+	segments := make([]store.SegmentCount, 0, len(analytics.Segments))
+	for _, name := range analytics.Segments {
+		sc, ok := bySegment[name]
+		if !ok {
+			sc = store.SegmentCount{Segment: name}
+		}
+		segments = append(segments, sc)
+	}
+
+	data, _ := json.Marshal(gin.H{"segments": segments, "total_customers": total})
+	h.cache.Set(c.Request.Context(), cacheKey, data, 5*time.Minute)
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// SegmentMembers handles GET /v1/agents/:agent_id/customers/segments/:segment?limit=50&offset=0
+func (h *Handler) SegmentMembers(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	segment := c.Param("segment")
+	if !analytics.IsSegment(segment) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown segment", "segments": analytics.Segments})
+		return
+	}
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 500 {
+			limit = v
+		}
+	}
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if v, err := strconv.Atoi(o); err == nil && v >= 0 {
+			offset = v
+		}
+	}
+
+	members, total, err := h.store.GetSegmentMembers(c.Request.Context(), dbID, segment, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to get segment members", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get segment members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"segment": segment, "customers": members, "total": total})
+}
+
+// SegmentMovement handles GET /v1/agents/:agent_id/customers/segments/movement?days=30
+// Returns how customers moved between segments since days ago and the daily
+// segment sizes over the same period.
+func (h *Handler) SegmentMovement(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	days := 30
+	if d := c.Query("days"); d != "" {
+		if v, err := strconv.Atoi(d); err == nil && v > 0 && v <= 365 {
+			days = v
+		}
+	}
+
+	cacheKey := fmt.Sprintf("agent:%s:customers:segments:movement:%d", c.Param("agent_id"), days)
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	now := time.Now().UTC()
+	since := now.AddDate(0, 0, -days)
+	movement, err := h.store.GetSegmentMovement(c.Request.Context(), dbID, since, now)
+	if err != nil {
+		h.logger.Error("failed to get segment movement", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get segment movement"})
+		return
+	}
+	history, err := h.store.GetSegmentHistory(c.Request.Context(), dbID, since)
+	if err != nil {
+		h.logger.Error("failed to get segment history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get segment movement"})
+		return
+	}
+
+	resp := gin.H{
+		"days":     days,
+		"movement": movement,
+		"history":  history,
+	}
+	data, _ := json.Marshal(resp)
+	h.cache.Set(c.Request.Context(), cacheKey, data, 15*time.Minute)
+	c.Data(http.StatusOK, "application/json", data)
+}
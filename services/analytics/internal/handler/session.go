@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// maxSessionRequests caps the requests returned with a session's detail.
+const maxSessionRequests = 500
+
+// parseSessionDays reads the days query param (default 30, max 90).
+func parseSessionDays(c *gin.Context) int {
+	days := 30
+	if d := c.Query("days"); d != "" {
+		if v, err := strconv.Atoi(d); err == nil && v > 0 && v <= 90 {
+			days = v
+		}
+	}
+	return days
+}
+
+// ListSessions handles GET /v1/agents/:agent_id/sessions?days=30&customer_id=&paid=&errored=&limit=&offset=
+func (h *Handler) ListSessions(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	f := store.SessionFilter{
+		Since:      time.Now().AddDate(0, 0, -parseSessionDays(c)),
+		CustomerID: c.Query("customer_id"),
+		PaidOnly:   c.Query("paid") == "true",
+		ErrorOnly:  c.Query("errored") == "true",
+		Limit:      50,
+	}
+	if l := c.Query("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 200 {
+			f.Limit = v
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if v, err := strconv.Atoi(o); err == nil && v >= 0 {
+			f.Offset = v
+		}
+	}
+
+	sessions, total, err := h.store.ListSessions(c.Request.Context(), dbID, f)
+	if err != nil {
+		h.logger.Error("failed to list sessions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions, "total": total})
+}
+
+// GetSession handles GET /v1/agents/:agent_id/sessions/:session_id
+// Returns the session aggregate and its requests in order.
+func (h *Handler) GetSession(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session_id"})
+		return
+	}
+
+	session, err := h.store.GetSession(c.Request.Context(), dbID, sessionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+		h.logger.Error("failed to get session", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get session"})
+		return
+	}
+
+	requests, err := h.store.GetSessionRequests(c.Request.Context(), session, maxSessionRequests)
+	if err != nil {
+		h.logger.Error("failed to get session requests", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"session": session, "requests": requests})
+}
+
+// SessionSummary handles GET /v1/agents/:agent_id/sessions/summary?days=30
+// Returns aggregate session metrics, the most common opening tool sequences
+// and the most common tool-to-tool transitions.
+func (h *Handler) SessionSummary(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	days := parseSessionDays(c)
+	cacheKey := fmt.Sprintf("agent:%s:sessions:summary:%d", c.Param("agent_id"), days)
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	var (
+		summary     *store.SessionSummary
+		sequences   []store.ToolSequenceCount
+		transitions []store.ToolSequenceCount
+	)
+	g, gctx := errgroup.WithContext(c.Request.Context())
+	g.Go(func() error {
+		var err error
+		summary, err = h.store.GetSessionSummary(gctx, dbID, since)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		sequences, err = h.store.GetCommonToolSequences(gctx, dbID, since, 3, 10)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		transitions, err = h.store.GetToolTransitions(gctx, dbID, since, 10)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		h.logger.Error("failed to get session summary", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get session summary"})
+		return
+	}
+
+	resp := gin.H{
+		"days":             days,
+		"summary":          summary,
+		"common_sequences": sequences,
+		"transitions":      transitions,
+	}
+	data, _ := json.Marshal(resp)
+	h.cache.Set(c.Request.Context(), cacheKey, data, 5*time.Minute)
+	c.Data(http.StatusOK, "application/json", data)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetAnalyticsSettings handles GET /v1/agents/:agent_id/analytics/settings
+func (h *Handler) GetAnalyticsSettings(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	tz, err := h.store.GetAgentTimezone(c.Request.Context(), dbID)
+	if err != nil {
+		h.logger.Error("failed to get agent timezone", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"timezone": tz})
+}
+
+type updateAnalyticsSettingsRequest struct {
+	Timezone string `json:"timezone" binding:"required"`
+}
+
+// UpdateAnalyticsSettings handles PUT /v1/agents/:agent_id/analytics/settings
+// The timezone is the default for daily and hourly buckets when a request
+// does not pass ?tz=.
+func (h *Handler) UpdateAnalyticsSettings(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	var req updateAnalyticsSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	loc, err := loadTimezone(req.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid timezone: must be an IANA time zone name such as Asia/Seoul"})
+		return
+	}
+
+	if err := h.store.SetAgentTimezone(c.Request.Context(), dbID, loc.String()); err != nil {
+		h.logger.Error("failed to set agent timezone", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"timezone": loc.String()})
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+// tagKeyPattern matches keys as normalized by the ingest service.
+var tagKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]{0,31}$`)
+
+// parseTagQuery reads group_by=tag:<key> and repeated tag=<key>:<value>
+// filters. present is false when the request uses neither, in which case the
+// endpoint returns its regular response. It writes a 400 response on
+// malformed input.
+func parseTagQuery(c *gin.Context) (q store.TagQuery, present, ok bool) {
+	if g := c.Query("group_by"); g != "" {
+		key, found := strings.CutPrefix(g, "tag:")
+		if !found || !tagKeyPattern.MatchString(key) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be tag:<key>"})
+			return q, false, false
+		}
+		q.GroupBy = key
+	}
+
+	for _, f := range c.QueryArray("tag") {
+		key, value, found := strings.Cut(f, ":")
+		if !found || value == "" || !tagKeyPattern.MatchString(key) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tag filters must be tag=<key>:<value>"})
+			return q, false, false
+		}
+		if q.Filters == nil {
+			q.Filters = map[string]string{}
+		}
+		q.Filters[key] = value
+	}
+
+	return q, q.GroupBy != "" || len(q.Filters) > 0, true
+}
+
+// tagQueryCacheKey renders a tag query deterministically for cache keys.
+func tagQueryCacheKey(q store.TagQuery) string {
+	parts := make([]string, 0, len(q.Filters))
+	for k, v := range q.Filters {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return q.GroupBy + "|" + strings.Join(parts, ",")
+}
+
+// tagBreakdown responds with tag-grouped metrics for requests in the last
+// window. Stats, performance and revenue share this response when a tag
+// group-by or filter is requested.
+func (h *Handler) tagBreakdown(c *gin.Context, dbID uuid.UUID, endpoint string, q store.TagQuery, window time.Duration, ttl time.Duration) {
+	cacheKey := fmt.Sprintf("agent:%s:%s:tags:%s:%d", c.Param("agent_id"), endpoint, tagQueryCacheKey(q), int64(window.Hours()))
+	if cached := h.cache.Get(c.Request.Context(), cacheKey); cached != nil {
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	since := time.Now().Add(-window)
+	groups, err := h.store.GetTagGroupMetrics(c.Request.Context(), dbID, since, q)
+	if err != nil {
+		h.logger.Error("failed to get tag group metrics", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get tag breakdown"})
+		return
+	}
+
+	resp := gin.H{
+		"since":   since,
+		"filters": q.Filters,
+		"groups":  groups,
+	}
+	if q.GroupBy != "" {
+		resp["group_by"] = "tag:" + q.GroupBy
+	}
+	data, _ := json.Marshal(resp)
+	h.cache.Set(c.Request.Context(), cacheKey, data, ttl)
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// ListTags handles GET /v1/agents/:agent_id/stats/tags
+// Returns the tag keys and values recorded for the agent.
+func (h *Handler) ListTags(c *gin.Context) {
+	dbID, ok := h.resolveOwnedAgent(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.store.ListTagKeys(c.Request.Context(), dbID)
+	if err != nil {
+		h.logger.Error("failed to list tag keys", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": keys})
+}
@@ -73,6 +73,11 @@ func (h *Handler) WalletDailyStats(c *gin.Context) {
 		days = 30
 	}
 
+	loc, ok := h.resolveTimezone(c, uuid.Nil)
+	if !ok {
+		return
+	}
+
 	chainFilter := parseChainIDs(c)
 
 	agentIDs, err := h.getWalletAgentIDs(c.Request.Context(), address, chainFilter)
@@ -82,14 +87,14 @@ func (h *Handler) WalletDailyStats(c *gin.Context) {
 		return
 	}
 
-	stats, err := h.store.GetWalletDailyStats(c.Request.Context(), agentIDs, days)
+	stats, err := h.store.GetWalletDailyStats(c.Request.Context(), agentIDs, days, loc)
 	if err != nil {
 		h.logger.Error("failed to get wallet daily stats", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch daily stats"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"stats": stats})
+	c.JSON(http.StatusOK, gin.H{"stats": stats, "timezone": loc.String()})
 }
 
 // WalletErrors returns error analysis across all owned agents
@@ -1,19 +1,30 @@
 package server
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/GT8004/gt8004-analytics/internal/store"
+	"github.com/GT8004/gt8004-common/apikey"
+	"github.com/GT8004/gt8004-common/org"
+	"github.com/GT8004/gt8004-common/ratelimit"
+	"github.com/GT8004/gt8004-common/session"
 )
 
-// OwnerAuthMiddleware authenticates the request via API key or wallet address.
-// Wallet address is only trusted when it matches a registered agent's EVM address.
-func OwnerAuthMiddleware(s *store.Store) gin.HandlerFunc {
+// OwnerAuthMiddleware authenticates the request via API key or wallet session.
+// Reads need a key with the analytics:read scope; changes to the agent's
+// analytics settings need registry:admin. A key is also bound to the role
+// that issued it, which must grant the route's permission.
+// A session's wallet is trusted when it is the agent's registered EVM
+// address or belongs to the agent's organization with a role that grants
+// the route's permission (see requiredPermission). The raw X-Wallet-Address
+// header is still accepted until legacyWalletUntil.
+func OwnerAuthMiddleware(s *store.Store, legacyWalletUntil time.Time) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 1) Try API key (prefer X-Forwarded-Authorization from API Gateway)
 		authHeader := c.GetHeader("X-Forwarded-Authorization")
@@ -23,42 +34,59 @@ func OwnerAuthMiddleware(s *store.Store) gin.HandlerFunc {
 		if authHeader != "" {
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
-				hash := sha256.Sum256([]byte(parts[1]))
-				keyHash := hex.EncodeToString(hash[:])
-				if agentAuth, err := s.ValidateAPIKey(c.Request.Context(), keyHash); err == nil {
+				agentAuth, err := s.ValidateAPIKey(c.Request.Context(), apikey.Hash(parts[1]), requiredScope(c.Request.Method), c.ClientIP())
+				if err != nil && !errors.Is(err, apikey.ErrInvalidKey) {
+					// A real key that may not be used here: do not fall back.
+					status, msg := apikey.HTTPStatus(err)
+					c.AbortWithStatusJSON(status, gin.H{"error": msg})
+					return
+				}
+				if err == nil {
 					// If the route has an :agent_id param, the API key must belong to that agent.
 					// This prevents any agent from accessing another agent's analytics data.
 					if agentID := c.Param("agent_id"); agentID != "" && agentAuth.AgentID != agentID {
 						c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api key does not belong to this agent"})
 						return
 					}
+					if perm := requiredPermission(c); !agentAuth.Role.Can(perm) {
+						c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient role", "role": agentAuth.Role, "required": perm})
+						return
+					}
 					// Look up the agent's EVM address for ownership verification
 					if agent, err := s.GetAgentByDBID(c.Request.Context(), agentAuth.AgentDBID); err == nil {
 						c.Set("auth_evm_address", agent.EVMAddress)
 					}
+					c.Set(ratelimit.ContextKeyAgentID, agentAuth.AgentID)
+					c.Set(ratelimit.ContextKeyTier, agentAuth.Tier)
 					c.Next()
 					return
 				}
 			}
 		}
 
-		// 2) Try wallet address — verify ownership of the requested agent
-		walletAddr := c.GetHeader("X-Wallet-Address")
-		if walletAddr != "" && strings.HasPrefix(walletAddr, "0x") && len(walletAddr) == 42 {
-			// For agent endpoints, verify the wallet owns the agent
+		// 2) Try wallet session — verify ownership of the requested agent
+		walletAddr := session.WalletAddress(c, legacyWalletUntil)
+		if walletAddr != "" {
+			// For agent endpoints, verify the wallet's role for the agent
 			if agentID := c.Param("agent_id"); agentID != "" {
-				if dbID, evmAddr, err := s.GetAgentEVMAddress(c.Request.Context(), agentID); err == nil {
-					if strings.EqualFold(evmAddr, walletAddr) {
-						_ = dbID
-						c.Set("auth_evm_address", strings.ToLower(walletAddr))
-						c.Next()
+				role, tier, err := s.GetAgentRole(c.Request.Context(), agentID, walletAddr)
+				if err == nil && role != "" {
+					perm := requiredPermission(c)
+					if !role.Can(perm) {
+						c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient role", "role": role, "required": perm})
 						return
 					}
+					c.Set("auth_evm_address", walletAddr)
+					c.Set("member_role", role)
+					c.Set(ratelimit.ContextKeyAgentID, agentID)
+					c.Set(ratelimit.ContextKeyTier, tier)
+					c.Next()
+					return
 				}
 			} else {
 				// For wallet endpoints (/wallet/:address/*), accept if header matches URL
 				if urlAddr := c.Param("address"); urlAddr != "" && strings.EqualFold(urlAddr, walletAddr) {
-					c.Set("auth_evm_address", strings.ToLower(walletAddr))
+					c.Set("auth_evm_address", walletAddr)
 					c.Next()
 					return
 				}
@@ -68,3 +96,88 @@ func OwnerAuthMiddleware(s *store.Store) gin.HandlerFunc {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
 	}
 }
+
+// billingRoutes are the agent routes a billing viewer may read.
+var billingRoutes = map[string]bool{
+	"/v1/agents/:agent_id/revenue":          true,
+	"/v1/agents/:agent_id/revenue/forecast": true,
+	"/v1/agents/:agent_id/costs":            true,
+	"/v1/agents/:agent_id/margins":          true,
+}
+
+// analystWriteRoutes are the agent route prefixes an analyst may change:
+// saved funnels and exports.
+var analystWriteRoutes = []string{
+	"/v1/agents/:agent_id/funnels",
+	"/v1/agents/:agent_id/exports",
+}
+
+// requiredPermission is the organization permission a wallet needs for the
+// request: billing reads, other reads, analyst-owned writes, or settings
+// changes, which need a managing role.
+func requiredPermission(c *gin.Context) org.Permission {
+	route := c.FullPath()
+	if requiredScope(c.Request.Method) == apikey.ScopeAnalyticsRead {
+		if billingRoutes[route] {
+			return org.PermBillingRead
+		}
+		return org.PermAnalyticsRead
+	}
+	for _, prefix := range analystWriteRoutes {
+		if strings.HasPrefix(route, prefix) {
+			return org.PermAnalyticsWrite
+		}
+	}
+	return org.PermAgentManage
+}
+
+// tierRoutes are the agent routes that need a paid tier: advanced analytics
+// (cohorts, segments, forecasts, saved funnels) and exports.
+var tierRoutes = map[string]string{
+	"/v1/agents/:agent_id/customers/cohorts":           "lite",
+	"/v1/agents/:agent_id/customers/segments":          "lite",
+	"/v1/agents/:agent_id/customers/segments/movement": "lite",
+	"/v1/agents/:agent_id/customers/segments/:segment": "lite",
+	"/v1/agents/:agent_id/revenue/forecast":            "lite",
+	"/v1/agents/:agent_id/funnels":                     "lite",
+	"/v1/agents/:agent_id/funnels/:funnel_id":          "lite",
+	"/v1/agents/:agent_id/funnels/:funnel_id/report":   "lite",
+	"/v1/agents/:agent_id/exports":                     "lite",
+	"/v1/agents/:agent_id/exports/:export_id":          "lite",
+}
+
+var tierLevels = map[string]int{"open": 1, "lite": 2}
+
+// TierRequiredMiddleware rejects requests to tierRoutes from agents below
+// the route's tier. It must run after OwnerAuthMiddleware, which sets the
+// agent's current tier.
+func TierRequiredMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requiredTier, ok := tierRoutes[c.FullPath()]
+		if !ok {
+			c.Next()
+			return
+		}
+		currentTier := c.GetString(ratelimit.ContextKeyTier)
+		if tierLevels[currentTier] < tierLevels[requiredTier] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":         "tier_required",
+				"current_tier":  currentTier,
+				"required_tier": requiredTier,
+				"upgrade_url":   fmt.Sprintf("/v1/services/%s/tier", c.Param("agent_id")),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requiredScope is the API key scope a request method needs.
+func requiredScope(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return apikey.ScopeAnalyticsRead
+	default:
+		return apikey.ScopeRegistryAdmin
+	}
+}
@@ -7,6 +7,9 @@ import (
 
 	"github.com/GT8004/gt8004-analytics/internal/config"
 	"github.com/GT8004/gt8004-analytics/internal/handler"
+	"github.com/GT8004/gt8004-common/clientip"
+	"github.com/GT8004/gt8004-common/ratelimit"
+	"github.com/GT8004/gt8004-common/session"
 )
 
 var allowedOrigins = map[string]bool{
@@ -43,43 +46,103 @@ func securityHeaders() gin.HandlerFunc {
 	}
 }
 
-func NewRouter(cfg *config.Config, h *handler.Handler) *gin.Engine {
+func NewRouter(cfg *config.Config, h *handler.Handler, sessionSigner *session.Signer, limiter *ratelimit.Limiter) *gin.Engine {
 	r := gin.New()
+	clientip.Configure(r, cfg.TrustedProxies)
 	r.Use(corsMiddleware(), securityHeaders(), gin.Logger(), gin.Recovery())
 
 	// Health
 	r.GET("/healthz", h.Healthz)
 	r.GET("/readyz", h.Readyz)
 
+	// Rate limits: every caller by API key, session wallet or IP, and by
+	// IP regardless, since bearer keys are counted before they are
+	// validated; agent analytics by the agent's tier once auth has run.
+	callerLimit := limiter.Middleware(ratelimit.Policy{
+		Name:  "analytics:caller",
+		Key:   ratelimit.First(ratelimit.ByAPIKey, ratelimit.ByWallet, ratelimit.ByIP),
+		Limit: ratelimit.PerMinute(cfg.RateLimitCaller),
+	})
+	ipLimit := limiter.Middleware(ratelimit.Policy{
+		Name:  "analytics:ip",
+		Key:   ratelimit.ByIP,
+		Limit: ratelimit.PerMinute(cfg.RateLimitIP),
+	})
+	agentLimit := limiter.Middleware(ratelimit.Policy{
+		Name:  "analytics:agent",
+		Key:   ratelimit.ByAgent,
+		Limit: cfg.RateLimitAgentTiers["open"],
+		Tiers: cfg.RateLimitAgentTiers,
+	})
+
 	v1 := r.Group("/v1")
+	v1.Use(session.Middleware(sessionSigner, h.Store()), ipLimit, callerLimit)
 
 	// Dashboard
 	v1.GET("/dashboard/overview", h.DashboardOverview)
 
 	// Benchmark
 	v1.GET("/benchmark", h.GetBenchmark)
+	v1.GET("/benchmark/agents/:agent_id", h.GetAgentBenchmark)
+	v1.GET("/benchmark/agents/:agent_id/history", h.GetAgentBenchmarkHistory)
+
+	// Reputation (public)
+	v1.GET("/agents/:agent_id/reputation", h.GetReputation)
+	v1.GET("/agents/:agent_id/reputation/history", h.GetReputationHistory)
+
+	// Export downloads (authorized by signed URL)
+	v1.GET("/exports/:export_id/download", h.DownloadExport)
 
-	// Agent analytics (owner-authenticated)
+	// Agent analytics (owner-authenticated; tierRoutes need a paid tier)
 	agentAuth := v1.Group("/agents/:agent_id")
-	agentAuth.Use(OwnerAuthMiddleware(h.Store()))
+	agentAuth.Use(OwnerAuthMiddleware(h.Store(), cfg.WalletHeaderAuthUntil), agentLimit, TierRequiredMiddleware())
 	{
 		agentAuth.GET("/analytics", h.AnalyticsReport)
+		agentAuth.GET("/analytics/settings", h.GetAnalyticsSettings)
+		agentAuth.PUT("/analytics/settings", h.UpdateAnalyticsSettings)
 		agentAuth.GET("/stats", h.AgentStats)
 		agentAuth.GET("/stats/daily", h.AgentDailyStats)
+		agentAuth.GET("/stats/tags", h.ListTags)
 		agentAuth.GET("/customers", h.ListCustomers)
+		agentAuth.GET("/customers/cohorts", h.CustomerCohorts)
+		agentAuth.GET("/customers/at-risk", h.AtRiskCustomers)
+		agentAuth.GET("/customers/segments", h.CustomerSegments)
+		agentAuth.GET("/customers/segments/movement", h.SegmentMovement)
+		agentAuth.GET("/customers/segments/:segment", h.SegmentMembers)
 		agentAuth.GET("/customers/:customer_id", h.GetCustomer)
 		agentAuth.GET("/customers/:customer_id/logs", h.CustomerLogs)
 		agentAuth.GET("/customers/:customer_id/tools", h.CustomerTools)
 		agentAuth.GET("/customers/:customer_id/daily", h.CustomerDailyStats)
+		agentAuth.GET("/sessions", h.ListSessions)
+		agentAuth.GET("/sessions/summary", h.SessionSummary)
+		agentAuth.GET("/sessions/:session_id", h.GetSession)
 		agentAuth.GET("/revenue", h.RevenueReport)
+		agentAuth.GET("/revenue/forecast", h.RevenueForecast)
+		agentAuth.GET("/costs", h.GetCosts)
+		agentAuth.PUT("/costs", h.UpdateCosts)
+		agentAuth.GET("/margins", h.MarginReport)
 		agentAuth.GET("/performance", h.PerformanceReport)
 		agentAuth.GET("/logs", h.ListLogs)
+		agentAuth.GET("/logs/search", h.SearchLogs)
+		agentAuth.GET("/logs/:log_id", h.GetLog)
 		agentAuth.GET("/funnel", h.ConversionFunnel)
+		agentAuth.POST("/funnels", h.CreateFunnel)
+		agentAuth.GET("/funnels", h.ListFunnels)
+		agentAuth.GET("/funnels/:funnel_id", h.GetFunnel)
+		agentAuth.PUT("/funnels/:funnel_id", h.UpdateFunnel)
+		agentAuth.DELETE("/funnels/:funnel_id", h.DeleteFunnel)
+		agentAuth.GET("/funnels/:funnel_id/report", h.FunnelReport)
+		agentAuth.GET("/peers", h.PeerComparison)
+		agentAuth.GET("/deployments", h.ListDeployments)
+		agentAuth.GET("/deployments/:deployment_id/compare", h.DeploymentComparison)
+		agentAuth.POST("/exports", h.CreateExport)
+		agentAuth.GET("/exports", h.ListExports)
+		agentAuth.GET("/exports/:export_id", h.GetExport)
 	}
 
 	// Owner-level analytics (wallet-authenticated)
 	walletAuth := v1.Group("/wallet/:address")
-	walletAuth.Use(OwnerAuthMiddleware(h.Store()))
+	walletAuth.Use(OwnerAuthMiddleware(h.Store(), cfg.WalletHeaderAuthUntil))
 	{
 		walletAuth.GET("/stats", h.WalletStats)
 		walletAuth.GET("/daily", h.WalletDailyStats)
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-analytics/internal/config"
+	"github.com/GT8004/gt8004-analytics/internal/handler"
+	"github.com/GT8004/gt8004-common/ratelimit"
+)
+
+var pathParams = strings.NewReplacer(
+	":agent_id", "agent-1",
+	":segment", "champions",
+	":funnel_id", "7",
+	":export_id", "9",
+)
+
+func TestNewRouter_TierRoutesRegistered(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter, err := ratelimit.New("", zap.NewNop())
+	if err != nil {
+		t.Fatalf("new limiter: %v", err)
+	}
+	r := NewRouter(&config.Config{}, &handler.Handler{}, nil, limiter)
+
+	registered := map[string]bool{}
+	for _, route := range r.Routes() {
+		registered[route.Path] = true
+	}
+	for path := range tierRoutes {
+		if !registered[path] {
+			t.Errorf("expected tier route %s to be registered", path)
+		}
+	}
+}
+
+func TestTierRequiredMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	agents := r.Group("/v1/agents/:agent_id")
+	agents.Use(func(c *gin.Context) {
+		c.Set(ratelimit.ContextKeyTier, c.GetHeader("X-Test-Tier"))
+	}, TierRequiredMiddleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	for path := range tierRoutes {
+		agents.GET(strings.TrimPrefix(path, "/v1/agents/:agent_id"), ok)
+	}
+	agents.GET("/stats", ok)
+
+	type tierCase struct {
+		name       string
+		path       string
+		tier       string
+		wantStatus int
+	}
+	tests := []tierCase{
+		{"ungated route on open", "/v1/agents/:agent_id/stats", "open", http.StatusOK},
+		{"ungated route without tier", "/v1/agents/:agent_id/stats", "", http.StatusOK},
+	}
+	for path := range tierRoutes {
+		tests = append(tests,
+			tierCase{path + " on open", path, "open", http.StatusForbidden},
+			tierCase{path + " without tier", path, "", http.StatusForbidden},
+			tierCase{path + " on lite", path, "lite", http.StatusOK},
+		)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, pathParams.Replace(tt.path), nil)
+			req.Header.Set("X-Test-Tier", tt.tier)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus != http.StatusForbidden {
+				return
+			}
+			var body struct {
+				Error        string `json:"error"`
+				RequiredTier string `json:"required_tier"`
+				UpgradeURL   string `json:"upgrade_url"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body.Error != "tier_required" || body.RequiredTier != "lite" {
+				t.Errorf("expected tier_required for lite, got %+v", body)
+			}
+			if body.UpgradeURL != "/v1/services/agent-1/tier" {
+				t.Errorf("expected upgrade url for agent-1, got %s", body.UpgradeURL)
+			}
+		})
+	}
+}
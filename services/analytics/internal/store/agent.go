@@ -2,10 +2,15 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/GT8004/gt8004-common/apikey"
+	"github.com/GT8004/gt8004-common/org"
 )
 
 // Agent is the full agent struct used by benchmark calculations.
@@ -168,6 +173,32 @@ func (s *Store) GetAgentEVMAddress(ctx context.Context, agentID string) (uuid.UU
 	return dbID, evmAddr, nil
 }
 
+// GetAgentRole returns a wallet's role for an active agent: owner when the
+// agent is registered to the wallet, otherwise its role in the agent's
+// organization, or "" when it has no access. The agent's tier is returned
+// alongside. Reads the registry's shared organization_members table.
+func (s *Store) GetAgentRole(ctx context.Context, agentID, wallet string) (org.Role, string, error) {
+	var role *string
+	var tier string
+	err := s.pool.QueryRow(ctx, `
+		SELECT CASE WHEN LOWER(a.evm_address) = LOWER($2) THEN 'owner' ELSE m.role END,
+			COALESCE(a.current_tier, 'open')
+		FROM agents a
+		LEFT JOIN organization_members m ON m.org_id = a.org_id AND m.wallet_address = LOWER($2)
+		WHERE a.agent_id = $1 AND a.status = 'active'
+	`, agentID, wallet).Scan(&role, &tier)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("get agent role: %w", err)
+	}
+	if role == nil {
+		return "", tier, nil
+	}
+	return org.Role(*role), tier, nil
+}
+
 // ---------- Benchmark-related agent queries ----------
 
 // GetActiveAgentsByCategory returns all active agents for a given category.
@@ -242,28 +273,28 @@ func (s *Store) GetDistinctAgentCategories(ctx context.Context) ([]string, error
 type APIKeyAuth struct {
 	AgentDBID uuid.UUID
 	AgentID   string
+	KeyID     uuid.UUID
+	Tier      string
+	// Role is the role of the wallet that issued the key.
+	Role org.Role
 }
 
-// ValidateAPIKey looks up an API key by its SHA-256 hash and returns agent info.
-// Read-only access to the shared api_keys and agents tables.
-func (s *Store) ValidateAPIKey(ctx context.Context, keyHash string) (*APIKeyAuth, error) {
-	auth := &APIKeyAuth{}
-	err := s.pool.QueryRow(ctx, `
-		SELECT ak.agent_id, a.agent_id
-		FROM api_keys ak
-		JOIN agents a ON a.id = ak.agent_id
-		WHERE ak.key_hash = $1 AND ak.revoked_at IS NULL
-	`, keyHash).Scan(&auth.AgentDBID, &auth.AgentID)
+// ValidateAPIKey looks up an API key by its SHA-256 hash and returns agent
+// info when the key is live, allowed from clientIP and carries scope.
+// Errors wrap the apikey package errors. Reads the shared api_keys and
+// agents tables; only the last-used columns are written.
+func (s *Store) ValidateAPIKey(ctx context.Context, keyHash, scope, clientIP string) (*APIKeyAuth, error) {
+	k, err := apikey.Validate(ctx, s.pool, keyHash, scope, clientIP)
 	if err != nil {
-		return nil, fmt.Errorf("validate api key: %w", err)
+		return nil, err
 	}
-
-	// Update last_used_at
-	_, _ = s.pool.Exec(ctx, `
-		UPDATE api_keys SET last_used_at = NOW() WHERE key_hash = $1
-	`, keyHash)
-
-	return auth, nil
+	return &APIKeyAuth{
+		AgentDBID: k.AgentDBID,
+		AgentID:   k.AgentID,
+		KeyID:     k.ID,
+		Tier:      k.Tier,
+		Role:      org.Role(k.Role),
+	}, nil
 }
 
 // SearchAgents returns all active agents (used by admin overview).
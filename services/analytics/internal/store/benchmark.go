@@ -23,31 +23,158 @@ type BenchmarkEntry struct {
 	Revenue       float64   `json:"revenue"`
 	CustomerCount int       `json:"customer_count"`
 	CalculatedAt  time.Time `json:"calculated_at"`
+	// PreviousRank is the rank in the latest snapshot before today;
+	// RankChange is positive when the agent moved up since then.
+	PreviousRank *int                `json:"previous_rank,omitempty"`
+	RankChange   *int                `json:"rank_change,omitempty"`
+	Breakdown    *BenchmarkBreakdown `json:"breakdown,omitempty"`
 }
 
-// UpsertBenchmarkEntry inserts or updates a benchmark entry for a given category and agent.
-func (s *Store) UpsertBenchmarkEntry(ctx context.Context, entry *BenchmarkEntry) error {
-	err := s.pool.QueryRow(ctx, `
-		INSERT INTO benchmark_cache (
-			category, agent_id, rank, score, total_requests,
-			avg_response_ms, error_rate, revenue, customer_count, calculated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		ON CONFLICT (category, agent_id) DO UPDATE SET
-			rank = EXCLUDED.rank,
-			score = EXCLUDED.score,
-			total_requests = EXCLUDED.total_requests,
-			avg_response_ms = EXCLUDED.avg_response_ms,
-			error_rate = EXCLUDED.error_rate,
-			revenue = EXCLUDED.revenue,
-			customer_count = EXCLUDED.customer_count,
-			calculated_at = EXCLUDED.calculated_at
-		RETURNING id
-	`,
-		entry.Category, entry.AgentID, entry.Rank, entry.Score, entry.TotalRequests,
-		entry.AvgResponseMs, entry.ErrorRate, entry.Revenue, entry.CustomerCount, entry.CalculatedAt,
-	).Scan(&entry.ID)
+// BenchmarkComponent is one scored dimension of a benchmark entry.
+// Percentile is the agent's position within its category (0-100, higher is
+// better) and Contribution is Weight * Percentile.
+type BenchmarkComponent struct {
+	Name         string  `json:"name"`
+	Value        float64 `json:"value"`
+	Percentile   float64 `json:"percentile"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"`
+}
+
+// BenchmarkBreakdown explains how an agent's benchmark score was built.
+type BenchmarkBreakdown struct {
+	CategorySize int                  `json:"category_size"`
+	Components   []BenchmarkComponent `json:"components"`
+}
+
+// BenchmarkSnapshot is an agent's ranking on one day.
+type BenchmarkSnapshot struct {
+	Date         string              `json:"date"`
+	Category     string              `json:"category"`
+	Rank         int                 `json:"rank"`
+	CategorySize int                 `json:"category_size"`
+	Score        float64             `json:"score"`
+	Breakdown    *BenchmarkBreakdown `json:"breakdown,omitempty"`
+}
+
+const benchmarkEntryCols = `
+	bc.id, bc.category, bc.agent_id,
+	COALESCE(a.name, '') AS agent_name,
+	COALESCE(a.agent_id, '') AS agent_string_id,
+	bc.rank, bc.score, bc.total_requests,
+	bc.avg_response_ms, bc.error_rate, bc.revenue,
+	bc.customer_count, bc.calculated_at,
+	bc.previous_rank, bc.breakdown`
+
+func scanBenchmarkEntry(row interface{ Scan(...any) error }) (*BenchmarkEntry, error) {
+	e := &BenchmarkEntry{}
+	if err := row.Scan(
+		&e.ID, &e.Category, &e.AgentID,
+		&e.AgentName, &e.AgentStringID,
+		&e.Rank, &e.Score, &e.TotalRequests,
+		&e.AvgResponseMs, &e.ErrorRate, &e.Revenue,
+		&e.CustomerCount, &e.CalculatedAt,
+		&e.PreviousRank, &e.Breakdown,
+	); err != nil {
+		return nil, err
+	}
+	if e.PreviousRank != nil {
+		change := *e.PreviousRank - e.Rank
+		e.RankChange = &change
+	}
+	return e, nil
+}
+
+// SaveBenchmarkCategory writes a category's full ranking in one transaction:
+// it upserts the leaderboard, drops agents that left the category and
+// records today's snapshot. Previous ranks come from the latest snapshot
+// before today.
+func (s *Store) SaveBenchmarkCategory(ctx context.Context, category string, entries []BenchmarkEntry) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ids := make([]uuid.UUID, len(entries))
+	for i, e := range entries {
+		ids[i] = e.AgentID
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO benchmark_cache (
+				category, agent_id, rank, score, total_requests,
+				avg_response_ms, error_rate, revenue, customer_count, calculated_at,
+				breakdown, previous_rank
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, (
+				SELECT rank FROM benchmark_snapshots
+				WHERE category = $1 AND agent_id = $2 AND snapshot_date < CURRENT_DATE
+				ORDER BY snapshot_date DESC LIMIT 1
+			))
+			ON CONFLICT (category, agent_id) DO UPDATE SET
+				rank = EXCLUDED.rank,
+				score = EXCLUDED.score,
+				total_requests = EXCLUDED.total_requests,
+				avg_response_ms = EXCLUDED.avg_response_ms,
+				error_rate = EXCLUDED.error_rate,
+				revenue = EXCLUDED.revenue,
+				customer_count = EXCLUDED.customer_count,
+				calculated_at = EXCLUDED.calculated_at,
+				breakdown = EXCLUDED.breakdown,
+				previous_rank = EXCLUDED.previous_rank
+		`,
+			category, e.AgentID, e.Rank, e.Score, e.TotalRequests,
+			e.AvgResponseMs, e.ErrorRate, e.Revenue, e.CustomerCount, e.CalculatedAt,
+			e.Breakdown,
+		)
+		if err != nil {
+			return fmt.Errorf("upsert benchmark entry: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO benchmark_snapshots (
+				category, agent_id, snapshot_date, rank, category_size, score, breakdown,
+				total_requests, avg_response_ms, error_rate, revenue, customer_count, calculated_at
+			) VALUES ($1, $2, CURRENT_DATE, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			ON CONFLICT (category, agent_id, snapshot_date) DO UPDATE SET
+				rank = EXCLUDED.rank,
+				category_size = EXCLUDED.category_size,
+				score = EXCLUDED.score,
+				breakdown = EXCLUDED.breakdown,
+				total_requests = EXCLUDED.total_requests,
+				avg_response_ms = EXCLUDED.avg_response_ms,
+				error_rate = EXCLUDED.error_rate,
+				revenue = EXCLUDED.revenue,
+				customer_count = EXCLUDED.customer_count,
+				calculated_at = EXCLUDED.calculated_at
+		`,
+			category, e.AgentID, e.Rank, len(entries), e.Score, e.Breakdown,
+			e.TotalRequests, e.AvgResponseMs, e.ErrorRate, e.Revenue, e.CustomerCount, e.CalculatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("upsert benchmark snapshot: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM benchmark_cache WHERE category = $1 AND agent_id <> ALL($2)
+	`, category, ids); err != nil {
+		return fmt.Errorf("prune benchmark category: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// PruneBenchmarkCategories removes leaderboard entries for categories that
+// no longer have active agents.
+func (s *Store) PruneBenchmarkCategories(ctx context.Context, active []string) error {
+	_, err := s.pool.Exec(ctx, `
+		DELETE FROM benchmark_cache WHERE category <> ALL($1)
+	`, active)
 	if err != nil {
-		return fmt.Errorf("upsert benchmark entry: %w", err)
+		return fmt.Errorf("prune benchmark categories: %w", err)
 	}
 	return nil
 }
@@ -55,13 +182,7 @@ func (s *Store) UpsertBenchmarkEntry(ctx context.Context, entry *BenchmarkEntry)
 // GetBenchmarkByCategory returns benchmark entries for a category, joined with agent data, ordered by rank.
 func (s *Store) GetBenchmarkByCategory(ctx context.Context, category string) ([]BenchmarkEntry, error) {
 	rows, err := s.pool.Query(ctx, `
-		SELECT
-			bc.id, bc.category, bc.agent_id,
-			COALESCE(a.name, '') AS agent_name,
-			COALESCE(a.agent_id, '') AS agent_string_id,
-			bc.rank, bc.score, bc.total_requests,
-			bc.avg_response_ms, bc.error_rate, bc.revenue,
-			bc.customer_count, bc.calculated_at
+		SELECT `+benchmarkEntryCols+`
 		FROM benchmark_cache bc
 		JOIN agents a ON a.id = bc.agent_id
 		WHERE bc.category = $1
@@ -74,17 +195,11 @@ func (s *Store) GetBenchmarkByCategory(ctx context.Context, category string) ([]
 
 	var entries []BenchmarkEntry
 	for rows.Next() {
-		var e BenchmarkEntry
-		if err := rows.Scan(
-			&e.ID, &e.Category, &e.AgentID,
-			&e.AgentName, &e.AgentStringID,
-			&e.Rank, &e.Score, &e.TotalRequests,
-			&e.AvgResponseMs, &e.ErrorRate, &e.Revenue,
-			&e.CustomerCount, &e.CalculatedAt,
-		); err != nil {
+		e, err := scanBenchmarkEntry(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan benchmark entry: %w", err)
 		}
-		entries = append(entries, e)
+		entries = append(entries, *e)
 	}
 
 	if entries == nil {
@@ -94,6 +209,54 @@ func (s *Store) GetBenchmarkByCategory(ctx context.Context, category string) ([]
 	return entries, nil
 }
 
+// GetAgentBenchmark returns the current benchmark entry for an agent
+// (by agents.agent_id string).
+func (s *Store) GetAgentBenchmark(ctx context.Context, agentID string) (*BenchmarkEntry, error) {
+	e, err := scanBenchmarkEntry(s.pool.QueryRow(ctx, `
+		SELECT `+benchmarkEntryCols+`
+		FROM benchmark_cache bc
+		JOIN agents a ON a.id = bc.agent_id
+		WHERE a.agent_id = $1 AND bc.category = a.category
+	`, agentID))
+	if err != nil {
+		return nil, fmt.Errorf("get agent benchmark: %w", err)
+	}
+	return e, nil
+}
+
+// GetBenchmarkHistory returns an agent's daily ranking snapshots for the
+// last days days, oldest first.
+func (s *Store) GetBenchmarkHistory(ctx context.Context, agentDBID uuid.UUID, category string, days int) ([]BenchmarkSnapshot, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT snapshot_date, category, rank, category_size, score, breakdown
+		FROM benchmark_snapshots
+		WHERE agent_id = $1 AND category = $2
+			AND snapshot_date >= CURRENT_DATE - $3 * INTERVAL '1 day'
+		ORDER BY snapshot_date ASC
+	`, agentDBID, category, days)
+	if err != nil {
+		return nil, fmt.Errorf("get benchmark history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []BenchmarkSnapshot
+	for rows.Next() {
+		var sn BenchmarkSnapshot
+		var date time.Time
+		if err := rows.Scan(&date, &sn.Category, &sn.Rank, &sn.CategorySize, &sn.Score, &sn.Breakdown); err != nil {
+			return nil, fmt.Errorf("scan benchmark snapshot: %w", err)
+		}
+		sn.Date = date.Format("2006-01-02")
+		history = append(history, sn)
+	}
+
+	if history == nil {
+		history = []BenchmarkSnapshot{}
+	}
+
+	return history, nil
+}
+
 // GetBenchmarkCategories returns all distinct categories in the benchmark cache.
 func (s *Store) GetBenchmarkCategories(ctx context.Context) ([]string, error) {
 	rows, err := s.pool.Query(ctx, `
@@ -119,14 +282,3 @@ func (s *Store) GetBenchmarkCategories(ctx context.Context) ([]string, error) {
 
 	return categories, nil
 }
-
-// ClearBenchmarkCategory deletes all benchmark entries for a category.
-func (s *Store) ClearBenchmarkCategory(ctx context.Context, category string) error {
-	_, err := s.pool.Exec(ctx, `
-		DELETE FROM benchmark_cache WHERE category = $1
-	`, category)
-	if err != nil {
-		return fmt.Errorf("clear benchmark category: %w", err)
-	}
-	return nil
-}
@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChurnInputs holds the per-customer behaviour used to score churn risk.
+type ChurnInputs struct {
+	CustomerID string
+	LastSeenAt time.Time
+	// Inter-arrival gaps (seconds) between visits in the last 90 days.
+	// Requests closer together than churnVisitGap belong to the same visit.
+	GapCount     int
+	MedianGapSec float64
+	P90GapSec    float64
+	// Request counts in the last 14 days and the 14 days before that.
+	RecentRequests   int64
+	PreviousRequests int64
+	// Requests and errors in the last 30 days.
+	Requests30d int64
+	Errors30d   int64
+	// Verified revenue in the last 14 days and the 14 days before that.
+	RecentRevenue   float64
+	PreviousRevenue float64
+	TotalRevenue    float64
+}
+
+// ChurnScore is a computed churn score for one customer.
+type ChurnScore struct {
+	CustomerID string
+	Score      float64
+	Risk       string
+	Factors    json.RawMessage
+}
+
+// AtRiskCustomer is a customer with its stored churn score and factors.
+type AtRiskCustomer struct {
+	CustomerID    string          `json:"customer_id"`
+	FirstSeenAt   time.Time       `json:"first_seen_at"`
+	LastSeenAt    time.Time       `json:"last_seen_at"`
+	TotalRequests int64           `json:"total_requests"`
+	TotalRevenue  float64         `json:"total_revenue"`
+	ChurnRisk     string          `json:"churn_risk"`
+	ChurnScore    float64         `json:"churn_score"`
+	ChurnFactors  json.RawMessage `json:"churn_factors"`
+	ScoredAt      time.Time       `json:"scored_at"`
+}
+
+// churnVisitGap is the minimum idle time in seconds that separates visits.
+const churnVisitGap = 1800
+
+// GetChurnInputs returns churn scoring inputs for every customer of an agent.
+func (s *Store) GetChurnInputs(ctx context.Context, agentDBID uuid.UUID) ([]ChurnInputs, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH arrivals AS (
+			SELECT ip_address AS customer,
+				EXTRACT(EPOCH FROM created_at - LAG(created_at) OVER (
+					PARTITION BY ip_address ORDER BY created_at
+				)) AS gap
+			FROM request_logs
+			WHERE agent_id = $1 AND ip_address IS NOT NULL
+				AND created_at >= NOW() - INTERVAL '90 days'
+		),
+		gaps AS (
+			SELECT customer, COUNT(*) AS n,
+				percentile_cont(0.5) WITHIN GROUP (ORDER BY gap) AS median_gap,
+				percentile_cont(0.9) WITHIN GROUP (ORDER BY gap) AS p90_gap
+			FROM arrivals
+			WHERE gap >= $2
+			GROUP BY customer
+		),
+		usage AS (
+			SELECT ip_address AS customer,
+				COUNT(*) FILTER (WHERE created_at >= NOW() - INTERVAL '14 days') AS recent,
+				COUNT(*) FILTER (WHERE created_at < NOW() - INTERVAL '14 days'
+					AND created_at >= NOW() - INTERVAL '28 days') AS previous,
+				COUNT(*) AS requests,
+				COUNT(*) FILTER (WHERE status_code >= 400 AND status_code != 402) AS errors
+			FROM request_logs
+			WHERE agent_id = $1 AND ip_address IS NOT NULL
+				AND created_at >= NOW() - INTERVAL '30 days'
+			GROUP BY ip_address
+		),
+		payments AS (
+			SELECT customer_id AS customer,
+				COALESCE(SUM(amount) FILTER (WHERE created_at >= NOW() - INTERVAL '14 days'), 0) AS recent,
+				COALESCE(SUM(amount) FILTER (WHERE created_at < NOW() - INTERVAL '14 days'), 0) AS previous
+			FROM revenue_entries
+			WHERE agent_id = $1 AND verified = TRUE AND customer_id IS NOT NULL
+				AND created_at >= NOW() - INTERVAL '28 days'
+			GROUP BY customer_id
+		)
+		SELECT c.customer_id, c.last_seen_at,
+			COALESCE(g.n, 0), COALESCE(g.median_gap, 0)::float8, COALESCE(g.p90_gap, 0)::float8,
+			COALESCE(u.recent, 0), COALESCE(u.previous, 0),
+			COALESCE(u.requests, 0), COALESCE(u.errors, 0),
+			COALESCE(p.recent, 0)::float8, COALESCE(p.previous, 0)::float8,
+			COALESCE(c.total_revenue, 0)::float8
+		FROM customers c
+		LEFT JOIN gaps g ON g.customer = c.customer_id
+		LEFT JOIN usage u ON u.customer = c.customer_id
+		LEFT JOIN payments p ON p.customer = c.customer_id
+		WHERE c.agent_id = $1
+	`, agentDBID, churnVisitGap)
+	if err != nil {
+		return nil, fmt.Errorf("get churn inputs: %w", err)
+	}
+	defer rows.Close()
+
+	var inputs []ChurnInputs
+	for rows.Next() {
+		var in ChurnInputs
+		if err := rows.Scan(
+			&in.CustomerID, &in.LastSeenAt,
+			&in.GapCount, &in.MedianGapSec, &in.P90GapSec,
+			&in.RecentRequests, &in.PreviousRequests,
+			&in.Requests30d, &in.Errors30d,
+			&in.RecentRevenue, &in.PreviousRevenue,
+			&in.TotalRevenue,
+		); err != nil {
+			return nil, fmt.Errorf("scan churn inputs: %w", err)
+		}
+		inputs = append(inputs, in)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate churn inputs: %w", err)
+	}
+
+	return inputs, nil
+}
+
+// UpdateChurnScores writes churn scores, risk labels and factors in one statement.
+func (s *Store) UpdateChurnScores(ctx context.Context, agentDBID uuid.UUID, scores []ChurnScore) error {
+	if len(scores) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(scores))
+	values := make([]float64, len(scores))
+	risks := make([]string, len(scores))
+	factors := make([]string, len(scores))
+	for i, sc := range scores {
+		ids[i] = sc.CustomerID
+		values[i] = sc.Score
+		risks[i] = sc.Risk
+		factors[i] = string(sc.Factors)
+	}
+
+	_, err := s.pool.Exec(ctx, `
+		UPDATE customers c
+		SET churn_score = u.score,
+			churn_risk = u.risk,
+			churn_factors = u.factors::jsonb,
+			churn_scored_at = NOW(),
+			updated_at = NOW()
+		FROM unnest($2::text[], $3::float8[], $4::text[], $5::text[]) AS u(customer_id, score, risk, factors)
+		WHERE c.agent_id = $1 AND c.customer_id = u.customer_id
+	`, agentDBID, ids, values, risks, factors)
+	if err != nil {
+		return fmt.Errorf("update churn scores: %w", err)
+	}
+	return nil
+}
+
+// GetAtRiskCustomers returns scored customers at or above minScore, highest
+// score first.
+func (s *Store) GetAtRiskCustomers(ctx context.Context, agentDBID uuid.UUID, minScore float64, limit, offset int) ([]AtRiskCustomer, int, error) {
+	var total int
+	err := s.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM customers
+		WHERE agent_id = $1 AND churn_score IS NOT NULL AND churn_score >= $2
+	`, agentDBID, minScore).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count at-risk customers: %w", err)
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT customer_id, first_seen_at, last_seen_at, total_requests,
+			COALESCE(total_revenue, 0)::float8, churn_risk, churn_score::float8,
+			COALESCE(churn_factors, '{}'::jsonb), churn_scored_at
+		FROM customers
+		WHERE agent_id = $1 AND churn_score IS NOT NULL AND churn_score >= $2
+		ORDER BY churn_score DESC, total_revenue DESC
+		LIMIT $3 OFFSET $4
+	`, agentDBID, minScore, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get at-risk customers: %w", err)
+	}
+	defer rows.Close()
+
+	var customers []AtRiskCustomer
+	for rows.Next() {
+		var c AtRiskCustomer
+		if err := rows.Scan(
+			&c.CustomerID, &c.FirstSeenAt, &c.LastSeenAt, &c.TotalRequests,
+			&c.TotalRevenue, &c.ChurnRisk, &c.ChurnScore,
+			&c.ChurnFactors, &c.ScoredAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("scan at-risk customer: %w", err)
+		}
+		customers = append(customers, c)
+	}
+
+	if customers == nil {
+		customers = []AtRiskCustomer{}
+	}
+
+	return customers, total, nil
+}
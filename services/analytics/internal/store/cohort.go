@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CohortActivity is one cell of a cohort retention matrix: the customers
+// first seen in Cohort that were active in Period, and the verified revenue
+// they generated in that period.
+type CohortActivity struct {
+	Cohort    time.Time
+	Period    time.Time
+	Customers int
+	Revenue   float64
+}
+
+// GetCohortActivity returns per-(cohort, period) activity for customers whose
+// first request falls on or after since. unit is a date_trunc unit ("week"
+// or "month"). Cohorts are assigned from the full request history, so
+// customers first seen before since are excluded rather than re-cohorted.
+// Periods are truncated in UTC.
+func (s *Store) GetCohortActivity(ctx context.Context, agentDBID uuid.UUID, unit string, since time.Time) ([]CohortActivity, error) {
+	if unit != "week" && unit != "month" {
+		return nil, fmt.Errorf("invalid cohort unit %q", unit)
+	}
+
+	query := fmt.Sprintf(`
+		WITH firsts AS (
+			SELECT ip_address AS customer, date_trunc('%[1]s', MIN(created_at) AT TIME ZONE 'UTC') AS cohort
+			FROM request_logs
+			WHERE agent_id = $1 AND ip_address IS NOT NULL
+			GROUP BY ip_address
+			HAVING MIN(created_at) >= $2
+		),
+		activity AS (
+			SELECT DISTINCT ip_address AS customer, date_trunc('%[1]s', created_at AT TIME ZONE 'UTC') AS period
+			FROM request_logs
+			WHERE agent_id = $1 AND ip_address IS NOT NULL AND created_at >= $2
+		),
+		revenue AS (
+			SELECT customer_id AS customer, date_trunc('%[1]s', created_at AT TIME ZONE 'UTC') AS period,
+				SUM(amount) AS amount
+			FROM revenue_entries
+			WHERE agent_id = $1 AND verified = TRUE AND customer_id IS NOT NULL AND created_at >= $2
+			GROUP BY 1, 2
+		)
+		SELECT f.cohort, a.period, COUNT(*) AS customers,
+			COALESCE(SUM(r.amount), 0)::float8 AS revenue
+		FROM firsts f
+		JOIN activity a ON a.customer = f.customer
+		LEFT JOIN revenue r ON r.customer = a.customer AND r.period = a.period
+		GROUP BY f.cohort, a.period
+		ORDER BY f.cohort, a.period
+	`, unit)
+
+	rows, err := s.pool.Query(ctx, query, agentDBID, since)
+	if err != nil {
+		return nil, fmt.Errorf("get cohort activity: %w", err)
+	}
+	defer rows.Close()
+
+	var cells []CohortActivity
+	for rows.Next() {
+		var c CohortActivity
+		if err := rows.Scan(&c.Cohort, &c.Period, &c.Customers, &c.Revenue); err != nil {
+			return nil, fmt.Errorf("scan cohort activity: %w", err)
+		}
+		cells = append(cells, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate cohort activity: %w", err)
+	}
+
+	if cells == nil {
+		cells = []CohortActivity{}
+	}
+
+	return cells, nil
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------- Cost Settings ----------
+
+// CostSettings are the operator-entered cost inputs for an agent. Token
+// rates are per million tokens, in the agent's pricing currency.
+type CostSettings struct {
+	FixedMonthlyCost float64    `json:"fixed_monthly_cost"`
+	InputTokenRate   float64    `json:"input_token_rate"`
+	OutputTokenRate  float64    `json:"output_token_rate"`
+	Tools            []ToolCost `json:"tools"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+}
+
+// ToolCost is the cost of serving one request of a tool. Token rates, when
+// set, override the agent-wide rates for the tool.
+type ToolCost struct {
+	ToolName        string   `json:"tool_name"`
+	CostPerRequest  float64  `json:"cost_per_request"`
+	InputTokenRate  *float64 `json:"input_token_rate,omitempty"`
+	OutputTokenRate *float64 `json:"output_token_rate,omitempty"`
+}
+
+// GetCostSettings returns the agent's cost inputs. An agent that has not
+// entered any costs gets zero values and no tools.
+func (s *Store) GetCostSettings(ctx context.Context, agentDBID uuid.UUID) (*CostSettings, error) {
+	cs := &CostSettings{Tools: []ToolCost{}}
+	var updatedAt time.Time
+	err := s.pool.QueryRow(ctx, `
+		SELECT fixed_monthly_cost::float8, input_token_rate::float8, output_token_rate::float8, updated_at
+		FROM agent_cost_settings
+		WHERE agent_id = $1
+	`, agentDBID).Scan(&cs.FixedMonthlyCost, &cs.InputTokenRate, &cs.OutputTokenRate, &updatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("get cost settings: %w", err)
+	}
+	if err == nil {
+		cs.UpdatedAt = &updatedAt
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT tool_name, cost_per_request::float8, input_token_rate::float8, output_token_rate::float8, updated_at
+		FROM tool_costs
+		WHERE agent_id = $1
+		ORDER BY tool_name
+	`, agentDBID)
+	if err != nil {
+		return nil, fmt.Errorf("get tool costs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tc ToolCost
+		var toolUpdatedAt time.Time
+		if err := rows.Scan(&tc.ToolName, &tc.CostPerRequest, &tc.InputTokenRate, &tc.OutputTokenRate, &toolUpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan tool cost: %w", err)
+		}
+		if cs.UpdatedAt == nil || toolUpdatedAt.After(*cs.UpdatedAt) {
+			cs.UpdatedAt = &toolUpdatedAt
+		}
+		cs.Tools = append(cs.Tools, tc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get tool costs: %w", err)
+	}
+	return cs, nil
+}
+
+// SaveCostSettings replaces the agent's cost inputs: the agent-wide values
+// are upserted and the per-tool list replaces the stored one.
+func (s *Store) SaveCostSettings(ctx context.Context, agentDBID uuid.UUID, cs *CostSettings) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO agent_cost_settings (agent_id, fixed_monthly_cost, input_token_rate, output_token_rate, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (agent_id) DO UPDATE SET
+			fixed_monthly_cost = EXCLUDED.fixed_monthly_cost,
+			input_token_rate = EXCLUDED.input_token_rate,
+			output_token_rate = EXCLUDED.output_token_rate,
+			updated_at = NOW()
+	`, agentDBID, cs.FixedMonthlyCost, cs.InputTokenRate, cs.OutputTokenRate)
+	if err != nil {
+		return fmt.Errorf("save cost settings: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM tool_costs WHERE agent_id = $1`, agentDBID); err != nil {
+		return fmt.Errorf("clear tool costs: %w", err)
+	}
+	for _, tc := range cs.Tools {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO tool_costs (agent_id, tool_name, cost_per_request, input_token_rate, output_token_rate, updated_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+		`, agentDBID, tc.ToolName, tc.CostPerRequest, tc.InputTokenRate, tc.OutputTokenRate)
+		if err != nil {
+			return fmt.Errorf("save tool cost: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// ---------- Margins ----------
+
+// Margin groupings.
+const (
+	MarginByTool     = "tool"
+	MarginByCustomer = "customer"
+	MarginByDay      = "day"
+)
+
+// marginKeys maps a grouping to its key expression over request_logs (rl)
+// and over revenue_entries. $3 is the reporting time zone.
+var marginKeys = map[string][2]string{
+	MarginByTool:     {"COALESCE(rl.tool_name, 'unknown')", "COALESCE(tool_name, 'unknown')"},
+	MarginByCustomer: {"COALESCE(rl.ip_address, 'unknown')", "COALESCE(customer_id, 'unknown')"},
+	MarginByDay:      {"(rl.created_at AT TIME ZONE $3)::date::text", "(created_at AT TIME ZONE $3)::date::text"},
+}
+
+// MarginUsage is the traffic, variable cost and verified revenue of one
+// margin group.
+type MarginUsage struct {
+	Key          string  `json:"key"`
+	Requests     int64   `json:"requests"`
+	PaidRequests int64   `json:"paid_requests"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	VariableCost float64 `json:"variable_cost"`
+	Revenue      float64 `json:"revenue"`
+}
+
+// GetMarginUsage returns, per group since the given time, request counts,
+// the variable cost of serving them and verified revenue. A request costs its
+// tool's per-request cost plus its input and output tokens at the tool's
+// token rates, falling back to the agent-wide rates. Groups with revenue but
+// no traffic (or the reverse) are included.
+func (s *Store) GetMarginUsage(ctx context.Context, agentDBID uuid.UUID, groupBy string, since time.Time, loc *time.Location) ([]MarginUsage, error) {
+	keys, ok := marginKeys[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("get margin usage: unknown grouping %q", groupBy)
+	}
+	args := []any{agentDBID, since}
+	if groupBy == MarginByDay {
+		args = append(args, loc.String())
+	}
+
+	query := fmt.Sprintf(`
+		WITH settings AS (
+			SELECT input_token_rate, output_token_rate
+			FROM agent_cost_settings
+			WHERE agent_id = $1
+		),
+		usage AS (
+			SELECT
+				%s AS key,
+				COUNT(*) AS requests,
+				COUNT(*) FILTER (WHERE rl.x402_amount IS NOT NULL AND rl.x402_amount > 0) AS paid_requests,
+				COALESCE(SUM(rl.input_tokens), 0) AS input_tokens,
+				COALESCE(SUM(rl.output_tokens), 0) AS output_tokens,
+				COALESCE(SUM(
+					COALESCE(tc.cost_per_request, 0)
+					+ COALESCE(rl.input_tokens, 0) * COALESCE(tc.input_token_rate, st.input_token_rate, 0) / 1000000
+					+ COALESCE(rl.output_tokens, 0) * COALESCE(tc.output_token_rate, st.output_token_rate, 0) / 1000000
+				), 0)::float8 AS variable_cost
+			FROM request_logs rl
+			LEFT JOIN tool_costs tc ON tc.agent_id = rl.agent_id AND tc.tool_name = rl.tool_name
+			LEFT JOIN settings st ON TRUE
+			WHERE rl.agent_id = $1 AND rl.created_at >= $2
+			GROUP BY 1
+		),
+		rev AS (
+			SELECT %s AS key, COALESCE(SUM(amount), 0)::float8 AS revenue
+			FROM revenue_entries
+			WHERE agent_id = $1 AND verified = TRUE AND created_at >= $2
+			GROUP BY 1
+		)
+		SELECT
+			COALESCE(u.key, r.key),
+			COALESCE(u.requests, 0),
+			COALESCE(u.paid_requests, 0),
+			COALESCE(u.input_tokens, 0),
+			COALESCE(u.output_tokens, 0),
+			COALESCE(u.variable_cost, 0),
+			COALESCE(r.revenue, 0)
+		FROM usage u
+		FULL OUTER JOIN rev r ON r.key = u.key
+		ORDER BY 1
+	`, keys[0], keys[1])
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get margin usage: %w", err)
+	}
+	defer rows.Close()
+
+	result := []MarginUsage{}
+	for rows.Next() {
+		var m MarginUsage
+		if err := rows.Scan(&m.Key, &m.Requests, &m.PaidRequests, &m.InputTokens, &m.OutputTokens, &m.VariableCost, &m.Revenue); err != nil {
+			return nil, fmt.Errorf("scan margin usage: %w", err)
+		}
+		result = append(result, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get margin usage: %w", err)
+	}
+	return result, nil
+}
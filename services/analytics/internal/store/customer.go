@@ -22,16 +22,17 @@ type Customer struct {
 	ChurnRisk      string    `json:"churn_risk"`
 	Country        string    `json:"country"`
 	City           string    `json:"city"`
+	// RFM scores (1-5), segment and projected LTV; nil until the customer
+	// has been segmented.
+	RecencyScore   *int     `json:"recency_score,omitempty"`
+	FrequencyScore *int     `json:"frequency_score,omitempty"`
+	MonetaryScore  *int     `json:"monetary_score,omitempty"`
+	Segment        *string  `json:"segment,omitempty"`
+	ProjectedLTV   *float64 `json:"projected_ltv,omitempty"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
 
-// CustomerCohort represents a monthly cohort of customers.
-type CustomerCohort struct {
-	Month        string `json:"month"`
-	NewCustomers int    `json:"new_customers"`
-}
-
 // UpsertCustomer inserts or updates a customer record with aggregated stats.
 func (s *Store) UpsertCustomer(ctx context.Context, agentDBID uuid.UUID, customerID string, requestCount int64, revenue float64, avgMs float32, errorRate float32, country string, city string) error {
 	_, err := s.pool.Exec(ctx, `
@@ -73,7 +74,8 @@ func (s *Store) GetCustomers(ctx context.Context, agentDBID uuid.UUID, limit, of
 	rows, err := s.pool.Query(ctx, `
 		SELECT id, agent_id, customer_id, first_seen_at, last_seen_at,
 			total_requests, total_revenue, avg_response_ms, error_rate, churn_risk,
-			country, city, created_at, updated_at
+			country, city, rfm_recency, rfm_frequency, rfm_monetary, segment,
+			projected_ltv::float8, created_at, updated_at
 		FROM customers
 		WHERE agent_id = $1
 		ORDER BY last_seen_at DESC
@@ -90,7 +92,8 @@ func (s *Store) GetCustomers(ctx context.Context, agentDBID uuid.UUID, limit, of
 		if err := rows.Scan(
 			&c.ID, &c.AgentID, &c.CustomerID, &c.FirstSeenAt, &c.LastSeenAt,
 			&c.TotalRequests, &c.TotalRevenue, &c.AvgResponseMs, &c.ErrorRate, &c.ChurnRisk,
-			&c.Country, &c.City, &c.CreatedAt, &c.UpdatedAt,
+			&c.Country, &c.City, &c.RecencyScore, &c.FrequencyScore, &c.MonetaryScore, &c.Segment,
+			&c.ProjectedLTV, &c.CreatedAt, &c.UpdatedAt,
 		); err != nil {
 			return nil, 0, fmt.Errorf("scan customer: %w", err)
 		}
@@ -110,13 +113,15 @@ func (s *Store) GetCustomer(ctx context.Context, agentDBID uuid.UUID, customerID
 	err := s.pool.QueryRow(ctx, `
 		SELECT id, agent_id, customer_id, first_seen_at, last_seen_at,
 			total_requests, total_revenue, avg_response_ms, error_rate, churn_risk,
-			country, city, created_at, updated_at
+			country, city, rfm_recency, rfm_frequency, rfm_monetary, segment,
+			projected_ltv::float8, created_at, updated_at
 		FROM customers
 		WHERE agent_id = $1 AND customer_id = $2
 	`, agentDBID, customerID).Scan(
 		&c.ID, &c.AgentID, &c.CustomerID, &c.FirstSeenAt, &c.LastSeenAt,
 		&c.TotalRequests, &c.TotalRevenue, &c.AvgResponseMs, &c.ErrorRate, &c.ChurnRisk,
-		&c.CreatedAt, &c.UpdatedAt,
+		&c.Country, &c.City, &c.RecencyScore, &c.FrequencyScore, &c.MonetaryScore, &c.Segment,
+		&c.ProjectedLTV, &c.CreatedAt, &c.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("get customer: %w", err)
@@ -124,56 +129,6 @@ func (s *Store) GetCustomer(ctx context.Context, agentDBID uuid.UUID, customerID
 	return c, nil
 }
 
-// GetCustomerCohorts returns monthly cohorts grouped by first_seen month.
-func (s *Store) GetCustomerCohorts(ctx context.Context, agentDBID uuid.UUID) ([]CustomerCohort, error) {
-	rows, err := s.pool.Query(ctx, `
-		SELECT to_char(date_trunc('month', first_seen_at), 'YYYY-MM') AS month,
-			COUNT(*) AS new_customers
-		FROM customers
-		WHERE agent_id = $1
-		GROUP BY date_trunc('month', first_seen_at)
-		ORDER BY date_trunc('month', first_seen_at) DESC
-	`, agentDBID)
-	if err != nil {
-		return nil, fmt.Errorf("get customer cohorts: %w", err)
-	}
-	defer rows.Close()
-
-	var cohorts []CustomerCohort
-	for rows.Next() {
-		var c CustomerCohort
-		if err := rows.Scan(&c.Month, &c.NewCustomers); err != nil {
-			return nil, fmt.Errorf("scan customer cohort: %w", err)
-		}
-		cohorts = append(cohorts, c)
-	}
-
-	if cohorts == nil {
-		cohorts = []CustomerCohort{}
-	}
-
-	return cohorts, nil
-}
-
-// UpdateChurnRisk bulk-updates churn risk for all customers of an agent.
-// 14+ days inactive = high, 7+ days = medium, else low.
-func (s *Store) UpdateChurnRisk(ctx context.Context, agentDBID uuid.UUID) error {
-	_, err := s.pool.Exec(ctx, `
-		UPDATE customers
-		SET churn_risk = CASE
-			WHEN last_seen_at < NOW() - INTERVAL '14 days' THEN 'high'
-			WHEN last_seen_at < NOW() - INTERVAL '7 days' THEN 'medium'
-			ELSE 'low'
-		END,
-		updated_at = NOW()
-		WHERE agent_id = $1
-	`, agentDBID)
-	if err != nil {
-		return fmt.Errorf("update churn risk: %w", err)
-	}
-	return nil
-}
-
 // GetDistinctCustomerCount returns the number of distinct customers for an agent.
 func (s *Store) GetDistinctCustomerCount(ctx context.Context, agentDBID uuid.UUID) (int, error) {
 	var count int
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ---------- Deployments ----------
+
+// Deployment is a release marker recorded by CI through the ingest service.
+type Deployment struct {
+	ID         uuid.UUID `json:"id"`
+	AgentID    uuid.UUID `json:"-"`
+	Version    string    `json:"version"`
+	CommitSHA  *string   `json:"commit,omitempty"`
+	DeployedAt time.Time `json:"deployed_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+const deploymentCols = `id, agent_id, version, commit_sha, deployed_at, created_at`
+
+func scanDeployment(row interface{ Scan(...any) error }) (*Deployment, error) {
+	var d Deployment
+	if err := row.Scan(&d.ID, &d.AgentID, &d.Version, &d.CommitSHA, &d.DeployedAt, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListDeployments returns an agent's deployments in [from, to), newest first.
+func (s *Store) ListDeployments(ctx context.Context, agentDBID uuid.UUID, from, to time.Time, limit int) ([]Deployment, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+deploymentCols+`
+		FROM deployments
+		WHERE agent_id = $1 AND deployed_at >= $2 AND deployed_at < $3
+		ORDER BY deployed_at DESC
+		LIMIT $4
+	`, agentDBID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list deployments: %w", err)
+	}
+	defer rows.Close()
+
+	deployments := []Deployment{}
+	for rows.Next() {
+		d, err := scanDeployment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan deployment: %w", err)
+		}
+		deployments = append(deployments, *d)
+	}
+	return deployments, rows.Err()
+}
+
+// GetDeployment returns one deployment of an agent.
+func (s *Store) GetDeployment(ctx context.Context, agentDBID, deploymentID uuid.UUID) (*Deployment, error) {
+	d, err := scanDeployment(s.pool.QueryRow(ctx, `
+		SELECT `+deploymentCols+` FROM deployments WHERE agent_id = $1 AND id = $2
+	`, agentDBID, deploymentID))
+	if err != nil {
+		return nil, fmt.Errorf("get deployment: %w", err)
+	}
+	return d, nil
+}
+
+// GetNeighborDeploymentTimes returns when the agent's previous and next
+// deployments around d happened; either is nil when there is none.
+func (s *Store) GetNeighborDeploymentTimes(ctx context.Context, d *Deployment) (prev, next *time.Time, err error) {
+	err = s.pool.QueryRow(ctx, `
+		SELECT
+			(SELECT MAX(deployed_at) FROM deployments
+			 WHERE agent_id = $1 AND id != $2 AND deployed_at < $3),
+			(SELECT MIN(deployed_at) FROM deployments
+			 WHERE agent_id = $1 AND id != $2 AND deployed_at > $3)
+	`, d.AgentID, d.ID, d.DeployedAt).Scan(&prev, &next)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get neighbor deployments: %w", err)
+	}
+	return prev, next, nil
+}
+
+// ReleaseWindowMetrics holds the raw sums needed to compare two time windows
+// with significance tests.
+type ReleaseWindowMetrics struct {
+	Requests        int64
+	Errors          int64
+	LatencySamples  int64
+	MeanResponseMs  float64
+	StddevMs        float64
+	P95ResponseMs   float64
+	Customers       int64
+	PayingCustomers int64
+	Revenue         float64 // verified, matched by tx hash
+	RevenueSumSq    float64 // sum of squared per-request verified revenue
+}
+
+// GetReleaseWindowMetrics aggregates an agent's requests in [from, to).
+func (s *Store) GetReleaseWindowMetrics(ctx context.Context, agentDBID uuid.UUID, from, to time.Time) (*ReleaseWindowMetrics, error) {
+	m := &ReleaseWindowMetrics{}
+	err := s.pool.QueryRow(ctx, `
+		WITH logs AS (
+			SELECT response_ms, status_code, ip_address, x402_amount, x402_tx_hash
+			FROM request_logs
+			WHERE agent_id = $1 AND created_at >= $2 AND created_at < $3
+		),
+		rev AS (
+			SELECT tx_hash, MAX(amount) AS amount
+			FROM revenue_entries
+			WHERE agent_id = $1 AND verified = TRUE AND created_at >= $2 AND tx_hash IS NOT NULL
+			GROUP BY tx_hash
+		)
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status_code >= 400 AND status_code != 402),
+			COUNT(response_ms),
+			COALESCE(AVG(response_ms), 0),
+			COALESCE(STDDEV_SAMP(response_ms), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY response_ms), 0),
+			COUNT(DISTINCT ip_address),
+			COUNT(DISTINCT ip_address) FILTER (WHERE x402_amount IS NOT NULL AND x402_amount > 0),
+			COALESCE(SUM(rev.amount), 0)::float8,
+			COALESCE(SUM(rev.amount * rev.amount), 0)::float8
+		FROM logs
+		LEFT JOIN rev ON rev.tx_hash = logs.x402_tx_hash
+	`, agentDBID, from, to).Scan(
+		&m.Requests, &m.Errors, &m.LatencySamples, &m.MeanResponseMs, &m.StddevMs,
+		&m.P95ResponseMs, &m.Customers, &m.PayingCustomers, &m.Revenue, &m.RevenueSumSq,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get release window metrics: %w", err)
+	}
+	return m, nil
+}
@@ -0,0 +1,410 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Export datasets.
+const (
+	ExportDatasetLogs      = "logs"
+	ExportDatasetCustomers = "customers"
+	ExportDatasetRevenue   = "revenue"
+)
+
+// Export job statuses.
+const (
+	ExportStatusPending   = "pending"
+	ExportStatusRunning   = "running"
+	ExportStatusCompleted = "completed"
+	ExportStatusFailed    = "failed"
+	ExportStatusExpired   = "expired"
+)
+
+// ExportJob is a queued or finished data export for an agent.
+// Filters reuse the log search filter; customers honour only the time range,
+// ip and country, and revenue only the time range, ip and tool.
+type ExportJob struct {
+	ID          uuid.UUID       `json:"id"`
+	AgentID     uuid.UUID       `json:"agent_id"`
+	Dataset     string          `json:"dataset"`
+	Format      string          `json:"format"`
+	Filters     LogSearchFilter `json:"filters"`
+	Status      string          `json:"status"`
+	RowCount    int64           `json:"row_count"`
+	SizeBytes   int64           `json:"size_bytes"`
+	BlobKey     *string         `json:"-"`
+	Error       *string         `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty"`
+}
+
+// ExportColumn describes one output column. Type is one of
+// "string", "int", "float", "bool" or "time".
+type ExportColumn struct {
+	Name string
+	Type string
+}
+
+var exportColumns = map[string][]ExportColumn{
+	ExportDatasetLogs: {
+		{"id", "int"}, {"created_at", "time"}, {"request_id", "string"},
+		{"tool_name", "string"}, {"method", "string"}, {"path", "string"},
+		{"status_code", "int"}, {"response_ms", "float"}, {"error_type", "string"},
+		{"x402_amount", "float"}, {"x402_tx_hash", "string"}, {"x402_token", "string"}, {"x402_payer", "string"},
+		{"request_body_size", "int"}, {"response_body_size", "int"},
+		{"protocol", "string"}, {"source", "string"}, {"sdk_version", "string"}, {"batch_id", "string"},
+		{"ip_address", "string"}, {"user_agent", "string"}, {"referer", "string"},
+		{"content_type", "string"}, {"accept_language", "string"},
+		{"country", "string"}, {"city", "string"},
+	},
+	ExportDatasetCustomers: {
+		{"customer_id", "string"}, {"first_seen_at", "time"}, {"last_seen_at", "time"},
+		{"total_requests", "int"}, {"total_revenue", "float"},
+		{"avg_response_ms", "float"}, {"error_rate", "float"}, {"churn_risk", "string"},
+		{"country", "string"}, {"city", "string"},
+	},
+	ExportDatasetRevenue: {
+		{"id", "int"}, {"created_at", "time"}, {"customer_id", "string"}, {"tool_name", "string"},
+		{"amount", "float"}, {"currency", "string"}, {"tx_hash", "string"}, {"payer_address", "string"},
+		{"verified", "bool"}, {"chain_id", "int"}, {"verified_at", "time"},
+	},
+}
+
+// ExportColumns returns the output columns for a dataset, or nil if unknown.
+func ExportColumns(dataset string) []ExportColumn {
+	return exportColumns[dataset]
+}
+
+const exportJobCols = `
+	id, agent_id, dataset, format, filters, status, row_count, size_bytes,
+	blob_key, error, created_at, started_at, completed_at, expires_at
+`
+
+func scanExportJob(scan func(dest ...any) error) (*ExportJob, error) {
+	j := &ExportJob{}
+	var filters []byte
+	err := scan(
+		&j.ID, &j.AgentID, &j.Dataset, &j.Format, &filters, &j.Status, &j.RowCount, &j.SizeBytes,
+		&j.BlobKey, &j.Error, &j.CreatedAt, &j.StartedAt, &j.CompletedAt, &j.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(filters) > 0 {
+		if err := json.Unmarshal(filters, &j.Filters); err != nil {
+			return nil, fmt.Errorf("decode export filters: %w", err)
+		}
+	}
+	return j, nil
+}
+
+// CreateExportJob queues a new export job.
+func (s *Store) CreateExportJob(ctx context.Context, job *ExportJob) error {
+	filters, err := json.Marshal(job.Filters)
+	if err != nil {
+		return fmt.Errorf("encode export filters: %w", err)
+	}
+	job.Status = ExportStatusPending
+	err = s.pool.QueryRow(ctx, `
+		INSERT INTO export_jobs (agent_id, dataset, format, filters, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, job.AgentID, job.Dataset, job.Format, filters, job.Status).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert export job: %w", err)
+	}
+	return nil
+}
+
+// GetExportJob returns an export job owned by the given agent.
+func (s *Store) GetExportJob(ctx context.Context, agentDBID, id uuid.UUID) (*ExportJob, error) {
+	row := s.pool.QueryRow(ctx, `SELECT `+exportJobCols+` FROM export_jobs WHERE id = $1 AND agent_id = $2`, id, agentDBID)
+	j, err := scanExportJob(row.Scan)
+	if err != nil {
+		return nil, fmt.Errorf("get export job: %w", err)
+	}
+	return j, nil
+}
+
+// GetExportJobByID returns an export job regardless of owner. Used by the
+// signed download endpoint, where the signature is the authorization.
+func (s *Store) GetExportJobByID(ctx context.Context, id uuid.UUID) (*ExportJob, error) {
+	row := s.pool.QueryRow(ctx, `SELECT `+exportJobCols+` FROM export_jobs WHERE id = $1`, id)
+	j, err := scanExportJob(row.Scan)
+	if err != nil {
+		return nil, fmt.Errorf("get export job by id: %w", err)
+	}
+	return j, nil
+}
+
+// ListExportJobs returns the most recent export jobs for an agent.
+func (s *Store) ListExportJobs(ctx context.Context, agentDBID uuid.UUID, limit int) ([]ExportJob, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+exportJobCols+` FROM export_jobs
+		WHERE agent_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, agentDBID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []ExportJob{}
+	for rows.Next() {
+		j, err := scanExportJob(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("scan export job: %w", err)
+		}
+		jobs = append(jobs, *j)
+	}
+	return jobs, rows.Err()
+}
+
+// ClaimExportJob atomically moves the oldest pending job to running and
+// returns it. Returns nil, nil when the queue is empty.
+func (s *Store) ClaimExportJob(ctx context.Context) (*ExportJob, error) {
+	row := s.pool.QueryRow(ctx, `
+		UPDATE export_jobs SET status = 'running', started_at = NOW()
+		WHERE id = (
+			SELECT id FROM export_jobs
+			WHERE status = 'pending'
+			ORDER BY created_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING `+exportJobCols)
+	j, err := scanExportJob(row.Scan)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim export job: %w", err)
+	}
+	return j, nil
+}
+
+// CompleteExportJob records a successful export.
+func (s *Store) CompleteExportJob(ctx context.Context, id uuid.UUID, rowCount, sizeBytes int64, blobKey string, expiresAt time.Time) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE export_jobs
+		SET status = 'completed', row_count = $2, size_bytes = $3, blob_key = $4,
+			expires_at = $5, completed_at = NOW()
+		WHERE id = $1
+	`, id, rowCount, sizeBytes, blobKey, expiresAt)
+	if err != nil {
+		return fmt.Errorf("complete export job: %w", err)
+	}
+	return nil
+}
+
+// FailExportJob records a failed export.
+func (s *Store) FailExportJob(ctx context.Context, id uuid.UUID, message string) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE export_jobs SET status = 'failed', error = $2, completed_at = NOW()
+		WHERE id = $1
+	`, id, message)
+	if err != nil {
+		return fmt.Errorf("fail export job: %w", err)
+	}
+	return nil
+}
+
+// FailStaleExportJobs fails jobs that have been running longer than maxAge,
+// e.g. because the instance processing them was terminated.
+func (s *Store) FailStaleExportJobs(ctx context.Context, maxAge time.Duration) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE export_jobs SET status = 'failed', error = 'export timed out', completed_at = NOW()
+		WHERE status = 'running' AND started_at < NOW() - $1 * INTERVAL '1 second'
+	`, int64(maxAge.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("fail stale export jobs: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ExpireExportJobs marks completed jobs past their expiry as expired and
+// returns the blob keys that should be deleted.
+func (s *Store) ExpireExportJobs(ctx context.Context) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `
+		UPDATE export_jobs e SET status = 'expired', blob_key = NULL
+		FROM (
+			SELECT id, blob_key FROM export_jobs
+			WHERE status = 'completed' AND expires_at < NOW()
+			FOR UPDATE SKIP LOCKED
+		) old
+		WHERE e.id = old.id
+		RETURNING old.blob_key
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("expire export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key *string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("scan expired export: %w", err)
+		}
+		if key != nil {
+			keys = append(keys, *key)
+		}
+	}
+	return keys, rows.Err()
+}
+
+// FetchExportBatch returns up to limit rows of the job's dataset after the
+// given cursor ("" for the first batch), in ExportColumns order, along with
+// the cursor for the next batch ("" when exhausted). Each batch is a single
+// short query so exports never hold a transaction open.
+func (s *Store) FetchExportBatch(ctx context.Context, job *ExportJob, cursor string, limit int) ([][]any, string, error) {
+	var (
+		query string
+		args  []interface{}
+		err   error
+	)
+	f := job.Filters
+
+	switch job.Dataset {
+	case ExportDatasetLogs:
+		query = `
+			SELECT id, created_at, request_id, tool_name, method, path,
+				status_code::bigint, response_ms::float8, error_type,
+				x402_amount::float8, x402_tx_hash, x402_token, x402_payer,
+				request_body_size::bigint, response_body_size::bigint,
+				protocol, source, sdk_version, batch_id,
+				ip_address, user_agent, referer, content_type, accept_language,
+				country, city
+			FROM request_logs
+			WHERE agent_id = $1`
+		args = []interface{}{job.AgentID}
+		query, args = appendLogFilters(query, args, f)
+		if query, args, err = appendTimeIDCursor(query, args, cursor); err != nil {
+			return nil, "", fmt.Errorf("fetch export batch: %w", err)
+		}
+		query += fmt.Sprintf(" ORDER BY created_at, id LIMIT $%d", len(args)+1)
+
+	case ExportDatasetRevenue:
+		query = `
+			SELECT id, created_at, customer_id, tool_name,
+				amount::float8, currency, tx_hash, payer_address,
+				COALESCE(verified, FALSE), chain_id::bigint, verified_at
+			FROM revenue_entries
+			WHERE agent_id = $1`
+		args = []interface{}{job.AgentID}
+		if f.From != nil {
+			args = append(args, *f.From)
+			query += fmt.Sprintf(" AND created_at >= $%d", len(args))
+		}
+		if f.To != nil {
+			args = append(args, *f.To)
+			query += fmt.Sprintf(" AND created_at < $%d", len(args))
+		}
+		if len(f.Tools) > 0 {
+			args = append(args, f.Tools)
+			query += fmt.Sprintf(" AND tool_name = ANY($%d)", len(args))
+		}
+		if f.IP != "" {
+			args = append(args, f.IP)
+			query += fmt.Sprintf(" AND customer_id = $%d", len(args))
+		}
+		if query, args, err = appendTimeIDCursor(query, args, cursor); err != nil {
+			return nil, "", fmt.Errorf("fetch export batch: %w", err)
+		}
+		query += fmt.Sprintf(" ORDER BY created_at, id LIMIT $%d", len(args)+1)
+
+	case ExportDatasetCustomers:
+		query = `
+			SELECT customer_id, first_seen_at, last_seen_at,
+				total_requests, total_revenue::float8,
+				avg_response_ms::float8, error_rate::float8, churn_risk,
+				country, city
+			FROM customers
+			WHERE agent_id = $1`
+		args = []interface{}{job.AgentID}
+		// A customer is in range if it was active at any point in [from, to).
+		if f.From != nil {
+			args = append(args, *f.From)
+			query += fmt.Sprintf(" AND last_seen_at >= $%d", len(args))
+		}
+		if f.To != nil {
+			args = append(args, *f.To)
+			query += fmt.Sprintf(" AND first_seen_at < $%d", len(args))
+		}
+		if f.Country != "" {
+			args = append(args, strings.ToUpper(f.Country))
+			query += fmt.Sprintf(" AND country = $%d", len(args))
+		}
+		if f.IP != "" {
+			args = append(args, f.IP)
+			query += fmt.Sprintf(" AND customer_id = $%d", len(args))
+		}
+		if cursor != "" {
+			args = append(args, cursor)
+			query += fmt.Sprintf(" AND customer_id > $%d", len(args))
+		}
+		query += fmt.Sprintf(" ORDER BY customer_id LIMIT $%d", len(args)+1)
+
+	default:
+		return nil, "", fmt.Errorf("unknown export dataset %q", job.Dataset)
+	}
+	args = append(args, limit)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch export batch: %w", err)
+	}
+	defer rows.Close()
+
+	var batch [][]any
+	for rows.Next() {
+		vals, err := rows.Values()
+		if err != nil {
+			return nil, "", fmt.Errorf("read export row: %w", err)
+		}
+		batch = append(batch, vals)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("iterate export rows: %w", err)
+	}
+
+	if len(batch) < limit {
+		return batch, "", nil
+	}
+	last := batch[len(batch)-1]
+	if job.Dataset == ExportDatasetCustomers {
+		next, _ := last[0].(string)
+		return batch, next, nil
+	}
+	id, _ := last[0].(int64)
+	createdAt, _ := last[1].(time.Time)
+	return batch, LogCursor{CreatedAt: createdAt, ID: id}.Encode(), nil
+}
+
+// appendTimeIDCursor adds an ascending (created_at, id) keyset condition.
+func appendTimeIDCursor(query string, args []interface{}, cursor string) (string, []interface{}, error) {
+	if cursor == "" {
+		return query, args, nil
+	}
+	cur, err := DecodeLogCursor(cursor)
+	if err != nil {
+		return "", nil, err
+	}
+	args = append(args, cur.CreatedAt, cur.ID)
+	query += fmt.Sprintf(" AND (created_at, id) > ($%d, $%d)", len(args)-1, len(args))
+	return query, args, nil
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ---------- Revenue Forecast ----------
+
+// GetDailyRevenueByTool returns verified revenue per tool for each of the
+// last days complete days in loc (today excluded), oldest first. Every
+// tool's series is dense and aligned with dates; revenue without a tool is
+// reported under "unknown".
+func (s *Store) GetDailyRevenueByTool(ctx context.Context, agentDBID uuid.UUID, days int, loc *time.Location) ([]string, map[string][]float64, error) {
+	since, dates := DayBuckets(loc, days+1, time.Now())
+	today, err := time.ParseInLocation("2006-01-02", dates[days], loc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse day bucket: %w", err)
+	}
+	dates = dates[:days]
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT
+			(created_at AT TIME ZONE $4)::date AS date,
+			COALESCE(tool_name, 'unknown') AS tool,
+			COALESCE(SUM(amount), 0)::float8
+		FROM revenue_entries
+		WHERE agent_id = $1 AND verified = TRUE
+		  AND created_at >= $2 AND created_at < $3
+		GROUP BY 1, 2
+	`, agentDBID, since, today, loc.String())
+	if err != nil {
+		return nil, nil, fmt.Errorf("get daily revenue by tool: %w", err)
+	}
+	defer rows.Close()
+
+	index := make(map[string]int, len(dates))
+	for i, d := range dates {
+		index[d] = i
+	}
+
+	byTool := make(map[string][]float64)
+	for rows.Next() {
+		var (
+			date   time.Time
+			tool   string
+			amount float64
+		)
+		if err := rows.Scan(&date, &tool, &amount); err != nil {
+			return nil, nil, fmt.Errorf("scan daily revenue by tool: %w", err)
+		}
+		i, ok := index[date.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		series, ok := byTool[tool]
+		if !ok {
+			series = make([]float64, len(dates))
+			byTool[tool] = series
+		}
+		series[i] += amount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("get daily revenue by tool: %w", err)
+	}
+
+	return dates, byTool, nil
+}
@@ -0,0 +1,255 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Funnel limits.
+const (
+	MaxFunnelSteps = 10
+	MaxFunnels     = 50
+)
+
+// ErrFunnelNameTaken is returned when an agent already has a funnel with
+// the same name.
+var ErrFunnelNameTaken = errors.New("funnel name already exists")
+
+// FunnelStep matches requests by any combination of tool, path, protocol
+// and paid/unpaid. A path ending in "*" matches as a prefix.
+type FunnelStep struct {
+	Name     string `json:"name,omitempty"`
+	Tool     string `json:"tool,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	Paid     *bool  `json:"paid,omitempty"`
+}
+
+// Funnel is an owner-defined ordered sequence of steps. A customer converts
+// through step k if they hit steps 1..k in order within WindowSeconds of
+// entering at step 1.
+type Funnel struct {
+	ID            uuid.UUID    `json:"id"`
+	AgentID       uuid.UUID    `json:"agent_id"`
+	Name          string       `json:"name"`
+	Steps         []FunnelStep `json:"steps"`
+	WindowSeconds int          `json:"window_seconds"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// Validate checks the step list.
+func (f *Funnel) Validate() error {
+	if strings.TrimSpace(f.Name) == "" {
+		return errors.New("name is required")
+	}
+	if len(f.Steps) < 2 || len(f.Steps) > MaxFunnelSteps {
+		return fmt.Errorf("a funnel needs between 2 and %d steps", MaxFunnelSteps)
+	}
+	for i, st := range f.Steps {
+		if st.Tool == "" && st.Path == "" && st.Protocol == "" && st.Paid == nil {
+			return fmt.Errorf("step %d must match on at least one of tool, path, protocol or paid", i+1)
+		}
+	}
+	if f.WindowSeconds <= 0 {
+		return errors.New("window_seconds must be positive")
+	}
+	return nil
+}
+
+const funnelSelectCols = `id, agent_id, name, steps, window_seconds, created_at, updated_at`
+
+func scanFunnel(row interface{ Scan(...any) error }) (*Funnel, error) {
+	f := &Funnel{}
+	var steps []byte
+	if err := row.Scan(&f.ID, &f.AgentID, &f.Name, &steps, &f.WindowSeconds, &f.CreatedAt, &f.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(steps, &f.Steps); err != nil {
+		return nil, fmt.Errorf("decode funnel steps: %w", err)
+	}
+	return f, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// CreateFunnel inserts a funnel and fills in its ID and timestamps.
+func (s *Store) CreateFunnel(ctx context.Context, f *Funnel) error {
+	steps, err := json.Marshal(f.Steps)
+	if err != nil {
+		return fmt.Errorf("encode funnel steps: %w", err)
+	}
+	err = s.pool.QueryRow(ctx, `
+		INSERT INTO funnels (agent_id, name, steps, window_seconds)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`, f.AgentID, f.Name, steps, f.WindowSeconds).Scan(&f.ID, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrFunnelNameTaken
+		}
+		return fmt.Errorf("create funnel: %w", err)
+	}
+	return nil
+}
+
+// UpdateFunnel replaces a funnel's name, steps and window.
+// Returns pgx.ErrNoRows if the funnel does not belong to the agent.
+func (s *Store) UpdateFunnel(ctx context.Context, f *Funnel) error {
+	steps, err := json.Marshal(f.Steps)
+	if err != nil {
+		return fmt.Errorf("encode funnel steps: %w", err)
+	}
+	err = s.pool.QueryRow(ctx, `
+		UPDATE funnels
+		SET name = $3, steps = $4, window_seconds = $5, updated_at = NOW()
+		WHERE id = $1 AND agent_id = $2
+		RETURNING created_at, updated_at
+	`, f.ID, f.AgentID, f.Name, steps, f.WindowSeconds).Scan(&f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrFunnelNameTaken
+		}
+		return fmt.Errorf("update funnel: %w", err)
+	}
+	return nil
+}
+
+// GetFunnel returns a funnel owned by the agent.
+func (s *Store) GetFunnel(ctx context.Context, agentDBID, funnelID uuid.UUID) (*Funnel, error) {
+	f, err := scanFunnel(s.pool.QueryRow(ctx, `
+		SELECT `+funnelSelectCols+` FROM funnels WHERE id = $1 AND agent_id = $2
+	`, funnelID, agentDBID))
+	if err != nil {
+		return nil, fmt.Errorf("get funnel: %w", err)
+	}
+	return f, nil
+}
+
+// ListFunnels returns all funnels for an agent, by name.
+func (s *Store) ListFunnels(ctx context.Context, agentDBID uuid.UUID) ([]Funnel, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+funnelSelectCols+` FROM funnels WHERE agent_id = $1 ORDER BY name
+	`, agentDBID)
+	if err != nil {
+		return nil, fmt.Errorf("list funnels: %w", err)
+	}
+	defer rows.Close()
+
+	var funnels []Funnel
+	for rows.Next() {
+		f, err := scanFunnel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan funnel: %w", err)
+		}
+		funnels = append(funnels, *f)
+	}
+
+	if funnels == nil {
+		funnels = []Funnel{}
+	}
+
+	return funnels, nil
+}
+
+// CountFunnels returns how many funnels an agent has defined.
+func (s *Store) CountFunnels(ctx context.Context, agentDBID uuid.UUID) (int, error) {
+	var n int
+	if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM funnels WHERE agent_id = $1`, agentDBID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count funnels: %w", err)
+	}
+	return n, nil
+}
+
+// DeleteFunnel removes a funnel. Returns false if it did not exist.
+func (s *Store) DeleteFunnel(ctx context.Context, agentDBID, funnelID uuid.UUID) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM funnels WHERE id = $1 AND agent_id = $2`, funnelID, agentDBID)
+	if err != nil {
+		return false, fmt.Errorf("delete funnel: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// funnelStepCondition builds the SQL predicate for one step.
+func funnelStepCondition(st FunnelStep, args []interface{}) (string, []interface{}) {
+	var conds []string
+	if st.Tool != "" {
+		args = append(args, st.Tool)
+		conds = append(conds, fmt.Sprintf("tool_name = $%d", len(args)))
+	}
+	if st.Path != "" {
+		if prefix, ok := strings.CutSuffix(st.Path, "*"); ok {
+			args = append(args, escapeLike(prefix)+"%")
+			conds = append(conds, fmt.Sprintf("path LIKE $%d", len(args)))
+		} else {
+			args = append(args, st.Path)
+			conds = append(conds, fmt.Sprintf("path = $%d", len(args)))
+		}
+	}
+	if st.Protocol != "" {
+		args = append(args, st.Protocol)
+		conds = append(conds, fmt.Sprintf("protocol = $%d", len(args)))
+	}
+	if st.Paid != nil {
+		if *st.Paid {
+			conds = append(conds, "(x402_amount IS NOT NULL AND x402_amount > 0)")
+		} else {
+			conds = append(conds, "(x402_amount IS NULL OR x402_amount = 0)")
+		}
+	}
+	return "(" + strings.Join(conds, " AND ") + ")", args
+}
+
+// ForEachFunnelEvent streams every request since the given time that matches
+// at least one funnel step, ordered by customer then time. mask has bit i set
+// when the request matches step i. Rows are streamed so large agents are
+// never loaded into memory at once.
+func (s *Store) ForEachFunnelEvent(ctx context.Context, agentDBID uuid.UUID, steps []FunnelStep, since time.Time, fn func(customer string, at time.Time, mask uint32)) error {
+	args := []interface{}{agentDBID, since}
+	masks := make([]string, len(steps))
+	conds := make([]string, len(steps))
+	for i, st := range steps {
+		var cond string
+		cond, args = funnelStepCondition(st, args)
+		conds[i] = cond
+		masks[i] = fmt.Sprintf("CASE WHEN %s THEN %d ELSE 0 END", cond, 1<<i)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT ip_address, created_at, (%s) AS mask
+		FROM request_logs
+		WHERE agent_id = $1 AND ip_address IS NOT NULL AND created_at >= $2
+			AND (%s)
+		ORDER BY ip_address, created_at
+	`, strings.Join(masks, " + "), strings.Join(conds, " OR "))
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("get funnel events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var customer string
+		var at time.Time
+		var mask int64
+		if err := rows.Scan(&customer, &at, &mask); err != nil {
+			return fmt.Errorf("scan funnel event: %w", err)
+		}
+		fn(customer, at, uint32(mask))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate funnel events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LogSearchFilter narrows a request log search. Zero values mean "no filter".
+type LogSearchFilter struct {
+	From          *time.Time `json:"from,omitempty"`
+	To            *time.Time `json:"to,omitempty"`
+	StatusCodes   []int      `json:"status_codes,omitempty"`   // exact codes, e.g. 404
+	StatusClasses []int      `json:"status_classes,omitempty"` // hundreds digit, e.g. 5 for 5xx
+	Tools         []string   `json:"tools,omitempty"`
+	Protocols     []string   `json:"protocols,omitempty"`
+	Methods       []string   `json:"methods,omitempty"`
+	Path          string     `json:"path,omitempty"` // substring match
+	IP            string     `json:"ip,omitempty"`   // client IP, which also keys customers
+	Country       string     `json:"country,omitempty"`
+	Paid          *bool      `json:"paid,omitempty"`
+	MinLatencyMs  *float64   `json:"min_latency_ms,omitempty"`
+	MaxLatencyMs  *float64   `json:"max_latency_ms,omitempty"`
+	SDKVersion    string     `json:"sdk_version,omitempty"`
+	Query         string     `json:"q,omitempty"` // substring match on request/response bodies
+	Cursor        *LogCursor `json:"-"`
+	Limit         int        `json:"-"`
+}
+
+// LogCursor is the keyset position of the last row of a page.
+type LogCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// Encode returns an opaque URL-safe token for the cursor.
+func (c LogCursor) Encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeLogCursor parses a token produced by LogCursor.Encode.
+func DecodeLogCursor(token string) (*LogCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid cursor")
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &LogCursor{CreatedAt: time.Unix(0, nanos).UTC(), ID: id}, nil
+}
+
+// LogSearchResult is one page of search results.
+type LogSearchResult struct {
+	Logs       []RequestLog `json:"logs"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	HasMore    bool         `json:"has_more"`
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally.
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+// SearchRequestLogs returns request logs matching the filter, newest first,
+// paginated by (created_at, id). Bodies and headers are omitted from results;
+// use GetRequestLog for the full record.
+func (s *Store) SearchRequestLogs(ctx context.Context, agentDBID uuid.UUID, f LogSearchFilter) (*LogSearchResult, error) {
+	if f.Limit <= 0 || f.Limit > 500 {
+		f.Limit = 50
+	}
+
+	query := `
+		SELECT id, agent_id, request_id, tool_name, method, path,
+			status_code, response_ms, error_type,
+			x402_amount, x402_tx_hash, x402_token, x402_payer,
+			request_body_size, response_body_size,
+			batch_id, sdk_version, protocol, source,
+			ip_address, user_agent, referer, content_type, accept_language,
+			country, city, created_at
+		FROM request_logs
+		WHERE agent_id = $1`
+	args := []interface{}{agentDBID}
+	query, args = appendLogFilters(query, args, f)
+	argIdx := len(args) + 1
+
+	if f.Cursor != nil {
+		query += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", argIdx, argIdx+1)
+		args = append(args, f.Cursor.CreatedAt, f.Cursor.ID)
+		argIdx += 2
+	}
+
+	// Fetch one extra row to detect whether another page exists.
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", argIdx)
+	args = append(args, f.Limit+1)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search request logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []RequestLog{}
+	for rows.Next() {
+		var l RequestLog
+		if err := rows.Scan(
+			&l.ID, &l.AgentID, &l.RequestID, &l.ToolName, &l.Method, &l.Path,
+			&l.StatusCode, &l.ResponseMs, &l.ErrorType,
+			&l.X402Amount, &l.X402TxHash, &l.X402Token, &l.X402Payer,
+			&l.RequestBodySize, &l.ResponseBodySize,
+			&l.BatchID, &l.SDKVersion, &l.Protocol, &l.Source,
+			&l.IPAddress, &l.UserAgent, &l.Referer, &l.ContentType, &l.AcceptLanguage,
+			&l.Country, &l.City, &l.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan request log: %w", err)
+		}
+		logs = append(logs, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate request logs: %w", err)
+	}
+
+	result := &LogSearchResult{Logs: logs}
+	if len(logs) > f.Limit {
+		result.Logs = logs[:f.Limit]
+		result.HasMore = true
+		last := result.Logs[len(result.Logs)-1]
+		result.NextCursor = LogCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	return result, nil
+}
+
+// appendLogFilters appends the WHERE conditions for f (excluding cursor and
+// limit) to query, numbering placeholders after the existing args.
+func appendLogFilters(query string, args []interface{}, f LogSearchFilter) (string, []interface{}) {
+	argIdx := len(args) + 1
+
+	add := func(clause string, v interface{}) {
+		query += fmt.Sprintf(clause, argIdx)
+		args = append(args, v)
+		argIdx++
+	}
+
+	if f.From != nil {
+		add(" AND created_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add(" AND created_at < $%d", *f.To)
+	}
+	if len(f.StatusCodes) > 0 && len(f.StatusClasses) > 0 {
+		query += fmt.Sprintf(" AND (status_code = ANY($%d) OR status_code / 100 = ANY($%d))", argIdx, argIdx+1)
+		args = append(args, f.StatusCodes, f.StatusClasses)
+		argIdx += 2
+	} else if len(f.StatusCodes) > 0 {
+		add(" AND status_code = ANY($%d)", f.StatusCodes)
+	} else if len(f.StatusClasses) > 0 {
+		add(" AND status_code / 100 = ANY($%d)", f.StatusClasses)
+	}
+	if len(f.Tools) > 0 {
+		add(" AND tool_name = ANY($%d)", f.Tools)
+	}
+	if len(f.Protocols) > 0 {
+		add(" AND protocol = ANY($%d)", f.Protocols)
+	}
+	if len(f.Methods) > 0 {
+		add(" AND method = ANY($%d)", f.Methods)
+	}
+	if f.Path != "" {
+		add(" AND path ILIKE '%%' || $%d || '%%'", escapeLike(f.Path))
+	}
+	if f.IP != "" {
+		add(" AND ip_address = $%d", f.IP)
+	}
+	if f.Country != "" {
+		add(" AND country = $%d", strings.ToUpper(f.Country))
+	}
+	if f.Paid != nil {
+		if *f.Paid {
+			query += " AND x402_amount IS NOT NULL AND x402_amount > 0"
+		} else {
+			query += " AND (x402_amount IS NULL OR x402_amount = 0)"
+		}
+	}
+	if f.MinLatencyMs != nil {
+		add(" AND response_ms >= $%d", *f.MinLatencyMs)
+	}
+	if f.MaxLatencyMs != nil {
+		add(" AND response_ms <= $%d", *f.MaxLatencyMs)
+	}
+	if f.SDKVersion != "" {
+		add(" AND sdk_version = $%d", f.SDKVersion)
+	}
+	if f.Query != "" {
+		query += fmt.Sprintf(" AND (request_body ILIKE '%%' || $%d || '%%' OR response_body ILIKE '%%' || $%d || '%%')", argIdx, argIdx)
+		args = append(args, escapeLike(f.Query))
+		argIdx++
+	}
+
+	return query, args
+}
+
+// GetRequestLog returns a single request log, including bodies and headers.
+func (s *Store) GetRequestLog(ctx context.Context, agentDBID uuid.UUID, id int64) (*RequestLog, error) {
+	var l RequestLog
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, agent_id, request_id, tool_name, method, path,
+			status_code, response_ms, error_type,
+			x402_amount, x402_tx_hash, x402_token, x402_payer,
+			request_body_size, response_body_size,
+			request_body, response_body, headers,
+			batch_id, sdk_version, protocol, source,
+			ip_address, user_agent, referer, content_type, accept_language,
+			country, city, created_at
+		FROM request_logs
+		WHERE agent_id = $1 AND id = $2
+	`, agentDBID, id).Scan(
+		&l.ID, &l.AgentID, &l.RequestID, &l.ToolName, &l.Method, &l.Path,
+		&l.StatusCode, &l.ResponseMs, &l.ErrorType,
+		&l.X402Amount, &l.X402TxHash, &l.X402Token, &l.X402Payer,
+		&l.RequestBodySize, &l.ResponseBodySize,
+		&l.RequestBody, &l.ResponseBody, &l.Headers,
+		&l.BatchID, &l.SDKVersion, &l.Protocol, &l.Source,
+		&l.IPAddress, &l.UserAgent, &l.Referer, &l.ContentType, &l.AcceptLanguage,
+		&l.Country, &l.City, &l.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get request log: %w", err)
+	}
+	return &l, nil
+}
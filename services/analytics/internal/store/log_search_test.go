@@ -0,0 +1,75 @@
+package store_test
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/GT8004/gt8004-analytics/internal/store"
+)
+
+func TestLogCursor_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor store.LogCursor
+	}{
+		{"typical", store.LogCursor{CreatedAt: time.Date(2026, 3, 14, 15, 9, 26, 535897932, time.UTC), ID: 42}},
+		{"zero id", store.LogCursor{CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), ID: 0}},
+		{"large id", store.LogCursor{CreatedAt: time.Unix(1700000000, 1).UTC(), ID: 1<<62 + 7}},
+		{"non-utc zone", store.LogCursor{CreatedAt: time.Date(2026, 6, 1, 9, 0, 0, 0, time.FixedZone("KST", 9*3600)), ID: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.cursor.Encode()
+			got, err := store.DecodeLogCursor(token)
+			if err != nil {
+				t.Fatalf("decode %q: %v", token, err)
+			}
+			if !got.CreatedAt.Equal(tt.cursor.CreatedAt) {
+				t.Errorf("expected created_at %v, got %v", tt.cursor.CreatedAt, got.CreatedAt)
+			}
+			if got.CreatedAt.Location() != time.UTC {
+				t.Errorf("expected UTC, got %v", got.CreatedAt.Location())
+			}
+			if got.ID != tt.cursor.ID {
+				t.Errorf("expected id %d, got %d", tt.cursor.ID, got.ID)
+			}
+		})
+	}
+}
+
+func TestLogCursor_EncodeIsURLSafe(t *testing.T) {
+	token := store.LogCursor{CreatedAt: time.Unix(0, 1<<62).UTC(), ID: 1<<63 - 1}.Encode()
+	for _, r := range token {
+		if r == '+' || r == '/' || r == '=' {
+			t.Fatalf("expected URL-safe token, got %q", token)
+		}
+	}
+}
+
+func TestDecodeLogCursor_Invalid(t *testing.T) {
+	enc := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"not base64", "!!!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("1:23"))},
+		{"no separator", enc("12345")},
+		{"non-numeric time", enc("abc:1")},
+		{"non-numeric id", enc("1:abc")},
+		{"empty id", enc("1:")},
+		{"extra field", enc("1:2:3")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cur, err := store.DecodeLogCursor(tt.token); err == nil {
+				t.Errorf("expected error for %q, got %+v", tt.token, cur)
+			}
+		})
+	}
+}
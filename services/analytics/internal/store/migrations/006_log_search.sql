@@ -0,0 +1,16 @@
+-- 006: Indexes backing the log search API
+-- Keyset pagination walks (created_at, id) per agent; trigram indexes let
+-- substring search on request/response bodies avoid a full scan.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_reqlog_agent_cursor
+    ON request_logs(agent_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_reqlog_status
+    ON request_logs(agent_id, status_code, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_reqlog_request_body_trgm
+    ON request_logs USING GIN (request_body gin_trgm_ops)
+    WHERE request_body IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_reqlog_response_body_trgm
+    ON request_logs USING GIN (response_body gin_trgm_ops)
+    WHERE response_body IS NOT NULL;
@@ -0,0 +1,24 @@
+-- 007: Asynchronous data export jobs
+-- Jobs are queued by the API and claimed by the export worker with
+-- FOR UPDATE SKIP LOCKED so multiple analytics instances can share the queue.
+
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    agent_id        UUID NOT NULL REFERENCES agents(id),
+    dataset         VARCHAR(16) NOT NULL,
+    format          VARCHAR(16) NOT NULL,
+    filters         JSONB NOT NULL DEFAULT '{}'::jsonb,
+    status          VARCHAR(16) NOT NULL DEFAULT 'pending',
+    row_count       BIGINT NOT NULL DEFAULT 0,
+    size_bytes      BIGINT NOT NULL DEFAULT 0,
+    blob_key        TEXT,
+    error           TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at      TIMESTAMPTZ,
+    completed_at    TIMESTAMPTZ,
+    expires_at      TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_jobs_agent ON export_jobs(agent_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_export_jobs_pending ON export_jobs(created_at)
+    WHERE status = 'pending';
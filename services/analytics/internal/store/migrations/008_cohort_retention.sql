@@ -0,0 +1,3 @@
+-- Cohort retention: per-customer first-seen and activity lookups
+CREATE INDEX IF NOT EXISTS idx_reqlog_agent_ip ON request_logs(agent_id, ip_address, created_at)
+    WHERE ip_address IS NOT NULL;
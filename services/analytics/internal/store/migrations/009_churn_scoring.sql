@@ -0,0 +1,8 @@
+-- Adaptive churn scoring: numeric score with explanatory factors.
+-- churn_risk stays as the derived low/medium/high label.
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS churn_score REAL;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS churn_factors JSONB;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS churn_scored_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_customers_churn_score ON customers(agent_id, churn_score DESC)
+    WHERE churn_score IS NOT NULL;
@@ -0,0 +1,14 @@
+-- 010: Owner-defined conversion funnels
+-- steps is an ordered JSON array of step matchers
+-- ({name, tool, path, protocol, paid}).
+
+CREATE TABLE IF NOT EXISTS funnels (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    agent_id          UUID NOT NULL REFERENCES agents(id),
+    name              VARCHAR(128) NOT NULL,
+    steps             JSONB NOT NULL,
+    window_seconds    INTEGER NOT NULL,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (agent_id, name)
+);
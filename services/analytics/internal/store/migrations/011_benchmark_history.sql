@@ -0,0 +1,26 @@
+-- 011: Benchmark score breakdowns and daily ranking snapshots
+-- benchmark_cache keeps the latest ranking; benchmark_snapshots keeps one
+-- row per agent per day (the last calculation of that day) for history.
+
+ALTER TABLE benchmark_cache ADD COLUMN IF NOT EXISTS previous_rank INT;
+ALTER TABLE benchmark_cache ADD COLUMN IF NOT EXISTS breakdown JSONB;
+
+CREATE TABLE IF NOT EXISTS benchmark_snapshots (
+    id              BIGSERIAL PRIMARY KEY,
+    category        VARCHAR(100) NOT NULL,
+    agent_id        UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    snapshot_date   DATE NOT NULL,
+    rank            INT NOT NULL,
+    category_size   INT NOT NULL,
+    score           DOUBLE PRECISION NOT NULL,
+    breakdown       JSONB,
+    total_requests  BIGINT NOT NULL DEFAULT 0,
+    avg_response_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    error_rate      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    revenue         DOUBLE PRECISION NOT NULL DEFAULT 0,
+    customer_count  INT NOT NULL DEFAULT 0,
+    calculated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (category, agent_id, snapshot_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_benchmark_snapshots_agent ON benchmark_snapshots(agent_id, snapshot_date DESC);
@@ -0,0 +1,28 @@
+-- 012: Reputation explanations and daily history
+-- reputation_breakdown (created by the registry) gains the inputs behind
+-- each component; reputation_snapshots keeps the last breakdown of each day.
+
+ALTER TABLE reputation_breakdown ADD COLUMN IF NOT EXISTS inputs JSONB;
+
+CREATE TABLE IF NOT EXISTS reputation_snapshots (
+    id                 BIGSERIAL PRIMARY KEY,
+    agent_id           UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    snapshot_date      DATE NOT NULL,
+    reliability        FLOAT NOT NULL DEFAULT 0,
+    performance        FLOAT NOT NULL DEFAULT 0,
+    activity           FLOAT NOT NULL DEFAULT 0,
+    revenue_quality    FLOAT NOT NULL DEFAULT 0,
+    customer_retention FLOAT NOT NULL DEFAULT 0,
+    peer_review        FLOAT NOT NULL DEFAULT 0,
+    onchain_score      FLOAT NOT NULL DEFAULT 0,
+    total_score        FLOAT NOT NULL DEFAULT 0,
+    onchain_count      INT NOT NULL DEFAULT 0,
+    review_count       INT NOT NULL DEFAULT 0,
+    inputs             JSONB,
+    calculated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (agent_id, snapshot_date)
+);
+
+-- Reviewer credibility looks up payments by payer wallet.
+CREATE INDEX IF NOT EXISTS idx_revenue_payer ON revenue_entries(LOWER(payer_address))
+    WHERE payer_address IS NOT NULL;
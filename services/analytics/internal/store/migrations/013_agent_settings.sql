@@ -0,0 +1,9 @@
+-- 013: Per-agent analytics settings
+-- timezone is an IANA zone name used for daily and hourly bucketing when a
+-- request does not pass ?tz=.
+
+CREATE TABLE IF NOT EXISTS agent_settings (
+    agent_id    UUID PRIMARY KEY REFERENCES agents(id) ON DELETE CASCADE,
+    timezone    VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
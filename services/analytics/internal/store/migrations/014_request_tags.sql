@@ -0,0 +1,18 @@
+-- 014: Custom dimensions (tags) on request logs
+-- tags is a flat JSON object of string values written by the ingest service.
+-- request_tag_values records every admitted (key, value) pair per agent so
+-- ingest can enforce per-key cardinality limits.
+
+ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS tags JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_reqlog_tags
+    ON request_logs USING GIN (tags jsonb_path_ops)
+    WHERE tags IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS request_tag_values (
+    agent_id    UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    key         VARCHAR(32) NOT NULL,
+    value       VARCHAR(64) NOT NULL,
+    first_seen  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (agent_id, key, value)
+);
@@ -0,0 +1,17 @@
+-- 015: Deployment markers
+-- Recorded by CI through the ingest service (agent API key) and used by
+-- analytics for before/after comparisons and time-series annotations.
+-- request_logs.version is the optional per-entry agent version.
+
+CREATE TABLE IF NOT EXISTS deployments (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    agent_id     UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    version      VARCHAR(64) NOT NULL,
+    commit_sha   VARCHAR(64),
+    deployed_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_deployments_agent ON deployments(agent_id, deployed_at DESC);
+
+ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS version VARCHAR(64);
@@ -0,0 +1,30 @@
+-- 016: Customer sessions
+-- Each customer's requests (by ip_address) are split into sessions by an
+-- inactivity gap. tool_sequence holds the tools called in order, with
+-- consecutive repeats collapsed. session_cursors records how far each
+-- agent's request_logs have been sessionized.
+
+CREATE TABLE IF NOT EXISTS customer_sessions (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    agent_id         UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    customer_id      VARCHAR(128) NOT NULL,
+    started_at       TIMESTAMPTZ NOT NULL,
+    ended_at         TIMESTAMPTZ NOT NULL,
+    request_count    INT NOT NULL DEFAULT 0,
+    error_count      INT NOT NULL DEFAULT 0,
+    paid_requests    INT NOT NULL DEFAULT 0,
+    paid_amount      NUMERIC(20,8) NOT NULL DEFAULT 0,
+    tool_sequence    TEXT[] NOT NULL DEFAULT '{}',
+    ended_in_error   BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (agent_id, customer_id, started_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_sessions_agent ON customer_sessions(agent_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_customer_sessions_customer ON customer_sessions(agent_id, customer_id, ended_at DESC);
+
+CREATE TABLE IF NOT EXISTS session_cursors (
+    agent_id         UUID PRIMARY KEY REFERENCES agents(id) ON DELETE CASCADE,
+    processed_until  TIMESTAMPTZ NOT NULL,
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,24 @@
+-- 017: RFM segmentation and projected lifetime value
+-- Recency, frequency and monetary scores (1-5, relative to the agent's other
+-- customers), the named segment they map to and a projected LTV are stored
+-- on customers. customer_segment_snapshots keeps each customer's segment per
+-- day so segment movement can be reported.
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS rfm_recency SMALLINT;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS rfm_frequency SMALLINT;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS rfm_monetary SMALLINT;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS segment VARCHAR(32);
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS projected_ltv NUMERIC(20,8);
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS segmented_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_customers_segment ON customers(agent_id, segment)
+    WHERE segment IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS customer_segment_snapshots (
+    agent_id       UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    snapshot_date  DATE NOT NULL,
+    customer_id    VARCHAR(128) NOT NULL,
+    segment        VARCHAR(32) NOT NULL,
+    projected_ltv  NUMERIC(20,8) NOT NULL DEFAULT 0,
+    PRIMARY KEY (agent_id, snapshot_date, customer_id)
+);
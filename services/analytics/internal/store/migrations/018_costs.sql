@@ -0,0 +1,26 @@
+-- 018: Operator-entered costs for margin reporting
+-- agent_cost_settings holds the agent-wide fixed monthly infrastructure cost
+-- and default LLM token rates (per million tokens). tool_costs holds the
+-- per-request cost of each tool and optional token rates that override the
+-- agent defaults. request_logs gains the LLM token counts reported by the SDK.
+
+CREATE TABLE IF NOT EXISTS agent_cost_settings (
+    agent_id             UUID PRIMARY KEY REFERENCES agents(id) ON DELETE CASCADE,
+    fixed_monthly_cost   NUMERIC(20,8) NOT NULL DEFAULT 0,
+    input_token_rate     NUMERIC(20,8) NOT NULL DEFAULT 0,
+    output_token_rate    NUMERIC(20,8) NOT NULL DEFAULT 0,
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS tool_costs (
+    agent_id             UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    tool_name            VARCHAR(128) NOT NULL,
+    cost_per_request     NUMERIC(20,8) NOT NULL DEFAULT 0,
+    input_token_rate     NUMERIC(20,8),
+    output_token_rate    NUMERIC(20,8),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (agent_id, tool_name)
+);
+
+ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS input_tokens INT;
+ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS output_tokens INT;
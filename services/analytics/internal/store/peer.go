@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PeerMetrics holds one agent's raw activity totals over a comparison window.
+type PeerMetrics struct {
+	AgentID         uuid.UUID
+	Requests        int64
+	P95ResponseMs   *float64 // nil when the agent served no requests
+	Errors          int64
+	Customers       int64
+	PayingCustomers int64
+	Revenue         float64 // verified revenue
+}
+
+// GetCategoryPeerMetrics returns window totals for every active agent in a
+// category, including agents without any traffic since since.
+func (s *Store) GetCategoryPeerMetrics(ctx context.Context, category string, since time.Time) ([]PeerMetrics, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH peers AS (
+			SELECT id FROM agents WHERE status = 'active' AND category = $1
+		),
+		logs AS (
+			SELECT rl.agent_id,
+				COUNT(*) AS requests,
+				percentile_cont(0.95) WITHIN GROUP (ORDER BY rl.response_ms) AS p95,
+				COUNT(*) FILTER (WHERE rl.status_code >= 400 AND rl.status_code != 402) AS errors,
+				COUNT(DISTINCT rl.ip_address) AS customers,
+				COUNT(DISTINCT rl.ip_address) FILTER (WHERE rl.x402_amount IS NOT NULL AND rl.x402_amount > 0) AS paying
+			FROM request_logs rl
+			JOIN peers p ON p.id = rl.agent_id
+			WHERE rl.created_at >= $2
+			GROUP BY rl.agent_id
+		),
+		rev AS (
+			SELECT re.agent_id, SUM(re.amount) AS revenue
+			FROM revenue_entries re
+			JOIN peers p ON p.id = re.agent_id
+			WHERE re.verified = TRUE AND re.created_at >= $2
+			GROUP BY re.agent_id
+		)
+		SELECT p.id, COALESCE(l.requests, 0), l.p95, COALESCE(l.errors, 0),
+			COALESCE(l.customers, 0), COALESCE(l.paying, 0), COALESCE(r.revenue, 0)::float8
+		FROM peers p
+		LEFT JOIN logs l ON l.agent_id = p.id
+		LEFT JOIN rev r ON r.agent_id = p.id
+	`, category, since)
+	if err != nil {
+		return nil, fmt.Errorf("get category peer metrics: %w", err)
+	}
+	defer rows.Close()
+
+	var peers []PeerMetrics
+	for rows.Next() {
+		var m PeerMetrics
+		if err := rows.Scan(&m.AgentID, &m.Requests, &m.P95ResponseMs, &m.Errors,
+			&m.Customers, &m.PayingCustomers, &m.Revenue); err != nil {
+			return nil, fmt.Errorf("scan peer metrics: %w", err)
+		}
+		peers = append(peers, m)
+	}
+
+	if peers == nil {
+		peers = []PeerMetrics{}
+	}
+
+	return peers, rows.Err()
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -22,16 +23,31 @@ type ReputationBreakdown struct {
 	OnchainCount      int       `json:"onchain_count"`
 	ReviewCount       int       `json:"review_count"`
 	CalculatedAt      time.Time `json:"calculated_at"`
+	// Inputs explains each component (see analytics.ReputationExplanation).
+	Inputs json.RawMessage `json:"inputs,omitempty"`
 }
 
-// UpsertReputationBreakdown inserts or updates a reputation breakdown record.
+const reputationCols = `reliability, performance, activity, revenue_quality,
+	customer_retention, peer_review, onchain_score, total_score,
+	onchain_count, review_count, calculated_at, inputs`
+
+func (rb *ReputationBreakdown) scanDest() []any {
+	return []any{
+		&rb.Reliability, &rb.Performance, &rb.Activity, &rb.RevenueQuality,
+		&rb.CustomerRetention, &rb.PeerReview, &rb.OnchainScore, &rb.TotalScore,
+		&rb.OnchainCount, &rb.ReviewCount, &rb.CalculatedAt, &rb.Inputs,
+	}
+}
+
+// UpsertReputationBreakdown inserts or updates a reputation breakdown record
+// and records it as the agent's snapshot for today.
 func (s *Store) UpsertReputationBreakdown(ctx context.Context, rb *ReputationBreakdown) error {
 	_, err := s.pool.Exec(ctx, `
 		INSERT INTO reputation_breakdown (
 			agent_id, reliability, performance, activity, revenue_quality,
 			customer_retention, peer_review, onchain_score, total_score,
-			onchain_count, review_count, calculated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			onchain_count, review_count, calculated_at, inputs
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		ON CONFLICT (agent_id) DO UPDATE SET
 			reliability        = EXCLUDED.reliability,
 			performance        = EXCLUDED.performance,
@@ -43,16 +59,86 @@ func (s *Store) UpsertReputationBreakdown(ctx context.Context, rb *ReputationBre
 			total_score        = EXCLUDED.total_score,
 			onchain_count      = EXCLUDED.onchain_count,
 			review_count       = EXCLUDED.review_count,
-			calculated_at      = EXCLUDED.calculated_at
+			calculated_at      = EXCLUDED.calculated_at,
+			inputs             = EXCLUDED.inputs
 	`, rb.AgentID, rb.Reliability, rb.Performance, rb.Activity, rb.RevenueQuality,
 		rb.CustomerRetention, rb.PeerReview, rb.OnchainScore, rb.TotalScore,
-		rb.OnchainCount, rb.ReviewCount, rb.CalculatedAt)
+		rb.OnchainCount, rb.ReviewCount, rb.CalculatedAt, rb.Inputs)
 	if err != nil {
 		return fmt.Errorf("upsert reputation breakdown: %w", err)
 	}
+
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO reputation_snapshots (
+			agent_id, snapshot_date, reliability, performance, activity, revenue_quality,
+			customer_retention, peer_review, onchain_score, total_score,
+			onchain_count, review_count, calculated_at, inputs
+		) VALUES ($1, CURRENT_DATE, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (agent_id, snapshot_date) DO UPDATE SET
+			reliability        = EXCLUDED.reliability,
+			performance        = EXCLUDED.performance,
+			activity           = EXCLUDED.activity,
+			revenue_quality    = EXCLUDED.revenue_quality,
+			customer_retention = EXCLUDED.customer_retention,
+			peer_review        = EXCLUDED.peer_review,
+			onchain_score      = EXCLUDED.onchain_score,
+			total_score        = EXCLUDED.total_score,
+			onchain_count      = EXCLUDED.onchain_count,
+			review_count       = EXCLUDED.review_count,
+			calculated_at      = EXCLUDED.calculated_at,
+			inputs             = EXCLUDED.inputs
+	`, rb.AgentID, rb.Reliability, rb.Performance, rb.Activity, rb.RevenueQuality,
+		rb.CustomerRetention, rb.PeerReview, rb.OnchainScore, rb.TotalScore,
+		rb.OnchainCount, rb.ReviewCount, rb.CalculatedAt, rb.Inputs)
+	if err != nil {
+		return fmt.Errorf("upsert reputation snapshot: %w", err)
+	}
 	return nil
 }
 
+// GetReputationBreakdown returns the latest reputation breakdown for an agent.
+func (s *Store) GetReputationBreakdown(ctx context.Context, agentID uuid.UUID) (*ReputationBreakdown, error) {
+	rb := &ReputationBreakdown{AgentID: agentID}
+	err := s.pool.QueryRow(ctx, `
+		SELECT `+reputationCols+` FROM reputation_breakdown WHERE agent_id = $1
+	`, agentID).Scan(rb.scanDest()...)
+	if err != nil {
+		return nil, fmt.Errorf("get reputation breakdown: %w", err)
+	}
+	return rb, nil
+}
+
+// GetReputationHistory returns an agent's daily reputation snapshots for
+// the last days days, oldest first. Inputs are omitted to keep it compact.
+func (s *Store) GetReputationHistory(ctx context.Context, agentID uuid.UUID, days int) ([]ReputationBreakdown, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+reputationCols+`
+		FROM reputation_snapshots
+		WHERE agent_id = $1 AND snapshot_date >= CURRENT_DATE - $2 * INTERVAL '1 day'
+		ORDER BY snapshot_date ASC
+	`, agentID, days)
+	if err != nil {
+		return nil, fmt.Errorf("get reputation history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []ReputationBreakdown
+	for rows.Next() {
+		rb := ReputationBreakdown{AgentID: agentID}
+		if err := rows.Scan(rb.scanDest()...); err != nil {
+			return nil, fmt.Errorf("scan reputation snapshot: %w", err)
+		}
+		rb.Inputs = nil
+		history = append(history, rb)
+	}
+
+	if history == nil {
+		history = []ReputationBreakdown{}
+	}
+
+	return history, nil
+}
+
 // UpdateAgentReputationScore updates the agents table reputation_score column.
 func (s *Store) UpdateAgentReputationScore(ctx context.Context, agentID uuid.UUID, score float64) error {
 	_, err := s.pool.Exec(ctx, `
@@ -98,9 +184,9 @@ type ReputationInputs struct {
 	LowRiskCustomers int
 	TotalCustomerRows int
 
-	// From agent_reviews table
-	AvgReviewScore float64
-	ReviewCount    int
+	// From agent_reviews table, with reviewer credibility signals
+	Reviews     []ReviewSignal
+	ReviewCount int
 
 	// From network_agents (on-chain reputation via discovery service)
 	OnchainScore float64
@@ -138,11 +224,13 @@ func (s *Store) GetReputationInputs(ctx context.Context, agentID uuid.UUID) (*Re
 		FROM customers WHERE agent_id = $1
 	`, agentID).Scan(&ri.LowRiskCustomers, &ri.TotalCustomerRows)
 
-	// 4. Peer reviews average
-	_ = s.pool.QueryRow(ctx, `
-		SELECT COALESCE(AVG(score), 0), COUNT(*)
-		FROM agent_reviews WHERE agent_id = $1
-	`, agentID).Scan(&ri.AvgReviewScore, &ri.ReviewCount)
+	// 4. Peer reviews with reviewer credibility
+	reviews, err := s.GetReviewSignals(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	ri.Reviews = reviews
+	ri.ReviewCount = len(reviews)
 
 	// 5. On-chain reputation (from network_agents via discovery service)
 	_ = s.pool.QueryRow(ctx, `
@@ -154,3 +242,58 @@ func (s *Store) GetReputationInputs(ctx context.Context, agentID uuid.UUID) (*Re
 
 	return ri, nil
 }
+
+// ReviewSignal is a peer review with the facts used to weigh its reviewer.
+type ReviewSignal struct {
+	ReviewerID string
+	Score      int
+	CreatedAt  time.Time
+	// VerifiedCustomer is true when the reviewer wallet has a verified
+	// payment to this agent.
+	VerifiedCustomer bool
+	// KnownSince is the earliest time the wallet was seen anywhere on the
+	// platform (payment, agent registration or review).
+	KnownSince time.Time
+}
+
+// GetReviewSignals returns every review for an agent with reviewer
+// credibility signals, oldest first.
+func (s *Store) GetReviewSignals(ctx context.Context, agentID uuid.UUID) ([]ReviewSignal, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT r.reviewer_id, r.score, r.created_at,
+			EXISTS (
+				SELECT 1 FROM revenue_entries re
+				WHERE re.agent_id = r.agent_id AND re.verified = TRUE
+					AND LOWER(re.payer_address) = r.reviewer_id
+			) AS verified_customer,
+			LEAST(
+				r.created_at,
+				(SELECT MIN(re.created_at) FROM revenue_entries re
+					WHERE LOWER(re.payer_address) = r.reviewer_id),
+				(SELECT MIN(a.created_at) FROM agents a
+					WHERE LOWER(a.evm_address) = r.reviewer_id),
+				(SELECT MIN(r2.created_at) FROM agent_reviews r2
+					WHERE r2.reviewer_id = r.reviewer_id)
+			) AS known_since
+		FROM agent_reviews r
+		WHERE r.agent_id = $1
+		ORDER BY r.created_at ASC
+	`, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("get review signals: %w", err)
+	}
+	defer rows.Close()
+
+	var reviews []ReviewSignal
+	for rows.Next() {
+		var r ReviewSignal
+		if err := rows.Scan(&r.ReviewerID, &r.Score, &r.CreatedAt, &r.VerifiedCustomer, &r.KnownSince); err != nil {
+			return nil, fmt.Errorf("scan review signal: %w", err)
+		}
+		reviews = append(reviews, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate review signals: %w", err)
+	}
+	return reviews, nil
+}
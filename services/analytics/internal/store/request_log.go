@@ -156,53 +156,55 @@ type DailyStats struct {
 	P95ResponseMs   float64 `json:"p95_response_ms"`
 }
 
-// GetDailyStats returns daily request/revenue/error counts and response time metrics for the last N days.
-func (s *Store) GetDailyStats(ctx context.Context, agentDBID uuid.UUID, days int) ([]DailyStats, error) {
+// GetDailyStats returns daily request/revenue/error counts and response time
+// metrics for the last N local days in loc, oldest first, with a zero row for
+// days without traffic.
+func (s *Store) GetDailyStats(ctx context.Context, agentDBID uuid.UUID, days int, loc *time.Location) ([]DailyStats, error) {
 	if days <= 0 {
 		days = 30
 	}
+	since, dates := DayBuckets(loc, days, time.Now())
 
 	// Revenue from revenue_entries (verified=true only), joined by date.
 	rows, err := s.pool.Query(ctx, `
 		WITH req AS (
 			SELECT
-				DATE(created_at) AS date,
+				(created_at AT TIME ZONE $3)::date AS date,
 				COUNT(*) AS requests,
 				COUNT(*) FILTER (WHERE status_code >= 400 AND status_code != 402) AS errors,
 				COUNT(DISTINCT ip_address) FILTER (WHERE ip_address IS NOT NULL) AS unique_customers,
 				COALESCE(AVG(response_ms), 0) AS avg_response_ms,
 				COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY response_ms), 0) AS p95_response_ms
 			FROM request_logs
-			WHERE agent_id = $1 AND created_at >= CURRENT_DATE - $2 * INTERVAL '1 day'
-			GROUP BY DATE(created_at)
+			WHERE agent_id = $1 AND created_at >= $2
+			GROUP BY 1
 		),
 		rev AS (
 			SELECT
-				DATE(created_at) AS date,
+				(created_at AT TIME ZONE $3)::date AS date,
 				COALESCE(SUM(amount), 0) AS revenue
 			FROM revenue_entries
 			WHERE agent_id = $1 AND verified = TRUE
-			  AND created_at >= CURRENT_DATE - $2 * INTERVAL '1 day'
-			GROUP BY DATE(created_at)
+			  AND created_at >= $2
+			GROUP BY 1
 		)
 		SELECT
-			req.date,
-			req.requests,
+			COALESCE(req.date, rev.date) AS date,
+			COALESCE(req.requests, 0),
 			COALESCE(rev.revenue, 0) AS revenue,
-			req.errors,
-			req.unique_customers,
-			req.avg_response_ms,
-			req.p95_response_ms
+			COALESCE(req.errors, 0),
+			COALESCE(req.unique_customers, 0),
+			COALESCE(req.avg_response_ms, 0),
+			COALESCE(req.p95_response_ms, 0)
 		FROM req
-		LEFT JOIN rev ON rev.date = req.date
-		ORDER BY req.date
-	`, agentDBID, days)
+		FULL OUTER JOIN rev ON rev.date = req.date
+	`, agentDBID, since, loc.String())
 	if err != nil {
 		return nil, fmt.Errorf("get daily stats: %w", err)
 	}
 	defer rows.Close()
 
-	var stats []DailyStats
+	byDate := make(map[string]DailyStats, days)
 	for rows.Next() {
 		var d DailyStats
 		var date time.Time
@@ -210,11 +212,17 @@ func (s *Store) GetDailyStats(ctx context.Context, agentDBID uuid.UUID, days int
 			return nil, fmt.Errorf("scan daily stats: %w", err)
 		}
 		d.Date = date.Format("2006-01-02")
-		stats = append(stats, d)
+		byDate[d.Date] = d
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get daily stats: %w", err)
 	}
 
-	if stats == nil {
-		stats = []DailyStats{}
+	stats := make([]DailyStats, len(dates))
+	for i, date := range dates {
+		d := byDate[date]
+		d.Date = date
+		stats[i] = d
 	}
 
 	return stats, nil
@@ -958,16 +966,18 @@ func (s *Store) GetFunnelSummary(ctx context.Context, agentDBID uuid.UUID, days
 	return f, nil
 }
 
-// GetDailyFunnelStats returns per-day funnel progression counts.
-func (s *Store) GetDailyFunnelStats(ctx context.Context, agentDBID uuid.UUID, days int) ([]DailyFunnelStats, error) {
+// GetDailyFunnelStats returns per-day funnel progression counts for the last
+// N local days in loc, oldest first and gap-filled.
+func (s *Store) GetDailyFunnelStats(ctx context.Context, agentDBID uuid.UUID, days int, loc *time.Location) ([]DailyFunnelStats, error) {
 	if days <= 0 {
 		days = 30
 	}
+	since, dates := DayBuckets(loc, days, time.Now())
 
 	rows, err := s.pool.Query(ctx, `
 		WITH daily_cumulative AS (
 			SELECT
-				DATE(created_at) AS date,
+				(created_at AT TIME ZONE $3)::date AS date,
 				ip_address,
 				BOOL_OR(protocol = 'mcp') AS has_mcp,
 				BOOL_OR(protocol = 'a2a') AS has_a2a,
@@ -975,8 +985,8 @@ func (s *Store) GetDailyFunnelStats(ctx context.Context, agentDBID uuid.UUID, da
 			FROM request_logs
 			WHERE agent_id = $1
 			  AND ip_address IS NOT NULL
-			  AND created_at >= CURRENT_DATE - $2 * INTERVAL '1 day'
-			GROUP BY DATE(created_at), ip_address
+			  AND created_at >= $2
+			GROUP BY 1, ip_address
 		)
 		SELECT
 			date,
@@ -985,14 +995,13 @@ func (s *Store) GetDailyFunnelStats(ctx context.Context, agentDBID uuid.UUID, da
 			COUNT(DISTINCT ip_address) FILTER (WHERE has_a2a_paid)
 		FROM daily_cumulative
 		GROUP BY date
-		ORDER BY date
-	`, agentDBID, days)
+	`, agentDBID, since, loc.String())
 	if err != nil {
 		return nil, fmt.Errorf("get daily funnel stats: %w", err)
 	}
 	defer rows.Close()
 
-	var stats []DailyFunnelStats
+	byDate := make(map[string]DailyFunnelStats, days)
 	for rows.Next() {
 		var d DailyFunnelStats
 		var date time.Time
@@ -1000,10 +1009,17 @@ func (s *Store) GetDailyFunnelStats(ctx context.Context, agentDBID uuid.UUID, da
 			return nil, fmt.Errorf("scan daily funnel stats: %w", err)
 		}
 		d.Date = date.Format("2006-01-02")
-		stats = append(stats, d)
+		byDate[d.Date] = d
 	}
-	if stats == nil {
-		stats = []DailyFunnelStats{}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get daily funnel stats: %w", err)
+	}
+
+	stats := make([]DailyFunnelStats, len(dates))
+	for i, date := range dates {
+		d := byDate[date]
+		d.Date = date
+		stats[i] = d
 	}
 	return stats, nil
 }
@@ -0,0 +1,354 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ---------- Customer Segments ----------
+
+// segmentSnapshotRetention is how long daily segment snapshots are kept.
+const segmentSnapshotRetention = "400 days"
+
+// SegmentInputs holds the per-customer history used for RFM scoring.
+type SegmentInputs struct {
+	CustomerID  string
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+	// ActiveDays is the number of distinct days with requests in the last
+	// 365 days.
+	ActiveDays int64
+	// Revenue is the customer's verified revenue; FirstPaymentAt is nil
+	// when the customer has never paid.
+	Revenue        float64
+	FirstPaymentAt *time.Time
+	ChurnScore     *float64
+}
+
+// CustomerSegment is a computed RFM score and segment for one customer.
+type CustomerSegment struct {
+	CustomerID   string
+	Recency      int
+	Frequency    int
+	Monetary     int
+	Segment      string
+	ProjectedLTV float64
+}
+
+// SegmentCount summarizes the customers currently in one segment.
+type SegmentCount struct {
+	Segment         string  `json:"segment"`
+	Customers       int64   `json:"customers"`
+	ProjectedLTV    float64 `json:"projected_ltv"`
+	AvgProjectedLTV float64 `json:"avg_projected_ltv"`
+}
+
+// SegmentMember is a customer with its stored RFM scores.
+type SegmentMember struct {
+	CustomerID    string    `json:"customer_id"`
+	FirstSeenAt   time.Time `json:"first_seen_at"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
+	TotalRequests int64     `json:"total_requests"`
+	TotalRevenue  float64   `json:"total_revenue"`
+	Recency       int       `json:"recency_score"`
+	Frequency     int       `json:"frequency_score"`
+	Monetary      int       `json:"monetary_score"`
+	ProjectedLTV  float64   `json:"projected_ltv"`
+	ChurnRisk     string    `json:"churn_risk"`
+	Country       *string   `json:"country,omitempty"`
+}
+
+// SegmentTransition counts customers that moved from one segment to another
+// between two snapshots. "none" stands for not being a customer yet (or no
+// longer tracked).
+type SegmentTransition struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Customers int64  `json:"customers"`
+}
+
+// SegmentMovement compares segment membership between two snapshot dates.
+// FromDate and ToDate are the snapshots actually used (the latest on or
+// before each requested date) and are empty when none exists.
+type SegmentMovement struct {
+	FromDate    string              `json:"from_date"`
+	ToDate      string              `json:"to_date"`
+	Transitions []SegmentTransition `json:"transitions"`
+}
+
+// SegmentHistoryPoint is the number of customers per segment on one day.
+type SegmentHistoryPoint struct {
+	Date     string           `json:"date"`
+	Segments map[string]int64 `json:"segments"`
+}
+
+// GetSegmentInputs returns RFM scoring inputs for every customer of an agent.
+func (s *Store) GetSegmentInputs(ctx context.Context, agentDBID uuid.UUID) ([]SegmentInputs, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH active AS (
+			SELECT ip_address AS customer, COUNT(DISTINCT date_trunc('day', created_at)) AS days
+			FROM request_logs
+			WHERE agent_id = $1 AND ip_address IS NOT NULL
+				AND created_at >= NOW() - INTERVAL '365 days'
+			GROUP BY ip_address
+		),
+		payments AS (
+			SELECT customer_id AS customer, SUM(amount) AS revenue, MIN(created_at) AS first_paid
+			FROM revenue_entries
+			WHERE agent_id = $1 AND verified = TRUE AND customer_id IS NOT NULL
+			GROUP BY customer_id
+		)
+		SELECT c.customer_id, c.first_seen_at, c.last_seen_at,
+			COALESCE(a.days, 0), COALESCE(p.revenue, 0)::float8, p.first_paid,
+			c.churn_score::float8
+		FROM customers c
+		LEFT JOIN active a ON a.customer = c.customer_id
+		LEFT JOIN payments p ON p.customer = c.customer_id
+		WHERE c.agent_id = $1
+	`, agentDBID)
+	if err != nil {
+		return nil, fmt.Errorf("get segment inputs: %w", err)
+	}
+	defer rows.Close()
+
+	var inputs []SegmentInputs
+	for rows.Next() {
+		var in SegmentInputs
+		if err := rows.Scan(
+			&in.CustomerID, &in.FirstSeenAt, &in.LastSeenAt,
+			&in.ActiveDays, &in.Revenue, &in.FirstPaymentAt, &in.ChurnScore,
+		); err != nil {
+			return nil, fmt.Errorf("scan segment inputs: %w", err)
+		}
+		inputs = append(inputs, in)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate segment inputs: %w", err)
+	}
+
+	return inputs, nil
+}
+
+// UpdateCustomerSegments writes RFM scores and segments, records them as the
+// day's snapshot and prunes old snapshots, in one transaction.
+func (s *Store) UpdateCustomerSegments(ctx context.Context, agentDBID uuid.UUID, segments []CustomerSegment) error {
+	if len(segments) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(segments))
+	recency := make([]int32, len(segments))
+	frequency := make([]int32, len(segments))
+	monetary := make([]int32, len(segments))
+	names := make([]string, len(segments))
+	ltv := make([]float64, len(segments))
+	for i, sg := range segments {
+		ids[i] = sg.CustomerID
+		recency[i] = int32(sg.Recency)
+		frequency[i] = int32(sg.Frequency)
+		monetary[i] = int32(sg.Monetary)
+		names[i] = sg.Segment
+		ltv[i] = sg.ProjectedLTV
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE customers c
+		SET rfm_recency = u.r,
+			rfm_frequency = u.f,
+			rfm_monetary = u.m,
+			segment = u.segment,
+			projected_ltv = u.ltv,
+			segmented_at = NOW()
+		FROM unnest($2::text[], $3::int[], $4::int[], $5::int[], $6::text[], $7::float8[])
+			AS u(customer_id, r, f, m, segment, ltv)
+		WHERE c.agent_id = $1 AND c.customer_id = u.customer_id
+	`, agentDBID, ids, recency, frequency, monetary, names, ltv)
+	if err != nil {
+		return fmt.Errorf("update customer segments: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO customer_segment_snapshots (agent_id, snapshot_date, customer_id, segment, projected_ltv)
+		SELECT $1, (NOW() AT TIME ZONE 'UTC')::date, u.customer_id, u.segment, u.ltv
+		FROM unnest($2::text[], $3::text[], $4::float8[]) AS u(customer_id, segment, ltv)
+		ON CONFLICT (agent_id, snapshot_date, customer_id) DO UPDATE SET
+			segment = EXCLUDED.segment,
+			projected_ltv = EXCLUDED.projected_ltv
+	`, agentDBID, ids, names, ltv)
+	if err != nil {
+		return fmt.Errorf("insert segment snapshots: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM customer_segment_snapshots
+		WHERE agent_id = $1 AND snapshot_date < (NOW() AT TIME ZONE 'UTC')::date - INTERVAL '`+segmentSnapshotRetention+`'
+	`, agentDBID)
+	if err != nil {
+		return fmt.Errorf("prune segment snapshots: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetSegmentCounts returns the current number of customers and projected
+// LTV per segment.
+func (s *Store) GetSegmentCounts(ctx context.Context, agentDBID uuid.UUID) ([]SegmentCount, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT segment, COUNT(*),
+			COALESCE(SUM(projected_ltv), 0)::float8,
+			COALESCE(AVG(projected_ltv), 0)::float8
+		FROM customers
+		WHERE agent_id = $1 AND segment IS NOT NULL
+		GROUP BY segment
+	`, agentDBID)
+	if err != nil {
+		return nil, fmt.Errorf("get segment counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []SegmentCount{}
+	for rows.Next() {
+		var sc SegmentCount
+		if err := rows.Scan(&sc.Segment, &sc.Customers, &sc.ProjectedLTV, &sc.AvgProjectedLTV); err != nil {
+			return nil, fmt.Errorf("scan segment count: %w", err)
+		}
+		counts = append(counts, sc)
+	}
+	return counts, rows.Err()
+}
+
+// GetSegmentMembers returns the customers in a segment, highest projected
+// LTV first, with the total count.
+func (s *Store) GetSegmentMembers(ctx context.Context, agentDBID uuid.UUID, segment string, limit, offset int) ([]SegmentMember, int, error) {
+	var total int
+	err := s.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM customers WHERE agent_id = $1 AND segment = $2
+	`, agentDBID, segment).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count segment members: %w", err)
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT customer_id, first_seen_at, last_seen_at, total_requests,
+			COALESCE(total_revenue, 0)::float8,
+			rfm_recency, rfm_frequency, rfm_monetary,
+			COALESCE(projected_ltv, 0)::float8, COALESCE(churn_risk, ''), country
+		FROM customers
+		WHERE agent_id = $1 AND segment = $2
+		ORDER BY projected_ltv DESC NULLS LAST, last_seen_at DESC
+		LIMIT $3 OFFSET $4
+	`, agentDBID, segment, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get segment members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []SegmentMember{}
+	for rows.Next() {
+		var m SegmentMember
+		if err := rows.Scan(
+			&m.CustomerID, &m.FirstSeenAt, &m.LastSeenAt, &m.TotalRequests, &m.TotalRevenue,
+			&m.Recency, &m.Frequency, &m.Monetary,
+			&m.ProjectedLTV, &m.ChurnRisk, &m.Country,
+		); err != nil {
+			return nil, 0, fmt.Errorf("scan segment member: %w", err)
+		}
+		members = append(members, m)
+	}
+	return members, total, rows.Err()
+}
+
+// GetSegmentMovement counts customers by (segment on from, segment on to),
+// using the latest snapshot on or before each date.
+func (s *Store) GetSegmentMovement(ctx context.Context, agentDBID uuid.UUID, from, to time.Time) (*SegmentMovement, error) {
+	var fromDate, toDate *time.Time
+	err := s.pool.QueryRow(ctx, `
+		SELECT
+			(SELECT MAX(snapshot_date) FROM customer_segment_snapshots WHERE agent_id = $1 AND snapshot_date <= $2::date),
+			(SELECT MAX(snapshot_date) FROM customer_segment_snapshots WHERE agent_id = $1 AND snapshot_date <= $3::date)
+	`, agentDBID, from, to).Scan(&fromDate, &toDate)
+	if err != nil {
+		return nil, fmt.Errorf("get segment snapshot dates: %w", err)
+	}
+
+	m := &SegmentMovement{Transitions: []SegmentTransition{}}
+	if fromDate != nil {
+		m.FromDate = fromDate.Format("2006-01-02")
+	}
+	if toDate == nil {
+		return m, nil
+	}
+	m.ToDate = toDate.Format("2006-01-02")
+
+	rows, err := s.pool.Query(ctx, `
+		WITH a AS (
+			SELECT customer_id, segment FROM customer_segment_snapshots
+			WHERE agent_id = $1 AND snapshot_date = $2
+		),
+		b AS (
+			SELECT customer_id, segment FROM customer_segment_snapshots
+			WHERE agent_id = $1 AND snapshot_date = $3
+		)
+		SELECT COALESCE(a.segment, 'none'), COALESCE(b.segment, 'none'), COUNT(*)
+		FROM a
+		FULL OUTER JOIN b ON b.customer_id = a.customer_id
+		GROUP BY 1, 2
+		ORDER BY 3 DESC, 1, 2
+	`, agentDBID, fromDate, toDate)
+	if err != nil {
+		return nil, fmt.Errorf("get segment movement: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t SegmentTransition
+		if err := rows.Scan(&t.From, &t.To, &t.Customers); err != nil {
+			return nil, fmt.Errorf("scan segment transition: %w", err)
+		}
+		m.Transitions = append(m.Transitions, t)
+	}
+	return m, rows.Err()
+}
+
+// GetSegmentHistory returns daily customer counts per segment since since,
+// oldest first. Days without a snapshot are omitted.
+func (s *Store) GetSegmentHistory(ctx context.Context, agentDBID uuid.UUID, since time.Time) ([]SegmentHistoryPoint, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT snapshot_date, segment, COUNT(*)
+		FROM customer_segment_snapshots
+		WHERE agent_id = $1 AND snapshot_date >= $2::date
+		GROUP BY snapshot_date, segment
+		ORDER BY snapshot_date, segment
+	`, agentDBID, since)
+	if err != nil {
+		return nil, fmt.Errorf("get segment history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []SegmentHistoryPoint{}
+	for rows.Next() {
+		var (
+			date    time.Time
+			segment string
+			n       int64
+		)
+		if err := rows.Scan(&date, &segment, &n); err != nil {
+			return nil, fmt.Errorf("scan segment history: %w", err)
+		}
+		d := date.Format("2006-01-02")
+		if len(history) == 0 || history[len(history)-1].Date != d {
+			history = append(history, SegmentHistoryPoint{Date: d, Segments: map[string]int64{}})
+		}
+		history[len(history)-1].Segments[segment] = n
+	}
+	return history, rows.Err()
+}
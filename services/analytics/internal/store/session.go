@@ -0,0 +1,411 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------- Customer Sessions ----------
+
+// CustomerSession is one visit of a customer: consecutive requests with no
+// gap longer than the sessionization inactivity gap.
+type CustomerSession struct {
+	ID           uuid.UUID `json:"id"`
+	AgentID      uuid.UUID `json:"-"`
+	CustomerID   string    `json:"customer_id"`
+	StartedAt    time.Time `json:"started_at"`
+	EndedAt      time.Time `json:"ended_at"`
+	DurationSec  float64   `json:"duration_sec"`
+	RequestCount int       `json:"request_count"`
+	ErrorCount   int       `json:"error_count"`
+	PaidRequests int       `json:"paid_requests"`
+	PaidAmount   float64   `json:"paid_amount"`
+	Paid         bool      `json:"paid"`
+	// ToolSequence lists the tools called in order, with consecutive
+	// repeats collapsed.
+	ToolSequence []string `json:"tool_sequence"`
+	EndedInError bool     `json:"ended_in_error"`
+}
+
+// SessionLog is the part of a request log needed to build sessions.
+type SessionLog struct {
+	CustomerID string
+	ToolName   *string
+	StatusCode int
+	X402Amount *float64
+	CreatedAt  time.Time
+}
+
+// SessionFilter narrows ListSessions.
+type SessionFilter struct {
+	Since      time.Time
+	CustomerID string
+	PaidOnly   bool
+	ErrorOnly  bool
+	Limit      int
+	Offset     int
+}
+
+const sessionCols = `id, agent_id, customer_id, started_at, ended_at,
+	request_count, error_count, paid_requests, paid_amount::float8,
+	tool_sequence, ended_in_error`
+
+func scanSession(row interface{ Scan(...any) error }) (*CustomerSession, error) {
+	var cs CustomerSession
+	if err := row.Scan(
+		&cs.ID, &cs.AgentID, &cs.CustomerID, &cs.StartedAt, &cs.EndedAt,
+		&cs.RequestCount, &cs.ErrorCount, &cs.PaidRequests, &cs.PaidAmount,
+		&cs.ToolSequence, &cs.EndedInError,
+	); err != nil {
+		return nil, err
+	}
+	cs.DurationSec = cs.EndedAt.Sub(cs.StartedAt).Seconds()
+	cs.Paid = cs.PaidRequests > 0
+	if cs.ToolSequence == nil {
+		cs.ToolSequence = []string{}
+	}
+	return &cs, nil
+}
+
+// GetSessionCursor returns how far the agent's request logs have been
+// sessionized. ok is false when the agent has never been processed.
+func (s *Store) GetSessionCursor(ctx context.Context, agentDBID uuid.UUID) (until time.Time, ok bool, err error) {
+	err = s.pool.QueryRow(ctx, `
+		SELECT processed_until FROM session_cursors WHERE agent_id = $1
+	`, agentDBID).Scan(&until)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("get session cursor: %w", err)
+	}
+	return until, true, nil
+}
+
+// GetFirstRequestTime returns the agent's earliest request log time, or nil
+// when it has none.
+func (s *Store) GetFirstRequestTime(ctx context.Context, agentDBID uuid.UUID) (*time.Time, error) {
+	var first *time.Time
+	err := s.pool.QueryRow(ctx, `
+		SELECT MIN(created_at) FROM request_logs WHERE agent_id = $1
+	`, agentDBID).Scan(&first)
+	if err != nil {
+		return nil, fmt.Errorf("get first request time: %w", err)
+	}
+	return first, nil
+}
+
+// GetSessionLogs returns the agent's customer-attributed requests in
+// (from, to], ordered by customer and time.
+func (s *Store) GetSessionLogs(ctx context.Context, agentDBID uuid.UUID, from, to time.Time) ([]SessionLog, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT ip_address, tool_name, status_code, x402_amount::float8, created_at
+		FROM request_logs
+		WHERE agent_id = $1 AND created_at > $2 AND created_at <= $3
+		  AND ip_address IS NOT NULL
+		ORDER BY ip_address, created_at
+	`, agentDBID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("get session logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []SessionLog{}
+	for rows.Next() {
+		var l SessionLog
+		if err := rows.Scan(&l.CustomerID, &l.ToolName, &l.StatusCode, &l.X402Amount, &l.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan session log: %w", err)
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+// GetLatestSessions returns each listed customer's most recent session that
+// ended at or after since, keyed by customer. These are the sessions new
+// requests may extend.
+func (s *Store) GetLatestSessions(ctx context.Context, agentDBID uuid.UUID, customerIDs []string, since time.Time) (map[string]*CustomerSession, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT DISTINCT ON (customer_id) `+sessionCols+`
+		FROM customer_sessions
+		WHERE agent_id = $1 AND customer_id = ANY($2) AND ended_at >= $3
+		ORDER BY customer_id, ended_at DESC
+	`, agentDBID, customerIDs, since)
+	if err != nil {
+		return nil, fmt.Errorf("get latest sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make(map[string]*CustomerSession)
+	for rows.Next() {
+		cs, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+		sessions[cs.CustomerID] = cs
+	}
+	return sessions, rows.Err()
+}
+
+// SaveSessions upserts sessions (keyed by customer and start time) and
+// advances the agent's session cursor in one transaction.
+func (s *Store) SaveSessions(ctx context.Context, agentDBID uuid.UUID, sessions []CustomerSession, processedUntil time.Time) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, cs := range sessions {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO customer_sessions (
+				agent_id, customer_id, started_at, ended_at,
+				request_count, error_count, paid_requests, paid_amount,
+				tool_sequence, ended_in_error
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (agent_id, customer_id, started_at) DO UPDATE SET
+				ended_at = EXCLUDED.ended_at,
+				request_count = EXCLUDED.request_count,
+				error_count = EXCLUDED.error_count,
+				paid_requests = EXCLUDED.paid_requests,
+				paid_amount = EXCLUDED.paid_amount,
+				tool_sequence = EXCLUDED.tool_sequence,
+				ended_in_error = EXCLUDED.ended_in_error,
+				updated_at = NOW()
+		`, agentDBID, cs.CustomerID, cs.StartedAt, cs.EndedAt,
+			cs.RequestCount, cs.ErrorCount, cs.PaidRequests, cs.PaidAmount,
+			cs.ToolSequence, cs.EndedInError,
+		)
+		if err != nil {
+			return fmt.Errorf("upsert session: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO session_cursors (agent_id, processed_until)
+		VALUES ($1, $2)
+		ON CONFLICT (agent_id) DO UPDATE SET processed_until = EXCLUDED.processed_until, updated_at = NOW()
+	`, agentDBID, processedUntil)
+	if err != nil {
+		return fmt.Errorf("update session cursor: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListSessions returns an agent's sessions that started since f.Since,
+// newest first, with the total matching count.
+func (s *Store) ListSessions(ctx context.Context, agentDBID uuid.UUID, f SessionFilter) ([]CustomerSession, int, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	where := `agent_id = $1 AND started_at >= $2`
+	args := []any{agentDBID, f.Since}
+	if f.CustomerID != "" {
+		args = append(args, f.CustomerID)
+		where += fmt.Sprintf(" AND customer_id = $%d", len(args))
+	}
+	if f.PaidOnly {
+		where += " AND paid_requests > 0"
+	}
+	if f.ErrorOnly {
+		where += " AND ended_in_error"
+	}
+
+	var total int
+	if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM customer_sessions WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count sessions: %w", err)
+	}
+
+	args = append(args, f.Limit, f.Offset)
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+sessionCols+`
+		FROM customer_sessions
+		WHERE `+where+fmt.Sprintf(`
+		ORDER BY started_at DESC
+		LIMIT $%d OFFSET $%d`, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []CustomerSession{}
+	for rows.Next() {
+		cs, err := scanSession(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("scan session: %w", err)
+		}
+		sessions = append(sessions, *cs)
+	}
+	return sessions, total, rows.Err()
+}
+
+// GetSession returns one session of an agent.
+func (s *Store) GetSession(ctx context.Context, agentDBID, sessionID uuid.UUID) (*CustomerSession, error) {
+	cs, err := scanSession(s.pool.QueryRow(ctx, `
+		SELECT `+sessionCols+` FROM customer_sessions WHERE agent_id = $1 AND id = $2
+	`, agentDBID, sessionID))
+	if err != nil {
+		return nil, fmt.Errorf("get session: %w", err)
+	}
+	return cs, nil
+}
+
+// GetSessionRequests returns the requests of a session in order, without
+// bodies or headers.
+func (s *Store) GetSessionRequests(ctx context.Context, cs *CustomerSession, limit int) ([]RequestLog, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, agent_id, request_id, tool_name, method, path,
+			status_code, response_ms, error_type,
+			x402_amount, x402_tx_hash, protocol, created_at
+		FROM request_logs
+		WHERE agent_id = $1 AND ip_address = $2
+		  AND created_at >= $3 AND created_at <= $4
+		ORDER BY created_at
+		LIMIT $5
+	`, cs.AgentID, cs.CustomerID, cs.StartedAt, cs.EndedAt, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get session requests: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []RequestLog{}
+	for rows.Next() {
+		var l RequestLog
+		if err := rows.Scan(
+			&l.ID, &l.AgentID, &l.RequestID, &l.ToolName, &l.Method, &l.Path,
+			&l.StatusCode, &l.ResponseMs, &l.ErrorType,
+			&l.X402Amount, &l.X402TxHash, &l.Protocol, &l.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan session request: %w", err)
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+// SessionSummary aggregates an agent's sessions over a window.
+type SessionSummary struct {
+	Sessions            int64   `json:"sessions"`
+	Customers           int64   `json:"customers"`
+	AvgDurationSec      float64 `json:"avg_duration_sec"`
+	MedianDurationSec   float64 `json:"median_duration_sec"`
+	AvgRequests         float64 `json:"avg_requests_per_session"`
+	SingleRequestRate   float64 `json:"single_request_rate"`
+	PaidSessionRate     float64 `json:"paid_session_rate"`
+	ErrorEndedRate      float64 `json:"error_ended_rate"`
+	SessionsPerCustomer float64 `json:"sessions_per_customer"`
+}
+
+// ToolSequenceCount is how often a tool sequence occurred and its share of
+// all occurrences.
+type ToolSequenceCount struct {
+	Sequence []string `json:"sequence"`
+	Count    int64    `json:"count"`
+	Share    float64  `json:"share"`
+}
+
+// GetSessionSummary aggregates sessions that started since since.
+func (s *Store) GetSessionSummary(ctx context.Context, agentDBID uuid.UUID, since time.Time) (*SessionSummary, error) {
+	var sum SessionSummary
+	err := s.pool.QueryRow(ctx, `
+		SELECT
+			COUNT(*),
+			COUNT(DISTINCT customer_id),
+			COALESCE(AVG(EXTRACT(EPOCH FROM ended_at - started_at)), 0)::float8,
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM ended_at - started_at)), 0)::float8,
+			COALESCE(AVG(request_count), 0)::float8,
+			COALESCE(AVG(CASE WHEN request_count = 1 THEN 1.0 ELSE 0.0 END), 0)::float8,
+			COALESCE(AVG(CASE WHEN paid_requests > 0 THEN 1.0 ELSE 0.0 END), 0)::float8,
+			COALESCE(AVG(CASE WHEN ended_in_error THEN 1.0 ELSE 0.0 END), 0)::float8
+		FROM customer_sessions
+		WHERE agent_id = $1 AND started_at >= $2
+	`, agentDBID, since).Scan(
+		&sum.Sessions, &sum.Customers, &sum.AvgDurationSec, &sum.MedianDurationSec,
+		&sum.AvgRequests, &sum.SingleRequestRate, &sum.PaidSessionRate, &sum.ErrorEndedRate,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get session summary: %w", err)
+	}
+	if sum.Customers > 0 {
+		sum.SessionsPerCustomer = float64(sum.Sessions) / float64(sum.Customers)
+	}
+	return &sum, nil
+}
+
+// GetCommonToolSequences returns the most frequent opening tool sequences
+// (the first up to length tools of each session) since since.
+func (s *Store) GetCommonToolSequences(ctx context.Context, agentDBID uuid.UUID, since time.Time, length, limit int) ([]ToolSequenceCount, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH seqs AS (
+			SELECT tool_sequence[1:$3] AS seq
+			FROM customer_sessions
+			WHERE agent_id = $1 AND started_at >= $2 AND cardinality(tool_sequence) > 0
+		)
+		SELECT seq, COUNT(*), COUNT(*)::float8 / SUM(COUNT(*)) OVER ()
+		FROM seqs
+		GROUP BY seq
+		ORDER BY 2 DESC, seq
+		LIMIT $4
+	`, agentDBID, since, length, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get common tool sequences: %w", err)
+	}
+	defer rows.Close()
+
+	seqs := []ToolSequenceCount{}
+	for rows.Next() {
+		var t ToolSequenceCount
+		if err := rows.Scan(&t.Sequence, &t.Count, &t.Share); err != nil {
+			return nil, fmt.Errorf("scan tool sequence: %w", err)
+		}
+		seqs = append(seqs, t)
+	}
+	return seqs, rows.Err()
+}
+
+// GetToolTransitions returns the most frequent tool-to-tool steps within
+// sessions since since, as two-element sequences.
+func (s *Store) GetToolTransitions(ctx context.Context, agentDBID uuid.UUID, since time.Time, limit int) ([]ToolSequenceCount, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH steps AS (
+			SELECT t.tool, t.ord, cs.id
+			FROM customer_sessions cs
+			CROSS JOIN LATERAL unnest(cs.tool_sequence) WITH ORDINALITY AS t(tool, ord)
+			WHERE cs.agent_id = $1 AND cs.started_at >= $2 AND cardinality(cs.tool_sequence) > 1
+		),
+		pairs AS (
+			SELECT a.tool AS from_tool, b.tool AS to_tool
+			FROM steps a
+			JOIN steps b ON b.id = a.id AND b.ord = a.ord + 1
+		)
+		SELECT ARRAY[from_tool, to_tool], COUNT(*), COUNT(*)::float8 / SUM(COUNT(*)) OVER ()
+		FROM pairs
+		GROUP BY from_tool, to_tool
+		ORDER BY 2 DESC, from_tool, to_tool
+		LIMIT $3
+	`, agentDBID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get tool transitions: %w", err)
+	}
+	defer rows.Close()
+
+	pairs := []ToolSequenceCount{}
+	for rows.Next() {
+		var t ToolSequenceCount
+		if err := rows.Scan(&t.Sequence, &t.Count, &t.Share); err != nil {
+			return nil, fmt.Errorf("scan tool transition: %w", err)
+		}
+		pairs = append(pairs, t)
+	}
+	return pairs, rows.Err()
+}
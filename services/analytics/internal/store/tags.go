@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ---------- Tags ----------
+
+// MaxTagGroups caps the number of groups returned by a tag group-by.
+const MaxTagGroups = 50
+
+// TagQuery selects request logs by tag and optionally groups them by the
+// value of one tag key.
+type TagQuery struct {
+	GroupBy string            // tag key, or "" for a single group
+	Filters map[string]string // every key must have exactly this value
+}
+
+// TagGroupMetrics holds request, latency and revenue metrics for one tag
+// group. Value is nil for requests without the group-by tag (or for the
+// single group of an ungrouped query).
+type TagGroupMetrics struct {
+	Value           *string `json:"value"`
+	Requests        int64   `json:"requests"`
+	Errors          int64   `json:"errors"`
+	ErrorRate       float64 `json:"error_rate"`
+	AvgResponseMs   float64 `json:"avg_response_ms"`
+	P50ResponseMs   float64 `json:"p50_response_ms"`
+	P95ResponseMs   float64 `json:"p95_response_ms"`
+	P99ResponseMs   float64 `json:"p99_response_ms"`
+	Customers       int64   `json:"customers"`
+	PaidRequests    int64   `json:"paid_requests"`
+	PayingCustomers int64   `json:"paying_customers"`
+	Revenue         float64 `json:"revenue"` // verified, matched by tx hash
+	ARPU            float64 `json:"arpu"`
+}
+
+// GetTagGroupMetrics aggregates request logs since the given time by tag.
+// Revenue is verified revenue joined through the payment tx hash, since
+// revenue_entries carry no tags. Groups are ordered by request count and
+// capped at MaxTagGroups.
+func (s *Store) GetTagGroupMetrics(ctx context.Context, agentDBID uuid.UUID, since time.Time, q TagQuery) ([]TagGroupMetrics, error) {
+	args := []any{agentDBID, since}
+	group := "NULL::text"
+	if q.GroupBy != "" {
+		args = append(args, q.GroupBy)
+		group = fmt.Sprintf("tags ->> $%d", len(args))
+	}
+	filter := ""
+	if len(q.Filters) > 0 {
+		data, _ := json.Marshal(q.Filters)
+		args = append(args, string(data))
+		filter = fmt.Sprintf("AND tags @> $%d::jsonb", len(args))
+	}
+
+	query := fmt.Sprintf(`
+		WITH logs AS (
+			SELECT %s AS grp, response_ms, status_code, ip_address, x402_amount, x402_tx_hash
+			FROM request_logs
+			WHERE agent_id = $1 AND created_at >= $2 %s
+		),
+		rev AS (
+			SELECT tx_hash, MAX(amount) AS amount
+			FROM revenue_entries
+			WHERE agent_id = $1 AND verified = TRUE AND created_at >= $2 AND tx_hash IS NOT NULL
+			GROUP BY tx_hash
+		)
+		SELECT
+			logs.grp,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status_code >= 400 AND status_code != 402),
+			COALESCE(AVG(response_ms), 0),
+			COALESCE(percentile_cont(0.50) WITHIN GROUP (ORDER BY response_ms), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY response_ms), 0),
+			COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY response_ms), 0),
+			COUNT(DISTINCT ip_address),
+			COUNT(*) FILTER (WHERE x402_amount IS NOT NULL AND x402_amount > 0),
+			COUNT(DISTINCT ip_address) FILTER (WHERE x402_amount IS NOT NULL AND x402_amount > 0),
+			COALESCE(SUM(rev.amount), 0)::float8
+		FROM logs
+		LEFT JOIN rev ON rev.tx_hash = logs.x402_tx_hash
+		GROUP BY logs.grp
+		ORDER BY COUNT(*) DESC
+		LIMIT %d
+	`, group, filter, MaxTagGroups)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get tag group metrics: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []TagGroupMetrics{}
+	for rows.Next() {
+		var g TagGroupMetrics
+		if err := rows.Scan(&g.Value, &g.Requests, &g.Errors, &g.AvgResponseMs,
+			&g.P50ResponseMs, &g.P95ResponseMs, &g.P99ResponseMs,
+			&g.Customers, &g.PaidRequests, &g.PayingCustomers, &g.Revenue); err != nil {
+			return nil, fmt.Errorf("scan tag group metrics: %w", err)
+		}
+		if g.Requests > 0 {
+			g.ErrorRate = float64(g.Errors) / float64(g.Requests)
+		}
+		if g.Customers > 0 {
+			g.ARPU = g.Revenue / float64(g.Customers)
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// TagKey lists the registered values of one tag key.
+type TagKey struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
+
+// ListTagKeys returns every tag key and value registered for an agent.
+func (s *Store) ListTagKeys(ctx context.Context, agentDBID uuid.UUID) ([]TagKey, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT key, array_agg(value ORDER BY value)
+		FROM request_tag_values
+		WHERE agent_id = $1
+		GROUP BY key
+		ORDER BY key
+	`, agentDBID)
+	if err != nil {
+		return nil, fmt.Errorf("list tag keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []TagKey{}
+	for rows.Next() {
+		var k TagKey
+		if err := rows.Scan(&k.Key, &k.Values); err != nil {
+			return nil, fmt.Errorf("scan tag key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
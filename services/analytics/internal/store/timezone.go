@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ---------- Agent settings ----------
+
+// GetAgentTimezone returns the agent's default reporting time zone, or "UTC"
+// when none has been set.
+func (s *Store) GetAgentTimezone(ctx context.Context, agentDBID uuid.UUID) (string, error) {
+	var tz string
+	err := s.pool.QueryRow(ctx, `
+		SELECT timezone FROM agent_settings WHERE agent_id = $1
+	`, agentDBID).Scan(&tz)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "UTC", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get agent timezone: %w", err)
+	}
+	return tz, nil
+}
+
+// SetAgentTimezone stores the agent's default reporting time zone. The name
+// must already be validated with time.LoadLocation.
+func (s *Store) SetAgentTimezone(ctx context.Context, agentDBID uuid.UUID, tz string) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO agent_settings (agent_id, timezone, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (agent_id) DO UPDATE SET timezone = EXCLUDED.timezone, updated_at = NOW()
+	`, agentDBID, tz)
+	if err != nil {
+		return fmt.Errorf("set agent timezone: %w", err)
+	}
+	return nil
+}
+
+// ---------- Time buckets ----------
+
+// DayBuckets returns the local calendar days (YYYY-MM-DD, oldest first) for
+// the last days days in loc, ending with today, and the instant the first
+// of them starts. Queries filter on created_at >= since and group by
+// (created_at AT TIME ZONE loc)::date so results line up with the buckets.
+func DayBuckets(loc *time.Location, days int, now time.Time) (time.Time, []string) {
+	local := now.In(loc)
+	first := time.Date(local.Year(), local.Month(), local.Day()-(days-1), 0, 0, 0, 0, loc)
+	dates := make([]string, days)
+	for i := range dates {
+		dates[i] = first.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	return first, dates
+}
+
+// HourBuckets returns the start of each of the last hours hours in loc,
+// oldest first, ending with the current (partial) hour. Hours are absolute,
+// so a repeated wall-clock hour at a DST change gets its own bucket.
+func HourBuckets(loc *time.Location, hours int, now time.Time) []time.Time {
+	// Truncate in local time: plain Truncate is wrong for zones with a
+	// sub-hour offset such as Asia/Kolkata.
+	_, offset := now.In(loc).Zone()
+	shift := time.Duration(offset) * time.Second
+	current := now.Add(shift).Truncate(time.Hour).Add(-shift).In(loc)
+	buckets := make([]time.Time, hours)
+	for i := range buckets {
+		buckets[i] = current.Add(time.Duration(i-hours+1) * time.Hour)
+	}
+	return buckets
+}
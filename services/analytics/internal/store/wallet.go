@@ -106,70 +106,77 @@ func (s *Store) GetWalletStats(ctx context.Context, agentDBIDs []uuid.UUID) (*Wa
 	return &stats, nil
 }
 
-// GetWalletDailyStats returns daily time-series data aggregated across owned agents.
-func (s *Store) GetWalletDailyStats(ctx context.Context, agentDBIDs []uuid.UUID, days int) ([]WalletDailyStats, error) {
-	if len(agentDBIDs) == 0 {
-		return []WalletDailyStats{}, nil
-	}
+// GetWalletDailyStats returns daily time-series data aggregated across owned
+// agents for the last N local days in loc, oldest first and gap-filled.
+func (s *Store) GetWalletDailyStats(ctx context.Context, agentDBIDs []uuid.UUID, days int, loc *time.Location) ([]WalletDailyStats, error) {
+	since, dates := DayBuckets(loc, days, time.Now())
+	byDate := make(map[string]WalletDailyStats, days)
 
-	// Revenue comes from revenue_entries (verified=true only), not from
-	// request_logs.x402_amount which is unverified claimed amounts.
-	query := `
-		WITH req AS (
-			SELECT
-				DATE(created_at) AS date,
-				COUNT(CASE WHEN status_code < 400 OR status_code = 402 THEN 1 END) AS requests,
-				COALESCE(AVG(response_ms), 0) AS avg_response_ms,
-				COALESCE(
-					SUM(CASE WHEN status_code >= 400 AND status_code != 402 THEN 1 ELSE 0 END)::float /
-					NULLIF(COUNT(*), 0),
-					0
-				) AS error_rate
-			FROM request_logs
-			WHERE agent_id = ANY($1)
-			  AND created_at >= NOW() - INTERVAL '1 day' * $2
-			GROUP BY DATE(created_at)
-		),
-		rev AS (
+	if len(agentDBIDs) > 0 {
+		// Revenue comes from revenue_entries (verified=true only), not from
+		// request_logs.x402_amount which is unverified claimed amounts.
+		query := `
+			WITH req AS (
+				SELECT
+					(created_at AT TIME ZONE $3)::date AS date,
+					COUNT(CASE WHEN status_code < 400 OR status_code = 402 THEN 1 END) AS requests,
+					COALESCE(AVG(response_ms), 0) AS avg_response_ms,
+					COALESCE(
+						SUM(CASE WHEN status_code >= 400 AND status_code != 402 THEN 1 ELSE 0 END)::float /
+						NULLIF(COUNT(*), 0),
+						0
+					) AS error_rate
+				FROM request_logs
+				WHERE agent_id = ANY($1)
+				  AND created_at >= $2
+				GROUP BY 1
+			),
+			rev AS (
+				SELECT
+					(created_at AT TIME ZONE $3)::date AS date,
+					COALESCE(SUM(amount), 0) AS revenue
+				FROM revenue_entries
+				WHERE agent_id = ANY($1)
+				  AND verified = TRUE
+				  AND created_at >= $2
+				GROUP BY 1
+			)
 			SELECT
-				DATE(created_at) AS date,
-				COALESCE(SUM(amount), 0) AS revenue
-			FROM revenue_entries
-			WHERE agent_id = ANY($1)
-			  AND verified = TRUE
-			  AND created_at >= NOW() - INTERVAL '1 day' * $2
-			GROUP BY DATE(created_at)
-		)
-		SELECT
-			req.date,
-			req.requests,
-			COALESCE(rev.revenue, 0) AS revenue,
-			req.avg_response_ms,
-			req.error_rate
-		FROM req
-		LEFT JOIN rev ON rev.date = req.date
-		ORDER BY req.date DESC
-	`
+				COALESCE(req.date, rev.date) AS date,
+				COALESCE(req.requests, 0),
+				COALESCE(rev.revenue, 0) AS revenue,
+				COALESCE(req.avg_response_ms, 0),
+				COALESCE(req.error_rate, 0)
+			FROM req
+			FULL OUTER JOIN rev ON rev.date = req.date
+		`
 
-	rows, err := s.pool.Query(ctx, query, agentDBIDs, days)
-	if err != nil {
-		return nil, fmt.Errorf("get wallet daily stats: %w", err)
-	}
-	defer rows.Close()
+		rows, err := s.pool.Query(ctx, query, agentDBIDs, since, loc.String())
+		if err != nil {
+			return nil, fmt.Errorf("get wallet daily stats: %w", err)
+		}
+		defer rows.Close()
 
-	var stats []WalletDailyStats
-	for rows.Next() {
-		var stat WalletDailyStats
-		var date time.Time
-		if err := rows.Scan(&date, &stat.Requests, &stat.Revenue, &stat.AvgResponseMs, &stat.ErrorRate); err != nil {
-			return nil, fmt.Errorf("scan daily stats: %w", err)
+		for rows.Next() {
+			var stat WalletDailyStats
+			var date time.Time
+			if err := rows.Scan(&date, &stat.Requests, &stat.Revenue, &stat.AvgResponseMs, &stat.ErrorRate); err != nil {
+				return nil, fmt.Errorf("scan daily stats: %w", err)
+			}
+			stat.Date = date.Format("2006-01-02")
+			byDate[stat.Date] = stat
+		}
+
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("iterate daily stats: %w", err)
 		}
-		stat.Date = date.Format("2006-01-02")
-		stats = append(stats, stat)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate daily stats: %w", err)
+	stats := make([]WalletDailyStats, len(dates))
+	for i, date := range dates {
+		stat := byDate[date]
+		stat.Date = date
+		stats[i] = stat
 	}
 
 	return stats, nil
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// IsSessionRevoked reports whether a wallet session (issued by the registry
+// in wallet_sessions) can no longer be used: it was revoked, its refresh
+// token expired, or it does not exist.
+func (s *Store) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return true, nil
+	}
+	var live bool
+	err = s.pool.QueryRow(ctx, `
+		SELECT revoked_at IS NULL AND expires_at > NOW() FROM wallet_sessions WHERE id = $1
+	`, id).Scan(&live)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check wallet session: %w", err)
+	}
+	return !live, nil
+}
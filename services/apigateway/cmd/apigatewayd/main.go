@@ -3,6 +3,8 @@ package main
 import (
 	"github.com/GT8004/apigateway/internal/config"
 	"github.com/GT8004/apigateway/internal/router"
+	"github.com/GT8004/gt8004-common/clientip"
+	"github.com/GT8004/gt8004-common/ratelimit"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -33,9 +35,17 @@ func main() {
 
 	// Create Gin engine
 	r := gin.New()
+	clientip.Configure(r, cfg.TrustedProxies)
+
+	// Rate limits (shared through Redis, per instance without it)
+	limiter, err := ratelimit.New(cfg.RedisURL, logger)
+	if err != nil {
+		logger.Fatal("failed to configure rate limits", zap.Error(err))
+	}
+	defer limiter.Close()
 
 	// Setup router
-	router.Setup(r, cfg, logger)
+	router.Setup(r, cfg, limiter, logger)
 
 	// Start server
 	logger.Info("API Gateway starting",
@@ -2,8 +2,11 @@ package config
 
 import (
 	"log"
+	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/GT8004/gt8004-common/clientip"
 )
 
 type Config struct {
@@ -12,6 +15,27 @@ type Config struct {
 	DiscoveryURL string `mapstructure:"discovery_url"`
 	RegistryURL  string `mapstructure:"registry_url"`
 	LogLevel     string `mapstructure:"log_level"`
+
+	// SessionSecret verifies wallet session tokens issued by the registry.
+	SessionSecret string `mapstructure:"session_secret"`
+	// WalletHeaderAuthUntil is when the raw X-Wallet-Address header stops
+	// being forwarded; unset never forwards it.
+	WalletHeaderAuthUntil time.Time `mapstructure:"-"`
+
+	// RedisURL holds rate limit state shared by gateway instances.
+	RedisURL string `mapstructure:"redis_url"`
+	// RateLimitCaller is the per-minute limit per API key, session wallet
+	// or IP across all proxied routes; zero disables it.
+	RateLimitCaller int `mapstructure:"rate_limit_caller_per_min"`
+	// RateLimitIP is the per-minute limit per client IP whatever the caller
+	// presents, so unvalidated bearer keys cannot spread one client over
+	// many buckets; zero disables it.
+	RateLimitIP int `mapstructure:"rate_limit_ip_per_min"`
+
+	// TrustedProxies are the proxies (addresses or CIDR ranges) whose
+	// X-Forwarded-For entries are believed when reading the client IP. The
+	// resolved IP is the only one forwarded to backends.
+	TrustedProxies []string `mapstructure:"-"`
 }
 
 func Load() *Config {
@@ -23,11 +47,28 @@ func Load() *Config {
 	viper.SetDefault("discovery_url", "http://discovery:8080")
 	viper.SetDefault("registry_url", "http://registry:8080")
 	viper.SetDefault("log_level", "info")
+	viper.SetDefault("session_secret", "")
+	viper.SetDefault("redis_url", "")
+	viper.SetDefault("rate_limit_caller_per_min", 1200)
+	viper.SetDefault("rate_limit_ip_per_min", 2400)
+	viper.SetDefault("trusted_proxies", clientip.CloudRun)
 
 	var cfg Config
 	if err := viper.Unmarshal(&cfg); err != nil {
 		log.Fatalf("failed to unmarshal config: %v", err)
 	}
+	if until := viper.GetString("wallet_header_auth_until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			log.Fatalf("invalid WALLET_HEADER_AUTH_UNTIL: %v", err)
+		}
+		cfg.WalletHeaderAuthUntil = t
+	}
+	proxies, err := clientip.ParseProxies(viper.GetString("trusted_proxies"))
+	if err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+	cfg.TrustedProxies = proxies
 
 	return &cfg
 }
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/GT8004/gt8004-common/session"
+)
+
+// LegacyWalletHeader drops the unauthenticated X-Wallet-Address header once
+// its deprecation window has ended, so backends never see it.
+func LegacyWalletHeader(until time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !session.LegacyHeaderAllowed(until) {
+			c.Request.Header.Del(session.LegacyWalletHeader)
+		}
+		c.Next()
+	}
+}
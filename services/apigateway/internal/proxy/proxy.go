@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httputil"
@@ -10,8 +11,22 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/ratelimit"
 )
 
+// rateLimitHeaders are replaced by the backend's when it reports its own,
+// finer limits.
+var rateLimitHeaders = []string{
+	ratelimit.HeaderLimit,
+	ratelimit.HeaderRemaining,
+	ratelimit.HeaderReset,
+	ratelimit.HeaderPolicy,
+}
+
+// clientHeaderKey carries the client response's headers to ModifyResponse.
+type clientHeaderKey struct{}
+
 // Identity token cache for Cloud Run service-to-service auth
 var (
 	tokenMu    sync.RWMutex
@@ -105,6 +120,15 @@ func ProxyTo(targetURL string, logger *zap.Logger) gin.HandlerFunc {
 		resp.Header.Del("Access-Control-Allow-Headers")
 		resp.Header.Del("Access-Control-Max-Age")
 		resp.Header.Del("Access-Control-Allow-Credentials")
+
+		// The proxy adds backend headers to the gateway's own.
+		if resp.Header.Get(ratelimit.HeaderRemaining) != "" {
+			if h, ok := resp.Request.Context().Value(clientHeaderKey{}).(http.Header); ok {
+				for _, name := range rateLimitHeaders {
+					h.Del(name)
+				}
+			}
+		}
 		return nil
 	}
 
@@ -119,7 +143,9 @@ func ProxyTo(targetURL string, logger *zap.Logger) gin.HandlerFunc {
 	}
 
 	return func(c *gin.Context) {
-		// Forward important headers
+		// Forward the client IP as resolved through trusted proxies only;
+		// entries the client wrote itself are dropped.
+		c.Request.Header.Set("X-Forwarded-For", c.ClientIP())
 		c.Request.Header.Set("X-Real-IP", c.ClientIP())
 
 		// Preserve X-Wallet-Address if present
@@ -127,6 +153,7 @@ func ProxyTo(targetURL string, logger *zap.Logger) gin.HandlerFunc {
 			c.Request.Header.Set("X-Wallet-Address", walletAddr)
 		}
 
-		proxy.ServeHTTP(c.Writer, c.Request)
+		ctx := context.WithValue(c.Request.Context(), clientHeaderKey{}, c.Writer.Header())
+		proxy.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
 	}
 }
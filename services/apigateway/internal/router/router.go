@@ -7,6 +7,8 @@ import (
 	"github.com/GT8004/apigateway/internal/config"
 	"github.com/GT8004/apigateway/internal/middleware"
 	"github.com/GT8004/apigateway/internal/proxy"
+	"github.com/GT8004/gt8004-common/ratelimit"
+	"github.com/GT8004/gt8004-common/session"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -21,13 +23,46 @@ var analyticsSubPaths = map[string]bool{
 	"logs":        true,
 	"analytics":   true,
 	"funnel":      true,
+	"funnels":     true,
+	"exports":     true,
+	"reputation":  true,
+	"peers":       true,
+	"deployments": true,
+	"sessions":    true,
+	"costs":       true,
+	"margins":     true,
 }
 
 // Setup configures all routes for the API Gateway.
-func Setup(r *gin.Engine, cfg *config.Config, logger *zap.Logger) {
+func Setup(r *gin.Engine, cfg *config.Config, limiter *ratelimit.Limiter, logger *zap.Logger) {
+	// Wallet session tokens are verified at the edge; revocation is checked
+	// by the backing service.
+	sessionSigner, err := session.NewSigner(cfg.SessionSecret)
+	if err != nil {
+		logger.Fatal("invalid session secret", zap.Error(err))
+	}
+
 	// Global middleware
 	r.Use(middleware.RequestLogger(logger))
 	r.Use(middleware.CORS())
+	r.Use(middleware.LegacyWalletHeader(cfg.WalletHeaderAuthUntil))
+	if sessionSigner.Enabled() {
+		r.Use(session.Middleware(sessionSigner, nil))
+	} else {
+		logger.Warn("SESSION_SECRET not set, session tokens are verified by backends only")
+	}
+	// Backing services apply their own, finer limits. Bearer keys are
+	// counted before any backend validates them, so each IP is limited too.
+	r.Use(limiter.Middleware(ratelimit.Policy{
+		Name:  "gateway:ip",
+		Key:   ratelimit.ByIP,
+		Limit: ratelimit.PerMinute(cfg.RateLimitIP),
+	}))
+	r.Use(limiter.Middleware(ratelimit.Policy{
+		Name:  "gateway:caller",
+		Key:   ratelimit.First(ratelimit.ByAPIKey, ratelimit.ByWallet, ratelimit.ByIP),
+		Limit: ratelimit.PerMinute(cfg.RateLimitCaller),
+	}))
 
 	// Health check (served directly by the gateway)
 	r.GET("/health", func(c *gin.Context) {
@@ -37,7 +72,9 @@ func Setup(r *gin.Engine, cfg *config.Config, logger *zap.Logger) {
 	// ── Analytics ───────────────────────────────────
 	r.Any("/v1/dashboard/*path", proxy.ProxyTo(cfg.AnalyticsURL, logger))
 	r.Any("/v1/benchmark", proxy.ProxyTo(cfg.AnalyticsURL, logger))
+	r.Any("/v1/benchmark/*path", proxy.ProxyTo(cfg.AnalyticsURL, logger))
 	r.Any("/v1/wallet/:address/*action", proxy.ProxyTo(cfg.AnalyticsURL, logger))
+	r.Any("/v1/exports/*path", proxy.ProxyTo(cfg.AnalyticsURL, logger))
 
 	// Agent sub-resources: route to Analytics or Registry based on sub-path.
 	// This avoids the Gin group conflict where /v1/agents/:id (Registry)
@@ -0,0 +1,205 @@
+// Package apikey defines agent API key scopes and the checks every service
+// applies when it validates a key. Keys are stored only as a SHA-256 hash;
+// the raw key is shown once, when it is created.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Prefix marks API keys so they can be told apart from session tokens.
+const Prefix = "gt8004_sk_"
+
+// Scopes a key can carry.
+const (
+	// ScopeIngest allows SDK log ingestion and deployment markers.
+	ScopeIngest = "ingest"
+	// ScopeAnalyticsRead allows reading the agent's analytics.
+	ScopeAnalyticsRead = "analytics:read"
+	// ScopeRegistryAdmin allows managing the agent: its registry record,
+	// keys and analytics settings. It implies ScopeAnalyticsRead.
+	ScopeRegistryAdmin = "registry:admin"
+)
+
+// AllScopes lists every scope, in display order. Keys created without
+// explicit scopes get all of them.
+var AllScopes = []string{ScopeIngest, ScopeAnalyticsRead, ScopeRegistryAdmin}
+
+// implied maps a scope to the scopes it also grants.
+var implied = map[string][]string{
+	ScopeRegistryAdmin: {ScopeAnalyticsRead},
+}
+
+var (
+	ErrInvalidKey   = errors.New("invalid api key")
+	ErrExpiredKey   = errors.New("api key expired")
+	ErrScopeDenied  = errors.New("api key lacks the required scope")
+	ErrIPNotAllowed = errors.New("api key not allowed from this ip")
+)
+
+// Generate returns a new raw key, its hash and its display prefix.
+func Generate() (raw, hash, prefix string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", fmt.Errorf("generate random bytes: %w", err)
+	}
+	raw = Prefix + hex.EncodeToString(b)
+	return raw, Hash(raw), raw[:16], nil
+}
+
+// Hash returns the hex SHA-256 of a raw key, which is what is stored.
+func Hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidScope reports whether s is a known scope.
+func ValidScope(s string) bool {
+	for _, scope := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeScopes validates scopes and returns them deduplicated in
+// AllScopes order. An empty list means every scope.
+func NormalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return append([]string(nil), AllScopes...), nil
+	}
+	want := make(map[string]bool, len(scopes))
+	for _, s := range scopes {
+		s = strings.TrimSpace(s)
+		if !ValidScope(s) {
+			return nil, fmt.Errorf("unknown scope %q", s)
+		}
+		want[s] = true
+	}
+	out := make([]string, 0, len(want))
+	for _, s := range AllScopes {
+		if want[s] {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// NormalizeAllowlist validates IP allowlist entries, which are single
+// addresses or CIDR ranges, and returns them in canonical form.
+func NormalizeAllowlist(entries []string) ([]string, error) {
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if strings.Contains(e, "/") {
+			p, err := netip.ParsePrefix(e)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cidr %q", e)
+			}
+			out = append(out, p.Masked().String())
+			continue
+		}
+		a, err := netip.ParseAddr(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ip %q", e)
+		}
+		out = append(out, a.Unmap().String())
+	}
+	return out, nil
+}
+
+// Grant is what a stored key allows.
+type Grant struct {
+	Scopes      []string
+	IPAllowlist []string
+	ExpiresAt   *time.Time
+}
+
+// HasScope reports whether the grant includes scope, directly or implied.
+func (g Grant) HasScope(scope string) bool {
+	for _, s := range g.Scopes {
+		if s == scope {
+			return true
+		}
+		for _, i := range implied[s] {
+			if i == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IPAllowed reports whether ip may use the key. An empty allowlist allows
+// every address.
+func (g Grant) IPAllowed(ip string) bool {
+	if len(g.IPAllowlist) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		// ClientIP may carry a port when no proxy header is set.
+		host, _, splitErr := net.SplitHostPort(ip)
+		if splitErr != nil {
+			return false
+		}
+		if addr, err = netip.ParseAddr(host); err != nil {
+			return false
+		}
+	}
+	addr = addr.Unmap()
+	for _, e := range g.IPAllowlist {
+		if strings.Contains(e, "/") {
+			if p, err := netip.ParsePrefix(e); err == nil && p.Contains(addr) {
+				return true
+			}
+		} else if a, err := netip.ParseAddr(e); err == nil && a.Unmap() == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize checks the grant for a request needing scope from ip. An empty
+// scope only checks expiry and the allowlist.
+func (g Grant) Authorize(scope, ip string) error {
+	if g.ExpiresAt != nil && !time.Now().Before(*g.ExpiresAt) {
+		return ErrExpiredKey
+	}
+	if !g.IPAllowed(ip) {
+		return ErrIPNotAllowed
+	}
+	if scope != "" && !g.HasScope(scope) {
+		return ErrScopeDenied
+	}
+	return nil
+}
+
+// HTTPStatus maps a validation error to the status and message a service
+// returns: 403 when the key is valid but not allowed, 401 otherwise. The
+// message is the package error's own, without any wrapping context.
+func HTTPStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrScopeDenied):
+		return http.StatusForbidden, ErrScopeDenied.Error()
+	case errors.Is(err, ErrIPNotAllowed):
+		return http.StatusForbidden, ErrIPNotAllowed.Error()
+	case errors.Is(err, ErrExpiredKey):
+		return http.StatusUnauthorized, ErrExpiredKey.Error()
+	default:
+		return http.StatusUnauthorized, ErrInvalidKey.Error()
+	}
+}
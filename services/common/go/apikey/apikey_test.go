@@ -0,0 +1,201 @@
+package apikey_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GT8004/gt8004-common/apikey"
+)
+
+func TestGenerate(t *testing.T) {
+	raw, hash, prefix, err := apikey.Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if !strings.HasPrefix(raw, apikey.Prefix) || len(raw) != len(apikey.Prefix)+64 {
+		t.Errorf("expected %s plus 64 hex chars, got %q", apikey.Prefix, raw)
+	}
+	if hash != apikey.Hash(raw) {
+		t.Errorf("expected hash of raw key, got %s", hash)
+	}
+	if prefix != raw[:16] {
+		t.Errorf("expected display prefix %s, got %s", raw[:16], prefix)
+	}
+	if other, _, _, _ := apikey.Generate(); other == raw {
+		t.Error("expected distinct keys")
+	}
+}
+
+func TestNormalizeScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []string
+		want    []string
+		wantErr bool
+	}{
+		{"empty means all", nil, apikey.AllScopes, false},
+		{"reordered and deduplicated", []string{"registry:admin", " ingest ", "ingest"}, []string{"ingest", "registry:admin"}, false},
+		{"single", []string{"analytics:read"}, []string{"analytics:read"}, false},
+		{"unknown", []string{"ingest", "admin"}, nil, true},
+		{"blank entry", []string{""}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := apikey.NormalizeScopes(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeAllowlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", nil, []string{}, false},
+		{"blank entries dropped", []string{" ", ""}, []string{}, false},
+		{"ipv4", []string{" 203.0.113.7 "}, []string{"203.0.113.7"}, false},
+		{"ipv4-mapped ipv6 unmapped", []string{"::ffff:203.0.113.7"}, []string{"203.0.113.7"}, false},
+		{"ipv6 canonical", []string{"2001:DB8:0:0::1"}, []string{"2001:db8::1"}, false},
+		{"cidr masked", []string{"10.1.2.3/8", "2001:db8::1/32"}, []string{"10.0.0.0/8", "2001:db8::/32"}, false},
+		{"invalid ip", []string{"203.0.113"}, nil, true},
+		{"hostname", []string{"example.com"}, nil, true},
+		{"invalid cidr", []string{"10.0.0.0/33"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := apikey.NormalizeAllowlist(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGrant_HasScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{"direct", []string{apikey.ScopeIngest}, apikey.ScopeIngest, true},
+		{"missing", []string{apikey.ScopeIngest}, apikey.ScopeAnalyticsRead, false},
+		{"admin implies analytics read", []string{apikey.ScopeRegistryAdmin}, apikey.ScopeAnalyticsRead, true},
+		{"admin does not imply ingest", []string{apikey.ScopeRegistryAdmin}, apikey.ScopeIngest, false},
+		{"analytics read does not imply admin", []string{apikey.ScopeAnalyticsRead}, apikey.ScopeRegistryAdmin, false},
+		{"no scopes", nil, apikey.ScopeIngest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (apikey.Grant{Scopes: tt.scopes}).HasScope(tt.scope); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGrant_IPAllowed(t *testing.T) {
+	allow := []string{"203.0.113.7", "10.0.0.0/8", "2001:db8::/32"}
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		ip        string
+		want      bool
+	}{
+		{"empty allowlist", nil, "198.51.100.1", true},
+		{"empty allowlist, garbage ip", nil, "nope", true},
+		{"exact match", allow, "203.0.113.7", true},
+		{"exact mismatch", allow, "203.0.113.8", false},
+		{"inside cidr", allow, "10.200.3.4", true},
+		{"outside cidr", allow, "11.0.0.1", false},
+		{"ipv6 in cidr", allow, "2001:db8:1::5", true},
+		{"ipv6 outside", allow, "2001:db9::1", false},
+		{"ipv4-mapped", allow, "::ffff:203.0.113.7", true},
+		{"with port", allow, "203.0.113.7:54321", true},
+		{"ipv6 with port", allow, "[2001:db8::1]:443", true},
+		{"surrounding space", allow, " 10.0.0.1 ", true},
+		{"unparseable", allow, "not-an-ip", false},
+		{"empty ip", allow, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (apikey.Grant{IPAllowlist: tt.allowlist}).IPAllowed(tt.ip); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGrant_Authorize(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		grant apikey.Grant
+		scope string
+		ip    string
+		want  error
+	}{
+		{"allowed", apikey.Grant{Scopes: []string{apikey.ScopeIngest}}, apikey.ScopeIngest, "1.2.3.4", nil},
+		{"no scope required", apikey.Grant{}, "", "1.2.3.4", nil},
+		{"not yet expired", apikey.Grant{Scopes: apikey.AllScopes, ExpiresAt: &future}, apikey.ScopeIngest, "1.2.3.4", nil},
+		{"expired", apikey.Grant{Scopes: apikey.AllScopes, ExpiresAt: &past}, apikey.ScopeIngest, "1.2.3.4", apikey.ErrExpiredKey},
+		{"expired before scope check", apikey.Grant{ExpiresAt: &past}, apikey.ScopeIngest, "1.2.3.4", apikey.ErrExpiredKey},
+		{"ip denied", apikey.Grant{Scopes: apikey.AllScopes, IPAllowlist: []string{"10.0.0.0/8"}}, apikey.ScopeIngest, "1.2.3.4", apikey.ErrIPNotAllowed},
+		{"ip denied before scope check", apikey.Grant{IPAllowlist: []string{"10.0.0.1"}}, apikey.ScopeIngest, "1.2.3.4", apikey.ErrIPNotAllowed},
+		{"scope denied", apikey.Grant{Scopes: []string{apikey.ScopeAnalyticsRead}}, apikey.ScopeIngest, "1.2.3.4", apikey.ErrScopeDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.grant.Authorize(tt.scope, tt.ip); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		msg    string
+	}{
+		{fmt.Errorf("validate api key: %w", apikey.ErrScopeDenied), http.StatusForbidden, apikey.ErrScopeDenied.Error()},
+		{apikey.ErrIPNotAllowed, http.StatusForbidden, apikey.ErrIPNotAllowed.Error()},
+		{apikey.ErrExpiredKey, http.StatusUnauthorized, apikey.ErrExpiredKey.Error()},
+		{apikey.ErrInvalidKey, http.StatusUnauthorized, apikey.ErrInvalidKey.Error()},
+		{errors.New("connection refused"), http.StatusUnauthorized, apikey.ErrInvalidKey.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			status, msg := apikey.HTTPStatus(tt.err)
+			if status != tt.status || msg != tt.msg {
+				t.Errorf("expected %d %q, got %d %q", tt.status, tt.msg, status, msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DB runs the validation queries against the shared api_keys and agents
+// tables. *pgxpool.Pool and pgx.Tx satisfy it.
+type DB interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// Key is a validated key and the agent it belongs to.
+type Key struct {
+	ID        uuid.UUID
+	AgentDBID uuid.UUID
+	AgentID   string
+	ChainID   int
+	Tier      string
+	// Role is the organization role of the wallet that issued the key
+	// ("owner" for the agent's own wallet); the key never grants more.
+	Role string
+	// CreatedBy is the wallet that issued the key, empty for keys issued at
+	// registration.
+	CreatedBy string
+	Grant
+}
+
+// Validate looks up an unrevoked key by its SHA-256 hash and checks it
+// against scope and clientIP (see Grant.Authorize). Errors wrap ErrInvalidKey,
+// ErrExpiredKey, ErrScopeDenied or ErrIPNotAllowed. The key's last use is
+// recorded at most once a minute, or when the IP changes.
+func Validate(ctx context.Context, db DB, keyHash, scope, clientIP string) (*Key, error) {
+	k := &Key{}
+	err := db.QueryRow(ctx, `
+		SELECT ak.id, ak.agent_id, a.agent_id, COALESCE(a.chain_id, 0), COALESCE(a.current_tier, 'open'),
+			ak.scopes, ak.ip_allowlist, ak.expires_at, ak.role, COALESCE(ak.created_by, '')
+		FROM api_keys ak
+		JOIN agents a ON a.id = ak.agent_id
+		WHERE ak.key_hash = $1 AND ak.revoked_at IS NULL
+	`, keyHash).Scan(&k.ID, &k.AgentDBID, &k.AgentID, &k.ChainID, &k.Tier,
+		&k.Scopes, &k.IPAllowlist, &k.ExpiresAt, &k.Role, &k.CreatedBy)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("validate api key: %w", ErrInvalidKey)
+		}
+		return nil, fmt.Errorf("validate api key: %w", err)
+	}
+	if err := k.Authorize(scope, clientIP); err != nil {
+		return nil, fmt.Errorf("validate api key: %w", err)
+	}
+
+	_, _ = db.Exec(ctx, `
+		UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute' OR last_used_ip IS DISTINCT FROM $2)
+	`, k.ID, clientIP)
+
+	return k, nil
+}
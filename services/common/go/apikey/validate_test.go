@@ -0,0 +1,100 @@
+package apikey_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/GT8004/gt8004-common/apikey"
+)
+
+// fakeRow scans vals into Scan's destinations, or returns err.
+type fakeRow struct {
+	vals []any
+	err  error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r.vals[i]))
+	}
+	return nil
+}
+
+// fakeDB answers the key lookup with row and records last-used updates.
+type fakeDB struct {
+	row     fakeRow
+	updates []any
+}
+
+func (db *fakeDB) QueryRow(_ context.Context, _ string, args ...any) pgx.Row {
+	return db.row
+}
+
+func (db *fakeDB) Exec(_ context.Context, _ string, args ...any) (pgconn.CommandTag, error) {
+	db.updates = append(db.updates, args...)
+	return pgconn.CommandTag{}, nil
+}
+
+func TestValidate(t *testing.T) {
+	keyID, agentDBID := uuid.New(), uuid.New()
+	past := time.Now().Add(-time.Minute)
+	row := func(scopes, allowlist []string, expiresAt *time.Time) fakeRow {
+		return fakeRow{vals: []any{keyID, agentDBID, "agent-1", 8453, "lite", scopes, allowlist, expiresAt, "admin", "0xabc"}}
+	}
+
+	tests := []struct {
+		name       string
+		row        fakeRow
+		scope      string
+		want       error
+		wantUpdate bool
+	}{
+		{"valid", row([]string{apikey.ScopeIngest}, nil, nil), apikey.ScopeIngest, nil, true},
+		{"implied scope", row([]string{apikey.ScopeRegistryAdmin}, nil, nil), apikey.ScopeAnalyticsRead, nil, true},
+		{"unknown key", fakeRow{err: pgx.ErrNoRows}, apikey.ScopeIngest, apikey.ErrInvalidKey, false},
+		{"expired", row(apikey.AllScopes, nil, &past), apikey.ScopeIngest, apikey.ErrExpiredKey, false},
+		{"ip not allowed", row(apikey.AllScopes, []string{"10.0.0.0/8"}, nil), apikey.ScopeIngest, apikey.ErrIPNotAllowed, false},
+		{"scope denied", row([]string{apikey.ScopeAnalyticsRead}, nil, nil), apikey.ScopeIngest, apikey.ErrScopeDenied, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeDB{row: tt.row}
+			k, err := apikey.Validate(context.Background(), db, "hash", tt.scope, "203.0.113.7")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			if got := len(db.updates) > 0; got != tt.wantUpdate {
+				t.Errorf("expected last-used update %v, got %v", tt.wantUpdate, got)
+			}
+			if err != nil {
+				return
+			}
+			if k.ID != keyID || k.AgentDBID != agentDBID || k.AgentID != "agent-1" || k.ChainID != 8453 || k.Tier != "lite" ||
+				k.Role != "admin" || k.CreatedBy != "0xabc" {
+				t.Errorf("unexpected key: %+v", k)
+			}
+			if db.updates[1] != "203.0.113.7" {
+				t.Errorf("expected last-used ip 203.0.113.7, got %v", db.updates[1])
+			}
+		})
+	}
+}
+
+func TestValidate_DatabaseError(t *testing.T) {
+	db := &fakeDB{row: fakeRow{err: errors.New("connection reset")}}
+	_, err := apikey.Validate(context.Background(), db, "hash", apikey.ScopeIngest, "203.0.113.7")
+	if err == nil || errors.Is(err, apikey.ErrInvalidKey) {
+		t.Fatalf("expected a database error distinct from ErrInvalidKey, got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+// Package clientip sets how services read a request's client IP, which API
+// key IP allowlists and per-IP rate limits rely on. Gin trusts every
+// X-Forwarded-For entry by default, so any caller could pick the IP it is
+// seen from; Configure trusts the header only as far as it was written by
+// known proxies.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CloudRun is the range Cloud Run's front end connects from. It appends the
+// address it accepted the connection from to X-Forwarded-For.
+const CloudRun = "169.254.0.0/16"
+
+// ParseProxies parses a comma-separated list of trusted proxy addresses or
+// CIDR ranges.
+func ParseProxies(s string) ([]string, error) {
+	var proxies []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", p)
+		}
+		proxies = append(proxies, p)
+	}
+	return proxies, nil
+}
+
+// Configure makes r read the client IP from X-Forwarded-For when the
+// connection comes from one of proxies, taking the nearest address that is
+// not itself a trusted proxy; otherwise the connection's own address is the
+// client IP. X-Real-IP and platform headers are ignored. proxies must have
+// been parsed by ParseProxies.
+func Configure(r *gin.Engine, proxies []string) {
+	r.ForwardedByClientIP = true
+	r.RemoteIPHeaders = []string{"X-Forwarded-For"}
+	r.TrustedPlatform = ""
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		panic("clientip: " + err.Error())
+	}
+}
@@ -0,0 +1,126 @@
+package clientip_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/GT8004/gt8004-common/apikey"
+	"github.com/GT8004/gt8004-common/clientip"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestParseProxies(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{clientip.CloudRun, 1, false},
+		{" 10.0.0.0/8 , 192.0.2.1,, ::1 ", 3, false},
+		{"10.0.0.0/33", 0, true},
+		{"proxy.internal", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := clientip.ParseProxies(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: expected error %v, got %v", tt.in, tt.wantErr, err)
+			continue
+		}
+		if len(got) != tt.want {
+			t.Errorf("%q: expected %d proxies, got %v", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"no proxies ignores forwarded header", nil, "203.0.113.7:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.7"},
+		{"untrusted peer ignores forwarded header", []string{clientip.CloudRun}, "203.0.113.7:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy appends client", []string{clientip.CloudRun}, "169.254.8.129:1234",
+			map[string]string{"X-Forwarded-For": "203.0.113.7"}, "203.0.113.7"},
+		{"forged entries before the proxy's are skipped", []string{clientip.CloudRun}, "169.254.8.129:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7"}, "203.0.113.7"},
+		{"chain of trusted proxies", []string{clientip.CloudRun, "10.8.0.0/28"}, "169.254.8.129:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7, 10.8.0.2"}, "203.0.113.7"},
+		{"X-Real-IP ignored", []string{clientip.CloudRun}, "169.254.8.129:1234",
+			map[string]string{"X-Real-IP": "198.51.100.1"}, "169.254.8.129"},
+		{"platform header ignored", []string{clientip.CloudRun}, "203.0.113.7:1234",
+			map[string]string{"X-Appengine-Remote-Addr": "198.51.100.1"}, "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			clientip.Configure(r, tt.proxies)
+			var got string
+			r.GET("/", func(c *gin.Context) { got = c.ClientIP() })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("expected client IP %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// A key allowed only from 198.51.100.0/24 cannot be used by claiming that
+// address in X-Forwarded-For.
+func TestConfigure_ForgedForwardedForRejectedByAllowlist(t *testing.T) {
+	grant := apikey.Grant{Scopes: apikey.AllScopes, IPAllowlist: []string{"198.51.100.0/24"}}
+
+	r := gin.New()
+	clientip.Configure(r, []string{clientip.CloudRun})
+	r.GET("/", func(c *gin.Context) {
+		if err := grant.Authorize(apikey.ScopeAnalyticsRead, c.ClientIP()); err != nil {
+			status, msg := apikey.HTTPStatus(err)
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    string
+		want   int
+	}{
+		{"forged header from client", "203.0.113.7:1234", "198.51.100.1", http.StatusForbidden},
+		{"forged entry behind proxy", "169.254.8.129:1234", "198.51.100.1, 203.0.113.7", http.StatusForbidden},
+		{"allowed client behind proxy", "169.254.8.129:1234", "198.51.100.1", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			req.Header.Set("X-Forwarded-For", tt.xff)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
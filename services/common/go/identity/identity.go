@@ -20,6 +20,10 @@ type AgentInfo struct {
 	EVMAddress      string  `json:"evm_address"`
 	ReputationScore float64 `json:"reputation_score,omitempty"`
 	Verified        bool    `json:"verified"`
+
+	// Registrations are the ERC-8004 agent tokens the address owns on the
+	// configured chains.
+	Registrations []Registration `json:"registrations,omitempty"`
 }
 
 // ChallengeRequest is the request to create a challenge.
@@ -38,6 +42,9 @@ type VerifyRequest struct {
 	Challenge string `json:"challenge" binding:"required"`
 	Signature string `json:"signature" binding:"required"`
 	AgentID   string `json:"agent_id" binding:"required"`
+	// ChainID selects the chain used to verify a smart-contract wallet
+	// signature; zero means the Verifier's default chain.
+	ChainID int `json:"chain_id,omitempty"`
 }
 
 // ChallengeStore abstracts challenge persistence so it can be backed by
@@ -100,6 +107,9 @@ type Verifier struct {
 
 	registryAddress string
 	registryRPC     string
+
+	smart smartWallets
+	reg   registries
 }
 
 func NewVerifier(registryAddress, registryRPC string, logger *zap.Logger) *Verifier {
@@ -110,6 +120,7 @@ func NewVerifier(registryAddress, registryRPC string, logger *zap.Logger) *Verif
 		registryAddress: registryAddress,
 		registryRPC:     registryRPC,
 	}
+	v.configureDefaultRegistry()
 	go v.cleanupLoop(ms)
 	return v
 }
@@ -118,23 +129,32 @@ func NewVerifier(registryAddress, registryRPC string, logger *zap.Logger) *Verif
 // (e.g. PostgreSQL). No cleanup goroutine is started because the store is
 // expected to handle expiry (e.g. via SQL DELETE).
 func NewVerifierWithStore(registryAddress, registryRPC string, cs ChallengeStore, logger *zap.Logger) *Verifier {
-	return &Verifier{
+	v := &Verifier{
 		store:           cs,
 		logger:          logger,
 		registryAddress: registryAddress,
 		registryRPC:     registryRPC,
 	}
+	v.configureDefaultRegistry()
+	return v
 }
 
+// challengeTTL is how long a raw challenge may be signed.
+const challengeTTL = 30 * time.Second
+
 // CreateChallenge generates a random 32-byte challenge for an agent.
 func (v *Verifier) CreateChallenge(agentID string) (*ChallengeResponse, error) {
+	return v.createChallenge(agentID, challengeTTL)
+}
+
+func (v *Verifier) createChallenge(agentID string, ttl time.Duration) (*ChallengeResponse, error) {
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
 		return nil, fmt.Errorf("generate random: %w", err)
 	}
 
 	ch := hex.EncodeToString(b)
-	expiresAt := time.Now().Add(30 * time.Second)
+	expiresAt := time.Now().Add(ttl)
 
 	// Normalize EVM address to lowercase to avoid checksum case mismatches.
 	agentID = strings.ToLower(agentID)
@@ -149,7 +169,9 @@ func (v *Verifier) CreateChallenge(agentID string) (*ChallengeResponse, error) {
 	}, nil
 }
 
-// VerifySignature verifies that the agent signed the challenge with their EVM key.
+// VerifySignature verifies that the agent signed the challenge with their EVM
+// key, or, for a smart-contract wallet, that the wallet accepts the signature
+// (see EnableSmartWallets).
 func (v *Verifier) VerifySignature(req VerifyRequest) (*AgentInfo, error) {
 	agentID, expiresAt, err := v.store.ConsumeChallenge(context.Background(), req.Challenge)
 	if err != nil {
@@ -163,68 +185,43 @@ func (v *Verifier) VerifySignature(req VerifyRequest) (*AgentInfo, error) {
 		return nil, fmt.Errorf("agent_id mismatch")
 	}
 
-	// Decode signature
-	sigBytes, err := hex.DecodeString(stripHexPrefix(req.Signature))
-	if err != nil {
-		return nil, fmt.Errorf("decode signature: %w", err)
-	}
-	if len(sigBytes) != 65 {
-		return nil, fmt.Errorf("invalid signature length: %d", len(sigBytes))
-	}
-
-	// Ethereum personal_sign: prefix the message
 	challengeBytes, err := hex.DecodeString(req.Challenge)
 	if err != nil {
 		return nil, fmt.Errorf("decode challenge: %w", err)
 	}
-	prefixed := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(challengeBytes), challengeBytes)
-	hash := crypto.Keccak256Hash([]byte(prefixed))
-
-	// Adjust V value for recovery (27/28 → 0/1)
-	if sigBytes[64] >= 27 {
-		sigBytes[64] -= 27
-	}
-
-	// Recover public key from signature
-	pubKey, err := crypto.SigToPub(hash.Bytes(), sigBytes)
-	if err != nil {
-		return nil, fmt.Errorf("recover public key: %w", err)
-	}
-
-	recoveredAddr := crypto.PubkeyToAddress(*pubKey)
 	expectedAddr := common.HexToAddress(req.AgentID)
-
-	if recoveredAddr != expectedAddr {
-		return nil, fmt.Errorf("signature does not match agent_id: recovered %s, expected %s",
-			recoveredAddr.Hex(), expectedAddr.Hex())
+	if err := v.verifySigner(req.ChainID, expectedAddr, challengeBytes, req.Signature); err != nil {
+		return nil, err
 	}
 
 	// Query ERC-8004 registry (if configured)
 	info := &AgentInfo{
 		AgentID:    req.AgentID,
-		EVMAddress: recoveredAddr.Hex(),
+		EVMAddress: expectedAddr.Hex(),
 		Verified:   true,
 	}
-
-	if v.registryAddress != "" {
-		regInfo, err := v.queryRegistry(recoveredAddr)
-		if err != nil {
-			v.logger.Warn("registry query failed, proceeding without",
-				zap.Error(err), zap.String("agent", req.AgentID))
-		} else if regInfo != nil {
-			info.ReputationScore = regInfo.ReputationScore
-		}
-	}
+	v.attachRegistrations(info, expectedAddr)
 
 	return info, nil
 }
 
-func (v *Verifier) queryRegistry(addr common.Address) (*AgentInfo, error) {
-	// TODO: Call ERC-8004 registry contract via ethclient
-	// For now, return nil (no registry data)
-	// This will be fully implemented when a real registry is deployed
-	v.logger.Debug("registry query stub", zap.String("address", addr.Hex()))
-	return nil, nil
+// recoverSigner recovers the address that produced a 65-byte ECDSA
+// signature over hash.
+func recoverSigner(hash common.Hash, sig []byte) (common.Address, error) {
+	sigBytes := make([]byte, len(sig))
+	copy(sigBytes, sig)
+
+	// Adjust V value for recovery (27/28 → 0/1)
+	if sigBytes[64] >= 27 {
+		sigBytes[64] -= 27
+	}
+
+	// Recover public key from signature
+	pubKey, err := crypto.SigToPub(hash.Bytes(), sigBytes)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("recover public key: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
 }
 
 func (v *Verifier) cleanupLoop(ms *memoryStore) {
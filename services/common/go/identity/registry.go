@@ -0,0 +1,415 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// The subset of the ERC-8004 Identity Registry (an ERC-721) and Reputation
+// Registry used to resolve an address's registrations. These are the same
+// functions the registry service's erc8004 clients call.
+const (
+	identityRegistryABI = `[
+		{"inputs":[{"name":"owner","type":"address"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
+		{"inputs":[{"name":"tokenId","type":"uint256"}],"name":"ownerOf","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"},
+		{"inputs":[{"name":"tokenId","type":"uint256"}],"name":"tokenURI","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},
+		{"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":true,"name":"tokenId","type":"uint256"}],"name":"Transfer","type":"event"}
+	]`
+	reputationRegistryABI = `[
+		{"inputs":[{"name":"agentId","type":"uint256"}],"name":"getClients","outputs":[{"name":"","type":"address[]"}],"stateMutability":"view","type":"function"},
+		{"inputs":[{"name":"agentId","type":"uint256"},{"name":"clientAddresses","type":"address[]"},{"name":"tag1","type":"string"},{"name":"tag2","type":"string"}],"name":"getSummary","outputs":[{"name":"count","type":"uint64"},{"name":"summaryValue","type":"int128"},{"name":"summaryValueDecimals","type":"uint8"}],"stateMutability":"view","type":"function"}
+	]`
+)
+
+var parsedIdentityABI, parsedReputationABI abi.ABI
+
+func init() {
+	var err error
+	if parsedIdentityABI, err = abi.JSON(strings.NewReader(identityRegistryABI)); err != nil {
+		panic(fmt.Sprintf("parse identity registry abi: %v", err))
+	}
+	if parsedReputationABI, err = abi.JSON(strings.NewReader(reputationRegistryABI)); err != nil {
+		panic(fmt.Sprintf("parse reputation registry abi: %v", err))
+	}
+}
+
+const (
+	// defaultRegistryCacheTTL is how long an address's registrations are
+	// reused before the chains are queried again.
+	defaultRegistryCacheTTL = 5 * time.Minute
+
+	// registryQueryTimeout bounds one lookup across all chains.
+	registryQueryTimeout = 20 * time.Second
+
+	// Transfer logs are scanned in chunks over this many recent blocks when
+	// the RPC rejects a full-range query.
+	transferScanBlocks    uint64 = 2000000
+	transferScanChunkSize uint64 = 49999
+)
+
+// RegistryChain is an ERC-8004 deployment consulted after a signature is
+// verified. ReputationAddr may be empty when the chain has no Reputation
+// Registry.
+type RegistryChain struct {
+	ChainID        int
+	RegistryAddr   string
+	ReputationAddr string
+	RPC            string
+}
+
+// Registration is an ERC-8004 agent token owned by a verified address.
+type Registration struct {
+	ChainID         int     `json:"chain_id"`
+	TokenID         int64   `json:"token_id"`
+	AgentURI        string  `json:"agent_uri,omitempty"`
+	ReputationScore float64 `json:"reputation_score"`
+	FeedbackCount   int64   `json:"feedback_count"`
+}
+
+type registryChain struct {
+	chainID    int
+	client     *ethclient.Client
+	registry   common.Address
+	reputation common.Address
+}
+
+type registryCacheEntry struct {
+	registrations []Registration
+	expiresAt     time.Time
+}
+
+type registries struct {
+	mu     sync.RWMutex
+	chains []*registryChain
+	ttl    time.Duration
+	cache  map[common.Address]registryCacheEntry
+}
+
+// ConfigureRegistries sets the ERC-8004 deployments queried after a
+// signature is verified, replacing the single registry given to the
+// constructor. Results are cached per address for cacheTTL (5 minutes when
+// zero).
+func (v *Verifier) ConfigureRegistries(chains []RegistryChain, cacheTTL time.Duration) error {
+	dialed := make([]*registryChain, 0, len(chains))
+	for _, ch := range chains {
+		if ch.RegistryAddr == "" || ch.RPC == "" {
+			continue
+		}
+		client, err := ethclient.Dial(ch.RPC)
+		if err != nil {
+			for _, d := range dialed {
+				d.client.Close()
+			}
+			return fmt.Errorf("dial chain %d registry rpc: %w", ch.ChainID, err)
+		}
+		rc := &registryChain{
+			chainID:  ch.ChainID,
+			client:   client,
+			registry: common.HexToAddress(ch.RegistryAddr),
+		}
+		if ch.ReputationAddr != "" {
+			rc.reputation = common.HexToAddress(ch.ReputationAddr)
+		}
+		dialed = append(dialed, rc)
+	}
+	sort.Slice(dialed, func(i, j int) bool { return dialed[i].chainID < dialed[j].chainID })
+	if cacheTTL <= 0 {
+		cacheTTL = defaultRegistryCacheTTL
+	}
+
+	v.reg.mu.Lock()
+	for _, old := range v.reg.chains {
+		old.client.Close()
+	}
+	v.reg.chains = dialed
+	v.reg.ttl = cacheTTL
+	v.reg.cache = make(map[common.Address]registryCacheEntry)
+	v.reg.mu.Unlock()
+	return nil
+}
+
+// configureDefaultRegistry sets up the constructor's single registry. Its
+// chain ID is read from the RPC on first use.
+func (v *Verifier) configureDefaultRegistry() {
+	if v.registryAddress == "" || v.registryRPC == "" {
+		return
+	}
+	if err := v.ConfigureRegistries([]RegistryChain{{
+		RegistryAddr: v.registryAddress,
+		RPC:          v.registryRPC,
+	}}, 0); err != nil {
+		v.logger.Warn("failed to connect to identity registry", zap.Error(err))
+	}
+}
+
+func (v *Verifier) hasRegistries() bool {
+	v.reg.mu.RLock()
+	defer v.reg.mu.RUnlock()
+	return len(v.reg.chains) > 0
+}
+
+// attachRegistrations adds the address's ERC-8004 registrations to info.
+// Lookup failures are logged and leave info unchanged: a valid signature is
+// not rejected because a chain RPC is down.
+func (v *Verifier) attachRegistrations(info *AgentInfo, addr common.Address) {
+	if !v.hasRegistries() {
+		return
+	}
+	regInfo, err := v.queryRegistry(addr)
+	if err != nil {
+		v.logger.Warn("registry query failed, proceeding without",
+			zap.Error(err), zap.String("agent", info.AgentID))
+		return
+	}
+	info.Registrations = regInfo.Registrations
+	info.ReputationScore = regInfo.ReputationScore
+}
+
+// queryRegistry resolves the ERC-8004 tokens addr owns on every configured
+// chain, with their agent URIs and reputation summaries. ReputationScore is
+// the feedback-weighted mean over the tokens. Results are cached.
+func (v *Verifier) queryRegistry(addr common.Address) (*AgentInfo, error) {
+	v.reg.mu.RLock()
+	chains, ttl := v.reg.chains, v.reg.ttl
+	entry, cached := v.reg.cache[addr]
+	v.reg.mu.RUnlock()
+
+	if !cached || time.Now().After(entry.expiresAt) {
+		ctx, cancel := context.WithTimeout(context.Background(), registryQueryTimeout)
+		defer cancel()
+
+		perChain := make([][]Registration, len(chains))
+		g, gctx := errgroup.WithContext(ctx)
+		for i, ch := range chains {
+			g.Go(func() error {
+				regs, err := v.queryChain(gctx, ch, addr)
+				if err != nil {
+					return fmt.Errorf("chain %d: %w", ch.chainID, err)
+				}
+				perChain[i] = regs
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+
+		entry = registryCacheEntry{registrations: []Registration{}, expiresAt: time.Now().Add(ttl)}
+		for _, regs := range perChain {
+			entry.registrations = append(entry.registrations, regs...)
+		}
+		v.reg.mu.Lock()
+		v.reg.pruneLocked()
+		v.reg.cache[addr] = entry
+		v.reg.mu.Unlock()
+	}
+
+	info := &AgentInfo{
+		AgentID:       strings.ToLower(addr.Hex()),
+		EVMAddress:    addr.Hex(),
+		Registrations: entry.registrations,
+	}
+	var weighted float64
+	var feedback int64
+	for _, r := range entry.registrations {
+		weighted += r.ReputationScore * float64(r.FeedbackCount)
+		feedback += r.FeedbackCount
+	}
+	if feedback > 0 {
+		info.ReputationScore = weighted / float64(feedback)
+	}
+	v.logger.Debug("registry query",
+		zap.String("address", addr.Hex()),
+		zap.Int("registrations", len(entry.registrations)),
+		zap.Bool("cached", cached))
+	return info, nil
+}
+
+// pruneLocked drops expired cache entries. The caller holds r.mu.
+func (r *registries) pruneLocked() {
+	now := time.Now()
+	for k, e := range r.cache {
+		if now.After(e.expiresAt) {
+			delete(r.cache, k)
+		}
+	}
+}
+
+// queryChain finds the tokens owner holds on one chain. balanceOf is checked
+// first so addresses without registrations cost a single call; otherwise
+// Transfer logs to the owner give the candidates and ownerOf confirms each.
+func (v *Verifier) queryChain(ctx context.Context, ch *registryChain, owner common.Address) ([]Registration, error) {
+	chainID, err := v.resolveChainID(ctx, ch)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance *big.Int
+	if err := callContract(ctx, ch.client, ch.registry, parsedIdentityABI, "balanceOf", &balance, owner); err != nil {
+		return nil, err
+	}
+	if balance.Sign() == 0 {
+		return nil, nil
+	}
+
+	candidates, err := transferredTo(ctx, ch, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	regs := make([]Registration, 0, balance.Int64())
+	for _, tokenID := range candidates {
+		var current common.Address
+		if err := callContract(ctx, ch.client, ch.registry, parsedIdentityABI, "ownerOf", &current, tokenID); err != nil || current != owner {
+			continue
+		}
+		reg := Registration{ChainID: chainID, TokenID: tokenID.Int64()}
+		if err := callContract(ctx, ch.client, ch.registry, parsedIdentityABI, "tokenURI", &reg.AgentURI, tokenID); err != nil {
+			v.logger.Debug("tokenURI call failed", zap.Int64("token_id", reg.TokenID), zap.Error(err))
+		}
+		reg.ReputationScore, reg.FeedbackCount = v.reputationSummary(ctx, ch, tokenID)
+		regs = append(regs, reg)
+		if int64(len(regs)) >= balance.Int64() {
+			break
+		}
+	}
+	return regs, nil
+}
+
+// resolveChainID returns the chain's ID, reading it from the RPC when the
+// chain was configured without one.
+func (v *Verifier) resolveChainID(ctx context.Context, ch *registryChain) (int, error) {
+	v.reg.mu.RLock()
+	chainID := ch.chainID
+	v.reg.mu.RUnlock()
+	if chainID != 0 {
+		return chainID, nil
+	}
+	id, err := ch.client.ChainID(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get chain id: %w", err)
+	}
+	v.reg.mu.Lock()
+	ch.chainID = int(id.Int64())
+	v.reg.mu.Unlock()
+	return int(id.Int64()), nil
+}
+
+// transferredTo returns the IDs of tokens ever transferred to owner. A
+// full-range log query is tried first; RPCs that limit block ranges are
+// scanned in chunks over recent blocks.
+func transferredTo(ctx context.Context, ch *registryChain, owner common.Address) ([]*big.Int, error) {
+	topics := [][]common.Hash{
+		{parsedIdentityABI.Events["Transfer"].ID},
+		nil,
+		{common.BytesToHash(owner.Bytes())},
+	}
+	query := ethereum.FilterQuery{Addresses: []common.Address{ch.registry}, Topics: topics}
+
+	logs, err := ch.client.FilterLogs(ctx, query)
+	if err != nil {
+		head, headErr := ch.client.BlockNumber(ctx)
+		if headErr != nil {
+			return nil, fmt.Errorf("get block number: %w", headErr)
+		}
+		var start uint64
+		if head > transferScanBlocks {
+			start = head - transferScanBlocks
+		}
+		logs = nil
+		for from := start; from <= head; from += transferScanChunkSize {
+			to := min(from+transferScanChunkSize-1, head)
+			query.FromBlock = new(big.Int).SetUint64(from)
+			query.ToBlock = new(big.Int).SetUint64(to)
+			chunk, err := ch.client.FilterLogs(ctx, query)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				continue
+			}
+			logs = append(logs, chunk...)
+		}
+	}
+
+	seen := make(map[string]bool)
+	ids := make([]*big.Int, 0, len(logs))
+	// Newest transfers first: a token received recently is the likeliest to
+	// still be held.
+	for i := len(logs) - 1; i >= 0; i-- {
+		if len(logs[i].Topics) < 4 {
+			continue
+		}
+		id := logs[i].Topics[3].Big()
+		if key := id.String(); !seen[key] {
+			seen[key] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// reputationSummary returns the token's score and feedback count from the
+// Reputation Registry, or zeros when the chain has none or the call fails.
+func (v *Verifier) reputationSummary(ctx context.Context, ch *registryChain, tokenID *big.Int) (float64, int64) {
+	if ch.reputation == (common.Address{}) {
+		return 0, 0
+	}
+	var clients []common.Address
+	if err := callContract(ctx, ch.client, ch.reputation, parsedReputationABI, "getClients", &clients, tokenID); err != nil || len(clients) == 0 {
+		return 0, 0
+	}
+
+	data, err := parsedReputationABI.Pack("getSummary", tokenID, clients, "", "")
+	if err != nil {
+		return 0, 0
+	}
+	out, err := ch.client.CallContract(ctx, ethereum.CallMsg{To: &ch.reputation, Data: data}, nil)
+	if err != nil {
+		v.logger.Debug("getSummary call failed", zap.String("token_id", tokenID.String()), zap.Error(err))
+		return 0, 0
+	}
+	values, err := parsedReputationABI.Unpack("getSummary", out)
+	if err != nil || len(values) != 3 {
+		return 0, 0
+	}
+	count := values[0].(uint64)
+	value := values[1].(*big.Int)
+	decimals := values[2].(uint8)
+
+	score, _ := new(big.Float).SetInt(value).Float64()
+	if decimals > 0 {
+		score /= math.Pow(10, float64(decimals))
+	}
+	return score, int64(count)
+}
+
+// callContract packs method with args, calls the contract and unpacks its
+// single return value into out.
+func callContract(ctx context.Context, client *ethclient.Client, to common.Address, contractABI abi.ABI, method string, out any, args ...any) error {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return fmt.Errorf("pack %s: %w", method, err)
+	}
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &to, Data: data}, nil)
+	if err != nil {
+		return fmt.Errorf("%s call failed: %w", method, err)
+	}
+	if err := contractABI.UnpackIntoInterface(out, method, result); err != nil {
+		return fmt.Errorf("unpack %s: %w", method, err)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// siweNonceTTL is how long a Sign-In With Ethereum nonce may be used. It is
+// longer than a raw challenge because the user reads the message in their
+// wallet before signing.
+const siweNonceTTL = 5 * time.Minute
+
+// siweClockSkew is how far a message's Issued At may stray outside the
+// nonce's lifetime to allow for wallet and server clock differences.
+const siweClockSkew = time.Minute
+
+const siweHeaderSuffix = " wants you to sign in with your Ethereum account:"
+
+// SIWEMessage is a parsed EIP-4361 Sign-In With Ethereum message.
+type SIWEMessage struct {
+	Domain         string     `json:"domain"`
+	Address        string     `json:"address"`
+	Statement      string     `json:"statement,omitempty"`
+	URI            string     `json:"uri"`
+	Version        string     `json:"version"`
+	ChainID        int        `json:"chain_id"`
+	Nonce          string     `json:"nonce"`
+	IssuedAt       time.Time  `json:"issued_at"`
+	ExpirationTime *time.Time `json:"expiration_time,omitempty"`
+	NotBefore      *time.Time `json:"not_before,omitempty"`
+	RequestID      string     `json:"request_id,omitempty"`
+	Resources      []string   `json:"resources,omitempty"`
+}
+
+// SIWENonceRequest is the request for a sign-in nonce.
+type SIWENonceRequest struct {
+	Address string `json:"address" binding:"required"`
+}
+
+// SIWEVerifyRequest is a signed EIP-4361 message.
+type SIWEVerifyRequest struct {
+	Message   string `json:"message" binding:"required"`
+	Signature string `json:"signature" binding:"required"`
+}
+
+// String renders the message in EIP-4361 form, which is what the wallet signs.
+func (m *SIWEMessage) String() string {
+	var b strings.Builder
+	b.WriteString(m.Domain + siweHeaderSuffix + "\n")
+	b.WriteString(m.Address + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString("URI: " + m.URI + "\n")
+	b.WriteString("Version: " + m.Version + "\n")
+	b.WriteString("Chain ID: " + strconv.Itoa(m.ChainID) + "\n")
+	b.WriteString("Nonce: " + m.Nonce + "\n")
+	b.WriteString("Issued At: " + m.IssuedAt.UTC().Format(time.RFC3339))
+	if m.ExpirationTime != nil {
+		b.WriteString("\nExpiration Time: " + m.ExpirationTime.UTC().Format(time.RFC3339))
+	}
+	if m.NotBefore != nil {
+		b.WriteString("\nNot Before: " + m.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if m.RequestID != "" {
+		b.WriteString("\nRequest ID: " + m.RequestID)
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("\nResources:")
+		for _, r := range m.Resources {
+			b.WriteString("\n- " + r)
+		}
+	}
+	return b.String()
+}
+
+// ParseSIWEMessage parses an EIP-4361 message.
+func ParseSIWEMessage(s string) (*SIWEMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if len(lines) < 8 {
+		return nil, fmt.Errorf("siwe: message too short")
+	}
+
+	m := &SIWEMessage{}
+	domain, ok := strings.CutSuffix(lines[0], siweHeaderSuffix)
+	if !ok || domain == "" {
+		return nil, fmt.Errorf("siwe: invalid header line")
+	}
+	// An optional scheme is allowed before the domain.
+	if _, rest, found := strings.Cut(domain, "://"); found {
+		domain = rest
+	}
+	m.Domain = domain
+
+	if !common.IsHexAddress(lines[1]) || !strings.HasPrefix(lines[1], "0x") {
+		return nil, fmt.Errorf("siwe: invalid address")
+	}
+	m.Address = lines[1]
+	if lines[2] != "" {
+		return nil, fmt.Errorf("siwe: expected blank line after address")
+	}
+
+	i := 3
+	if lines[i] != "" {
+		m.Statement = lines[i]
+		i++
+	}
+	if i >= len(lines) || lines[i] != "" {
+		return nil, fmt.Errorf("siwe: expected blank line before fields")
+	}
+	i++
+
+	field := func(name string, required bool) (string, error) {
+		if i < len(lines) {
+			if v, ok := strings.CutPrefix(lines[i], name+": "); ok {
+				i++
+				return v, nil
+			}
+		}
+		if required {
+			return "", fmt.Errorf("siwe: missing %s", name)
+		}
+		return "", nil
+	}
+	timeField := func(name string, required bool) (*time.Time, error) {
+		v, err := field(name, required)
+		if err != nil || v == "" {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, fmt.Errorf("siwe: invalid %s: %w", name, err)
+		}
+		return &t, nil
+	}
+
+	var err error
+	if m.URI, err = field("URI", true); err != nil {
+		return nil, err
+	}
+	if m.Version, err = field("Version", true); err != nil {
+		return nil, err
+	}
+	chainID, err := field("Chain ID", true)
+	if err != nil {
+		return nil, err
+	}
+	if m.ChainID, err = strconv.Atoi(chainID); err != nil || m.ChainID <= 0 {
+		return nil, fmt.Errorf("siwe: invalid Chain ID")
+	}
+	if m.Nonce, err = field("Nonce", true); err != nil {
+		return nil, err
+	}
+	if len(m.Nonce) < 8 {
+		return nil, fmt.Errorf("siwe: nonce too short")
+	}
+	issuedAt, err := timeField("Issued At", true)
+	if err != nil {
+		return nil, err
+	}
+	m.IssuedAt = *issuedAt
+	if m.ExpirationTime, err = timeField("Expiration Time", false); err != nil {
+		return nil, err
+	}
+	if m.NotBefore, err = timeField("Not Before", false); err != nil {
+		return nil, err
+	}
+	if m.RequestID, err = field("Request ID", false); err != nil {
+		return nil, err
+	}
+	if i < len(lines) && lines[i] == "Resources:" {
+		i++
+		for i < len(lines) && strings.HasPrefix(lines[i], "- ") {
+			m.Resources = append(m.Resources, strings.TrimPrefix(lines[i], "- "))
+			i++
+		}
+	}
+	for ; i < len(lines); i++ {
+		if lines[i] != "" {
+			return nil, fmt.Errorf("siwe: unexpected line %q", lines[i])
+		}
+	}
+	return m, nil
+}
+
+// CreateSIWENonce issues a single-use nonce for address to embed in a
+// Sign-In With Ethereum message. It is stored like a challenge, so it works
+// across instances when the Verifier has a shared ChallengeStore.
+func (v *Verifier) CreateSIWENonce(address string) (*ChallengeResponse, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid address")
+	}
+	return v.createChallenge(address, siweNonceTTL)
+}
+
+// VerifySIWE verifies a signed EIP-4361 message: the domain must be one of
+// domains (when any are given) and the URI must be an http(s) URL on that
+// domain, the message must be within its validity window, the nonce must
+// have been issued to the signing address by CreateSIWENonce and is
+// consumed, the message must not claim to be issued before the nonce, and
+// the message's address must have signed it. Smart-contract wallets are
+// verified on the message's chain.
+func (v *Verifier) VerifySIWE(req SIWEVerifyRequest, domains []string) (*SIWEMessage, *AgentInfo, error) {
+	msg, err := ParseSIWEMessage(req.Message)
+	if err != nil {
+		return nil, nil, err
+	}
+	if msg.Version != "1" {
+		return nil, nil, fmt.Errorf("siwe: unsupported version %q", msg.Version)
+	}
+	if len(domains) > 0 && !containsFold(domains, msg.Domain) {
+		return nil, nil, fmt.Errorf("siwe: domain %q not allowed", msg.Domain)
+	}
+	if u, err := url.Parse(msg.URI); err != nil || (u.Scheme != "https" && u.Scheme != "http") || !strings.EqualFold(u.Host, msg.Domain) {
+		return nil, nil, fmt.Errorf("siwe: uri %q does not match domain %q", msg.URI, msg.Domain)
+	}
+	now := time.Now()
+	if msg.ExpirationTime != nil && !now.Before(*msg.ExpirationTime) {
+		return nil, nil, fmt.Errorf("siwe: message expired")
+	}
+	if msg.NotBefore != nil && now.Before(*msg.NotBefore) {
+		return nil, nil, fmt.Errorf("siwe: message not yet valid")
+	}
+
+	address, expiresAt, err := v.store.ConsumeChallenge(context.Background(), msg.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	if now.After(expiresAt) {
+		return nil, nil, fmt.Errorf("siwe: nonce expired")
+	}
+	if address != strings.ToLower(msg.Address) {
+		return nil, nil, fmt.Errorf("siwe: nonce was issued to a different address")
+	}
+	issuedNonce := expiresAt.Add(-siweNonceTTL)
+	if msg.IssuedAt.Before(issuedNonce.Add(-siweClockSkew)) || msg.IssuedAt.After(now.Add(siweClockSkew)) {
+		return nil, nil, fmt.Errorf("siwe: issued at is outside the nonce's lifetime")
+	}
+
+	signer := common.HexToAddress(msg.Address)
+	if err := v.verifySigner(msg.ChainID, signer, []byte(req.Message), req.Signature); err != nil {
+		return nil, nil, fmt.Errorf("siwe: %w", err)
+	}
+
+	info := &AgentInfo{
+		AgentID:    strings.ToLower(msg.Address),
+		EVMAddress: signer.Hex(),
+		Verified:   true,
+	}
+	v.attachRegistrations(info, signer)
+	return msg, info, nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
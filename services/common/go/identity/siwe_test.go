@@ -0,0 +1,338 @@
+package identity_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/identity"
+)
+
+const testAddress = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+
+func siweText(lines ...string) string {
+	return strings.Join(lines, "\n")
+}
+
+func validSIWE() string {
+	return siweText(
+		"app.gt8004.xyz wants you to sign in with your Ethereum account:",
+		testAddress,
+		"",
+		"Sign in to GT8004.",
+		"",
+		"URI: https://app.gt8004.xyz/login",
+		"Version: 1",
+		"Chain ID: 8453",
+		"Nonce: 0123456789abcdef",
+		"Issued At: 2026-10-18T10:00:00Z",
+		"Expiration Time: 2026-10-18T10:05:00Z",
+		"Not Before: 2026-10-18T09:59:00Z",
+		"Request ID: req-1",
+		"Resources:",
+		"- https://app.gt8004.xyz/terms",
+		"- ipfs://bafy",
+	)
+}
+
+func TestParseSIWEMessage_Valid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		check func(t *testing.T, m *identity.SIWEMessage)
+	}{
+		{
+			name:  "all fields",
+			input: validSIWE(),
+			check: func(t *testing.T, m *identity.SIWEMessage) {
+				if m.Domain != "app.gt8004.xyz" || m.Address != testAddress || m.Statement != "Sign in to GT8004." {
+					t.Errorf("unexpected header fields: %+v", m)
+				}
+				if m.URI != "https://app.gt8004.xyz/login" || m.Version != "1" || m.ChainID != 8453 || m.Nonce != "0123456789abcdef" {
+					t.Errorf("unexpected fields: %+v", m)
+				}
+				if !m.IssuedAt.Equal(time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)) {
+					t.Errorf("expected issued at 10:00, got %v", m.IssuedAt)
+				}
+				if m.ExpirationTime == nil || m.NotBefore == nil || m.RequestID != "req-1" {
+					t.Errorf("expected optional fields, got %+v", m)
+				}
+				if len(m.Resources) != 2 || m.Resources[1] != "ipfs://bafy" {
+					t.Errorf("expected 2 resources, got %v", m.Resources)
+				}
+			},
+		},
+		{
+			name: "minimal without statement",
+			input: siweText(
+				"app.gt8004.xyz wants you to sign in with your Ethereum account:",
+				testAddress, "", "",
+				"URI: https://app.gt8004.xyz", "Version: 1", "Chain ID: 1",
+				"Nonce: abcdefgh", "Issued At: 2026-10-18T10:00:00.123Z",
+			),
+			check: func(t *testing.T, m *identity.SIWEMessage) {
+				if m.Statement != "" || m.ExpirationTime != nil || m.NotBefore != nil || m.Resources != nil {
+					t.Errorf("expected no optional fields, got %+v", m)
+				}
+				if m.IssuedAt.Nanosecond() != 123000000 {
+					t.Errorf("expected fractional seconds, got %v", m.IssuedAt)
+				}
+			},
+		},
+		{
+			name:  "crlf and trailing newline",
+			input: strings.ReplaceAll(validSIWE(), "\n", "\r\n") + "\r\n",
+			check: func(t *testing.T, m *identity.SIWEMessage) {
+				if m.Domain != "app.gt8004.xyz" || len(m.Resources) != 2 {
+					t.Errorf("unexpected parse: %+v", m)
+				}
+			},
+		},
+		{
+			name:  "scheme before domain",
+			input: strings.Replace(validSIWE(), "app.gt8004.xyz wants", "https://app.gt8004.xyz wants", 1),
+			check: func(t *testing.T, m *identity.SIWEMessage) {
+				if m.Domain != "app.gt8004.xyz" {
+					t.Errorf("expected scheme stripped, got %q", m.Domain)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := identity.ParseSIWEMessage(tt.input)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			tt.check(t, m)
+		})
+	}
+}
+
+func TestParseSIWEMessage_Invalid(t *testing.T) {
+	replace := func(old, new string) string { return strings.Replace(validSIWE(), old, new, 1) }
+	drop := func(prefix string) string {
+		var out []string
+		for _, l := range strings.Split(validSIWE(), "\n") {
+			if !strings.HasPrefix(l, prefix) {
+				out = append(out, l)
+			}
+		}
+		return strings.Join(out, "\n")
+	}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"too short", siweText("a", "b", "c")},
+		{"bad header", replace("wants you to sign in", "would like you to sign in")},
+		{"empty domain", replace("app.gt8004.xyz wants", " wants")},
+		{"address without 0x", replace(testAddress, strings.TrimPrefix(testAddress, "0x")+"00")},
+		{"bad address", replace(testAddress, "0x1234")},
+		{"no blank after address", replace(testAddress+"\n\n", testAddress+"\nx\n")},
+		{"multi-line statement", replace("Sign in to GT8004.\n", "Sign in\nto GT8004.\n")},
+		{"missing URI", drop("URI: ")},
+		{"missing version", drop("Version: ")},
+		{"missing chain id", drop("Chain ID: ")},
+		{"zero chain id", replace("Chain ID: 8453", "Chain ID: 0")},
+		{"non-numeric chain id", replace("Chain ID: 8453", "Chain ID: base")},
+		{"missing nonce", drop("Nonce: ")},
+		{"short nonce", replace("Nonce: 0123456789abcdef", "Nonce: 1234567")},
+		{"missing issued at", drop("Issued At: ")},
+		{"bad issued at", replace("Issued At: 2026-10-18T10:00:00Z", "Issued At: yesterday")},
+		{"bad expiration", replace("Expiration Time: 2026-10-18T10:05:00Z", "Expiration Time: soon")},
+		{"fields out of order", replace("Version: 1\nChain ID: 8453", "Chain ID: 8453\nVersion: 1")},
+		{"trailing garbage", validSIWE() + "\nextra"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if m, err := identity.ParseSIWEMessage(tt.input); err == nil {
+				t.Errorf("expected error, got %+v", m)
+			}
+		})
+	}
+}
+
+func TestSIWEMessage_StringRoundTrip(t *testing.T) {
+	m, err := identity.ParseSIWEMessage(validSIWE())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := m.String(); got != validSIWE() {
+		t.Errorf("expected round trip\n%s\ngot\n%s", validSIWE(), got)
+	}
+}
+
+// shiftStore is a ChallengeStore whose nonces can be aged by shift, as if
+// they had been issued that much earlier.
+type shiftStore struct {
+	mu    sync.Mutex
+	shift time.Duration
+	saved map[string]struct {
+		agentID   string
+		expiresAt time.Time
+	}
+}
+
+func newShiftStore() *shiftStore {
+	return &shiftStore{saved: make(map[string]struct {
+		agentID   string
+		expiresAt time.Time
+	})}
+}
+
+func (s *shiftStore) SaveChallenge(_ context.Context, ch, agentID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved[ch] = struct {
+		agentID   string
+		expiresAt time.Time
+	}{agentID, expiresAt.Add(-s.shift)}
+	return nil
+}
+
+func (s *shiftStore) ConsumeChallenge(_ context.Context, ch string) (string, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.saved[ch]
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("challenge not found or already used")
+	}
+	delete(s.saved, ch)
+	return v.agentID, v.expiresAt, nil
+}
+
+func signSIWE(t *testing.T, key *ecdsa.PrivateKey, msg string) string {
+	t.Helper()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(msg)), key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	sig[64] += 27
+	return "0x" + hex.EncodeToString(sig)
+}
+
+func TestVerifier_VerifySIWE(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	other, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	domains := []string{"app.gt8004.xyz"}
+
+	tests := []struct {
+		name string
+		// shift ages the nonce as if it was issued that long ago.
+		shift  time.Duration
+		mutate func(m *identity.SIWEMessage)
+		// signer signs the message; nil means key.
+		signer *ecdsa.PrivateKey
+		// reuse submits the same nonce a second time.
+		reuse   bool
+		wantErr string
+	}{
+		{name: "valid"},
+		{name: "valid http uri with path", mutate: func(m *identity.SIWEMessage) { m.URI = "http://app.gt8004.xyz/a/b?c=d" }},
+		{name: "domain case-insensitive", mutate: func(m *identity.SIWEMessage) {
+			m.Domain = "App.GT8004.xyz"
+			m.URI = "https://app.gt8004.xyz"
+		}},
+		{name: "issued at within skew of nonce", shift: 2 * time.Minute, mutate: func(m *identity.SIWEMessage) {
+			m.IssuedAt = time.Now().Add(-2*time.Minute - 30*time.Second)
+		}},
+		{name: "unsupported version", mutate: func(m *identity.SIWEMessage) { m.Version = "2" }, wantErr: "unsupported version"},
+		{name: "domain not allowed", mutate: func(m *identity.SIWEMessage) {
+			m.Domain = "evil.example"
+			m.URI = "https://evil.example"
+		}, wantErr: "not allowed"},
+		{name: "uri on another host", mutate: func(m *identity.SIWEMessage) { m.URI = "https://evil.example/login" }, wantErr: "does not match domain"},
+		{name: "uri with userinfo host trick", mutate: func(m *identity.SIWEMessage) { m.URI = "https://app.gt8004.xyz@evil.example" }, wantErr: "does not match domain"},
+		{name: "uri not http", mutate: func(m *identity.SIWEMessage) { m.URI = "ipfs://app.gt8004.xyz" }, wantErr: "does not match domain"},
+		{name: "uri without scheme", mutate: func(m *identity.SIWEMessage) { m.URI = "app.gt8004.xyz" }, wantErr: "does not match domain"},
+		{name: "expired", mutate: func(m *identity.SIWEMessage) {
+			exp := time.Now().Add(-time.Second)
+			m.ExpirationTime = &exp
+		}, wantErr: "expired"},
+		{name: "not yet valid", mutate: func(m *identity.SIWEMessage) {
+			nb := time.Now().Add(time.Hour)
+			m.NotBefore = &nb
+		}, wantErr: "not yet valid"},
+		{name: "nonce expired", shift: 6 * time.Minute, wantErr: "nonce expired"},
+		{name: "issued before nonce", shift: 3 * time.Minute, mutate: func(m *identity.SIWEMessage) {
+			m.IssuedAt = time.Now().Add(-10 * time.Minute)
+		}, wantErr: "outside the nonce's lifetime"},
+		{name: "issued in the future", mutate: func(m *identity.SIWEMessage) {
+			m.IssuedAt = time.Now().Add(5 * time.Minute)
+		}, wantErr: "outside the nonce's lifetime"},
+		{name: "signed by another key", signer: other, wantErr: "signature does not match"},
+		{name: "nonce reused", reuse: true, wantErr: "already used"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newShiftStore()
+			store.shift = tt.shift
+			v := identity.NewVerifierWithStore("", "", store, zap.NewNop())
+
+			nonce, err := v.CreateSIWENonce(address)
+			if err != nil {
+				t.Fatalf("nonce: %v", err)
+			}
+			msg := &identity.SIWEMessage{
+				Domain:    "app.gt8004.xyz",
+				Address:   address,
+				Statement: "Sign in to GT8004.",
+				URI:       "https://app.gt8004.xyz",
+				Version:   "1",
+				ChainID:   8453,
+				Nonce:     nonce.Challenge,
+				IssuedAt:  time.Now().Add(-tt.shift),
+			}
+			if tt.mutate != nil {
+				tt.mutate(msg)
+			}
+			signer := key
+			if tt.signer != nil {
+				signer = tt.signer
+			}
+			text := msg.String()
+			req := identity.SIWEVerifyRequest{Message: text, Signature: signSIWE(t, signer, text)}
+
+			if tt.reuse {
+				if _, _, err := v.VerifySIWE(req, domains); err != nil {
+					t.Fatalf("first verify: %v", err)
+				}
+			}
+			got, info, err := v.VerifySIWE(req, domains)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if got.Nonce != nonce.Challenge {
+				t.Errorf("expected nonce %s, got %s", nonce.Challenge, got.Nonce)
+			}
+			if info.AgentID != strings.ToLower(address) || info.EVMAddress != address || !info.Verified {
+				t.Errorf("unexpected agent info: %+v", info)
+			}
+		})
+	}
+}
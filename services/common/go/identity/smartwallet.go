@@ -0,0 +1,285 @@
+package identity
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.uber.org/zap"
+)
+
+// erc1271MagicValue is returned by isValidSignature(bytes32,bytes) when the
+// signature is valid (EIP-1271).
+var erc1271MagicValue = [4]byte{0x16, 0x26, 0xba, 0x7e}
+
+// erc6492MagicSuffix ends a signature from a counterfactual (not yet
+// deployed) smart account (EIP-6492).
+var erc6492MagicSuffix = common.FromHex("0x6492649264926492649264926492649264926492649264926492649264926492")
+
+const erc1271ABI = `[{"inputs":[{"name":"hash","type":"bytes32"},{"name":"signature","type":"bytes"}],"name":"isValidSignature","outputs":[{"name":"magicValue","type":"bytes4"}],"stateMutability":"view","type":"function"}]`
+
+var (
+	parsedERC1271ABI abi.ABI
+
+	// erc6492Wrapper is the ABI layout of an EIP-6492 signature before its
+	// magic suffix: (factory, factoryCalldata, originalSignature).
+	erc6492Wrapper abi.Arguments
+)
+
+func init() {
+	var err error
+	parsedERC1271ABI, err = abi.JSON(strings.NewReader(erc1271ABI))
+	if err != nil {
+		panic(fmt.Sprintf("parse erc1271 abi: %v", err))
+	}
+	addressTy, _ := abi.NewType("address", "", nil)
+	bytesTy, _ := abi.NewType("bytes", "", nil)
+	erc6492Wrapper = abi.Arguments{{Type: addressTy}, {Type: bytesTy}, {Type: bytesTy}}
+}
+
+// defaultSmartWalletTimeout bounds the RPC calls made to verify one
+// smart-wallet signature.
+const defaultSmartWalletTimeout = 10 * time.Second
+
+// SmartWalletConfig enables signature verification for smart-contract
+// wallets (Safe multisigs, ERC-4337 accounts). RPCs maps a chain ID to its
+// JSON-RPC endpoint; DefaultChainID is used when a request names no chain.
+type SmartWalletConfig struct {
+	RPCs           map[int]string
+	DefaultChainID int
+	Timeout        time.Duration
+}
+
+type smartWallets struct {
+	mu             sync.RWMutex
+	clients        map[int]*rpc.Client
+	defaultChainID int
+	timeout        time.Duration
+}
+
+// EnableSmartWallets turns on EIP-1271 and EIP-6492 verification for the
+// configured chains. Signatures that recover to the expected address with
+// ECDSA are accepted without any RPC call, so EOA logins are unaffected.
+func (v *Verifier) EnableSmartWallets(cfg SmartWalletConfig) error {
+	clients := make(map[int]*rpc.Client, len(cfg.RPCs))
+	for chainID, url := range cfg.RPCs {
+		if url == "" {
+			continue
+		}
+		client, err := rpc.DialContext(context.Background(), url)
+		if err != nil {
+			for _, c := range clients {
+				c.Close()
+			}
+			return fmt.Errorf("dial chain %d rpc: %w", chainID, err)
+		}
+		clients[chainID] = client
+	}
+	if _, ok := clients[cfg.DefaultChainID]; !ok && len(clients) > 0 {
+		return fmt.Errorf("default chain %d has no rpc configured", cfg.DefaultChainID)
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultSmartWalletTimeout
+	}
+
+	v.smart.mu.Lock()
+	for _, c := range v.smart.clients {
+		c.Close()
+	}
+	v.smart.clients = clients
+	v.smart.defaultChainID = cfg.DefaultChainID
+	v.smart.timeout = timeout
+	v.smart.mu.Unlock()
+	return nil
+}
+
+// chainClient returns the RPC client for chainID (0 means the default
+// chain), or nil when smart-wallet verification is not configured for it.
+func (v *Verifier) chainClient(chainID int) (*rpc.Client, int, time.Duration) {
+	v.smart.mu.RLock()
+	defer v.smart.mu.RUnlock()
+	if chainID == 0 {
+		chainID = v.smart.defaultChainID
+	}
+	return v.smart.clients[chainID], chainID, v.smart.timeout
+}
+
+// VerifyMessage checks that address signed message with personal_sign. Like
+// challenge signatures, smart-contract wallets are verified on chainID (0
+// means the default chain) when smart-wallet verification is enabled. The
+// caller is responsible for making the message single-use.
+func (v *Verifier) VerifyMessage(address string, chainID int, message, signature string) error {
+	if !common.IsHexAddress(address) {
+		return fmt.Errorf("invalid address")
+	}
+	return v.verifySigner(chainID, common.HexToAddress(address), []byte(message), signature)
+}
+
+// verifySigner checks that signer produced sig over msg with personal_sign
+// (EIP-191). An ECDSA signature from the address itself is tried first;
+// otherwise, when chainID has an RPC configured, the signature is checked
+// with the account contract (EIP-1271) or, for an EIP-6492 wrapped signature,
+// against the account as it would be after deployment.
+func (v *Verifier) verifySigner(chainID int, signer common.Address, msg []byte, sig string) error {
+	sigBytes, err := hex.DecodeString(stripHexPrefix(sig))
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+	hash := personalSignHash(msg)
+
+	var recovered common.Address
+	var recoverErr error
+	if len(sigBytes) == 65 {
+		recovered, recoverErr = recoverSigner(hash, sigBytes)
+		if recoverErr == nil && recovered == signer {
+			return nil
+		}
+	} else {
+		recoverErr = fmt.Errorf("invalid signature length: %d", len(sigBytes))
+	}
+
+	client, chainID, timeout := v.chainClient(chainID)
+	if client == nil {
+		if recoverErr != nil {
+			return recoverErr
+		}
+		return fmt.Errorf("signature does not match address: recovered %s, expected %s",
+			recovered.Hex(), signer.Hex())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if bytes.HasSuffix(sigBytes, erc6492MagicSuffix) {
+		err = v.verifyERC6492(ctx, client, signer, hash, sigBytes)
+	} else {
+		err = v.verifyERC1271(ctx, client, signer, hash, sigBytes)
+	}
+	if err != nil {
+		return fmt.Errorf("chain %d: %w", chainID, err)
+	}
+	v.logger.Debug("smart wallet signature verified",
+		zap.String("address", signer.Hex()), zap.Int("chain_id", chainID))
+	return nil
+}
+
+// verifyERC1271 asks a deployed account contract whether sig is valid for
+// hash. An address without code is an EOA whose ECDSA check already failed.
+func (v *Verifier) verifyERC1271(ctx context.Context, client *rpc.Client, signer common.Address, hash common.Hash, sig []byte) error {
+	var code hexutil.Bytes
+	if err := client.CallContext(ctx, &code, "eth_getCode", signer, "latest"); err != nil {
+		return fmt.Errorf("get code: %w", err)
+	}
+	if len(code) == 0 {
+		return fmt.Errorf("signature does not match address %s", signer.Hex())
+	}
+
+	valid, err := isValidSignature(ctx, client, signer, hash, sig)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("erc1271: signature rejected by %s", signer.Hex())
+	}
+	return nil
+}
+
+// verifyERC6492 unwraps an EIP-6492 signature. A deployed account is checked
+// with EIP-1271 directly; otherwise the factory deployment and the
+// isValidSignature call are simulated together with eth_simulateV1, so
+// nothing is deployed on chain.
+func (v *Verifier) verifyERC6492(ctx context.Context, client *rpc.Client, signer common.Address, hash common.Hash, sig []byte) error {
+	values, err := erc6492Wrapper.Unpack(sig[:len(sig)-len(erc6492MagicSuffix)])
+	if err != nil || len(values) != 3 {
+		return fmt.Errorf("erc6492: malformed signature")
+	}
+	factory := values[0].(common.Address)
+	factoryCalldata := values[1].([]byte)
+	innerSig := values[2].([]byte)
+
+	var code hexutil.Bytes
+	if err := client.CallContext(ctx, &code, "eth_getCode", signer, "latest"); err != nil {
+		return fmt.Errorf("get code: %w", err)
+	}
+	if len(code) > 0 {
+		valid, err := isValidSignature(ctx, client, signer, hash, innerSig)
+		if err == nil && valid {
+			return nil
+		}
+	}
+
+	callData, err := parsedERC1271ABI.Pack("isValidSignature", hash, innerSig)
+	if err != nil {
+		return fmt.Errorf("erc6492: pack isValidSignature: %w", err)
+	}
+	type simCall struct {
+		To   common.Address `json:"to"`
+		Data hexutil.Bytes  `json:"data"`
+	}
+	opts := map[string]any{
+		"blockStateCalls": []map[string]any{{
+			"calls": []simCall{
+				{To: factory, Data: factoryCalldata},
+				{To: signer, Data: callData},
+			},
+		}},
+	}
+	var result []struct {
+		Calls []struct {
+			ReturnData hexutil.Bytes  `json:"returnData"`
+			Status     hexutil.Uint64 `json:"status"`
+		} `json:"calls"`
+	}
+	if err := client.CallContext(ctx, &result, "eth_simulateV1", opts, "latest"); err != nil {
+		return fmt.Errorf("erc6492: simulate deployment: %w", err)
+	}
+	if len(result) != 1 || len(result[0].Calls) != 2 {
+		return fmt.Errorf("erc6492: unexpected simulation result")
+	}
+	check := result[0].Calls[1]
+	if check.Status != 1 || !hasMagicValue(check.ReturnData) {
+		return fmt.Errorf("erc6492: signature rejected by counterfactual account %s", signer.Hex())
+	}
+	return nil
+}
+
+// isValidSignature calls EIP-1271 isValidSignature on account. A revert is
+// reported as an invalid signature rather than an error.
+func isValidSignature(ctx context.Context, client *rpc.Client, account common.Address, hash common.Hash, sig []byte) (bool, error) {
+	data, err := parsedERC1271ABI.Pack("isValidSignature", hash, sig)
+	if err != nil {
+		return false, fmt.Errorf("erc1271: pack isValidSignature: %w", err)
+	}
+	var out hexutil.Bytes
+	err = client.CallContext(ctx, &out, "eth_call", map[string]any{
+		"to":   account,
+		"data": hexutil.Bytes(data),
+	}, "latest")
+	if err != nil {
+		if _, reverted := err.(rpc.DataError); reverted {
+			return false, nil
+		}
+		return false, fmt.Errorf("erc1271: call isValidSignature: %w", err)
+	}
+	return hasMagicValue(out), nil
+}
+
+func hasMagicValue(ret []byte) bool {
+	return len(ret) >= 4 && [4]byte(ret[:4]) == erc1271MagicValue
+}
+
+// personalSignHash is the EIP-191 hash a wallet signs for personal_sign.
+func personalSignHash(msg []byte) common.Hash {
+	prefixed := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(msg), msg)
+	return crypto.Keccak256Hash([]byte(prefixed))
+}
@@ -0,0 +1,288 @@
+package identity
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.uber.org/zap"
+)
+
+const testChainID = 8453
+
+// fakeChain serves the eth_ methods smart-wallet verification uses. Deployed
+// accounts accept the signature in valid; a counterfactual account accepts
+// it only when simulated after its factory call.
+type fakeChain struct {
+	code     map[common.Address][]byte
+	valid    []byte
+	factory  common.Address
+	calldata []byte
+
+	mu      sync.Mutex
+	methods []string
+	checked [][]byte
+}
+
+type fakeCallArgs struct {
+	To   common.Address `json:"to"`
+	Data hexutil.Bytes  `json:"data"`
+}
+
+type fakeSimResult struct {
+	Calls []fakeSimCall `json:"calls"`
+}
+
+type fakeSimCall struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	Status     hexutil.Uint64 `json:"status"`
+}
+
+func (f *fakeChain) record(method string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.methods = append(f.methods, method)
+}
+
+func (f *fakeChain) GetCode(addr common.Address, _ string) hexutil.Bytes {
+	f.record("eth_getCode")
+	return f.code[addr]
+}
+
+func (f *fakeChain) Call(args fakeCallArgs, _ string) hexutil.Bytes {
+	f.record("eth_call")
+	if len(f.code[args.To]) == 0 {
+		return nil
+	}
+	return f.isValidSignature(args.Data)
+}
+
+func (f *fakeChain) SimulateV1(opts struct {
+	BlockStateCalls []struct {
+		Calls []fakeCallArgs `json:"calls"`
+	} `json:"blockStateCalls"`
+}, _ string) []fakeSimResult {
+	f.record("eth_simulateV1")
+	calls := opts.BlockStateCalls[0].Calls
+	deployed := calls[0].To == f.factory && bytes.Equal(calls[0].Data, f.calldata)
+	ret := f.isValidSignature(calls[1].Data)
+	if !deployed {
+		ret = nil
+	}
+	return []fakeSimResult{{Calls: []fakeSimCall{{Status: 1}, {ReturnData: ret, Status: 1}}}}
+}
+
+// isValidSignature answers an isValidSignature(bytes32,bytes) call with the
+// EIP-1271 magic value when the signature is the accepted one.
+func (f *fakeChain) isValidSignature(data []byte) hexutil.Bytes {
+	method := parsedERC1271ABI.Methods["isValidSignature"]
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil
+	}
+	sig := args[1].([]byte)
+	f.mu.Lock()
+	f.checked = append(f.checked, sig)
+	f.mu.Unlock()
+
+	ret := make([]byte, 32)
+	if bytes.Equal(sig, f.valid) {
+		copy(ret, erc1271MagicValue[:])
+	}
+	return ret
+}
+
+func newSmartWalletVerifier(t *testing.T, chain *fakeChain) *Verifier {
+	t.Helper()
+	v := NewVerifierWithStore("", "", nil, zap.NewNop())
+	if chain == nil {
+		return v
+	}
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("eth", chain); err != nil {
+		t.Fatalf("register fake chain: %v", err)
+	}
+	client := rpc.DialInProc(srv)
+	t.Cleanup(func() {
+		client.Close()
+		srv.Stop()
+	})
+	v.smart.clients = map[int]*rpc.Client{testChainID: client}
+	v.smart.defaultChainID = testChainID
+	v.smart.timeout = 5 * time.Second
+	return v
+}
+
+func personalSign(t *testing.T, key *ecdsa.PrivateKey, msg []byte) []byte {
+	t.Helper()
+	sig, err := crypto.Sign(personalSignHash(msg).Bytes(), key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	sig[64] += 27
+	return sig
+}
+
+func wrapERC6492(t *testing.T, factory common.Address, calldata, sig []byte) []byte {
+	t.Helper()
+	wrapped, err := erc6492Wrapper.Pack(factory, calldata, sig)
+	if err != nil {
+		t.Fatalf("pack erc6492 wrapper: %v", err)
+	}
+	return append(wrapped, erc6492MagicSuffix...)
+}
+
+func TestVerifier_VerifySigner(t *testing.T) {
+	msg := []byte("Sign in to GT8004.")
+	owner, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	eoa := crypto.PubkeyToAddress(owner.PublicKey)
+	account := common.HexToAddress("0x00000000000000000000000000000000000a11ce")
+	factory := common.HexToAddress("0x0000000000000000000000000000000000fac701")
+	calldata := []byte{0xde, 0xad, 0xbe, 0xef}
+
+	ownerSig := personalSign(t, owner, msg)
+	otherSig := personalSign(t, other, msg)
+	// Smart accounts often sign with a non-65-byte encoding.
+	accountSig := append(append([]byte{0x01}, ownerSig...), 0x02)
+
+	tests := []struct {
+		name     string
+		chain    *fakeChain
+		signer   common.Address
+		sig      string
+		wantErr  string
+		wantRPC  []string
+		wantSigs [][]byte
+	}{
+		{
+			name:   "ecdsa without rpc",
+			signer: eoa,
+			sig:    "0x" + hex.EncodeToString(ownerSig),
+		},
+		{
+			name:   "ecdsa skips rpc",
+			chain:  &fakeChain{},
+			signer: eoa,
+			sig:    hex.EncodeToString(ownerSig),
+		},
+		{
+			name:    "not hex",
+			signer:  eoa,
+			sig:     "0xzz",
+			wantErr: "decode signature",
+		},
+		{
+			name:    "wrong length without rpc",
+			signer:  eoa,
+			sig:     "0x" + hex.EncodeToString(ownerSig[:64]),
+			wantErr: "invalid signature length: 64",
+		},
+		{
+			name:    "wrong signer without rpc",
+			signer:  eoa,
+			sig:     "0x" + hex.EncodeToString(otherSig),
+			wantErr: "signature does not match address",
+		},
+		{
+			name:    "wrong signer on eoa",
+			chain:   &fakeChain{},
+			signer:  eoa,
+			sig:     "0x" + hex.EncodeToString(otherSig),
+			wantErr: "signature does not match address",
+			wantRPC: []string{"eth_getCode"},
+		},
+		{
+			name:     "erc1271 accepted",
+			chain:    &fakeChain{code: map[common.Address][]byte{account: {0x60}}, valid: accountSig},
+			signer:   account,
+			sig:      "0x" + hex.EncodeToString(accountSig),
+			wantRPC:  []string{"eth_getCode", "eth_call"},
+			wantSigs: [][]byte{accountSig},
+		},
+		{
+			name:     "erc1271 rejected",
+			chain:    &fakeChain{code: map[common.Address][]byte{account: {0x60}}, valid: accountSig},
+			signer:   account,
+			sig:      "0x" + hex.EncodeToString(otherSig),
+			wantErr:  "chain 8453: erc1271: signature rejected",
+			wantRPC:  []string{"eth_getCode", "eth_call"},
+			wantSigs: [][]byte{otherSig},
+		},
+		{
+			name:     "erc6492 counterfactual",
+			chain:    &fakeChain{valid: accountSig, factory: factory, calldata: calldata},
+			signer:   account,
+			sig:      "0x" + hex.EncodeToString(wrapERC6492(t, factory, calldata, accountSig)),
+			wantRPC:  []string{"eth_getCode", "eth_simulateV1"},
+			wantSigs: [][]byte{accountSig},
+		},
+		{
+			name:     "erc6492 wrong factory",
+			chain:    &fakeChain{valid: accountSig, factory: factory, calldata: calldata},
+			signer:   account,
+			sig:      "0x" + hex.EncodeToString(wrapERC6492(t, account, calldata, accountSig)),
+			wantErr:  "erc6492: signature rejected by counterfactual account",
+			wantRPC:  []string{"eth_getCode", "eth_simulateV1"},
+			wantSigs: [][]byte{accountSig},
+		},
+		{
+			name:     "erc6492 already deployed",
+			chain:    &fakeChain{code: map[common.Address][]byte{account: {0x60}}, valid: accountSig},
+			signer:   account,
+			sig:      "0x" + hex.EncodeToString(wrapERC6492(t, factory, calldata, accountSig)),
+			wantRPC:  []string{"eth_getCode", "eth_call"},
+			wantSigs: [][]byte{accountSig},
+		},
+		{
+			name:    "erc6492 malformed wrapper",
+			chain:   &fakeChain{valid: accountSig},
+			signer:  account,
+			sig:     "0x" + hex.EncodeToString(append([]byte{0x01, 0x02, 0x03}, erc6492MagicSuffix...)),
+			wantErr: "erc6492: malformed signature",
+		},
+		{
+			name:    "erc6492 truncated wrapper",
+			chain:   &fakeChain{valid: accountSig},
+			signer:  account,
+			sig:     "0x" + hex.EncodeToString(append(wrapERC6492(t, factory, calldata, accountSig)[:96], erc6492MagicSuffix...)),
+			wantErr: "erc6492: malformed signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newSmartWalletVerifier(t, tt.chain)
+			err := v.verifySigner(0, tt.signer, msg, tt.sig)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if tt.chain == nil {
+				return
+			}
+			if strings.Join(tt.chain.methods, ",") != strings.Join(tt.wantRPC, ",") {
+				t.Errorf("expected rpc calls %v, got %v", tt.wantRPC, tt.chain.methods)
+			}
+			if len(tt.chain.checked) != len(tt.wantSigs) {
+				t.Fatalf("expected %d isValidSignature calls, got %d", len(tt.wantSigs), len(tt.chain.checked))
+			}
+			for i, want := range tt.wantSigs {
+				if !bytes.Equal(tt.chain.checked[i], want) {
+					t.Errorf("expected account to check %x, got %x", want, tt.chain.checked[i])
+				}
+			}
+		})
+	}
+}
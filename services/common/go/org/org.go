@@ -0,0 +1,114 @@
+// Package org defines organization roles and the permissions each grants.
+// An organization groups agents and member wallets; a wallet's role in the
+// organization decides what it may do with the organization's agents. The
+// wallet an agent is registered to is always an owner of that agent.
+package org
+
+import "github.com/GT8004/gt8004-common/apikey"
+
+// Role is a member's role in an organization.
+type Role string
+
+const (
+	RoleOwner         Role = "owner"
+	RoleAdmin         Role = "admin"
+	RoleAnalyst       Role = "analyst"
+	RoleBillingViewer Role = "billing_viewer"
+)
+
+// Roles lists every role, most privileged first.
+var Roles = []Role{RoleOwner, RoleAdmin, RoleAnalyst, RoleBillingViewer}
+
+// Permission is an action a role may be allowed.
+type Permission string
+
+const (
+	// PermAgentView allows reading an agent's registry record and tier.
+	PermAgentView Permission = "agent:view"
+	// PermAgentManage allows managing an agent: API keys, tier, gateway,
+	// ERC-8004 link and analytics settings.
+	PermAgentManage Permission = "agent:manage"
+	// PermAgentDelete allows deregistering an agent. It is never granted to
+	// API keys: deregistering needs the owner's wallet session.
+	PermAgentDelete Permission = "agent:delete"
+	// PermAnalyticsRead allows reading an agent's analytics.
+	PermAnalyticsRead Permission = "analytics:read"
+	// PermAnalyticsWrite allows saving funnels and requesting exports.
+	PermAnalyticsWrite Permission = "analytics:write"
+	// PermBillingRead allows reading revenue, costs and margins.
+	PermBillingRead Permission = "billing:read"
+	// PermMembersManage allows inviting and removing members of a lower role.
+	PermMembersManage Permission = "members:manage"
+	// PermOrgAdmin allows assigning any role, removing any agent and
+	// deleting the organization.
+	PermOrgAdmin Permission = "org:admin"
+)
+
+var permissions = map[Role][]Permission{
+	RoleOwner: {
+		PermAgentView, PermAgentManage, PermAgentDelete, PermAnalyticsRead,
+		PermAnalyticsWrite, PermBillingRead, PermMembersManage, PermOrgAdmin,
+	},
+	RoleAdmin: {
+		PermAgentView, PermAgentManage, PermAnalyticsRead, PermAnalyticsWrite,
+		PermBillingRead, PermMembersManage,
+	},
+	RoleAnalyst:       {PermAgentView, PermAnalyticsRead, PermAnalyticsWrite, PermBillingRead},
+	RoleBillingViewer: {PermAgentView, PermBillingRead},
+}
+
+// ParseRole returns the role named s.
+func ParseRole(s string) (Role, bool) {
+	r := Role(s)
+	_, ok := permissions[r]
+	return r, ok
+}
+
+// Can reports whether the role grants p. The zero Role grants nothing.
+func (r Role) Can(p Permission) bool {
+	for _, granted := range permissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Role) rank() int {
+	for i, role := range Roles {
+		if role == r {
+			return len(Roles) - i
+		}
+	}
+	return 0
+}
+
+// Outranks reports whether r is more privileged than other.
+func (r Role) Outranks(other Role) bool {
+	return r.rank() > other.rank()
+}
+
+// CanAssign reports whether a member with role r may invite, promote to or
+// remove a member with role target. Owners may assign any role; members who
+// manage members may only assign roles below their own.
+func (r Role) CanAssign(target Role) bool {
+	if r.Can(PermOrgAdmin) {
+		return true
+	}
+	return r.Can(PermMembersManage) && target.rank() < r.rank()
+}
+
+// KeyScopes returns the API key scopes a wallet with this role may be
+// issued for an agent. Roles without API access get none. A key is bound to
+// the role that issued it: its registry:admin scope only allows what that
+// role may do.
+func (r Role) KeyScopes() []string {
+	switch r {
+	case RoleOwner, RoleAdmin:
+		return append([]string(nil), apikey.AllScopes...)
+	case RoleAnalyst:
+		return []string{apikey.ScopeAnalyticsRead}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often keys whose limit has fully refilled are
+// dropped from a Memory limiter.
+const sweepInterval = time.Minute
+
+// Memory is a process-local GCRA limiter.
+type Memory struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemory creates an empty process-local limiter.
+func NewMemory() *Memory {
+	return &Memory{tats: make(map[string]time.Time), lastSweep: time.Now()}
+}
+
+// Allow counts one request for key against limit. It never fails.
+func (m *Memory) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastSweep) > sweepInterval {
+		for k, tat := range m.tats {
+			if !tat.After(now) {
+				delete(m.tats, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	tat, ok := m.tats[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+	emission := limit.emission()
+	newTAT := tat.Add(emission)
+	if allowAt := newTAT.Add(-limit.Period); now.Before(allowAt) {
+		return Result{
+			Limit:      limit,
+			ResetAfter: tat.Sub(now),
+			RetryAfter: allowAt.Sub(now),
+		}, nil
+	}
+
+	m.tats[key] = newTAT
+	return Result{
+		Allowed:    true,
+		Limit:      limit,
+		Remaining:  int((limit.Period - newTAT.Sub(now)) / emission),
+		ResetAfter: newTAT.Sub(now),
+	}, nil
+}
@@ -0,0 +1,88 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GT8004/gt8004-common/ratelimit"
+)
+
+func TestMemory_Allow(t *testing.T) {
+	ctx := context.Background()
+	m := ratelimit.NewMemory()
+	limit := ratelimit.PerMinute(3)
+
+	for want := 2; want >= 0; want-- {
+		res, err := m.Allow(ctx, "a", limit)
+		if err != nil {
+			t.Fatalf("allow: %v", err)
+		}
+		if !res.Allowed {
+			t.Fatalf("expected request to be allowed with %d remaining", want)
+		}
+		if res.Remaining != want {
+			t.Errorf("expected %d remaining, got %d", want, res.Remaining)
+		}
+		if res.RetryAfter != 0 {
+			t.Errorf("expected no retry delay, got %v", res.RetryAfter)
+		}
+	}
+
+	res, _ := m.Allow(ctx, "a", limit)
+	if res.Allowed {
+		t.Fatal("expected fourth request to be limited")
+	}
+	if res.Remaining != 0 {
+		t.Errorf("expected 0 remaining, got %d", res.Remaining)
+	}
+	// One request refills every 20s.
+	if res.RetryAfter <= 19*time.Second || res.RetryAfter > 20*time.Second {
+		t.Errorf("expected retry after about 20s, got %v", res.RetryAfter)
+	}
+	if res.ResetAfter <= 59*time.Second || res.ResetAfter > time.Minute {
+		t.Errorf("expected reset after about 1m, got %v", res.ResetAfter)
+	}
+
+	if res, _ := m.Allow(ctx, "b", limit); !res.Allowed {
+		t.Error("expected a different key to have its own budget")
+	}
+}
+
+func TestMemory_Refill(t *testing.T) {
+	ctx := context.Background()
+	m := ratelimit.NewMemory()
+	limit := ratelimit.Limit{Requests: 2, Period: 100 * time.Millisecond}
+
+	m.Allow(ctx, "k", limit)
+	m.Allow(ctx, "k", limit)
+	res, _ := m.Allow(ctx, "k", limit)
+	if res.Allowed {
+		t.Fatal("expected burst to be exhausted")
+	}
+
+	time.Sleep(res.RetryAfter + 5*time.Millisecond)
+	if res, _ := m.Allow(ctx, "k", limit); !res.Allowed {
+		t.Error("expected one request to refill")
+	}
+	if res, _ := m.Allow(ctx, "k", limit); res.Allowed {
+		t.Error("expected only one request to refill")
+	}
+}
+
+func TestMemory_FasterThanMicrosecond(t *testing.T) {
+	m := ratelimit.NewMemory()
+	// 1e9 per minute is capped at one request per microsecond.
+	limit := ratelimit.Limit{Requests: 1_000_000_000, Period: time.Minute}
+
+	res, err := m.Allow(context.Background(), "k", limit)
+	if err != nil {
+		t.Fatalf("allow: %v", err)
+	}
+	if !res.Allowed {
+		t.Fatal("expected request to be allowed")
+	}
+	if want := 60_000_000 - 1; res.Remaining != want {
+		t.Errorf("expected %d remaining, got %d", want, res.Remaining)
+	}
+}
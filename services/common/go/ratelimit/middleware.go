@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/GT8004/gt8004-common/apikey"
+	"github.com/GT8004/gt8004-common/session"
+)
+
+// Context keys read by ByAgent and tier lookups. Auth middleware sets them
+// once the caller is known.
+const (
+	ContextKeyAgentID = "agent_id"
+	ContextKeyTier    = "agent_tier"
+)
+
+// Response headers (draft-ietf-httpapi-ratelimit-headers).
+const (
+	HeaderLimit     = "RateLimit-Limit"
+	HeaderRemaining = "RateLimit-Remaining"
+	HeaderReset     = "RateLimit-Reset"
+	HeaderPolicy    = "RateLimit-Policy"
+)
+
+// KeyFunc names the bucket a request is counted in. An empty key leaves the
+// request unlimited by the policy.
+type KeyFunc func(c *gin.Context) string
+
+// ByIP counts requests per client IP. The engine must read client IPs
+// through clientip.Configure, or any caller can pick its IP.
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByAPIKey counts requests per bearer API key, before the key is validated.
+// Only the key's hash is used. Session tokens are not API keys. A client can
+// present any number of made-up keys, so pair it with a ByIP policy.
+func ByAPIKey(c *gin.Context) string {
+	authHeader := c.GetHeader("X-Forwarded-Authorization")
+	if authHeader == "" {
+		authHeader = c.GetHeader("Authorization")
+	}
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" || session.IsToken(parts[1]) {
+		return ""
+	}
+	return "key:" + apikey.Hash(parts[1])
+}
+
+// ByWallet counts requests per session wallet.
+func ByWallet(c *gin.Context) string {
+	if claims, ok := session.FromContext(c); ok {
+		return "wallet:" + claims.Address
+	}
+	return ""
+}
+
+// ByAgent counts requests per authenticated agent. It must run after the
+// service's auth middleware.
+func ByAgent(c *gin.Context) string {
+	if id := c.GetString(ContextKeyAgentID); id != "" {
+		return "agent:" + id
+	}
+	return ""
+}
+
+// First uses the first of fns that returns a key.
+func First(fns ...KeyFunc) KeyFunc {
+	return func(c *gin.Context) string {
+		for _, fn := range fns {
+			if key := fn(c); key != "" {
+				return key
+			}
+		}
+		return ""
+	}
+}
+
+// Policy is one limit applied by Middleware.
+type Policy struct {
+	// Name namespaces the policy's buckets, e.g. "ingest:agent".
+	Name string
+	Key  KeyFunc
+	// Limit applies when the request's tier has no entry in Tiers.
+	Limit Limit
+	// Tiers overrides Limit per agent tier, read from ContextKeyTier.
+	Tiers map[string]Limit
+}
+
+func (p Policy) limitFor(c *gin.Context) Limit {
+	if l, ok := p.Tiers[c.GetString(ContextKeyTier)]; ok {
+		return l
+	}
+	return p.Limit
+}
+
+// Middleware enforces p, answering 429 with Retry-After once the request's
+// bucket is exhausted. Every limited response carries RateLimit-* headers
+// for the policy closest to its limit.
+func (l *Limiter) Middleware(p Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := p.limitFor(c)
+		if l == nil || !limit.Enabled() {
+			c.Next()
+			return
+		}
+		key := p.Key(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		res := l.Allow(c.Request.Context(), p.Name+":"+key, limit)
+		setHeaders(c, res)
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// setHeaders writes res unless an earlier policy left fewer requests.
+func setHeaders(c *gin.Context, res Result) {
+	if prev := c.Writer.Header().Get(HeaderRemaining); prev != "" {
+		if n, err := strconv.Atoi(prev); err == nil && n <= res.Remaining {
+			return
+		}
+	}
+	c.Header(HeaderLimit, strconv.Itoa(res.Limit.Requests))
+	c.Header(HeaderRemaining, strconv.Itoa(res.Remaining))
+	c.Header(HeaderReset, strconv.Itoa(ceilSeconds(res.ResetAfter)))
+	c.Header(HeaderPolicy, strconv.Itoa(res.Limit.Requests)+";w="+strconv.Itoa(ceilSeconds(res.Limit.Period)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,264 @@
+package ratelimit_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/apikey"
+	"github.com/GT8004/gt8004-common/clientip"
+	"github.com/GT8004/gt8004-common/ratelimit"
+	"github.com/GT8004/gt8004-common/session"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newLimiter(t *testing.T) *ratelimit.Limiter {
+	t.Helper()
+	l, err := ratelimit.New("", zap.NewNop())
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	return l
+}
+
+func serve(r *gin.Engine, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.7:1234"
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	r := gin.New()
+	r.Use(newLimiter(t).Middleware(ratelimit.Policy{Name: "test", Key: ratelimit.ByIP, Limit: ratelimit.PerMinute(2)}))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, remaining := range []string{"1", "0"} {
+		w := serve(r, "", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		if got := w.Header().Get(ratelimit.HeaderRemaining); got != remaining {
+			t.Errorf("expected %s remaining, got %s", remaining, got)
+		}
+		if got := w.Header().Get(ratelimit.HeaderLimit); got != "2" {
+			t.Errorf("expected limit 2, got %s", got)
+		}
+		if got := w.Header().Get(ratelimit.HeaderPolicy); got != "2;w=60" {
+			t.Errorf("expected policy 2;w=60, got %s", got)
+		}
+	}
+
+	w := serve(r, "", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("expected Retry-After 30, got %s", got)
+	}
+	if !strings.Contains(w.Body.String(), "rate limit exceeded") {
+		t.Errorf("expected rate limit error, got %s", w.Body.String())
+	}
+}
+
+func TestLimiter_MiddlewareByIPIgnoresForgedForwardedFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		remote string
+		xff    func(i int) string
+	}{
+		{"direct client", "203.0.113.7:1234", func(i int) string {
+			return fmt.Sprintf("198.51.100.%d", i)
+		}},
+		{"behind trusted proxy", "169.254.8.129:1234", func(i int) string {
+			return fmt.Sprintf("198.51.100.%d, 203.0.113.7", i)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			clientip.Configure(r, []string{clientip.CloudRun})
+			r.Use(newLimiter(t).Middleware(ratelimit.Policy{Name: "test", Key: ratelimit.ByIP, Limit: ratelimit.PerMinute(2)}))
+			r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			codes := make([]int, 3)
+			for i := range codes {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = tt.remote
+				req.Header.Set("X-Forwarded-For", tt.xff(i))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				codes[i] = w.Code
+			}
+			if codes[2] != http.StatusTooManyRequests {
+				t.Errorf("expected rotating X-Forwarded-For to share one bucket, got %v", codes)
+			}
+		})
+	}
+}
+
+func TestLimiter_MiddlewareUnlimited(t *testing.T) {
+	var nilLimiter *ratelimit.Limiter
+	tests := []struct {
+		name    string
+		limiter *ratelimit.Limiter
+		policy  ratelimit.Policy
+	}{
+		{"nil limiter", nilLimiter, ratelimit.Policy{Name: "test", Key: ratelimit.ByIP, Limit: ratelimit.PerMinute(1)}},
+		{"disabled limit", newLimiter(t), ratelimit.Policy{Name: "test", Key: ratelimit.ByIP}},
+		{"empty key", newLimiter(t), ratelimit.Policy{Name: "test", Key: ratelimit.ByAgent, Limit: ratelimit.PerMinute(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(tt.limiter.Middleware(tt.policy))
+			r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			for i := 0; i < 3; i++ {
+				w := serve(r, "", "")
+				if w.Code != http.StatusOK {
+					t.Fatalf("expected 200, got %d", w.Code)
+				}
+				if got := w.Header().Get(ratelimit.HeaderLimit); got != "" {
+					t.Errorf("expected no rate limit headers, got limit %s", got)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiter_MiddlewareTiers(t *testing.T) {
+	l := newLimiter(t)
+	policy := ratelimit.Policy{
+		Name:  "test",
+		Key:   ratelimit.ByAgent,
+		Limit: ratelimit.PerMinute(1),
+		Tiers: map[string]ratelimit.Limit{"lite": ratelimit.PerMinute(5)},
+	}
+
+	tests := []struct {
+		tier string
+		want string
+	}{
+		{"", "1"},
+		{"open", "1"},
+		{"lite", "5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tier, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set(ratelimit.ContextKeyAgentID, "agent-"+tt.tier)
+				c.Set(ratelimit.ContextKeyTier, tt.tier)
+			}, l.Middleware(policy))
+			r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			if got := serve(r, "", "").Header().Get(ratelimit.HeaderLimit); got != tt.want {
+				t.Errorf("expected limit %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLimiter_MiddlewareHeadersKeepTightestPolicy(t *testing.T) {
+	l := newLimiter(t)
+	r := gin.New()
+	r.Use(
+		l.Middleware(ratelimit.Policy{Name: "ip", Key: ratelimit.ByIP, Limit: ratelimit.PerMinute(2)}),
+		l.Middleware(ratelimit.Policy{Name: "wide", Key: ratelimit.ByIP, Limit: ratelimit.PerMinute(100)}),
+	)
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := serve(r, "", "")
+	if got := w.Header().Get(ratelimit.HeaderLimit); got != "2" {
+		t.Errorf("expected limit 2, got %s", got)
+	}
+	if got := w.Header().Get(ratelimit.HeaderRemaining); got != "1" {
+		t.Errorf("expected 1 remaining, got %s", got)
+	}
+}
+
+func TestByAPIKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   string
+	}{
+		{"bearer key", "Authorization", "Bearer gt8_abc", "key:" + apikey.Hash("gt8_abc")},
+		{"lowercase scheme", "Authorization", "bearer gt8_abc", "key:" + apikey.Hash("gt8_abc")},
+		{"forwarded header", "X-Forwarded-Authorization", "Bearer gt8_abc", "key:" + apikey.Hash("gt8_abc")},
+		{"session token", "Authorization", "Bearer " + session.TokenPrefix + "abc", ""},
+		{"basic auth", "Authorization", "Basic dXNlcjpwYXNz", ""},
+		{"empty bearer", "Authorization", "Bearer ", ""},
+		{"missing", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				c.Request.Header.Set(tt.header, tt.value)
+			}
+			if got := ratelimit.ByAPIKey(c); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFirst(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.RemoteAddr = "203.0.113.7:1234"
+
+	key := ratelimit.First(ratelimit.ByAgent, ratelimit.ByWallet, ratelimit.ByIP)
+	if got := key(c); got != "ip:203.0.113.7" {
+		t.Errorf("expected ip key, got %q", got)
+	}
+
+	c.Set(ratelimit.ContextKeyAgentID, "a1")
+	if got := key(c); got != "agent:a1" {
+		t.Errorf("expected agent key, got %q", got)
+	}
+
+	if got := ratelimit.First()(c); got != "" {
+		t.Errorf("expected empty key, got %q", got)
+	}
+}
+
+func TestLimiter_MiddlewareRetryAfterRoundsUp(t *testing.T) {
+	r := gin.New()
+	r.Use(newLimiter(t).Middleware(ratelimit.Policy{
+		Name:  "test",
+		Key:   ratelimit.ByIP,
+		Limit: ratelimit.Limit{Requests: 1, Period: 1500 * time.Millisecond},
+	}))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve(r, "", "")
+	w := serve(r, "", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After 2, got %s", got)
+	}
+}
@@ -0,0 +1,164 @@
+// Package ratelimit limits request rates across service instances. Limits
+// are enforced with GCRA (the generic cell rate algorithm): each key holds
+// the theoretical arrival time of its next request, so a limit of N
+// requests per period allows bursts of N and refills at one request every
+// period/N. State lives in Redis when configured, so every instance shares
+// one budget per key; a process-local limiter takes over when Redis is not
+// configured or unreachable.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Limit allows Requests requests per Period. A zero limit disables
+// limiting.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// PerMinute returns a limit of n requests per minute.
+func PerMinute(n int) Limit {
+	return Limit{Requests: n, Period: time.Minute}
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// minEmission is the shortest refill interval. Redis keeps state in
+// microseconds, so faster limits are capped at one request per microsecond.
+const minEmission = time.Microsecond
+
+// emission is the interval at which the limit refills one request, never
+// shorter than minEmission.
+func (l Limit) emission() time.Duration {
+	if e := l.Period / time.Duration(l.Requests); e >= minEmission {
+		return e
+	}
+	return minEmission
+}
+
+// Result is the outcome of one request against a limit.
+type Result struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// ResetAfter is how long until the full limit is available again.
+	ResetAfter time.Duration
+	// RetryAfter is how long until the next request is allowed; zero when
+	// this one was.
+	RetryAfter time.Duration
+}
+
+// Backend counts requests against a limit.
+type Backend interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+const (
+	// redisTimeout bounds one Redis round trip so a slow server does not
+	// stall requests.
+	redisTimeout = 250 * time.Millisecond
+	// redisRetryAfter is how long requests are counted locally after Redis
+	// fails before it is tried again.
+	redisRetryAfter = 10 * time.Second
+)
+
+// Limiter counts requests in Redis and falls back to a process-local
+// limiter when Redis is not configured or fails. A nil *Limiter allows
+// everything.
+type Limiter struct {
+	redis  *Redis
+	local  *Memory
+	client *redis.Client
+	logger *zap.Logger
+	// downUntil is when Redis is next tried after a failure (Unix nanos).
+	downUntil atomic.Int64
+}
+
+// New creates a limiter backed by the Redis server at redisURL, or a
+// process-local one when redisURL is empty. An unreachable server is not
+// an error: requests are counted locally until it answers.
+func New(redisURL string, logger *zap.Logger) (*Limiter, error) {
+	l := &Limiter{local: NewMemory(), logger: logger}
+	if redisURL == "" {
+		logger.Info("redis not configured, rate limits are per instance")
+		return l, nil
+	}
+
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	l.client = redis.NewClient(opts)
+	l.redis = NewRedis(l.client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.client.Ping(ctx).Err(); err != nil {
+		logger.Warn("redis ping failed, rate limits are per instance until it recovers", zap.Error(err))
+	}
+	return l, nil
+}
+
+// Allow counts one request for key against limit. Redis errors are logged
+// and the request is counted locally instead.
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit) Result {
+	if l == nil || !limit.Enabled() {
+		return Result{Allowed: true, Limit: limit, Remaining: limit.Requests}
+	}
+	if l.redis != nil && time.Now().UnixNano() >= l.downUntil.Load() {
+		rctx, cancel := context.WithTimeout(ctx, redisTimeout)
+		res, err := l.redis.Allow(rctx, key, limit)
+		cancel()
+		if err == nil {
+			return res
+		}
+		now := time.Now()
+		if last := l.downUntil.Load(); now.UnixNano() >= last && l.downUntil.CompareAndSwap(last, now.Add(redisRetryAfter).UnixNano()) {
+			l.logger.Warn("redis rate limit failed, counting locally",
+				zap.Duration("retry_after", redisRetryAfter), zap.Error(err))
+		}
+	}
+	res, _ := l.local.Allow(ctx, key, limit)
+	return res
+}
+
+// Close releases the Redis connection.
+func (l *Limiter) Close() error {
+	if l == nil || l.client == nil {
+		return nil
+	}
+	return l.client.Close()
+}
+
+// ParseTiers parses per-minute limits per tier from "tier=requests" pairs
+// separated by commas, e.g. "open=300,lite=1200". Limits above one request
+// per microsecond are rejected.
+func ParseTiers(s string) (map[string]Limit, error) {
+	tiers := make(map[string]Limit)
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		tier, n, ok := strings.Cut(pair, "=")
+		requests, err := strconv.Atoi(strings.TrimSpace(n))
+		if !ok || err != nil || requests < 0 || strings.TrimSpace(tier) == "" ||
+			requests > int(time.Minute/minEmission) {
+			return nil, fmt.Errorf("invalid tier limit %q", pair)
+		}
+		tiers[strings.TrimSpace(tier)] = PerMinute(requests)
+	}
+	return tiers, nil
+}
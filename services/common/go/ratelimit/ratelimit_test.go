@@ -0,0 +1,117 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/ratelimit"
+)
+
+func TestParseTiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    map[string]ratelimit.Limit
+		wantErr bool
+	}{
+		{"empty", "", map[string]ratelimit.Limit{}, false},
+		{"single", "open=300", map[string]ratelimit.Limit{"open": ratelimit.PerMinute(300)}, false},
+		{"several with spaces", " open = 300 , lite=1200,, pro=0 ", map[string]ratelimit.Limit{
+			"open": ratelimit.PerMinute(300),
+			"lite": ratelimit.PerMinute(1200),
+			"pro":  ratelimit.PerMinute(0),
+		}, false},
+		{"one per microsecond", "max=60000000", map[string]ratelimit.Limit{"max": ratelimit.PerMinute(60_000_000)}, false},
+		{"above one per microsecond", "max=60000001", nil, true},
+		{"missing equals", "open", nil, true},
+		{"missing tier", "=300", nil, true},
+		{"not a number", "open=lots", nil, true},
+		{"negative", "open=-1", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ratelimit.ParseTiers(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d tiers, got %d", len(tt.want), len(got))
+			}
+			for tier, limit := range tt.want {
+				if got[tier] != limit {
+					t.Errorf("expected %s=%v, got %v", tier, limit, got[tier])
+				}
+			}
+		})
+	}
+}
+
+func TestLimit_Enabled(t *testing.T) {
+	tests := []struct {
+		limit ratelimit.Limit
+		want  bool
+	}{
+		{ratelimit.Limit{}, false},
+		{ratelimit.PerMinute(0), false},
+		{ratelimit.Limit{Requests: 10}, false},
+		{ratelimit.PerMinute(10), true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.limit.Enabled(); got != tt.want {
+			t.Errorf("expected Enabled()=%v for %+v, got %v", tt.want, tt.limit, got)
+		}
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	ctx := context.Background()
+
+	var nilLimiter *ratelimit.Limiter
+	if res := nilLimiter.Allow(ctx, "k", ratelimit.PerMinute(1)); !res.Allowed {
+		t.Error("expected nil limiter to allow")
+	}
+
+	l, err := ratelimit.New("", zap.NewNop())
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 3; i++ {
+		if res := l.Allow(ctx, "k", ratelimit.Limit{}); !res.Allowed {
+			t.Fatal("expected disabled limit to allow")
+		}
+	}
+	if res := l.Allow(ctx, "k", ratelimit.PerMinute(1)); !res.Allowed {
+		t.Error("expected first request to be allowed")
+	}
+	if res := l.Allow(ctx, "k", ratelimit.PerMinute(1)); res.Allowed {
+		t.Error("expected second request to be limited")
+	}
+}
+
+func TestLimiter_RedisUnavailable(t *testing.T) {
+	if _, err := ratelimit.New("not a url", zap.NewNop()); err == nil {
+		t.Error("expected error for invalid redis url")
+	}
+
+	// An unreachable server falls back to counting locally.
+	l, err := ratelimit.New("redis://127.0.0.1:1?max_retries=-1", zap.NewNop())
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer l.Close()
+
+	limit := ratelimit.Limit{Requests: 1, Period: time.Hour}
+	if res := l.Allow(context.Background(), "k", limit); !res.Allowed {
+		t.Error("expected first request to be allowed")
+	}
+	if res := l.Allow(context.Background(), "k", limit); res.Allowed {
+		t.Error("expected second request to be limited")
+	}
+}
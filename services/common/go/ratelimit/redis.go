@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces rate limit state in Redis.
+const keyPrefix = "ratelimit:"
+
+// gcraScript applies one request to the GCRA state at KEYS[1]. ARGV holds
+// the emission interval and period in microseconds. The server clock is
+// used so instances with skewed clocks agree. Returns allowed (0/1),
+// remaining, and the reset and retry delays in microseconds.
+var gcraScript = redis.NewScript(`
+local emission = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if not tat or tat < now then
+  tat = now
+end
+
+local new_tat = tat + emission
+local allow_at = new_tat - period
+if now < allow_at then
+  return {0, 0, tat - now, allow_at - now}
+end
+
+redis.call('SET', KEYS[1], new_tat, 'PX', math.ceil((new_tat - now) / 1000))
+return {1, math.floor((period - (new_tat - now)) / emission), new_tat - now, 0}
+`)
+
+// Redis is a GCRA limiter whose state is shared through Redis.
+type Redis struct {
+	client redis.Scripter
+}
+
+// NewRedis creates a limiter that keeps its state in client.
+func NewRedis(client redis.Scripter) *Redis {
+	return &Redis{client: client}
+}
+
+// Allow counts one request for key against limit.
+func (r *Redis) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	vals, err := gcraScript.Run(ctx, r.client, []string{keyPrefix + key},
+		limit.emission().Microseconds(), limit.Period.Microseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("run gcra script: %w", err)
+	}
+	if len(vals) != 4 {
+		return Result{}, fmt.Errorf("run gcra script: unexpected reply %v", vals)
+	}
+	return Result{
+		Allowed:    vals[0] == 1,
+		Limit:      limit,
+		Remaining:  int(vals[1]),
+		ResetAfter: time.Duration(vals[2]) * time.Microsecond,
+		RetryAfter: time.Duration(vals[3]) * time.Microsecond,
+	}, nil
+}
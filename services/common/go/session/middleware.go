@@ -0,0 +1,114 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKeyClaims holds the verified *Claims of a session-authenticated
+// request.
+const ContextKeyClaims = "session_claims"
+
+// LegacyWalletHeader is the unauthenticated wallet header that session
+// tokens replace.
+const LegacyWalletHeader = "X-Wallet-Address"
+
+// RevocationChecker reports whether a session has been revoked (logged out,
+// or its refresh token expired or was revoked by the owner).
+type RevocationChecker interface {
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+}
+
+// Middleware verifies a session token in the bearer credential (preferring
+// X-Forwarded-Authorization set by the API Gateway) and stores its claims on
+// the context. Requests without a session token pass through untouched, so
+// API keys are still handled by the service's own middleware; requests with
+// an invalid, expired or revoked token are rejected. revocations may be nil
+// where no session store is reachable (the gateway), leaving revocation to
+// the backing service.
+func Middleware(signer *Signer, revocations RevocationChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerCredential(c)
+		if !IsToken(token) {
+			c.Next()
+			return
+		}
+
+		claims, err := signer.Verify(token)
+		if err != nil {
+			msg := "invalid session token"
+			if errors.Is(err, ErrExpiredToken) {
+				msg = "session expired"
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": msg})
+			return
+		}
+		if revocations != nil {
+			revoked, err := revocations.IsSessionRevoked(c.Request.Context(), claims.SessionID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "session check failed"})
+				return
+			}
+			if revoked {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+				return
+			}
+		}
+
+		c.Set(ContextKeyClaims, claims)
+		c.Next()
+	}
+}
+
+// FromContext returns the claims of a session-authenticated request.
+func FromContext(c *gin.Context) (*Claims, bool) {
+	v, ok := c.Get(ContextKeyClaims)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := v.(*Claims)
+	return claims, ok
+}
+
+// WalletAddress returns the lowercase wallet address the request is
+// authenticated as: the session's address, or, until legacyUntil, the raw
+// X-Wallet-Address header. Legacy responses carry Deprecation and Sunset
+// headers; after legacyUntil the header is ignored.
+func WalletAddress(c *gin.Context, legacyUntil time.Time) string {
+	if claims, ok := FromContext(c); ok {
+		return claims.Address
+	}
+	if !LegacyHeaderAllowed(legacyUntil) {
+		return ""
+	}
+	addr := strings.ToLower(c.GetHeader(LegacyWalletHeader))
+	if !strings.HasPrefix(addr, "0x") || len(addr) != 42 {
+		return ""
+	}
+	c.Header("Deprecation", "true")
+	c.Header("Sunset", legacyUntil.UTC().Format(http.TimeFormat))
+	return addr
+}
+
+// LegacyHeaderAllowed reports whether the raw X-Wallet-Address header is
+// still accepted.
+func LegacyHeaderAllowed(legacyUntil time.Time) bool {
+	return time.Now().Before(legacyUntil)
+}
+
+func bearerCredential(c *gin.Context) string {
+	authHeader := c.GetHeader("X-Forwarded-Authorization")
+	if authHeader == "" {
+		authHeader = c.GetHeader("Authorization")
+	}
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return ""
+	}
+	return parts[1]
+}
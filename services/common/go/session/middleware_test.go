@@ -0,0 +1,121 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/GT8004/gt8004-common/session"
+)
+
+type revocations map[string]bool
+
+func (r revocations) IsSessionRevoked(_ context.Context, sessionID string) (bool, error) {
+	if sessionID == "broken" {
+		return false, errors.New("db down")
+	}
+	return r[sessionID], nil
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s, _ := session.NewSigner(testSecret)
+	valid, _, _ := s.Issue("0xABC", "live", 0, time.Hour)
+	revoked, _, _ := s.Issue("0xabc", "gone", 0, time.Hour)
+	broken, _, _ := s.Issue("0xabc", "broken", 0, time.Hour)
+	expired, _, _ := s.Issue("0xabc", "live", 0, -time.Second)
+
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		status    int
+		wantClaim string
+	}{
+		{"no credential", nil, http.StatusOK, ""},
+		{"api key passes through", map[string]string{"Authorization": "Bearer gt8004_sk_abc"}, http.StatusOK, ""},
+		{"valid session", map[string]string{"Authorization": "Bearer " + valid}, http.StatusOK, "0xabc"},
+		{"forwarded header preferred", map[string]string{
+			"X-Forwarded-Authorization": "Bearer " + valid,
+			"Authorization":             "Bearer gt8004_sk_abc",
+		}, http.StatusOK, "0xabc"},
+		{"tampered", map[string]string{"Authorization": "Bearer " + valid + "x"}, http.StatusUnauthorized, ""},
+		{"expired", map[string]string{"Authorization": "Bearer " + expired}, http.StatusUnauthorized, ""},
+		{"revoked", map[string]string{"Authorization": "Bearer " + revoked}, http.StatusUnauthorized, ""},
+		{"revocation check fails", map[string]string{"Authorization": "Bearer " + broken}, http.StatusServiceUnavailable, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(session.Middleware(s, revocations{"gone": true}))
+			var got string
+			r.GET("/", func(c *gin.Context) {
+				if claims, ok := session.FromContext(c); ok {
+					got = claims.Address
+				}
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if got != tt.wantClaim {
+				t.Errorf("expected session address %q, got %q", tt.wantClaim, got)
+			}
+		})
+	}
+}
+
+func TestWalletAddress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const wallet = "0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED"
+
+	tests := []struct {
+		name       string
+		claims     *session.Claims
+		header     string
+		until      time.Time
+		want       string
+		deprecated bool
+	}{
+		{"session wins", &session.Claims{Address: "0xsession"}, wallet, time.Now().Add(time.Hour), "0xsession", false},
+		{"legacy header unset is rejected", nil, wallet, time.Time{}, "", false},
+		{"legacy header after sunset", nil, wallet, time.Now().Add(-time.Hour), "", false},
+		{"legacy header before sunset", nil, wallet, time.Now().Add(time.Hour), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
+		{"malformed legacy header", nil, "0x1234", time.Now().Add(time.Hour), "", false},
+		{"nothing", nil, "", time.Now().Add(time.Hour), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				c.Request.Header.Set(session.LegacyWalletHeader, tt.header)
+			}
+			if tt.claims != nil {
+				c.Set(session.ContextKeyClaims, tt.claims)
+			}
+
+			if got := session.WalletAddress(c, tt.until); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			if got := w.Header().Get("Deprecation") == "true"; got != tt.deprecated {
+				t.Errorf("expected deprecation header %v, got %v", tt.deprecated, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Package session issues and verifies short-lived wallet session tokens. A
+// token is minted after a Sign-In With Ethereum login and proves control of
+// the wallet address it names until it expires or its session is revoked.
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TokenPrefix marks session tokens so they can be told apart from API keys
+// (gt8004_sk_) in the same Authorization header.
+const TokenPrefix = "gt8004_st_"
+
+// minSecretLen is the shortest HMAC secret accepted.
+const minSecretLen = 32
+
+var (
+	ErrInvalidToken = errors.New("invalid session token")
+	ErrExpiredToken = errors.New("session token expired")
+	ErrDisabled     = errors.New("session tokens are not configured")
+)
+
+// Claims are the contents of a session token.
+type Claims struct {
+	Address   string `json:"sub"`
+	SessionID string `json:"sid"`
+	ChainID   int    `json:"cid,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Signer mints and verifies session tokens with an HMAC-SHA256 secret
+// shared by every service that accepts them. A nil Signer is disabled.
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner returns a Signer for secret. An empty secret returns a nil
+// (disabled) Signer.
+func NewSigner(secret string) (*Signer, error) {
+	if secret == "" {
+		return nil, nil
+	}
+	if len(secret) < minSecretLen {
+		return nil, fmt.Errorf("session secret must be at least %d characters", minSecretLen)
+	}
+	return &Signer{secret: []byte(secret)}, nil
+}
+
+// Enabled reports whether the Signer can mint and verify tokens.
+func (s *Signer) Enabled() bool {
+	return s != nil
+}
+
+// Issue mints a token for address in session sessionID, valid for ttl.
+func (s *Signer) Issue(address, sessionID string, chainID int, ttl time.Duration) (string, time.Time, error) {
+	if s == nil {
+		return "", time.Time{}, ErrDisabled
+	}
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	payload, err := json.Marshal(Claims{
+		Address:   strings.ToLower(address),
+		SessionID: sessionID,
+		ChainID:   chainID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("marshal session claims: %w", err)
+	}
+	body := TokenPrefix + base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + s.sign(body), expiresAt, nil
+}
+
+// Verify checks a token's signature and expiry and returns its claims.
+// Revocation is checked separately (see Middleware).
+func (s *Signer) Verify(token string) (*Claims, error) {
+	if s == nil {
+		return nil, ErrDisabled
+	}
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok || !strings.HasPrefix(body, TokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.sign(body))) {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(body, TokenPrefix))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Address == "" || claims.SessionID == "" {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
+
+func (s *Signer) sign(body string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// IsToken reports whether a bearer credential is a session token rather
+// than an API key.
+func IsToken(credential string) bool {
+	return strings.HasPrefix(credential, TokenPrefix)
+}
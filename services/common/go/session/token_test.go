@@ -0,0 +1,119 @@
+package session_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GT8004/gt8004-common/session"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestNewSigner(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		enabled bool
+		wantErr bool
+	}{
+		{"empty disables", "", false, false},
+		{"too short", "short", false, true},
+		{"one under minimum", testSecret[:31], false, true},
+		{"minimum length", testSecret, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := session.NewSigner(tt.secret)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if s.Enabled() != tt.enabled {
+				t.Errorf("expected enabled %v, got %v", tt.enabled, s.Enabled())
+			}
+		})
+	}
+}
+
+func TestSigner_IssueVerify(t *testing.T) {
+	s, _ := session.NewSigner(testSecret)
+
+	token, expiresAt, err := s.Issue("0xABCDEF0123456789abcdef0123456789ABCDEF01", "sess-1", 8453, time.Hour)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if !session.IsToken(token) {
+		t.Errorf("expected %s prefix, got %q", session.TokenPrefix, token)
+	}
+	if until := time.Until(expiresAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("expected expiry about an hour out, got %v", until)
+	}
+
+	claims, err := s.Verify(token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if claims.Address != "0xabcdef0123456789abcdef0123456789abcdef01" {
+		t.Errorf("expected lowercased address, got %s", claims.Address)
+	}
+	if claims.SessionID != "sess-1" || claims.ChainID != 8453 || claims.ExpiresAt != expiresAt.Unix() {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestSigner_Verify_Rejects(t *testing.T) {
+	s, _ := session.NewSigner(testSecret)
+	other, _ := session.NewSigner(strings.Repeat("x", 32))
+	token, _, _ := s.Issue("0xabc", "sess-1", 0, time.Hour)
+	expired, _, _ := s.Issue("0xabc", "sess-1", 0, -time.Second)
+	noSession, _, _ := s.Issue("0xabc", "", 0, time.Hour)
+	body, sig, _ := strings.Cut(token, ".")
+
+	forged := session.TokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"0xevil","sid":"s","exp":9999999999}`))
+
+	tests := []struct {
+		name   string
+		signer *session.Signer
+		token  string
+		want   error
+	}{
+		{"disabled signer", nil, token, session.ErrDisabled},
+		{"empty", s, "", session.ErrInvalidToken},
+		{"api key", s, "gt8004_sk_abc", session.ErrInvalidToken},
+		{"no signature", s, body, session.ErrInvalidToken},
+		{"other secret", other, token, session.ErrInvalidToken},
+		{"tampered signature", s, body + "." + strings.Repeat("A", len(sig)), session.ErrInvalidToken},
+		{"forged body with copied signature", s, forged + "." + sig, session.ErrInvalidToken},
+		{"missing prefix", s, strings.TrimPrefix(token, session.TokenPrefix), session.ErrInvalidToken},
+		{"missing session id", s, noSession, session.ErrInvalidToken},
+		{"expired", s, expired, session.ErrExpiredToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.signer.Verify(tt.token)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v (claims %+v)", tt.want, err, claims)
+			}
+		})
+	}
+}
+
+func TestIsToken(t *testing.T) {
+	tests := []struct {
+		credential string
+		want       bool
+	}{
+		{session.TokenPrefix + "abc.def", true},
+		{"gt8004_sk_abc", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := session.IsToken(tt.credential); got != tt.want {
+			t.Errorf("IsToken(%q): expected %v, got %v", tt.credential, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Package webhook defines the platform events agent owners can subscribe
+// to and how deliveries are signed. Services record events in the shared
+// database; the registry delivers them to subscribed endpoints.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	mrand "math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event types.
+const (
+	EventAgentRegistered           = "agent.registered"
+	EventAgentDeregistered         = "agent.deregistered"
+	EventTierChanged               = "tier.changed"
+	EventReviewCreated             = "review.created"
+	EventPaymentVerified           = "payment.verified"
+	EventPaymentVerificationFailed = "payment.verification_failed"
+	EventSDKConnected              = "sdk.connected"
+	EventSDKDisconnected           = "sdk.disconnected"
+	EventOwnershipTransferred      = "ownership.transferred"
+)
+
+// Events lists every event type a subscription may name.
+var Events = []string{
+	EventAgentRegistered,
+	EventAgentDeregistered,
+	EventTierChanged,
+	EventReviewCreated,
+	EventPaymentVerified,
+	EventPaymentVerificationFailed,
+	EventSDKConnected,
+	EventSDKDisconnected,
+	EventOwnershipTransferred,
+}
+
+// IsEvent reports whether s is a known event type.
+func IsEvent(s string) bool {
+	for _, e := range Events {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery headers.
+const (
+	SignatureHeader = "X-GT8004-Signature"
+	EventHeader     = "X-GT8004-Event"
+	DeliveryHeader  = "X-GT8004-Delivery"
+)
+
+// Envelope is the JSON body POSTed to a subscribed endpoint. AgentID is
+// empty for events about a wallet's token that is not registered yet.
+type Envelope struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	AgentID   string          `json:"agent_id,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// SecretPrefix marks webhook signing secrets.
+const SecretPrefix = "whsec_"
+
+// NewSecret returns a random signing secret.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+	return SecretPrefix + hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header value for body sent at ts:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + signature(secret, t, body)
+}
+
+func signature(secret, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Errors returned by Verify.
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleSignature   = errors.New("webhook signature timestamp outside tolerance")
+)
+
+// Verify checks a signature header against body. Signatures older or newer
+// than tolerance relative to now are rejected to limit replays.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var t string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrInvalidSignature
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+		return ErrStaleSignature
+	}
+	want := signature(secret, t, body)
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), []byte(want)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// MaxAttempts is how many times a delivery is tried before it fails.
+const MaxAttempts = 8
+
+// Backoff returns the wait before retrying after the given failed attempt
+// (1-based): one minute doubling each time, capped at six hours, with up to
+// 10% jitter so retries from one outage spread out.
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := time.Duration(math.Min(float64(time.Minute)*math.Pow(2, float64(attempt-1)), float64(6*time.Hour)))
+	return d + time.Duration(mrand.Int64N(int64(d)/10+1))
+}
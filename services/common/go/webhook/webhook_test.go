@@ -0,0 +1,130 @@
+package webhook_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GT8004/gt8004-common/webhook"
+)
+
+const testSecret = "whsec_test"
+
+func TestSign(t *testing.T) {
+	ts := time.Unix(1760000000, 0)
+	body := []byte(`{"id":"evt_1"}`)
+
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte("1760000000." + string(body)))
+	want := "t=1760000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := webhook.Sign(testSecret, ts, body); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if webhook.Sign(testSecret, ts, body) == webhook.Sign("whsec_other", ts, body) {
+		t.Error("expected signatures to depend on the secret")
+	}
+	if webhook.Sign(testSecret, ts, body) == webhook.Sign(testSecret, ts.Add(time.Second), body) {
+		t.Error("expected signatures to depend on the timestamp")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	body := []byte(`{"id":"evt_1","type":"tier.changed"}`)
+	valid := webhook.Sign(testSecret, now, body)
+	_, v1, _ := strings.Cut(valid, ",")
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{"valid", testSecret, valid, body, now, nil},
+		{"within tolerance", testSecret, valid, body, now.Add(4 * time.Minute), nil},
+		{"clock behind within tolerance", testSecret, valid, body, now.Add(-4 * time.Minute), nil},
+		{"spaces and extra fields", testSecret, " t=1760000000 , v0=abc, " + v1, body, now, nil},
+		{"one of several v1 signatures", testSecret, valid + ",v1=deadbeef", body, now, nil},
+		{"rotated secret signature first", testSecret, webhook.Sign("whsec_old", now, body) + "," + v1, body, now, nil},
+		{"too old", testSecret, valid, body, now.Add(6 * time.Minute), webhook.ErrStaleSignature},
+		{"too new", testSecret, valid, body, now.Add(-6 * time.Minute), webhook.ErrStaleSignature},
+		{"wrong secret", "whsec_other", valid, body, now, webhook.ErrInvalidSignature},
+		{"tampered body", testSecret, valid, []byte(`{"id":"evt_2","type":"tier.changed"}`), now, webhook.ErrInvalidSignature},
+		{"timestamp swapped", testSecret, "t=1760000001," + v1, body, now, webhook.ErrInvalidSignature},
+		{"no timestamp", testSecret, v1, body, now, webhook.ErrInvalidSignature},
+		{"bad timestamp", testSecret, "t=soon," + v1, body, now, webhook.ErrInvalidSignature},
+		{"no signature", testSecret, "t=1760000000", body, now, webhook.ErrInvalidSignature},
+		{"empty header", testSecret, "", body, now, webhook.ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := webhook.Verify(tt.secret, tt.header, tt.body, 5*time.Minute, tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{-1, time.Minute},
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{8, 128 * time.Minute},
+		{9, 256 * time.Minute},
+		{10, 6 * time.Hour},
+		{100, 6 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.base.String(), func(t *testing.T) {
+			for i := 0; i < 200; i++ {
+				d := webhook.Backoff(tt.attempt)
+				if d < tt.base || d > tt.base+tt.base/10 {
+					t.Fatalf("attempt %d: expected %v to %v, got %v", tt.attempt, tt.base, tt.base+tt.base/10, d)
+				}
+			}
+		})
+	}
+}
+
+func TestIsEvent(t *testing.T) {
+	for _, e := range webhook.Events {
+		if !webhook.IsEvent(e) {
+			t.Errorf("expected %s to be an event", e)
+		}
+	}
+	for _, s := range []string{"", "tier", "Tier.Changed", "tier.changed "} {
+		if webhook.IsEvent(s) {
+			t.Errorf("expected %q not to be an event", s)
+		}
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := webhook.NewSecret()
+	if err != nil {
+		t.Fatalf("new secret: %v", err)
+	}
+	b, _ := webhook.NewSecret()
+	if !strings.HasPrefix(a, webhook.SecretPrefix) || len(a) != len(webhook.SecretPrefix)+64 {
+		t.Errorf("expected %s plus 64 hex chars, got %q", webhook.SecretPrefix, a)
+	}
+	if a == b {
+		t.Error("expected distinct secrets")
+	}
+}
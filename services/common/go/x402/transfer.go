@@ -0,0 +1,161 @@
+package x402
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"go.uber.org/zap"
+)
+
+// USDC contract addresses per chain.
+var usdcContracts = map[int]common.Address{
+	1:        common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"), // Ethereum Mainnet
+	8453:     common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"), // Base Mainnet
+	84532:    common.HexToAddress("0x036CbD53842c5426634e7929541eC2318f3dCF7e"), // Base Sepolia
+	11155111: common.HexToAddress("0x1c7D4B196Cb0C7B01d743Fbc6116a902379C7238"), // Ethereum Sepolia
+}
+
+// DefaultRPCs are the RPC endpoints used per chain.
+var DefaultRPCs = map[int]string{
+	1:        "https://ethereum-rpc.publicnode.com",
+	8453:     "https://base-rpc.publicnode.com",
+	84532:    "https://base-sepolia-rpc.publicnode.com",
+	11155111: "https://ethereum-sepolia-rpc.publicnode.com",
+}
+
+// ERC-20 Transfer event topic: Transfer(address,address,uint256)
+var topicTransfer = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// Errors returned by TransferChecker.Check.
+var (
+	ErrUnsupportedChain   = errors.New("unsupported chain")
+	ErrReceiptUnavailable = errors.New("transaction receipt unavailable")
+	ErrTxReverted         = errors.New("transaction reverted")
+	ErrNoMatchingTransfer = errors.New("no matching USDC transfer")
+	ErrTransferTooOld     = errors.New("transaction mined before the payment was requested")
+)
+
+// Expected describes the USDC transfer a transaction must contain. Empty
+// Payer or PayTo match any address; a zero NotBefore matches any block time.
+type Expected struct {
+	Amount    float64
+	Payer     string
+	PayTo     string
+	NotBefore time.Time
+}
+
+// Transfer is the matching USDC transfer found in a transaction.
+type Transfer struct {
+	From   string
+	To     string
+	Amount float64
+}
+
+// TransferChecker verifies on-chain that a transaction moved USDC.
+type TransferChecker struct {
+	clients map[int]*ethclient.Client
+	logger  *zap.Logger
+}
+
+// NewTransferChecker dials an ethclient for every chain in rpcs that has a
+// known USDC contract.
+func NewTransferChecker(rpcs map[int]string, logger *zap.Logger) *TransferChecker {
+	tc := &TransferChecker{
+		clients: make(map[int]*ethclient.Client),
+		logger:  logger,
+	}
+
+	for chainID, rpc := range rpcs {
+		if _, ok := usdcContracts[chainID]; !ok {
+			continue
+		}
+		client, err := ethclient.Dial(rpc)
+		if err != nil {
+			logger.Error("failed to connect to chain RPC",
+				zap.Int("chain_id", chainID), zap.String("rpc", rpc), zap.Error(err))
+			continue
+		}
+		tc.clients[chainID] = client
+		logger.Info("transfer checker connected to chain RPC",
+			zap.Int("chain_id", chainID), zap.String("rpc", rpc))
+	}
+
+	return tc
+}
+
+// Supports reports whether transfers on chainID can be checked.
+func (tc *TransferChecker) Supports(chainID int) bool {
+	_, ok := tc.clients[chainID]
+	return ok
+}
+
+// Check fetches txHash's receipt on chainID and looks for a successful USDC
+// Transfer matching want. The amount matches within 0.001 USDC, and the
+// transaction's block must not be older than want.NotBefore.
+func (tc *TransferChecker) Check(ctx context.Context, chainID int, txHash string, want Expected) (*Transfer, error) {
+	client, ok := tc.clients[chainID]
+	if !ok {
+		return nil, ErrUnsupportedChain
+	}
+	usdcAddr := usdcContracts[chainID]
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	receipt, err := client.TransactionReceipt(ctx, common.HexToHash(txHash))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReceiptUnavailable, err)
+	}
+	if receipt.Status != 1 {
+		return nil, ErrTxReverted
+	}
+	if !want.NotBefore.IsZero() {
+		header, err := client.HeaderByNumber(ctx, receipt.BlockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("%w: block header: %v", ErrReceiptUnavailable, err)
+		}
+		if time.Unix(int64(header.Time), 0).Before(want.NotBefore.Truncate(time.Second)) {
+			return nil, ErrTransferTooOld
+		}
+	}
+
+	for _, log := range receipt.Logs {
+		if len(log.Topics) < 3 {
+			continue
+		}
+		// Must be Transfer event from USDC contract
+		if log.Topics[0] != topicTransfer || log.Address != usdcAddr {
+			continue
+		}
+		if len(log.Data) < 32 {
+			continue
+		}
+
+		from := common.BytesToAddress(log.Topics[1].Bytes())
+		to := common.BytesToAddress(log.Topics[2].Bytes())
+		// USDC has 6 decimals
+		amount := float64(new(big.Int).SetBytes(log.Data[:32]).Int64()) / 1e6
+
+		payerMatch := want.Payer == "" || strings.EqualFold(from.Hex(), want.Payer)
+		payToMatch := want.PayTo == "" || strings.EqualFold(to.Hex(), want.PayTo)
+		// Allow small floating-point tolerance
+		amountMatch := math.Abs(amount-want.Amount) < 0.001
+
+		if payerMatch && payToMatch && amountMatch {
+			return &Transfer{
+				From:   strings.ToLower(from.Hex()),
+				To:     strings.ToLower(to.Hex()),
+				Amount: amount,
+			}, nil
+		}
+	}
+	return nil, ErrNoMatchingTransfer
+}
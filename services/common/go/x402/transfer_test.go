@@ -0,0 +1,180 @@
+package x402_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/x402"
+)
+
+const (
+	baseUSDC = "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
+	payer    = "0x1111111111111111111111111111111111111111"
+	payTo    = "0x2222222222222222222222222222222222222222"
+)
+
+var testTx = "0x" + strings.Repeat("cd", 32)
+
+// chain is a fake JSON-RPC node serving one receipt and its block.
+type chain struct {
+	status    uint64
+	blockTime time.Time
+	logs      []map[string]any
+	noReceipt bool
+}
+
+func transferLog(token, from, to string, amount int64) map[string]any {
+	topic := func(addr string) string { return common.BytesToHash(common.HexToAddress(addr).Bytes()).Hex() }
+	return map[string]any{
+		"address":          token,
+		"topics":           []string{crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")).Hex(), topic(from), topic(to)},
+		"data":             hexutil.Encode(common.LeftPadBytes(big.NewInt(amount).Bytes(), 32)),
+		"blockNumber":      "0x10",
+		"transactionHash":  testTx,
+		"transactionIndex": "0x0",
+		"blockHash":        common.Hash{1}.Hex(),
+		"logIndex":         "0x0",
+		"removed":          false,
+	}
+}
+
+func (c *chain) serve(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode rpc request: %v", err)
+			return
+		}
+		var result any
+		switch req.Method {
+		case "eth_getTransactionReceipt":
+			if c.noReceipt {
+				break
+			}
+			logs := c.logs
+			if logs == nil {
+				logs = []map[string]any{}
+			}
+			result = map[string]any{
+				"type":              "0x2",
+				"status":            hexutil.EncodeUint64(c.status),
+				"cumulativeGasUsed": "0x5208",
+				"logsBloom":         hexutil.Encode(make([]byte, 256)),
+				"logs":              logs,
+				"transactionHash":   testTx,
+				"contractAddress":   nil,
+				"gasUsed":           "0x5208",
+				"effectiveGasPrice": "0x1",
+				"blockHash":         common.Hash{1}.Hex(),
+				"blockNumber":       "0x10",
+				"transactionIndex":  "0x0",
+			}
+		case "eth_getBlockByNumber":
+			zero := common.Hash{}.Hex()
+			result = map[string]any{
+				"parentHash":       zero,
+				"sha3Uncles":       zero,
+				"miner":            common.Address{}.Hex(),
+				"stateRoot":        zero,
+				"transactionsRoot": zero,
+				"receiptsRoot":     zero,
+				"logsBloom":        hexutil.Encode(make([]byte, 256)),
+				"difficulty":       "0x0",
+				"number":           "0x10",
+				"gasLimit":         "0x1c9c380",
+				"gasUsed":          "0x5208",
+				"timestamp":        hexutil.EncodeUint64(uint64(c.blockTime.Unix())),
+				"extraData":        "0x",
+				"mixHash":          zero,
+				"nonce":            "0x0000000000000000",
+				"baseFeePerGas":    "0x1",
+				"hash":             common.Hash{1}.Hex(),
+			}
+		default:
+			t.Errorf("unexpected rpc method %s", req.Method)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+}
+
+func TestTransferChecker_Check(t *testing.T) {
+	mined := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	ok := []map[string]any{transferLog(baseUSDC, payer, payTo, 10_000_000)}
+	want := x402.Expected{Amount: 10, Payer: payer, PayTo: payTo}
+
+	tests := []struct {
+		name    string
+		chain   chain
+		want    x402.Expected
+		wantErr error
+	}{
+		{"matching transfer", chain{status: 1, blockTime: mined, logs: ok}, want, nil},
+		{"checksummed addresses", chain{status: 1, blockTime: mined, logs: ok},
+			x402.Expected{Amount: 10, Payer: common.HexToAddress(payer).Hex(), PayTo: common.HexToAddress(payTo).Hex()}, nil},
+		{"any payer", chain{status: 1, blockTime: mined, logs: ok}, x402.Expected{Amount: 10, PayTo: payTo}, nil},
+		{"within amount tolerance", chain{status: 1, blockTime: mined, logs: []map[string]any{transferLog(baseUSDC, payer, payTo, 10_000_500)}}, want, nil},
+		{"mined after challenge", chain{status: 1, blockTime: mined, logs: ok},
+			x402.Expected{Amount: 10, Payer: payer, PayTo: payTo, NotBefore: mined.Add(-time.Minute)}, nil},
+		{"mined in the challenge's second", chain{status: 1, blockTime: mined, logs: ok},
+			x402.Expected{Amount: 10, Payer: payer, PayTo: payTo, NotBefore: mined.Add(900 * time.Millisecond)}, nil},
+		{"mined before challenge", chain{status: 1, blockTime: mined, logs: ok},
+			x402.Expected{Amount: 10, Payer: payer, PayTo: payTo, NotBefore: mined.Add(time.Second)}, x402.ErrTransferTooOld},
+		{"reverted", chain{status: 0, blockTime: mined, logs: ok}, want, x402.ErrTxReverted},
+		{"not mined", chain{noReceipt: true}, want, x402.ErrReceiptUnavailable},
+		{"wrong payer", chain{status: 1, blockTime: mined, logs: ok},
+			x402.Expected{Amount: 10, Payer: payTo, PayTo: payTo}, x402.ErrNoMatchingTransfer},
+		{"wrong recipient", chain{status: 1, blockTime: mined, logs: ok},
+			x402.Expected{Amount: 10, Payer: payer, PayTo: payer}, x402.ErrNoMatchingTransfer},
+		{"wrong amount", chain{status: 1, blockTime: mined, logs: []map[string]any{transferLog(baseUSDC, payer, payTo, 9_990_000)}}, want, x402.ErrNoMatchingTransfer},
+		{"other token", chain{status: 1, blockTime: mined, logs: []map[string]any{transferLog(payTo, payer, payTo, 10_000_000)}}, want, x402.ErrNoMatchingTransfer},
+		{"second log matches", chain{status: 1, blockTime: mined, logs: []map[string]any{
+			transferLog(baseUSDC, payer, payer, 10_000_000), transferLog(baseUSDC, payer, payTo, 10_000_000),
+		}}, want, nil},
+		{"no logs", chain{status: 1, blockTime: mined}, want, x402.ErrNoMatchingTransfer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := tt.chain.serve(t)
+			defer srv.Close()
+			tc := x402.NewTransferChecker(map[int]string{8453: srv.URL}, zap.NewNop())
+
+			got, err := tc.Check(context.Background(), 8453, testTx, tt.want)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got.From != payer || got.To != payTo {
+				t.Errorf("expected %s -> %s, got %s -> %s", payer, payTo, got.From, got.To)
+			}
+		})
+	}
+}
+
+func TestTransferChecker_UnsupportedChain(t *testing.T) {
+	tc := x402.NewTransferChecker(map[int]string{10: "http://127.0.0.1:1"}, zap.NewNop())
+	if tc.Supports(10) || tc.Supports(8453) {
+		t.Error("expected no supported chains")
+	}
+	if _, err := tc.Check(context.Background(), 8453, testTx, x402.Expected{}); !errors.Is(err, x402.ErrUnsupportedChain) {
+		t.Errorf("expected ErrUnsupportedChain, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+// Package x402 implements the parts of the x402 payment protocol the
+// platform uses: 402 challenges describing what to pay, the payment and
+// settlement headers, and the on-chain check that a USDC transfer settled.
+package x402
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Version is the x402 protocol version spoken here.
+const Version = 1
+
+// Headers.
+const (
+	// PaymentHeader carries the client's payment when it retries a request
+	// that answered 402.
+	PaymentHeader = "X-PAYMENT"
+	// PaymentResponseHeader carries the settlement result.
+	PaymentResponseHeader = "X-PAYMENT-RESPONSE"
+)
+
+// SchemeExact pays an exact amount of an ERC-20 token.
+const SchemeExact = "exact"
+
+// networks names supported chains the way x402 does.
+var networks = map[int]string{
+	1:        "ethereum",
+	8453:     "base",
+	84532:    "base-sepolia",
+	11155111: "ethereum-sepolia",
+}
+
+// Network returns the x402 network name of chainID, or "" when unsupported.
+func Network(chainID int) string { return networks[chainID] }
+
+// ChainID returns the chain ID of an x402 network name, or 0.
+func ChainID(network string) int {
+	for id, n := range networks {
+		if n == network {
+			return id
+		}
+	}
+	return 0
+}
+
+// Requirements describes one accepted way to pay. Amounts are in the
+// asset's base units (USDC has 6 decimals).
+type Requirements struct {
+	Scheme            string         `json:"scheme"`
+	Network           string         `json:"network"`
+	MaxAmountRequired string         `json:"maxAmountRequired"`
+	Resource          string         `json:"resource"`
+	Description       string         `json:"description"`
+	MimeType          string         `json:"mimeType"`
+	PayTo             string         `json:"payTo"`
+	MaxTimeoutSeconds int            `json:"maxTimeoutSeconds"`
+	Asset             string         `json:"asset"`
+	Extra             map[string]any `json:"extra,omitempty"`
+}
+
+// PaymentRequired is the body of a 402 response.
+type PaymentRequired struct {
+	X402Version int            `json:"x402Version"`
+	Error       string         `json:"error"`
+	Accepts     []Requirements `json:"accepts"`
+}
+
+// Payment is the decoded PaymentHeader. The platform accepts payments
+// already settled on-chain, identified by Payload.Transaction. Payload.Nonce
+// echoes the "nonce" from the challenge's Extra when it had one.
+type Payment struct {
+	X402Version int    `json:"x402Version"`
+	Scheme      string `json:"scheme"`
+	Network     string `json:"network"`
+	Payload     struct {
+		Transaction string `json:"transaction"`
+		Nonce       string `json:"nonce,omitempty"`
+	} `json:"payload"`
+}
+
+// ErrInvalidPayment is returned for a malformed PaymentHeader.
+var ErrInvalidPayment = errors.New("invalid x402 payment header")
+
+// DecodePayment decodes a base64-encoded JSON PaymentHeader value.
+func DecodePayment(header string) (*Payment, error) {
+	raw, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		return nil, ErrInvalidPayment
+	}
+	var p Payment
+	if err := json.Unmarshal(raw, &p); err != nil || p.Payload.Transaction == "" {
+		return nil, ErrInvalidPayment
+	}
+	return &p, nil
+}
+
+// SettlementResponse is the decoded PaymentResponseHeader.
+type SettlementResponse struct {
+	Success     bool   `json:"success"`
+	Transaction string `json:"transaction"`
+	Network     string `json:"network"`
+	Payer       string `json:"payer"`
+}
+
+// Encode returns the PaymentResponseHeader value.
+func (r SettlementResponse) Encode() string {
+	b, _ := json.Marshal(r)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// USDCBaseUnits converts a USDC amount to base units as a decimal string.
+func USDCBaseUnits(amount float64) string {
+	return strconv.FormatInt(int64(math.Round(amount*1e6)), 10)
+}
+
+// USDCAsset returns the USDC contract address on chainID.
+func USDCAsset(chainID int) (string, error) {
+	addr, ok := usdcContracts[chainID]
+	if !ok {
+		return "", fmt.Errorf("no USDC contract for chain %d", chainID)
+	}
+	return addr.Hex(), nil
+}
@@ -0,0 +1,136 @@
+package x402_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/GT8004/gt8004-common/x402"
+)
+
+func encodePayment(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func TestDecodePayment(t *testing.T) {
+	tx := "0x" + strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name    string
+		header  string
+		tx      string
+		nonce   string
+		network string
+		wantErr bool
+	}{
+		{
+			name: "with nonce",
+			header: encodePayment(t, map[string]any{
+				"x402Version": 1, "scheme": "exact", "network": "base",
+				"payload": map[string]string{"transaction": tx, "nonce": "n1"},
+			}),
+			tx: tx, nonce: "n1", network: "base",
+		},
+		{
+			name: "without nonce",
+			header: encodePayment(t, map[string]any{
+				"network": "base-sepolia", "payload": map[string]string{"transaction": tx},
+			}),
+			tx: tx, network: "base-sepolia",
+		},
+		{name: "not base64", header: "%%%", wantErr: true},
+		{name: "url-safe base64", header: base64.URLEncoding.EncodeToString([]byte(`{"payload":{"transaction":"0x>>>"}}`)), wantErr: true},
+		{name: "not json", header: base64.StdEncoding.EncodeToString([]byte("nope")), wantErr: true},
+		{name: "missing transaction", header: encodePayment(t, map[string]any{"payload": map[string]string{"nonce": "n1"}}), wantErr: true},
+		{name: "empty", header: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := x402.DecodePayment(tt.header)
+			if tt.wantErr {
+				if !errors.Is(err, x402.ErrInvalidPayment) {
+					t.Fatalf("expected ErrInvalidPayment, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if p.Payload.Transaction != tt.tx || p.Payload.Nonce != tt.nonce || p.Network != tt.network {
+				t.Errorf("unexpected payment: %+v", p)
+			}
+		})
+	}
+}
+
+func TestSettlementResponse_Encode(t *testing.T) {
+	in := x402.SettlementResponse{Success: true, Transaction: "0xabc", Network: "base", Payer: "0xdef"}
+	raw, err := base64.StdEncoding.DecodeString(in.Encode())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var out x402.SettlementResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out != in {
+		t.Errorf("expected %+v, got %+v", in, out)
+	}
+}
+
+func TestUSDCBaseUnits(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   string
+	}{
+		{0, "0"},
+		{1, "1000000"},
+		{10, "10000000"},
+		{0.1, "100000"},
+		{0.000001, "1"},
+		{19.99, "19990000"},
+		{0.0000004, "0"},
+	}
+	for _, tt := range tests {
+		if got := x402.USDCBaseUnits(tt.amount); got != tt.want {
+			t.Errorf("USDCBaseUnits(%v): expected %s, got %s", tt.amount, tt.want, got)
+		}
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	tests := []struct {
+		chainID int
+		network string
+	}{
+		{1, "ethereum"},
+		{8453, "base"},
+		{84532, "base-sepolia"},
+		{11155111, "ethereum-sepolia"},
+	}
+	for _, tt := range tests {
+		if got := x402.Network(tt.chainID); got != tt.network {
+			t.Errorf("Network(%d): expected %s, got %s", tt.chainID, tt.network, got)
+		}
+		if got := x402.ChainID(tt.network); got != tt.chainID {
+			t.Errorf("ChainID(%s): expected %d, got %d", tt.network, tt.chainID, got)
+		}
+		if _, err := x402.USDCAsset(tt.chainID); err != nil {
+			t.Errorf("USDCAsset(%d): %v", tt.chainID, err)
+		}
+	}
+	if x402.Network(10) != "" || x402.ChainID("optimism") != 0 {
+		t.Error("expected unsupported chains to map to zero values")
+	}
+	if _, err := x402.USDCAsset(10); err == nil {
+		t.Error("expected no USDC asset for an unsupported chain")
+	}
+}
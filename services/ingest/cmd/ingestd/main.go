@@ -11,6 +11,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/GT8004/gt8004-common/ratelimit"
 	"github.com/GT8004/gt8004-ingest/internal/config"
 	"github.com/GT8004/gt8004-ingest/internal/handler"
 	"github.com/GT8004/gt8004-ingest/internal/ingest"
@@ -41,13 +42,20 @@ func main() {
 
 	// Verifier and enricher
 	verifier := ingest.NewVerifier(dbStore, logger)
-	enricher := ingest.NewEnricher(dbStore, verifier, logger, cfg.MaxBodySizeBytes)
+	enricher := ingest.NewEnricher(dbStore, verifier, logger, cfg.MaxBodySizeBytes, cfg.TagMaxKeys, cfg.TagMaxValues)
 	worker := ingest.NewWorker(enricher, cfg.IngestWorkers, cfg.IngestBufferSize, logger)
 	worker.Start()
 
+	// Rate limits (shared through Redis, per instance without it)
+	limiter, err := ratelimit.New(cfg.RedisURL, logger)
+	if err != nil {
+		logger.Fatal("failed to configure rate limits", zap.Error(err))
+	}
+	defer limiter.Close()
+
 	// Handler and router
 	h := handler.New(dbStore, worker, logger)
-	router := server.NewRouter(h)
+	router := server.NewRouter(cfg, h, limiter)
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
@@ -1,6 +1,13 @@
 package config
 
-import "github.com/spf13/viper"
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/GT8004/gt8004-common/clientip"
+	"github.com/GT8004/gt8004-common/ratelimit"
+)
 
 type Config struct {
 	Port             int    `mapstructure:"PORT"`
@@ -9,6 +16,21 @@ type Config struct {
 	IngestWorkers    int    `mapstructure:"INGEST_WORKERS"`
 	IngestBufferSize int    `mapstructure:"INGEST_BUFFER_SIZE"`
 	MaxBodySizeBytes int    `mapstructure:"MAX_BODY_SIZE_BYTES"`
+	TagMaxKeys       int    `mapstructure:"TAG_MAX_KEYS"`
+	TagMaxValues     int    `mapstructure:"TAG_MAX_VALUES_PER_KEY"`
+	RedisURL         string `mapstructure:"REDIS_URL"`
+
+	// Rate limits per minute: RateLimitCaller per API key or IP and
+	// RateLimitIP per IP before auth, RateLimitAgentTiers per agent by its
+	// tier. Zero disables a limit.
+	RateLimitCaller     int                        `mapstructure:"RATE_LIMIT_CALLER_PER_MIN"`
+	RateLimitIP         int                        `mapstructure:"RATE_LIMIT_IP_PER_MIN"`
+	RateLimitAgentTiers map[string]ratelimit.Limit `mapstructure:"-"`
+
+	// TrustedProxies are the proxies (addresses or CIDR ranges) whose
+	// X-Forwarded-For entries are believed when reading the client IP for
+	// API key allowlists and per-IP rate limits.
+	TrustedProxies []string `mapstructure:"-"`
 }
 
 func Load() (*Config, error) {
@@ -20,6 +42,12 @@ func Load() (*Config, error) {
 	viper.SetDefault("INGEST_WORKERS", 4)
 	viper.SetDefault("INGEST_BUFFER_SIZE", 1000)
 	viper.SetDefault("MAX_BODY_SIZE_BYTES", 51200)
+	viper.SetDefault("TAG_MAX_KEYS", 20)
+	viper.SetDefault("TAG_MAX_VALUES_PER_KEY", 100)
+	viper.SetDefault("RATE_LIMIT_CALLER_PER_MIN", 1200)
+	viper.SetDefault("RATE_LIMIT_IP_PER_MIN", 2400)
+	viper.SetDefault("RATE_LIMIT_AGENT_TIERS", "open=600,lite=3000")
+	viper.SetDefault("TRUSTED_PROXIES", clientip.CloudRun)
 
 	cfg := &Config{}
 	cfg.Port = viper.GetInt("PORT")
@@ -28,6 +56,21 @@ func Load() (*Config, error) {
 	cfg.IngestWorkers = viper.GetInt("INGEST_WORKERS")
 	cfg.IngestBufferSize = viper.GetInt("INGEST_BUFFER_SIZE")
 	cfg.MaxBodySizeBytes = viper.GetInt("MAX_BODY_SIZE_BYTES")
+	cfg.TagMaxKeys = viper.GetInt("TAG_MAX_KEYS")
+	cfg.TagMaxValues = viper.GetInt("TAG_MAX_VALUES_PER_KEY")
+	cfg.RedisURL = viper.GetString("REDIS_URL")
+	cfg.RateLimitCaller = viper.GetInt("RATE_LIMIT_CALLER_PER_MIN")
+	cfg.RateLimitIP = viper.GetInt("RATE_LIMIT_IP_PER_MIN")
+	agentTiers, err := ratelimit.ParseTiers(viper.GetString("RATE_LIMIT_AGENT_TIERS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_AGENT_TIERS: %w", err)
+	}
+	cfg.RateLimitAgentTiers = agentTiers
+	trustedProxies, err := clientip.ParseProxies(viper.GetString("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	cfg.TrustedProxies = trustedProxies
 
 	return cfg, nil
 }
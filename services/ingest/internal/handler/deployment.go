@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-ingest/internal/middleware"
+	"github.com/GT8004/gt8004-ingest/internal/store"
+)
+
+type createDeploymentRequest struct {
+	Version    string     `json:"version" binding:"required,max=64"`
+	Commit     string     `json:"commit" binding:"max=64"`
+	DeployedAt *time.Time `json:"deployed_at"`
+}
+
+// CreateDeployment handles POST /v1/deployments - records a release marker
+// for the agent that owns the API key. Intended to be called from CI.
+func (h *Handler) CreateDeployment(c *gin.Context) {
+	agentDBID, exists := c.Get(middleware.ContextKeyAgentDBID)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req createDeploymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	req.Version = strings.TrimSpace(req.Version)
+	if req.Version == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "version is required"})
+		return
+	}
+
+	now := time.Now()
+	deployedAt := now
+	if req.DeployedAt != nil {
+		// Allow backfilling, but not markers in the future.
+		if req.DeployedAt.After(now.Add(5 * time.Minute)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "deployed_at must not be in the future"})
+			return
+		}
+		deployedAt = *req.DeployedAt
+	}
+
+	d := &store.Deployment{
+		AgentID:    agentDBID.(uuid.UUID),
+		Version:    req.Version,
+		DeployedAt: deployedAt,
+	}
+	if commit := strings.TrimSpace(req.Commit); commit != "" {
+		d.CommitSHA = &commit
+	}
+
+	if err := h.store.InsertDeployment(c.Request.Context(), d); err != nil {
+		h.logger.Error("failed to record deployment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record deployment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, d)
+}
@@ -2,10 +2,12 @@ package ingest
 
 import (
 	"context"
+	"strings"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/GT8004/gt8004-common/webhook"
 	"github.com/GT8004/gt8004-ingest/internal/store"
 )
 
@@ -14,17 +16,29 @@ type Enricher struct {
 	verifier    *Verifier
 	logger      *zap.Logger
 	maxBodySize int
+
+	// Per-agent tag cardinality limits
+	maxTagKeys   int
+	maxTagValues int
 }
 
-func NewEnricher(s *store.Store, v *Verifier, logger *zap.Logger, maxBodySize int) *Enricher {
+func NewEnricher(s *store.Store, v *Verifier, logger *zap.Logger, maxBodySize, maxTagKeys, maxTagValues int) *Enricher {
 	if maxBodySize <= 0 {
 		maxBodySize = 51200 // 50KB default
 	}
+	if maxTagKeys <= 0 {
+		maxTagKeys = 20
+	}
+	if maxTagValues <= 0 {
+		maxTagValues = 100
+	}
 	return &Enricher{
-		store:       s,
-		verifier:    v,
-		logger:      logger,
-		maxBodySize: maxBodySize,
+		store:        s,
+		verifier:     v,
+		logger:       logger,
+		maxBodySize:  maxBodySize,
+		maxTagKeys:   maxTagKeys,
+		maxTagValues: maxTagValues,
 	}
 }
 
@@ -42,6 +56,27 @@ func normalizeProtocol(p *string) *string {
 	}
 }
 
+// normalizeVersion trims the per-entry agent version and drops values that
+// are empty or longer than the 64-character column.
+func normalizeVersion(v *string) *string {
+	if v == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*v)
+	if trimmed == "" || len(trimmed) > 64 {
+		return nil
+	}
+	return &trimmed
+}
+
+// normalizeTokens drops negative LLM token counts.
+func normalizeTokens(n *int) *int {
+	if n == nil || *n < 0 {
+		return nil
+	}
+	return n
+}
+
 // customerStats holds aggregated per-customer stats from a batch.
 type customerStats struct {
 	requestCount int64
@@ -59,7 +94,8 @@ func (e *Enricher) Process(ctx context.Context, agentDBID uuid.UUID, chainID int
 	realEntries := make([]LogEntry, 0, len(batch.Entries))
 	for _, entry := range batch.Entries {
 		if entry.Source != nil && *entry.Source == "sdk_ping" {
-			if err := e.store.UpdateAgentSDKConnected(ctx, agentDBID); err != nil {
+			connected, err := e.store.UpdateAgentSDKConnected(ctx, agentDBID)
+			if err != nil {
 				e.logger.Error("failed to update agent sdk connected",
 					zap.Error(err), zap.String("agent_db_id", agentDBID.String()))
 			} else {
@@ -67,6 +103,14 @@ func (e *Enricher) Process(ctx context.Context, agentDBID uuid.UUID, chainID int
 					zap.String("agent_db_id", agentDBID.String()),
 					zap.String("sdk_version", batch.SDKVersion))
 			}
+			if connected {
+				err := e.store.EnqueueWebhookEvent(ctx, agentDBID, webhook.EventSDKConnected, map[string]any{
+					"sdk_version": batch.SDKVersion,
+				})
+				if err != nil {
+					e.logger.Error("failed to enqueue sdk.connected", zap.Error(err))
+				}
+			}
 			continue
 		}
 		realEntries = append(realEntries, entry)
@@ -131,6 +175,10 @@ func (e *Enricher) Process(ctx context.Context, agentDBID uuid.UUID, chainID int
 			AcceptLanguage:   entry.AcceptLanguage,
 			Country:          entry.Country,
 			City:             entry.City,
+			Tags:             sanitizeTags(entry.Tags),
+			Version:          normalizeVersion(entry.Version),
+			InputTokens:      normalizeTokens(entry.InputTokens),
+			OutputTokens:     normalizeTokens(entry.OutputTokens),
 		}
 
 		if entry.X402Amount != nil {
@@ -161,6 +209,8 @@ func (e *Enricher) Process(ctx context.Context, agentDBID uuid.UUID, chainID int
 		}
 	}
 
+	e.applyTagLimits(ctx, agentDBID, batch.BatchID, logs)
+
 	// Batch insert request logs
 	if err := e.store.InsertRequestLogs(ctx, agentDBID, logs); err != nil {
 		e.logger.Error("failed to insert request logs",
@@ -231,7 +281,7 @@ func (e *Enricher) Process(ctx context.Context, agentDBID uuid.UUID, chainID int
 			if entry.X402Payer != nil {
 				payer = *entry.X402Payer
 			}
-			go e.verifier.VerifyPayment(context.Background(), entryID, txHash, amount, payer, chainID)
+			go e.verifier.VerifyPayment(context.Background(), agentDBID, entryID, txHash, amount, payer, chainID)
 		}
 	}
 
@@ -257,3 +307,44 @@ func (e *Enricher) Process(ctx context.Context, agentDBID uuid.UUID, chainID int
 
 	return nil
 }
+
+// applyTagLimits enforces the agent's tag cardinality limits on a batch:
+// tags whose key is over the key limit are dropped and values over the
+// per-key limit are replaced with store.TagOverflowValue. If the limits
+// cannot be checked, tags are dropped rather than stored unchecked.
+func (e *Enricher) applyTagLimits(ctx context.Context, agentDBID uuid.UUID, batchID string, logs []store.RequestLog) {
+	var pairs []store.TagPair
+	for _, l := range logs {
+		for k, v := range l.Tags {
+			pairs = append(pairs, store.TagPair{Key: k, Value: v})
+		}
+	}
+	if len(pairs) == 0 {
+		return
+	}
+
+	resolved, err := e.store.ResolveTagValues(ctx, agentDBID, pairs, e.maxTagKeys, e.maxTagValues)
+	if err != nil {
+		e.logger.Error("failed to resolve tag values, dropping tags",
+			zap.Error(err), zap.String("batch_id", batchID))
+		for i := range logs {
+			logs[i].Tags = nil
+		}
+		return
+	}
+
+	for i := range logs {
+		for k, v := range logs[i].Tags {
+			switch stored := resolved[store.TagPair{Key: k, Value: v}]; stored {
+			case "":
+				delete(logs[i].Tags, k)
+			case v:
+			default:
+				logs[i].Tags[k] = stored
+			}
+		}
+		if len(logs[i].Tags) == 0 {
+			logs[i].Tags = nil
+		}
+	}
+}
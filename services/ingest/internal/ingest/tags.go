@@ -0,0 +1,57 @@
+package ingest
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tag limits applied per log entry. Per-agent cardinality limits are
+// enforced by the store when tag values are registered.
+const (
+	maxTagsPerEntry = 10
+	maxTagKeyLen    = 32
+	maxTagValueLen  = 64
+)
+
+var tagKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]*$`)
+
+// sanitizeTags normalizes an entry's tags: keys are lower-cased and must be
+// identifiers of at most 32 characters, values are trimmed and at most 64
+// characters, and empty values are dropped. When more than 10 tags remain,
+// the first 10 keys in sorted order are kept so the result is deterministic.
+func sanitizeTags(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	clean := make(map[string]string, len(tags))
+	for k, v := range tags {
+		k = strings.ToLower(strings.TrimSpace(k))
+		v = strings.TrimSpace(v)
+		if len(k) > maxTagKeyLen || !tagKeyPattern.MatchString(k) {
+			continue
+		}
+		if v == "" || utf8.RuneCountInString(v) > maxTagValueLen || !utf8.ValidString(v) {
+			continue
+		}
+		clean[k] = v
+	}
+
+	if len(clean) > maxTagsPerEntry {
+		keys := make([]string, 0, len(clean))
+		for k := range clean {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys[maxTagsPerEntry:] {
+			delete(clean, k)
+		}
+	}
+
+	if len(clean) == 0 {
+		return nil
+	}
+	return clean
+}
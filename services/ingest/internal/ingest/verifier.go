@@ -2,71 +2,51 @@ package ingest
 
 import (
 	"context"
-	"math"
-	"math/big"
-	"strings"
+	"errors"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/GT8004/gt8004-common/webhook"
+	"github.com/GT8004/gt8004-common/x402"
 	"github.com/GT8004/gt8004-ingest/internal/store"
 )
 
-// USDC contract addresses per chain.
-var usdcContracts = map[int]common.Address{
-	1:        common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"), // Ethereum Mainnet
-	8453:     common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"), // Base Mainnet
-	84532:    common.HexToAddress("0x036CbD53842c5426634e7929541eC2318f3dCF7e"), // Base Sepolia
-	11155111: common.HexToAddress("0x1c7D4B196Cb0C7B01d743Fbc6116a902379C7238"), // Ethereum Sepolia
-}
-
-// RPC endpoints per chain.
-var chainRPCs = map[int]string{
-	1:        "https://ethereum-rpc.publicnode.com",
-	8453:     "https://base-rpc.publicnode.com",
-	84532:    "https://base-sepolia-rpc.publicnode.com",
-	11155111: "https://ethereum-sepolia-rpc.publicnode.com",
-}
-
-// ERC-20 Transfer event topic: Transfer(address,address,uint256)
-var topicTransfer = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
-
 // Verifier performs on-chain verification of x402 payment transactions.
 type Verifier struct {
-	clients map[int]*ethclient.Client
+	checker *x402.TransferChecker
 	store   *store.Store
 	logger  *zap.Logger
 }
 
 // NewVerifier creates a Verifier with ethclients for all supported chains.
 func NewVerifier(s *store.Store, logger *zap.Logger) *Verifier {
-	v := &Verifier{
-		clients: make(map[int]*ethclient.Client),
+	return &Verifier{
+		checker: x402.NewTransferChecker(x402.DefaultRPCs, logger),
 		store:   s,
 		logger:  logger,
 	}
-
-	for chainID, rpc := range chainRPCs {
-		client, err := ethclient.Dial(rpc)
-		if err != nil {
-			logger.Error("failed to connect to chain RPC",
-				zap.Int("chain_id", chainID), zap.String("rpc", rpc), zap.Error(err))
-			continue
-		}
-		v.clients[chainID] = client
-		logger.Info("verifier connected to chain RPC",
-			zap.Int("chain_id", chainID), zap.String("rpc", rpc))
-	}
-
-	return v
 }
 
 // VerifyPayment checks a tx_hash on-chain and updates the revenue entry.
-// This is called asynchronously from the enricher.
-func (v *Verifier) VerifyPayment(ctx context.Context, entryID int64, txHash string, expectedAmount float64, expectedPayer string, chainID int) {
+// This is called asynchronously from the enricher. The outcome is sent to
+// the agent's webhook subscribers as payment.verified or
+// payment.verification_failed with a reason.
+func (v *Verifier) VerifyPayment(ctx context.Context, agentDBID uuid.UUID, entryID int64, txHash string, expectedAmount float64, expectedPayer string, chainID int) {
+	payment := map[string]any{
+		"entry_id": entryID,
+		"tx_hash":  txHash,
+		"chain_id": chainID,
+		"amount":   expectedAmount,
+		"currency": "USDC",
+		"payer":    expectedPayer,
+	}
+	failed := func(reason string) {
+		payment["reason"] = reason
+		v.emit(ctx, agentDBID, webhook.EventPaymentVerificationFailed, payment)
+	}
+
 	// Reject if this tx_hash was already verified for another entry.
 	// Prevents the same on-chain transaction from being counted multiple times.
 	alreadyVerified, err := v.store.IsTxHashAlreadyVerified(ctx, txHash, entryID)
@@ -78,82 +58,32 @@ func (v *Verifier) VerifyPayment(ctx context.Context, entryID int64, txHash stri
 	if alreadyVerified {
 		v.logger.Warn("tx_hash already verified for another entry, skipping",
 			zap.String("tx_hash", txHash), zap.Int64("entry_id", entryID))
+		failed("tx_already_counted")
 		return
 	}
 
-	client, ok := v.clients[chainID]
-	if !ok {
+	_, err = v.checker.Check(ctx, chainID, txHash, x402.Expected{Amount: expectedAmount, Payer: expectedPayer})
+	switch {
+	case errors.Is(err, x402.ErrUnsupportedChain):
 		v.logger.Warn("no RPC client for chain, skipping verification",
 			zap.Int("chain_id", chainID), zap.Int64("entry_id", entryID))
+		failed("unsupported_chain")
 		return
-	}
-
-	usdcAddr, ok := usdcContracts[chainID]
-	if !ok {
-		v.logger.Warn("no USDC contract for chain, skipping verification",
-			zap.Int("chain_id", chainID), zap.Int64("entry_id", entryID))
-		return
-	}
-
-	verifyCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	hash := common.HexToHash(txHash)
-	receipt, err := client.TransactionReceipt(verifyCtx, hash)
-	if err != nil {
+	case errors.Is(err, x402.ErrReceiptUnavailable):
 		v.logger.Warn("failed to get tx receipt",
 			zap.String("tx_hash", txHash), zap.Int("chain_id", chainID), zap.Error(err))
+		failed("receipt_unavailable")
 		return
-	}
-
-	// Check transaction succeeded
-	if receipt.Status != 1 {
-		v.logger.Warn("tx failed on-chain",
-			zap.String("tx_hash", txHash), zap.Uint64("status", receipt.Status))
+	case errors.Is(err, x402.ErrTxReverted):
+		v.logger.Warn("tx failed on-chain", zap.String("tx_hash", txHash))
+		failed("tx_reverted")
 		return
-	}
-
-	// Look for USDC Transfer event in receipt logs
-	verified := false
-	for _, log := range receipt.Logs {
-		if len(log.Topics) < 3 {
-			continue
-		}
-		// Must be Transfer event from USDC contract
-		if log.Topics[0] != topicTransfer {
-			continue
-		}
-		if log.Address != usdcAddr {
-			continue
-		}
-
-		// Decode from address (topic[1])
-		from := common.BytesToAddress(log.Topics[1].Bytes())
-
-		// Decode amount from data (uint256, USDC has 6 decimals)
-		if len(log.Data) < 32 {
-			continue
-		}
-		amountBig := new(big.Int).SetBytes(log.Data[:32])
-		amountFloat := float64(amountBig.Int64()) / 1e6
-
-		// Verify payer matches (case-insensitive)
-		payerMatch := expectedPayer == "" || strings.EqualFold(from.Hex(), expectedPayer)
-
-		// Verify amount matches (allow small floating-point tolerance)
-		amountMatch := math.Abs(amountFloat-expectedAmount) < 0.001
-
-		if payerMatch && amountMatch {
-			verified = true
-			break
-		}
-	}
-
-	if !verified {
+	case err != nil:
 		v.logger.Warn("tx verification failed: no matching USDC transfer found",
 			zap.String("tx_hash", txHash),
 			zap.Float64("expected_amount", expectedAmount),
 			zap.String("expected_payer", expectedPayer))
+		failed("no_matching_transfer")
 		return
 	}
 
@@ -170,4 +100,13 @@ func (v *Verifier) VerifyPayment(ctx context.Context, entryID int64, txHash stri
 		zap.String("tx_hash", txHash),
 		zap.Int("chain_id", chainID),
 		zap.Float64("amount", expectedAmount))
+	v.emit(ctx, agentDBID, webhook.EventPaymentVerified, payment)
+}
+
+// emit queues a webhook event; failures are only logged.
+func (v *Verifier) emit(ctx context.Context, agentDBID uuid.UUID, eventType string, data any) {
+	if err := v.store.EnqueueWebhookEvent(ctx, agentDBID, eventType, data); err != nil {
+		v.logger.Error("failed to enqueue webhook event",
+			zap.String("event_type", eventType), zap.String("agent_db_id", agentDBID.String()), zap.Error(err))
+	}
 }
@@ -1,13 +1,13 @@
 package middleware
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/GT8004/gt8004-common/apikey"
+	"github.com/GT8004/gt8004-common/ratelimit"
 	"github.com/GT8004/gt8004-ingest/internal/store"
 )
 
@@ -15,9 +15,11 @@ const (
 	ContextKeyAgentDBID = "agent_db_id"
 	ContextKeyAgentID   = "agent_id"
 	ContextKeyChainID   = "chain_id"
+	ContextKeyAgentTier = ratelimit.ContextKeyTier
 )
 
-// APIKeyAuth validates the Authorization: Bearer <key> header using SHA-256 hash lookup.
+// APIKeyAuth validates the Authorization: Bearer <key> header using SHA-256
+// hash lookup. The key must carry the ingest scope.
 func APIKeyAuth(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -32,18 +34,17 @@ func APIKeyAuth(s *store.Store) gin.HandlerFunc {
 			return
 		}
 
-		hash := sha256.Sum256([]byte(parts[1]))
-		keyHash := hex.EncodeToString(hash[:])
-
-		agentAuth, err := s.ValidateAPIKey(c.Request.Context(), keyHash)
+		agentAuth, err := s.ValidateAPIKey(c.Request.Context(), apikey.Hash(parts[1]), apikey.ScopeIngest, c.ClientIP())
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+			status, msg := apikey.HTTPStatus(err)
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
 			return
 		}
 
 		c.Set(ContextKeyAgentDBID, agentAuth.AgentDBID)
 		c.Set(ContextKeyAgentID, agentAuth.AgentID)
 		c.Set(ContextKeyChainID, agentAuth.ChainID)
+		c.Set(ContextKeyAgentTier, agentAuth.Tier)
 		c.Next()
 	}
 }
@@ -5,6 +5,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/GT8004/gt8004-common/clientip"
+	"github.com/GT8004/gt8004-common/ratelimit"
+	"github.com/GT8004/gt8004-ingest/internal/config"
 	"github.com/GT8004/gt8004-ingest/internal/handler"
 	"github.com/GT8004/gt8004-ingest/internal/middleware"
 )
@@ -43,16 +46,39 @@ func securityHeaders() gin.HandlerFunc {
 	}
 }
 
-func NewRouter(h *handler.Handler) *gin.Engine {
+func NewRouter(cfg *config.Config, h *handler.Handler, limiter *ratelimit.Limiter) *gin.Engine {
 	r := gin.New()
+	clientip.Configure(r, cfg.TrustedProxies)
 	r.Use(corsMiddleware(), securityHeaders(), gin.Logger(), gin.Recovery())
 
 	// Health
 	r.GET("/healthz", h.Healthz)
 	r.GET("/readyz", h.Readyz)
 
+	// Rate limits: callers by API key or IP, and by IP regardless, before
+	// the key is looked up; then agents by tier.
+	callerLimit := limiter.Middleware(ratelimit.Policy{
+		Name:  "ingest:caller",
+		Key:   ratelimit.First(ratelimit.ByAPIKey, ratelimit.ByIP),
+		Limit: ratelimit.PerMinute(cfg.RateLimitCaller),
+	})
+	ipLimit := limiter.Middleware(ratelimit.Policy{
+		Name:  "ingest:ip",
+		Key:   ratelimit.ByIP,
+		Limit: ratelimit.PerMinute(cfg.RateLimitIP),
+	})
+	agentLimit := limiter.Middleware(ratelimit.Policy{
+		Name:  "ingest:agent",
+		Key:   ratelimit.ByAgent,
+		Limit: cfg.RateLimitAgentTiers["open"],
+		Tiers: cfg.RateLimitAgentTiers,
+	})
+
 	// SDK batch log ingestion (authenticated)
-	r.POST("/v1/ingest", middleware.APIKeyAuth(h.Store()), h.IngestLogs)
+	r.POST("/v1/ingest", ipLimit, callerLimit, middleware.APIKeyAuth(h.Store()), agentLimit, h.IngestLogs)
+
+	// Deployment markers from CI (authenticated)
+	r.POST("/v1/deployments", ipLimit, callerLimit, middleware.APIKeyAuth(h.Store()), agentLimit, h.CreateDeployment)
 
 	return r
 }
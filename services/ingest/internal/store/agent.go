@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+
+	"github.com/GT8004/gt8004-common/apikey"
 )
 
 // APIKeyAuth holds the result of API key validation.
@@ -12,27 +14,25 @@ type APIKeyAuth struct {
 	AgentDBID uuid.UUID
 	AgentID   string
 	ChainID   int
+	KeyID     uuid.UUID
+	Tier      string
 }
 
-// ValidateAPIKey looks up an API key by its SHA-256 hash and returns agent info.
-func (s *Store) ValidateAPIKey(ctx context.Context, keyHash string) (*APIKeyAuth, error) {
-	auth := &APIKeyAuth{}
-	err := s.pool.QueryRow(ctx, `
-		SELECT ak.agent_id, a.agent_id, COALESCE(a.chain_id, 0)
-		FROM api_keys ak
-		JOIN agents a ON a.id = ak.agent_id
-		WHERE ak.key_hash = $1 AND ak.revoked_at IS NULL
-	`, keyHash).Scan(&auth.AgentDBID, &auth.AgentID, &auth.ChainID)
+// ValidateAPIKey looks up an API key by its SHA-256 hash and returns agent
+// info when the key is live, allowed from clientIP and carries scope.
+// Errors wrap the apikey package errors.
+func (s *Store) ValidateAPIKey(ctx context.Context, keyHash, scope, clientIP string) (*APIKeyAuth, error) {
+	k, err := apikey.Validate(ctx, s.pool, keyHash, scope, clientIP)
 	if err != nil {
-		return nil, fmt.Errorf("validate api key: %w", err)
+		return nil, err
 	}
-
-	// Update last_used_at
-	_, _ = s.pool.Exec(ctx, `
-		UPDATE api_keys SET last_used_at = NOW() WHERE key_hash = $1
-	`, keyHash)
-
-	return auth, nil
+	return &APIKeyAuth{
+		AgentDBID: k.AgentDBID,
+		AgentID:   k.AgentID,
+		ChainID:   k.ChainID,
+		KeyID:     k.ID,
+		Tier:      k.Tier,
+	}, nil
 }
 
 // UpdateAgentStats increments request count, revenue, and recomputes avg response time.
@@ -62,13 +62,23 @@ func (s *Store) UpdateAgentTotalCustomers(ctx context.Context, agentDBID uuid.UU
 	return nil
 }
 
-// UpdateAgentSDKConnected sets the sdk_connected_at timestamp on the agents table.
-func (s *Store) UpdateAgentSDKConnected(ctx context.Context, agentDBID uuid.UUID) error {
-	_, err := s.pool.Exec(ctx, `
-		UPDATE agents SET sdk_connected_at = NOW(), updated_at = NOW() WHERE id = $1
-	`, agentDBID)
+// UpdateAgentSDKConnected sets the sdk_connected_at timestamp on the agents
+// table and clears sdk_disconnected_at. It reports whether the SDK was not
+// connected before: first ping ever, or first since it was marked
+// disconnected.
+func (s *Store) UpdateAgentSDKConnected(ctx context.Context, agentDBID uuid.UUID) (bool, error) {
+	var reconnected bool
+	err := s.pool.QueryRow(ctx, `
+		UPDATE agents a
+		SET sdk_connected_at = NOW(), sdk_disconnected_at = NULL, updated_at = NOW()
+		FROM (
+			SELECT id, sdk_connected_at, sdk_disconnected_at FROM agents WHERE id = $1 FOR UPDATE
+		) prev
+		WHERE a.id = prev.id
+		RETURNING prev.sdk_connected_at IS NULL OR prev.sdk_disconnected_at IS NOT NULL
+	`, agentDBID).Scan(&reconnected)
 	if err != nil {
-		return fmt.Errorf("update agent sdk connected: %w", err)
+		return false, fmt.Errorf("update agent sdk connected: %w", err)
 	}
-	return nil
+	return reconnected, nil
 }
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Deployment is a release marker recorded for an agent.
+type Deployment struct {
+	ID         uuid.UUID `json:"id"`
+	AgentID    uuid.UUID `json:"-"`
+	Version    string    `json:"version"`
+	CommitSHA  *string   `json:"commit,omitempty"`
+	DeployedAt time.Time `json:"deployed_at"`
+}
+
+// InsertDeployment records a deployment marker and fills in its ID.
+func (s *Store) InsertDeployment(ctx context.Context, d *Deployment) error {
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO deployments (agent_id, version, commit_sha, deployed_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, d.AgentID, d.Version, d.CommitSHA, d.DeployedAt).Scan(&d.ID)
+	if err != nil {
+		return fmt.Errorf("insert deployment: %w", err)
+	}
+	return nil
+}
@@ -9,34 +9,38 @@ import (
 )
 
 type RequestLog struct {
-	AgentID          uuid.UUID        `json:"agent_id"`
-	RequestID        string           `json:"request_id"`
-	ToolName         *string          `json:"tool_name,omitempty"`
-	Method           string           `json:"method"`
-	Path             string           `json:"path"`
-	StatusCode       int              `json:"status_code"`
-	ResponseMs       float32          `json:"response_ms"`
-	ErrorType        *string          `json:"error_type,omitempty"`
-	X402Amount       *float64         `json:"x402_amount,omitempty"`
-	X402TxHash       *string          `json:"x402_tx_hash,omitempty"`
-	X402Token        *string          `json:"x402_token,omitempty"`
-	X402Payer        *string          `json:"x402_payer,omitempty"`
-	RequestBodySize  *int             `json:"request_body_size,omitempty"`
-	ResponseBodySize *int             `json:"response_body_size,omitempty"`
-	RequestBody      *string          `json:"request_body,omitempty"`
-	ResponseBody     *string          `json:"response_body,omitempty"`
-	Headers          *json.RawMessage `json:"headers,omitempty"`
-	BatchID          string           `json:"batch_id"`
-	SDKVersion       string           `json:"sdk_version"`
-	Protocol         *string          `json:"protocol,omitempty"`
-	Source           *string          `json:"source,omitempty"`
-	IPAddress        *string          `json:"ip_address,omitempty"`
-	UserAgent        *string          `json:"user_agent,omitempty"`
-	Referer          *string          `json:"referer,omitempty"`
-	ContentType      *string          `json:"content_type,omitempty"`
-	AcceptLanguage   *string          `json:"accept_language,omitempty"`
-	Country          *string          `json:"country,omitempty"`
-	City             *string          `json:"city,omitempty"`
+	AgentID          uuid.UUID         `json:"agent_id"`
+	RequestID        string            `json:"request_id"`
+	ToolName         *string           `json:"tool_name,omitempty"`
+	Method           string            `json:"method"`
+	Path             string            `json:"path"`
+	StatusCode       int               `json:"status_code"`
+	ResponseMs       float32           `json:"response_ms"`
+	ErrorType        *string           `json:"error_type,omitempty"`
+	X402Amount       *float64          `json:"x402_amount,omitempty"`
+	X402TxHash       *string           `json:"x402_tx_hash,omitempty"`
+	X402Token        *string           `json:"x402_token,omitempty"`
+	X402Payer        *string           `json:"x402_payer,omitempty"`
+	RequestBodySize  *int              `json:"request_body_size,omitempty"`
+	ResponseBodySize *int              `json:"response_body_size,omitempty"`
+	RequestBody      *string           `json:"request_body,omitempty"`
+	ResponseBody     *string           `json:"response_body,omitempty"`
+	Headers          *json.RawMessage  `json:"headers,omitempty"`
+	BatchID          string            `json:"batch_id"`
+	SDKVersion       string            `json:"sdk_version"`
+	Protocol         *string           `json:"protocol,omitempty"`
+	Source           *string           `json:"source,omitempty"`
+	IPAddress        *string           `json:"ip_address,omitempty"`
+	UserAgent        *string           `json:"user_agent,omitempty"`
+	Referer          *string           `json:"referer,omitempty"`
+	ContentType      *string           `json:"content_type,omitempty"`
+	AcceptLanguage   *string           `json:"accept_language,omitempty"`
+	Country          *string           `json:"country,omitempty"`
+	City             *string           `json:"city,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`
+	Version          *string           `json:"version,omitempty"`
+	InputTokens      *int              `json:"input_tokens,omitempty"`
+	OutputTokens     *int              `json:"output_tokens,omitempty"`
 }
 
 // InsertRequestLogs batch-inserts request log entries for an agent.
@@ -61,8 +65,9 @@ func (s *Store) InsertRequestLogs(ctx context.Context, agentDBID uuid.UUID, entr
 				request_body, response_body, headers,
 				batch_id, sdk_version, protocol, source,
 				ip_address, user_agent, referer, content_type, accept_language,
-				country, city
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28)
+				country, city, tags, version,
+				input_tokens, output_tokens
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32)
 		`,
 			agentDBID, e.RequestID, e.ToolName, e.Method, e.Path,
 			e.StatusCode, e.ResponseMs, e.ErrorType,
@@ -71,7 +76,8 @@ func (s *Store) InsertRequestLogs(ctx context.Context, agentDBID uuid.UUID, entr
 			e.RequestBody, e.ResponseBody, e.Headers,
 			e.BatchID, e.SDKVersion, e.Protocol, e.Source,
 			e.IPAddress, e.UserAgent, e.Referer, e.ContentType, e.AcceptLanguage,
-			e.Country, e.City,
+			e.Country, e.City, tagsJSON(e.Tags), e.Version,
+			e.InputTokens, e.OutputTokens,
 		)
 		if err != nil {
 			return fmt.Errorf("insert request log: %w", err)
@@ -84,3 +90,12 @@ func (s *Store) InsertRequestLogs(ctx context.Context, agentDBID uuid.UUID, entr
 
 	return nil
 }
+
+// tagsJSON encodes tags for the JSONB column, storing NULL when empty.
+func tagsJSON(tags map[string]string) []byte {
+	if len(tags) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(tags)
+	return data
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// TagPair is one tag key/value observed on a request.
+type TagPair struct {
+	Key   string
+	Value string
+}
+
+// TagOverflowValue replaces values of a key that has reached its
+// cardinality limit.
+const TagOverflowValue = "_other"
+
+// ResolveTagValues registers newly seen tag values for an agent and returns
+// the value to store for each pair. A new key is admitted while the agent
+// has fewer than maxKeys keys; pairs of rejected keys map to "" and should be
+// dropped. A new value is admitted while its key has fewer than maxValues
+// values, otherwise it maps to TagOverflowValue. An advisory lock serializes
+// concurrent batches for the same agent so the limits hold exactly.
+func (s *Store) ResolveTagValues(ctx context.Context, agentDBID uuid.UUID, pairs []TagPair, maxKeys, maxValues int) (map[TagPair]string, error) {
+	resolved := make(map[TagPair]string, len(pairs))
+	if len(pairs) == 0 {
+		return resolved, nil
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('request_tag_values:' || $1::text))`, agentDBID); err != nil {
+		return nil, fmt.Errorf("lock tag values: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT key, value FROM request_tag_values WHERE agent_id = $1
+	`, agentDBID)
+	if err != nil {
+		return nil, fmt.Errorf("get tag values: %w", err)
+	}
+	known := make(map[TagPair]bool)
+	valuesPerKey := make(map[string]int)
+	for rows.Next() {
+		var p TagPair
+		if err := rows.Scan(&p.Key, &p.Value); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan tag value: %w", err)
+		}
+		known[p] = true
+		valuesPerKey[p.Key]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get tag values: %w", err)
+	}
+
+	var newKeys, newValues []string
+	for _, p := range pairs {
+		if _, done := resolved[p]; done {
+			continue
+		}
+		if known[p] {
+			resolved[p] = p.Value
+			continue
+		}
+		n, keyExists := valuesPerKey[p.Key]
+		switch {
+		case !keyExists && len(valuesPerKey) >= maxKeys:
+			resolved[p] = ""
+		case n >= maxValues:
+			resolved[p] = TagOverflowValue
+		default:
+			valuesPerKey[p.Key] = n + 1
+			known[p] = true
+			resolved[p] = p.Value
+			newKeys = append(newKeys, p.Key)
+			newValues = append(newValues, p.Value)
+		}
+	}
+
+	if len(newKeys) > 0 {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO request_tag_values (agent_id, key, value)
+			SELECT $1, k, v FROM unnest($2::text[], $3::text[]) AS t(k, v)
+			ON CONFLICT DO NOTHING
+		`, agentDBID, newKeys, newValues); err != nil {
+			return nil, fmt.Errorf("insert tag values: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	return resolved, nil
+}
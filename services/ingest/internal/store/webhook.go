@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// EnqueueWebhookEvent records an event for an agent and queues a delivery
+// to each active subscription to its type, whether scoped to the agent or
+// to its owner wallet. The registry delivers them. Events nobody
+// subscribes to are not stored.
+func (s *Store) EnqueueWebhookEvent(ctx context.Context, agentDBID uuid.UUID, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal webhook payload: %w", err)
+	}
+	_, err = s.pool.Exec(ctx, `
+		WITH subs AS (
+			SELECT id FROM webhook_subscriptions
+			WHERE active AND $2 = ANY(events)
+			  AND (agent_id = $1
+			    OR wallet_address = (SELECT LOWER(evm_address) FROM agents WHERE id = $1))
+		), ev AS (
+			INSERT INTO webhook_events (agent_id, event_type, payload)
+			SELECT $1, $2, $3 WHERE EXISTS (SELECT 1 FROM subs)
+			RETURNING id
+		)
+		INSERT INTO webhook_deliveries (subscription_id, event_id)
+		SELECT subs.id, ev.id FROM subs, ev
+	`, agentDBID, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("enqueue webhook event: %w", err)
+	}
+	return nil
+}
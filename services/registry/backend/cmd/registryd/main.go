@@ -13,13 +13,19 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/GT8004/gt8004-common/identity"
+	"github.com/GT8004/gt8004-common/ratelimit"
+	"github.com/GT8004/gt8004-common/session"
 	"github.com/GT8004/gt8004-common/ws"
+	"github.com/GT8004/gt8004-common/x402"
+	"github.com/GT8004/gt8004/internal/billing"
 	"github.com/GT8004/gt8004/internal/cache"
 	"github.com/GT8004/gt8004/internal/config"
 	"github.com/GT8004/gt8004/internal/erc8004"
 	"github.com/GT8004/gt8004/internal/handler"
+	"github.com/GT8004/gt8004/internal/ownership"
 	"github.com/GT8004/gt8004/internal/server"
 	"github.com/GT8004/gt8004/internal/store"
+	"github.com/GT8004/gt8004/internal/webhook"
 )
 
 func main() {
@@ -54,10 +60,44 @@ func main() {
 		defer redisCache.Close()
 	}
 
+	// Rate limits (shared through Redis, per instance without it)
+	limiter, err := ratelimit.New(cfg.RedisURL, logger)
+	if err != nil {
+		logger.Fatal("failed to configure rate limits", zap.Error(err))
+	}
+	defer limiter.Close()
+
 	// === Shared components ===
 
 	// ERC-8004 identity verifier (backed by PostgreSQL for multi-instance safety)
 	idVerifier := identity.NewVerifierWithStore(cfg.IdentityRegistryAddr, cfg.IdentityRegistryRPC, db, logger)
+	if err := idVerifier.EnableSmartWallets(identity.SmartWalletConfig{
+		RPCs:           cfg.SmartWalletRPCs,
+		DefaultChainID: cfg.SmartWalletChainID,
+	}); err != nil {
+		logger.Fatal("failed to configure smart wallet verification", zap.Error(err))
+	}
+	registryChains := make([]identity.RegistryChain, 0, len(config.SupportedNetworks))
+	for _, nc := range config.SupportedNetworks {
+		registryChains = append(registryChains, identity.RegistryChain{
+			ChainID:        nc.ChainID,
+			RegistryAddr:   nc.RegistryAddr,
+			ReputationAddr: nc.ReputationAddr,
+			RPC:            nc.RegistryRPC,
+		})
+	}
+	if err := idVerifier.ConfigureRegistries(registryChains, cfg.IdentityRegistryCacheTTL); err != nil {
+		logger.Fatal("failed to configure identity registry lookups", zap.Error(err))
+	}
+
+	// Wallet session tokens (Sign-In With Ethereum)
+	sessionSigner, err := session.NewSigner(cfg.SessionSecret)
+	if err != nil {
+		logger.Fatal("invalid session secret", zap.Error(err))
+	}
+	if !sessionSigner.Enabled() {
+		logger.Warn("SESSION_SECRET not set, wallet sessions disabled")
+	}
 
 	// WebSocket hub for real-time events (from common)
 	hub := ws.NewHub(logger)
@@ -75,6 +115,37 @@ func main() {
 	}
 	erc8004Registry := erc8004.NewRegistry(networkConfigs, logger)
 
+	// Outbound webhook delivery (background job)
+	webhookWorker := webhook.NewWorker(db, webhook.Config{
+		SDKDisconnectAfter:   cfg.WebhookSDKDisconnectAfter,
+		AllowPrivateNetworks: cfg.WebhookAllowPrivate,
+	}, logger)
+	webhookWorker.Start()
+
+	// ERC-8004 transfer watcher (background job)
+	var ownershipWatcher *ownership.Watcher
+	if cfg.OwnershipWatchInterval > 0 {
+		ownershipWatcher = ownership.NewWatcher(db, erc8004Registry, webhookWorker, ownership.Config{
+			Interval:      cfg.OwnershipWatchInterval,
+			Confirmations: cfg.OwnershipWatchConfirmations,
+		}, logger)
+		ownershipWatcher.Start()
+	}
+
+	// x402 tier payments are checked the same way ingest verifies revenue.
+	// Periods only expire once they can be renewed.
+	var tierChecker *x402.TransferChecker
+	var tierExpirer *billing.Expirer
+	if cfg.TierPaymentRecipient != "" {
+		tierChecker = x402.NewTransferChecker(map[int]string{
+			cfg.TierPaymentChainID: x402.DefaultRPCs[cfg.TierPaymentChainID],
+		}, logger)
+		tierExpirer = billing.NewExpirer(db, webhookWorker, logger)
+		tierExpirer.Start()
+	} else {
+		logger.Warn("TIER_PAYMENT_RECIPIENT not set, paid tier upgrades and expiry disabled")
+	}
+
 	// === Registry handler and server ===
 
 	h := handler.New(
@@ -84,8 +155,26 @@ func main() {
 		logger,
 		cfg.DiscoveryURL,
 		cfg.InternalSecret,
+		handler.SessionConfig{
+			Signer:            sessionSigner,
+			AccessTTL:         cfg.SessionAccessTTL,
+			RefreshTTL:        cfg.SessionRefreshTTL,
+			SIWEDomains:       cfg.SIWEDomains,
+			WalletHeaderUntil: cfg.WalletHeaderAuthUntil,
+		},
+		handler.WebhookConfig{
+			Notifier:  webhookWorker,
+			AllowHTTP: cfg.WebhookAllowPrivate,
+		},
+		handler.TierConfig{
+			Checker: tierChecker,
+			PayTo:   cfg.TierPaymentRecipient,
+			ChainID: cfg.TierPaymentChainID,
+			Prices:  map[string]float64{"lite": cfg.TierLitePriceUSDC},
+			Period:  cfg.TierPeriod,
+		},
 	)
-	srv := server.New(cfg, h, logger)
+	srv := server.New(cfg, h, limiter, logger)
 
 	// Metrics server
 	metricsMux := http.NewServeMux()
@@ -123,6 +212,13 @@ func main() {
 
 	srv.Shutdown(shutdownCtx)
 	metricsServer.Shutdown(shutdownCtx)
+	if ownershipWatcher != nil {
+		ownershipWatcher.Stop()
+	}
+	if tierExpirer != nil {
+		tierExpirer.Stop()
+	}
+	webhookWorker.Stop()
 
 	logger.Info("Registry service stopped")
 }
@@ -0,0 +1,114 @@
+// Package billing moves agents back to the open tier when their paid
+// period ends.
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/webhook"
+	"github.com/GT8004/gt8004/internal/store"
+)
+
+// checkInterval is how often expired periods are looked for.
+const checkInterval = time.Minute
+
+// Notifier is woken after tier.changed events are queued.
+type Notifier interface {
+	Notify()
+}
+
+// Expirer downgrades agents whose paid tier has no period covering now.
+type Expirer struct {
+	store    *store.Store
+	notifier Notifier
+	logger   *zap.Logger
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewExpirer creates a tier expirer.
+func NewExpirer(s *store.Store, notifier Notifier, logger *zap.Logger) *Expirer {
+	return &Expirer{
+		store:    s,
+		notifier: notifier,
+		logger:   logger,
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Start begins checking in a background goroutine.
+func (e *Expirer) Start() {
+	go func() {
+		defer close(e.doneCh)
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		e.logger.Info("tier expirer started")
+
+		for {
+			e.expire()
+
+			select {
+			case <-ticker.C:
+			case <-e.stopCh:
+				e.logger.Info("tier expirer stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop signals the expirer to stop after the check in flight.
+func (e *Expirer) Stop() {
+	close(e.stopCh)
+	<-e.doneCh
+}
+
+func (e *Expirer) expire() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	expired, err := e.store.ExpireTiers(ctx)
+	if err != nil {
+		e.logger.Error("failed to expire tiers", zap.Error(err))
+		return
+	}
+
+	for _, x := range expired {
+		e.logger.Info("agent tier expired",
+			zap.String("agent_id", x.AgentID), zap.String("tier", x.Tier))
+
+		before, _ := json.Marshal(map[string]any{"tier": x.Tier})
+		after, _ := json.Marshal(map[string]any{"tier": "open"})
+		if err := e.store.AppendAudit(ctx, &store.AuditEntry{
+			Chain:      "agent:" + x.AgentID,
+			ActorType:  "system",
+			Actor:      "tier-expirer",
+			Action:     "agent.tier_expired",
+			TargetType: "agent",
+			TargetID:   x.AgentID,
+			Before:     before,
+			After:      after,
+		}); err != nil {
+			e.logger.Error("failed to append audit entry",
+				zap.Error(err), zap.String("agent_id", x.AgentID), zap.String("action", "agent.tier_expired"))
+		}
+
+		if err := e.store.EnqueueWebhookEvent(ctx, store.WebhookEvent{
+			AgentDBID: &x.AgentDBID,
+			Type:      webhook.EventTierChanged,
+			Data:      map[string]any{"from": x.Tier, "to": "open", "reason": "expired"},
+		}); err != nil {
+			e.logger.Error("failed to enqueue tier.changed",
+				zap.String("agent_id", x.AgentID), zap.Error(err))
+		}
+	}
+	if len(expired) > 0 && e.notifier != nil {
+		e.notifier.Notify()
+	}
+}
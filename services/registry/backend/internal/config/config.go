@@ -1,9 +1,16 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/GT8004/gt8004-common/clientip"
+	"github.com/GT8004/gt8004-common/ratelimit"
 )
 
 // NetworkConfig holds ERC-8004 registry info for a specific chain.
@@ -47,6 +54,10 @@ type Config struct {
 	IdentityRegistryAddr string `mapstructure:"IDENTITY_REGISTRY_ADDRESS"`
 	IdentityRegistryRPC  string `mapstructure:"IDENTITY_REGISTRY_RPC"`
 
+	// How long an address's on-chain registrations are cached after a
+	// signature is verified.
+	IdentityRegistryCacheTTL time.Duration `mapstructure:"IDENTITY_REGISTRY_CACHE_TTL"`
+
 	// Redis
 	RedisURL string `mapstructure:"REDIS_URL"`
 
@@ -59,6 +70,55 @@ type Config struct {
 
 	// Discovery service URL for single-token sync trigger
 	DiscoveryURL string `mapstructure:"DISCOVERY_URL"`
+
+	// Wallet sessions (Sign-In With Ethereum). SessionSecret is shared with
+	// analytics and the API gateway; sessions are disabled when empty.
+	SessionSecret     string        `mapstructure:"SESSION_SECRET"`
+	SessionAccessTTL  time.Duration `mapstructure:"SESSION_ACCESS_TTL"`
+	SessionRefreshTTL time.Duration `mapstructure:"SESSION_REFRESH_TTL"`
+	SIWEDomains       []string      `mapstructure:"SIWE_DOMAINS"`
+
+	// The raw X-Wallet-Address header is accepted until this time; unset
+	// rejects it.
+	WalletHeaderAuthUntil time.Time `mapstructure:"WALLET_HEADER_AUTH_UNTIL"`
+
+	// Smart-contract wallet signatures (EIP-1271/6492). SmartWalletRPCs
+	// overrides the RPC used per chain ID; chains without an override use
+	// their SupportedNetworks RPC.
+	SmartWalletRPCs    map[int]string `mapstructure:"SMART_WALLET_RPC_URLS"`
+	SmartWalletChainID int            `mapstructure:"SMART_WALLET_CHAIN_ID"`
+
+	// Outbound webhooks. WebhookAllowPrivate accepts http:// endpoints and
+	// endpoints on private networks (local development only).
+	WebhookSDKDisconnectAfter time.Duration `mapstructure:"WEBHOOK_SDK_DISCONNECT_AFTER"`
+	WebhookAllowPrivate       bool          `mapstructure:"WEBHOOK_ALLOW_PRIVATE"`
+
+	// ERC-8004 transfer watcher. Agents linked to a token follow it to its
+	// new owner; a zero interval disables the watcher.
+	OwnershipWatchInterval      time.Duration `mapstructure:"OWNERSHIP_WATCH_INTERVAL"`
+	OwnershipWatchConfirmations uint64        `mapstructure:"OWNERSHIP_WATCH_CONFIRMATIONS"`
+
+	// Paid tiers. Upgrades are paid over x402 in USDC to TierPaymentRecipient
+	// on TierPaymentChainID; paid upgrades are disabled when it is empty.
+	TierPaymentRecipient string        `mapstructure:"TIER_PAYMENT_RECIPIENT"`
+	TierPaymentChainID   int           `mapstructure:"TIER_PAYMENT_CHAIN_ID"`
+	TierLitePriceUSDC    float64       `mapstructure:"TIER_LITE_PRICE_USDC"`
+	TierPeriod           time.Duration `mapstructure:"TIER_PERIOD_DAYS"`
+
+	// Rate limits, per minute and shared across instances through Redis.
+	// RateLimitAuth applies per IP to sign-in and invitation acceptance,
+	// RateLimitCaller per API key, session wallet or IP, RateLimitIP per IP
+	// whatever the caller presents, and RateLimitAgentTiers per
+	// authenticated agent by its tier. Zero disables a limit.
+	RateLimitAuth       int                        `mapstructure:"RATE_LIMIT_AUTH_PER_MIN"`
+	RateLimitCaller     int                        `mapstructure:"RATE_LIMIT_CALLER_PER_MIN"`
+	RateLimitIP         int                        `mapstructure:"RATE_LIMIT_IP_PER_MIN"`
+	RateLimitAgentTiers map[string]ratelimit.Limit `mapstructure:"-"`
+
+	// TrustedProxies are the proxies (addresses or CIDR ranges) whose
+	// X-Forwarded-For entries are believed when reading the client IP for
+	// API key allowlists and per-IP rate limits.
+	TrustedProxies []string `mapstructure:"-"`
 }
 
 func Load() (*Config, error) {
@@ -70,10 +130,28 @@ func Load() (*Config, error) {
 
 	if os.Getenv("NETWORK_MODE") == "mainnet" {
 		viper.SetDefault("IDENTITY_REGISTRY_RPC", "https://ethereum-rpc.publicnode.com")
+		viper.SetDefault("SMART_WALLET_CHAIN_ID", 8453)
+		viper.SetDefault("TIER_PAYMENT_CHAIN_ID", 8453)
 	} else {
 		viper.SetDefault("IDENTITY_REGISTRY_RPC", "https://sepolia.base.org")
+		viper.SetDefault("SMART_WALLET_CHAIN_ID", 84532)
+		viper.SetDefault("TIER_PAYMENT_CHAIN_ID", 84532)
 	}
 	viper.SetDefault("IDENTITY_REGISTRY_ADDRESS", "0x8004A169FB4a3325136EB29fA0ceB6D2e539a432")
+	viper.SetDefault("IDENTITY_REGISTRY_CACHE_TTL", 300)
+	viper.SetDefault("SESSION_ACCESS_TTL", 900)
+	viper.SetDefault("SESSION_REFRESH_TTL", 2592000)
+	viper.SetDefault("SIWE_DOMAINS", "gt8004.xyz,www.gt8004.xyz,localhost:3000")
+	viper.SetDefault("WEBHOOK_SDK_DISCONNECT_AFTER", 900)
+	viper.SetDefault("OWNERSHIP_WATCH_INTERVAL", 60)
+	viper.SetDefault("OWNERSHIP_WATCH_CONFIRMATIONS", 3)
+	viper.SetDefault("TIER_LITE_PRICE_USDC", 10)
+	viper.SetDefault("TIER_PERIOD_DAYS", 30)
+	viper.SetDefault("RATE_LIMIT_AUTH_PER_MIN", 20)
+	viper.SetDefault("RATE_LIMIT_CALLER_PER_MIN", 600)
+	viper.SetDefault("RATE_LIMIT_IP_PER_MIN", 1200)
+	viper.SetDefault("RATE_LIMIT_AGENT_TIERS", "open=300,lite=1200")
+	viper.SetDefault("TRUSTED_PROXIES", clientip.CloudRun)
 
 	cfg := &Config{}
 	cfg.Port = viper.GetInt("PORT")
@@ -82,11 +160,66 @@ func Load() (*Config, error) {
 	cfg.DatabaseURL = viper.GetString("DATABASE_URL")
 	cfg.IdentityRegistryAddr = viper.GetString("IDENTITY_REGISTRY_ADDRESS")
 	cfg.IdentityRegistryRPC = viper.GetString("IDENTITY_REGISTRY_RPC")
+	cfg.IdentityRegistryCacheTTL = time.Duration(viper.GetInt("IDENTITY_REGISTRY_CACHE_TTL")) * time.Second
 	cfg.RedisURL = viper.GetString("REDIS_URL")
 	cfg.GT8004TokenID = viper.GetInt64("GT8004_TOKEN_ID")
 	cfg.GT8004AgentURI = viper.GetString("GT8004_AGENT_URI")
 	cfg.InternalSecret = viper.GetString("INTERNAL_SECRET")
 	cfg.DiscoveryURL = viper.GetString("DISCOVERY_URL")
+	cfg.SessionSecret = viper.GetString("SESSION_SECRET")
+	cfg.SessionAccessTTL = time.Duration(viper.GetInt("SESSION_ACCESS_TTL")) * time.Second
+	cfg.SessionRefreshTTL = time.Duration(viper.GetInt("SESSION_REFRESH_TTL")) * time.Second
+	for _, d := range strings.Split(viper.GetString("SIWE_DOMAINS"), ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			cfg.SIWEDomains = append(cfg.SIWEDomains, d)
+		}
+	}
+	if until := viper.GetString("WALLET_HEADER_AUTH_UNTIL"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WALLET_HEADER_AUTH_UNTIL: %w", err)
+		}
+		cfg.WalletHeaderAuthUntil = t
+	}
+	cfg.SmartWalletChainID = viper.GetInt("SMART_WALLET_CHAIN_ID")
+	cfg.WebhookSDKDisconnectAfter = time.Duration(viper.GetInt("WEBHOOK_SDK_DISCONNECT_AFTER")) * time.Second
+	cfg.WebhookAllowPrivate = viper.GetBool("WEBHOOK_ALLOW_PRIVATE")
+	cfg.OwnershipWatchInterval = time.Duration(viper.GetInt("OWNERSHIP_WATCH_INTERVAL")) * time.Second
+	cfg.OwnershipWatchConfirmations = viper.GetUint64("OWNERSHIP_WATCH_CONFIRMATIONS")
+	cfg.TierPaymentRecipient = viper.GetString("TIER_PAYMENT_RECIPIENT")
+	cfg.TierPaymentChainID = viper.GetInt("TIER_PAYMENT_CHAIN_ID")
+	cfg.TierLitePriceUSDC = viper.GetFloat64("TIER_LITE_PRICE_USDC")
+	cfg.TierPeriod = time.Duration(viper.GetInt("TIER_PERIOD_DAYS")) * 24 * time.Hour
+	cfg.RateLimitAuth = viper.GetInt("RATE_LIMIT_AUTH_PER_MIN")
+	cfg.RateLimitCaller = viper.GetInt("RATE_LIMIT_CALLER_PER_MIN")
+	cfg.RateLimitIP = viper.GetInt("RATE_LIMIT_IP_PER_MIN")
+	// RATE_LIMIT_AGENT_TIERS is "tier=requests,tier=requests".
+	agentTiers, err := ratelimit.ParseTiers(viper.GetString("RATE_LIMIT_AGENT_TIERS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_AGENT_TIERS: %w", err)
+	}
+	cfg.RateLimitAgentTiers = agentTiers
+	trustedProxies, err := clientip.ParseProxies(viper.GetString("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	cfg.TrustedProxies = trustedProxies
+	cfg.SmartWalletRPCs = map[int]string{}
+	for chainID, nc := range SupportedNetworks {
+		cfg.SmartWalletRPCs[chainID] = nc.RegistryRPC
+	}
+	// SMART_WALLET_RPC_URLS is "chainID=url,chainID=url".
+	for _, pair := range strings.Split(viper.GetString("SMART_WALLET_RPC_URLS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		id, url, ok := strings.Cut(pair, "=")
+		chainID, err := strconv.Atoi(strings.TrimSpace(id))
+		if !ok || err != nil || chainID <= 0 {
+			return nil, fmt.Errorf("invalid SMART_WALLET_RPC_URLS entry %q", pair)
+		}
+		cfg.SmartWalletRPCs[chainID] = strings.TrimSpace(url)
+	}
 
 	return cfg, nil
 }
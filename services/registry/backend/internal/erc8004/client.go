@@ -463,6 +463,75 @@ func (c *Client) filterMintLogs(ctx context.Context, fromBlock, toBlock *big.Int
 	return events, nil
 }
 
+// TransferEvent is a Transfer log that moves an existing token between two
+// wallets. Addresses are lowercase hex.
+type TransferEvent struct {
+	TokenID     int64
+	From        string
+	To          string
+	BlockNumber uint64
+	TxHash      string
+	LogIndex    uint
+}
+
+// Connected reports whether the client has an RPC connection.
+func (c *Client) Connected() bool { return c.ethClient != nil }
+
+// BlockNumber returns the latest block number.
+func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	if c.ethClient == nil {
+		return 0, fmt.Errorf("ethclient not initialised")
+	}
+	return c.ethClient.BlockNumber(ctx)
+}
+
+// FilterTransfers returns the Transfer events between fromBlock and toBlock
+// (inclusive) in log order. Mints and burns are skipped, as are token IDs
+// that do not fit in an int64, which no agent can be linked to.
+func (c *Client) FilterTransfers(ctx context.Context, fromBlock, toBlock uint64) ([]TransferEvent, error) {
+	if c.ethClient == nil {
+		return nil, fmt.Errorf("ethclient not initialised")
+	}
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
+		Addresses: []common.Address{c.contractAddr},
+		Topics:    [][]common.Hash{{topicTransfer}},
+	}
+	logs, err := c.ethClient.FilterLogs(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("filter transfer logs: %w", err)
+	}
+
+	var zero common.Address
+	events := make([]TransferEvent, 0, len(logs))
+	for _, log := range logs {
+		if len(log.Topics) < 4 || log.Removed {
+			continue
+		}
+		from := common.BytesToAddress(log.Topics[1].Bytes())
+		to := common.BytesToAddress(log.Topics[2].Bytes())
+		if from == zero || to == zero {
+			continue
+		}
+		tokenID := new(big.Int).SetBytes(log.Topics[3].Bytes())
+		if !tokenID.IsInt64() {
+			c.logger.Warn("skipping transfer of out-of-range token id",
+				zap.String("token_id", tokenID.String()), zap.String("tx_hash", log.TxHash.Hex()))
+			continue
+		}
+		events = append(events, TransferEvent{
+			TokenID:     tokenID.Int64(),
+			From:        strings.ToLower(from.Hex()),
+			To:          strings.ToLower(to.Hex()),
+			BlockNumber: log.BlockNumber,
+			TxHash:      log.TxHash.Hex(),
+			LogIndex:    log.Index,
+		})
+	}
+	return events, nil
+}
+
 // ChainID returns the chain ID this client is connected to.
 func (c *Client) ChainID() int { return c.chainID }
 
@@ -70,7 +70,7 @@ func (h *Handler) RegisterAgent(c *gin.Context) {
 	}
 
 	// Generate API key
-	rawKey, err := h.store.CreateAPIKey(c.Request.Context(), agent.ID)
+	rawKey, err := h.store.CreateAPIKey(c.Request.Context(), agent.ID, "default")
 	if err != nil {
 		h.logger.Error("failed to create api key", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate api key"})
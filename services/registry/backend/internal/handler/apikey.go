@@ -1,15 +1,34 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/apikey"
+	"github.com/GT8004/gt8004-common/org"
+	"github.com/GT8004/gt8004/internal/store"
+)
+
+const (
+	// maxActiveAPIKeys caps the live keys an agent can hold.
+	maxActiveAPIKeys = 20
+
+	// defaultRotationGrace is how long a rotated key keeps working when the
+	// request does not say.
+	defaultRotationGrace = 24 * time.Hour
+	maxRotationGrace     = 30 * 24 * time.Hour
 )
 
-// GetAPIKey returns the current active API key for the agent.
-func (h *Handler) GetAPIKey(c *gin.Context) {
+// ListAPIKeys handles GET /v1/agents/:agent_id/api-keys
+// Returns key metadata only; raw keys are shown once, on creation.
+func (h *Handler) ListAPIKeys(c *gin.Context) {
 	agentDBID, exists := c.Get("agent_db_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -17,17 +36,248 @@ func (h *Handler) GetAPIKey(c *gin.Context) {
 	}
 	dbID := agentDBID.(uuid.UUID)
 
-	rawKey, err := h.store.GetActiveAPIKey(c.Request.Context(), dbID)
+	keys, err := h.store.ListAPIKeys(c.Request.Context(), dbID)
 	if err != nil {
-		h.logger.Error("failed to get api key", zap.Error(err))
-		c.JSON(http.StatusNotFound, gin.H{"error": "no active api key found"})
+		h.logger.Error("failed to list api keys", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list api keys"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"api_key": rawKey})
+	c.JSON(http.StatusOK, gin.H{"keys": keys, "scopes": apikey.AllScopes})
+}
+
+type createAPIKeyRequest struct {
+	Name        string     `json:"name" binding:"required,max=64"`
+	Scopes      []string   `json:"scopes"`
+	IPAllowlist []string   `json:"ip_allowlist" binding:"max=50"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// keyIssuer returns the role and wallet a new key is issued under: the
+// session wallet's, or for API key auth those the authenticating key was
+// issued under.
+func keyIssuer(c *gin.Context) (org.Role, string) {
+	role, _ := c.Get("member_role")
+	r, _ := role.(org.Role)
+	wallet := c.GetString("wallet_address")
+	if wallet == "" {
+		wallet = c.GetString("api_key_created_by")
+	}
+	return r, wallet
+}
+
+// withinScopes reports whether every scope in scopes is in allowed.
+func withinScopes(scopes, allowed []string) bool {
+	for _, s := range scopes {
+		found := false
+		for _, a := range allowed {
+			if s == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateAPIKey handles POST /v1/agents/:agent_id/api-keys
+// Creates a named key bound to the caller's role. Scopes default to all the
+// role may hold; expires_at and ip_allowlist (addresses or CIDR ranges) are
+// optional.
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	agentDBID, exists := c.Get("agent_db_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	dbID := agentDBID.(uuid.UUID)
+
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	role, issuer := keyIssuer(c)
+	allowed := role.KeyScopes()
+	if len(allowed) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "role cannot hold api keys", "role": role})
+		return
+	}
+	scopes := allowed
+	if len(req.Scopes) > 0 {
+		normalized, err := apikey.NormalizeScopes(req.Scopes)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !withinScopes(normalized, allowed) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "scopes exceed role", "role": role, "allowed": allowed})
+			return
+		}
+		scopes = normalized
+	}
+	allowlist, err := apikey.NormalizeAllowlist(req.IPAllowlist)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	n, err := h.store.CountActiveAPIKeys(c.Request.Context(), dbID)
+	if err != nil {
+		h.logger.Error("failed to count api keys", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create api key"})
+		return
+	}
+	if n >= maxActiveAPIKeys {
+		c.JSON(http.StatusConflict, gin.H{"error": "too many active api keys; revoke one first"})
+		return
+	}
+
+	key, rawKey, err := h.store.CreateScopedAPIKey(c.Request.Context(), dbID, store.NewAPIKey{
+		Name:        name,
+		Scopes:      scopes,
+		IPAllowlist: allowlist,
+		ExpiresAt:   req.ExpiresAt,
+		Role:        role,
+		CreatedBy:   issuer,
+	})
+	if err != nil {
+		h.logger.Error("failed to create api key", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create api key"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      agentChain(c.GetString("agent_id")),
+		Action:     "api_key.created",
+		TargetType: "api_key",
+		TargetID:   key.ID.String(),
+		After:      gin.H{"name": key.Name, "scopes": key.Scopes, "role": key.Role, "ip_allowlist": key.IPAllowlist, "expires_at": key.ExpiresAt},
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"key": key, "api_key": rawKey})
+}
+
+// RotateAPIKey handles POST /v1/agents/:agent_id/api-keys/:key_id/rotate
+// Issues a replacement key. The old key keeps working for
+// grace_period_seconds (default 24h, 0 revokes it now) so instances can
+// switch over one at a time.
+func (h *Handler) RotateAPIKey(c *gin.Context) {
+	agentDBID, exists := c.Get("agent_db_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	dbID := agentDBID.(uuid.UUID)
+
+	keyID, err := uuid.Parse(c.Param("key_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid key_id"})
+		return
+	}
+	var req struct {
+		GracePeriodSeconds *int64 `json:"grace_period_seconds"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+	grace := defaultRotationGrace
+	if req.GracePeriodSeconds != nil {
+		grace = time.Duration(*req.GracePeriodSeconds) * time.Second
+		if grace < 0 || grace > maxRotationGrace {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "grace_period_seconds must be between 0 and 2592000"})
+			return
+		}
+	}
+
+	role, _ := keyIssuer(c)
+	key, rawKey, err := h.store.RotateAPIKey(c.Request.Context(), dbID, keyID, grace, role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+			return
+		}
+		if errors.Is(err, store.ErrKeyOutranksIssuer) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "api key was issued by a higher role", "role": role})
+			return
+		}
+		h.logger.Error("failed to rotate api key", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rotate api key"})
+		return
+	}
+
+	previousExpiresAt := time.Now().Add(grace)
+	h.audit(c, auditEvent{
+		Chain:      agentChain(c.GetString("agent_id")),
+		Action:     "api_key.rotated",
+		TargetType: "api_key",
+		TargetID:   keyID.String(),
+		Before:     gin.H{"key_id": keyID},
+		After:      gin.H{"key_id": key.ID, "previous_expires_at": previousExpiresAt},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"key":                 key,
+		"api_key":             rawKey,
+		"previous_key_id":     keyID,
+		"previous_expires_at": previousExpiresAt,
+	})
+}
+
+// RevokeAPIKey handles DELETE /v1/agents/:agent_id/api-keys/:key_id
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	agentDBID, exists := c.Get("agent_db_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	dbID := agentDBID.(uuid.UUID)
+
+	keyID, err := uuid.Parse(c.Param("key_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid key_id"})
+		return
+	}
+	if err := h.store.RevokeAPIKey(c.Request.Context(), dbID, keyID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+			return
+		}
+		h.logger.Error("failed to revoke api key", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke api key"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      agentChain(c.GetString("agent_id")),
+		Action:     "api_key.revoked",
+		TargetType: "api_key",
+		TargetID:   keyID.String(),
+		Before:     gin.H{"revoked": false},
+		After:      gin.H{"revoked": true},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
 }
 
-// RegenerateAPIKey revokes all existing keys and issues a new one.
+// RegenerateAPIKey revokes all existing keys and issues a new one with every
+// scope the caller's role may hold. Prefer RotateAPIKey, which keeps the old
+// key working meanwhile.
 func (h *Handler) RegenerateAPIKey(c *gin.Context) {
 	agentDBID, exists := c.Get("agent_db_id")
 	if !exists {
@@ -36,6 +286,13 @@ func (h *Handler) RegenerateAPIKey(c *gin.Context) {
 	}
 	dbID := agentDBID.(uuid.UUID)
 
+	role, issuer := keyIssuer(c)
+	scopes := role.KeyScopes()
+	if len(scopes) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "role cannot hold api keys", "role": role})
+		return
+	}
+
 	// Revoke existing keys
 	if err := h.store.RevokeAPIKeys(c.Request.Context(), dbID); err != nil {
 		h.logger.Error("failed to revoke api keys", zap.Error(err))
@@ -44,12 +301,26 @@ func (h *Handler) RegenerateAPIKey(c *gin.Context) {
 	}
 
 	// Create new key
-	rawKey, err := h.store.CreateAPIKey(c.Request.Context(), dbID)
+	_, rawKey, err := h.store.CreateScopedAPIKey(c.Request.Context(), dbID, store.NewAPIKey{
+		Name:      "default",
+		Scopes:    scopes,
+		Role:      role,
+		CreatedBy: issuer,
+	})
 	if err != nil {
 		h.logger.Error("failed to create api key", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate api key"})
 		return
 	}
 
+	agentID := c.GetString("agent_id")
+	h.audit(c, auditEvent{
+		Chain:      agentChain(agentID),
+		Action:     "api_key.regenerated",
+		TargetType: "agent",
+		TargetID:   agentID,
+		After:      gin.H{"revoked_all": true, "name": "default", "role": role},
+	})
+
 	c.JSON(http.StatusOK, gin.H{"api_key": rawKey})
 }
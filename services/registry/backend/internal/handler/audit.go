@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/org"
+	"github.com/GT8004/gt8004/internal/store"
+)
+
+// Audit chains. Each agent, organization and wallet has its own hash chain
+// so its owner can verify it; operator actions go to the system chain.
+func agentChain(agentID string) string  { return "agent:" + agentID }
+func orgChain(orgID uuid.UUID) string   { return "org:" + orgID.String() }
+func walletChain(address string) string { return "wallet:" + strings.ToLower(address) }
+func systemChain() string               { return "system" }
+
+// auditEvent describes a mutation to record. Actor defaults to the API key,
+// wallet or internal caller that authenticated the request.
+type auditEvent struct {
+	Chain      string
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     any
+	After      any
+	Actor      string
+}
+
+// audit appends an event to the audit log. It runs after the change is
+// made; a failure is logged and does not fail the request.
+func (h *Handler) audit(c *gin.Context, ev auditEvent) {
+	e := &store.AuditEntry{
+		Chain:      ev.Chain,
+		Action:     ev.Action,
+		TargetType: ev.TargetType,
+		TargetID:   ev.TargetID,
+		Before:     marshalAudit(ev.Before),
+		After:      marshalAudit(ev.After),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	switch {
+	case ev.Actor != "":
+		e.ActorType, e.Actor = "wallet", strings.ToLower(ev.Actor)
+	case c.GetString("api_key_id") != "":
+		e.ActorType, e.Actor = "api_key", c.GetString("api_key_id")
+	case c.GetString("wallet_address") != "":
+		e.ActorType, e.Actor = "wallet", c.GetString("wallet_address")
+	case h.WalletAddress(c) != "":
+		e.ActorType, e.Actor = "wallet", h.WalletAddress(c)
+	case strings.HasPrefix(c.FullPath(), "/internal/"):
+		e.ActorType, e.Actor = "internal", "service"
+	default:
+		e.ActorType, e.Actor = "anonymous", "-"
+	}
+
+	if err := h.store.AppendAudit(c.Request.Context(), e); err != nil {
+		h.logger.Error("failed to append audit entry",
+			zap.Error(err), zap.String("chain", ev.Chain), zap.String("action", ev.Action))
+	}
+}
+
+func marshalAudit(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// listAudit serves an audit chain with the shared query parameters:
+// action, since and until (RFC 3339), before_seq, limit (max 500) and
+// verify=true, which re-hashes the whole chain.
+func (h *Handler) listAudit(c *gin.Context, chain string) {
+	f := store.AuditFilter{Action: c.Query("action"), Limit: 50}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		f.Limit = n
+	}
+	if v := c.Query("before_seq"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before_seq"})
+			return
+		}
+		f.BeforeSeq = n
+	}
+	for name, dst := range map[string]**time.Time{"since": &f.Since, "until": &f.Until} {
+		if v := c.Query(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name + ": must be RFC 3339"})
+				return
+			}
+			*dst = &t
+		}
+	}
+
+	entries, err := h.store.ListAudit(c.Request.Context(), chain, f)
+	if err != nil {
+		h.logger.Error("failed to list audit log", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list audit log"})
+		return
+	}
+	resp := gin.H{"chain": chain, "entries": entries}
+	if c.Query("verify") == "true" {
+		status, err := h.store.VerifyAuditChain(c.Request.Context(), chain)
+		if err != nil {
+			h.logger.Error("failed to verify audit chain", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify audit log"})
+			return
+		}
+		resp["verification"] = status
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListAgentAuditLog handles GET /v1/agents/:agent_id/audit-log
+func (h *Handler) ListAgentAuditLog(c *gin.Context) {
+	agentID, _ := c.Get("agent_id")
+	slug, _ := agentID.(string)
+	h.listAudit(c, agentChain(slug))
+}
+
+// ListOrgAuditLog handles GET /v1/orgs/:org_id/audit-log
+func (h *Handler) ListOrgAuditLog(c *gin.Context) {
+	orgID, _, _, ok := h.orgAccess(c, org.PermMembersManage)
+	if !ok {
+		return
+	}
+	h.listAudit(c, orgChain(orgID))
+}
+
+// ListWalletAuditLog handles GET /v1/auth/audit-log
+// Returns the session wallet's own sign-in and session events.
+func (h *Handler) ListWalletAuditLog(c *gin.Context) {
+	h.listAudit(c, walletChain(h.WalletAddress(c)))
+}
+
+// InternalAuditLog handles GET /internal/audit-log?chain=
+// Reads any chain; defaults to the system chain.
+func (h *Handler) InternalAuditLog(c *gin.Context) {
+	chain := c.Query("chain")
+	if chain == "" {
+		chain = systemChain()
+	}
+	h.listAudit(c, chain)
+}
@@ -2,13 +2,21 @@ package handler
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
 	"github.com/GT8004/gt8004-common/identity"
+	"github.com/GT8004/gt8004-common/org"
+	"github.com/GT8004/gt8004/internal/store"
 )
 
+// walletLoginKeyTTL is how long a key issued by wallet login works. Each login
+// replaces the wallet's previous login key for the agent.
+const walletLoginKeyTTL = 30 * 24 * time.Hour
+
 func (h *Handler) AuthChallenge(c *gin.Context) {
 	var req identity.ChallengeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -42,8 +50,22 @@ func (h *Handler) AuthVerify(c *gin.Context) {
 
 	// Persist verified EVM address to DB
 	if info.EVMAddress != "" {
+		var before string
+		if agent, err := h.store.GetAgentByID(c.Request.Context(), req.AgentID); err == nil {
+			before = agent.EVMAddress
+		}
 		if err := h.store.SaveAgentEVMAddress(c.Request.Context(), req.AgentID, info.EVMAddress); err != nil {
 			h.logger.Warn("failed to save agent EVM address", zap.Error(err))
+		} else if !strings.EqualFold(before, info.EVMAddress) {
+			h.audit(c, auditEvent{
+				Chain:      agentChain(req.AgentID),
+				Action:     "agent.evm_address_verified",
+				TargetType: "agent",
+				TargetID:   req.AgentID,
+				Before:     gin.H{"evm_address": before},
+				After:      gin.H{"evm_address": info.EVMAddress},
+				Actor:      info.EVMAddress,
+			})
 		}
 	}
 
@@ -51,12 +73,16 @@ func (h *Handler) AuthVerify(c *gin.Context) {
 }
 
 // WalletLogin authenticates via wallet signature and returns an API key for
-// the agent linked to that wallet address.
+// an agent the wallet can access: one registered to it, or one in an
+// organization where its role may hold keys. agent_id picks the agent and is
+// required when the wallet owns several. The key's scopes follow the role.
 func (h *Handler) WalletLogin(c *gin.Context) {
 	var req struct {
 		Address   string `json:"address" binding:"required"`
 		Challenge string `json:"challenge" binding:"required"`
 		Signature string `json:"signature" binding:"required"`
+		ChainID   int    `json:"chain_id"`
+		AgentID   string `json:"agent_id"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
@@ -68,38 +94,92 @@ func (h *Handler) WalletLogin(c *gin.Context) {
 		AgentID:   req.Address,
 		Challenge: req.Challenge,
 		Signature: req.Signature,
+		ChainID:   req.ChainID,
 	})
 	if err != nil {
 		h.logger.Warn("wallet login verification failed", zap.Error(err), zap.String("address", req.Address))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "signature verification failed"})
 		return
 	}
-
-	// Look up agents linked to this EVM address
-	agents, err := h.store.GetAgentsByEVMAddress(c.Request.Context(), info.EVMAddress)
-	if err != nil {
-		h.logger.Error("failed to query agents by evm address", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
-		return
+	wallet := strings.ToLower(info.EVMAddress)
+	ctx := c.Request.Context()
+
+	var agent *store.Agent
+	role := org.RoleOwner
+	if req.AgentID != "" {
+		agent, err = h.store.GetAgentByID(ctx, req.AgentID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+			return
+		}
+		if !strings.EqualFold(agent.EVMAddress, wallet) {
+			role, err = h.store.AgentRole(ctx, agent.ID, wallet)
+			if err != nil {
+				h.logger.Error("failed to resolve agent role", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+				return
+			}
+			if role == "" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "wallet has no access to this agent"})
+				return
+			}
+		}
+	} else {
+		// Look up agents registered to this EVM address
+		agents, err := h.store.GetAgentsByEVMAddress(ctx, wallet)
+		if err != nil {
+			h.logger.Error("failed to query agents by evm address", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+		switch len(agents) {
+		case 0:
+			c.JSON(http.StatusNotFound, gin.H{"error": "no agent registered with this wallet"})
+			return
+		case 1:
+			agent = &agents[0]
+		default:
+			ids := make([]string, len(agents))
+			for i, a := range agents {
+				ids[i] = a.AgentID
+			}
+			c.JSON(http.StatusConflict, gin.H{"error": "wallet has several agents; specify agent_id", "agent_ids": ids})
+			return
+		}
 	}
-	if len(agents) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "no agent registered with this wallet"})
+
+	scopes := role.KeyScopes()
+	if len(scopes) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "role cannot hold api keys", "role": role})
 		return
 	}
-
-	// Use the first agent (most common case: 1 wallet = 1 agent)
-	agent := agents[0]
-
-	// Issue a new API key
-	apiKey, err := h.store.CreateAPIKey(c.Request.Context(), agent.ID)
+	expiresAt := time.Now().Add(walletLoginKeyTTL)
+	key, apiKey, err := h.store.ReissueAPIKey(ctx, agent.ID, store.NewAPIKey{
+		Name:      "wallet-login",
+		Scopes:    scopes,
+		ExpiresAt: &expiresAt,
+		Role:      role,
+		CreatedBy: wallet,
+	})
 	if err != nil {
 		h.logger.Error("failed to create api key for wallet login", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue api key"})
 		return
 	}
+	h.audit(c, auditEvent{
+		Chain:      agentChain(agent.AgentID),
+		Action:     "api_key.created",
+		TargetType: "api_key",
+		TargetID:   key.ID.String(),
+		After:      gin.H{"name": key.Name, "scopes": key.Scopes, "role": role, "expires_at": key.ExpiresAt, "via": "wallet-login"},
+		Actor:      wallet,
+	})
 
 	c.JSON(http.StatusOK, gin.H{
-		"agent":   agent,
-		"api_key": apiKey,
+		"agent":      agent,
+		"api_key":    apiKey,
+		"expires_at": key.ExpiresAt,
+		"role":       role,
+		"scopes":     scopes,
 	})
 }
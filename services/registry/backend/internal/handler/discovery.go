@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/webhook"
+	"github.com/GT8004/gt8004/internal/store"
 )
 
 var discoveryHTTPClient = &http.Client{Timeout: 10 * time.Second}
@@ -84,5 +88,58 @@ func (h *Handler) NotifyMint(c *gin.Context) {
 	}
 
 	h.triggerDiscoverySync(req.ChainID, req.TokenID)
+	h.emitMintEvent(req.ChainID, req.TokenID)
 	c.JSON(http.StatusOK, gin.H{"status": "sync scheduled"})
 }
+
+// mintOwnerRetries are the delays before each ownerOf lookup of a freshly
+// minted token, since RPC nodes may not have the mint yet.
+var mintOwnerRetries = []time.Duration{0, 30 * time.Second, 2 * time.Minute}
+
+// emitMintEvent sends agent.registered to the token owner's wallet
+// subscriptions (and the linked agent's, if any) once the mint is visible
+// on-chain. The owner is read from the registry contract rather than taken
+// from the unauthenticated request, and the event is sent once per token.
+func (h *Handler) emitMintEvent(chainID int, tokenID int64) {
+	client, err := h.erc8004Registry.GetClient(chainID)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		var owner string
+		var err error
+		for _, delay := range mintOwnerRetries {
+			time.Sleep(delay)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			owner, err = client.VerifyOwnership(ctx, tokenID)
+			cancel()
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			h.logger.Warn("mint webhook: failed to resolve token owner",
+				zap.Int("chain_id", chainID), zap.Int64("token_id", tokenID), zap.Error(err))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		ev := store.WebhookEvent{
+			Type: webhook.EventAgentRegistered,
+			Data: gin.H{
+				"source":           "erc8004_mint",
+				"chain_id":         chainID,
+				"erc8004_token_id": tokenID,
+				"owner":            owner,
+			},
+			Wallets:  []string{owner},
+			DedupKey: fmt.Sprintf("mint:%d:%d", chainID, tokenID),
+		}
+		if agent, err := h.store.GetAgentByTokenID(ctx, tokenID, chainID); err == nil {
+			ev.AgentDBID = &agent.ID
+		}
+		h.emit(ctx, ev)
+	}()
+}
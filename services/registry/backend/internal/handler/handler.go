@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
 	"github.com/GT8004/gt8004-common/identity"
+	"github.com/GT8004/gt8004-common/session"
 	"github.com/GT8004/gt8004-common/ws"
 	"github.com/GT8004/gt8004/internal/cache"
 	"github.com/GT8004/gt8004/internal/erc8004"
@@ -29,6 +31,27 @@ type Handler struct {
 	// Discovery sync trigger (fire-and-forget)
 	discoveryURL   string
 	internalSecret string
+
+	// Wallet sessions (Sign-In With Ethereum)
+	sessions SessionConfig
+
+	// Outbound webhooks
+	webhooks WebhookConfig
+
+	// Paid tiers (x402)
+	tiers TierConfig
+}
+
+// SessionConfig configures wallet sessions. A nil Signer disables
+// Sign-In With Ethereum.
+type SessionConfig struct {
+	Signer      *session.Signer
+	AccessTTL   time.Duration
+	RefreshTTL  time.Duration
+	SIWEDomains []string
+
+	// The raw X-Wallet-Address header is accepted until this time.
+	WalletHeaderUntil time.Time
 }
 
 func New(
@@ -40,6 +63,9 @@ func New(
 	logger *zap.Logger,
 	discoveryURL string,
 	internalSecret string,
+	sessions SessionConfig,
+	webhooks WebhookConfig,
+	tiers TierConfig,
 ) *Handler {
 	return &Handler{
 		store:           s,
@@ -50,6 +76,9 @@ func New(
 		logger:          logger,
 		discoveryURL:    discoveryURL,
 		internalSecret:  internalSecret,
+		sessions:        sessions,
+		webhooks:        webhooks,
+		tiers:           tiers,
 	}
 }
 
@@ -61,6 +90,16 @@ func (h *Handler) Logger() *zap.Logger {
 	return h.logger
 }
 
+func (h *Handler) SessionSigner() *session.Signer {
+	return h.sessions.Signer
+}
+
+// WalletAddress returns the wallet the request is authenticated as, from
+// its session token or, during the deprecation window, X-Wallet-Address.
+func (h *Handler) WalletAddress(c *gin.Context) string {
+	return session.WalletAddress(c, h.sessions.WalletHeaderUntil)
+}
+
 // resolvePublicAgent resolves an agent by its slug (agent_id) from URL params.
 // Used for public read-only endpoints that don't require authentication.
 func (h *Handler) resolvePublicAgent(c *gin.Context) (uuid.UUID, bool) {
@@ -6,6 +6,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/apikey"
 )
 
 // InternalGetAgent handles GET /internal/agents/:slug
@@ -24,9 +26,16 @@ func (h *Handler) InternalGetAgent(c *gin.Context) {
 	})
 }
 
-// InternalValidateKey handles POST /internal/validate-key
-// Used by Analytics service to validate API keys.
+// InternalValidateKey handles POST /internal/validate-key/:scope
+// Used by other services to validate API keys. The key must carry the scope
+// named in the path and be allowed from the client IP, read from
+// X-Forwarded-For only as far as the trusted proxies wrote it.
 func (h *Handler) InternalValidateKey(c *gin.Context) {
+	scope := c.Param("scope")
+	if !apikey.ValidScope(scope) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scope"})
+		return
+	}
 	var req struct {
 		KeyHash string `json:"key_hash"`
 	}
@@ -34,14 +43,18 @@ func (h *Handler) InternalValidateKey(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "key_hash required"})
 		return
 	}
-	auth, err := h.store.ValidateAPIKey(c.Request.Context(), req.KeyHash)
+	auth, err := h.store.ValidateAPIKey(c.Request.Context(), req.KeyHash, scope, c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+		status, msg := apikey.HTTPStatus(err)
+		c.JSON(status, gin.H{"error": msg})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"agent_db_id": auth.AgentDBID,
 		"agent_id":    auth.AgentID,
+		"key_id":      auth.KeyID,
+		"scopes":      auth.Scopes,
+		"tier":        auth.Tier,
 	})
 }
 
@@ -103,5 +116,12 @@ func (h *Handler) InternalReconcile(c *gin.Context) {
 		return
 	}
 	h.logger.Info("erc8004 reconciliation complete", zap.Int64("cleaned", count))
+	h.audit(c, auditEvent{
+		Chain:      systemChain(),
+		Action:     "erc8004.reconciled",
+		TargetType: "registry",
+		TargetID:   "erc8004",
+		After:      gin.H{"cleaned": count},
+	})
 	c.JSON(http.StatusOK, gin.H{"cleaned": count})
 }
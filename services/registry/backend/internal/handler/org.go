@@ -0,0 +1,672 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/org"
+	"github.com/GT8004/gt8004/internal/store"
+)
+
+// invitationTTL is how long an organization invitation can be accepted.
+const invitationTTL = 7 * 24 * time.Hour
+
+// invitationSignWindow is how far an inviter's Issued At may be from the
+// time the invitation is created.
+const invitationSignWindow = 5 * time.Minute
+
+// normalizeWallet returns addr as a lowercase EVM address.
+func normalizeWallet(addr string) (string, bool) {
+	if !common.IsHexAddress(addr) || !strings.HasPrefix(addr, "0x") {
+		return "", false
+	}
+	return strings.ToLower(addr), true
+}
+
+// orgAccess resolves :org_id and the session wallet's role in it, and
+// writes the error response when the wallet is not a member or its role
+// lacks perm.
+func (h *Handler) orgAccess(c *gin.Context, perm org.Permission) (uuid.UUID, string, org.Role, bool) {
+	orgID, err := uuid.Parse(c.Param("org_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid org_id"})
+		return uuid.UUID{}, "", "", false
+	}
+	wallet := h.WalletAddress(c)
+	role, err := h.store.OrgRole(c.Request.Context(), orgID, wallet)
+	if err != nil {
+		h.logger.Error("failed to get organization role", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check organization access"})
+		return uuid.UUID{}, "", "", false
+	}
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+		return uuid.UUID{}, "", "", false
+	}
+	if !role.Can(perm) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient role", "role": role, "required": perm})
+		return uuid.UUID{}, "", "", false
+	}
+	return orgID, wallet, role, true
+}
+
+// CreateOrganization handles POST /v1/orgs
+// The session wallet becomes the organization's first owner.
+func (h *Handler) CreateOrganization(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required,max=128"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	o, err := h.store.CreateOrganization(c.Request.Context(), name, h.WalletAddress(c))
+	if err != nil {
+		h.logger.Error("failed to create organization", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create organization"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      orgChain(o.ID),
+		Action:     "org.created",
+		TargetType: "organization",
+		TargetID:   o.ID.String(),
+		After:      gin.H{"name": o.Name},
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"organization": o})
+}
+
+// ListOrganizations handles GET /v1/orgs
+// Returns the organizations the session wallet belongs to, with its role.
+func (h *Handler) ListOrganizations(c *gin.Context) {
+	orgs, err := h.store.ListWalletOrganizations(c.Request.Context(), h.WalletAddress(c))
+	if err != nil {
+		h.logger.Error("failed to list organizations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list organizations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organizations": orgs})
+}
+
+// GetOrganization handles GET /v1/orgs/:org_id
+// Returns the organization with its members and agents. Any member may read it.
+func (h *Handler) GetOrganization(c *gin.Context) {
+	orgID, _, role, ok := h.orgAccess(c, org.PermAgentView)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	o, err := h.store.GetOrganization(ctx, orgID)
+	if err != nil {
+		h.logger.Error("failed to get organization", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get organization"})
+		return
+	}
+	o.Role = role
+	members, err := h.store.ListOrgMembers(ctx, orgID)
+	if err != nil {
+		h.logger.Error("failed to list organization members", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get organization"})
+		return
+	}
+	agents, err := h.store.ListOrgAgents(ctx, orgID)
+	if err != nil {
+		h.logger.Error("failed to list organization agents", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get organization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organization": o, "members": members, "agents": agents})
+}
+
+// DeleteOrganization handles DELETE /v1/orgs/:org_id
+// Owner only. Agents stay registered to their wallets.
+func (h *Handler) DeleteOrganization(c *gin.Context) {
+	orgID, _, _, ok := h.orgAccess(c, org.PermOrgAdmin)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteOrganization(c.Request.Context(), orgID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+			return
+		}
+		h.logger.Error("failed to delete organization", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete organization"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      orgChain(orgID),
+		Action:     "org.deleted",
+		TargetType: "organization",
+		TargetID:   orgID.String(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// AddOrgAgent handles POST /v1/orgs/:org_id/agents
+// Moves an agent registered to the session wallet into the organization.
+// The wallet must be an owner or admin of the organization.
+func (h *Handler) AddOrgAgent(c *gin.Context) {
+	orgID, wallet, _, ok := h.orgAccess(c, org.PermAgentManage)
+	if !ok {
+		return
+	}
+	var req struct {
+		AgentID string `json:"agent_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	agent, err := h.store.GetAgentByID(ctx, req.AgentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+		return
+	}
+	if !strings.EqualFold(agent.EVMAddress, wallet) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the agent's registered wallet can add it to an organization"})
+		return
+	}
+	current, err := h.store.AgentOrgID(ctx, agent.ID)
+	if err != nil {
+		h.logger.Error("failed to get agent organization", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add agent"})
+		return
+	}
+	if current != nil && *current != orgID {
+		c.JSON(http.StatusConflict, gin.H{"error": "agent already belongs to another organization"})
+		return
+	}
+	if err := h.store.SetAgentOrg(ctx, agent.ID, &orgID); err != nil {
+		h.logger.Error("failed to add agent to organization", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add agent"})
+		return
+	}
+
+	for _, chain := range []string{orgChain(orgID), agentChain(agent.AgentID)} {
+		h.audit(c, auditEvent{
+			Chain:      chain,
+			Action:     "org.agent_added",
+			TargetType: "agent",
+			TargetID:   agent.AgentID,
+			Before:     gin.H{"org_id": current},
+			After:      gin.H{"org_id": orgID},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "added", "agent_id": agent.AgentID})
+}
+
+// RemoveOrgAgent handles DELETE /v1/orgs/:org_id/agents/:agent_id
+// Allowed for the agent's registered wallet and organization owners.
+func (h *Handler) RemoveOrgAgent(c *gin.Context) {
+	orgID, wallet, role, ok := h.orgAccess(c, org.PermAgentView)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	agent, err := h.store.GetAgentByID(ctx, c.Param("agent_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+		return
+	}
+	current, err := h.store.AgentOrgID(ctx, agent.ID)
+	if err != nil {
+		h.logger.Error("failed to get agent organization", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove agent"})
+		return
+	}
+	if current == nil || *current != orgID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "agent not in this organization"})
+		return
+	}
+	if !strings.EqualFold(agent.EVMAddress, wallet) && !role.Can(org.PermOrgAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient role", "role": role, "required": org.PermOrgAdmin})
+		return
+	}
+	if err := h.store.SetAgentOrg(ctx, agent.ID, nil); err != nil {
+		h.logger.Error("failed to remove agent from organization", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove agent"})
+		return
+	}
+
+	for _, chain := range []string{orgChain(orgID), agentChain(agent.AgentID)} {
+		h.audit(c, auditEvent{
+			Chain:      chain,
+			Action:     "org.agent_removed",
+			TargetType: "agent",
+			TargetID:   agent.AgentID,
+			Before:     gin.H{"org_id": orgID},
+			After:      gin.H{"org_id": nil},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "removed", "agent_id": agent.AgentID})
+}
+
+// UpdateOrgMember handles PUT /v1/orgs/:org_id/members/:address
+// Owners may assign any role; admins may move members between the roles
+// below admin.
+func (h *Handler) UpdateOrgMember(c *gin.Context) {
+	orgID, _, role, ok := h.orgAccess(c, org.PermMembersManage)
+	if !ok {
+		return
+	}
+	member, valid := normalizeWallet(c.Param("address"))
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address"})
+		return
+	}
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	newRole, valid := org.ParseRole(req.Role)
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	current, err := h.store.OrgRole(ctx, orgID, member)
+	if err != nil {
+		h.logger.Error("failed to get organization role", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update member"})
+		return
+	}
+	if current == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		return
+	}
+	if !role.CanAssign(current) || !role.CanAssign(newRole) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient role", "role": role})
+		return
+	}
+
+	if err := h.store.UpdateMemberRole(ctx, orgID, member, newRole); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		case errors.Is(err, store.ErrLastOwner):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("failed to update member role", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update member"})
+		}
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      orgChain(orgID),
+		Action:     "org.member_role_changed",
+		TargetType: "member",
+		TargetID:   member,
+		Before:     gin.H{"role": current},
+		After:      gin.H{"role": newRole},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"wallet_address": member, "role": newRole})
+}
+
+// RemoveOrgMember handles DELETE /v1/orgs/:org_id/members/:address
+// Members may leave; owners and admins may remove members they can assign.
+// API keys issued to the removed wallet for the organization's agents are
+// revoked.
+func (h *Handler) RemoveOrgMember(c *gin.Context) {
+	orgID, wallet, role, ok := h.orgAccess(c, org.PermAgentView)
+	if !ok {
+		return
+	}
+	member, valid := normalizeWallet(c.Param("address"))
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	if member != wallet {
+		current, err := h.store.OrgRole(ctx, orgID, member)
+		if err != nil {
+			h.logger.Error("failed to get organization role", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove member"})
+			return
+		}
+		if current == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+			return
+		}
+		if !role.CanAssign(current) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient role", "role": role})
+			return
+		}
+	}
+
+	revoked, err := h.store.RemoveMember(ctx, orgID, member)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		case errors.Is(err, store.ErrLastOwner):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("failed to remove member", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove member"})
+		}
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      orgChain(orgID),
+		Action:     "org.member_removed",
+		TargetType: "member",
+		TargetID:   member,
+		After:      gin.H{"revoked_api_keys": revoked},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"status": "removed", "revoked_api_keys": revoked})
+}
+
+// inviterMessage is the text the inviting wallet signs to create an
+// invitation. Clients build it from the request fields.
+func inviterMessage(orgID uuid.UUID, role org.Role, invitee, inviter string, issuedAt time.Time) string {
+	return fmt.Sprintf("GT8004 organization invitation request\n\n"+
+		"Organization ID: %s\n"+
+		"Role: %s\n"+
+		"Wallet: %s\n"+
+		"Invited By: %s\n"+
+		"Issued At: %s",
+		orgID, role, invitee, inviter, issuedAt.UTC().Format(time.RFC3339))
+}
+
+// invitationMessage is the text the invited wallet signs to accept.
+func invitationMessage(inv *store.OrgInvitation) string {
+	return fmt.Sprintf("GT8004 organization invitation\n\n"+
+		"Organization: %s\n"+
+		"Organization ID: %s\n"+
+		"Role: %s\n"+
+		"Wallet: %s\n"+
+		"Invited By: %s\n"+
+		"Invitation ID: %s\n"+
+		"Expires At: %s",
+		inv.OrgName, inv.OrgID, inv.Role, inv.WalletAddress, inv.InvitedBy, inv.ID,
+		inv.ExpiresAt.UTC().Format(time.RFC3339))
+}
+
+// CreateOrgInvitation handles POST /v1/orgs/:org_id/invitations
+// Invites a wallet with a role the caller can assign. The caller signs
+// inviterMessage for the request with an Issued At within a few minutes of
+// now; each signature creates one invitation. The response carries the
+// message the invited wallet signs to accept.
+func (h *Handler) CreateOrgInvitation(c *gin.Context) {
+	orgID, wallet, role, ok := h.orgAccess(c, org.PermMembersManage)
+	if !ok {
+		return
+	}
+	var req struct {
+		WalletAddress string `json:"wallet_address" binding:"required"`
+		Role          string `json:"role" binding:"required"`
+		IssuedAt      string `json:"issued_at" binding:"required"`
+		Signature     string `json:"signature" binding:"required"`
+		ChainID       int    `json:"chain_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	invitee, valid := normalizeWallet(req.WalletAddress)
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet_address"})
+		return
+	}
+	inviteRole, valid := org.ParseRole(req.Role)
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+		return
+	}
+	if !role.CanAssign(inviteRole) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient role", "role": role})
+		return
+	}
+	issuedAt, err := time.Parse(time.RFC3339, req.IssuedAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid issued_at: must be RFC3339"})
+		return
+	}
+	if d := time.Since(issuedAt); d > invitationSignWindow || d < -invitationSignWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "issued_at is too far from the current time"})
+		return
+	}
+	signed := inviterMessage(orgID, inviteRole, invitee, wallet, issuedAt)
+	if err := h.identity.VerifyMessage(wallet, req.ChainID, signed, req.Signature); err != nil {
+		h.logger.Warn("invitation signature verification failed", zap.Error(err), zap.String("wallet", wallet))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "signature verification failed"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	existing, err := h.store.OrgRole(ctx, orgID, invitee)
+	if err != nil {
+		h.logger.Error("failed to get organization role", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create invitation"})
+		return
+	}
+	if existing != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "wallet is already a member", "role": existing})
+		return
+	}
+	o, err := h.store.GetOrganization(ctx, orgID)
+	if err != nil {
+		h.logger.Error("failed to get organization", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create invitation"})
+		return
+	}
+
+	inv := &store.OrgInvitation{
+		ID:               uuid.New(),
+		OrgID:            orgID,
+		OrgName:          o.Name,
+		WalletAddress:    invitee,
+		Role:             inviteRole,
+		InvitedBy:        wallet,
+		InviterMessage:   signed,
+		InviterSignature: req.Signature,
+		ExpiresAt:        time.Now().Add(invitationTTL).Truncate(time.Second),
+	}
+	inv.Message = invitationMessage(inv)
+	if err := h.store.CreateInvitation(ctx, inv); err != nil {
+		if errors.Is(err, store.ErrInvitationReplayed) {
+			c.JSON(http.StatusConflict, gin.H{"error": "invitation signature already used"})
+			return
+		}
+		h.logger.Error("failed to create invitation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create invitation"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      orgChain(orgID),
+		Action:     "org.invitation_created",
+		TargetType: "invitation",
+		TargetID:   inv.ID.String(),
+		After:      gin.H{"wallet_address": invitee, "role": inviteRole, "expires_at": inv.ExpiresAt},
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"invitation": inv})
+}
+
+// ListOrgInvitations handles GET /v1/orgs/:org_id/invitations
+func (h *Handler) ListOrgInvitations(c *gin.Context) {
+	orgID, _, _, ok := h.orgAccess(c, org.PermMembersManage)
+	if !ok {
+		return
+	}
+
+	invs, err := h.store.ListPendingInvitations(c.Request.Context(), &orgID, "")
+	if err != nil {
+		h.logger.Error("failed to list invitations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invs})
+}
+
+// RevokeOrgInvitation handles DELETE /v1/orgs/:org_id/invitations/:invitation_id
+func (h *Handler) RevokeOrgInvitation(c *gin.Context) {
+	orgID, _, _, ok := h.orgAccess(c, org.PermMembersManage)
+	if !ok {
+		return
+	}
+	invID, err := uuid.Parse(c.Param("invitation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invitation_id"})
+		return
+	}
+
+	if err := h.store.RevokeInvitation(c.Request.Context(), orgID, invID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invitation not found"})
+			return
+		}
+		h.logger.Error("failed to revoke invitation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke invitation"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      orgChain(orgID),
+		Action:     "org.invitation_revoked",
+		TargetType: "invitation",
+		TargetID:   invID.String(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
+
+// ListMyInvitations handles GET /v1/invitations
+// Returns the pending invitations addressed to the session wallet.
+func (h *Handler) ListMyInvitations(c *gin.Context) {
+	invs, err := h.store.ListPendingInvitations(c.Request.Context(), nil, h.WalletAddress(c))
+	if err != nil {
+		h.logger.Error("failed to list invitations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invs})
+}
+
+// GetInvitation handles GET /v1/invitations/:invitation_id
+// Returns the invitation and the message to sign. The ID is the secret
+// shared with the invited wallet.
+func (h *Handler) GetInvitation(c *gin.Context) {
+	invID, err := uuid.Parse(c.Param("invitation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invitation_id"})
+		return
+	}
+
+	inv, err := h.store.GetInvitation(c.Request.Context(), invID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invitation not found"})
+			return
+		}
+		h.logger.Error("failed to get invitation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitation": inv})
+}
+
+// AcceptInvitation handles POST /v1/invitations/:invitation_id/accept
+// The invited wallet accepts by signing the invitation message
+// (personal_sign); smart-contract wallets are verified on chain_id.
+func (h *Handler) AcceptInvitation(c *gin.Context) {
+	invID, err := uuid.Parse(c.Param("invitation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invitation_id"})
+		return
+	}
+	var req struct {
+		Signature string `json:"signature" binding:"required"`
+		ChainID   int    `json:"chain_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	inv, err := h.store.GetInvitation(ctx, invID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invitation not found"})
+			return
+		}
+		h.logger.Error("failed to get invitation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to accept invitation"})
+		return
+	}
+	if inv.AcceptedAt != nil || inv.RevokedAt != nil || !time.Now().Before(inv.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "invitation is no longer pending"})
+		return
+	}
+	if err := h.identity.VerifyMessage(inv.WalletAddress, req.ChainID, inv.Message, req.Signature); err != nil {
+		h.logger.Warn("invitation signature verification failed", zap.Error(err), zap.String("wallet", inv.WalletAddress))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "signature verification failed"})
+		return
+	}
+
+	member, err := h.store.AcceptInvitation(ctx, invID, req.Signature)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusGone, gin.H{"error": "invitation is no longer pending"})
+			return
+		}
+		h.logger.Error("failed to accept invitation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to accept invitation"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      orgChain(inv.OrgID),
+		Action:     "org.invitation_accepted",
+		TargetType: "invitation",
+		TargetID:   invID.String(),
+		After:      gin.H{"wallet_address": member.WalletAddress, "role": member.Role},
+		Actor:      inv.WalletAddress,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"org_id": inv.OrgID, "member": member})
+}
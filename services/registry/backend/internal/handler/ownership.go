@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ListOwnershipTransfers handles GET /v1/agents/:agent_id/ownership-history
+// Returns the owner changes the transfer watcher applied, newest first.
+func (h *Handler) ListOwnershipTransfers(c *gin.Context) {
+	agentDBID, exists := c.Get("agent_db_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	transfers, err := h.store.ListOwnershipTransfers(c.Request.Context(), agentDBID.(uuid.UUID))
+	if err != nil {
+		h.logger.Error("failed to list ownership transfers", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list ownership transfers"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"transfers": transfers})
+}
@@ -8,20 +8,22 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/GT8004/gt8004-common/webhook"
 	"github.com/GT8004/gt8004/internal/store"
 )
 
 // CreateReview handles POST /v1/agents/:agent_id/reviews
-// Requires X-Wallet-Address header for reviewer identification.
+// The reviewer is the wallet of the request's session (or, during the
+// deprecation window, the X-Wallet-Address header).
 func (h *Handler) CreateReview(c *gin.Context) {
 	agentDBID, ok := h.resolvePublicAgent(c)
 	if !ok {
 		return
 	}
 
-	reviewerAddr := strings.ToLower(c.GetHeader("X-Wallet-Address"))
-	if reviewerAddr == "" || !strings.HasPrefix(reviewerAddr, "0x") || len(reviewerAddr) != 42 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "valid X-Wallet-Address header required"})
+	reviewerAddr := h.WalletAddress(c)
+	if reviewerAddr == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "wallet session required"})
 		return
 	}
 
@@ -62,6 +64,25 @@ func (h *Handler) CreateReview(c *gin.Context) {
 		return
 	}
 
+	h.audit(c, auditEvent{
+		Chain:      agentChain(c.Param("agent_id")),
+		Action:     "review.created",
+		TargetType: "review",
+		TargetID:   review.ID.String(),
+		After:      gin.H{"score": review.Score, "tags": review.Tags},
+	})
+	h.emit(c.Request.Context(), store.WebhookEvent{
+		AgentDBID: &agentDBID,
+		Type:      webhook.EventReviewCreated,
+		Data: gin.H{
+			"review_id": review.ID,
+			"reviewer":  review.ReviewerID,
+			"score":     review.Score,
+			"tags":      review.Tags,
+			"comment":   review.Comment,
+		},
+	})
+
 	c.JSON(http.StatusCreated, review)
 }
 
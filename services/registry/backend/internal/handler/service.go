@@ -15,6 +15,8 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/GT8004/gt8004-common/identity"
+	"github.com/GT8004/gt8004-common/session"
+	"github.com/GT8004/gt8004-common/webhook"
 	"github.com/GT8004/gt8004/internal/store"
 )
 
@@ -153,6 +155,23 @@ func (h *Handler) RegisterService(c *gin.Context) {
 		reactivate = true
 	}
 
+	// Paid tiers are bought through the tier endpoint; a reactivated agent
+	// keeps one it still has a period of.
+	if tier != "open" {
+		var sub *store.TierSubscription
+		if reactivate {
+			sub, err = h.store.GetActiveTierSubscription(c.Request.Context(), existing.ID, tier)
+			if err != nil {
+				h.logger.Error("failed to get tier subscription", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to register service"})
+				return
+			}
+		}
+		if sub == nil {
+			tier = "open"
+		}
+	}
+
 	// Get agent URI from contract
 	agentURI, err := erc8004Client.GetAgentURI(c.Request.Context(), *req.ERC8004TokenID)
 	if err != nil || agentURI == "" {
@@ -245,7 +264,7 @@ func (h *Handler) RegisterService(c *gin.Context) {
 		}
 	}
 
-	rawKey, err := h.store.CreateAPIKey(c.Request.Context(), agent.ID)
+	rawKey, err := h.store.CreateAPIKey(c.Request.Context(), agent.ID, "default")
 	if err != nil {
 		h.logger.Error("failed to create api key", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate api key"})
@@ -259,6 +278,37 @@ func (h *Handler) RegisterService(c *gin.Context) {
 		h.cache.Del(c.Request.Context(), fmt.Sprintf("wallet:%s", strings.ToLower(agent.EVMAddress)))
 	}
 
+	action := "agent.registered"
+	if reactivate {
+		action = "agent.reactivated"
+	}
+	h.audit(c, auditEvent{
+		Chain:      agentChain(agentID),
+		Action:     action,
+		TargetType: "agent",
+		TargetID:   agentID,
+		After: gin.H{
+			"erc8004_token_id": *req.ERC8004TokenID,
+			"chain_id":         chainID,
+			"evm_address":      agent.EVMAddress,
+			"tier":             tier,
+			"status":           "active",
+		},
+		Actor: req.WalletAddress,
+	})
+	h.emit(c.Request.Context(), store.WebhookEvent{
+		AgentDBID: &agent.ID,
+		Type:      webhook.EventAgentRegistered,
+		Data: gin.H{
+			"name":             agent.Name,
+			"erc8004_token_id": *req.ERC8004TokenID,
+			"chain_id":         chainID,
+			"evm_address":      agent.EVMAddress,
+			"tier":             tier,
+			"reactivated":      reactivate,
+		},
+	})
+
 	// Trigger immediate Discovery sync for this token (fire-and-forget)
 	h.triggerDiscoverySync(chainID, *req.ERC8004TokenID)
 
@@ -304,11 +354,17 @@ func (h *Handler) GetService(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// UpdateTierRequest changes an agent's tier. Renew pays for another period
+// of a paid tier the agent already has.
 type UpdateTierRequest struct {
-	Tier       string `json:"tier" binding:"required"`
-	EVMAddress string `json:"evm_address"`
+	Tier  string `json:"tier" binding:"required"`
+	Renew bool   `json:"renew"`
 }
 
+// UpdateTier handles PUT /v1/services/:agent_id/tier
+// Moving to a paid tier without an active period answers 402 with an x402
+// challenge; the retry carries the settled payment in X-PAYMENT. Only the
+// agent's owner wallet, signed in with SIWE, can pay.
 func (h *Handler) UpdateTier(c *gin.Context) {
 	agentDBID, exists := c.Get("agent_db_id")
 	if !exists {
@@ -334,17 +390,37 @@ func (h *Handler) UpdateTier(c *gin.Context) {
 		return
 	}
 
-	// Upgrade to lite requires verified EVM address
+	// Upgrade to lite requires a verified EVM address.
 	if req.Tier == "lite" && agent.EVMAddress == "" {
-		if req.EVMAddress == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "EVM address required for tier upgrade. Complete auth/verify first or provide evm_address.",
-			})
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "EVM address required for tier upgrade. Complete auth/verify first.",
+		})
+		return
+	}
+
+	// Paid tiers need a period covering now; without one the request must
+	// carry an x402 payment made by the owner's session wallet.
+	var sub *store.TierSubscription
+	if req.Tier != "open" {
+		sub, err = h.store.GetActiveTierSubscription(c.Request.Context(), dbID, req.Tier)
+		if err != nil {
+			h.logger.Error("failed to get tier subscription", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update tier"})
 			return
 		}
-		if err := h.store.SaveAgentEVMAddress(c.Request.Context(), agent.AgentID, req.EVMAddress); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save EVM address"})
-			return
+		if sub == nil || req.Renew {
+			claims, ok := session.FromContext(c)
+			if !ok {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "paying for a tier requires a wallet session"})
+				return
+			}
+			if !strings.EqualFold(claims.Address, agent.EVMAddress) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "only the owner wallet can pay for a tier"})
+				return
+			}
+			if sub, ok = h.settleTierPayment(c, agent, req.Tier, strings.ToLower(claims.Address)); !ok {
+				return
+			}
 		}
 	}
 
@@ -354,17 +430,39 @@ func (h *Handler) UpdateTier(c *gin.Context) {
 		return
 	}
 
+	h.audit(c, auditEvent{
+		Chain:      agentChain(agent.AgentID),
+		Action:     "agent.tier_changed",
+		TargetType: "agent",
+		TargetID:   agent.AgentID,
+		Before:     gin.H{"tier": agent.CurrentTier},
+		After:      gin.H{"tier": req.Tier},
+	})
+	if agent.CurrentTier != req.Tier {
+		data := gin.H{"from": agent.CurrentTier, "to": req.Tier}
+		if sub != nil {
+			data["period_end"] = sub.PeriodEnd
+		}
+		h.emit(c.Request.Context(), store.WebhookEvent{
+			AgentDBID: &dbID,
+			Type:      webhook.EventTierChanged,
+			Data:      data,
+		})
+	}
+
 	now := time.Now()
 	c.JSON(http.StatusOK, gin.H{
 		"agent_id":        agent.AgentID,
 		"tier":            req.Tier,
 		"tier_updated_at": now,
+		"subscription":    sub,
 	})
 }
 
 type DeregisterRequest struct {
 	Challenge string `json:"challenge"`
 	Signature string `json:"signature"`
+	ChainID   int    `json:"chain_id"`
 }
 
 func (h *Handler) DeregisterService(c *gin.Context) {
@@ -375,27 +473,31 @@ func (h *Handler) DeregisterService(c *gin.Context) {
 	}
 	dbID := agentDBID.(uuid.UUID)
 
-	// If using wallet auth (X-Wallet-Address), require signature verification
-	walletAddr := strings.ToLower(c.GetHeader("X-Wallet-Address"))
-	if walletAddr != "" {
-		var req DeregisterRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "challenge and signature required for wallet auth"})
-			return
-		}
+	// Deregistering needs the owner's wallet session plus a fresh signature
+	// as confirmation; API keys cannot deregister an agent.
+	walletAddr := c.GetString("wallet_address")
+	if walletAddr == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "wallet session required"})
+		return
+	}
+	var req DeregisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "challenge and signature required for wallet auth"})
+		return
+	}
 
-		// Verify signature — use walletAddr as AgentID because VerifySignature
-		// recovers the signer and compares against AgentID as an EVM address.
-		verifyReq := identity.VerifyRequest{
-			AgentID:   walletAddr,
-			Challenge: req.Challenge,
-			Signature: req.Signature,
-		}
-		if _, err := h.identity.VerifySignature(verifyReq); err != nil {
-			h.logger.Warn("signature verification failed", zap.Error(err))
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
-			return
-		}
+	// Verify signature — use walletAddr as AgentID because VerifySignature
+	// recovers the signer and compares against AgentID as an EVM address.
+	verifyReq := identity.VerifyRequest{
+		AgentID:   walletAddr,
+		Challenge: req.Challenge,
+		Signature: req.Signature,
+		ChainID:   req.ChainID,
+	}
+	if _, err := h.identity.VerifySignature(verifyReq); err != nil {
+		h.logger.Warn("signature verification failed", zap.Error(err))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+		return
 	}
 
 	if err := h.store.DeregisterAgent(c.Request.Context(), dbID); err != nil {
@@ -404,6 +506,21 @@ func (h *Handler) DeregisterService(c *gin.Context) {
 		return
 	}
 
+	agentID := c.GetString("agent_id")
+	h.audit(c, auditEvent{
+		Chain:      agentChain(agentID),
+		Action:     "agent.deregistered",
+		TargetType: "agent",
+		TargetID:   agentID,
+		Before:     gin.H{"status": "active"},
+		After:      gin.H{"status": "deregistered"},
+	})
+	h.emit(c.Request.Context(), store.WebhookEvent{
+		AgentDBID: &dbID,
+		Type:      webhook.EventAgentDeregistered,
+		Data:      gin.H{"status": "deregistered"},
+	})
+
 	c.JSON(http.StatusOK, gin.H{"status": "deregistered"})
 }
 
@@ -436,6 +553,12 @@ func (h *Handler) LinkERC8004(c *gin.Context) {
 		chainID = *req.ChainID
 	}
 
+	current, err := h.store.GetAgentByDBID(c.Request.Context(), dbID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+		return
+	}
+
 	// Check duplicate token ID on the same chain
 	existing, _ := h.store.GetAgentByTokenID(c.Request.Context(), req.ERC8004TokenID, chainID)
 	if existing != nil && existing.ID != dbID {
@@ -448,6 +571,7 @@ func (h *Handler) LinkERC8004(c *gin.Context) {
 		AgentID:   c.Param("agent_id"),
 		Challenge: req.Challenge,
 		Signature: req.Signature,
+		ChainID:   chainID,
 	}
 	info, err := h.identity.VerifySignature(verifyReq)
 	if err != nil {
@@ -489,6 +613,23 @@ func (h *Handler) LinkERC8004(c *gin.Context) {
 		return
 	}
 
+	h.audit(c, auditEvent{
+		Chain:      agentChain(current.AgentID),
+		Action:     "agent.erc8004_linked",
+		TargetType: "agent",
+		TargetID:   current.AgentID,
+		Before: gin.H{
+			"erc8004_token_id": current.ERC8004TokenID,
+			"chain_id":         current.ChainID,
+			"evm_address":      current.EVMAddress,
+		},
+		After: gin.H{
+			"erc8004_token_id": req.ERC8004TokenID,
+			"chain_id":         chainID,
+			"evm_address":      info.EVMAddress,
+		},
+	})
+
 	// Invalidate wallet + search caches after ERC-8004 link
 	h.cache.Del(c.Request.Context(), fmt.Sprintf("wallet:%s", strings.ToLower(info.EVMAddress)))
 	h.cache.DelPattern(c.Request.Context(), "search:*")
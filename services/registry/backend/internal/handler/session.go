@@ -0,0 +1,280 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/identity"
+	"github.com/GT8004/gt8004-common/session"
+	"github.com/GT8004/gt8004/internal/store"
+)
+
+// sessionTokens is the token pair returned by login and refresh.
+type sessionTokens struct {
+	SessionID             uuid.UUID `json:"session_id"`
+	Address               string    `json:"address"`
+	ChainID               int       `json:"chain_id"`
+	AccessToken           string    `json:"access_token"`
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+// sessionsEnabled reports whether Sign-In With Ethereum is configured and
+// answers 503 when it is not.
+func (h *Handler) sessionsEnabled(c *gin.Context) bool {
+	if !h.sessions.Signer.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "wallet sessions not configured"})
+		return false
+	}
+	return true
+}
+
+// issueTokens mints an access token for a session and pairs it with the
+// session's raw refresh token.
+func (h *Handler) issueTokens(ws *store.WalletSession, refreshToken string) (*sessionTokens, error) {
+	access, accessExpiresAt, err := h.sessions.Signer.Issue(ws.Address, ws.ID.String(), ws.ChainID, h.sessions.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionTokens{
+		SessionID:             ws.ID,
+		Address:               ws.Address,
+		ChainID:               ws.ChainID,
+		AccessToken:           access,
+		AccessTokenExpiresAt:  accessExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: ws.ExpiresAt,
+	}, nil
+}
+
+// SIWENonce handles POST /v1/auth/siwe/nonce
+// Returns a single-use nonce for the address to put in its EIP-4361 message.
+func (h *Handler) SIWENonce(c *gin.Context) {
+	if !h.sessionsEnabled(c) {
+		return
+	}
+
+	var req identity.SIWENonceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	resp, err := h.identity.CreateSIWENonce(req.Address)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid address") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address"})
+			return
+		}
+		h.logger.Error("failed to create siwe nonce", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create nonce"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"nonce":      resp.Challenge,
+		"expires_at": resp.ExpiresAt,
+		"domains":    h.sessions.SIWEDomains,
+	})
+}
+
+// SIWELogin handles POST /v1/auth/siwe/login
+// Verifies a signed EIP-4361 message and starts a session. Returns a
+// short-lived access token, a refresh token and the wallet's agents.
+func (h *Handler) SIWELogin(c *gin.Context) {
+	if !h.sessionsEnabled(c) {
+		return
+	}
+
+	var req identity.SIWEVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	msg, info, err := h.identity.VerifySIWE(req, h.sessions.SIWEDomains)
+	if err != nil {
+		h.logger.Warn("siwe login verification failed", zap.Error(err))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "signature verification failed"})
+		return
+	}
+
+	address := strings.ToLower(info.EVMAddress)
+	ws, refreshToken, err := h.store.CreateWalletSession(c.Request.Context(), address, msg.ChainID,
+		c.Request.UserAgent(), c.ClientIP(), h.sessions.RefreshTTL)
+	if err != nil {
+		h.logger.Error("failed to create wallet session", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+	tokens, err := h.issueTokens(ws, refreshToken)
+	if err != nil {
+		h.logger.Error("failed to issue session token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+
+	agents, err := h.store.GetAgentsByEVMAddress(c.Request.Context(), address)
+	if err != nil {
+		h.logger.Error("failed to query agents by evm address", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	if agents == nil {
+		agents = []store.Agent{}
+	}
+	registrations := info.Registrations
+	if registrations == nil {
+		registrations = []identity.Registration{}
+	}
+	orgs, err := h.store.ListWalletOrganizations(c.Request.Context(), address)
+	if err != nil {
+		h.logger.Error("failed to list wallet organizations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      walletChain(address),
+		Action:     "session.created",
+		TargetType: "session",
+		TargetID:   ws.ID.String(),
+		After:      gin.H{"chain_id": ws.ChainID, "expires_at": ws.ExpiresAt},
+		Actor:      address,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"session": tokens, "agents": agents, "registrations": registrations, "organizations": orgs})
+}
+
+// RefreshSession handles POST /v1/auth/refresh
+// Exchanges a refresh token for a new access token and a new refresh token;
+// the old refresh token stops working.
+func (h *Handler) RefreshSession(c *gin.Context) {
+	if !h.sessionsEnabled(c) {
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	ws, refreshToken, err := h.store.RotateRefreshToken(c.Request.Context(), req.RefreshToken, h.sessions.RefreshTTL)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired refresh token"})
+			return
+		}
+		h.logger.Error("failed to rotate refresh token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh session"})
+		return
+	}
+	tokens, err := h.issueTokens(ws, refreshToken)
+	if err != nil {
+		h.logger.Error("failed to issue session token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"session": tokens})
+}
+
+// Logout handles POST /v1/auth/logout
+// Revokes the current session; ?all=true revokes every session of the wallet.
+func (h *Handler) Logout(c *gin.Context) {
+	claims, _ := session.FromContext(c)
+
+	if c.Query("all") == "true" {
+		n, err := h.store.RevokeAllWalletSessions(c.Request.Context(), claims.Address)
+		if err != nil {
+			h.logger.Error("failed to revoke wallet sessions", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out"})
+			return
+		}
+		h.audit(c, auditEvent{
+			Chain:      walletChain(claims.Address),
+			Action:     "session.revoked_all",
+			TargetType: "wallet",
+			TargetID:   claims.Address,
+			After:      gin.H{"revoked": n},
+		})
+		c.JSON(http.StatusOK, gin.H{"status": "logged_out", "revoked": n})
+		return
+	}
+
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid session token"})
+		return
+	}
+	if err := h.store.RevokeWalletSession(c.Request.Context(), sessionID, claims.Address); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Error("failed to revoke wallet session", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      walletChain(claims.Address),
+		Action:     "session.revoked",
+		TargetType: "session",
+		TargetID:   sessionID.String(),
+		After:      gin.H{"reason": "logout"},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"status": "logged_out", "revoked": 1})
+}
+
+// ListSessions handles GET /v1/auth/sessions
+// Lists the wallet's live sessions.
+func (h *Handler) ListSessions(c *gin.Context) {
+	claims, _ := session.FromContext(c)
+
+	sessions, err := h.store.ListWalletSessions(c.Request.Context(), claims.Address)
+	if err != nil {
+		h.logger.Error("failed to list wallet sessions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions, "current_session_id": claims.SessionID})
+}
+
+// RevokeSession handles DELETE /v1/auth/sessions/:session_id
+func (h *Handler) RevokeSession(c *gin.Context) {
+	claims, _ := session.FromContext(c)
+
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session_id"})
+		return
+	}
+	if err := h.store.RevokeWalletSession(c.Request.Context(), sessionID, claims.Address); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+		h.logger.Error("failed to revoke wallet session", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      walletChain(claims.Address),
+		Action:     "session.revoked",
+		TargetType: "session",
+		TargetID:   sessionID.String(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/x402"
+	"github.com/GT8004/gt8004/internal/store"
+)
+
+// tierChallengeTTL is how long the nonce of a 402 tier challenge can be
+// paid against.
+const tierChallengeTTL = 30 * time.Minute
+
+// TierConfig configures paid tiers. Upgrading to a priced tier without an
+// active period answers with an x402 challenge to pay PayTo in USDC on
+// ChainID; the payment buys one Period. Paid upgrades are unavailable when
+// PayTo is empty.
+type TierConfig struct {
+	Checker *x402.TransferChecker
+	PayTo   string
+	ChainID int
+	Prices  map[string]float64
+	Period  time.Duration
+}
+
+func (t TierConfig) enabled() bool {
+	return t.Checker != nil && t.PayTo != "" && x402.Network(t.ChainID) != ""
+}
+
+func (t TierConfig) periodDays() int {
+	return int(t.Period / (24 * time.Hour))
+}
+
+// requirements describes how to pay for tier. nonce is set on challenges
+// and must be echoed in the payment; plans listed for display have none.
+func (t TierConfig) requirements(c *gin.Context, agentID, tier, payer, nonce string) x402.Requirements {
+	asset, _ := x402.USDCAsset(t.ChainID)
+	req := x402.Requirements{
+		Scheme:            x402.SchemeExact,
+		Network:           x402.Network(t.ChainID),
+		MaxAmountRequired: x402.USDCBaseUnits(t.Prices[tier]),
+		Resource:          c.Request.URL.Path,
+		Description:       fmt.Sprintf("GT8004 %s tier for %s (%d days)", tier, agentID, t.periodDays()),
+		MimeType:          "application/json",
+		PayTo:             t.PayTo,
+		MaxTimeoutSeconds: 600,
+		Asset:             asset,
+		Extra: map[string]any{
+			"tier":        tier,
+			"agent_id":    agentID,
+			"period_days": t.periodDays(),
+			"payer":       payer,
+		},
+	}
+	if nonce != "" {
+		req.Extra["nonce"] = nonce
+	}
+	return req
+}
+
+// tierChallengeSubject binds a challenge nonce to who pays for which tier
+// of which agent.
+func tierChallengeSubject(agent *store.Agent, tier, payer string) string {
+	return "tier:" + agent.ID.String() + ":" + tier + ":" + payer
+}
+
+// paymentRequired answers 402 with an x402 challenge for tier carrying a
+// fresh nonce. Only transfers mined after the challenge can pay it.
+func (h *Handler) paymentRequired(c *gin.Context, agent *store.Agent, tier, payer, msg string) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		h.logger.Error("failed to generate tier challenge", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create payment challenge"})
+		return
+	}
+	nonce := hex.EncodeToString(b)
+	err := h.store.SaveChallenge(c.Request.Context(), nonce, tierChallengeSubject(agent, tier, payer),
+		time.Now().Add(tierChallengeTTL))
+	if err != nil {
+		h.logger.Error("failed to save tier challenge", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create payment challenge"})
+		return
+	}
+	c.JSON(http.StatusPaymentRequired, x402.PaymentRequired{
+		X402Version: x402.Version,
+		Error:       msg,
+		Accepts:     []x402.Requirements{h.tiers.requirements(c, agent.AgentID, tier, payer, nonce)},
+	})
+}
+
+// settleTierPayment verifies the request's X-PAYMENT transaction on-chain
+// and records the period it buys. payer is the session wallet the USDC must
+// come from. The payment must echo the nonce of a challenge issued for this
+// agent, tier and payer, and its transaction must be mined after that
+// challenge. It writes the response and returns false when the payment is
+// missing or not accepted.
+func (h *Handler) settleTierPayment(c *gin.Context, agent *store.Agent, tier, payer string) (*store.TierSubscription, bool) {
+	price, priced := h.tiers.Prices[tier]
+	if !priced || !h.tiers.enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "paid tiers are not available"})
+		return nil, false
+	}
+
+	header := c.GetHeader(x402.PaymentHeader)
+	if header == "" {
+		h.paymentRequired(c, agent, tier, payer, "X-PAYMENT header is required")
+		return nil, false
+	}
+	payment, err := x402.DecodePayment(header)
+	if err != nil {
+		h.paymentRequired(c, agent, tier, payer, err.Error())
+		return nil, false
+	}
+	network := x402.Network(h.tiers.ChainID)
+	if payment.Network != network {
+		h.paymentRequired(c, agent, tier, payer, "payment must be made on "+network)
+		return nil, false
+	}
+	txHash := strings.ToLower(payment.Payload.Transaction)
+
+	if payment.Payload.Nonce == "" {
+		h.paymentRequired(c, agent, tier, payer, "payment must echo the challenge nonce")
+		return nil, false
+	}
+	subject, challengedAt, err := h.store.GetChallenge(c.Request.Context(), payment.Payload.Nonce)
+	if err != nil || subject != tierChallengeSubject(agent, tier, payer) {
+		h.paymentRequired(c, agent, tier, payer, "payment nonce is invalid or expired")
+		return nil, false
+	}
+
+	used, err := h.store.IsTierPaymentUsed(c.Request.Context(), txHash)
+	if err != nil {
+		h.logger.Error("failed to check tier payment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify payment"})
+		return nil, false
+	}
+	if used {
+		h.paymentRequired(c, agent, tier, payer, "payment already used")
+		return nil, false
+	}
+
+	_, err = h.tiers.Checker.Check(c.Request.Context(), h.tiers.ChainID, txHash, x402.Expected{
+		Amount:    price,
+		Payer:     payer,
+		PayTo:     h.tiers.PayTo,
+		NotBefore: challengedAt,
+	})
+	switch {
+	case errors.Is(err, x402.ErrReceiptUnavailable):
+		h.paymentRequired(c, agent, tier, payer, "payment transaction not found; retry once it is mined")
+		return nil, false
+	case errors.Is(err, x402.ErrTransferTooOld):
+		h.paymentRequired(c, agent, tier, payer, "payment transaction was mined before the challenge")
+		return nil, false
+	case errors.Is(err, x402.ErrTxReverted):
+		h.paymentRequired(c, agent, tier, payer, "payment transaction reverted")
+		return nil, false
+	case errors.Is(err, x402.ErrNoMatchingTransfer):
+		h.paymentRequired(c, agent, tier, payer,
+			fmt.Sprintf("transaction has no transfer of %s USDC from %s to %s", x402.USDCBaseUnits(price), payer, h.tiers.PayTo))
+		return nil, false
+	case err != nil:
+		h.logger.Error("failed to verify tier payment", zap.Error(err), zap.String("tx_hash", txHash))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "payment verification unavailable"})
+		return nil, false
+	}
+
+	chainID := h.tiers.ChainID
+	payerLower := strings.ToLower(payer)
+	sub := &store.TierSubscription{
+		Tier:       tier,
+		AmountUSDC: price,
+		ChainID:    &chainID,
+		TxHash:     &txHash,
+		Payer:      &payerLower,
+	}
+	if err := h.store.RecordTierPayment(c.Request.Context(), agent.ID, sub, h.tiers.Period); err != nil {
+		if errors.Is(err, store.ErrPaymentUsed) {
+			h.paymentRequired(c, agent, tier, payer, "payment already used")
+			return nil, false
+		}
+		h.logger.Error("failed to record tier payment", zap.Error(err), zap.String("tx_hash", txHash))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record payment"})
+		return nil, false
+	}
+	if _, _, err := h.store.ConsumeChallenge(c.Request.Context(), payment.Payload.Nonce); err != nil {
+		h.logger.Warn("failed to consume tier challenge", zap.Error(err))
+	}
+
+	c.Header(x402.PaymentResponseHeader, x402.SettlementResponse{
+		Success:     true,
+		Transaction: txHash,
+		Network:     network,
+		Payer:       payerLower,
+	}.Encode())
+	h.audit(c, auditEvent{
+		Chain:      agentChain(agent.AgentID),
+		Action:     "agent.tier_purchased",
+		TargetType: "agent",
+		TargetID:   agent.AgentID,
+		After: gin.H{
+			"tier":         tier,
+			"period_start": sub.PeriodStart,
+			"period_end":   sub.PeriodEnd,
+			"amount_usdc":  price,
+			"chain_id":     chainID,
+			"tx_hash":      txHash,
+			"payer":        payerLower,
+		},
+	})
+	return sub, true
+}
+
+// GetTierStatus handles GET /v1/services/:agent_id/tier
+// Returns the agent's tier, the period keeping it, past periods and what
+// paid tiers cost.
+func (h *Handler) GetTierStatus(c *gin.Context) {
+	agentDBID, exists := c.Get("agent_db_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	dbID := agentDBID.(uuid.UUID)
+
+	agent, err := h.store.GetAgentByDBID(c.Request.Context(), dbID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+		return
+	}
+
+	var active *store.TierSubscription
+	if agent.CurrentTier != "open" {
+		active, err = h.store.GetActiveTierSubscription(c.Request.Context(), dbID, agent.CurrentTier)
+		if err != nil {
+			h.logger.Error("failed to get tier subscription", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get tier status"})
+			return
+		}
+	}
+	periods, err := h.store.ListTierSubscriptions(c.Request.Context(), dbID)
+	if err != nil {
+		h.logger.Error("failed to list tier subscriptions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get tier status"})
+		return
+	}
+
+	resp := gin.H{
+		"agent_id":        agent.AgentID,
+		"tier":            agent.CurrentTier,
+		"tier_updated_at": agent.TierUpdatedAt,
+		"subscription":    active,
+		"periods":         periods,
+	}
+	if active != nil {
+		// Renewals queued after the active period extend it.
+		expires := active.PeriodEnd
+		for i := len(periods) - 1; i >= 0; i-- {
+			if p := periods[i]; p.Tier == active.Tier && p.PeriodStart.Equal(expires) {
+				expires = p.PeriodEnd
+			}
+		}
+		resp["expires_at"] = expires
+	}
+	if h.tiers.enabled() {
+		plans := make([]x402.Requirements, 0, len(h.tiers.Prices))
+		for tier := range h.tiers.Prices {
+			plans = append(plans, h.tiers.requirements(c, agent.AgentID, tier, agent.EVMAddress, ""))
+		}
+		resp["plans"] = plans
+	}
+	c.JSON(http.StatusOK, resp)
+}
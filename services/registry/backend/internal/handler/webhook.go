@@ -0,0 +1,428 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/webhook"
+	"github.com/GT8004/gt8004/internal/store"
+)
+
+// maxWebhookSubscriptions caps the endpoints an agent can subscribe.
+const maxWebhookSubscriptions = 10
+
+// WebhookConfig configures outbound webhooks. Notifier, if set, is woken
+// after an event is queued so it is delivered without waiting for the next
+// poll.
+type WebhookConfig struct {
+	Notifier interface{ Notify() }
+
+	// AllowHTTP accepts plain http:// endpoint URLs (local development).
+	AllowHTTP bool
+}
+
+// emit queues a webhook event for its subscribers. Failures are logged and
+// do not fail the request.
+func (h *Handler) emit(ctx context.Context, ev store.WebhookEvent) {
+	if err := h.store.EnqueueWebhookEvent(ctx, ev); err != nil {
+		h.logger.Error("failed to enqueue webhook event", zap.Error(err), zap.String("event_type", ev.Type))
+		return
+	}
+	if h.webhooks.Notifier != nil {
+		h.webhooks.Notifier.Notify()
+	}
+}
+
+// validateWebhookURL checks an endpoint URL and returns it normalized.
+func (h *Handler) validateWebhookURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return "", errors.New("url must be an absolute URL")
+	}
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && h.webhooks.AllowHTTP:
+	default:
+		return "", errors.New("url must use https")
+	}
+	if u.User != nil {
+		return "", errors.New("url must not contain credentials")
+	}
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// normalizeWebhookEvents checks event types and removes duplicates.
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, errors.New("events is required")
+	}
+	seen := make(map[string]bool, len(events))
+	out := make([]string, 0, len(events))
+	for _, e := range events {
+		e = strings.TrimSpace(e)
+		if !webhook.IsEvent(e) {
+			return nil, fmt.Errorf("unknown event type %q", e)
+		}
+		if !seen[e] {
+			seen[e] = true
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// webhookOwner returns whose subscriptions the request manages and the
+// audit chain for changes: the authenticated agent on
+// /v1/agents/:agent_id/webhooks, otherwise the session wallet (covering
+// every agent it owns).
+func (h *Handler) webhookOwner(c *gin.Context) (store.WebhookOwner, string, bool) {
+	if agentDBID, exists := c.Get("agent_db_id"); exists {
+		id := agentDBID.(uuid.UUID)
+		return store.WebhookOwner{AgentDBID: &id}, agentChain(c.GetString("agent_id")), true
+	}
+	wallet := h.WalletAddress(c)
+	if wallet == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return store.WebhookOwner{}, "", false
+	}
+	return store.WebhookOwner{Wallet: wallet}, walletChain(wallet), true
+}
+
+// webhookSubscription resolves :webhook_id for the request's owner and
+// writes the error response when it does not exist.
+func (h *Handler) webhookSubscription(c *gin.Context) (*store.WebhookSubscription, store.WebhookOwner, string, bool) {
+	owner, chain, ok := h.webhookOwner(c)
+	if !ok {
+		return nil, owner, "", false
+	}
+	id, err := uuid.Parse(c.Param("webhook_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook_id"})
+		return nil, owner, "", false
+	}
+	sub, err := h.store.GetWebhookSubscription(c.Request.Context(), owner, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+			return nil, owner, "", false
+		}
+		h.logger.Error("failed to get webhook subscription", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get webhook"})
+		return nil, owner, "", false
+	}
+	return sub, owner, chain, true
+}
+
+// ListWebhooks handles GET /v1/agents/:agent_id/webhooks and GET /v1/webhooks
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	owner, _, ok := h.webhookOwner(c)
+	if !ok {
+		return
+	}
+
+	subs, err := h.store.ListWebhookSubscriptions(c.Request.Context(), owner)
+	if err != nil {
+		h.logger.Error("failed to list webhook subscriptions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": subs, "events": webhook.Events})
+}
+
+// CreateWebhook handles POST /v1/agents/:agent_id/webhooks and POST /v1/webhooks
+// Subscribes an endpoint to event types for one agent or, on /v1/webhooks,
+// for every agent the session wallet owns. The signing secret is returned
+// only in this response.
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	owner, chain, ok := h.webhookOwner(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		URL         string   `json:"url" binding:"required,max=2048"`
+		Events      []string `json:"events" binding:"required"`
+		Description string   `json:"description" binding:"max=256"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	endpoint, err := h.validateWebhookURL(req.URL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existing, err := h.store.ListWebhookSubscriptions(c.Request.Context(), owner)
+	if err != nil {
+		h.logger.Error("failed to list webhook subscriptions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create webhook"})
+		return
+	}
+	if len(existing) >= maxWebhookSubscriptions {
+		c.JSON(http.StatusConflict, gin.H{"error": "too many webhooks; delete one first"})
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		h.logger.Error("failed to generate webhook secret", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create webhook"})
+		return
+	}
+	sub, err := h.store.CreateWebhookSubscription(c.Request.Context(), owner, endpoint, secret, events,
+		strings.TrimSpace(req.Description), h.WalletAddress(c))
+	if err != nil {
+		h.logger.Error("failed to create webhook subscription", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create webhook"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      chain,
+		Action:     "webhook.created",
+		TargetType: "webhook",
+		TargetID:   sub.ID.String(),
+		After:      gin.H{"url": sub.URL, "events": sub.Events},
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"webhook": sub, "secret": secret})
+}
+
+// GetWebhook handles GET /v1/agents/:agent_id/webhooks/:webhook_id and
+// GET /v1/webhooks/:webhook_id
+func (h *Handler) GetWebhook(c *gin.Context) {
+	sub, _, _, ok := h.webhookSubscription(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhook": sub})
+}
+
+// UpdateWebhook handles PUT /v1/agents/:agent_id/webhooks/:webhook_id
+// Changes the URL, events, description or active flag; omitted fields are
+// kept.
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	sub, owner, chain, ok := h.webhookSubscription(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		URL         *string  `json:"url" binding:"omitempty,max=2048"`
+		Events      []string `json:"events"`
+		Description *string  `json:"description" binding:"omitempty,max=256"`
+		Active      *bool    `json:"active"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	u := store.WebhookUpdate{Description: req.Description, Active: req.Active}
+	if req.URL != nil {
+		endpoint, err := h.validateWebhookURL(*req.URL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		u.URL = &endpoint
+	}
+	if req.Events != nil {
+		events, err := normalizeWebhookEvents(req.Events)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		u.Events = events
+	}
+
+	updated, err := h.store.UpdateWebhookSubscription(c.Request.Context(), owner, sub.ID, u)
+	if err != nil {
+		h.logger.Error("failed to update webhook subscription", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update webhook"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      chain,
+		Action:     "webhook.updated",
+		TargetType: "webhook",
+		TargetID:   sub.ID.String(),
+		Before:     gin.H{"url": sub.URL, "events": sub.Events, "active": sub.Active},
+		After:      gin.H{"url": updated.URL, "events": updated.Events, "active": updated.Active},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"webhook": updated})
+}
+
+// RotateWebhookSecret handles POST /v1/agents/:agent_id/webhooks/:webhook_id/rotate-secret
+// Replaces the signing secret and returns the new one. Deliveries already
+// queued are signed with the new secret.
+func (h *Handler) RotateWebhookSecret(c *gin.Context) {
+	sub, owner, chain, ok := h.webhookSubscription(c)
+	if !ok {
+		return
+	}
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		h.logger.Error("failed to generate webhook secret", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rotate webhook secret"})
+		return
+	}
+	if err := h.store.RotateWebhookSecret(c.Request.Context(), owner, sub.ID, secret); err != nil {
+		h.logger.Error("failed to rotate webhook secret", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rotate webhook secret"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      chain,
+		Action:     "webhook.secret_rotated",
+		TargetType: "webhook",
+		TargetID:   sub.ID.String(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"secret": secret})
+}
+
+// DeleteWebhook handles DELETE /v1/agents/:agent_id/webhooks/:webhook_id
+// Removes the subscription together with its delivery log.
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	sub, owner, chain, ok := h.webhookSubscription(c)
+	if !ok {
+		return
+	}
+	if err := h.store.DeleteWebhookSubscription(c.Request.Context(), owner, sub.ID); err != nil {
+		h.logger.Error("failed to delete webhook subscription", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete webhook"})
+		return
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      chain,
+		Action:     "webhook.deleted",
+		TargetType: "webhook",
+		TargetID:   sub.ID.String(),
+		Before:     gin.H{"url": sub.URL, "events": sub.Events},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// ListWebhookDeliveries handles GET /v1/agents/:agent_id/webhooks/:webhook_id/deliveries
+// Supports ?status= (pending, delivering, succeeded, failed) and ?limit=
+// (default 50, max 200).
+func (h *Handler) ListWebhookDeliveries(c *gin.Context) {
+	sub, _, _, ok := h.webhookSubscription(c)
+	if !ok {
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", "pending", "delivering", "succeeded", "failed":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.store.ListWebhookDeliveries(c.Request.Context(), sub.ID, status, limit)
+	if err != nil {
+		h.logger.Error("failed to list webhook deliveries", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// GetWebhookDelivery handles GET /v1/agents/:agent_id/webhooks/:webhook_id/deliveries/:delivery_id
+// Returns the delivery with each attempt's status code, error and response.
+func (h *Handler) GetWebhookDelivery(c *gin.Context) {
+	sub, _, _, ok := h.webhookSubscription(c)
+	if !ok {
+		return
+	}
+	id, err := uuid.Parse(c.Param("delivery_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery_id"})
+		return
+	}
+
+	d, attempts, err := h.store.GetWebhookDelivery(c.Request.Context(), sub.ID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+			return
+		}
+		h.logger.Error("failed to get webhook delivery", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get delivery"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"delivery": d, "attempts": attempts})
+}
+
+// RedeliverWebhook handles POST /v1/agents/:agent_id/webhooks/:webhook_id/deliveries/:delivery_id/redeliver
+// Queues the same event again as a new delivery.
+func (h *Handler) RedeliverWebhook(c *gin.Context) {
+	sub, _, chain, ok := h.webhookSubscription(c)
+	if !ok {
+		return
+	}
+	id, err := uuid.Parse(c.Param("delivery_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery_id"})
+		return
+	}
+
+	newID, err := h.store.RedeliverWebhook(c.Request.Context(), sub.ID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+			return
+		}
+		h.logger.Error("failed to redeliver webhook", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to redeliver"})
+		return
+	}
+	if h.webhooks.Notifier != nil {
+		h.webhooks.Notifier.Notify()
+	}
+
+	h.audit(c, auditEvent{
+		Chain:      chain,
+		Action:     "webhook.redelivered",
+		TargetType: "webhook_delivery",
+		TargetID:   newID.String(),
+		After:      gin.H{"redelivery_of": id, "webhook_id": sub.ID},
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"delivery_id": newID, "status": "pending"})
+}
@@ -0,0 +1,203 @@
+// Package ownership keeps agents linked to ERC-8004 tokens owned by the
+// token's current holder.
+package ownership
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004/internal/erc8004"
+	"github.com/GT8004/gt8004/internal/store"
+)
+
+// maxBlockRange bounds one eth_getLogs request; public RPCs reject wide ranges.
+const maxBlockRange = 2000
+
+// Config configures the watcher.
+type Config struct {
+	// Interval is how often each chain is scanned.
+	Interval time.Duration
+	// Confirmations is how many blocks a log must be buried under before it
+	// is applied, so shallow reorgs do not move ownership.
+	Confirmations uint64
+}
+
+// Notifier is woken after ownership.transferred events are queued.
+type Notifier interface {
+	Notify()
+}
+
+// Watcher scans the identity registry on every configured chain for
+// Transfer logs of linked tokens and moves the agent to the new owner.
+type Watcher struct {
+	store    *store.Store
+	registry *erc8004.Registry
+	notifier Notifier
+	cfg      Config
+	logger   *zap.Logger
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewWatcher creates a transfer watcher.
+func NewWatcher(s *store.Store, registry *erc8004.Registry, notifier Notifier, cfg Config, logger *zap.Logger) *Watcher {
+	return &Watcher{
+		store:    s,
+		registry: registry,
+		notifier: notifier,
+		cfg:      cfg,
+		logger:   logger,
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Start begins scanning in a background goroutine.
+func (w *Watcher) Start() {
+	go func() {
+		defer close(w.doneCh)
+		ticker := time.NewTicker(w.cfg.Interval)
+		defer ticker.Stop()
+
+		w.logger.Info("ownership watcher started",
+			zap.Duration("interval", w.cfg.Interval),
+			zap.Uint64("confirmations", w.cfg.Confirmations))
+
+		for {
+			for _, client := range w.registry.Clients() {
+				if client.Connected() {
+					w.scan(client)
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-w.stopCh:
+				w.logger.Info("ownership watcher stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop signals the watcher to stop after the scan in flight.
+func (w *Watcher) Stop() {
+	close(w.stopCh)
+	<-w.doneCh
+}
+
+// scan applies the Transfer logs between the chain's cursor and the latest
+// confirmed block. The first scan of a chain starts at the head and instead
+// compares every linked token's ownerOf, catching transfers made before the
+// watcher ran.
+func (w *Watcher) scan(client *erc8004.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	chainID := client.ChainID()
+	logger := w.logger.With(zap.Int("chain_id", chainID))
+
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		logger.Warn("failed to get block number", zap.Error(err))
+		return
+	}
+	if head < w.cfg.Confirmations {
+		return
+	}
+	safe := head - w.cfg.Confirmations
+
+	cursor, ok, err := w.store.GetTransferCursor(ctx, chainID)
+	if err != nil {
+		logger.Error("failed to get transfer cursor", zap.Error(err))
+		return
+	}
+	if !ok {
+		w.reconcile(ctx, client)
+		if err := w.store.SetTransferCursor(ctx, chainID, safe); err != nil {
+			logger.Error("failed to set transfer cursor", zap.Error(err))
+		}
+		return
+	}
+
+	for from := cursor + 1; from <= safe; {
+		to := min(from+maxBlockRange-1, safe)
+		events, err := client.FilterTransfers(ctx, from, to)
+		if err != nil {
+			logger.Warn("failed to filter transfer logs",
+				zap.Uint64("from", from), zap.Uint64("to", to), zap.Error(err))
+			return
+		}
+		for _, ev := range events {
+			txHash, logIndex, block := ev.TxHash, int(ev.LogIndex), int64(ev.BlockNumber)
+			t := &store.OwnershipTransfer{
+				ChainID:     chainID,
+				TokenID:     ev.TokenID,
+				ToAddress:   ev.To,
+				TxHash:      &txHash,
+				LogIndex:    &logIndex,
+				BlockNumber: &block,
+			}
+			// The cursor stays put so a failed log is retried next scan.
+			if !w.apply(ctx, t) {
+				return
+			}
+		}
+		if err := w.store.SetTransferCursor(ctx, chainID, to); err != nil {
+			logger.Error("failed to set transfer cursor", zap.Error(err))
+			return
+		}
+		from = to + 1
+	}
+}
+
+// reconcile moves every linked token whose on-chain owner differs from the
+// registry's.
+func (w *Watcher) reconcile(ctx context.Context, client *erc8004.Client) {
+	tokens, err := w.store.ListLinkedTokens(ctx, client.ChainID())
+	if err != nil {
+		w.logger.Error("failed to list linked tokens", zap.Int("chain_id", client.ChainID()), zap.Error(err))
+		return
+	}
+	for _, tok := range tokens {
+		owner, err := client.VerifyOwnership(ctx, tok.TokenID)
+		if err != nil || owner == tok.Owner {
+			continue // burned tokens are left to ReconcileERC8004
+		}
+		w.apply(ctx, &store.OwnershipTransfer{
+			ChainID:   client.ChainID(),
+			TokenID:   tok.TokenID,
+			ToAddress: owner,
+		})
+	}
+}
+
+// apply moves one agent. It reports false when the change could not be
+// stored.
+func (w *Watcher) apply(ctx context.Context, t *store.OwnershipTransfer) bool {
+	applied, err := w.store.ApplyOwnershipTransfer(ctx, t)
+	if err != nil {
+		w.logger.Error("failed to apply ownership transfer",
+			zap.Int("chain_id", t.ChainID), zap.Int64("token_id", t.TokenID), zap.Error(err))
+		return false
+	}
+	if !applied {
+		return true
+	}
+
+	w.logger.Info("agent ownership transferred",
+		zap.String("agent_id", t.AgentID),
+		zap.Int("chain_id", t.ChainID),
+		zap.Int64("token_id", t.TokenID),
+		zap.String("from", t.FromAddress),
+		zap.String("to", t.ToAddress),
+		zap.Int64("revoked_api_keys", t.RevokedAPIKeys),
+		zap.Int64("revoked_sessions", t.RevokedSessions))
+
+	if w.notifier != nil {
+		w.notifier.Notify()
+	}
+	return true
+}
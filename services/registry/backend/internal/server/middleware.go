@@ -1,28 +1,34 @@
 package server
 
 import (
-	"crypto/sha256"
 	"crypto/subtle"
-	"encoding/hex"
-	"fmt"
+	"errors"
 	"net/http"
 	"strings"
-	"sync"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/GT8004/gt8004-common/apikey"
+	"github.com/GT8004/gt8004-common/org"
+	"github.com/GT8004/gt8004-common/ratelimit"
+	"github.com/GT8004/gt8004-common/session"
 	"github.com/GT8004/gt8004/internal/handler"
 )
 
 const (
-	ContextKeyAgentDBID = "agent_db_id"
-	ContextKeyAgentID   = "agent_id"
+	ContextKeyAgentDBID     = "agent_db_id"
+	ContextKeyAgentID       = "agent_id"
+	ContextKeyWalletAddress = "wallet_address"
+	ContextKeyMemberRole    = "member_role"
+	ContextKeyAPIKeyID      = "api_key_id"
+	ContextKeyAPIKeyIssuer  = "api_key_created_by"
+	ContextKeyAgentTier     = ratelimit.ContextKeyTier
 )
 
-func APIKeyAuthMiddleware(h *handler.Handler) gin.HandlerFunc {
+// APIKeyAuthMiddleware requires an API key carrying scope (any live key when
+// scope is empty) that is allowed from the client's IP.
+func APIKeyAuthMiddleware(h *handler.Handler, scope string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Prefer X-Forwarded-Authorization (set by the API Gateway before
 		// overwriting Authorization with the GCP identity token).
@@ -41,31 +47,35 @@ func APIKeyAuthMiddleware(h *handler.Handler) gin.HandlerFunc {
 			return
 		}
 
-		rawKey := parts[1]
-		hash := sha256.Sum256([]byte(rawKey))
-		keyHash := hex.EncodeToString(hash[:])
-
-		agentAuth, err := h.Store().ValidateAPIKey(c.Request.Context(), keyHash)
+		agentAuth, err := h.Store().ValidateAPIKey(c.Request.Context(), apikey.Hash(parts[1]), scope, c.ClientIP())
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+			status, msg := apikey.HTTPStatus(err)
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
 			return
 		}
 
 		c.Set(ContextKeyAgentDBID, agentAuth.AgentDBID)
 		c.Set(ContextKeyAgentID, agentAuth.AgentID)
+		c.Set(ContextKeyAPIKeyID, agentAuth.KeyID.String())
+		c.Set(ContextKeyAgentTier, agentAuth.Tier)
 		c.Next()
 	}
 }
 
-// WalletOwnerAuthMiddleware authenticates via API key or wallet address.
-// Wallet owners are treated as root owners of their agents.
-func WalletOwnerAuthMiddleware(h *handler.Handler) gin.HandlerFunc {
+// WalletOwnerAuthMiddleware authenticates via an API key with the
+// registry:admin scope or a wallet session. A wallet must be the agent's
+// registered wallet (its owner) or a member of the agent's organization
+// whose role grants perm; a key must have been issued by such a role. The
+// raw X-Wallet-Address header is still accepted until the deprecation
+// deadline, except for org.PermAgentDelete, which needs a wallet session.
+func WalletOwnerAuthMiddleware(h *handler.Handler, perm org.Permission) gin.HandlerFunc {
+	sessionOnly := perm == org.PermAgentDelete
 	return func(c *gin.Context) {
 		h.Logger().Debug("WalletOwnerAuth - Request",
 			zap.String("method", c.Request.Method),
 			zap.String("path", c.Request.URL.Path),
 			zap.Bool("has_auth", c.GetHeader("Authorization") != ""),
-			zap.String("X-Wallet-Address", c.GetHeader("X-Wallet-Address")),
+			zap.Bool("has_session", hasSession(c)),
 			zap.String("agent_id_param", c.Param("agent_id")))
 
 		// 1) Try API key (prefer forwarded header from API Gateway)
@@ -73,13 +83,12 @@ func WalletOwnerAuthMiddleware(h *handler.Handler) gin.HandlerFunc {
 		if authHeader == "" {
 			authHeader = c.GetHeader("Authorization")
 		}
-		if authHeader != "" {
+		if authHeader != "" && !sessionOnly {
 			h.Logger().Info("WalletOwnerAuth - Trying API key auth")
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
-				hash := sha256.Sum256([]byte(parts[1]))
-				keyHash := hex.EncodeToString(hash[:])
-				if agentAuth, err := h.Store().ValidateAPIKey(c.Request.Context(), keyHash); err == nil {
+				agentAuth, err := h.Store().ValidateAPIKey(c.Request.Context(), apikey.Hash(parts[1]), apikey.ScopeRegistryAdmin, c.ClientIP())
+				if err == nil {
 					// If the route has an :agent_id param, the API key must belong to that agent.
 					// This prevents any agent from accessing or modifying another agent's resources.
 					if paramAgentID := c.Param("agent_id"); paramAgentID != "" && agentAuth.AgentID != paramAgentID {
@@ -89,19 +98,37 @@ func WalletOwnerAuthMiddleware(h *handler.Handler) gin.HandlerFunc {
 						c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api key does not belong to this agent"})
 						return
 					}
+					if !agentAuth.Role.Can(perm) {
+						c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient role", "role": agentAuth.Role, "required": perm})
+						return
+					}
 					h.Logger().Info("WalletOwnerAuth - API key auth SUCCESS")
 					c.Set(ContextKeyAgentDBID, agentAuth.AgentDBID)
 					c.Set(ContextKeyAgentID, agentAuth.AgentID)
+					c.Set(ContextKeyAPIKeyID, agentAuth.KeyID.String())
+					c.Set(ContextKeyAPIKeyIssuer, agentAuth.CreatedBy)
+					c.Set(ContextKeyMemberRole, agentAuth.Role)
+					c.Set(ContextKeyAgentTier, agentAuth.Tier)
 					c.Next()
 					return
+				} else if !errors.Is(err, apikey.ErrInvalidKey) {
+					// A real key that may not be used here: do not fall back.
+					status, msg := apikey.HTTPStatus(err)
+					c.AbortWithStatusJSON(status, gin.H{"error": msg})
+					return
 				} else {
 					h.Logger().Warn("WalletOwnerAuth - API key validation failed", zap.Error(err))
 				}
 			}
 		}
 
-		// 2) Try wallet address — verify the agent belongs to this wallet
-		walletAddr := c.GetHeader("X-Wallet-Address")
+		if sessionOnly && !hasSession(c) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "wallet session required"})
+			return
+		}
+
+		// 2) Try wallet session — verify the wallet's role for the agent
+		walletAddr := h.WalletAddress(c)
 		agentID := c.Param("agent_id")
 		h.Logger().Info("WalletOwnerAuth - Trying wallet auth",
 			zap.String("wallet", walletAddr),
@@ -113,15 +140,44 @@ func WalletOwnerAuthMiddleware(h *handler.Handler) gin.HandlerFunc {
 				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "agent not found"})
 				return
 			}
-			h.Logger().Info("Checking wallet ownership",
-				zap.String("agent_evm", agent.EVMAddress),
-				zap.String("wallet", walletAddr))
+			role := org.RoleOwner
 			if !strings.EqualFold(agent.EVMAddress, walletAddr) {
+				role, err = h.Store().AgentRole(c.Request.Context(), agent.ID, walletAddr)
+				if err != nil {
+					h.Logger().Error("failed to resolve agent role", zap.Error(err))
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check access"})
+					return
+				}
+			}
+			if claims, ok := session.FromContext(c); ok {
+				revoked, err := h.Store().IsSessionRevokedForAgent(c.Request.Context(), claims.SessionID, agent.ID)
+				if err != nil {
+					h.Logger().Error("failed to check agent session revocation", zap.Error(err))
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check access"})
+					return
+				}
+				if revoked {
+					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked for this agent"})
+					return
+				}
+			}
+			h.Logger().Info("Checking wallet access",
+				zap.String("agent_evm", agent.EVMAddress),
+				zap.String("wallet", walletAddr),
+				zap.String("role", string(role)))
+			if role == "" {
 				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "wallet does not own this agent"})
 				return
 			}
+			if !role.Can(perm) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient role", "role": role, "required": perm})
+				return
+			}
 			c.Set(ContextKeyAgentDBID, agent.ID)
 			c.Set(ContextKeyAgentID, agent.AgentID)
+			c.Set(ContextKeyWalletAddress, walletAddr)
+			c.Set(ContextKeyMemberRole, role)
+			c.Set(ContextKeyAgentTier, agent.CurrentTier)
 			c.Next()
 			return
 		}
@@ -131,139 +187,36 @@ func WalletOwnerAuthMiddleware(h *handler.Handler) gin.HandlerFunc {
 	}
 }
 
-// InternalAuthMiddleware validates the shared secret for service-to-service calls.
-// The secret is required — if empty, all internal requests are rejected.
-func InternalAuthMiddleware(secret string) gin.HandlerFunc {
+// RequireSessionMiddleware rejects requests without a valid wallet session
+// token. It must run after session.Middleware.
+func RequireSessionMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if secret == "" {
-			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "internal auth not configured"})
-			return
-		}
-		token := c.GetHeader("X-Internal-Secret")
-		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		if !hasSession(c) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "wallet session required"})
 			return
 		}
 		c.Next()
 	}
 }
 
-// ipRateLimiter tracks request counts per IP within a sliding window.
-type ipRateLimiter struct {
-	mu       sync.Mutex
-	requests map[string][]time.Time
-	limit    int
-	window   time.Duration
-}
-
-func newIPRateLimiter(limit int, window time.Duration) *ipRateLimiter {
-	rl := &ipRateLimiter{
-		requests: make(map[string][]time.Time),
-		limit:    limit,
-		window:   window,
-	}
-	go rl.cleanup()
-	return rl
-}
-
-func (rl *ipRateLimiter) allow(ip string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	now := time.Now()
-	cutoff := now.Add(-rl.window)
-
-	// Remove expired entries
-	reqs := rl.requests[ip]
-	valid := reqs[:0]
-	for _, t := range reqs {
-		if t.After(cutoff) {
-			valid = append(valid, t)
-		}
-	}
-
-	if len(valid) >= rl.limit {
-		rl.requests[ip] = valid
-		return false
-	}
-	rl.requests[ip] = append(valid, now)
-	return true
-}
-
-func (rl *ipRateLimiter) cleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-	for range ticker.C {
-		rl.mu.Lock()
-		cutoff := time.Now().Add(-rl.window)
-		for ip, reqs := range rl.requests {
-			valid := reqs[:0]
-			for _, t := range reqs {
-				if t.After(cutoff) {
-					valid = append(valid, t)
-				}
-			}
-			if len(valid) == 0 {
-				delete(rl.requests, ip)
-			} else {
-				rl.requests[ip] = valid
-			}
-		}
-		rl.mu.Unlock()
-	}
+func hasSession(c *gin.Context) bool {
+	_, ok := session.FromContext(c)
+	return ok
 }
 
-// RateLimitMiddleware limits requests per IP address.
-// limit: max requests, window: time window.
-func RateLimitMiddleware(limit int, window time.Duration) gin.HandlerFunc {
-	limiter := newIPRateLimiter(limit, window)
+// InternalAuthMiddleware validates the shared secret for service-to-service calls.
+// The secret is required — if empty, all internal requests are rejected.
+func InternalAuthMiddleware(secret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		if !limiter.allow(ip) {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+		if secret == "" {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "internal auth not configured"})
 			return
 		}
-		c.Next()
-	}
-}
-
-// TierRequiredMiddleware checks that the authenticated agent has at least the required tier.
-func TierRequiredMiddleware(requiredTier string, h *handler.Handler) gin.HandlerFunc {
-	tierLevels := map[string]int{"open": 1, "lite": 2}
-
-	return func(c *gin.Context) {
-		agentDBID, exists := c.Get(ContextKeyAgentDBID)
-		if !exists {
+		token := c.GetHeader("X-Internal-Secret")
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			return
 		}
-
-		dbID, ok := agentDBID.(uuid.UUID)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "invalid agent context"})
-			return
-		}
-
-		agent, err := h.Store().GetAgentByDBID(c.Request.Context(), dbID)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "agent lookup failed"})
-			return
-		}
-
-		currentLevel := tierLevels[agent.CurrentTier]
-		requiredLevel := tierLevels[requiredTier]
-
-		if currentLevel < requiredLevel {
-			agentID, _ := c.Get(ContextKeyAgentID)
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-				"error":         "tier_required",
-				"current_tier":  agent.CurrentTier,
-				"required_tier": requiredTier,
-				"upgrade_url":   fmt.Sprintf("/v1/services/%s/tier", agentID),
-			})
-			return
-		}
-
 		c.Next()
 	}
 }
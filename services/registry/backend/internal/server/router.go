@@ -2,11 +2,14 @@ package server
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/GT8004/gt8004-common/clientip"
+	"github.com/GT8004/gt8004-common/org"
+	"github.com/GT8004/gt8004-common/ratelimit"
+	"github.com/GT8004/gt8004-common/session"
 	"github.com/GT8004/gt8004/internal/config"
 	"github.com/GT8004/gt8004/internal/handler"
 )
@@ -45,8 +48,9 @@ func securityHeaders() gin.HandlerFunc {
 	}
 }
 
-func NewRouter(cfg *config.Config, h *handler.Handler, logger *zap.Logger) *gin.Engine {
+func NewRouter(cfg *config.Config, h *handler.Handler, limiter *ratelimit.Limiter, logger *zap.Logger) *gin.Engine {
 	r := gin.New()
+	clientip.Configure(r, cfg.TrustedProxies)
 	r.Use(corsMiddleware(), securityHeaders(), gin.Logger(), gin.Recovery())
 
 	// Health
@@ -56,15 +60,55 @@ func NewRouter(cfg *config.Config, h *handler.Handler, logger *zap.Logger) *gin.
 	// ERC-8004 agent descriptor
 	r.GET("/.well-known/agent.json", h.AgentDescriptor)
 
+	// Rate limits: every caller by API key, session wallet or IP, and by
+	// IP regardless, since bearer keys are counted before they are
+	// validated; sign-in by IP; authenticated agents by tier once auth has
+	// run.
+	callerLimit := limiter.Middleware(ratelimit.Policy{
+		Name:  "registry:caller",
+		Key:   ratelimit.First(ratelimit.ByAPIKey, ratelimit.ByWallet, ratelimit.ByIP),
+		Limit: ratelimit.PerMinute(cfg.RateLimitCaller),
+	})
+	ipLimit := limiter.Middleware(ratelimit.Policy{
+		Name:  "registry:ip",
+		Key:   ratelimit.ByIP,
+		Limit: ratelimit.PerMinute(cfg.RateLimitIP),
+	})
+	authLimit := limiter.Middleware(ratelimit.Policy{
+		Name:  "registry:auth",
+		Key:   ratelimit.ByIP,
+		Limit: ratelimit.PerMinute(cfg.RateLimitAuth),
+	})
+	agentLimit := limiter.Middleware(ratelimit.Policy{
+		Name:  "registry:agent",
+		Key:   ratelimit.ByAgent,
+		Limit: cfg.RateLimitAgentTiers["open"],
+		Tiers: cfg.RateLimitAgentTiers,
+	})
+
 	v1 := r.Group("/v1")
+	v1.Use(session.Middleware(h.SessionSigner(), h.Store()), ipLimit, callerLimit)
 
 	// Auth (public, rate-limited)
 	auth := v1.Group("/auth")
-	auth.Use(RateLimitMiddleware(20, time.Minute))
+	auth.Use(authLimit)
 	{
 		auth.POST("/challenge", h.AuthChallenge)
 		auth.POST("/verify", h.AuthVerify)
 		auth.POST("/wallet-login", h.WalletLogin)
+		auth.POST("/siwe/nonce", h.SIWENonce)
+		auth.POST("/siwe/login", h.SIWELogin)
+		auth.POST("/refresh", h.RefreshSession)
+	}
+
+	// Wallet sessions (session-authenticated)
+	sessions := v1.Group("/auth")
+	sessions.Use(RequireSessionMiddleware())
+	{
+		sessions.POST("/logout", h.Logout)
+		sessions.GET("/sessions", h.ListSessions)
+		sessions.DELETE("/sessions/:session_id", h.RevokeSession)
+		sessions.GET("/audit-log", h.ListWalletAuditLog)
 	}
 
 	// ERC-8004 token verification (public)
@@ -78,13 +122,19 @@ func NewRouter(cfg *config.Config, h *handler.Handler, logger *zap.Logger) *gin.
 	// === Service Lifecycle ===
 	v1.POST("/services/register", h.RegisterService)
 
+	// Owner routes accept an API key or a wallet whose role for the agent
+	// grants the permission.
+	canView := WalletOwnerAuthMiddleware(h, org.PermAgentView)
+	canManage := WalletOwnerAuthMiddleware(h, org.PermAgentManage)
+	canDelete := WalletOwnerAuthMiddleware(h, org.PermAgentDelete)
+
 	servicesAuth := v1.Group("/services")
-	servicesAuth.Use(WalletOwnerAuthMiddleware(h))
 	{
-		servicesAuth.GET("/:agent_id", h.GetService)
-		servicesAuth.PUT("/:agent_id/tier", h.UpdateTier)
-		servicesAuth.PUT("/:agent_id/link-erc8004", h.LinkERC8004)
-		servicesAuth.DELETE("/:agent_id", h.DeregisterService)
+		servicesAuth.GET("/:agent_id", canView, agentLimit, h.GetService)
+		servicesAuth.GET("/:agent_id/tier", canView, agentLimit, h.GetTierStatus)
+		servicesAuth.PUT("/:agent_id/tier", canManage, agentLimit, h.UpdateTier)
+		servicesAuth.PUT("/:agent_id/link-erc8004", canManage, agentLimit, h.LinkERC8004)
+		servicesAuth.DELETE("/:agent_id", canDelete, agentLimit, h.DeregisterService)
 	}
 
 	// === Agent Routes (backwards compatible) ===
@@ -93,7 +143,7 @@ func NewRouter(cfg *config.Config, h *handler.Handler, logger *zap.Logger) *gin.
 	v1.GET("/agents/search", h.SearchAgents)
 	v1.GET("/agents/wallet/:address", h.ListWalletAgents)
 
-	// Agent reviews (public read, wallet-auth write)
+	// Agent reviews (public read, wallet-session write)
 	v1.GET("/agents/:agent_id/reviews", h.ListReviews)
 	v1.POST("/agents/:agent_id/reviews", h.CreateReview)
 
@@ -106,28 +156,81 @@ func NewRouter(cfg *config.Config, h *handler.Handler, logger *zap.Logger) *gin.
 
 	// API key authenticated routes (write operations)
 	authenticated := v1.Group("")
-	authenticated.Use(APIKeyAuthMiddleware(h))
+	authenticated.Use(APIKeyAuthMiddleware(h, ""), agentLimit)
 	{
 		authenticated.GET("/agents/me", h.GetMe)
 	}
 
-	// Owner-authenticated routes (API key or wallet address)
+	// Owner-authenticated routes (API key or wallet with a managing role)
 	ownerAuth := v1.Group("")
-	ownerAuth.Use(WalletOwnerAuthMiddleware(h))
+	ownerAuth.Use(canManage, agentLimit)
 	{
-		ownerAuth.GET("/agents/:agent_id/api-key", h.GetAPIKey)
+		ownerAuth.GET("/agents/:agent_id/api-keys", h.ListAPIKeys)
+		ownerAuth.POST("/agents/:agent_id/api-keys", h.CreateAPIKey)
+		ownerAuth.POST("/agents/:agent_id/api-keys/:key_id/rotate", h.RotateAPIKey)
+		ownerAuth.DELETE("/agents/:agent_id/api-keys/:key_id", h.RevokeAPIKey)
 		ownerAuth.POST("/agents/:agent_id/api-key/regenerate", h.RegenerateAPIKey)
+		ownerAuth.GET("/agents/:agent_id/audit-log", h.ListAgentAuditLog)
+		ownerAuth.GET("/agents/:agent_id/ownership-history", h.ListOwnershipTransfers)
+		ownerAuth.GET("/agents/:agent_id/webhooks", h.ListWebhooks)
+		ownerAuth.POST("/agents/:agent_id/webhooks", h.CreateWebhook)
+		ownerAuth.GET("/agents/:agent_id/webhooks/:webhook_id", h.GetWebhook)
+		ownerAuth.PUT("/agents/:agent_id/webhooks/:webhook_id", h.UpdateWebhook)
+		ownerAuth.DELETE("/agents/:agent_id/webhooks/:webhook_id", h.DeleteWebhook)
+		ownerAuth.POST("/agents/:agent_id/webhooks/:webhook_id/rotate-secret", h.RotateWebhookSecret)
+		ownerAuth.GET("/agents/:agent_id/webhooks/:webhook_id/deliveries", h.ListWebhookDeliveries)
+		ownerAuth.GET("/agents/:agent_id/webhooks/:webhook_id/deliveries/:delivery_id", h.GetWebhookDelivery)
+		ownerAuth.POST("/agents/:agent_id/webhooks/:webhook_id/deliveries/:delivery_id/redeliver", h.RedeliverWebhook)
+	}
+
+	// === Wallet-wide webhooks (every agent the session wallet owns) ===
+	webhooks := v1.Group("/webhooks")
+	webhooks.Use(RequireSessionMiddleware())
+	{
+		webhooks.GET("", h.ListWebhooks)
+		webhooks.POST("", h.CreateWebhook)
+		webhooks.GET("/:webhook_id", h.GetWebhook)
+		webhooks.PUT("/:webhook_id", h.UpdateWebhook)
+		webhooks.DELETE("/:webhook_id", h.DeleteWebhook)
+		webhooks.POST("/:webhook_id/rotate-secret", h.RotateWebhookSecret)
+		webhooks.GET("/:webhook_id/deliveries", h.ListWebhookDeliveries)
+		webhooks.GET("/:webhook_id/deliveries/:delivery_id", h.GetWebhookDelivery)
+		webhooks.POST("/:webhook_id/deliveries/:delivery_id/redeliver", h.RedeliverWebhook)
+	}
+
+	// === Organizations (wallet-session authenticated) ===
+	orgs := v1.Group("/orgs")
+	orgs.Use(RequireSessionMiddleware())
+	{
+		orgs.POST("", h.CreateOrganization)
+		orgs.GET("", h.ListOrganizations)
+		orgs.GET("/:org_id", h.GetOrganization)
+		orgs.DELETE("/:org_id", h.DeleteOrganization)
+		orgs.POST("/:org_id/agents", h.AddOrgAgent)
+		orgs.DELETE("/:org_id/agents/:agent_id", h.RemoveOrgAgent)
+		orgs.PUT("/:org_id/members/:address", h.UpdateOrgMember)
+		orgs.DELETE("/:org_id/members/:address", h.RemoveOrgMember)
+		orgs.POST("/:org_id/invitations", h.CreateOrgInvitation)
+		orgs.GET("/:org_id/invitations", h.ListOrgInvitations)
+		orgs.DELETE("/:org_id/invitations/:invitation_id", h.RevokeOrgInvitation)
+		orgs.GET("/:org_id/audit-log", h.ListOrgAuditLog)
 	}
 
+	// Invitations are accepted by a wallet signature, without a session.
+	v1.GET("/invitations", RequireSessionMiddleware(), h.ListMyInvitations)
+	v1.GET("/invitations/:invitation_id", h.GetInvitation)
+	v1.POST("/invitations/:invitation_id/accept", authLimit, h.AcceptInvitation)
+
 	// === Internal API (service-to-service, shared-secret auth) ===
 	internal := r.Group("/internal")
 	internal.Use(InternalAuthMiddleware(cfg.InternalSecret))
 	{
 		internal.GET("/agents/:slug", h.InternalGetAgent)
-		internal.POST("/validate-key", h.InternalValidateKey)
+		internal.POST("/validate-key/:scope", h.InternalValidateKey)
 		internal.PUT("/agents/:id/stats", h.InternalUpdateAgentStats)
 		internal.PUT("/agents/:id/customers-count", h.InternalUpdateCustomersCount)
 		internal.POST("/reconcile", h.InternalReconcile)
+		internal.GET("/audit-log", h.InternalAuditLog)
 	}
 
 	return r
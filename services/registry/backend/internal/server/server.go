@@ -8,6 +8,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/GT8004/gt8004-common/ratelimit"
 	"github.com/GT8004/gt8004/internal/config"
 	"github.com/GT8004/gt8004/internal/handler"
 )
@@ -18,8 +19,8 @@ type Server struct {
 	logger     *zap.Logger
 }
 
-func New(cfg *config.Config, h *handler.Handler, logger *zap.Logger) *Server {
-	router := NewRouter(cfg, h, logger)
+func New(cfg *config.Config, h *handler.Handler, limiter *ratelimit.Limiter, logger *zap.Logger) *Server {
+	router := NewRouter(cfg, h, limiter, logger)
 	return &Server{
 		httpServer: &http.Server{
 			Addr:         fmt.Sprintf(":%d", cfg.Port),
@@ -2,91 +2,295 @@ package store
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/GT8004/gt8004-common/apikey"
+	"github.com/GT8004/gt8004-common/org"
 )
 
+// ErrKeyOutranksIssuer is returned when rotating a key issued under a higher
+// role than the caller's.
+var ErrKeyOutranksIssuer = errors.New("api key was issued by a higher role")
+
 type AgentAuth struct {
 	AgentDBID uuid.UUID
 	AgentID   string
+	KeyID     uuid.UUID
+	Scopes    []string
+	Tier      string
+	// Role is the role of the wallet that issued the key; CreatedBy is that
+	// wallet, empty for keys issued at registration.
+	Role      org.Role
+	CreatedBy string
 }
 
-// CreateAPIKey generates a new API key for an agent.
-// Returns the raw key (only shown once). The SHA-256 hash is stored in the database.
-func (s *Store) CreateAPIKey(ctx context.Context, agentDBID uuid.UUID) (string, error) {
-	// Generate 32 random bytes
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
-		return "", fmt.Errorf("generate random bytes: %w", err)
+// APIKey is a key's metadata. The raw key is never stored; it is returned
+// once, by the call that creates it.
+type APIKey struct {
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	KeyPrefix   string     `json:"key_prefix"`
+	Scopes      []string   `json:"scopes"`
+	IPAllowlist []string   `json:"ip_allowlist"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP  *string    `json:"last_used_ip,omitempty"`
+	RotatedFrom *uuid.UUID `json:"rotated_from,omitempty"`
+	Role        org.Role   `json:"role"`
+	CreatedBy   *string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// NewAPIKey describes a key to create. Scopes and IPAllowlist must already be
+// normalized with the apikey package.
+type NewAPIKey struct {
+	Name        string
+	Scopes      []string
+	IPAllowlist []string
+	ExpiresAt   *time.Time
+
+	// Role is the role of the wallet issuing the key, which bounds what the
+	// key may do. It is required.
+	Role org.Role
+	// CreatedBy is the wallet issuing the key, if any. Removing it from an
+	// organization revokes the key.
+	CreatedBy string
+}
+
+const apiKeyCols = `id, name, key_prefix, scopes, ip_allowlist, expires_at, last_used_at, last_used_ip, rotated_from, role, created_by, created_at`
+
+func scanAPIKey(row pgx.Row) (*APIKey, error) {
+	k := &APIKey{}
+	err := row.Scan(&k.ID, &k.Name, &k.KeyPrefix, &k.Scopes, &k.IPAllowlist,
+		&k.ExpiresAt, &k.LastUsedAt, &k.LastUsedIP, &k.RotatedFrom, &k.Role, &k.CreatedBy, &k.CreatedAt)
+	if err != nil {
+		return nil, err
 	}
+	return k, nil
+}
 
-	rawKey := "gt8004_sk_" + hex.EncodeToString(b)
-	keyPrefix := rawKey[:16]
+// CreateAPIKey generates a new API key with every scope for an agent.
+// Returns the raw key (only shown once). The SHA-256 hash is stored in the database.
+func (s *Store) CreateAPIKey(ctx context.Context, agentDBID uuid.UUID, name string) (string, error) {
+	_, rawKey, err := s.CreateScopedAPIKey(ctx, agentDBID, NewAPIKey{Name: name, Scopes: apikey.AllScopes, Role: org.RoleOwner})
+	return rawKey, err
+}
 
-	hash := sha256.Sum256([]byte(rawKey))
-	keyHash := hex.EncodeToString(hash[:])
+// CreateScopedAPIKey generates a new API key as described by spec and returns
+// its metadata and the raw key.
+func (s *Store) CreateScopedAPIKey(ctx context.Context, agentDBID uuid.UUID, spec NewAPIKey) (*APIKey, string, error) {
+	return createAPIKey(ctx, s.pool, agentDBID, spec, nil)
+}
 
-	_, err := s.pool.Exec(ctx, `
-		INSERT INTO api_keys (agent_id, key_hash, key_prefix, key_raw)
-		VALUES ($1, $2, $3, $4)
-	`, agentDBID, keyHash, keyPrefix, rawKey)
+// execQuerier is satisfied by both the pool and a transaction.
+type execQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func createAPIKey(ctx context.Context, q execQuerier, agentDBID uuid.UUID, spec NewAPIKey, rotatedFrom *uuid.UUID) (*APIKey, string, error) {
+	rawKey, keyHash, keyPrefix, err := apikey.Generate()
 	if err != nil {
-		return "", fmt.Errorf("insert api key: %w", err)
+		return nil, "", err
+	}
+	if spec.Role == "" {
+		return nil, "", errors.New("api key role required")
+	}
+	if spec.IPAllowlist == nil {
+		spec.IPAllowlist = []string{}
+	}
+	var createdBy *string
+	if spec.CreatedBy != "" {
+		createdBy = &spec.CreatedBy
 	}
 
-	return rawKey, nil
+	k, err := scanAPIKey(q.QueryRow(ctx, `
+		INSERT INTO api_keys (agent_id, key_hash, key_prefix, name, scopes, ip_allowlist, expires_at, rotated_from, role, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+apiKeyCols,
+		agentDBID, keyHash, keyPrefix, spec.Name, spec.Scopes, spec.IPAllowlist, spec.ExpiresAt, rotatedFrom, spec.Role, createdBy))
+	if err != nil {
+		return nil, "", fmt.Errorf("insert api key: %w", err)
+	}
+	return k, rawKey, nil
 }
 
-// RevokeAPIKeys revokes all active API keys for an agent.
-func (s *Store) RevokeAPIKeys(ctx context.Context, agentDBID uuid.UUID) error {
-	_, err := s.pool.Exec(ctx, `
-		UPDATE api_keys SET revoked_at = NOW()
+// ListAPIKeys returns the agent's unrevoked keys, newest first. Expired keys
+// are included until they are revoked.
+func (s *Store) ListAPIKeys(ctx context.Context, agentDBID uuid.UUID) ([]APIKey, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+apiKeyCols+`
+		FROM api_keys
 		WHERE agent_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
 	`, agentDBID)
 	if err != nil {
-		return fmt.Errorf("revoke api keys: %w", err)
+		return nil, fmt.Errorf("list api keys: %w", err)
 	}
-	return nil
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, *k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	return keys, nil
 }
 
-// GetActiveAPIKey returns the raw API key for the agent's current (non-revoked) key.
-func (s *Store) GetActiveAPIKey(ctx context.Context, agentDBID uuid.UUID) (string, error) {
-	var raw *string
+// CountActiveAPIKeys counts the agent's unrevoked, unexpired keys.
+func (s *Store) CountActiveAPIKeys(ctx context.Context, agentDBID uuid.UUID) (int, error) {
+	var n int
 	err := s.pool.QueryRow(ctx, `
-		SELECT key_raw FROM api_keys
-		WHERE agent_id = $1 AND revoked_at IS NULL
-		ORDER BY created_at DESC LIMIT 1
-	`, agentDBID).Scan(&raw)
+		SELECT COUNT(*) FROM api_keys
+		WHERE agent_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	`, agentDBID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count api keys: %w", err)
+	}
+	return n, nil
+}
+
+// RotateAPIKey issues a replacement for a key with the same name, scopes,
+// allowlist, role and lifetime, and lets the old key keep working for grace
+// so SDK instances can be redeployed one at a time. A zero grace revokes the
+// old key immediately. Returns pgx.ErrNoRows when the key is not a live key
+// of the agent and ErrKeyOutranksIssuer when the key's role outranks issuer.
+func (s *Store) RotateAPIKey(ctx context.Context, agentDBID, keyID uuid.UUID, grace time.Duration, issuer org.Role) (*APIKey, string, error) {
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
-		return "", fmt.Errorf("get active api key: %w", err)
+		return nil, "", fmt.Errorf("begin tx: %w", err)
 	}
-	if raw == nil {
-		return "", fmt.Errorf("get active api key: raw key not stored")
+	defer tx.Rollback(ctx)
+
+	old, err := scanAPIKey(tx.QueryRow(ctx, `
+		SELECT `+apiKeyCols+`
+		FROM api_keys
+		WHERE id = $1 AND agent_id = $2 AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+		FOR UPDATE
+	`, keyID, agentDBID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", pgx.ErrNoRows
+		}
+		return nil, "", fmt.Errorf("get api key: %w", err)
 	}
-	return *raw, nil
+	if old.Role.Outranks(issuer) {
+		return nil, "", ErrKeyOutranksIssuer
+	}
+
+	spec := NewAPIKey{Name: old.Name, Scopes: old.Scopes, IPAllowlist: old.IPAllowlist, Role: old.Role}
+	if old.CreatedBy != nil {
+		spec.CreatedBy = *old.CreatedBy
+	}
+	now := time.Now()
+	if old.ExpiresAt != nil {
+		expiresAt := now.Add(old.ExpiresAt.Sub(old.CreatedAt))
+		spec.ExpiresAt = &expiresAt
+	}
+	k, rawKey, err := createAPIKey(ctx, tx, agentDBID, spec, &old.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if grace <= 0 {
+		_, err = tx.Exec(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1`, old.ID)
+	} else {
+		_, err = tx.Exec(ctx, `
+			UPDATE api_keys SET expires_at = LEAST(COALESCE(expires_at, 'infinity'), $2)
+			WHERE id = $1
+		`, old.ID, now.Add(grace))
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("retire rotated api key: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, "", fmt.Errorf("commit tx: %w", err)
+	}
+	return k, rawKey, nil
 }
 
-// ValidateAPIKey looks up an API key by its SHA-256 hash and returns agent info.
-func (s *Store) ValidateAPIKey(ctx context.Context, keyHash string) (*AgentAuth, error) {
-	auth := &AgentAuth{}
-	err := s.pool.QueryRow(ctx, `
-		SELECT ak.agent_id, a.agent_id
-		FROM api_keys ak
-		JOIN agents a ON a.id = ak.agent_id
-		WHERE ak.key_hash = $1 AND ak.revoked_at IS NULL
-	`, keyHash).Scan(&auth.AgentDBID, &auth.AgentID)
+// ReissueAPIKey revokes the agent's live keys with spec's name issued by
+// spec.CreatedBy and creates spec in their place, so repeated logins do not
+// pile up keys.
+func (s *Store) ReissueAPIKey(ctx context.Context, agentDBID uuid.UUID, spec NewAPIKey) (*APIKey, string, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE agent_id = $1 AND name = $2 AND created_by = $3 AND revoked_at IS NULL
+	`, agentDBID, spec.Name, spec.CreatedBy); err != nil {
+		return nil, "", fmt.Errorf("revoke reissued api keys: %w", err)
+	}
+	k, rawKey, err := createAPIKey(ctx, tx, agentDBID, spec, nil)
 	if err != nil {
-		return nil, fmt.Errorf("validate api key: %w", err)
+		return nil, "", err
 	}
 
-	// Update last_used_at
-	_, _ = s.pool.Exec(ctx, `
-		UPDATE api_keys SET last_used_at = NOW() WHERE key_hash = $1
-	`, keyHash)
+	if err := tx.Commit(ctx); err != nil {
+		return nil, "", fmt.Errorf("commit tx: %w", err)
+	}
+	return k, rawKey, nil
+}
 
-	return auth, nil
+// RevokeAPIKey revokes one of the agent's keys. Returns pgx.ErrNoRows when
+// the key does not exist, belongs to another agent or is already revoked.
+func (s *Store) RevokeAPIKey(ctx context.Context, agentDBID, keyID uuid.UUID) error {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND agent_id = $2 AND revoked_at IS NULL
+	`, keyID, agentDBID)
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// RevokeAPIKeys revokes all active API keys for an agent.
+func (s *Store) RevokeAPIKeys(ctx context.Context, agentDBID uuid.UUID) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE agent_id = $1 AND revoked_at IS NULL
+	`, agentDBID)
+	if err != nil {
+		return fmt.Errorf("revoke api keys: %w", err)
+	}
+	return nil
+}
+
+// ValidateAPIKey looks up an API key by its SHA-256 hash and returns agent
+// info when the key is live, allowed from clientIP and carries scope (an
+// empty scope skips the scope check). Errors wrap the apikey package errors.
+func (s *Store) ValidateAPIKey(ctx context.Context, keyHash, scope, clientIP string) (*AgentAuth, error) {
+	k, err := apikey.Validate(ctx, s.pool, keyHash, scope, clientIP)
+	if err != nil {
+		return nil, err
+	}
+	return &AgentAuth{
+		KeyID:     k.ID,
+		AgentDBID: k.AgentDBID,
+		AgentID:   k.AgentID,
+		Tier:      k.Tier,
+		Scopes:    k.Scopes,
+		Role:      org.Role(k.Role),
+		CreatedBy: k.CreatedBy,
+	}, nil
 }
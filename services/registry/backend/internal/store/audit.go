@@ -0,0 +1,239 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// auditGenesisHash is the prev_hash of the first entry of every chain.
+var auditGenesisHash = strings.Repeat("0", 64)
+
+// AuditEntry is one audit log record. Before and After hold the changed
+// fields as JSON objects.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	Chain      string          `json:"chain"`
+	Seq        int64           `json:"seq"`
+	ActorType  string          `json:"actor_type"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	IPAddress  string          `json:"ip_address,omitempty"`
+	UserAgent  string          `json:"user_agent,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+// AuditFilter narrows an audit log query. BeforeSeq pages backwards.
+type AuditFilter struct {
+	Action    string
+	Since     *time.Time
+	Until     *time.Time
+	BeforeSeq int64
+	Limit     int
+}
+
+// AuditChainStatus is the result of re-hashing a chain.
+type AuditChainStatus struct {
+	Valid       bool   `json:"valid"`
+	Length      int64  `json:"length"`
+	HeadHash    string `json:"head_hash,omitempty"`
+	BrokenAtSeq *int64 `json:"broken_at_seq,omitempty"`
+}
+
+// auditHash hashes an entry together with the previous entry's hash. JSON
+// fields are re-encoded so the hash does not depend on how JSONB stores them.
+func auditHash(e *AuditEntry) string {
+	payload, _ := json.Marshal(struct {
+		Chain      string `json:"chain"`
+		Seq        int64  `json:"seq"`
+		ActorType  string `json:"actor_type"`
+		Actor      string `json:"actor"`
+		Action     string `json:"action"`
+		TargetType string `json:"target_type"`
+		TargetID   string `json:"target_id"`
+		Before     any    `json:"before"`
+		After      any    `json:"after"`
+		IPAddress  string `json:"ip_address"`
+		UserAgent  string `json:"user_agent"`
+		CreatedAt  string `json:"created_at"`
+	}{
+		e.Chain, e.Seq, e.ActorType, e.Actor, e.Action, e.TargetType, e.TargetID,
+		canonicalJSON(e.Before), canonicalJSON(e.After), e.IPAddress, e.UserAgent,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(append([]byte(e.PrevHash+"\n"), payload...))
+	return hex.EncodeToString(sum[:])
+}
+
+func canonicalJSON(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	return v
+}
+
+// AppendAudit appends an entry to the end of its chain, filling in Seq,
+// CreatedAt, PrevHash and Hash. Appends to one chain are serialized.
+func (s *Store) AppendAudit(ctx context.Context, e *AuditEntry) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := appendAudit(ctx, tx, e); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// appendAudit appends e within tx, holding its chain's lock until tx ends.
+// Callers appending to several chains must do so in a consistent order.
+func appendAudit(ctx context.Context, tx pgx.Tx, e *AuditEntry) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('audit_log:' || $1))`, e.Chain); err != nil {
+		return fmt.Errorf("lock audit chain: %w", err)
+	}
+	e.Seq, e.PrevHash = 1, auditGenesisHash
+	var lastSeq int64
+	var lastHash string
+	err := tx.QueryRow(ctx, `
+		SELECT seq, hash FROM audit_log WHERE chain = $1 ORDER BY seq DESC LIMIT 1
+	`, e.Chain).Scan(&lastSeq, &lastHash)
+	switch {
+	case err == nil:
+		e.Seq, e.PrevHash = lastSeq+1, lastHash
+	case !errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("get audit chain head: %w", err)
+	}
+	// Postgres keeps microseconds; truncate so the stored time hashes the same.
+	e.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	e.Hash = auditHash(e)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO audit_log (chain, seq, actor_type, actor, action, target_type, target_id,
+			before, after, ip_address, user_agent, created_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id
+	`, e.Chain, e.Seq, e.ActorType, e.Actor, e.Action, e.TargetType, e.TargetID,
+		nullJSON(e.Before), nullJSON(e.After), e.IPAddress, e.UserAgent, e.CreatedAt, e.PrevHash, e.Hash).Scan(&e.ID)
+	if err != nil {
+		return fmt.Errorf("insert audit entry: %w", err)
+	}
+	return nil
+}
+
+func nullJSON(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+const auditCols = `id, chain, seq, actor_type, actor, action, target_type, target_id,
+	before, after, COALESCE(ip_address, ''), COALESCE(user_agent, ''), created_at, prev_hash, hash`
+
+func scanAuditEntry(row pgx.Row) (*AuditEntry, error) {
+	e := &AuditEntry{}
+	var before, after []byte
+	err := row.Scan(&e.ID, &e.Chain, &e.Seq, &e.ActorType, &e.Actor, &e.Action, &e.TargetType, &e.TargetID,
+		&before, &after, &e.IPAddress, &e.UserAgent, &e.CreatedAt, &e.PrevHash, &e.Hash)
+	if err != nil {
+		return nil, err
+	}
+	e.Before, e.After = before, after
+	return e, nil
+}
+
+// ListAudit returns a chain's entries, newest first.
+func (s *Store) ListAudit(ctx context.Context, chain string, f AuditFilter) ([]AuditEntry, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+auditCols+`
+		FROM audit_log
+		WHERE chain = $1
+		  AND ($2 = '' OR action = $2)
+		  AND ($3::timestamptz IS NULL OR created_at >= $3)
+		  AND ($4::timestamptz IS NULL OR created_at < $4)
+		  AND ($5 = 0 OR seq < $5)
+		ORDER BY seq DESC
+		LIMIT $6
+	`, chain, f.Action, f.Since, f.Until, f.BeforeSeq, f.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("list audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan audit entry: %w", err)
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
+
+// VerifyAuditChain re-hashes a chain from its first entry and reports the
+// first entry whose hash, link or sequence number does not match.
+func (s *Store) VerifyAuditChain(ctx context.Context, chain string) (*AuditChainStatus, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+auditCols+` FROM audit_log WHERE chain = $1 ORDER BY seq
+	`, chain)
+	if err != nil {
+		return nil, fmt.Errorf("verify audit chain: %w", err)
+	}
+	defer rows.Close()
+
+	status := &AuditChainStatus{Valid: true}
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan audit entry: %w", err)
+		}
+		status.add(e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("verify audit chain: %w", err)
+	}
+	return status, nil
+}
+
+// add checks that e is the next entry of the chain seen so far and makes it
+// the head. The first mismatch is kept in BrokenAtSeq.
+func (st *AuditChainStatus) add(e *AuditEntry) {
+	prev := st.HeadHash
+	if prev == "" {
+		prev = auditGenesisHash
+	}
+	st.Length++
+	if st.Valid && (e.Seq != st.Length || e.PrevHash != prev || auditHash(e) != e.Hash) {
+		seq := e.Seq
+		st.Valid = false
+		st.BrokenAtSeq = &seq
+	}
+	st.HeadHash = e.Hash
+}
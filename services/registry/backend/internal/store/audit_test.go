@@ -0,0 +1,159 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// auditChain builds n correctly linked entries on one chain.
+func auditChain(n int) []*AuditEntry {
+	base := time.Date(2026, 10, 1, 12, 0, 0, 123456000, time.UTC)
+	prev := auditGenesisHash
+	entries := make([]*AuditEntry, n)
+	for i := range entries {
+		e := &AuditEntry{
+			Chain:      "agent:a1",
+			Seq:        int64(i + 1),
+			ActorType:  "wallet",
+			Actor:      "0xabc",
+			Action:     "agent.tier_changed",
+			TargetType: "agent",
+			TargetID:   "a1",
+			Before:     json.RawMessage(`{"tier":"open"}`),
+			After:      json.RawMessage(`{"tier":"lite"}`),
+			IPAddress:  "203.0.113.7",
+			UserAgent:  "test",
+			CreatedAt:  base.Add(time.Duration(i) * time.Minute),
+			PrevHash:   prev,
+		}
+		e.Hash = auditHash(e)
+		prev = e.Hash
+		entries[i] = e
+	}
+	return entries
+}
+
+func TestAuditHash(t *testing.T) {
+	base := auditChain(1)[0]
+	want := auditHash(base)
+
+	tests := []struct {
+		name   string
+		mutate func(e *AuditEntry)
+		same   bool
+	}{
+		{"unchanged", func(e *AuditEntry) {}, true},
+		{"json whitespace", func(e *AuditEntry) {
+			e.After = json.RawMessage(` { "tier" : "lite" } `)
+		}, true},
+		{"created_at in another zone", func(e *AuditEntry) {
+			e.CreatedAt = e.CreatedAt.In(time.FixedZone("KST", 9*3600))
+		}, true},
+		{"actor", func(e *AuditEntry) { e.Actor = "0xdef" }, false},
+		{"action", func(e *AuditEntry) { e.Action = "agent.deleted" }, false},
+		{"target", func(e *AuditEntry) { e.TargetID = "a2" }, false},
+		{"before", func(e *AuditEntry) { e.Before = json.RawMessage(`{"tier":"lite"}`) }, false},
+		{"after removed", func(e *AuditEntry) { e.After = nil }, false},
+		{"ip", func(e *AuditEntry) { e.IPAddress = "198.51.100.1" }, false},
+		{"user agent", func(e *AuditEntry) { e.UserAgent = "other" }, false},
+		{"seq", func(e *AuditEntry) { e.Seq = 2 }, false},
+		{"chain", func(e *AuditEntry) { e.Chain = "agent:a2" }, false},
+		{"created_at", func(e *AuditEntry) { e.CreatedAt = e.CreatedAt.Add(time.Microsecond) }, false},
+		{"prev hash", func(e *AuditEntry) { e.PrevHash = e.Hash }, false},
+		{"stored id and hash are not hashed", func(e *AuditEntry) {
+			e.ID = 99
+			e.Hash = "x"
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := *base
+			tt.mutate(&e)
+			if got := auditHash(&e); (got == want) != tt.same {
+				t.Errorf("expected same hash %v, got %s vs %s", tt.same, got, want)
+			}
+		})
+	}
+}
+
+func TestAuditHash_KeyOrderIndependent(t *testing.T) {
+	a, b := *auditChain(1)[0], *auditChain(1)[0]
+	a.Before = json.RawMessage(`{"tier":"open","evm_address":"0x1"}`)
+	b.Before = json.RawMessage(`{"evm_address":"0x1","tier":"open"}`)
+	if auditHash(&a) != auditHash(&b) {
+		t.Error("expected JSON key order not to affect the hash")
+	}
+}
+
+func TestAuditChainStatus_Add(t *testing.T) {
+	tests := []struct {
+		name   string
+		n      int
+		mutate func(entries []*AuditEntry) []*AuditEntry
+		broken int64 // 0 means valid
+	}{
+		{"empty", 0, nil, 0},
+		{"single", 1, nil, 0},
+		{"linked", 5, nil, 0},
+		{"edited entry", 5, func(es []*AuditEntry) []*AuditEntry {
+			es[2].After = json.RawMessage(`{"tier":"pro"}`)
+			return es
+		}, 3},
+		{"rehashed edit breaks next link", 5, func(es []*AuditEntry) []*AuditEntry {
+			es[1].Actor = "0xevil"
+			es[1].Hash = auditHash(es[1])
+			return es
+		}, 3},
+		{"deleted entry", 5, func(es []*AuditEntry) []*AuditEntry { return append(es[:2], es[3:]...) }, 4},
+		{"first entry not from genesis", 3, func(es []*AuditEntry) []*AuditEntry {
+			es[0].PrevHash = es[2].Hash
+			es[0].Hash = auditHash(es[0])
+			return es
+		}, 1},
+		{"sequence gap", 3, func(es []*AuditEntry) []*AuditEntry {
+			es[2].Seq = 4
+			es[2].Hash = auditHash(es[2])
+			return es
+		}, 4},
+		{"first break reported", 5, func(es []*AuditEntry) []*AuditEntry {
+			es[1].Hash = "bad"
+			es[3].Hash = "bad"
+			return es
+		}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := auditChain(tt.n)
+			if tt.mutate != nil {
+				entries = tt.mutate(entries)
+			}
+
+			st := &AuditChainStatus{Valid: true}
+			for _, e := range entries {
+				st.add(e)
+			}
+
+			if st.Length != int64(len(entries)) {
+				t.Errorf("expected length %d, got %d", len(entries), st.Length)
+			}
+			if len(entries) > 0 && st.HeadHash != entries[len(entries)-1].Hash {
+				t.Errorf("expected head %s, got %s", entries[len(entries)-1].Hash, st.HeadHash)
+			}
+			if len(entries) == 0 && st.HeadHash != "" {
+				t.Errorf("expected no head for an empty chain, got %s", st.HeadHash)
+			}
+			if tt.broken == 0 {
+				if !st.Valid || st.BrokenAtSeq != nil {
+					t.Errorf("expected valid chain, got %+v", st)
+				}
+				return
+			}
+			if st.Valid || st.BrokenAtSeq == nil || *st.BrokenAtSeq != tt.broken {
+				t.Errorf("expected break at seq %d, got %+v", tt.broken, st)
+			}
+		})
+	}
+}
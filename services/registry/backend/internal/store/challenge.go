@@ -32,6 +32,21 @@ func (s *Store) ConsumeChallenge(ctx context.Context, challengeHex string) (stri
 	return agentID, expiresAt, nil
 }
 
+// GetChallenge returns an unexpired challenge's subject and creation time
+// without consuming it.
+func (s *Store) GetChallenge(ctx context.Context, challengeHex string) (string, time.Time, error) {
+	var agentID string
+	var createdAt time.Time
+	err := s.pool.QueryRow(ctx,
+		`SELECT agent_id, created_at FROM challenges WHERE challenge = $1 AND expires_at > NOW()`,
+		challengeHex,
+	).Scan(&agentID, &createdAt)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("challenge not found or expired")
+	}
+	return agentID, createdAt, nil
+}
+
 // CleanupExpiredChallenges removes expired challenges from the database.
 func (s *Store) CleanupExpiredChallenges(ctx context.Context) error {
 	_, err := s.pool.Exec(ctx, `DELETE FROM challenges WHERE expires_at < NOW()`)
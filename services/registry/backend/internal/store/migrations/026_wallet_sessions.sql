@@ -0,0 +1,17 @@
+-- Wallet sessions issued by Sign-In With Ethereum. Access tokens are
+-- stateless and short-lived; the refresh token (stored hashed) keeps the
+-- session alive until it expires or the session is revoked.
+CREATE TABLE IF NOT EXISTS wallet_sessions (
+    id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    address             VARCHAR(42) NOT NULL,
+    chain_id            INT NOT NULL,
+    refresh_token_hash  VARCHAR(64) NOT NULL UNIQUE,
+    user_agent          TEXT,
+    ip_address          VARCHAR(64),
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    refreshed_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at          TIMESTAMPTZ NOT NULL,
+    revoked_at          TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_sessions_address ON wallet_sessions (address, created_at DESC);
@@ -0,0 +1,12 @@
+-- Multiple named, scoped, expiring API keys per agent. Existing keys keep
+-- full access. Raw keys are no longer stored: only key_hash remains.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS name VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT ARRAY['ingest', 'analytics:read', 'registry:admin'];
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS ip_allowlist TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS last_used_ip VARCHAR(64);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS rotated_from UUID REFERENCES api_keys(id) ON DELETE SET NULL;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS key_raw;
+
+CREATE INDEX IF NOT EXISTS idx_apikeys_agent ON api_keys (agent_id, created_at DESC);
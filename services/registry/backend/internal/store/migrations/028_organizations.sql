@@ -0,0 +1,58 @@
+-- Organizations group agents and member wallets with roles. An agent's
+-- registered wallet stays its owner; members get access through org_id.
+-- Wallet addresses are stored lowercase.
+CREATE TABLE IF NOT EXISTS organizations (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name        VARCHAR(128) NOT NULL,
+    created_by  VARCHAR(42) NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    org_id          UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    wallet_address  VARCHAR(42) NOT NULL,
+    role            VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'admin', 'analyst', 'billing_viewer')),
+    invited_by      VARCHAR(42),
+    joined_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, wallet_address)
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_members_wallet ON organization_members (wallet_address);
+
+-- Invitations are signed by the inviting wallet (inviter_message) and
+-- accepted by the invited wallet signing message. The inviter's signature is
+-- unique so one signed request cannot create two invitations.
+CREATE TABLE IF NOT EXISTS organization_invitations (
+    id                 UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id             UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    wallet_address     VARCHAR(42) NOT NULL,
+    role               VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'admin', 'analyst', 'billing_viewer')),
+    invited_by         VARCHAR(42) NOT NULL,
+    inviter_message    TEXT NOT NULL,
+    inviter_signature  TEXT NOT NULL,
+    message            TEXT NOT NULL,
+    signature          TEXT,
+    expires_at         TIMESTAMPTZ NOT NULL,
+    accepted_at        TIMESTAMPTZ,
+    revoked_at         TIMESTAMPTZ,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_org_invitations_pending
+    ON organization_invitations (org_id, wallet_address)
+    WHERE accepted_at IS NULL AND revoked_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_org_invitations_inviter_signature
+    ON organization_invitations (inviter_signature);
+CREATE INDEX IF NOT EXISTS idx_org_invitations_wallet ON organization_invitations (wallet_address);
+
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS org_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_agents_org ON agents (org_id) WHERE org_id IS NOT NULL;
+
+-- Keys issued to a member wallet are revoked when the member leaves. A key
+-- carries the role of the wallet that issued it and never grants more;
+-- existing keys were issued to the agent's owner.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS created_by VARCHAR(42);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'owner'
+    CHECK (role IN ('owner', 'admin', 'analyst', 'billing_viewer'));
+ALTER TABLE api_keys ALTER COLUMN role DROP DEFAULT;
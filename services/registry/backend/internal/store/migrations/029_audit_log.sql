@@ -0,0 +1,41 @@
+-- Append-only audit log of owner, admin and operator actions. Entries form
+-- hash chains (one per agent, organization, wallet, or "system"): each
+-- entry's hash covers its content and the previous entry's hash, so an edit
+-- or deletion breaks the chain.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id           BIGSERIAL PRIMARY KEY,
+    chain        VARCHAR(80) NOT NULL,
+    seq          BIGINT NOT NULL,
+    actor_type   VARCHAR(16) NOT NULL,
+    actor        VARCHAR(128) NOT NULL,
+    action       VARCHAR(64) NOT NULL,
+    target_type  VARCHAR(32) NOT NULL,
+    target_id    VARCHAR(128) NOT NULL,
+    before       JSONB,
+    after        JSONB,
+    ip_address   VARCHAR(64),
+    user_agent   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL,
+    prev_hash    CHAR(64) NOT NULL,
+    hash         CHAR(64) NOT NULL,
+    UNIQUE (chain, seq)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_chain_time ON audit_log (chain, created_at DESC);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END
+$$;
+
+DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log;
+CREATE TRIGGER audit_log_no_update
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,72 @@
+-- Outbound webhooks. Owners subscribe endpoints to event types, either for
+-- one agent or for every agent a wallet owns; services record events, and
+-- one delivery row per matching subscription is retried with backoff until
+-- it succeeds or runs out of attempts.
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    agent_id       UUID REFERENCES agents(id) ON DELETE CASCADE,
+    wallet_address VARCHAR(42),
+    url            TEXT NOT NULL,
+    secret         VARCHAR(80) NOT NULL,
+    events         TEXT[] NOT NULL,
+    description    VARCHAR(256),
+    active         BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by     VARCHAR(42),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((agent_id IS NULL) <> (wallet_address IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_agent ON webhook_subscriptions (agent_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_wallet ON webhook_subscriptions (wallet_address);
+
+-- agent_id is NULL for events about a wallet's on-chain token that is not
+-- registered here yet. dedup_key drops repeats of the same occurrence.
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    agent_id    UUID REFERENCES agents(id) ON DELETE CASCADE,
+    event_type  VARCHAR(64) NOT NULL,
+    payload     JSONB NOT NULL,
+    dedup_key   VARCHAR(128) UNIQUE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id  UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id         UUID NOT NULL REFERENCES webhook_events(id) ON DELETE CASCADE,
+    redelivery_of    UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+    status           VARCHAR(16) NOT NULL DEFAULT 'pending'
+                     CHECK (status IN ('pending', 'delivering', 'succeeded', 'failed')),
+    attempts         INT NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status_code INT,
+    last_error       TEXT,
+    delivered_at     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+    ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription
+    ON webhook_deliveries (subscription_id, created_at DESC);
+
+-- One row per HTTP attempt, for the delivery log.
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id            BIGSERIAL PRIMARY KEY,
+    delivery_id   UUID NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    attempt       INT NOT NULL,
+    status_code   INT,
+    error         TEXT,
+    response_body TEXT,
+    duration_ms   INT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery
+    ON webhook_delivery_attempts (delivery_id, attempt);
+
+-- Set when the SDK stops pinging, so sdk.disconnected is sent once per
+-- outage; cleared by the next ping.
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS sdk_disconnected_at TIMESTAMPTZ;
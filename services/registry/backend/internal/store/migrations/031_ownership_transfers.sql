@@ -0,0 +1,38 @@
+-- Ownership changes of agents linked to ERC-8004 tokens. The transfer
+-- watcher moves an agent to the token's new owner and records one row per
+-- change; tx_hash and log_index are NULL when the change was found by
+-- comparing ownerOf instead of reading a Transfer log.
+CREATE TABLE IF NOT EXISTS agent_ownership_transfers (
+    id                BIGSERIAL PRIMARY KEY,
+    agent_id          UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    chain_id          INT NOT NULL,
+    token_id          BIGINT NOT NULL,
+    from_address      VARCHAR(42) NOT NULL,
+    to_address        VARCHAR(42) NOT NULL,
+    tx_hash           VARCHAR(66),
+    log_index         INT,
+    block_number      BIGINT,
+    revoked_api_keys  INT NOT NULL DEFAULT 0,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ownership_transfers_log
+    ON agent_ownership_transfers (chain_id, tx_hash, log_index);
+CREATE INDEX IF NOT EXISTS idx_ownership_transfers_agent
+    ON agent_ownership_transfers (agent_id, created_at DESC);
+
+-- Last block each chain's Transfer logs were scanned through.
+CREATE TABLE IF NOT EXISTS erc8004_transfer_cursors (
+    chain_id    INT PRIMARY KEY,
+    last_block  BIGINT NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Sessions of a previous owner that may no longer act on the agent. The
+-- wallet's sessions stay valid for its other agents.
+CREATE TABLE IF NOT EXISTS agent_session_revocations (
+    agent_id    UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    session_id  UUID NOT NULL REFERENCES wallet_sessions(id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (agent_id, session_id)
+);
@@ -0,0 +1,24 @@
+-- Paid tier periods. An x402 payment for a tier buys one period, starting
+-- when the agent's current period of that tier ends; agents are moved back
+-- to "open" once no period covers the present. tx_hash is unique so one
+-- payment cannot be applied twice.
+CREATE TABLE IF NOT EXISTS tier_subscriptions (
+    id            BIGSERIAL PRIMARY KEY,
+    agent_id      UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    tier          VARCHAR(8) NOT NULL,
+    period_start  TIMESTAMPTZ NOT NULL,
+    period_end    TIMESTAMPTZ NOT NULL,
+    amount_usdc   NUMERIC(20, 6) NOT NULL DEFAULT 0,
+    chain_id      INT,
+    tx_hash       VARCHAR(66) UNIQUE,
+    payer         VARCHAR(42),
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tier_subscriptions_agent ON tier_subscriptions (agent_id, period_end DESC);
+
+-- Agents upgraded before tiers were paid keep their tier for one period.
+INSERT INTO tier_subscriptions (agent_id, tier, period_start, period_end)
+SELECT id, current_tier, NOW(), NOW() + INTERVAL '30 days'
+FROM agents
+WHERE current_tier <> 'open';
@@ -0,0 +1,587 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/GT8004/gt8004-common/org"
+)
+
+// ErrLastOwner is returned when a change would leave an organization
+// without an owner.
+var ErrLastOwner = errors.New("organization must keep at least one owner")
+
+// ErrInvitationReplayed is returned when an inviter signature was already
+// used to create an invitation.
+var ErrInvitationReplayed = errors.New("invitation signature already used")
+
+// Organization groups agents and member wallets.
+type Organization struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Role is the requesting wallet's role, when listed for a wallet.
+	Role org.Role `json:"role,omitempty"`
+}
+
+// OrgMember is a wallet's membership in an organization.
+type OrgMember struct {
+	WalletAddress string    `json:"wallet_address"`
+	Role          org.Role  `json:"role"`
+	InvitedBy     *string   `json:"invited_by,omitempty"`
+	JoinedAt      time.Time `json:"joined_at"`
+}
+
+// OrgAgent is an agent belonging to an organization.
+type OrgAgent struct {
+	ID         uuid.UUID `json:"id"`
+	AgentID    string    `json:"agent_id"`
+	Name       string    `json:"name"`
+	EVMAddress string    `json:"evm_address,omitempty"`
+	Status     string    `json:"status"`
+}
+
+// OrgInvitation invites a wallet to an organization. The inviting wallet
+// signs InviterMessage to create it; the invited wallet accepts by signing
+// Message.
+type OrgInvitation struct {
+	ID            uuid.UUID `json:"id"`
+	OrgID         uuid.UUID `json:"org_id"`
+	OrgName       string    `json:"org_name"`
+	WalletAddress string    `json:"wallet_address"`
+	Role          org.Role  `json:"role"`
+	InvitedBy     string    `json:"invited_by"`
+	Message       string    `json:"message"`
+	// InviterMessage is what InvitedBy signed; the signature itself is
+	// stored but never returned.
+	InviterMessage   string     `json:"inviter_message"`
+	InviterSignature string     `json:"-"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	AcceptedAt       *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// CreateOrganization creates an organization with owner as its first owner.
+func (s *Store) CreateOrganization(ctx context.Context, name, owner string) (*Organization, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	o := &Organization{Name: name, CreatedBy: owner, Role: org.RoleOwner}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO organizations (name, created_by)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at
+	`, name, owner).Scan(&o.ID, &o.CreatedAt, &o.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert organization: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO organization_members (org_id, wallet_address, role)
+		VALUES ($1, $2, $3)
+	`, o.ID, owner, org.RoleOwner); err != nil {
+		return nil, fmt.Errorf("insert organization owner: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	return o, nil
+}
+
+// GetOrganization returns an organization by ID.
+func (s *Store) GetOrganization(ctx context.Context, orgID uuid.UUID) (*Organization, error) {
+	o := &Organization{}
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, name, created_by, created_at, updated_at
+		FROM organizations WHERE id = $1
+	`, orgID).Scan(&o.ID, &o.Name, &o.CreatedBy, &o.CreatedAt, &o.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("get organization: %w", err)
+	}
+	return o, nil
+}
+
+// ListWalletOrganizations returns the organizations a wallet belongs to,
+// with its role in each.
+func (s *Store) ListWalletOrganizations(ctx context.Context, wallet string) ([]Organization, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT o.id, o.name, o.created_by, o.created_at, o.updated_at, m.role
+		FROM organization_members m
+		JOIN organizations o ON o.id = m.org_id
+		WHERE m.wallet_address = $1
+		ORDER BY o.name
+	`, wallet)
+	if err != nil {
+		return nil, fmt.Errorf("list wallet organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []Organization{}
+	for rows.Next() {
+		var o Organization
+		if err := rows.Scan(&o.ID, &o.Name, &o.CreatedBy, &o.CreatedAt, &o.UpdatedAt, &o.Role); err != nil {
+			return nil, fmt.Errorf("scan organization: %w", err)
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// DeleteOrganization deletes an organization. Its agents stay registered to
+// their wallets; memberships and invitations are removed, and API keys that
+// members issued for agents they do not own are revoked.
+func (s *Store) DeleteOrganization(ctx context.Context, orgID uuid.UUID) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE api_keys k SET revoked_at = NOW()
+		FROM agents a
+		WHERE a.id = k.agent_id AND a.org_id = $1
+		  AND k.revoked_at IS NULL AND k.created_by IS NOT NULL
+		  AND k.created_by <> LOWER(COALESCE(a.evm_address, ''))
+	`, orgID); err != nil {
+		return fmt.Errorf("revoke member api keys: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM organizations WHERE id = $1`, orgID)
+	if err != nil {
+		return fmt.Errorf("delete organization: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// OrgRole returns a wallet's role in an organization, or "" when the wallet
+// is not a member.
+func (s *Store) OrgRole(ctx context.Context, orgID uuid.UUID, wallet string) (org.Role, error) {
+	var role org.Role
+	err := s.pool.QueryRow(ctx, `
+		SELECT role FROM organization_members WHERE org_id = $1 AND wallet_address = $2
+	`, orgID, wallet).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("get organization role: %w", err)
+	}
+	return role, nil
+}
+
+// AgentRole returns a wallet's role for an agent: owner when the agent is
+// registered to the wallet, otherwise the wallet's role in the agent's
+// organization, or "" when it has no access.
+func (s *Store) AgentRole(ctx context.Context, agentDBID uuid.UUID, wallet string) (org.Role, error) {
+	var role *string
+	err := s.pool.QueryRow(ctx, `
+		SELECT CASE WHEN LOWER(a.evm_address) = $2 THEN 'owner' ELSE m.role END
+		FROM agents a
+		LEFT JOIN organization_members m ON m.org_id = a.org_id AND m.wallet_address = $2
+		WHERE a.id = $1
+	`, agentDBID, wallet).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("get agent role: %w", err)
+	}
+	if role == nil {
+		return "", nil
+	}
+	return org.Role(*role), nil
+}
+
+// ListOrgMembers returns an organization's members, owners first.
+func (s *Store) ListOrgMembers(ctx context.Context, orgID uuid.UUID) ([]OrgMember, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT wallet_address, role, invited_by, joined_at
+		FROM organization_members
+		WHERE org_id = $1
+		ORDER BY CASE role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 WHEN 'analyst' THEN 2 ELSE 3 END, joined_at
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("list organization members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []OrgMember{}
+	for rows.Next() {
+		var m OrgMember
+		if err := rows.Scan(&m.WalletAddress, &m.Role, &m.InvitedBy, &m.JoinedAt); err != nil {
+			return nil, fmt.Errorf("scan organization member: %w", err)
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// UpdateMemberRole changes a member's role and revokes the API keys the member
+// issued under a higher role for the organization's agents it does not own.
+// Returns pgx.ErrNoRows when the wallet is not a member and ErrLastOwner when
+// it would demote the last owner.
+func (s *Store) UpdateMemberRole(ctx context.Context, orgID uuid.UUID, wallet string, role org.Role) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := lockMember(ctx, tx, orgID, wallet)
+	if err != nil {
+		return err
+	}
+	if current == org.RoleOwner && role != org.RoleOwner {
+		if err := ensureOtherOwner(ctx, tx, orgID, wallet); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE organization_members SET role = $3 WHERE org_id = $1 AND wallet_address = $2
+	`, orgID, wallet, role); err != nil {
+		return fmt.Errorf("update member role: %w", err)
+	}
+	var higher []string
+	for _, r := range org.Roles {
+		if r.Outranks(role) {
+			higher = append(higher, string(r))
+		}
+	}
+	if len(higher) > 0 {
+		if _, err := tx.Exec(ctx, `
+			UPDATE api_keys SET revoked_at = NOW()
+			WHERE created_by = $2 AND revoked_at IS NULL AND role = ANY($3)
+			  AND agent_id IN (
+				SELECT id FROM agents WHERE org_id = $1 AND LOWER(COALESCE(evm_address, '')) <> $2
+			  )
+		`, orgID, wallet, higher); err != nil {
+			return fmt.Errorf("revoke member api keys: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// RemoveMember removes a wallet from an organization and revokes the API keys
+// issued to it for the organization's agents it does not own. Returns the
+// number of revoked keys, pgx.ErrNoRows when the wallet is not a member and
+// ErrLastOwner when it is the last owner.
+func (s *Store) RemoveMember(ctx context.Context, orgID uuid.UUID, wallet string) (int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := lockMember(ctx, tx, orgID, wallet)
+	if err != nil {
+		return 0, err
+	}
+	if current == org.RoleOwner {
+		if err := ensureOtherOwner(ctx, tx, orgID, wallet); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM organization_members WHERE org_id = $1 AND wallet_address = $2
+	`, orgID, wallet); err != nil {
+		return 0, fmt.Errorf("delete member: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE created_by = $2 AND revoked_at IS NULL
+		  AND agent_id IN (
+			SELECT id FROM agents WHERE org_id = $1 AND LOWER(COALESCE(evm_address, '')) <> $2
+		  )
+	`, orgID, wallet)
+	if err != nil {
+		return 0, fmt.Errorf("revoke member api keys: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func lockMember(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, wallet string) (org.Role, error) {
+	var role org.Role
+	err := tx.QueryRow(ctx, `
+		SELECT role FROM organization_members
+		WHERE org_id = $1 AND wallet_address = $2
+		FOR UPDATE
+	`, orgID, wallet).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", pgx.ErrNoRows
+		}
+		return "", fmt.Errorf("get member: %w", err)
+	}
+	return role, nil
+}
+
+func ensureOtherOwner(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, wallet string) error {
+	// Lock the owner rows so two owners cannot demote each other concurrently.
+	rows, err := tx.Query(ctx, `
+		SELECT wallet_address FROM organization_members
+		WHERE org_id = $1 AND role = 'owner' AND wallet_address <> $2
+		FOR UPDATE
+	`, orgID, wallet)
+	if err != nil {
+		return fmt.Errorf("count owners: %w", err)
+	}
+	others := 0
+	for rows.Next() {
+		others++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("count owners: %w", err)
+	}
+	if others == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// ListOrgAgents returns the agents in an organization.
+func (s *Store) ListOrgAgents(ctx context.Context, orgID uuid.UUID) ([]OrgAgent, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, agent_id, name, COALESCE(evm_address, ''), status
+		FROM agents
+		WHERE org_id = $1 AND status IN ('active', 'deregistered')
+		ORDER BY name
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("list organization agents: %w", err)
+	}
+	defer rows.Close()
+
+	agents := []OrgAgent{}
+	for rows.Next() {
+		var a OrgAgent
+		if err := rows.Scan(&a.ID, &a.AgentID, &a.Name, &a.EVMAddress, &a.Status); err != nil {
+			return nil, fmt.Errorf("scan organization agent: %w", err)
+		}
+		agents = append(agents, a)
+	}
+	return agents, rows.Err()
+}
+
+// SetAgentOrg moves an agent into an organization, or out of any
+// organization when orgID is nil. When the organization changes, API keys
+// that members of the previous organization issued for the agent are revoked.
+func (s *Store) SetAgentOrg(ctx context.Context, agentDBID uuid.UUID, orgID *uuid.UUID) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE agents SET org_id = $2, updated_at = NOW()
+		WHERE id = $1 AND org_id IS DISTINCT FROM $2
+	`, agentDBID, orgID)
+	if err != nil {
+		return fmt.Errorf("set agent organization: %w", err)
+	}
+	if tag.RowsAffected() > 0 {
+		if _, err := tx.Exec(ctx, `
+			UPDATE api_keys k SET revoked_at = NOW()
+			FROM agents a
+			WHERE a.id = k.agent_id AND k.agent_id = $1
+			  AND k.revoked_at IS NULL AND k.created_by IS NOT NULL
+			  AND k.created_by <> LOWER(COALESCE(a.evm_address, ''))
+		`, agentDBID); err != nil {
+			return fmt.Errorf("revoke member api keys: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// AgentOrgID returns the organization an agent belongs to, or nil.
+func (s *Store) AgentOrgID(ctx context.Context, agentDBID uuid.UUID) (*uuid.UUID, error) {
+	var orgID *uuid.UUID
+	err := s.pool.QueryRow(ctx, `SELECT org_id FROM agents WHERE id = $1`, agentDBID).Scan(&orgID)
+	if err != nil {
+		return nil, fmt.Errorf("get agent organization: %w", err)
+	}
+	return orgID, nil
+}
+
+const invitationCols = `i.id, i.org_id, o.name, i.wallet_address, i.role, i.invited_by, i.message,
+	i.inviter_message, i.expires_at, i.accepted_at, i.revoked_at, i.created_at`
+
+func scanInvitation(row pgx.Row) (*OrgInvitation, error) {
+	inv := &OrgInvitation{}
+	err := row.Scan(&inv.ID, &inv.OrgID, &inv.OrgName, &inv.WalletAddress, &inv.Role, &inv.InvitedBy,
+		&inv.Message, &inv.InviterMessage, &inv.ExpiresAt, &inv.AcceptedAt, &inv.RevokedAt, &inv.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// CreateInvitation stores an invitation whose ID, messages and inviter
+// signature were built and checked by the caller. A pending invitation for
+// the same wallet is revoked. Returns ErrInvitationReplayed when the
+// inviter signature was already used.
+func (s *Store) CreateInvitation(ctx context.Context, inv *OrgInvitation) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE organization_invitations SET revoked_at = NOW()
+		WHERE org_id = $1 AND wallet_address = $2 AND accepted_at IS NULL AND revoked_at IS NULL
+	`, inv.OrgID, inv.WalletAddress); err != nil {
+		return fmt.Errorf("revoke previous invitation: %w", err)
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO organization_invitations (id, org_id, wallet_address, role, invited_by, message,
+			inviter_message, inviter_signature, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (inviter_signature) DO NOTHING
+		RETURNING created_at
+	`, inv.ID, inv.OrgID, inv.WalletAddress, inv.Role, inv.InvitedBy, inv.Message,
+		inv.InviterMessage, inv.InviterSignature, inv.ExpiresAt).Scan(&inv.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvitationReplayed
+	}
+	if err != nil {
+		return fmt.Errorf("insert invitation: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// GetInvitation returns an invitation by ID.
+func (s *Store) GetInvitation(ctx context.Context, id uuid.UUID) (*OrgInvitation, error) {
+	inv, err := scanInvitation(s.pool.QueryRow(ctx, `
+		SELECT `+invitationCols+`
+		FROM organization_invitations i
+		JOIN organizations o ON o.id = i.org_id
+		WHERE i.id = $1
+	`, id))
+	if err != nil {
+		return nil, fmt.Errorf("get invitation: %w", err)
+	}
+	return inv, nil
+}
+
+// ListPendingInvitations returns the unexpired, unanswered invitations of an
+// organization (orgID set) or addressed to a wallet.
+func (s *Store) ListPendingInvitations(ctx context.Context, orgID *uuid.UUID, wallet string) ([]OrgInvitation, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+invitationCols+`
+		FROM organization_invitations i
+		JOIN organizations o ON o.id = i.org_id
+		WHERE ($1::uuid IS NULL OR i.org_id = $1)
+		  AND ($2 = '' OR i.wallet_address = $2)
+		  AND i.accepted_at IS NULL AND i.revoked_at IS NULL AND i.expires_at > NOW()
+		ORDER BY i.created_at DESC
+	`, orgID, wallet)
+	if err != nil {
+		return nil, fmt.Errorf("list invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invs := []OrgInvitation{}
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan invitation: %w", err)
+		}
+		invs = append(invs, *inv)
+	}
+	return invs, rows.Err()
+}
+
+// RevokeInvitation revokes a pending invitation of an organization. Returns
+// pgx.ErrNoRows when there is no such pending invitation.
+func (s *Store) RevokeInvitation(ctx context.Context, orgID, id uuid.UUID) error {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE organization_invitations SET revoked_at = NOW()
+		WHERE id = $1 AND org_id = $2 AND accepted_at IS NULL AND revoked_at IS NULL
+	`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("revoke invitation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// AcceptInvitation records the invited wallet's signature and adds it to the
+// organization. A wallet that is already a member keeps its current role.
+// Returns pgx.ErrNoRows when the invitation is no longer pending.
+func (s *Store) AcceptInvitation(ctx context.Context, id uuid.UUID, signature string) (*OrgMember, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var orgID uuid.UUID
+	var wallet, invitedBy string
+	var role org.Role
+	err = tx.QueryRow(ctx, `
+		UPDATE organization_invitations SET accepted_at = NOW(), signature = $2
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING org_id, wallet_address, role, invited_by
+	`, id, signature).Scan(&orgID, &wallet, &role, &invitedBy)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, pgx.ErrNoRows
+		}
+		return nil, fmt.Errorf("accept invitation: %w", err)
+	}
+
+	m := &OrgMember{}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO organization_members (org_id, wallet_address, role, invited_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (org_id, wallet_address) DO UPDATE SET wallet_address = EXCLUDED.wallet_address
+		RETURNING wallet_address, role, invited_by, joined_at
+	`, orgID, wallet, role, invitedBy).Scan(&m.WalletAddress, &m.Role, &m.InvitedBy, &m.JoinedAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert member: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	return m, nil
+}
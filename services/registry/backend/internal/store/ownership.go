@@ -0,0 +1,282 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/GT8004/gt8004-common/webhook"
+)
+
+// OwnershipTransfer is one change of an agent's owner wallet following its
+// ERC-8004 token. TxHash, LogIndex and BlockNumber are nil when the change
+// was found by comparing ownerOf rather than from a Transfer log.
+type OwnershipTransfer struct {
+	ID             int64     `json:"id"`
+	AgentDBID      uuid.UUID `json:"-"`
+	AgentID        string    `json:"agent_id"`
+	ChainID        int       `json:"chain_id"`
+	TokenID        int64     `json:"token_id"`
+	FromAddress    string    `json:"from_address"`
+	ToAddress      string    `json:"to_address"`
+	TxHash         *string   `json:"tx_hash,omitempty"`
+	LogIndex       *int      `json:"log_index,omitempty"`
+	BlockNumber    *int64    `json:"block_number,omitempty"`
+	RevokedAPIKeys int64     `json:"revoked_api_keys"`
+	CreatedAt      time.Time `json:"created_at"`
+	// RevokedSessions is how many of the previous owner's sessions lost
+	// access to the agent. It is set by ApplyOwnershipTransfer only.
+	RevokedSessions int64 `json:"-"`
+}
+
+// ApplyOwnershipTransfer moves the agent linked to t.TokenID on t.ChainID
+// to t.ToAddress. In one transaction it records the change, queues
+// ownership.transferred for both wallets, disables the agent's webhook
+// subscriptions, detaches it from its organization, revokes its API keys
+// and the previous owner's sessions on this agent, and appends
+// agent.ownership_transferred to the agent's and both wallets' audit
+// chains. The previous owner's sessions stay valid for its other agents.
+// The remaining fields of t are filled in. Returns false when no agent is linked to the token, it is already
+// owned by t.ToAddress or the log was applied before.
+func (s *Store) ApplyOwnershipTransfer(ctx context.Context, t *OwnershipTransfer) (bool, error) {
+	t.ToAddress = strings.ToLower(t.ToAddress)
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		SELECT id, agent_id, LOWER(COALESCE(evm_address, ''))
+		FROM agents WHERE erc8004_token_id = $1 AND chain_id = $2
+		FOR UPDATE
+	`, t.TokenID, t.ChainID).Scan(&t.AgentDBID, &t.AgentID, &t.FromAddress)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("lock agent: %w", err)
+	}
+	if t.FromAddress == t.ToAddress {
+		return false, nil
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO agent_ownership_transfers (agent_id, chain_id, token_id, from_address, to_address,
+			tx_hash, log_index, block_number)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at
+	`, t.AgentDBID, t.ChainID, t.TokenID, t.FromAddress, t.ToAddress,
+		t.TxHash, t.LogIndex, t.BlockNumber).Scan(&t.ID, &t.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("insert ownership transfer: %w", err)
+	}
+
+	// Queued before the agent changes hands so the previous owner's
+	// subscriptions still match.
+	err = enqueueWebhookEvent(ctx, tx, WebhookEvent{
+		AgentDBID: &t.AgentDBID,
+		Type:      webhook.EventOwnershipTransferred,
+		Data: map[string]any{
+			"chain_id":     t.ChainID,
+			"token_id":     t.TokenID,
+			"from":         t.FromAddress,
+			"to":           t.ToAddress,
+			"tx_hash":      t.TxHash,
+			"log_index":    t.LogIndex,
+			"block_number": t.BlockNumber,
+		},
+		Wallets:  []string{t.FromAddress, t.ToAddress},
+		DedupKey: "ownership:" + strconv.FormatInt(t.ID, 10),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE webhook_subscriptions SET active = FALSE, updated_at = NOW()
+		WHERE agent_id = $1 AND active
+	`, t.AgentDBID); err != nil {
+		return false, fmt.Errorf("disable agent webhooks: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE agents SET evm_address = $2, org_id = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, t.AgentDBID, t.ToAddress); err != nil {
+		return false, fmt.Errorf("update agent owner: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE agent_id = $1 AND revoked_at IS NULL
+	`, t.AgentDBID)
+	if err != nil {
+		return false, fmt.Errorf("revoke api keys: %w", err)
+	}
+	t.RevokedAPIKeys = tag.RowsAffected()
+	if t.FromAddress != "" {
+		tag, err = tx.Exec(ctx, `
+			INSERT INTO agent_session_revocations (agent_id, session_id)
+			SELECT $1, id FROM wallet_sessions
+			WHERE address = $2 AND revoked_at IS NULL AND expires_at > NOW()
+			ON CONFLICT DO NOTHING
+		`, t.AgentDBID, t.FromAddress)
+		if err != nil {
+			return false, fmt.Errorf("revoke agent sessions: %w", err)
+		}
+		t.RevokedSessions = tag.RowsAffected()
+	}
+	// A wallet that owned the agent before gets it back with its current
+	// sessions.
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM agent_session_revocations
+		WHERE agent_id = $1 AND session_id IN (SELECT id FROM wallet_sessions WHERE address = $2)
+	`, t.AgentDBID, t.ToAddress); err != nil {
+		return false, fmt.Errorf("restore agent sessions: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE agent_ownership_transfers SET revoked_api_keys = $2
+		WHERE id = $1
+	`, t.ID, t.RevokedAPIKeys); err != nil {
+		return false, fmt.Errorf("update ownership transfer: %w", err)
+	}
+
+	before, _ := json.Marshal(map[string]any{"evm_address": t.FromAddress})
+	after, _ := json.Marshal(map[string]any{
+		"evm_address":      t.ToAddress,
+		"chain_id":         t.ChainID,
+		"token_id":         t.TokenID,
+		"tx_hash":          t.TxHash,
+		"log_index":        t.LogIndex,
+		"revoked_api_keys": t.RevokedAPIKeys,
+		"revoked_sessions": t.RevokedSessions,
+	})
+	chains := []string{"agent:" + t.AgentID, "wallet:" + t.ToAddress}
+	if t.FromAddress != "" {
+		chains = append(chains, "wallet:"+t.FromAddress)
+	}
+	// Chain locks are taken in a fixed order so concurrent transfers
+	// between the same wallets cannot deadlock.
+	sort.Strings(chains)
+	for _, chain := range chains {
+		err := appendAudit(ctx, tx, &AuditEntry{
+			Chain:      chain,
+			ActorType:  "system",
+			Actor:      "erc8004-transfer-watcher",
+			Action:     "agent.ownership_transferred",
+			TargetType: "agent",
+			TargetID:   t.AgentID,
+			Before:     before,
+			After:      after,
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit tx: %w", err)
+	}
+	return true, nil
+}
+
+// ListOwnershipTransfers returns an agent's ownership changes, newest first.
+func (s *Store) ListOwnershipTransfers(ctx context.Context, agentDBID uuid.UUID) ([]OwnershipTransfer, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT t.id, t.agent_id, a.agent_id, t.chain_id, t.token_id, t.from_address, t.to_address,
+			t.tx_hash, t.log_index, t.block_number, t.revoked_api_keys, t.created_at
+		FROM agent_ownership_transfers t
+		JOIN agents a ON a.id = t.agent_id
+		WHERE t.agent_id = $1
+		ORDER BY t.created_at DESC, t.id DESC
+	`, agentDBID)
+	if err != nil {
+		return nil, fmt.Errorf("list ownership transfers: %w", err)
+	}
+	defer rows.Close()
+
+	transfers := []OwnershipTransfer{}
+	for rows.Next() {
+		var t OwnershipTransfer
+		if err := rows.Scan(&t.ID, &t.AgentDBID, &t.AgentID, &t.ChainID, &t.TokenID, &t.FromAddress, &t.ToAddress,
+			&t.TxHash, &t.LogIndex, &t.BlockNumber, &t.RevokedAPIKeys, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan ownership transfer: %w", err)
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
+
+// LinkedToken is an agent linked to an ERC-8004 token and the wallet the
+// registry has as its owner.
+type LinkedToken struct {
+	AgentID string
+	TokenID int64
+	Owner   string
+}
+
+// ListLinkedTokens returns every agent linked to a token on chainID.
+func (s *Store) ListLinkedTokens(ctx context.Context, chainID int) ([]LinkedToken, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT agent_id, erc8004_token_id, LOWER(COALESCE(evm_address, ''))
+		FROM agents
+		WHERE erc8004_token_id IS NOT NULL AND chain_id = $1
+		ORDER BY erc8004_token_id
+	`, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("list linked tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []LinkedToken{}
+	for rows.Next() {
+		var t LinkedToken
+		if err := rows.Scan(&t.AgentID, &t.TokenID, &t.Owner); err != nil {
+			return nil, fmt.Errorf("scan linked token: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// GetTransferCursor returns the last block scanned for Transfer logs on
+// chainID. ok is false when the chain has not been scanned yet.
+func (s *Store) GetTransferCursor(ctx context.Context, chainID int) (uint64, bool, error) {
+	var block int64
+	err := s.pool.QueryRow(ctx, `
+		SELECT last_block FROM erc8004_transfer_cursors WHERE chain_id = $1
+	`, chainID).Scan(&block)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("get transfer cursor: %w", err)
+	}
+	return uint64(block), true, nil
+}
+
+// SetTransferCursor records that Transfer logs on chainID were scanned
+// through block. The cursor never moves backwards.
+func (s *Store) SetTransferCursor(ctx context.Context, chainID int, block uint64) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO erc8004_transfer_cursors (chain_id, last_block) VALUES ($1, $2)
+		ON CONFLICT (chain_id) DO UPDATE
+		SET last_block = GREATEST(erc8004_transfer_cursors.last_block, EXCLUDED.last_block), updated_at = NOW()
+	`, chainID, int64(block))
+	if err != nil {
+		return fmt.Errorf("set transfer cursor: %w", err)
+	}
+	return nil
+}
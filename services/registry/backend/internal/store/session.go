@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// WalletSession is a Sign-In With Ethereum session.
+type WalletSession struct {
+	ID          uuid.UUID  `json:"id"`
+	Address     string     `json:"address"`
+	ChainID     int        `json:"chain_id"`
+	UserAgent   string     `json:"user_agent,omitempty"`
+	IPAddress   string     `json:"ip_address,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RefreshedAt time.Time  `json:"refreshed_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// newRefreshToken returns a random refresh token and its SHA-256 hash.
+func newRefreshToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("generate random bytes: %w", err)
+	}
+	raw := "gt8004_rt_" + hex.EncodeToString(b)
+	hash := sha256.Sum256([]byte(raw))
+	return raw, hex.EncodeToString(hash[:]), nil
+}
+
+// CreateWalletSession starts a session for a lowercase wallet address and
+// returns it with its raw refresh token (only returned once).
+func (s *Store) CreateWalletSession(ctx context.Context, address string, chainID int, userAgent, ip string, ttl time.Duration) (*WalletSession, string, error) {
+	raw, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	ws := &WalletSession{Address: address, ChainID: chainID, UserAgent: userAgent, IPAddress: ip}
+	err = s.pool.QueryRow(ctx, `
+		INSERT INTO wallet_sessions (address, chain_id, refresh_token_hash, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, refreshed_at, expires_at
+	`, address, chainID, hash, userAgent, ip, time.Now().Add(ttl)).Scan(&ws.ID, &ws.CreatedAt, &ws.RefreshedAt, &ws.ExpiresAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("create wallet session: %w", err)
+	}
+	return ws, raw, nil
+}
+
+// RotateRefreshToken exchanges a live session's refresh token for a new
+// one and extends the session by ttl. The old token stops working.
+// Returns pgx.ErrNoRows when the token is unknown, expired or revoked.
+func (s *Store) RotateRefreshToken(ctx context.Context, refreshToken string, ttl time.Duration) (*WalletSession, string, error) {
+	oldHash := sha256.Sum256([]byte(refreshToken))
+	raw, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	ws := &WalletSession{}
+	var userAgent, ip *string
+	err = s.pool.QueryRow(ctx, `
+		UPDATE wallet_sessions
+		SET refresh_token_hash = $2, refreshed_at = NOW(), expires_at = $3
+		WHERE refresh_token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING id, address, chain_id, user_agent, ip_address, created_at, refreshed_at, expires_at
+	`, hex.EncodeToString(oldHash[:]), newHash, time.Now().Add(ttl)).Scan(
+		&ws.ID, &ws.Address, &ws.ChainID, &userAgent, &ip, &ws.CreatedAt, &ws.RefreshedAt, &ws.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", err
+		}
+		return nil, "", fmt.Errorf("rotate refresh token: %w", err)
+	}
+	if userAgent != nil {
+		ws.UserAgent = *userAgent
+	}
+	if ip != nil {
+		ws.IPAddress = *ip
+	}
+	return ws, raw, nil
+}
+
+// RevokeWalletSession revokes one of address's sessions. Returns
+// pgx.ErrNoRows when no live session with that ID belongs to address.
+func (s *Store) RevokeWalletSession(ctx context.Context, sessionID uuid.UUID, address string) error {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE wallet_sessions SET revoked_at = NOW()
+		WHERE id = $1 AND address = $2 AND revoked_at IS NULL
+	`, sessionID, address)
+	if err != nil {
+		return fmt.Errorf("revoke wallet session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// RevokeAllWalletSessions revokes every live session of address and returns
+// how many were revoked.
+func (s *Store) RevokeAllWalletSessions(ctx context.Context, address string) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE wallet_sessions SET revoked_at = NOW()
+		WHERE address = $1 AND revoked_at IS NULL
+	`, address)
+	if err != nil {
+		return 0, fmt.Errorf("revoke wallet sessions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ListWalletSessions returns address's live sessions, newest first.
+func (s *Store) ListWalletSessions(ctx context.Context, address string) ([]WalletSession, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, address, chain_id, COALESCE(user_agent, ''), COALESCE(ip_address, ''),
+		       created_at, refreshed_at, expires_at
+		FROM wallet_sessions
+		WHERE address = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`, address)
+	if err != nil {
+		return nil, fmt.Errorf("list wallet sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []WalletSession{}
+	for rows.Next() {
+		var ws WalletSession
+		if err := rows.Scan(&ws.ID, &ws.Address, &ws.ChainID, &ws.UserAgent, &ws.IPAddress,
+			&ws.CreatedAt, &ws.RefreshedAt, &ws.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("scan wallet session: %w", err)
+		}
+		sessions = append(sessions, ws)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list wallet sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// IsSessionRevoked reports whether a session can no longer be used: it was
+// revoked, its refresh token expired, or it does not exist.
+func (s *Store) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return true, nil
+	}
+	var live bool
+	err = s.pool.QueryRow(ctx, `
+		SELECT revoked_at IS NULL AND expires_at > NOW() FROM wallet_sessions WHERE id = $1
+	`, id).Scan(&live)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check wallet session: %w", err)
+	}
+	return !live, nil
+}
+
+// IsSessionRevokedForAgent reports whether a session lost access to an
+// agent when the agent's ownership was transferred away from its wallet.
+func (s *Store) IsSessionRevokedForAgent(ctx context.Context, sessionID string, agentDBID uuid.UUID) (bool, error) {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return true, nil
+	}
+	var revoked bool
+	err = s.pool.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM agent_session_revocations WHERE agent_id = $1 AND session_id = $2)
+	`, agentDBID, id).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("check agent session revocation: %w", err)
+	}
+	return revoked, nil
+}
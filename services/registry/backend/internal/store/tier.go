@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrPaymentUsed is returned when a payment transaction was already applied.
+var ErrPaymentUsed = errors.New("payment already used")
+
+// TierSubscription is one paid period of a tier. ChainID, TxHash and Payer
+// are nil for periods granted without a payment.
+type TierSubscription struct {
+	ID          int64     `json:"id"`
+	Tier        string    `json:"tier"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	AmountUSDC  float64   `json:"amount_usdc"`
+	ChainID     *int      `json:"chain_id,omitempty"`
+	TxHash      *string   `json:"tx_hash,omitempty"`
+	Payer       *string   `json:"payer,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+const tierSubscriptionCols = `id, tier, period_start, period_end, amount_usdc::float8, chain_id, tx_hash, payer, created_at`
+
+func scanTierSubscription(row pgx.Row) (*TierSubscription, error) {
+	var t TierSubscription
+	err := row.Scan(&t.ID, &t.Tier, &t.PeriodStart, &t.PeriodEnd, &t.AmountUSDC,
+		&t.ChainID, &t.TxHash, &t.Payer, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetActiveTierSubscription returns the agent's period of tier covering
+// now, or nil when there is none.
+func (s *Store) GetActiveTierSubscription(ctx context.Context, agentDBID uuid.UUID, tier string) (*TierSubscription, error) {
+	t, err := scanTierSubscription(s.pool.QueryRow(ctx, `
+		SELECT `+tierSubscriptionCols+` FROM tier_subscriptions
+		WHERE agent_id = $1 AND tier = $2 AND period_start <= NOW() AND period_end > NOW()
+		ORDER BY period_end DESC
+		LIMIT 1
+	`, agentDBID, tier))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get active tier subscription: %w", err)
+	}
+	return t, nil
+}
+
+// ListTierSubscriptions returns the agent's periods, latest first.
+func (s *Store) ListTierSubscriptions(ctx context.Context, agentDBID uuid.UUID) ([]TierSubscription, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+tierSubscriptionCols+` FROM tier_subscriptions
+		WHERE agent_id = $1
+		ORDER BY period_end DESC, id DESC
+	`, agentDBID)
+	if err != nil {
+		return nil, fmt.Errorf("list tier subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []TierSubscription{}
+	for rows.Next() {
+		t, err := scanTierSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan tier subscription: %w", err)
+		}
+		subs = append(subs, *t)
+	}
+	return subs, rows.Err()
+}
+
+// IsTierPaymentUsed reports whether txHash already paid for a period.
+func (s *Store) IsTierPaymentUsed(ctx context.Context, txHash string) (bool, error) {
+	var used bool
+	err := s.pool.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM tier_subscriptions WHERE LOWER(tx_hash) = LOWER($1))
+	`, txHash).Scan(&used)
+	if err != nil {
+		return false, fmt.Errorf("check tier payment: %w", err)
+	}
+	return used, nil
+}
+
+// RecordTierPayment adds a paid period of sub.Tier lasting period, starting
+// when the agent's latest period of that tier ends (or now), and moves the
+// agent to the tier. sub's period and ID fields are filled in. Returns
+// ErrPaymentUsed when sub.TxHash was already applied.
+func (s *Store) RecordTierPayment(ctx context.Context, agentDBID uuid.UUID, sub *TierSubscription, period time.Duration) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Serializes payments for one agent so periods do not overlap.
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM agents WHERE id = $1 FOR UPDATE`, agentDBID); err != nil {
+		return fmt.Errorf("lock agent: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO tier_subscriptions (agent_id, tier, period_start, period_end, amount_usdc, chain_id, tx_hash, payer)
+		SELECT $1, $2, start, start + make_interval(secs => $3), $4, $5, LOWER($6), LOWER($7)
+		FROM (
+			SELECT GREATEST(NOW(), COALESCE(MAX(period_end), NOW())) AS start
+			FROM tier_subscriptions WHERE agent_id = $1 AND tier = $2
+		) p
+		ON CONFLICT (tx_hash) DO NOTHING
+		RETURNING id, period_start, period_end, created_at
+	`, agentDBID, sub.Tier, period.Seconds(), sub.AmountUSDC, sub.ChainID, sub.TxHash, sub.Payer,
+	).Scan(&sub.ID, &sub.PeriodStart, &sub.PeriodEnd, &sub.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrPaymentUsed
+	}
+	if err != nil {
+		return fmt.Errorf("insert tier subscription: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE agents
+		SET current_tier = $2, tier_updated_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND current_tier <> $2
+	`, agentDBID, sub.Tier); err != nil {
+		return fmt.Errorf("update tier: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// ExpiredTier is an agent moved back to "open" when its paid period ended.
+type ExpiredTier struct {
+	AgentDBID uuid.UUID
+	AgentID   string
+	Tier      string
+}
+
+// ExpireTiers moves agents on a paid tier with no period of it covering
+// now back to "open" and returns them.
+func (s *Store) ExpireTiers(ctx context.Context) ([]ExpiredTier, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH expired AS (
+			SELECT id, agent_id, current_tier FROM agents a
+			WHERE current_tier <> 'open'
+			  AND NOT EXISTS (
+				SELECT 1 FROM tier_subscriptions t
+				WHERE t.agent_id = a.id AND t.tier = a.current_tier
+				  AND t.period_start <= NOW() AND t.period_end > NOW()
+			  )
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE agents a
+		SET current_tier = 'open', tier_updated_at = NOW(), updated_at = NOW()
+		FROM expired e
+		WHERE a.id = e.id
+		RETURNING e.id, e.agent_id, e.current_tier
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("expire tiers: %w", err)
+	}
+	defer rows.Close()
+
+	expired := []ExpiredTier{}
+	for rows.Next() {
+		var e ExpiredTier
+		if err := rows.Scan(&e.AgentDBID, &e.AgentID, &e.Tier); err != nil {
+			return nil, fmt.Errorf("scan expired tier: %w", err)
+		}
+		expired = append(expired, e)
+	}
+	return expired, rows.Err()
+}
@@ -0,0 +1,489 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// WebhookOwner scopes subscriptions to one agent or, when AgentDBID is
+// nil, to every agent registered to Wallet.
+type WebhookOwner struct {
+	AgentDBID *uuid.UUID
+	Wallet    string
+}
+
+func (o WebhookOwner) args() (any, any) {
+	if o.AgentDBID != nil {
+		return *o.AgentDBID, nil
+	}
+	return nil, strings.ToLower(o.Wallet)
+}
+
+// webhookOwnerCond matches the owner passed as ($n, $n+1) from args.
+func webhookOwnerCond(n int) string {
+	return fmt.Sprintf("agent_id IS NOT DISTINCT FROM $%d::uuid AND wallet_address IS NOT DISTINCT FROM $%d::text", n, n+1)
+}
+
+// WebhookSubscription is an endpoint subscribed to events. The signing
+// secret is only returned when the subscription is created or rotated.
+type WebhookSubscription struct {
+	ID            uuid.UUID `json:"id"`
+	WalletAddress *string   `json:"wallet_address,omitempty"`
+	URL           string    `json:"url"`
+	Events        []string  `json:"events"`
+	Description   *string   `json:"description,omitempty"`
+	Active        bool      `json:"active"`
+	CreatedBy     *string   `json:"created_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// WebhookUpdate holds the fields to change on a subscription; nil fields
+// are left as they are.
+type WebhookUpdate struct {
+	URL         *string
+	Events      []string
+	Description *string
+	Active      *bool
+}
+
+// WebhookDelivery is one event sent to one subscription.
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	RedeliveryOf   *uuid.UUID      `json:"redelivery_of,omitempty"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// WebhookAttempt is one HTTP attempt of a delivery.
+type WebhookAttempt struct {
+	Attempt      int       `json:"attempt"`
+	StatusCode   *int      `json:"status_code,omitempty"`
+	Error        *string   `json:"error,omitempty"`
+	ResponseBody *string   `json:"response_body,omitempty"`
+	DurationMs   int       `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// WebhookJob is a claimed delivery with everything needed to send it.
+type WebhookJob struct {
+	DeliveryID     uuid.UUID
+	Attempt        int
+	URL            string
+	Secret         string
+	EventID        uuid.UUID
+	EventType      string
+	AgentID        string // empty for wallet-only events
+	Payload        json.RawMessage
+	EventCreatedAt time.Time
+}
+
+const webhookSubscriptionCols = `id, wallet_address, url, events, description, active, created_by, created_at, updated_at`
+
+func scanWebhookSubscription(row pgx.Row) (*WebhookSubscription, error) {
+	w := &WebhookSubscription{}
+	err := row.Scan(&w.ID, &w.WalletAddress, &w.URL, &w.Events, &w.Description, &w.Active, &w.CreatedBy, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// CreateWebhookSubscription subscribes url to events for owner.
+func (s *Store) CreateWebhookSubscription(ctx context.Context, owner WebhookOwner, url, secret string, events []string, description, createdBy string) (*WebhookSubscription, error) {
+	agentDBID, wallet := owner.args()
+	w, err := scanWebhookSubscription(s.pool.QueryRow(ctx, `
+		INSERT INTO webhook_subscriptions (agent_id, wallet_address, url, secret, events, description, created_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''))
+		RETURNING `+webhookSubscriptionCols,
+		agentDBID, wallet, url, secret, events, description, createdBy))
+	if err != nil {
+		return nil, fmt.Errorf("create webhook subscription: %w", err)
+	}
+	return w, nil
+}
+
+// ListWebhookSubscriptions returns owner's subscriptions, newest first.
+func (s *Store) ListWebhookSubscriptions(ctx context.Context, owner WebhookOwner) ([]WebhookSubscription, error) {
+	agentDBID, wallet := owner.args()
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+webhookSubscriptionCols+`
+		FROM webhook_subscriptions
+		WHERE `+webhookOwnerCond(1)+`
+		ORDER BY created_at DESC
+	`, agentDBID, wallet)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []WebhookSubscription{}
+	for rows.Next() {
+		w, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook subscription: %w", err)
+		}
+		subs = append(subs, *w)
+	}
+	return subs, rows.Err()
+}
+
+// GetWebhookSubscription returns one of owner's subscriptions, or
+// pgx.ErrNoRows.
+func (s *Store) GetWebhookSubscription(ctx context.Context, owner WebhookOwner, id uuid.UUID) (*WebhookSubscription, error) {
+	agentDBID, wallet := owner.args()
+	w, err := scanWebhookSubscription(s.pool.QueryRow(ctx, `
+		SELECT `+webhookSubscriptionCols+`
+		FROM webhook_subscriptions
+		WHERE id = $1 AND `+webhookOwnerCond(2),
+		id, agentDBID, wallet))
+	if err != nil {
+		return nil, fmt.Errorf("get webhook subscription: %w", err)
+	}
+	return w, nil
+}
+
+// UpdateWebhookSubscription applies u to one of owner's subscriptions.
+func (s *Store) UpdateWebhookSubscription(ctx context.Context, owner WebhookOwner, id uuid.UUID, u WebhookUpdate) (*WebhookSubscription, error) {
+	agentDBID, wallet := owner.args()
+	w, err := scanWebhookSubscription(s.pool.QueryRow(ctx, `
+		UPDATE webhook_subscriptions
+		SET url = COALESCE($4, url),
+			events = COALESCE($5, events),
+			description = CASE WHEN $6::text IS NULL THEN description ELSE NULLIF($6, '') END,
+			active = COALESCE($7, active),
+			updated_at = NOW()
+		WHERE id = $1 AND `+webhookOwnerCond(2)+`
+		RETURNING `+webhookSubscriptionCols,
+		id, agentDBID, wallet, u.URL, u.Events, u.Description, u.Active))
+	if err != nil {
+		return nil, fmt.Errorf("update webhook subscription: %w", err)
+	}
+	return w, nil
+}
+
+// RotateWebhookSecret replaces a subscription's signing secret.
+func (s *Store) RotateWebhookSecret(ctx context.Context, owner WebhookOwner, id uuid.UUID, secret string) error {
+	agentDBID, wallet := owner.args()
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE webhook_subscriptions SET secret = $4, updated_at = NOW()
+		WHERE id = $1 AND `+webhookOwnerCond(2),
+		id, agentDBID, wallet, secret)
+	if err != nil {
+		return fmt.Errorf("rotate webhook secret: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteWebhookSubscription deletes a subscription and its delivery log.
+func (s *Store) DeleteWebhookSubscription(ctx context.Context, owner WebhookOwner, id uuid.UUID) error {
+	agentDBID, wallet := owner.args()
+	tag, err := s.pool.Exec(ctx, `
+		DELETE FROM webhook_subscriptions WHERE id = $1 AND `+webhookOwnerCond(2),
+		id, agentDBID, wallet)
+	if err != nil {
+		return fmt.Errorf("delete webhook subscription: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// WebhookEvent is an event to queue. It goes to subscriptions of the agent
+// (when set), of its owner wallet, and of each of Wallets (e.g. both sides
+// of a transfer). A non-empty DedupKey is recorded once; repeats are
+// dropped.
+type WebhookEvent struct {
+	AgentDBID *uuid.UUID
+	Type      string
+	Data      any
+	Wallets   []string
+	DedupKey  string
+}
+
+// EnqueueWebhookEvent records an event and queues a delivery to each
+// active subscription to its type. Events nobody subscribes to are not
+// stored.
+func (s *Store) EnqueueWebhookEvent(ctx context.Context, ev WebhookEvent) error {
+	return enqueueWebhookEvent(ctx, s.pool, ev)
+}
+
+// execer is satisfied by both the pool and a transaction.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func enqueueWebhookEvent(ctx context.Context, q execer, ev WebhookEvent) error {
+	payload, err := json.Marshal(ev.Data)
+	if err != nil {
+		return fmt.Errorf("marshal webhook payload: %w", err)
+	}
+	wallets := make([]string, 0, len(ev.Wallets))
+	for _, w := range ev.Wallets {
+		if w != "" {
+			wallets = append(wallets, strings.ToLower(w))
+		}
+	}
+	_, err = q.Exec(ctx, `
+		WITH subs AS (
+			SELECT id FROM webhook_subscriptions
+			WHERE active AND $2 = ANY(events)
+			  AND (agent_id = $1
+			    OR wallet_address = (SELECT LOWER(evm_address) FROM agents WHERE id = $1)
+			    OR wallet_address = ANY($4::text[]))
+		), ev AS (
+			INSERT INTO webhook_events (agent_id, event_type, payload, dedup_key)
+			SELECT $1, $2, $3, NULLIF($5, '') WHERE EXISTS (SELECT 1 FROM subs)
+			ON CONFLICT (dedup_key) DO NOTHING
+			RETURNING id
+		)
+		INSERT INTO webhook_deliveries (subscription_id, event_id)
+		SELECT subs.id, ev.id FROM subs, ev
+	`, ev.AgentDBID, ev.Type, payload, wallets, ev.DedupKey)
+	if err != nil {
+		return fmt.Errorf("enqueue webhook event: %w", err)
+	}
+	return nil
+}
+
+const webhookDeliveryCols = `d.id, d.subscription_id, d.event_id, e.event_type, e.payload, d.redelivery_of,
+	d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.delivered_at, d.created_at`
+
+func scanWebhookDelivery(row pgx.Row) (*WebhookDelivery, error) {
+	d := &WebhookDelivery{}
+	var payload []byte
+	var next time.Time
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.RedeliveryOf,
+		&d.Status, &d.Attempts, &next, &d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	if d.Status == "pending" {
+		d.NextAttemptAt = &next
+	}
+	return d, nil
+}
+
+// ListWebhookDeliveries returns a subscription's deliveries, newest first,
+// optionally filtered by status.
+func (s *Store) ListWebhookDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int) ([]WebhookDelivery, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+webhookDeliveryCols+`
+		FROM webhook_deliveries d
+		JOIN webhook_events e ON e.id = d.event_id
+		WHERE d.subscription_id = $1 AND ($2 = '' OR d.status = $2)
+		ORDER BY d.created_at DESC
+		LIMIT $3
+	`, subscriptionID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetWebhookDelivery returns a delivery of a subscription with its
+// attempts, or pgx.ErrNoRows.
+func (s *Store) GetWebhookDelivery(ctx context.Context, subscriptionID, id uuid.UUID) (*WebhookDelivery, []WebhookAttempt, error) {
+	d, err := scanWebhookDelivery(s.pool.QueryRow(ctx, `
+		SELECT `+webhookDeliveryCols+`
+		FROM webhook_deliveries d
+		JOIN webhook_events e ON e.id = d.event_id
+		WHERE d.id = $1 AND d.subscription_id = $2
+	`, id, subscriptionID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("get webhook delivery: %w", err)
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT attempt, status_code, error, response_body, duration_ms, created_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY attempt
+	`, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list webhook attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []WebhookAttempt{}
+	for rows.Next() {
+		var a WebhookAttempt
+		if err := rows.Scan(&a.Attempt, &a.StatusCode, &a.Error, &a.ResponseBody, &a.DurationMs, &a.CreatedAt); err != nil {
+			return nil, nil, fmt.Errorf("scan webhook attempt: %w", err)
+		}
+		attempts = append(attempts, a)
+	}
+	return d, attempts, rows.Err()
+}
+
+// RedeliverWebhook queues a new delivery of the same event to the same
+// subscription and returns its ID.
+func (s *Store) RedeliverWebhook(ctx context.Context, subscriptionID, id uuid.UUID) (uuid.UUID, error) {
+	var newID uuid.UUID
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, redelivery_of)
+		SELECT subscription_id, event_id, id FROM webhook_deliveries
+		WHERE id = $1 AND subscription_id = $2
+		RETURNING id
+	`, id, subscriptionID).Scan(&newID)
+	if err != nil {
+		return uuid.UUID{}, fmt.Errorf("redeliver webhook: %w", err)
+	}
+	return newID, nil
+}
+
+// ClaimWebhookDelivery marks the next due delivery as in flight and returns
+// it, or nil when none is due.
+func (s *Store) ClaimWebhookDelivery(ctx context.Context) (*WebhookJob, error) {
+	j := &WebhookJob{}
+	var payload []byte
+	err := s.pool.QueryRow(ctx, `
+		WITH claimed AS (
+			UPDATE webhook_deliveries
+			SET status = 'delivering', attempts = attempts + 1, updated_at = NOW()
+			WHERE id = (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at
+				FOR UPDATE SKIP LOCKED
+				LIMIT 1
+			)
+			RETURNING id, subscription_id, event_id, attempts
+		)
+		SELECT c.id, c.attempts, ws.url, ws.secret, e.id, e.event_type, COALESCE(a.agent_id, ''), e.payload, e.created_at
+		FROM claimed c
+		JOIN webhook_subscriptions ws ON ws.id = c.subscription_id
+		JOIN webhook_events e ON e.id = c.event_id
+		LEFT JOIN agents a ON a.id = e.agent_id
+	`).Scan(&j.DeliveryID, &j.Attempt, &j.URL, &j.Secret, &j.EventID, &j.EventType, &j.AgentID, &payload, &j.EventCreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook delivery: %w", err)
+	}
+	j.Payload = payload
+	return j, nil
+}
+
+// RecordWebhookAttempt logs an attempt and settles the delivery: succeeded,
+// pending until retryAt, or failed when retryAt is nil.
+func (s *Store) RecordWebhookAttempt(ctx context.Context, deliveryID uuid.UUID, a WebhookAttempt, succeeded bool, retryAt *time.Time) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, response_body, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, deliveryID, a.Attempt, a.StatusCode, a.Error, a.ResponseBody, a.DurationMs)
+	if err != nil {
+		return fmt.Errorf("insert webhook attempt: %w", err)
+	}
+
+	status := "failed"
+	switch {
+	case succeeded:
+		status = "succeeded"
+	case retryAt != nil:
+		status = "pending"
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2,
+			next_attempt_at = COALESCE($3, next_attempt_at),
+			last_status_code = $4,
+			last_error = $5,
+			delivered_at = CASE WHEN $2 = 'succeeded' THEN NOW() ELSE delivered_at END,
+			updated_at = NOW()
+		WHERE id = $1
+	`, deliveryID, status, retryAt, a.StatusCode, a.Error)
+	if err != nil {
+		return fmt.Errorf("update webhook delivery: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// ReleaseStaleWebhookDeliveries requeues deliveries left in flight longer
+// than after, presumably by an instance that died.
+func (s *Store) ReleaseStaleWebhookDeliveries(ctx context.Context, after time.Duration) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE webhook_deliveries SET status = 'pending', next_attempt_at = NOW(), updated_at = NOW()
+		WHERE status = 'delivering' AND updated_at < NOW() - make_interval(secs => $1)
+	`, after.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("release stale webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DisconnectedSDK is an agent whose SDK stopped pinging.
+type DisconnectedSDK struct {
+	AgentDBID  uuid.UUID
+	AgentID    string
+	LastPingAt time.Time
+}
+
+// MarkSDKDisconnected flags agents whose last SDK ping is older than after
+// and returns those newly flagged. The next ping clears the flag.
+func (s *Store) MarkSDKDisconnected(ctx context.Context, after time.Duration) ([]DisconnectedSDK, error) {
+	rows, err := s.pool.Query(ctx, `
+		UPDATE agents SET sdk_disconnected_at = NOW()
+		WHERE sdk_connected_at < NOW() - make_interval(secs => $1)
+		  AND sdk_disconnected_at IS NULL
+		  AND status = 'active'
+		RETURNING id, agent_id, sdk_connected_at
+	`, after.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("mark sdk disconnected: %w", err)
+	}
+	defer rows.Close()
+
+	agents := []DisconnectedSDK{}
+	for rows.Next() {
+		var d DisconnectedSDK
+		if err := rows.Scan(&d.AgentDBID, &d.AgentID, &d.LastPingAt); err != nil {
+			return nil, fmt.Errorf("scan disconnected sdk: %w", err)
+		}
+		agents = append(agents, d)
+	}
+	return agents, rows.Err()
+}
@@ -0,0 +1,281 @@
+// Package webhook delivers queued platform events to subscribed endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	"github.com/GT8004/gt8004-common/webhook"
+	"github.com/GT8004/gt8004/internal/store"
+)
+
+const (
+	// pollInterval is how often the worker checks for due deliveries when it
+	// has not been notified.
+	pollInterval = 5 * time.Second
+	// requestTimeout bounds one delivery attempt.
+	requestTimeout = 10 * time.Second
+	// staleAfter requeues deliveries an instance claimed but never settled.
+	staleAfter = 5 * time.Minute
+	// maxResponseBody is how much of an endpoint's response is logged.
+	maxResponseBody = 2048
+)
+
+// Config configures the worker.
+type Config struct {
+	// SDKDisconnectAfter is how long an agent's SDK may go without pinging
+	// before sdk.disconnected is sent.
+	SDKDisconnectAfter time.Duration
+	// AllowPrivateNetworks permits endpoints that resolve to loopback or
+	// private addresses (local development).
+	AllowPrivateNetworks bool
+}
+
+// Worker sends due deliveries one at a time, retrying failures with
+// backoff, and emits sdk.disconnected for agents whose SDK went quiet.
+type Worker struct {
+	store    *store.Store
+	cfg      Config
+	client   *http.Client
+	logger   *zap.Logger
+	notifyCh chan struct{}
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewWorker creates a delivery worker.
+func NewWorker(s *store.Store, cfg Config, logger *zap.Logger) *Worker {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = rejectPrivate
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Worker{
+		store:  s,
+		cfg:    cfg,
+		logger: logger,
+		client: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: transport,
+			// A redirect is reported as the endpoint's response, not followed.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		notifyCh: make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// rejectPrivate refuses connections to loopback, private, link-local and
+// unspecified addresses so subscriptions cannot reach internal services.
+func rejectPrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("webhook endpoint resolves to a disallowed address %s", host)
+	}
+	return nil
+}
+
+// Notify wakes the worker after events have been queued.
+func (w *Worker) Notify() {
+	select {
+	case w.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// Start begins delivering in a background goroutine.
+func (w *Worker) Start() {
+	go func() {
+		defer close(w.doneCh)
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		w.logger.Info("webhook worker started",
+			zap.Duration("sdk_disconnect_after", w.cfg.SDKDisconnectAfter))
+
+		for {
+			w.housekeep()
+			w.drain()
+
+			select {
+			case <-ticker.C:
+			case <-w.notifyCh:
+			case <-w.stopCh:
+				w.logger.Info("webhook worker stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop signals the worker to stop after the delivery in flight.
+func (w *Worker) Stop() {
+	close(w.stopCh)
+	<-w.doneCh
+}
+
+// drain sends due deliveries until none are left or the worker stops.
+func (w *Worker) drain() {
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		job, err := w.store.ClaimWebhookDelivery(ctx)
+		cancel()
+		if err != nil {
+			w.logger.Error("failed to claim webhook delivery", zap.Error(err))
+			return
+		}
+		if job == nil {
+			return
+		}
+		w.deliver(job)
+	}
+}
+
+func (w *Worker) deliver(job *store.WebhookJob) {
+	logger := w.logger.With(
+		zap.String("delivery_id", job.DeliveryID.String()),
+		zap.String("event_type", job.EventType),
+		zap.Int("attempt", job.Attempt),
+	)
+
+	attempt, ok := w.send(job)
+	var retryAt *time.Time
+	if !ok && job.Attempt < webhook.MaxAttempts {
+		t := time.Now().Add(webhook.Backoff(job.Attempt))
+		retryAt = &t
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := w.store.RecordWebhookAttempt(ctx, job.DeliveryID, attempt, ok, retryAt); err != nil {
+		logger.Error("failed to record webhook attempt", zap.Error(err))
+		return
+	}
+	switch {
+	case ok:
+		logger.Debug("webhook delivered")
+	case retryAt != nil:
+		logger.Info("webhook delivery failed, will retry", zap.Time("retry_at", *retryAt))
+	default:
+		logger.Warn("webhook delivery failed permanently")
+	}
+}
+
+// send POSTs the signed event and reports whether the endpoint answered 2xx.
+func (w *Worker) send(job *store.WebhookJob) (store.WebhookAttempt, bool) {
+	attempt := store.WebhookAttempt{Attempt: job.Attempt}
+	fail := func(err error) (store.WebhookAttempt, bool) {
+		msg := err.Error()
+		attempt.Error = &msg
+		return attempt, false
+	}
+
+	body, err := json.Marshal(webhook.Envelope{
+		ID:        job.EventID.String(),
+		Type:      job.EventType,
+		AgentID:   job.AgentID,
+		CreatedAt: job.EventCreatedAt,
+		Data:      job.Payload,
+	})
+	if err != nil {
+		return fail(fmt.Errorf("marshal event: %w", err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(body))
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GT8004-Webhooks/1.0")
+	req.Header.Set(webhook.EventHeader, job.EventType)
+	req.Header.Set(webhook.DeliveryHeader, job.DeliveryID.String())
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(job.Secret, time.Now(), body))
+
+	start := time.Now()
+	resp, err := w.client.Do(req)
+	attempt.DurationMs = int(time.Since(start).Milliseconds())
+	if err != nil {
+		var urlErr interface{ Timeout() bool }
+		if errors.As(err, &urlErr) && urlErr.Timeout() {
+			return fail(fmt.Errorf("timed out after %s", requestTimeout))
+		}
+		return fail(err)
+	}
+	defer resp.Body.Close()
+
+	code := resp.StatusCode
+	attempt.StatusCode = &code
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if len(snippet) > 0 {
+		// Postgres text cannot hold NUL or invalid UTF-8.
+		s := strings.ReplaceAll(string(bytes.ToValidUTF8(snippet, []byte(string(utf8.RuneError)))), "\x00", "")
+		attempt.ResponseBody = &s
+	}
+	if code < 200 || code > 299 {
+		msg := fmt.Sprintf("endpoint returned %d", code)
+		attempt.Error = &msg
+		return attempt, false
+	}
+	return attempt, true
+}
+
+// housekeep requeues abandoned deliveries and emits sdk.disconnected.
+func (w *Worker) housekeep() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if n, err := w.store.ReleaseStaleWebhookDeliveries(ctx, staleAfter); err != nil {
+		w.logger.Error("failed to release stale webhook deliveries", zap.Error(err))
+	} else if n > 0 {
+		w.logger.Warn("requeued stale webhook deliveries", zap.Int64("count", n))
+	}
+
+	if w.cfg.SDKDisconnectAfter <= 0 {
+		return
+	}
+	agents, err := w.store.MarkSDKDisconnected(ctx, w.cfg.SDKDisconnectAfter)
+	if err != nil {
+		w.logger.Error("failed to check sdk connections", zap.Error(err))
+		return
+	}
+	for _, a := range agents {
+		err := w.store.EnqueueWebhookEvent(ctx, store.WebhookEvent{
+			AgentDBID: &a.AgentDBID,
+			Type:      webhook.EventSDKDisconnected,
+			Data:      map[string]any{"last_ping_at": a.LastPingAt},
+		})
+		if err != nil {
+			w.logger.Error("failed to enqueue sdk.disconnected",
+				zap.String("agent_id", a.AgentID), zap.Error(err))
+		}
+	}
+}